@@ -0,0 +1,61 @@
+// eventstore-admin inspects and maintains a SQLite event store.
+//
+// The stock binary has no knowledge of your event types: payloads are shown
+// only for proto messages linked into it, and replay and projection rebuilds
+// are unavailable. Embed pkg/admin in your own binary to register them.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/plaenen/eventstore/pkg/admin"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func main() {
+	var dbPath string
+
+	flag.StringVar(&dbPath, "db", "events.db", "Path to the SQLite event store")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, admin.Usage)
+		fmt.Fprintf(os.Stderr, "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if _, err := os.Stat(dbPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot open event store: %v\n", err)
+		os.Exit(1)
+	}
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithDSN(dbPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open event store: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err = admin.New(eventStore).Run(ctx, flag.Args())
+	stop()
+	eventStore.Close()
+
+	if errors.Is(err, admin.ErrUsage) {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
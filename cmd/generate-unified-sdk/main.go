@@ -25,6 +25,7 @@ type UnifiedSDKData struct {
 	PackageName      string
 	Services         []ServiceSDK
 	EventsourcingPkg string
+	CQRSPkg          string
 }
 
 func main() {
@@ -35,6 +36,7 @@ func main() {
 		modulePath      string
 		outputPkg       string
 		eventsourcingPkg string
+		cqrsPkg          string
 	)
 
 	flag.StringVar(&pbDir, "pb-dir", "", "Directory containing protobuf generated files (required)")
@@ -42,6 +44,7 @@ func main() {
 	flag.StringVar(&modulePath, "module", "", "Go module path (auto-detected if not provided)")
 	flag.StringVar(&outputPkg, "package", "sdk", "Package name for generated SDK")
 	flag.StringVar(&eventsourcingPkg, "eventsourcing", "github.com/plaenen/eventstore/pkg/eventsourcing", "Import path for eventsourcing package")
+	flag.StringVar(&cqrsPkg, "cqrs", "github.com/plaenen/eventstore/pkg/cqrs", "Import path for cqrs package")
 	flag.Parse()
 
 	// Validate required flags
@@ -59,6 +62,8 @@ func main() {
 		fmt.Fprintf(os.Stderr, "        Package name for generated SDK (default \"sdk\")\n")
 		fmt.Fprintf(os.Stderr, "  -eventsourcing string\n")
 		fmt.Fprintf(os.Stderr, "        Import path for eventsourcing package (default \"github.com/plaenen/eventstore/pkg/eventsourcing\")\n")
+		fmt.Fprintf(os.Stderr, "  -cqrs string\n")
+		fmt.Fprintf(os.Stderr, "        Import path for cqrs package (default \"github.com/plaenen/eventstore/pkg/cqrs\")\n")
 		fmt.Fprintf(os.Stderr, "\nExample:\n")
 		fmt.Fprintf(os.Stderr, "  %s -pb-dir ./examples/pb -output ./examples/sdk/unified.go\n", os.Args[0])
 		os.Exit(1)
@@ -92,7 +97,7 @@ func main() {
 		fmt.Printf("  - %s.%s\n", svc.PackageName, svc.SDKType)
 	}
 
-	if err := generateUnifiedSDK(services, outputFile, outputPkg, eventsourcingPkg); err != nil {
+	if err := generateUnifiedSDK(services, outputFile, outputPkg, eventsourcingPkg, cqrsPkg); err != nil {
 		fmt.Fprintf(os.Stderr, "Error generating unified SDK: %v\n", err)
 		os.Exit(1)
 	}
@@ -213,7 +218,7 @@ func parseSDKFile(filePath, pbDir, modulePath string) ([]ServiceSDK, error) {
 }
 
 // generateUnifiedSDK generates the unified SDK Go file
-func generateUnifiedSDK(services []ServiceSDK, outputFile string, packageName string, eventsourcingPkg string) error {
+func generateUnifiedSDK(services []ServiceSDK, outputFile string, packageName string, eventsourcingPkg string, cqrsPkg string) error {
 	// Create output directory if it doesn't exist
 	outputDir := filepath.Dir(outputFile)
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
@@ -225,6 +230,7 @@ func generateUnifiedSDK(services []ServiceSDK, outputFile string, packageName st
 		PackageName:      packageName,
 		Services:         services,
 		EventsourcingPkg: eventsourcingPkg,
+		CQRSPkg:          cqrsPkg,
 	}
 
 	// Execute template
@@ -251,8 +257,17 @@ const unifiedSDKTemplate = `// Code generated by generate-unified-sdk. DO NOT ED
 package {{.PackageName}}
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
 {{range .Services}}	{{.PackageName}} "{{.ImportPath}}"
-{{end}}	"{{.EventsourcingPkg}}"
+{{end}}	"{{.CQRSPkg}}"
+	"{{.EventsourcingPkg}}"
+	"google.golang.org/protobuf/proto"
 )
 
 // SDK provides a unified interface to all services in the application.
@@ -272,14 +287,114 @@ type SDK struct {
 	{{.FieldName}} *{{.PackageName}}.{{.SDKType}}
 {{end}}
 	transport eventsourcing.Transport
+	requests  *inflightTransport
+	options   options
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// ErrShuttingDown is returned for requests made after Shutdown was called.
+var ErrShuttingDown = errors.New("sdk is shutting down")
+
+// Option configures the SDK.
+type Option func(*options)
+
+type options struct {
+	commandBus eventsourcing.Transport
+
+	// Components owned by the SDK, closed by Shutdown in this order
+	commandServer   io.Closer
+	projections     []io.Closer
+	eventBus        io.Closer
+	eventStore      io.Closer
+	shutdownHooks   []func(context.Context) error
+	shutdownTimeout time.Duration
+}
+
+// WithCommandBus sends commands through bus instead of the SDK transport;
+// queries keep using the transport. Combined with cqrs.SyncCommandBus, commands
+// run inline and their effects are visible as soon as the call returns, which
+// makes tests deterministic:
+//
+//	bus := cqrs.NewSyncCommandBus()
+//	accountv1.NewAccountCommandServiceServer(bus, handler).Start(ctx)
+//	sdk := NewSDK(transport, WithCommandBus(bus))
+//
+// The SDK does not close the command bus.
+func WithCommandBus(bus eventsourcing.Transport) Option {
+	return func(o *options) {
+		o.commandBus = bus
+	}
+}
+
+// WithCommandServer hands the command handler server (e.g. a *nats.Server) to
+// the SDK; Shutdown shuts it down once in-flight requests have drained.
+func WithCommandServer(server io.Closer) Option {
+	return func(o *options) {
+		o.commandServer = server
+	}
+}
+
+// WithProjections hands projections to the SDK; Shutdown closes them after the
+// command server. Closing a *sqlite.SQLiteProjection flushes its batched
+// checkpoint.
+func WithProjections(projections ...io.Closer) Option {
+	return func(o *options) {
+		o.projections = append(o.projections, projections...)
+	}
+}
+
+// WithEventBus hands the event bus to the SDK; Shutdown closes it after the
+// projections it feeds.
+func WithEventBus(bus io.Closer) Option {
+	return func(o *options) {
+		o.eventBus = bus
+	}
+}
+
+// WithEventStore hands the event store to the SDK; Shutdown closes it after
+// everything that writes to it.
+func WithEventStore(store io.Closer) Option {
+	return func(o *options) {
+		o.eventStore = store
+	}
+}
+
+// OnShutdown registers a hook Shutdown runs last, e.g. stopping an embedded
+// NATS server. Hooks run in registration order.
+func OnShutdown(hook func(context.Context) error) Option {
+	return func(o *options) {
+		o.shutdownHooks = append(o.shutdownHooks, hook)
+	}
+}
+
+// WithShutdownTimeout bounds Shutdown. Default is 30 seconds.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.shutdownTimeout = timeout
+	}
 }
 
 // NewSDK creates a new unified SDK that combines all services.
 // It only requires a single transport - all service SDKs are created automatically.
-func NewSDK(transport eventsourcing.Transport) *SDK {
+func NewSDK(transport eventsourcing.Transport, opts ...Option) *SDK {
+	o := options{shutdownTimeout: 30 * time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	serviceTransport := transport
+	if o.commandBus != nil {
+		serviceTransport = &commandRouter{Transport: transport, commands: o.commandBus}
+	}
+	requests := newInflightTransport(serviceTransport)
+
 	return &SDK{
-{{range .Services}}		{{.FieldName}}: {{.PackageName}}.New{{.SDKType}}(transport),
+{{range .Services}}		{{.FieldName}}: {{.PackageName}}.New{{.SDKType}}(requests),
 {{end}}		transport: transport,
+		requests:  requests,
+		options:   o,
 	}
 }
 
@@ -291,7 +406,166 @@ func (s *SDK) Transport() eventsourcing.Transport {
 
 // Close closes the underlying transport connection.
 // This will close the connection for all service SDKs.
+// Use Shutdown to stop a service that owns more than the transport.
 func (s *SDK) Close() error {
 	return s.transport.Close()
 }
+
+// Shutdown stops the application in dependency order, so no accepted command
+// is lost and every checkpoint is flushed:
+//
+//  1. stop accepting requests (they fail with ErrShuttingDown) and wait for
+//     the ones in flight
+//  2. shut down the command server: a server with a Shutdown(ctx) method,
+//     such as *nats.Server, stops accepting requests and waits for its
+//     handlers in flight before cancelling them; others are closed
+//  3. close the projections
+//  4. close the event bus
+//  5. close the transport
+//  6. close the event store
+//  7. run the OnShutdown hooks
+//
+// Steps that were not configured are skipped. A failing step does not stop the
+// later ones; all errors are returned together. Shutdown gives up when ctx is
+// done or the shutdown timeout passes, whichever comes first. Only the first
+// call does any work; later calls return its result.
+//
+// Shutdown does not listen for signals: callers wire SIGTERM to it, as below.
+//
+// Example (SIGTERM handling in a handler service):
+//
+//	sdk := NewSDK(transport,
+//	    WithCommandServer(server),
+//	    WithProjections(balanceProjection),
+//	    WithEventBus(eventBus),
+//	    WithEventStore(eventStore),
+//	    OnShutdown(func(ctx context.Context) error { natsServer.Shutdown(); return nil }),
+//	)
+//	runner.WaitForShutdownSignal()
+//	if err := sdk.Shutdown(context.Background()); err != nil {
+//	    log.Printf("unclean shutdown: %v", err)
+//	}
+func (s *SDK) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.shutdown(ctx)
+	})
+	return s.shutdownErr
+}
+
+func (s *SDK) shutdown(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.options.shutdownTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		var errs []error
+		closeAll := func(step string, closers ...io.Closer) {
+			for _, c := range closers {
+				if c == nil {
+					continue
+				}
+				if err := c.Close(); err != nil {
+					errs = append(errs, fmt.Errorf("close %s: %w", step, err))
+				}
+			}
+		}
+
+		if err := s.requests.drain(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("drain requests: %w", err))
+		}
+		if server, ok := s.options.commandServer.(interface{ Shutdown(context.Context) error }); ok {
+			if err := server.Shutdown(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("shut down command server: %w", err))
+			}
+		} else {
+			closeAll("command server", s.options.commandServer)
+		}
+		closeAll("projection", s.options.projections...)
+		closeAll("event bus", s.options.eventBus)
+		closeAll("transport", s.transport)
+		closeAll("event store", s.options.eventStore)
+		for _, hook := range s.options.shutdownHooks {
+			if err := hook(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("shutdown hook: %w", err))
+			}
+		}
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("shutdown timeout exceeded: %w", ctx.Err())
+	}
+}
+
+// inflightTransport counts requests in flight so Shutdown can drain them, and
+// rejects new requests once draining has started.
+type inflightTransport struct {
+	eventsourcing.Transport
+
+	mu      sync.Mutex
+	closing bool
+	active  int
+	drained chan struct{} // Closed once closing and no request is active
+}
+
+func newInflightTransport(transport eventsourcing.Transport) *inflightTransport {
+	return &inflightTransport{Transport: transport, drained: make(chan struct{})}
+}
+
+func (t *inflightTransport) Request(ctx context.Context, subject string, request proto.Message) (*eventsourcing.Response, error) {
+	t.mu.Lock()
+	if t.closing {
+		t.mu.Unlock()
+		return nil, ErrShuttingDown
+	}
+	t.active++
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		t.active--
+		if t.closing && t.active == 0 {
+			close(t.drained)
+		}
+		t.mu.Unlock()
+	}()
+
+	return t.Transport.Request(ctx, subject, request)
+}
+
+// drain rejects new requests and waits for the active ones to finish.
+func (t *inflightTransport) drain(ctx context.Context) error {
+	t.mu.Lock()
+	if !t.closing {
+		t.closing = true
+		if t.active == 0 {
+			close(t.drained)
+		}
+	}
+	t.mu.Unlock()
+
+	select {
+	case <-t.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// commandRouter sends command subjects to the command bus and everything else
+// to the wrapped transport.
+type commandRouter struct {
+	eventsourcing.Transport
+	commands eventsourcing.Transport
+}
+
+func (r *commandRouter) Request(ctx context.Context, subject string, request proto.Message) (*eventsourcing.Response, error) {
+	if cqrs.IsCommandSubject(subject) {
+		return r.commands.Request(ctx, subject, request)
+	}
+	return r.Transport.Request(ctx, subject, request)
+}
 `
@@ -28,6 +28,13 @@
 //
 //	Commands: NO OPTIONS - inherit from service
 //
+// Plugin parameters (e.g., "opt: [queries=false]" in buf.gen.yaml):
+//
+//	queries - generate the query side of <Aggregate>QueryService services: the
+//	          <Service>Handler query methods, <Service>Server registering them
+//	          for NATS request/reply, and the typed client and SDK methods
+//	          (default true; false generates the command side only)
+//
 // Developer responsibilities:
 //   - Implement ApplyEvent methods for state updates
 //   - Handle unique constraints in command handlers
@@ -36,6 +43,7 @@ package main
 
 import (
 	"flag"
+	"slices"
 	"strings"
 
 	"google.golang.org/protobuf/compiler/protogen"
@@ -47,40 +55,90 @@ import (
 
 var version = "0.0.8"
 
+// pluginOptions are the plugin parameters.
+type pluginOptions struct {
+	// queries generates the query side of QueryServices
+	queries bool
+}
+
+// newPluginFlags returns the flags that set opts from plugin parameters.
+func newPluginFlags(opts *pluginOptions) *flag.FlagSet {
+	flags := flag.NewFlagSet("protoc-gen-eventsourcing", flag.ContinueOnError)
+	flags.BoolVar(&opts.queries, "queries", true, "generate query handlers, servers and clients")
+	return flags
+}
+
 func main() {
-	var flags flag.FlagSet
+	opts := &pluginOptions{}
+	flags := newPluginFlags(opts)
 
 	protogen.Options{
 		ParamFunc: flags.Set,
 	}.Run(func(gen *protogen.Plugin) error {
-		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		return generate(gen, opts)
+	})
+}
 
-		for _, f := range gen.Files {
-			if !f.Generate {
-				continue
-			}
+// hasAggregateEvents reports whether any of aggregates has events.
+func hasAggregateEvents(gen *protogen.Plugin, aggregates []*AggregateInfo) bool {
+	for _, agg := range aggregates {
+		if len(findEventsForAggregate(gen, agg.TypeName)) > 0 {
+			return true
+		}
+	}
+	return false
+}
 
-			generateFile(gen, f)
+// packageFiles returns the files to generate of file's Go package.
+func packageFiles(gen *protogen.Plugin, file *protogen.File) []*protogen.File {
+	var files []*protogen.File
+	for _, f := range gen.Files {
+		if f.Generate && f.GoImportPath == file.GoImportPath {
+			files = append(files, f)
 		}
+	}
+	return files
+}
 
-		return nil
-	})
+// firstEventFile returns the first file of file's Go package with aggregate
+// events. Declarations shared by the package, such as ApplyEventOption and the
+// EventRegistry, are generated into it only.
+func firstEventFile(gen *protogen.Plugin, file *protogen.File) *protogen.File {
+	for _, f := range packageFiles(gen, file) {
+		if hasAggregateEvents(gen, findAggregates(f)) {
+			return f
+		}
+	}
+	return nil
 }
 
-func generateFile(gen *protogen.Plugin, file *protogen.File) {
-	// Check if there are any aggregates or events to generate
-	aggregates := findAggregates(file)
-	hasEvents := false
-	for _, agg := range aggregates {
-		events := findEventsForAggregate(gen, agg.TypeName)
-		if len(events) > 0 {
-			hasEvents = true
-			break
+// generate generates the files of every proto file to generate.
+func generate(gen *protogen.Plugin, opts *pluginOptions) error {
+	gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+
+	for _, f := range gen.Files {
+		if !f.Generate {
+			continue
 		}
+
+		generateFile(gen, f, opts)
 	}
 
+	return nil
+}
+
+func generateFile(gen *protogen.Plugin, file *protogen.File, opts *pluginOptions) {
+	// Check if there are any aggregates or events to generate
+	aggregates := findAggregates(file)
+	hasEvents := hasAggregateEvents(gen, aggregates)
+
 	// Find services for later use
 	services := findServices(file)
+	if !opts.queries {
+		services = slices.DeleteFunc(services, func(svc *ServiceInfo) bool {
+			return strings.HasSuffix(svc.Name, "QueryService")
+		})
+	}
 
 	// Skip files that don't have anything to generate
 	if len(aggregates) == 0 && len(services) == 0 && !hasEvents {
@@ -98,6 +156,7 @@ func generateFile(gen *protogen.Plugin, file *protogen.File) {
 		generateEventAppliers(g, file, gen)
 		generateRepository(g, file, gen)
 		generateProjectionSDK(g, file, gen)
+		generateEventRegistry(g, file, gen)
 	}
 
 	// Generate service-related files if there are commands or queries
@@ -208,6 +267,7 @@ func generateCommandHandlers(g *protogen.GeneratedFile, file *protogen.File) {
 
 func generateEventAppliers(g *protogen.GeneratedFile, file *protogen.File, gen *protogen.Plugin) {
 	aggregates := findAggregates(file)
+	generateOptions := firstEventFile(gen, file) == file
 
 	for _, agg := range aggregates {
 		aggregateType := agg.TypeName + "Aggregate"
@@ -261,6 +321,24 @@ func generateEventAppliers(g *protogen.GeneratedFile, file *protogen.File, gen *
 		g.P("}")
 		g.P()
 
+		// Generate the Compensate hook (delegates to the applier when supported)
+		g.P("// ", agg.TypeName, "Compensator is implemented by appliers that can logically undo")
+		g.P("// a command on ", agg.TypeName, ". See domain.Compensator.")
+		g.P("type ", agg.TypeName, "Compensator interface {")
+		g.P("	Compensate(ctx context.Context, agg *", aggregateType, ", originalCommandID string, meta domain.EventMetadata) error")
+		g.P("}")
+		g.P()
+		g.P("// Compensate logically undoes the command originalCommandID by delegating to")
+		g.P("// the injected applier when it implements ", agg.TypeName, "Compensator.")
+		g.P("func (a *", aggregateType, ") Compensate(ctx context.Context, originalCommandID string, meta domain.EventMetadata) error {")
+		g.P("	compensator, ok := a.applier.(", agg.TypeName, "Compensator)")
+		g.P("	if !ok {")
+		g.P("		return fmt.Errorf(\"%w: ", agg.TypeName, "\", domain.ErrCompensationNotSupported)")
+		g.P("	}")
+		g.P("	return compensator.Compensate(ctx, a, originalCommandID, meta)")
+		g.P("}")
+		g.P()
+
 		g.P("// ============================================================================")
 		g.P("// Implementing Event Appliers (Recommended Pattern)")
 		g.P("// ============================================================================")
@@ -320,38 +398,41 @@ func generateEventAppliers(g *protogen.GeneratedFile, file *protogen.File, gen *
 		g.P("// metadata and unique constraints. They eliminate error-prone string event types.")
 		g.P()
 
-		// Generate option types
-		g.P("// ApplyEventOption configures event application with metadata and constraints")
-		g.P("type ApplyEventOption func(*ApplyEventOptions)")
-		g.P()
+		// Generate option types, once per package
+		if generateOptions {
+			generateOptions = false
+			g.P("// ApplyEventOption configures event application with metadata and constraints")
+			g.P("type ApplyEventOption func(*ApplyEventOptions)")
+			g.P()
 
-		g.P("// ApplyEventOptions holds configuration for event application")
-		g.P("type ApplyEventOptions struct {")
-		g.P("	Metadata    domain.EventMetadata")
-		g.P("	Constraints []domain.UniqueConstraint")
-		g.P("}")
-		g.P()
+			g.P("// ApplyEventOptions holds configuration for event application")
+			g.P("type ApplyEventOptions struct {")
+			g.P("	Metadata    domain.EventMetadata")
+			g.P("	Constraints []domain.UniqueConstraint")
+			g.P("}")
+			g.P()
 
-		g.P("// WithMetadata sets the event metadata")
-		g.P("func WithMetadata(metadata domain.EventMetadata) ApplyEventOption {")
-		g.P("	return func(o *ApplyEventOptions) {")
-		g.P("		o.Metadata = metadata")
-		g.P("	}")
-		g.P("}")
-		g.P()
+			g.P("// WithMetadata sets the event metadata")
+			g.P("func WithMetadata(metadata domain.EventMetadata) ApplyEventOption {")
+			g.P("	return func(o *ApplyEventOptions) {")
+			g.P("		o.Metadata = metadata")
+			g.P("	}")
+			g.P("}")
+			g.P()
 
-		g.P("// WithUniqueConstraints adds unique constraints to the event")
-		g.P("func WithUniqueConstraints(constraints ...domain.UniqueConstraint) ApplyEventOption {")
-		g.P("	return func(o *ApplyEventOptions) {")
-		g.P("		o.Constraints = constraints")
-		g.P("	}")
-		g.P("}")
-		g.P()
+			g.P("// WithUniqueConstraints adds unique constraints to the event")
+			g.P("func WithUniqueConstraints(constraints ...domain.UniqueConstraint) ApplyEventOption {")
+			g.P("	return func(o *ApplyEventOptions) {")
+			g.P("		o.Constraints = constraints")
+			g.P("	}")
+			g.P("}")
+			g.P()
+		}
 
 		// Generate type-safe Apply methods for each event
 		for _, evt := range events {
 			methodName := "Apply" + evt.MessageName
-			fullEventType := string(file.GoPackageName) + "." + evt.MessageName
+			constName := evt.MessageName + "Type"
 
 			g.P("// ", methodName, " applies the ", evt.MessageName, " with type safety and optional configuration")
 			g.P("// This eliminates the need to manually specify event type strings")
@@ -364,7 +445,7 @@ func generateEventAppliers(g *protogen.GeneratedFile, file *protogen.File, gen *
 			g.P("	if len(options.Constraints) > 0 {")
 			g.P("		return a.AggregateRoot.ApplyChangeWithConstraints(")
 			g.P("			event,")
-			g.P(`			"`, fullEventType, `",`)
+			g.P("			", constName, ",")
 			g.P("			options.Metadata,")
 			g.P("			options.Constraints,")
 			g.P("		)")
@@ -372,7 +453,7 @@ func generateEventAppliers(g *protogen.GeneratedFile, file *protogen.File, gen *
 			g.P()
 			g.P("	return a.AggregateRoot.ApplyChange(")
 			g.P("		event,")
-			g.P(`		"`, fullEventType, `",`)
+			g.P("		", constName, ",")
 			g.P("		options.Metadata,")
 			g.P("	)")
 			g.P("}")
@@ -399,7 +480,8 @@ func generateRepository(g *protogen.GeneratedFile, file *protogen.File, gen *pro
 
 		g.P("// New", repoName, " creates a new repository")
 		g.P("// factory: function to create new aggregate instances (should inject appliers)")
-		g.P("func New", repoName, "(eventStore store.EventStore, factory func(string) *", aggregateType, ") *", repoName, " {")
+		g.P("// opts: optional repository configuration (e.g., store.WithAggregateCache)")
+		g.P("func New", repoName, "(eventStore store.EventStore, factory func(string) *", aggregateType, ", opts ...store.RepositoryOption) *", repoName, " {")
 		g.P("	return &", repoName, "{")
 		g.P("		BaseRepository: store.NewRepository[*", aggregateType, "](")
 		g.P("			eventStore,")
@@ -413,6 +495,7 @@ func generateRepository(g *protogen.GeneratedFile, file *protogen.File, gen *pro
 		g.P("				}")
 		g.P("				return agg.ApplyEvent(msg)")
 		g.P("			},")
+		g.P("			opts...,")
 		g.P("		),")
 		g.P("	}")
 		g.P("}")
@@ -422,11 +505,10 @@ func generateRepository(g *protogen.GeneratedFile, file *protogen.File, gen *pro
 		events := findEventsForAggregate(gen, agg.TypeName)
 
 		g.P("func deserializeEvent", agg.TypeName, "(event *domain.Event) (proto.Message, error) {")
-		g.P("	switch event.EventType {")
+		g.P("	switch domain.CanonicalEventType(event.EventType) {")
 
 		for _, evt := range events {
-			fullName := string(file.GoPackageName) + "." + evt.MessageName
-			g.P("	case \"", fullName, "\":")
+			g.P("	case ", evt.MessageName, "Type:")
 			g.P("		msg := &", evt.MessageName, "{}")
 			g.P("		if err := proto.Unmarshal(event.Data, msg); err != nil {")
 			g.P("			return nil, err")
@@ -461,6 +543,13 @@ type CommandInfo struct {
 type EventInfo struct {
 	MessageName   string
 	AggregateName string
+
+	// EventType is the fully qualified proto name, stored as Event.EventType
+	EventType string
+
+	// LegacyEventType is the <go package>.<Message> name written by generator
+	// versions before fully qualified names were enforced
+	LegacyEventType string
 }
 
 // Helper functions for reading new proto options
@@ -606,8 +695,10 @@ func findEventsForAggregate(gen *protogen.Plugin, aggregateName string) []*Event
 			}
 
 			events = append(events, &EventInfo{
-				MessageName:   string(msg.Desc.Name()),
-				AggregateName: aggregateName,
+				MessageName:     string(msg.Desc.Name()),
+				AggregateName:   aggregateName,
+				EventType:       string(msg.Desc.FullName()),
+				LegacyEventType: string(file.GoPackageName) + "." + string(msg.Desc.Name()),
 			})
 		}
 	}
@@ -825,9 +916,11 @@ func generateSDKClient(gen *protogen.Plugin, file *protogen.File, aggregates []*
 				outputType := g.QualifiedGoIdent(method.Output.GoIdent)
 				subject := string(file.Desc.Package()) + "." + svc.Name + "." + methodName
 
-				g.P("// ", methodName, " sends a ", methodName, " command and returns the response")
-				g.P("func (c *", clientName, ") ", methodName, "(ctx context.Context, cmd *", inputType, ") (*", outputType, ", *eventsourcing.AppError) {")
+				g.P("// ", methodName, " sends a ", methodName, " command and returns the response. Pass")
+				g.P("// eventsourcing.WithCommandResultInto to also receive the handler's result.")
+				g.P("func (c *", clientName, ") ", methodName, "(ctx context.Context, cmd *", inputType, ", opts ...eventsourcing.RequestOption) (*", outputType, ", *eventsourcing.AppError) {")
 				g.P("	// Send request via transport")
+				g.P("	ctx = eventsourcing.WithRequestOptions(ctx, opts...)")
 				g.P(`	resp, err := c.transport.Request(ctx, "`, subject, `", cmd)`)
 				g.P("	if err != nil {")
 				g.P("		return nil, &eventsourcing.AppError{")
@@ -1167,8 +1260,8 @@ func generateUnifiedSDK(gen *protogen.Plugin, file *protogen.File, aggregates []
 				description := extractDescription(methodName)
 
 				g.P("// ", methodName, " ", description)
-				g.P("func (s *", sdkName, ") ", methodName, "(ctx context.Context, cmd *", inputType, ") (*", outputType, ", *eventsourcing.AppError) {")
-				g.P("	return s.client.", methodName, "(ctx, cmd)")
+				g.P("func (s *", sdkName, ") ", methodName, "(ctx context.Context, cmd *", inputType, ", opts ...eventsourcing.RequestOption) (*", outputType, ", *eventsourcing.AppError) {")
+				g.P("	return s.client.", methodName, "(ctx, cmd, opts...)")
 				g.P("}")
 				g.P()
 			}
@@ -1253,12 +1346,29 @@ func generateProjectionSDK(g *protogen.GeneratedFile, file *protogen.File, gen *
 		g.P("const (")
 		for _, evt := range events {
 			constName := evt.MessageName + "Type"
-			fullEventType := string(file.GoPackageName) + "." + evt.MessageName
-			g.P("	", constName, " = \"", fullEventType, "\"")
+			g.P("	", constName, " = \"", evt.EventType, "\"")
 		}
 		g.P(")")
 		g.P()
 
+		// Legacy event type names, so events stored by earlier versions still load
+		var aliases []string
+		for _, evt := range events {
+			if evt.LegacyEventType != evt.EventType {
+				aliases = append(aliases, "	domain.RegisterEventTypeAlias(\""+evt.LegacyEventType+"\", "+evt.MessageName+"Type)")
+			}
+		}
+		if len(aliases) > 0 {
+			g.P("// Event types written by earlier generator versions (<go package>.<Message>)")
+			g.P("// are aliases of the fully qualified names above.")
+			g.P("func init() {")
+			for _, line := range aliases {
+				g.P(line)
+			}
+			g.P("}")
+			g.P()
+		}
+
 		// Typed event handler function types
 		g.P("// Typed event handlers for ", agg.TypeName)
 		for _, evt := range events {
@@ -1342,6 +1452,14 @@ func generateProjectionSDK(g *protogen.GeneratedFile, file *protogen.File, gen *
 			g.P("	}")
 			g.P("}")
 			g.P()
+
+			mapName := "Map" + strings.TrimSuffix(evt.MessageName, "Event")
+			g.P("// ", mapName, " starts a declarative row mapping for ", evt.MessageName)
+			g.P("// Use with sqlite.NewProjectionBuilder().Map(", mapName, "().ToTable(...)...)")
+			g.P("func ", mapName, "() *store.EventMapping[*", evt.MessageName, "] {")
+			g.P("	return store.MapEvent[*", evt.MessageName, "](", constName, ")")
+			g.P("}")
+			g.P()
 		}
 		g.P()
 
@@ -1374,7 +1492,7 @@ func generateProjectionSDK(g *protogen.GeneratedFile, file *protogen.File, gen *
 
 		g.P("// Handle dispatches events to registered typed handlers")
 		g.P("func (p *", projectionType, ") Handle(ctx context.Context, envelope *domain.EventEnvelope) error {")
-		g.P("	handler, exists := p.handlers[envelope.EventType]")
+		g.P("	handler, exists := p.handlers[domain.CanonicalEventType(envelope.EventType)]")
 		g.P("	if !exists {")
 		g.P("		// No handler registered for this event type - skip it")
 		g.P("		return nil")
@@ -1393,3 +1511,50 @@ func generateProjectionSDK(g *protogen.GeneratedFile, file *protogen.File, gen *
 		g.P()
 	}
 }
+
+// generateEventRegistry emits the EventRegistry of file's Go package. Several
+// proto files can share a Go package, so the registry lists the events of the
+// aggregates of all of them and is only emitted into the first file with
+// events (see firstEventFile).
+func generateEventRegistry(g *protogen.GeneratedFile, file *protogen.File, gen *protogen.Plugin) {
+	if firstEventFile(gen, file) != file {
+		return
+	}
+	var aggregates []*AggregateInfo
+	for _, f := range packageFiles(gen, file) {
+		aggregates = append(aggregates, findAggregates(f)...)
+	}
+
+	var entries []string
+	for _, agg := range aggregates {
+		aggregateType := agg.TypeName + "Aggregate"
+		for _, evt := range findEventsForAggregate(gen, agg.TypeName) {
+			entries = append(entries,
+				"	"+evt.MessageName+"Type: {",
+				"		AggregateType: \""+agg.TypeName+"\",",
+				"		New:           func() proto.Message { return &"+evt.MessageName+"{} },",
+				"		Apply: func(agg domain.Aggregate, event proto.Message) error {",
+				"			a, ok := agg.(*"+aggregateType+")",
+				"			if !ok {",
+				"				return fmt.Errorf(\"expected *"+aggregateType+", got %T\", agg)",
+				"			}",
+				"			return a.ApplyEvent(event)",
+				"		},",
+				"	},",
+			)
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	g.P("// EventRegistry maps every event type in this package to its descriptor.")
+	g.P("// Generic tooling can use it to decode and apply events without knowing")
+	g.P("// the concrete types at compile time.")
+	g.P("var EventRegistry = domain.EventRegistry{")
+	for _, line := range entries {
+		g.P(line)
+	}
+	g.P("}")
+	g.P()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// codeGeneratorRequest returns the request protoc sends for generating files
+// with the plugin parameter param.
+func codeGeneratorRequest(param string, files ...protoreflect.FileDescriptor) *pluginpb.CodeGeneratorRequest {
+	// protoc lists every file after its dependencies
+	var protoFiles []*descriptorpb.FileDescriptorProto
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		protoFiles = append(protoFiles, protodesc.ToFileDescriptorProto(fd))
+	}
+	var toGenerate []string
+	for _, file := range files {
+		add(file)
+		toGenerate = append(toGenerate, file.Path())
+	}
+
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: toGenerate,
+		Parameter:      proto.String(param),
+		ProtoFile:      protoFiles,
+	}
+}
+
+// aggregateFile returns a proto file of the Go package multiv1 declaring the
+// aggregate root name with an ID field and one event.
+func aggregateFile(t *testing.T, name string) protoreflect.FileDescriptor {
+	t.Helper()
+	rootOptions := &descriptorpb.MessageOptions{}
+	proto.SetExtension(rootOptions, eventsourcing.E_AggregateRoot, &eventsourcing.AggregateRootOptions{IdField: "id"})
+	eventOptions := &descriptorpb.MessageOptions{}
+	proto.SetExtension(eventOptions, eventsourcing.E_Event, &eventsourcing.EventOptions{AggregateName: name})
+
+	idField := []*descriptorpb.FieldDescriptorProto{{
+		Name:     proto.String("id"),
+		JsonName: proto.String("id"),
+		Number:   proto.Int32(1),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+	}}
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("multi/v1/" + strings.ToLower(name) + ".proto"),
+		Package:    proto.String("multi.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"eventsourcing/options.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/multi/v1;multiv1")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String(name), Field: idField, Options: rootOptions},
+			{Name: proto.String(name + "CreatedEvent"), Field: idField, Options: eventOptions},
+		},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build %s.proto: %v", name, err)
+	}
+	return file
+}
+
+func TestGenerateGolden(t *testing.T) {
+	account := []protoreflect.FileDescriptor{accountv1.File_account_v1_account_proto}
+	tests := []struct {
+		name  string
+		param string
+		files []protoreflect.FileDescriptor
+	}{
+		{name: "account", param: "paths=source_relative", files: account},
+		{name: "account_commands_only", param: "paths=source_relative,queries=false", files: account},
+		// One EventRegistry for both aggregates of the package
+		{name: "two_aggregates_one_package", param: "paths=source_relative", files: []protoreflect.FileDescriptor{
+			aggregateFile(t, "Order"),
+			aggregateFile(t, "Invoice"),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := &pluginOptions{}
+			gen, err := protogen.Options{
+				ParamFunc: newPluginFlags(opts).Set,
+			}.New(codeGeneratorRequest(tt.param, tt.files...))
+			if err != nil {
+				t.Fatalf("failed to create plugin: %v", err)
+			}
+			if err := generate(gen, opts); err != nil {
+				t.Fatalf("failed to generate: %v", err)
+			}
+			resp := gen.Response()
+			if resp.Error != nil {
+				t.Fatalf("generation failed: %s", resp.GetError())
+			}
+
+			dir := filepath.Join("testdata", tt.name)
+			if *update {
+				if err := os.RemoveAll(dir); err != nil {
+					t.Fatalf("failed to clear %s: %v", dir, err)
+				}
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatalf("failed to create %s: %v", dir, err)
+				}
+			}
+
+			generated := make(map[string]bool)
+			for _, file := range resp.File {
+				name := filepath.Base(file.GetName()) + ".golden"
+				generated[name] = true
+				path := filepath.Join(dir, name)
+				if *update {
+					if err := os.WriteFile(path, []byte(file.GetContent()), 0o644); err != nil {
+						t.Fatalf("failed to write %s: %v", path, err)
+					}
+					continue
+				}
+				want, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("missing golden file (run go test -update): %v", err)
+				}
+				if file.GetContent() != string(want) {
+					t.Errorf("%s differs from %s (run go test -update and review the diff)", file.GetName(), path)
+				}
+			}
+
+			// Every golden file is still generated
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", dir, err)
+			}
+			for _, entry := range entries {
+				if !generated[entry.Name()] {
+					t.Errorf("%s is no longer generated", strings.TrimSuffix(entry.Name(), ".golden"))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/shopspring/decimal"
+)
+
+// Compensate reverses the money movements of an erroneous command: a deposit
+// is undone by withdrawing the same amount and a withdrawal by depositing it
+// back. The reversal is a new event linked to the original command, so the
+// account history keeps both. Implements accountv1.AccountCompensator.
+//
+// Use store.CompensateCommand to call it:
+//
+//	err := store.CompensateCommand(ctx, eventStore, repo, "acc-123", depositCommandID,
+//	    domain.EventMetadata{PrincipalID: "back-office"})
+func (ap *AccountAppliers) Compensate(ctx context.Context, agg *accountv1.AccountAggregate, originalCommandID string, meta domain.EventMetadata) error {
+	if agg.Status != accountv1.AccountStatus_ACCOUNT_STATUS_OPEN {
+		return fmt.Errorf("cannot compensate %s: account %s is not open", originalCommandID, agg.ID())
+	}
+
+	compensation := accountv1.WithMetadata(domain.CompensationMetadata(originalCommandID, meta))
+	for _, event := range domain.CompensatedEvents(ctx) {
+		msg, err := accountv1.EventRegistry.Decode(event)
+		if err != nil {
+			return fmt.Errorf("failed to decode event %s: %w", event.ID, err)
+		}
+
+		balance, _ := decimal.NewFromString(agg.Balance)
+		switch e := msg.(type) {
+		case *accountv1.MoneyDepositedEvent:
+			amount, _ := decimal.NewFromString(e.Amount)
+			newBalance := balance.Sub(amount)
+			if newBalance.IsNegative() {
+				return fmt.Errorf("cannot reverse deposit of %s: balance is %s", amount, balance)
+			}
+			err = agg.ApplyMoneyWithdrawnEvent(&accountv1.MoneyWithdrawnEvent{
+				AccountId:  agg.AccountId,
+				Amount:     e.Amount,
+				NewBalance: newBalance.String(),
+				Timestamp:  time.Now().Unix(),
+			}, compensation)
+		case *accountv1.MoneyWithdrawnEvent:
+			amount, _ := decimal.NewFromString(e.Amount)
+			err = agg.ApplyMoneyDepositedEvent(&accountv1.MoneyDepositedEvent{
+				AccountId:  agg.AccountId,
+				Amount:     e.Amount,
+				NewBalance: balance.Add(amount).String(),
+				Timestamp:  time.Now().Unix(),
+			}, compensation)
+		default:
+			return fmt.Errorf("cannot compensate %s: %s is not reversible", originalCommandID, event.EventType)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
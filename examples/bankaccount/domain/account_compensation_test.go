@@ -0,0 +1,109 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	accountdomain "github.com/plaenen/eventstore/examples/bankaccount/domain"
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/memory"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+// backends are the event stores the integration tests run against.
+var backends = []struct {
+	name     string
+	newStore func(t *testing.T) store.EventStore
+}{
+	{"SQLite", func(t *testing.T) store.EventStore {
+		eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		return eventStore
+	}},
+	{"Memory", func(t *testing.T) store.EventStore {
+		return memory.NewEventStore()
+	}},
+}
+
+func TestReverseDeposit(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			eventStore := backend.newStore(t)
+			defer eventStore.Close()
+			testReverseDeposit(t, eventStore)
+		})
+	}
+}
+
+func testReverseDeposit(t *testing.T, eventStore store.EventStore) {
+	ctx := context.Background()
+	repo := accountv1.NewAccountRepository(eventStore, accountdomain.NewAccount)
+
+	agg := accountdomain.NewAccount("acc-1")
+	if err := agg.ApplyAccountOpenedEvent(&accountv1.AccountOpenedEvent{
+		AccountId:      "acc-1",
+		OwnerName:      "Alice",
+		InitialBalance: "100.00",
+	}, accountv1.WithMetadata(domain.EventMetadata{CausationID: "cmd-open"})); err != nil {
+		t.Fatalf("failed to open account: %v", err)
+	}
+	if err := agg.ApplyMoneyDepositedEvent(&accountv1.MoneyDepositedEvent{
+		AccountId:  "acc-1",
+		Amount:     "1000.00",
+		NewBalance: "1100.00",
+	}, accountv1.WithMetadata(domain.EventMetadata{CausationID: "cmd-deposit"})); err != nil {
+		t.Fatalf("failed to deposit: %v", err)
+	}
+	if err := repo.Save(agg); err != nil {
+		t.Fatalf("failed to save account: %v", err)
+	}
+
+	err := store.CompensateCommand(ctx, eventStore, repo, "acc-1", "cmd-deposit",
+		domain.EventMetadata{PrincipalID: "back-office"})
+	if err != nil {
+		t.Fatalf("compensation failed: %v", err)
+	}
+
+	loaded, err := repo.Load("acc-1")
+	if err != nil {
+		t.Fatalf("failed to load account: %v", err)
+	}
+	if loaded.Balance != "100" || loaded.Version() != 3 {
+		t.Errorf("expected deposit to be reversed, got balance %s at version %d", loaded.Balance, loaded.Version())
+	}
+
+	compensations, err := eventStore.LoadCompensations("cmd-deposit")
+	if err != nil {
+		t.Fatalf("failed to load compensations: %v", err)
+	}
+	if len(compensations) != 1 {
+		t.Fatalf("expected 1 compensation, got %d", len(compensations))
+	}
+	compensation := compensations[0]
+	if compensation.EventType != accountv1.MoneyWithdrawnEventType ||
+		compensation.AggregateID != "acc-1" ||
+		compensation.Metadata.CausationID != "cmd-deposit" ||
+		compensation.Metadata.PrincipalID != "back-office" ||
+		!domain.IsCompensation(compensation.Metadata) {
+		t.Errorf("compensation not linked to the deposit: %+v", compensation)
+	}
+
+	if others, err := eventStore.LoadCompensations("cmd-open"); err != nil || len(others) != 0 {
+		t.Errorf("expected no compensations for cmd-open, got %d (%v)", len(others), err)
+	}
+
+	err = store.CompensateCommand(ctx, eventStore, repo, "acc-1", "cmd-deposit", domain.EventMetadata{})
+	if !errors.Is(err, domain.ErrAlreadyCompensated) {
+		t.Errorf("expected ErrAlreadyCompensated, got %v", err)
+	}
+
+	err = store.CompensateCommand(ctx, eventStore, repo, "acc-1", "cmd-unknown", domain.EventMetadata{})
+	if !errors.Is(err, domain.ErrNothingToCompensate) {
+		t.Errorf("expected ErrNothingToCompensate, got %v", err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
 	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/validators"
 	"github.com/shopspring/decimal"
 )
 
@@ -26,31 +27,21 @@ func NewAccountCommandHandler(repo *accountv1.AccountRepository) *AccountCommand
 
 // OpenAccount handles the OpenAccount command
 func (h *AccountCommandHandler) OpenAccount(ctx context.Context, cmd *accountv1.OpenAccountCommand) (*accountv1.OpenAccountResponse, *eventsourcing.AppError) {
-	// Validate command
-	if cmd.AccountId == "" {
-		return nil, &eventsourcing.AppError{
-			Code:     "INVALID_ACCOUNT_ID",
-			Message:  "Account ID is required",
-			Solution: "Provide a valid account ID",
-		}
-	}
-
-	if cmd.OwnerName == "" {
-		return nil, &eventsourcing.AppError{
-			Code:     "INVALID_OWNER_NAME",
-			Message:  "Owner name is required",
-			Solution: "Provide a valid owner name",
-		}
-	}
+	// Validate command, reporting every invalid field at once
+	validation := validators.NewValidationBuilder().
+		Add(validators.ValidateStringEmpty(cmd.AccountId, "account_id")).
+		Add(validators.ValidateStringEmpty(cmd.OwnerName, "owner_name"))
 
-	// Parse initial balance
 	balance, err := decimal.NewFromString(cmd.InitialBalance)
 	if err != nil || balance.IsNegative() {
-		return nil, &eventsourcing.AppError{
-			Code:     "INVALID_BALANCE",
-			Message:  "Initial balance must be a non-negative number",
-			Solution: "Provide a valid balance (e.g., '100.00')",
-		}
+		validation.Add(validators.NewValidationResult(false, "initial_balance",
+			validators.WithMessage("Initial balance must be a non-negative number (e.g., '100.00')."),
+			validators.WithValidationCode(validators.ValidationCodeInvalid),
+		))
+	}
+
+	if appErr := validation.BuildErrors().ToAppError(); appErr != nil {
+		return nil, appErr
 	}
 
 	// Create new aggregate (appliers are injected by domain factory)
@@ -66,6 +57,7 @@ func (h *AccountCommandHandler) OpenAccount(ctx context.Context, cmd *accountv1.
 
 	// Use generated type-safe Apply method with unique constraint
 	if err := agg.ApplyAccountOpenedEvent(event,
+		eventMetadata(ctx),
 		accountv1.WithUniqueConstraints(domain.UniqueConstraint{
 			IndexName: "account_id",
 			Value:     cmd.AccountId,
@@ -78,8 +70,14 @@ func (h *AccountCommandHandler) OpenAccount(ctx context.Context, cmd *accountv1.
 		}
 	}
 
-	// Save aggregate
-	if err := h.repo.Save(agg); err != nil {
+	// Save aggregate. A retry carrying the caller's idempotency key is
+	// recorded once instead of failing on the account_id constraint.
+	if key, ok := domain.IdempotencyKeyFromContext(ctx); ok {
+		_, err = h.repo.SaveWithIdempotencyKey(agg, key)
+	} else {
+		err = h.repo.Save(agg)
+	}
+	if err != nil {
 		return nil, &eventsourcing.AppError{
 			Code:    "SAVE_FAILED",
 			Message: fmt.Sprintf("Failed to save account: %v", err),
@@ -134,7 +132,7 @@ func (h *AccountCommandHandler) Deposit(ctx context.Context, cmd *accountv1.Depo
 		}
 
 		// Use generated type-safe Apply method (no constraints needed)
-		if err := agg.ApplyMoneyDepositedEvent(event); err != nil {
+		if err := agg.ApplyMoneyDepositedEvent(event, eventMetadata(ctx)); err != nil {
 			return fmt.Errorf("EVENT_EMIT_FAILED: Failed to emit event: %v", err)
 		}
 
@@ -210,7 +208,7 @@ func (h *AccountCommandHandler) Withdraw(ctx context.Context, cmd *accountv1.Wit
 		}
 
 		// Use generated type-safe Apply method (no constraints needed)
-		if err := agg.ApplyMoneyWithdrawnEvent(event); err != nil {
+		if err := agg.ApplyMoneyWithdrawnEvent(event, eventMetadata(ctx)); err != nil {
 			return fmt.Errorf("EVENT_EMIT_FAILED: Failed to emit event: %v", err)
 		}
 
@@ -277,6 +275,7 @@ func (h *AccountCommandHandler) CloseAccount(ctx context.Context, cmd *accountv1
 
 	// Use generated type-safe Apply method with constraint release
 	if err := agg.ApplyAccountClosedEvent(event,
+		eventMetadata(ctx),
 		accountv1.WithUniqueConstraints(domain.UniqueConstraint{
 			IndexName: "account_id",
 			Value:     cmd.AccountId,
@@ -302,3 +301,10 @@ func (h *AccountCommandHandler) CloseAccount(ctx context.Context, cmd *accountv1
 		Version:      agg.Version(),
 	}, nil
 }
+
+// eventMetadata carries the correlation ID of the command, e.g. set by a saga
+// dispatching it, over to the events it emits.
+func eventMetadata(ctx context.Context) accountv1.ApplyEventOption {
+	correlationID, _ := domain.CorrelationIDFromContext(ctx)
+	return accountv1.WithMetadata(domain.EventMetadata{CorrelationID: correlationID})
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"log"
 	"time"
@@ -23,9 +22,8 @@ func main() {
 	fmt.Println("=== Bank Account SQLite Observability Demo ===")
 	fmt.Println("Single-binary application with file-based storage")
 	fmt.Println()
-	fmt.Println("This demo creates two SQLite database files:")
-	fmt.Println("  • eventstore.db - Event sourcing data (aggregates, events)")
-	fmt.Println("  • observability.db - Traces and metrics")
+	fmt.Println("This demo stores everything in one SQLite database file:")
+	fmt.Println("  • app.db - Event sourcing data (aggregates, events), traces and metrics")
 	fmt.Println()
 
 	ctx := context.Background()
@@ -53,23 +51,28 @@ func main() {
 	// 2. Setup SQLite for Observability
 	fmt.Println("2️⃣  Setting up SQLite for observability...")
 
-	// Create separate database file for observability data
-	observabilityDBPath := "./observability.db"
-	// Configure SQLite for concurrent access with WAL mode
-	observabilityDB, err := sql.Open("sqlite", observabilityDBPath+"?_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL")
+	// One pool shared by the exporters and the event store. The event store
+	// opens it in WAL mode; busy_timeout lets writers on different connections
+	// wait for each other instead of failing
+	dbPath := "./app.db"
+	eventStore, err := sqlite.NewEventStore(
+		sqlite.WithDSN(dbPath),
+		sqlite.WithMaxOpenConns(10),
+		sqlite.WithMaxIdleConns(5),
+		sqlite.WithBusyTimeout(5*time.Second),
+		sqlite.WithSynchronous("NORMAL"),
+		sqlite.WithWALCheckpointInterval(5*time.Minute), // Keep the WAL bounded while the demo runs
+	)
 	if err != nil {
-		log.Fatalf("Failed to open observability database: %v", err)
+		log.Fatalf("Failed to create event store: %v", err)
 	}
-	defer observabilityDB.Close()
-
-	// Set connection pool settings for better concurrency
-	observabilityDB.SetMaxOpenConns(1) // SQLite works best with single writer
-	observabilityDB.SetMaxIdleConns(1)
+	defer eventStore.Close()
+	db := eventStore.DB()
 
-	fmt.Printf("   📁 Observability database: %s\n", observabilityDBPath)
+	fmt.Printf("   📁 Database: %s\n", dbPath)
 
 	// Create SQLite exporters
-	exporterConfig := observability.DefaultSQLiteExporterConfig(observabilityDB)
+	exporterConfig := observability.DefaultSQLiteExporterConfig(db)
 	exporterConfig.RetentionDays = 7 // Keep 1 week
 
 	traceExporter, err := observability.NewSQLiteTraceExporter(exporterConfig)
@@ -112,19 +115,7 @@ func main() {
 	// 3. Setup SQLite Event Store
 	fmt.Println("3️⃣  Setting up SQLite event store...")
 
-	// Create separate database file for event store with WAL mode and pragmas for concurrency
-	eventStoreDBPath := "./eventstore.db?_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL"
-	eventStore, err := sqlite.NewEventStore(
-		sqlite.WithFilename(eventStoreDBPath),
-		sqlite.WithWALMode(true),
-	)
-	if err != nil {
-		log.Fatalf("Failed to create event store: %v", err)
-	}
-	defer eventStore.Close()
-
-	fmt.Printf("   📁 Event store database: ./eventstore.db\n")
-	fmt.Println("   ✅ Event store ready (WAL mode enabled)")
+	fmt.Println("   ✅ Event store ready (WAL mode enabled, shared pool)")
 	fmt.Println()
 
 	// 4. Create Repository and Handlers
@@ -262,7 +253,7 @@ func main() {
 	fmt.Println("🔟 Querying observability data from SQLite...")
 	fmt.Println()
 
-	queries := observability.NewSQLiteObservabilityQueries(observabilityDB, exporterConfig)
+	queries := observability.NewSQLiteObservabilityQueries(db, exporterConfig)
 
 	// Query recent traces
 	fmt.Println("   📊 Recent traces:")
@@ -352,9 +343,8 @@ func main() {
 	// Summary
 	fmt.Println("🎉 Demo Complete!")
 	fmt.Println()
-	fmt.Println("📁 Database Files Created:")
-	fmt.Println("  • eventstore.db - Contains all event sourcing data")
-	fmt.Println("  • observability.db - Contains traces and metrics")
+	fmt.Println("📁 Database File Created:")
+	fmt.Println("  • app.db - Contains all event sourcing data, traces and metrics")
 	fmt.Println()
 	fmt.Println("Summary:")
 	fmt.Println("  ✅ All data persisted to disk")
@@ -369,28 +359,28 @@ func main() {
 	fmt.Println("  • Great for embedded systems, edge devices, CLIs")
 	fmt.Println("  • Can migrate to external backends later")
 	fmt.Println()
-	fmt.Println("Event Store Tables (eventstore.db):")
+	fmt.Println("Event Store Tables (app.db):")
 	fmt.Println("  • events - All domain events")
 	fmt.Println("  • snapshots - Aggregate snapshots")
 	fmt.Println()
-	fmt.Println("Observability Tables (observability.db):")
+	fmt.Println("Observability Tables (app.db):")
 	fmt.Println("  • otel_traces - Trace metadata")
 	fmt.Println("  • otel_spans - Individual span data with full context")
 	fmt.Println("  • otel_metrics - Time-series metric data")
 	fmt.Println()
 	fmt.Println("Query Examples:")
 	fmt.Println("  # View all events")
-	fmt.Println("  sqlite3 eventstore.db \"SELECT aggregate_id, event_type, version FROM events\"")
+	fmt.Println("  sqlite3 app.db \"SELECT aggregate_id, event_type, version FROM events\"")
 	fmt.Println()
 	fmt.Println("  # View traces")
-	fmt.Println("  sqlite3 observability.db \"SELECT trace_id, COUNT(*) as span_count FROM otel_spans GROUP BY trace_id\"")
+	fmt.Println("  sqlite3 app.db \"SELECT trace_id, COUNT(*) as span_count FROM otel_spans GROUP BY trace_id\"")
 	fmt.Println()
 	fmt.Println("  # View metrics")
-	fmt.Println("  sqlite3 observability.db \"SELECT name, type, COUNT(*) FROM otel_metrics GROUP BY name, type\"")
+	fmt.Println("  sqlite3 app.db \"SELECT name, type, COUNT(*) FROM otel_metrics GROUP BY name, type\"")
 	fmt.Println()
 	fmt.Println("  # Slow operations")
-	fmt.Println("  sqlite3 observability.db \"SELECT name, (end_time - start_time)/1000000 as duration_ms FROM otel_spans ORDER BY duration_ms DESC LIMIT 10\"")
+	fmt.Println("  sqlite3 app.db \"SELECT name, (end_time - start_time)/1000000 as duration_ms FROM otel_spans ORDER BY duration_ms DESC LIMIT 10\"")
 	fmt.Println()
-	fmt.Println("💡 Tip: Keep these database files for historical analysis!")
+	fmt.Println("💡 Tip: Keep this database file for historical analysis!")
 	fmt.Println("   You can query past performance, debug issues, or migrate to production backends.")
 }
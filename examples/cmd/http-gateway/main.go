@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	accountdomain "github.com/plaenen/eventstore/examples/bankaccount/domain"
+	"github.com/plaenen/eventstore/examples/bankaccount/handlers"
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// This demo puts an HTTP gateway in front of the bank account service:
+//
+// - Requests are decoded from JSON and sent over NATS with the generated client
+// - Validation failures come back as VALIDATION_FAILED AppErrors listing each
+//   invalid field, and the gateway turns them into 422 responses
+// - Other AppErrors are mapped to 4xx/5xx status codes
+// - The Idempotency-Key header is passed through, so a retried request opens
+//   the account only once
+
+func main() {
+	fmt.Println("=== HTTP Gateway Demo ===")
+	fmt.Println()
+
+	ctx := context.Background()
+
+	// 1. Start the bank account service
+	fmt.Println("1️⃣  Starting bank account service...")
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		log.Fatalf("Failed to start NATS: %v", err)
+	}
+	defer srv.Shutdown()
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		log.Fatalf("Failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "BankAccountService",
+		Version:      "1.0.0",
+	})
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	repo := accountv1.NewAccountRepository(eventStore, accountdomain.NewAccount)
+	commandService := accountv1.NewAccountCommandServiceServer(server, handlers.NewAccountCommandHandler(repo))
+	if err := commandService.Start(ctx); err != nil {
+		log.Fatalf("Failed to start command service: %v", err)
+	}
+	fmt.Println("   ✅ Service ready")
+	fmt.Println()
+
+	// 2. Start the gateway
+	fmt.Println("2️⃣  Starting HTTP gateway...")
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "http-gateway",
+	})
+	if err != nil {
+		log.Fatalf("Failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	// A real gateway would call http.ListenAndServe(":8080", newGateway(...))
+	gateway := httptest.NewServer(newGateway(accountv1.NewAccountClient(transport)))
+	defer gateway.Close()
+	fmt.Printf("   ✅ Listening on %s\n", gateway.URL)
+	fmt.Println()
+
+	// 3. Send requests
+	fmt.Println("3️⃣  POST /accounts with missing and invalid fields...")
+	post(gateway.URL+"/accounts", `{"account_id": "", "owner_name": "", "initial_balance": "-5"}`, "")
+
+	fmt.Println("4️⃣  POST /accounts with a valid body and an Idempotency-Key...")
+	post(gateway.URL+"/accounts", `{"account_id": "acc-1", "owner_name": "Alice", "initial_balance": "100.00"}`, "open-acc-1")
+
+	fmt.Println("5️⃣  Retrying the same request (same Idempotency-Key)...")
+	post(gateway.URL+"/accounts", `{"account_id": "acc-1", "owner_name": "Alice", "initial_balance": "100.00"}`, "open-acc-1")
+
+	events, err := eventStore.LoadEvents(ctx, "acc-1", 0)
+	if err != nil {
+		log.Fatalf("Failed to load events: %v", err)
+	}
+	fmt.Printf("   acc-1 has %d event(s)\n", len(events))
+	fmt.Println()
+
+	fmt.Println("✅ Demo complete!")
+}
+
+// newGateway returns the gateway's HTTP handler.
+func newGateway(client *accountv1.AccountClient) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /accounts", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		cmd := &accountv1.OpenAccountCommand{}
+		if err := protojson.Unmarshal(body, cmd); err != nil {
+			writeAppError(w, &eventsourcing.AppError{Code: "INVALID_JSON", Message: err.Error()})
+			return
+		}
+
+		ctx := r.Context()
+		if key := r.Header.Get(cqrs.HeaderIdempotencyKey); key != "" {
+			ctx = domain.WithIdempotencyKey(ctx, key)
+		}
+
+		resp, appErr := client.OpenAccount(ctx, cmd)
+		if appErr != nil {
+			writeAppError(w, appErr)
+			return
+		}
+		writeJSON(w, http.StatusCreated, resp)
+	})
+	return mux
+}
+
+// writeAppError writes appErr, including its field violations, as JSON.
+func writeAppError(w http.ResponseWriter, appErr *eventsourcing.AppError) {
+	status := http.StatusInternalServerError
+	switch appErr.Code {
+	case eventsourcing.ValidationFailedCode:
+		status = http.StatusUnprocessableEntity
+	case "INVALID_JSON":
+		status = http.StatusBadRequest
+	case "ACCOUNT_NOT_FOUND":
+		status = http.StatusNotFound
+	case "TRANSPORT_ERROR", "TIMEOUT", cqrs.RateLimitedCode:
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, appErr)
+}
+
+// writeJSON writes a protobuf message as JSON with proto field names.
+func writeJSON(w http.ResponseWriter, status int, msg proto.Message) {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// post sends body to url, with an Idempotency-Key header unless
+// idempotencyKey is empty, and prints the response.
+func post(url, body, idempotencyKey string) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+	if err != nil {
+		log.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set(cqrs.HeaderIdempotencyKey, idempotencyKey)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	fmt.Printf("   → %s\n", resp.Status)
+	fmt.Printf("   %s\n", data)
+	fmt.Println()
+}
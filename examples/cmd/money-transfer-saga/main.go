@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	accountdomain "github.com/plaenen/eventstore/examples/bankaccount/domain"
+	"github.com/plaenen/eventstore/examples/bankaccount/handlers"
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/domain"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	natseventbus "github.com/plaenen/eventstore/pkg/messaging/nats"
+	"github.com/plaenen/eventstore/pkg/saga"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+// This demo moves money between two accounts with a saga:
+//
+// - Begin withdraws the amount from the source account
+// - On MoneyWithdrawn, the saga deposits it into the target account
+// - On MoneyDeposited, the transfer is complete
+// - If the deposit is rejected (here: the target account is closed) or the
+//   transfer takes too long, the withdrawal is compensated by depositing the
+//   amount back into the source account
+//
+// The saga's commands carry the transfer ID as correlation ID, which the
+// account handlers copy into the events, so the saga can tell which transfer
+// an event belongs to.
+//
+// The event store writes the account events to its outbox in the transaction
+// that saves them, and an outbox relay publishes them to the event bus, so no
+// event is lost between saving and publishing.
+
+func main() {
+	fmt.Println("=== Money Transfer Saga Demo ===")
+	fmt.Println()
+
+	ctx := context.Background()
+
+	// 1. Start the bank account service
+	fmt.Println("1️⃣  Starting bank account service...")
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		log.Fatalf("Failed to start NATS: %v", err)
+	}
+	defer srv.Shutdown()
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithOutbox())
+	if err != nil {
+		log.Fatalf("Failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	busConfig := natseventbus.DefaultConfig()
+	busConfig.URL = srv.URL()
+	eventBus, err := natseventbus.NewEventBus(busConfig)
+	if err != nil {
+		log.Fatalf("Failed to create event bus: %v", err)
+	}
+	defer eventBus.Close()
+
+	relay, err := sqlite.NewOutboxRelay(eventStore.DB(), eventBus, sqlite.WithOutboxPollInterval(10*time.Millisecond))
+	if err != nil {
+		log.Fatalf("Failed to create outbox relay: %v", err)
+	}
+	if err := relay.Start(ctx); err != nil {
+		log.Fatalf("Failed to start outbox relay: %v", err)
+	}
+	defer relay.Stop(ctx)
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "BankAccountService",
+		Version:      "1.0.0",
+	})
+	if err != nil {
+		log.Fatalf("Failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	repo := accountv1.NewAccountRepository(eventStore, accountdomain.NewAccount)
+	commandService := accountv1.NewAccountCommandServiceServer(server, handlers.NewAccountCommandHandler(repo))
+	if err := commandService.Start(ctx); err != nil {
+		log.Fatalf("Failed to start command service: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "money-transfer-saga",
+	})
+	if err != nil {
+		log.Fatalf("Failed to create transport: %v", err)
+	}
+	defer transport.Close()
+	client := accountv1.NewAccountClient(transport)
+	fmt.Println("   ✅ Service ready")
+	fmt.Println()
+
+	// 2. Open the accounts
+	fmt.Println("2️⃣  Opening accounts...")
+	for _, cmd := range []*accountv1.OpenAccountCommand{
+		{AccountId: "alice", OwnerName: "Alice", InitialBalance: "100.00"},
+		{AccountId: "bob", OwnerName: "Bob", InitialBalance: "0.00"},
+		{AccountId: "carol", OwnerName: "Carol", InitialBalance: "0.00"},
+	} {
+		if _, appErr := client.OpenAccount(ctx, cmd); appErr != nil {
+			log.Fatalf("Failed to open %s: %v", cmd.AccountId, appErr)
+		}
+	}
+	if _, appErr := client.CloseAccount(ctx, &accountv1.CloseAccountCommand{AccountId: "carol"}); appErr != nil {
+		log.Fatalf("Failed to close carol: %v", appErr)
+	}
+	printBalances(repo, "alice", "bob")
+	fmt.Println("   (carol's account is closed)")
+	fmt.Println()
+
+	// 3. Define and start the saga
+	fmt.Println("3️⃣  Starting the money transfer saga...")
+	sagaStore, err := sqlite.NewSagaStore(eventStore.DB())
+	if err != nil {
+		log.Fatalf("Failed to create saga store: %v", err)
+	}
+	transfer := saga.NewBuilder("money-transfer", transport, sagaStore).
+		StartWith(withdraw("from")).Compensate(deposit("from")).
+		On(accountv1.MoneyWithdrawnEventType).Then(deposit("to")).
+		On(accountv1.MoneyDepositedEventType).Complete().
+		WithTimeout(30 * time.Second).
+		Build()
+	if err := transfer.Start(ctx, eventBus); err != nil {
+		log.Fatalf("Failed to start saga: %v", err)
+	}
+	defer transfer.Stop()
+	fmt.Println("   ✅ Saga subscribed to the event bus")
+	fmt.Println()
+
+	// 4. A transfer that succeeds
+	fmt.Println("4️⃣  Transferring 30.00 from alice to bob...")
+	runTransfer(ctx, transfer, "transfer-1", map[string]string{"from": "alice", "to": "bob", "amount": "30.00"})
+	printBalances(repo, "alice", "bob")
+	fmt.Println()
+
+	// 5. A transfer whose deposit is rejected
+	fmt.Println("5️⃣  Transferring 20.00 from alice to carol (closed)...")
+	runTransfer(ctx, transfer, "transfer-2", map[string]string{"from": "alice", "to": "carol", "amount": "20.00"})
+	printBalances(repo, "alice", "carol")
+	fmt.Println()
+
+	fmt.Println("✅ Demo complete!")
+}
+
+// withdraw returns the action withdrawing the transfer amount from the
+// account in data[key].
+func withdraw(key string) saga.Action {
+	return func(ctx context.Context, instance *store.SagaInstance, envelope *domain.EventEnvelope) (*saga.Command, error) {
+		return &saga.Command{
+			Subject: "account.v1.AccountCommandService.Withdraw",
+			Message: &accountv1.WithdrawCommand{AccountId: instance.Data[key], Amount: instance.Data["amount"]},
+		}, nil
+	}
+}
+
+// deposit returns the action depositing the transfer amount into the account
+// in data[key].
+func deposit(key string) saga.Action {
+	return func(ctx context.Context, instance *store.SagaInstance, envelope *domain.EventEnvelope) (*saga.Command, error) {
+		return &saga.Command{
+			Subject: "account.v1.AccountCommandService.Deposit",
+			Message: &accountv1.DepositCommand{AccountId: instance.Data[key], Amount: instance.Data["amount"]},
+		}, nil
+	}
+}
+
+// runTransfer begins a transfer and waits until it is no longer running.
+func runTransfer(ctx context.Context, transfer *saga.Saga, id string, data map[string]string) {
+	if err := transfer.Begin(ctx, id, data); err != nil {
+		fmt.Printf("   ❌ %v\n", err)
+		return
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		instance, err := transfer.Instance(ctx, id)
+		if err != nil {
+			log.Fatalf("Failed to load %s: %v", id, err)
+		}
+		if instance.Status != store.SagaStatusRunning {
+			fmt.Printf("   %s: %s %v\n", id, instance.Status, instance.Steps)
+			if instance.Error != "" {
+				fmt.Printf("   reason: %s\n", instance.Error)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			log.Fatalf("%s is still running", id)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func printBalances(repo *accountv1.AccountRepository, accountIDs ...string) {
+	for _, id := range accountIDs {
+		account, err := repo.Load(id)
+		if err != nil {
+			log.Fatalf("Failed to load %s: %v", id, err)
+		}
+		fmt.Printf("   %s: %s\n", id, account.Balance)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
 	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
 	"github.com/plaenen/eventstore/pkg/store/sqlite"
 	"google.golang.org/protobuf/proto"
 	_ "modernc.org/sqlite"
@@ -54,6 +55,16 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Share one status store with the projection to watch its transitions
+	statusStore, err := sqlite.NewProjectionStatusStore(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	unsubscribe := statusStore.Subscribe("account-balance", func(state store.ProjectionState) {
+		fmt.Printf("   📡 Status of %s: %s\n", state.ProjectionName, state.Status)
+	})
+	defer unsubscribe()
+
 	fmt.Println("   ✅ Infrastructure ready")
 	fmt.Println()
 
@@ -71,6 +82,7 @@ func main() {
 		// Use WithMigrations instead of WithSchema!
 		// Migrations are automatically run during Build()
 		WithMigrations(migrationsFS, "projections/account_balance/migrations").
+		WithStatusStore(statusStore).
 		// Register event handlers
 		On(accountv1.OnAccountOpened(func(ctx context.Context, event *accountv1.AccountOpenedEvent, envelope *domain.EventEnvelope) error {
 			fmt.Printf("   ✨ AccountOpened: %s (Owner: %s)\n", event.AccountId, event.OwnerName)
@@ -210,6 +222,11 @@ func main() {
 	fmt.Println()
 	fmt.Println("5️⃣  Querying projection...")
 
+	// Don't serve queries while the projection is rebuilding
+	if err := statusStore.WaitForReady(ctx, "account-balance"); err != nil {
+		log.Fatalf("Projection not ready: %v", err)
+	}
+
 	// Query the projection with all evolved fields
 	var accountID, ownerName, balance string
 	var updatedAt int64
@@ -38,9 +38,11 @@ func main() {
 	// 1. Setup infrastructure
 	fmt.Println("1️⃣  Setting up infrastructure...")
 
-	// Event store
+	// Event store, writing every appended event to its outbox in the same
+	// transaction
 	eventStore, err := sqlite.NewEventStore(
 		sqlite.WithDSN("file:projection_nats_demo.db?mode=memory&cache=shared"),
+		sqlite.WithOutbox(),
 	)
 	if err != nil {
 		log.Fatal(err)
@@ -72,6 +74,19 @@ func main() {
 	}
 	defer eventBus.Close()
 
+	// Outbox relay, publishing committed events to NATS
+	relay, err := sqlite.NewOutboxRelay(db, eventBus,
+		sqlite.WithOutboxPollInterval(100*time.Millisecond),
+		sqlite.WithOutboxRetention(time.Hour),
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := relay.Start(ctx); err != nil {
+		log.Fatal(err)
+	}
+	defer relay.Stop(ctx)
+
 	fmt.Println("   ✅ Infrastructure ready")
 	fmt.Println()
 
@@ -218,23 +233,19 @@ func main() {
 	fmt.Println()
 
 	// Start both projections
-	if err := projectionManager.Start(ctx, "account-balance"); err != nil {
-		log.Fatalf("Failed to start account-balance projection: %v", err)
-	}
-
-	if err := projectionManager.Start(ctx, "account-activity-log"); err != nil {
-		log.Fatalf("Failed to start activity-log projection: %v", err)
+	if err := projectionManager.StartAll(ctx); err != nil {
+		log.Fatalf("Failed to start projections: %v", err)
 	}
 
 	fmt.Println("   ✅ Projections started")
 	fmt.Println()
 
-	// 5. Simulate events being published to NATS
-	fmt.Println("5️⃣  Publishing events to NATS...")
+	// 5. Append events; the outbox relay publishes them to NATS
+	fmt.Println("5️⃣  Appending events (published to NATS by the outbox relay)...")
 	fmt.Println("   📝 Projections will automatically process these events")
 	fmt.Println()
 
-	// Simulate command handler publishing events
+	// Simulate command handler saving events
 	events := []*domain.Event{
 		{
 			ID:            "evt-1",
@@ -280,12 +291,13 @@ func main() {
 		},
 	}
 
-	// Publish events to NATS
-	if err := eventBus.Publish(events); err != nil {
-		log.Fatalf("Failed to publish events: %v", err)
+	// Append events; they are published only once committed, even if the
+	// process crashes right after the commit
+	if err := eventStore.AppendEvents(ctx, "acc-carol-001", 0, events); err != nil {
+		log.Fatalf("Failed to append events: %v", err)
 	}
 
-	fmt.Println("   ✅ Events published to NATS")
+	fmt.Println("   ✅ Events appended and queued for NATS")
 	fmt.Println()
 
 	// Give projections time to process events
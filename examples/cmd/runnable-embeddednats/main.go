@@ -197,12 +197,6 @@ func main() {
 	streamName := fmt.Sprintf("DEMO_STREAM_%d", uniqueID)
 	subject := fmt.Sprintf("demo.jetstream.%d.*", uniqueID)
 
-	// Try to delete any existing streams that might conflict
-	for _, existingStream := range existingStreams {
-		fmt.Printf("   🗑️  Deleting existing stream: %s\n", existingStream)
-		_ = js.DeleteStream(existingStream)
-	}
-
 	_, err = js.AddStream(&natsclient.StreamConfig{
 		Name:     streamName,
 		Subjects: []string{subject},
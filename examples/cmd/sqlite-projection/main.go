@@ -5,11 +5,13 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
 
 	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
 	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/store/sqlite"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 	_ "modernc.org/sqlite"
 )
 
@@ -23,6 +25,10 @@ import (
 //
 // Compare this to the generic builder where you manually manage transactions!
 
+// overdraftDetectedEventType is the derived event emitted by the overdraft
+// handler. Its payload is the offending balance as a StringValue.
+const overdraftDetectedEventType = "account.v1.OverdraftDetected"
+
 func main() {
 	fmt.Println("=== SQLite Projection Builder Demo ===")
 	fmt.Println()
@@ -115,11 +121,16 @@ func main() {
 
 			return err
 		})).
-		On(accountv1.OnMoneyWithdrawn(func(ctx context.Context, event *accountv1.MoneyWithdrawnEvent, envelope *domain.EventEnvelope) error {
+		// Overdraft detection - the handler emits a derived event that is
+		// appended to the event store in the projection's transaction.
+		// Derived events never re-trigger the projection that emitted them.
+		OnWithEmit(accountv1.MoneyWithdrawnEventType, func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) ([]*domain.Event, error) {
+			var event accountv1.MoneyWithdrawnEvent
+			if err := proto.Unmarshal(envelope.Data, &event); err != nil {
+				return nil, err
+			}
 			fmt.Printf("   💸 MoneyWithdrawn: Amount %s\n", event.Amount)
 
-			tx, _ := sqlite.TxFromContext(ctx)
-
 			_, err := tx.Exec(`
 				UPDATE account_summary
 				SET balance = ?,
@@ -127,9 +138,23 @@ func main() {
 				    updated_at = ?
 				WHERE account_id = ?
 			`, event.NewBalance, event.Timestamp, event.AccountId)
-
-			return err
-		})).
+			if err != nil {
+				return nil, err
+			}
+
+			balance, err := strconv.ParseFloat(event.NewBalance, 64)
+			if err != nil || balance >= 0 {
+				return nil, err
+			}
+
+			fmt.Printf("   🚨 Overdraft detected: balance %s\n", event.NewBalance)
+			return []*domain.Event{{
+				AggregateID:   "overdraft-" + event.AccountId,
+				AggregateType: "OverdraftAlert",
+				EventType:     overdraftDetectedEventType,
+				Data:          mustMarshal(wrapperspb.String(event.NewBalance)),
+			}}, nil
+		}).
 		On(accountv1.OnAccountClosed(func(ctx context.Context, event *accountv1.AccountClosedEvent, envelope *domain.EventEnvelope) error {
 			fmt.Printf("   🔒 AccountClosed\n")
 
@@ -199,8 +224,8 @@ func main() {
 				Version:     3,
 				Data:        mustMarshal(&accountv1.MoneyWithdrawnEvent{
 					AccountId:  "acc-bob-001",
-					Amount:     "500.00",
-					NewBalance: "5500.00",
+					Amount:     "6500.00",
+					NewBalance: "-500.00",
 					Timestamp:  1234567910,
 				}),
 			},
@@ -214,7 +239,7 @@ func main() {
 				Data:        mustMarshal(&accountv1.MoneyDepositedEvent{
 					AccountId:  "acc-bob-001",
 					Amount:     "2000.00",
-					NewBalance: "7500.00",
+					NewBalance: "1500.00",
 					Timestamp:  1234567920,
 				}),
 			},
@@ -227,7 +252,7 @@ func main() {
 				Version:     5,
 				Data:        mustMarshal(&accountv1.AccountClosedEvent{
 					AccountId:    "acc-bob-001",
-					FinalBalance: "7500.00",
+					FinalBalance: "1500.00",
 					Timestamp:    1234567930,
 				}),
 			},
@@ -263,6 +288,20 @@ func main() {
 	fmt.Printf("   Transactions: %d\n", transactionCount)
 	fmt.Println()
 
+	// Derived events live in the event store like any other event
+	alerts, err := eventStore.LoadEvents(ctx, "overdraft-acc-bob-001", 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, alert := range alerts {
+		var balance wrapperspb.StringValue
+		if err := proto.Unmarshal(alert.Data, &balance); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("   🚨 %s (balance %s, caused by %s)\n", alert.EventType, balance.Value, alert.Metadata.CausationID)
+	}
+	fmt.Println()
+
 	// 5. Demonstrate rebuild functionality
 	fmt.Println("5️⃣  Testing rebuild functionality...")
 	fmt.Println("   📝 Rebuild replays all events from event store")
@@ -295,6 +334,7 @@ func main() {
 	fmt.Println("  🏗️  Schema initialization support")
 	fmt.Println("  🔄 Built-in rebuild functionality")
 	fmt.Println("  🎯 Type-safe event handlers")
+	fmt.Println("  🚨 Derived events via OnWithEmit")
 	fmt.Println()
 	fmt.Println("Usage pattern:")
 	fmt.Println("  sqlite.NewSQLiteProjectionBuilder(name, db, checkpointStore, eventStore).")
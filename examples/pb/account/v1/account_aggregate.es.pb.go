@@ -103,6 +103,22 @@ type AccountEventApplier interface {
 	ApplyAccountClosedEvent(agg *AccountAggregate, e *AccountClosedEvent) error
 }
 
+// AccountCompensator is implemented by appliers that can logically undo
+// a command on Account. See domain.Compensator.
+type AccountCompensator interface {
+	Compensate(ctx context.Context, agg *AccountAggregate, originalCommandID string, meta domain.EventMetadata) error
+}
+
+// Compensate logically undoes the command originalCommandID by delegating to
+// the injected applier when it implements AccountCompensator.
+func (a *AccountAggregate) Compensate(ctx context.Context, originalCommandID string, meta domain.EventMetadata) error {
+	compensator, ok := a.applier.(AccountCompensator)
+	if !ok {
+		return fmt.Errorf("%w: Account", domain.ErrCompensationNotSupported)
+	}
+	return compensator.Compensate(ctx, a, originalCommandID, meta)
+}
+
 // ============================================================================
 // Implementing Event Appliers (Recommended Pattern)
 // ============================================================================
@@ -206,7 +222,7 @@ func (a *AccountAggregate) ApplyAccountOpenedEvent(event *AccountOpenedEvent, op
 	if len(options.Constraints) > 0 {
 		return a.AggregateRoot.ApplyChangeWithConstraints(
 			event,
-			"accountv1.AccountOpenedEvent",
+			AccountOpenedEventType,
 			options.Metadata,
 			options.Constraints,
 		)
@@ -214,7 +230,7 @@ func (a *AccountAggregate) ApplyAccountOpenedEvent(event *AccountOpenedEvent, op
 
 	return a.AggregateRoot.ApplyChange(
 		event,
-		"accountv1.AccountOpenedEvent",
+		AccountOpenedEventType,
 		options.Metadata,
 	)
 }
@@ -230,7 +246,7 @@ func (a *AccountAggregate) ApplyMoneyDepositedEvent(event *MoneyDepositedEvent,
 	if len(options.Constraints) > 0 {
 		return a.AggregateRoot.ApplyChangeWithConstraints(
 			event,
-			"accountv1.MoneyDepositedEvent",
+			MoneyDepositedEventType,
 			options.Metadata,
 			options.Constraints,
 		)
@@ -238,7 +254,7 @@ func (a *AccountAggregate) ApplyMoneyDepositedEvent(event *MoneyDepositedEvent,
 
 	return a.AggregateRoot.ApplyChange(
 		event,
-		"accountv1.MoneyDepositedEvent",
+		MoneyDepositedEventType,
 		options.Metadata,
 	)
 }
@@ -254,7 +270,7 @@ func (a *AccountAggregate) ApplyMoneyWithdrawnEvent(event *MoneyWithdrawnEvent,
 	if len(options.Constraints) > 0 {
 		return a.AggregateRoot.ApplyChangeWithConstraints(
 			event,
-			"accountv1.MoneyWithdrawnEvent",
+			MoneyWithdrawnEventType,
 			options.Metadata,
 			options.Constraints,
 		)
@@ -262,7 +278,7 @@ func (a *AccountAggregate) ApplyMoneyWithdrawnEvent(event *MoneyWithdrawnEvent,
 
 	return a.AggregateRoot.ApplyChange(
 		event,
-		"accountv1.MoneyWithdrawnEvent",
+		MoneyWithdrawnEventType,
 		options.Metadata,
 	)
 }
@@ -278,7 +294,7 @@ func (a *AccountAggregate) ApplyAccountClosedEvent(event *AccountClosedEvent, op
 	if len(options.Constraints) > 0 {
 		return a.AggregateRoot.ApplyChangeWithConstraints(
 			event,
-			"accountv1.AccountClosedEvent",
+			AccountClosedEventType,
 			options.Metadata,
 			options.Constraints,
 		)
@@ -286,7 +302,7 @@ func (a *AccountAggregate) ApplyAccountClosedEvent(event *AccountClosedEvent, op
 
 	return a.AggregateRoot.ApplyChange(
 		event,
-		"accountv1.AccountClosedEvent",
+		AccountClosedEventType,
 		options.Metadata,
 	)
 }
@@ -300,7 +316,8 @@ type AccountRepository struct {
 
 // NewAccountRepository creates a new repository
 // factory: function to create new aggregate instances (should inject appliers)
-func NewAccountRepository(eventStore store.EventStore, factory func(string) *AccountAggregate) *AccountRepository {
+// opts: optional repository configuration (e.g., store.WithAggregateCache)
+func NewAccountRepository(eventStore store.EventStore, factory func(string) *AccountAggregate, opts ...store.RepositoryOption) *AccountRepository {
 	return &AccountRepository{
 		BaseRepository: store.NewRepository[*AccountAggregate](
 			eventStore,
@@ -314,31 +331,32 @@ func NewAccountRepository(eventStore store.EventStore, factory func(string) *Acc
 				}
 				return agg.ApplyEvent(msg)
 			},
+			opts...,
 		),
 	}
 }
 
 func deserializeEventAccount(event *domain.Event) (proto.Message, error) {
-	switch event.EventType {
-	case "accountv1.AccountOpenedEvent":
+	switch domain.CanonicalEventType(event.EventType) {
+	case AccountOpenedEventType:
 		msg := &AccountOpenedEvent{}
 		if err := proto.Unmarshal(event.Data, msg); err != nil {
 			return nil, err
 		}
 		return msg, nil
-	case "accountv1.MoneyDepositedEvent":
+	case MoneyDepositedEventType:
 		msg := &MoneyDepositedEvent{}
 		if err := proto.Unmarshal(event.Data, msg); err != nil {
 			return nil, err
 		}
 		return msg, nil
-	case "accountv1.MoneyWithdrawnEvent":
+	case MoneyWithdrawnEventType:
 		msg := &MoneyWithdrawnEvent{}
 		if err := proto.Unmarshal(event.Data, msg); err != nil {
 			return nil, err
 		}
 		return msg, nil
-	case "accountv1.AccountClosedEvent":
+	case AccountClosedEventType:
 		msg := &AccountClosedEvent{}
 		if err := proto.Unmarshal(event.Data, msg); err != nil {
 			return nil, err
@@ -351,12 +369,21 @@ func deserializeEventAccount(event *domain.Event) (proto.Message, error) {
 
 // Event type constants for Account
 const (
-	AccountOpenedEventType  = "accountv1.AccountOpenedEvent"
-	MoneyDepositedEventType = "accountv1.MoneyDepositedEvent"
-	MoneyWithdrawnEventType = "accountv1.MoneyWithdrawnEvent"
-	AccountClosedEventType  = "accountv1.AccountClosedEvent"
+	AccountOpenedEventType  = "account.v1.AccountOpenedEvent"
+	MoneyDepositedEventType = "account.v1.MoneyDepositedEvent"
+	MoneyWithdrawnEventType = "account.v1.MoneyWithdrawnEvent"
+	AccountClosedEventType  = "account.v1.AccountClosedEvent"
 )
 
+// Event types written by earlier generator versions (<go package>.<Message>)
+// are aliases of the fully qualified names above.
+func init() {
+	domain.RegisterEventTypeAlias("accountv1.AccountOpenedEvent", AccountOpenedEventType)
+	domain.RegisterEventTypeAlias("accountv1.MoneyDepositedEvent", MoneyDepositedEventType)
+	domain.RegisterEventTypeAlias("accountv1.MoneyWithdrawnEvent", MoneyWithdrawnEventType)
+	domain.RegisterEventTypeAlias("accountv1.AccountClosedEvent", AccountClosedEventType)
+}
+
 // Typed event handlers for Account
 type AccountOpenedEventHandler func(ctx context.Context, event *AccountOpenedEvent, envelope *domain.EventEnvelope) error
 type MoneyDepositedEventHandler func(ctx context.Context, event *MoneyDepositedEvent, envelope *domain.EventEnvelope) error
@@ -460,6 +487,12 @@ func OnAccountOpened(handler AccountOpenedEventHandler) store.EventHandlerRegist
 	}
 }
 
+// MapAccountOpened starts a declarative row mapping for AccountOpenedEvent
+// Use with sqlite.NewProjectionBuilder().Map(MapAccountOpened().ToTable(...)...)
+func MapAccountOpened() *store.EventMapping[*AccountOpenedEvent] {
+	return store.MapEvent[*AccountOpenedEvent](AccountOpenedEventType)
+}
+
 // OnMoneyDeposited creates an event handler registration for MoneyDepositedEvent
 // Use with eventsourcing.NewProjectionBuilder().On(OnMoneyDeposited(handler))
 func OnMoneyDeposited(handler MoneyDepositedEventHandler) store.EventHandlerRegistration {
@@ -477,6 +510,12 @@ func OnMoneyDeposited(handler MoneyDepositedEventHandler) store.EventHandlerRegi
 	}
 }
 
+// MapMoneyDeposited starts a declarative row mapping for MoneyDepositedEvent
+// Use with sqlite.NewProjectionBuilder().Map(MapMoneyDeposited().ToTable(...)...)
+func MapMoneyDeposited() *store.EventMapping[*MoneyDepositedEvent] {
+	return store.MapEvent[*MoneyDepositedEvent](MoneyDepositedEventType)
+}
+
 // OnMoneyWithdrawn creates an event handler registration for MoneyWithdrawnEvent
 // Use with eventsourcing.NewProjectionBuilder().On(OnMoneyWithdrawn(handler))
 func OnMoneyWithdrawn(handler MoneyWithdrawnEventHandler) store.EventHandlerRegistration {
@@ -494,6 +533,12 @@ func OnMoneyWithdrawn(handler MoneyWithdrawnEventHandler) store.EventHandlerRegi
 	}
 }
 
+// MapMoneyWithdrawn starts a declarative row mapping for MoneyWithdrawnEvent
+// Use with sqlite.NewProjectionBuilder().Map(MapMoneyWithdrawn().ToTable(...)...)
+func MapMoneyWithdrawn() *store.EventMapping[*MoneyWithdrawnEvent] {
+	return store.MapEvent[*MoneyWithdrawnEvent](MoneyWithdrawnEventType)
+}
+
 // OnAccountClosed creates an event handler registration for AccountClosedEvent
 // Use with eventsourcing.NewProjectionBuilder().On(OnAccountClosed(handler))
 func OnAccountClosed(handler AccountClosedEventHandler) store.EventHandlerRegistration {
@@ -511,6 +556,12 @@ func OnAccountClosed(handler AccountClosedEventHandler) store.EventHandlerRegist
 	}
 }
 
+// MapAccountClosed starts a declarative row mapping for AccountClosedEvent
+// Use with sqlite.NewProjectionBuilder().Map(MapAccountClosed().ToTable(...)...)
+func MapAccountClosed() *store.EventMapping[*AccountClosedEvent] {
+	return store.MapEvent[*AccountClosedEvent](AccountClosedEventType)
+}
+
 // Build creates the final Projection implementation
 func (b *AccountProjectionBuilder) Build() eventsourcing.Projection {
 	return &AccountProjection{
@@ -534,7 +585,7 @@ func (p *AccountProjection) Name() string {
 
 // Handle dispatches events to registered typed handlers
 func (p *AccountProjection) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
-	handler, exists := p.handlers[envelope.EventType]
+	handler, exists := p.handlers[domain.CanonicalEventType(envelope.EventType)]
 	if !exists {
 		// No handler registered for this event type - skip it
 		return nil
@@ -549,3 +600,53 @@ func (p *AccountProjection) Reset(ctx context.Context) error {
 	}
 	return p.resetFunc(ctx)
 }
+
+// EventRegistry maps every event type in this package to its descriptor.
+// Generic tooling can use it to decode and apply events without knowing
+// the concrete types at compile time.
+var EventRegistry = domain.EventRegistry{
+	AccountOpenedEventType: {
+		AggregateType: "Account",
+		New:           func() proto.Message { return &AccountOpenedEvent{} },
+		Apply: func(agg domain.Aggregate, event proto.Message) error {
+			a, ok := agg.(*AccountAggregate)
+			if !ok {
+				return fmt.Errorf("expected *AccountAggregate, got %T", agg)
+			}
+			return a.ApplyEvent(event)
+		},
+	},
+	MoneyDepositedEventType: {
+		AggregateType: "Account",
+		New:           func() proto.Message { return &MoneyDepositedEvent{} },
+		Apply: func(agg domain.Aggregate, event proto.Message) error {
+			a, ok := agg.(*AccountAggregate)
+			if !ok {
+				return fmt.Errorf("expected *AccountAggregate, got %T", agg)
+			}
+			return a.ApplyEvent(event)
+		},
+	},
+	MoneyWithdrawnEventType: {
+		AggregateType: "Account",
+		New:           func() proto.Message { return &MoneyWithdrawnEvent{} },
+		Apply: func(agg domain.Aggregate, event proto.Message) error {
+			a, ok := agg.(*AccountAggregate)
+			if !ok {
+				return fmt.Errorf("expected *AccountAggregate, got %T", agg)
+			}
+			return a.ApplyEvent(event)
+		},
+	},
+	AccountClosedEventType: {
+		AggregateType: "Account",
+		New:           func() proto.Message { return &AccountClosedEvent{} },
+		Apply: func(agg domain.Aggregate, event proto.Message) error {
+			a, ok := agg.(*AccountAggregate)
+			if !ok {
+				return fmt.Errorf("expected *AccountAggregate, got %T", agg)
+			}
+			return a.ApplyEvent(event)
+		},
+	},
+}
@@ -20,9 +20,11 @@ func NewAccountClient(transport eventsourcing.Transport) *AccountClient {
 	return &AccountClient{transport: transport}
 }
 
-// OpenAccount sends a OpenAccount command and returns the response
-func (c *AccountClient) OpenAccount(ctx context.Context, cmd *OpenAccountCommand) (*OpenAccountResponse, *eventsourcing.AppError) {
+// OpenAccount sends a OpenAccount command and returns the response. Pass
+// eventsourcing.WithCommandResultInto to also receive the handler's result.
+func (c *AccountClient) OpenAccount(ctx context.Context, cmd *OpenAccountCommand, opts ...eventsourcing.RequestOption) (*OpenAccountResponse, *eventsourcing.AppError) {
 	// Send request via transport
+	ctx = eventsourcing.WithRequestOptions(ctx, opts...)
 	resp, err := c.transport.Request(ctx, "account.v1.AccountCommandService.OpenAccount", cmd)
 	if err != nil {
 		return nil, &eventsourcing.AppError{
@@ -48,9 +50,11 @@ func (c *AccountClient) OpenAccount(ctx context.Context, cmd *OpenAccountCommand
 	return result, nil
 }
 
-// Deposit sends a Deposit command and returns the response
-func (c *AccountClient) Deposit(ctx context.Context, cmd *DepositCommand) (*DepositResponse, *eventsourcing.AppError) {
+// Deposit sends a Deposit command and returns the response. Pass
+// eventsourcing.WithCommandResultInto to also receive the handler's result.
+func (c *AccountClient) Deposit(ctx context.Context, cmd *DepositCommand, opts ...eventsourcing.RequestOption) (*DepositResponse, *eventsourcing.AppError) {
 	// Send request via transport
+	ctx = eventsourcing.WithRequestOptions(ctx, opts...)
 	resp, err := c.transport.Request(ctx, "account.v1.AccountCommandService.Deposit", cmd)
 	if err != nil {
 		return nil, &eventsourcing.AppError{
@@ -76,9 +80,11 @@ func (c *AccountClient) Deposit(ctx context.Context, cmd *DepositCommand) (*Depo
 	return result, nil
 }
 
-// Withdraw sends a Withdraw command and returns the response
-func (c *AccountClient) Withdraw(ctx context.Context, cmd *WithdrawCommand) (*WithdrawResponse, *eventsourcing.AppError) {
+// Withdraw sends a Withdraw command and returns the response. Pass
+// eventsourcing.WithCommandResultInto to also receive the handler's result.
+func (c *AccountClient) Withdraw(ctx context.Context, cmd *WithdrawCommand, opts ...eventsourcing.RequestOption) (*WithdrawResponse, *eventsourcing.AppError) {
 	// Send request via transport
+	ctx = eventsourcing.WithRequestOptions(ctx, opts...)
 	resp, err := c.transport.Request(ctx, "account.v1.AccountCommandService.Withdraw", cmd)
 	if err != nil {
 		return nil, &eventsourcing.AppError{
@@ -104,9 +110,11 @@ func (c *AccountClient) Withdraw(ctx context.Context, cmd *WithdrawCommand) (*Wi
 	return result, nil
 }
 
-// CloseAccount sends a CloseAccount command and returns the response
-func (c *AccountClient) CloseAccount(ctx context.Context, cmd *CloseAccountCommand) (*CloseAccountResponse, *eventsourcing.AppError) {
+// CloseAccount sends a CloseAccount command and returns the response. Pass
+// eventsourcing.WithCommandResultInto to also receive the handler's result.
+func (c *AccountClient) CloseAccount(ctx context.Context, cmd *CloseAccountCommand, opts ...eventsourcing.RequestOption) (*CloseAccountResponse, *eventsourcing.AppError) {
 	// Send request via transport
+	ctx = eventsourcing.WithRequestOptions(ctx, opts...)
 	resp, err := c.transport.Request(ctx, "account.v1.AccountCommandService.CloseAccount", cmd)
 	if err != nil {
 		return nil, &eventsourcing.AppError{
@@ -35,23 +35,23 @@ func NewAccountSDK(transport eventsourcing.Transport) *AccountSDK {
 // Commands
 
 // OpenAccount opens account
-func (s *AccountSDK) OpenAccount(ctx context.Context, cmd *OpenAccountCommand) (*OpenAccountResponse, *eventsourcing.AppError) {
-	return s.client.OpenAccount(ctx, cmd)
+func (s *AccountSDK) OpenAccount(ctx context.Context, cmd *OpenAccountCommand, opts ...eventsourcing.RequestOption) (*OpenAccountResponse, *eventsourcing.AppError) {
+	return s.client.OpenAccount(ctx, cmd, opts...)
 }
 
 // Deposit adds money to an account
-func (s *AccountSDK) Deposit(ctx context.Context, cmd *DepositCommand) (*DepositResponse, *eventsourcing.AppError) {
-	return s.client.Deposit(ctx, cmd)
+func (s *AccountSDK) Deposit(ctx context.Context, cmd *DepositCommand, opts ...eventsourcing.RequestOption) (*DepositResponse, *eventsourcing.AppError) {
+	return s.client.Deposit(ctx, cmd, opts...)
 }
 
 // Withdraw removes money from an account
-func (s *AccountSDK) Withdraw(ctx context.Context, cmd *WithdrawCommand) (*WithdrawResponse, *eventsourcing.AppError) {
-	return s.client.Withdraw(ctx, cmd)
+func (s *AccountSDK) Withdraw(ctx context.Context, cmd *WithdrawCommand, opts ...eventsourcing.RequestOption) (*WithdrawResponse, *eventsourcing.AppError) {
+	return s.client.Withdraw(ctx, cmd, opts...)
 }
 
 // CloseAccount closes account
-func (s *AccountSDK) CloseAccount(ctx context.Context, cmd *CloseAccountCommand) (*CloseAccountResponse, *eventsourcing.AppError) {
-	return s.client.CloseAccount(ctx, cmd)
+func (s *AccountSDK) CloseAccount(ctx context.Context, cmd *CloseAccountCommand, opts ...eventsourcing.RequestOption) (*CloseAccountResponse, *eventsourcing.AppError) {
+	return s.client.CloseAccount(ctx, cmd, opts...)
 }
 
 // Queries
@@ -95,6 +95,22 @@ type SubscriptionEventApplier interface {
 	ApplySubscriptionCancelledEvent(agg *SubscriptionAggregate, e *SubscriptionCancelledEvent) error
 }
 
+// SubscriptionCompensator is implemented by appliers that can logically undo
+// a command on Subscription. See domain.Compensator.
+type SubscriptionCompensator interface {
+	Compensate(ctx context.Context, agg *SubscriptionAggregate, originalCommandID string, meta domain.EventMetadata) error
+}
+
+// Compensate logically undoes the command originalCommandID by delegating to
+// the injected applier when it implements SubscriptionCompensator.
+func (a *SubscriptionAggregate) Compensate(ctx context.Context, originalCommandID string, meta domain.EventMetadata) error {
+	compensator, ok := a.applier.(SubscriptionCompensator)
+	if !ok {
+		return fmt.Errorf("%w: Subscription", domain.ErrCompensationNotSupported)
+	}
+	return compensator.Compensate(ctx, a, originalCommandID, meta)
+}
+
 // ============================================================================
 // Implementing Event Appliers (Recommended Pattern)
 // ============================================================================
@@ -186,7 +202,7 @@ func (a *SubscriptionAggregate) ApplySubscriptionCreatedEvent(event *Subscriptio
 	if len(options.Constraints) > 0 {
 		return a.AggregateRoot.ApplyChangeWithConstraints(
 			event,
-			"subscriptionv1.SubscriptionCreatedEvent",
+			SubscriptionCreatedEventType,
 			options.Metadata,
 			options.Constraints,
 		)
@@ -194,7 +210,7 @@ func (a *SubscriptionAggregate) ApplySubscriptionCreatedEvent(event *Subscriptio
 
 	return a.AggregateRoot.ApplyChange(
 		event,
-		"subscriptionv1.SubscriptionCreatedEvent",
+		SubscriptionCreatedEventType,
 		options.Metadata,
 	)
 }
@@ -210,7 +226,7 @@ func (a *SubscriptionAggregate) ApplySubscriptionCancelledEvent(event *Subscript
 	if len(options.Constraints) > 0 {
 		return a.AggregateRoot.ApplyChangeWithConstraints(
 			event,
-			"subscriptionv1.SubscriptionCancelledEvent",
+			SubscriptionCancelledEventType,
 			options.Metadata,
 			options.Constraints,
 		)
@@ -218,7 +234,7 @@ func (a *SubscriptionAggregate) ApplySubscriptionCancelledEvent(event *Subscript
 
 	return a.AggregateRoot.ApplyChange(
 		event,
-		"subscriptionv1.SubscriptionCancelledEvent",
+		SubscriptionCancelledEventType,
 		options.Metadata,
 	)
 }
@@ -232,7 +248,8 @@ type SubscriptionRepository struct {
 
 // NewSubscriptionRepository creates a new repository
 // factory: function to create new aggregate instances (should inject appliers)
-func NewSubscriptionRepository(eventStore store.EventStore, factory func(string) *SubscriptionAggregate) *SubscriptionRepository {
+// opts: optional repository configuration (e.g., store.WithAggregateCache)
+func NewSubscriptionRepository(eventStore store.EventStore, factory func(string) *SubscriptionAggregate, opts ...store.RepositoryOption) *SubscriptionRepository {
 	return &SubscriptionRepository{
 		BaseRepository: store.NewRepository[*SubscriptionAggregate](
 			eventStore,
@@ -246,19 +263,20 @@ func NewSubscriptionRepository(eventStore store.EventStore, factory func(string)
 				}
 				return agg.ApplyEvent(msg)
 			},
+			opts...,
 		),
 	}
 }
 
 func deserializeEventSubscription(event *domain.Event) (proto.Message, error) {
-	switch event.EventType {
-	case "subscriptionv1.SubscriptionCreatedEvent":
+	switch domain.CanonicalEventType(event.EventType) {
+	case SubscriptionCreatedEventType:
 		msg := &SubscriptionCreatedEvent{}
 		if err := proto.Unmarshal(event.Data, msg); err != nil {
 			return nil, err
 		}
 		return msg, nil
-	case "subscriptionv1.SubscriptionCancelledEvent":
+	case SubscriptionCancelledEventType:
 		msg := &SubscriptionCancelledEvent{}
 		if err := proto.Unmarshal(event.Data, msg); err != nil {
 			return nil, err
@@ -271,10 +289,17 @@ func deserializeEventSubscription(event *domain.Event) (proto.Message, error) {
 
 // Event type constants for Subscription
 const (
-	SubscriptionCreatedEventType   = "subscriptionv1.SubscriptionCreatedEvent"
-	SubscriptionCancelledEventType = "subscriptionv1.SubscriptionCancelledEvent"
+	SubscriptionCreatedEventType   = "subscription.v1.SubscriptionCreatedEvent"
+	SubscriptionCancelledEventType = "subscription.v1.SubscriptionCancelledEvent"
 )
 
+// Event types written by earlier generator versions (<go package>.<Message>)
+// are aliases of the fully qualified names above.
+func init() {
+	domain.RegisterEventTypeAlias("subscriptionv1.SubscriptionCreatedEvent", SubscriptionCreatedEventType)
+	domain.RegisterEventTypeAlias("subscriptionv1.SubscriptionCancelledEvent", SubscriptionCancelledEventType)
+}
+
 // Typed event handlers for Subscription
 type SubscriptionCreatedEventHandler func(ctx context.Context, event *SubscriptionCreatedEvent, envelope *domain.EventEnvelope) error
 type SubscriptionCancelledEventHandler func(ctx context.Context, event *SubscriptionCancelledEvent, envelope *domain.EventEnvelope) error
@@ -348,6 +373,12 @@ func OnSubscriptionCreated(handler SubscriptionCreatedEventHandler) store.EventH
 	}
 }
 
+// MapSubscriptionCreated starts a declarative row mapping for SubscriptionCreatedEvent
+// Use with sqlite.NewProjectionBuilder().Map(MapSubscriptionCreated().ToTable(...)...)
+func MapSubscriptionCreated() *store.EventMapping[*SubscriptionCreatedEvent] {
+	return store.MapEvent[*SubscriptionCreatedEvent](SubscriptionCreatedEventType)
+}
+
 // OnSubscriptionCancelled creates an event handler registration for SubscriptionCancelledEvent
 // Use with eventsourcing.NewProjectionBuilder().On(OnSubscriptionCancelled(handler))
 func OnSubscriptionCancelled(handler SubscriptionCancelledEventHandler) store.EventHandlerRegistration {
@@ -365,6 +396,12 @@ func OnSubscriptionCancelled(handler SubscriptionCancelledEventHandler) store.Ev
 	}
 }
 
+// MapSubscriptionCancelled starts a declarative row mapping for SubscriptionCancelledEvent
+// Use with sqlite.NewProjectionBuilder().Map(MapSubscriptionCancelled().ToTable(...)...)
+func MapSubscriptionCancelled() *store.EventMapping[*SubscriptionCancelledEvent] {
+	return store.MapEvent[*SubscriptionCancelledEvent](SubscriptionCancelledEventType)
+}
+
 // Build creates the final Projection implementation
 func (b *SubscriptionProjectionBuilder) Build() eventsourcing.Projection {
 	return &SubscriptionProjection{
@@ -388,7 +425,7 @@ func (p *SubscriptionProjection) Name() string {
 
 // Handle dispatches events to registered typed handlers
 func (p *SubscriptionProjection) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
-	handler, exists := p.handlers[envelope.EventType]
+	handler, exists := p.handlers[domain.CanonicalEventType(envelope.EventType)]
 	if !exists {
 		// No handler registered for this event type - skip it
 		return nil
@@ -403,3 +440,31 @@ func (p *SubscriptionProjection) Reset(ctx context.Context) error {
 	}
 	return p.resetFunc(ctx)
 }
+
+// EventRegistry maps every event type in this package to its descriptor.
+// Generic tooling can use it to decode and apply events without knowing
+// the concrete types at compile time.
+var EventRegistry = domain.EventRegistry{
+	SubscriptionCreatedEventType: {
+		AggregateType: "Subscription",
+		New:           func() proto.Message { return &SubscriptionCreatedEvent{} },
+		Apply: func(agg domain.Aggregate, event proto.Message) error {
+			a, ok := agg.(*SubscriptionAggregate)
+			if !ok {
+				return fmt.Errorf("expected *SubscriptionAggregate, got %T", agg)
+			}
+			return a.ApplyEvent(event)
+		},
+	},
+	SubscriptionCancelledEventType: {
+		AggregateType: "Subscription",
+		New:           func() proto.Message { return &SubscriptionCancelledEvent{} },
+		Apply: func(agg domain.Aggregate, event proto.Message) error {
+			a, ok := agg.(*SubscriptionAggregate)
+			if !ok {
+				return fmt.Errorf("expected *SubscriptionAggregate, got %T", agg)
+			}
+			return a.ApplyEvent(event)
+		},
+	},
+}
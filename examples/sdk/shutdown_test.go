@@ -0,0 +1,148 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+)
+
+// shutdownLog records the order components are closed in.
+type shutdownLog struct {
+	mu     sync.Mutex
+	closed []string
+}
+
+func (l *shutdownLog) add(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = append(l.closed, name)
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func (l *shutdownLog) closer(name string, err error) closerFunc {
+	return func() error {
+		l.add(name)
+		return err
+	}
+}
+
+// blockingTransport holds every request until release is closed.
+type blockingTransport struct {
+	log     *shutdownLog
+	started chan struct{}
+	release chan struct{}
+}
+
+func (t *blockingTransport) Request(ctx context.Context, subject string, request proto.Message) (*eventsourcing.Response, error) {
+	close(t.started)
+	<-t.release
+	t.log.add("request finished")
+	return eventsourcing.NewSimpleErrorResponse("TEST", "done"), nil
+}
+
+func (t *blockingTransport) Close() error {
+	t.log.add("transport")
+	return nil
+}
+
+func TestShutdown(t *testing.T) {
+	log := &shutdownLog{}
+	transport := &blockingTransport{log: log, started: make(chan struct{}), release: make(chan struct{})}
+	sdk := NewSDK(transport,
+		WithCommandServer(log.closer("command server", nil)),
+		WithProjections(log.closer("projection a", nil), log.closer("projection b", errors.New("flush failed"))),
+		WithEventBus(log.closer("event bus", nil)),
+		WithEventStore(log.closer("event store", nil)),
+		OnShutdown(func(ctx context.Context) error {
+			log.add("hook")
+			return nil
+		}),
+	)
+
+	ctx := context.Background()
+	go sdk.Account.OpenAccount(ctx, &accountv1.OpenAccountCommand{})
+	<-transport.started
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- sdk.Shutdown(ctx) }()
+
+	// New requests are rejected while the in-flight one drains
+	time.Sleep(10 * time.Millisecond)
+	if _, appErr := sdk.Account.Deposit(ctx, &accountv1.DepositCommand{}); appErr == nil || !strings.Contains(appErr.Message, ErrShuttingDown.Error()) {
+		t.Errorf("expected request during shutdown to fail with ErrShuttingDown, got %v", appErr)
+	}
+	close(transport.release)
+
+	err := <-shutdownErr
+	if err == nil || !strings.Contains(err.Error(), "flush failed") {
+		t.Errorf("expected the projection error to be reported, got %v", err)
+	}
+
+	expected := []string{"request finished", "command server", "projection a", "projection b", "event bus", "transport", "event store", "hook"}
+	if strings.Join(log.closed, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected shutdown order %v, got %v", expected, log.closed)
+	}
+
+	if again := sdk.Shutdown(ctx); again != err {
+		t.Errorf("expected repeated Shutdown to return the first result, got %v", again)
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	log := &shutdownLog{}
+	transport := &blockingTransport{log: log, started: make(chan struct{}), release: make(chan struct{})}
+	defer close(transport.release)
+	sdk := NewSDK(transport, WithShutdownTimeout(20*time.Millisecond))
+
+	go sdk.Account.OpenAccount(context.Background(), &accountv1.OpenAccountCommand{})
+	<-transport.started
+
+	if err := sdk.Shutdown(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected shutdown to time out on a stuck request, got %v", err)
+	}
+}
+
+// drainingServer is a command server with a graceful Shutdown.
+type drainingServer struct {
+	log      *shutdownLog
+	deadline time.Time
+}
+
+func (s *drainingServer) Shutdown(ctx context.Context) error {
+	s.deadline, _ = ctx.Deadline()
+	s.log.add("command server shutdown")
+	return nil
+}
+
+func (s *drainingServer) Close() error {
+	s.log.add("command server close")
+	return nil
+}
+
+func TestShutdownDrainsCommandServer(t *testing.T) {
+	log := &shutdownLog{}
+	transport := &blockingTransport{log: log, started: make(chan struct{}), release: make(chan struct{})}
+	server := &drainingServer{log: log}
+	sdk := NewSDK(transport, WithCommandServer(server), WithShutdownTimeout(time.Minute))
+
+	if err := sdk.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shut down: %v", err)
+	}
+	expected := []string{"command server shutdown", "transport"}
+	if strings.Join(log.closed, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("expected shutdown order %v, got %v", expected, log.closed)
+	}
+	if server.deadline.IsZero() {
+		t.Error("expected the server to be given the shutdown deadline")
+	}
+}
@@ -0,0 +1,276 @@
+// Package admin implements the eventstore-admin command line tool.
+//
+// Every command goes through the public store APIs rather than raw SQL, so
+// it sees the same data an application does: events are decoded through the
+// event registries, aggregates are rebuilt through their ApplyEvent (including
+// upcasting), and errors are the typed store errors.
+//
+// The stock cmd/eventstore-admin binary knows nothing about your domain. To
+// decode payloads, replay aggregates and rebuild projections, embed the admin
+// in a small binary of your own:
+//
+//	func main() {
+//	    eventStore, err := sqlite.NewEventStore(sqlite.WithDSN("events.db"))
+//	    if err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    defer eventStore.Close()
+//
+//	    cli := admin.New(eventStore,
+//	        admin.WithEventRegistry(accountv1.EventRegistry),
+//	        admin.WithAggregate("Account", func(id string) domain.Aggregate {
+//	            return accountdomain.NewAccount(id)
+//	        }),
+//	        admin.WithProjection(balanceProjection),
+//	    )
+//	    if err := cli.Run(context.Background(), os.Args[1:]); err != nil {
+//	        fmt.Fprintln(os.Stderr, err)
+//	        os.Exit(1)
+//	    }
+//	}
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"github.com/plaenen/eventstore/pkg/store/sqlite/migrate"
+)
+
+// ErrUsage is returned when the command line is invalid.
+var ErrUsage = errors.New("invalid usage")
+
+// Usage describes the available commands.
+const Usage = `Usage: eventstore-admin [flags] <command> [arguments]
+
+Commands:
+  inspect <aggregateID>          Show the events and latest snapshot of an aggregate
+  replay <aggregateID>           Rebuild an aggregate from its events and show its state
+  trace <correlationID>          Show every event of one business transaction, across aggregates
+  export <aggregateID>           Write an aggregate's events, snapshots and claims as JSON
+  import <file>                  Load an exported aggregate (into a scratch store)
+  projections status             Show the status and checkpoint of every projection
+  projections rebuild <name>     Rebuild a projection from scratch
+  projections verify-schema [name]
+                                 Compare projection tables with their migrations
+  constraints stats              Show the claimed unique values per constraint index
+  constraints orphans            List claimed unique values whose aggregate has no events
+  prune-commands                 Remove expired command idempotency records
+  compact                        Reclaim unused space and truncate the WAL
+`
+
+// Projection is a projection that can be rebuilt by the admin, such as a
+// *sqlite.SQLiteProjection.
+type Projection interface {
+	Name() string
+	Rebuild(ctx context.Context, opts ...store.RebuildOption) error
+}
+
+// progressRebuilder is implemented by projections that report rebuild progress.
+type progressRebuilder interface {
+	RebuildWithProgress(ctx context.Context, opts ...store.RebuildOption) (<-chan store.RebuildProgress, <-chan error)
+}
+
+// schemaVerifier is implemented by projections that can compare their tables
+// with their migrations, such as *sqlite.SQLiteProjection.
+type schemaVerifier interface {
+	VerifySchema() ([]migrate.SchemaDrift, error)
+}
+
+// Admin runs admin commands against an event store.
+type Admin struct {
+	eventStore   *sqlite.EventStore
+	projectionDB *sql.DB
+	registry     domain.EventRegistry
+	aggregates   map[string]func(id string) domain.Aggregate
+	projections  map[string]Projection
+	exportOpts   []sqlite.ExportOption
+	rebuildOpts  []store.RebuildOption
+	out          io.Writer
+}
+
+// Option configures an Admin.
+type Option func(*Admin)
+
+// WithEventRegistry registers event types so inspect can decode payloads and
+// replay can apply events. Can be passed once per proto package.
+func WithEventRegistry(registry domain.EventRegistry) Option {
+	return func(a *Admin) {
+		a.registry = domain.MergeEventRegistries(a.registry, registry)
+	}
+}
+
+// WithAggregate registers a factory for an aggregate type, used by replay.
+func WithAggregate(aggregateType string, factory func(id string) domain.Aggregate) Option {
+	return func(a *Admin) {
+		a.aggregates[aggregateType] = factory
+	}
+}
+
+// WithProjection makes a projection available to "projections rebuild" and
+// "projections verify-schema".
+func WithProjection(projection Projection) Option {
+	return func(a *Admin) {
+		a.projections[projection.Name()] = projection
+	}
+}
+
+// WithExportOptions configures export, typically with redaction hooks (see
+// sqlite.WithEventRedactor) so bundles leave without personal data.
+func WithExportOptions(opts ...sqlite.ExportOption) Option {
+	return func(a *Admin) {
+		a.exportOpts = append(a.exportOpts, opts...)
+	}
+}
+
+// WithRebuildOptions configures "projections rebuild", e.g. to quarantine
+// events a projection fails on instead of aborting:
+//
+//	admin.WithRebuildOptions(
+//	    store.OnEventError(store.RebuildQuarantine),
+//	    store.WithQuarantineStore(quarantine))
+func WithRebuildOptions(opts ...store.RebuildOption) Option {
+	return func(a *Admin) {
+		a.rebuildOpts = append(a.rebuildOpts, opts...)
+	}
+}
+
+// WithProjectionDB sets the database holding projection checkpoints and
+// status, when it is not the event store database.
+func WithProjectionDB(db *sql.DB) Option {
+	return func(a *Admin) {
+		a.projectionDB = db
+	}
+}
+
+// WithOutput sets where command output is written (default: os.Stdout).
+func WithOutput(w io.Writer) Option {
+	return func(a *Admin) {
+		a.out = w
+	}
+}
+
+// New creates an Admin for eventStore.
+func New(eventStore *sqlite.EventStore, opts ...Option) *Admin {
+	a := &Admin{
+		eventStore:   eventStore,
+		projectionDB: eventStore.DB(),
+		registry:     make(domain.EventRegistry),
+		aggregates:   make(map[string]func(id string) domain.Aggregate),
+		projections:  make(map[string]Projection),
+		out:          os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Run executes the command in args (without the program name), e.g.
+// []string{"inspect", "acc-123"}. Invalid command lines return an error
+// wrapping ErrUsage.
+func (a *Admin) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing command", ErrUsage)
+	}
+
+	switch args[0] {
+	case "inspect":
+		id, err := singleArg(args)
+		if err != nil {
+			return err
+		}
+		return a.Inspect(ctx, id)
+	case "replay":
+		id, err := singleArg(args)
+		if err != nil {
+			return err
+		}
+		return a.Replay(ctx, id)
+	case "trace":
+		id, err := singleArg(args)
+		if err != nil {
+			return err
+		}
+		return a.Trace(ctx, id)
+	case "export":
+		id, err := singleArg(args)
+		if err != nil {
+			return err
+		}
+		return a.Export(ctx, id)
+	case "import":
+		path, err := singleArg(args)
+		if err != nil {
+			return err
+		}
+		return a.Import(ctx, path)
+	case "projections":
+		if len(args) < 2 {
+			return fmt.Errorf("%w: projections requires a subcommand (status, rebuild, verify-schema)", ErrUsage)
+		}
+		switch args[1] {
+		case "status":
+			if len(args) != 2 {
+				return fmt.Errorf("%w: projections status takes no arguments", ErrUsage)
+			}
+			return a.ProjectionStatus(ctx)
+		case "rebuild":
+			name, err := singleArg(args[1:])
+			if err != nil {
+				return err
+			}
+			return a.RebuildProjection(ctx, name)
+		case "verify-schema":
+			if len(args) > 3 {
+				return fmt.Errorf("%w: projections verify-schema takes at most one argument", ErrUsage)
+			}
+			var name string
+			if len(args) == 3 {
+				name = args[2]
+			}
+			return a.VerifyProjectionSchema(ctx, name)
+		default:
+			return fmt.Errorf("%w: unknown projections subcommand %q", ErrUsage, args[1])
+		}
+	case "constraints":
+		if len(args) != 2 {
+			return fmt.Errorf("%w: constraints requires a subcommand (stats, orphans)", ErrUsage)
+		}
+		switch args[1] {
+		case "stats":
+			return a.ConstraintStats(ctx)
+		case "orphans":
+			return a.OrphanedConstraints(ctx)
+		default:
+			return fmt.Errorf("%w: unknown constraints subcommand %q", ErrUsage, args[1])
+		}
+	case "prune-commands":
+		if len(args) != 1 {
+			return fmt.Errorf("%w: prune-commands takes no arguments", ErrUsage)
+		}
+		return a.PruneCommands(ctx)
+	case "compact":
+		if len(args) != 1 {
+			return fmt.Errorf("%w: compact takes no arguments", ErrUsage)
+		}
+		return a.Compact(ctx)
+	default:
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, args[0])
+	}
+}
+
+// singleArg returns the only argument of a command.
+func singleArg(args []string) (string, error) {
+	if len(args) != 2 || args[1] == "" {
+		return "", fmt.Errorf("%w: %s requires exactly one argument", ErrUsage, args[0])
+	}
+	return args[1], nil
+}
@@ -0,0 +1,409 @@
+package admin_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	accountdomain "github.com/plaenen/eventstore/examples/bankaccount/domain"
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/admin"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"github.com/plaenen/eventstore/pkg/store/sqlite/migrate"
+)
+
+func newAccountStore(t *testing.T) *sqlite.EventStore {
+	t.Helper()
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithDSN(filepath.Join(t.TempDir(), "events.db")))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	t.Cleanup(func() { eventStore.Close() })
+
+	agg := accountdomain.NewAccount("acc-1")
+	if err := agg.ApplyAccountOpenedEvent(&accountv1.AccountOpenedEvent{
+		AccountId:      "acc-1",
+		OwnerName:      "Alice",
+		InitialBalance: "100.00",
+	}); err != nil {
+		t.Fatalf("failed to open account: %v", err)
+	}
+	if err := agg.ApplyMoneyDepositedEvent(&accountv1.MoneyDepositedEvent{
+		AccountId:  "acc-1",
+		Amount:     "50.00",
+		NewBalance: "150.00",
+	}); err != nil {
+		t.Fatalf("failed to deposit: %v", err)
+	}
+
+	repo := accountv1.NewAccountRepository(eventStore, accountdomain.NewAccount)
+	if err := repo.Save(agg); err != nil {
+		t.Fatalf("failed to save account: %v", err)
+	}
+	return eventStore
+}
+
+func newAdmin(eventStore *sqlite.EventStore, out *bytes.Buffer, opts ...admin.Option) *admin.Admin {
+	opts = append([]admin.Option{
+		admin.WithOutput(out),
+		admin.WithEventRegistry(accountv1.EventRegistry),
+		admin.WithAggregate("Account", func(id string) domain.Aggregate {
+			return accountdomain.NewAccount(id)
+		}),
+	}, opts...)
+	return admin.New(eventStore, opts...)
+}
+
+func TestAdmin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Inspect", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		var out bytes.Buffer
+
+		if err := newAdmin(eventStore, &out).Run(ctx, []string{"inspect", "acc-1"}); err != nil {
+			t.Fatalf("inspect failed: %v", err)
+		}
+
+		output := out.String()
+		for _, want := range []string{
+			"Aggregate acc-1 (Account), version 2",
+			accountv1.AccountOpenedEventType,
+			accountv1.MoneyDepositedEventType,
+			"Alice",
+			"Snapshot: none",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected output to contain %q, got:\n%s", want, output)
+			}
+		}
+	})
+
+	t.Run("InspectUnknownAggregate", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		var out bytes.Buffer
+
+		err := newAdmin(eventStore, &out).Run(ctx, []string{"inspect", "missing"})
+		if !errors.Is(err, domain.ErrAggregateNotFound) {
+			t.Fatalf("expected ErrAggregateNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Trace", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		for i, aggregateID := range []string{"acc-1", "acc-2"} {
+			version, _ := eventStore.GetAggregateVersion(aggregateID)
+			err := eventStore.AppendEvents(ctx, aggregateID, version, []*domain.Event{{
+				ID:            "transfer-" + aggregateID,
+				AggregateID:   aggregateID,
+				AggregateType: "Account",
+				EventType:     "test.TransferBooked",
+				Data:          []byte("transfer"),
+				Version:       version + 1,
+				Timestamp:     time.Now(),
+				Metadata: domain.EventMetadata{
+					CorrelationID: "corr-transfer",
+					CausationID:   []string{"cmd-transfer", "transfer-acc-1"}[i],
+				},
+			}})
+			if err != nil {
+				t.Fatalf("failed to append to %s: %v", aggregateID, err)
+			}
+		}
+		var out bytes.Buffer
+
+		if err := newAdmin(eventStore, &out).Run(ctx, []string{"trace", "corr-transfer"}); err != nil {
+			t.Fatalf("trace failed: %v", err)
+		}
+
+		output := out.String()
+		for _, want := range []string{
+			"Correlation corr-transfer: 2 events across 2 aggregates",
+			"transfer-acc-1",
+			"cmd-transfer",
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected output to contain %q, got:\n%s", want, output)
+			}
+		}
+		if strings.Index(output, "acc-1") > strings.Index(output, "acc-2") {
+			t.Errorf("expected events in position order, got:\n%s", output)
+		}
+
+		if err := newAdmin(eventStore, &out).Run(ctx, []string{"trace", "corr-unknown"}); err == nil {
+			t.Error("expected an unknown correlation ID to fail")
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		var out bytes.Buffer
+
+		if err := newAdmin(eventStore, &out).Run(ctx, []string{"replay", "acc-1"}); err != nil {
+			t.Fatalf("replay failed: %v", err)
+		}
+
+		output := out.String()
+		if !strings.Contains(output, "replayed 2 events") || !strings.Contains(output, `"balance": "150.00"`) {
+			t.Errorf("unexpected replay output:\n%s", output)
+		}
+	})
+
+	t.Run("ReplayRequiresRegisteredAggregate", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		var out bytes.Buffer
+
+		err := admin.New(eventStore, admin.WithOutput(&out)).Run(ctx, []string{"replay", "acc-1"})
+		if err == nil || !strings.Contains(err.Error(), "not registered") {
+			t.Fatalf("expected unregistered aggregate error, got %v", err)
+		}
+	})
+
+	t.Run("Projections", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+		if err != nil {
+			t.Fatalf("failed to create checkpoint store: %v", err)
+		}
+
+		var opened int
+		built, err := sqlite.NewSQLiteProjectionBuilder("accounts", eventStore.DB(), checkpointStore, eventStore).
+			OnWithTx(accountv1.AccountOpenedEventType, func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+				opened++
+				return nil
+			}).
+			Build()
+		if err != nil {
+			t.Fatalf("failed to build projection: %v", err)
+		}
+		projection := built.(*sqlite.SQLiteProjection)
+
+		var out bytes.Buffer
+		cli := newAdmin(eventStore, &out, admin.WithProjection(projection))
+
+		if err := cli.Run(ctx, []string{"projections", "rebuild", "accounts"}); err != nil {
+			t.Fatalf("rebuild failed: %v", err)
+		}
+		if opened != 1 {
+			t.Errorf("expected 1 handled event, got %d", opened)
+		}
+		if !strings.Contains(out.String(), "Rebuilt projection accounts") {
+			t.Errorf("unexpected rebuild output:\n%s", out.String())
+		}
+
+		out.Reset()
+		if err := cli.Run(ctx, []string{"projections", "status"}); err != nil {
+			t.Fatalf("status failed: %v", err)
+		}
+		output := out.String()
+		if !strings.Contains(output, "Event store: 2 events") {
+			t.Errorf("expected event count, got:\n%s", output)
+		}
+		var row string
+		for _, line := range strings.Split(output, "\n") {
+			if strings.HasPrefix(line, "accounts ") {
+				row = line
+			}
+		}
+		if !strings.Contains(row, string(store.ProjectionStatusReady)) {
+			t.Errorf("expected READY accounts projection, got:\n%s", output)
+		}
+
+		err = cli.Run(ctx, []string{"projections", "rebuild", "unknown"})
+		if err == nil || !strings.Contains(err.Error(), "not registered") {
+			t.Errorf("expected unregistered projection error, got %v", err)
+		}
+	})
+
+	t.Run("VerifySchema", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		var out bytes.Buffer
+		cli := newAdmin(eventStore, &out,
+			admin.WithProjection(&driftingProjection{name: "balances"}),
+			admin.WithProjection(&driftingProjection{name: "owners", drifts: []migrate.SchemaDrift{
+				{Table: "owners", Kind: migrate.DriftMissingColumn, Name: "email", Expected: "TEXT NOT NULL"},
+			}}),
+		)
+
+		if err := cli.Run(ctx, []string{"projections", "verify-schema", "balances"}); err != nil {
+			t.Fatalf("verify-schema failed: %v", err)
+		}
+		if !strings.Contains(out.String(), "balances  OK") {
+			t.Errorf("expected balances to be OK, got:\n%s", out.String())
+		}
+
+		out.Reset()
+		err := cli.Run(ctx, []string{"projections", "verify-schema"})
+		if !errors.Is(err, migrate.ErrSchemaDrift) || !strings.Contains(err.Error(), "owners") {
+			t.Errorf("expected drift in owners, got %v", err)
+		}
+		if !strings.Contains(out.String(), "owners    owners: missing column email (TEXT NOT NULL)") {
+			t.Errorf("expected owners drift, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("PruneCommands", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		_, err := eventStore.AppendEventsIdempotent(ctx, "acc-2", 0, []*domain.Event{{
+			ID:            "evt-acc-2",
+			AggregateID:   "acc-2",
+			AggregateType: "Account",
+			EventType:     accountv1.AccountOpenedEventType,
+			Version:       1,
+			Timestamp:     time.Now(),
+			Data:          []byte("data"),
+		}}, "cmd-1", -time.Hour)
+		if err != nil {
+			t.Fatalf("failed to append events: %v", err)
+		}
+
+		var out bytes.Buffer
+		if err := newAdmin(eventStore, &out).Run(ctx, []string{"prune-commands"}); err != nil {
+			t.Fatalf("prune-commands failed: %v", err)
+		}
+		if !strings.Contains(out.String(), "Removed 1 expired command records") {
+			t.Errorf("unexpected prune output:\n%s", out.String())
+		}
+	})
+
+	t.Run("Constraints", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		claimEmail := func(aggregateID, email string) {
+			t.Helper()
+			err := eventStore.AppendEvents(ctx, aggregateID, 0, []*domain.Event{{
+				ID:                "evt-" + aggregateID,
+				AggregateID:       aggregateID,
+				AggregateType:     "Account",
+				EventType:         accountv1.AccountOpenedEventType,
+				Version:           1,
+				Timestamp:         time.Now(),
+				Data:              []byte("data"),
+				UniqueConstraints: []domain.UniqueConstraint{{IndexName: "email", Value: email, Operation: domain.ConstraintClaim}},
+			}})
+			if err != nil {
+				t.Fatalf("failed to append events: %v", err)
+			}
+		}
+		claimEmail("acc-2", "bob@example.com")
+		claimEmail("acc-3", "carol@example.com")
+
+		// Deleting an account behind the store's back leaves its email taken
+		if _, err := eventStore.DB().Exec("DELETE FROM events WHERE aggregate_id = ?", "acc-3"); err != nil {
+			t.Fatalf("failed to delete events: %v", err)
+		}
+
+		var out bytes.Buffer
+		cli := newAdmin(eventStore, &out)
+		if err := cli.Run(ctx, []string{"constraints", "stats"}); err != nil {
+			t.Fatalf("constraints stats failed: %v", err)
+		}
+		if !strings.Contains(out.String(), "email ") || !strings.Contains(out.String(), " 2 ") {
+			t.Errorf("expected 2 email claims, got:\n%s", out.String())
+		}
+
+		out.Reset()
+		if err := cli.Run(ctx, []string{"constraints", "orphans"}); err != nil {
+			t.Fatalf("constraints orphans failed: %v", err)
+		}
+		output := out.String()
+		if !strings.Contains(output, "carol@example.com") || !strings.Contains(output, "acc-3") || strings.Contains(output, "bob@example.com") {
+			t.Errorf("expected only acc-3's email to be orphaned, got:\n%s", output)
+		}
+	})
+
+	t.Run("Compact", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		var out bytes.Buffer
+
+		if err := newAdmin(eventStore, &out).Run(ctx, []string{"compact"}); err != nil {
+			t.Fatalf("compact failed: %v", err)
+		}
+		if !strings.Contains(out.String(), "WAL: ") || !strings.HasSuffix(out.String(), "-> 0 bytes\n") {
+			t.Errorf("expected truncated WAL, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("ExportImport", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		var bundle bytes.Buffer
+		redact := sqlite.WithEventRedactor(func(event *domain.Event) error {
+			event.Metadata.PrincipalID = "redacted"
+			return nil
+		})
+		if err := newAdmin(eventStore, &bundle, admin.WithExportOptions(redact)).Run(ctx, []string{"export", "acc-1"}); err != nil {
+			t.Fatalf("export failed: %v", err)
+		}
+		path := filepath.Join(t.TempDir(), "acc-1.json")
+		if err := os.WriteFile(path, bundle.Bytes(), 0o600); err != nil {
+			t.Fatalf("failed to write bundle: %v", err)
+		}
+
+		scratch, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create scratch store: %v", err)
+		}
+		defer scratch.Close()
+		var out bytes.Buffer
+		if err := newAdmin(scratch, &out).Run(ctx, []string{"import", path}); err != nil {
+			t.Fatalf("import failed: %v", err)
+		}
+		if out.String() != "Imported acc-1: 2 events, 0 snapshots\n" {
+			t.Errorf("unexpected output: %q", out.String())
+		}
+
+		out.Reset()
+		if err := newAdmin(scratch, &out).Run(ctx, []string{"replay", "acc-1"}); err != nil {
+			t.Fatalf("replay of imported aggregate failed: %v", err)
+		}
+		if !strings.Contains(out.String(), "150.00") {
+			t.Errorf("expected the imported balance, got:\n%s", out.String())
+		}
+		events, err := scratch.LoadEvents(ctx, "acc-1", 0)
+		if err != nil || events[0].Metadata.PrincipalID != "redacted" {
+			t.Errorf("expected redacted events, got %v (%v)", events, err)
+		}
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		eventStore := newAccountStore(t)
+		cli := admin.New(eventStore)
+
+		for _, args := range [][]string{
+			nil,
+			{"unknown"},
+			{"inspect"},
+			{"inspect", "a", "b"},
+			{"export"},
+			{"import"},
+			{"projections"},
+			{"projections", "rebuild"},
+			{"projections", "verify-schema", "a", "b"},
+			{"constraints"},
+			{"constraints", "unknown"},
+			{"compact", "now"},
+		} {
+			if err := cli.Run(ctx, args); !errors.Is(err, admin.ErrUsage) {
+				t.Errorf("expected usage error for %v, got %v", args, err)
+			}
+		}
+	})
+}
+
+// driftingProjection is a projection whose schema check reports fixed drifts.
+type driftingProjection struct {
+	name   string
+	drifts []migrate.SchemaDrift
+}
+
+func (p *driftingProjection) Name() string                                          { return p.name }
+func (p *driftingProjection) Rebuild(context.Context, ...store.RebuildOption) error { return nil }
+func (p *driftingProjection) VerifySchema() ([]migrate.SchemaDrift, error)          { return p.drifts, nil }
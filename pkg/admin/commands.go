@@ -0,0 +1,410 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"github.com/plaenen/eventstore/pkg/store/sqlite/migrate"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Inspect prints the events and latest snapshot of an aggregate.
+func (a *Admin) Inspect(ctx context.Context, aggregateID string) error {
+	events, err := a.loadEvents(ctx, aggregateID)
+	if err != nil {
+		return err
+	}
+
+	last := events[len(events)-1]
+	fmt.Fprintf(a.out, "Aggregate %s (%s), version %d\n\n", aggregateID, last.AggregateType, last.Version)
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tPOSITION\tTIMESTAMP\tEVENT TYPE\tEVENT ID\tPAYLOAD")
+	for _, event := range events {
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\n",
+			event.Version, event.Position, event.Timestamp.UTC().Format(time.RFC3339),
+			event.EventType, event.ID, a.formatPayload(event))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	snapshot, err := sqlite.NewSnapshotStore(a.eventStore.DB()).GetLatestSnapshot(aggregateID)
+	switch {
+	case errors.Is(err, domain.ErrSnapshotNotFound):
+		fmt.Fprintln(a.out, "\nSnapshot: none")
+	case err != nil:
+		return err
+	default:
+		fmt.Fprintf(a.out, "\nSnapshot: version %d, created %s, %d bytes\n",
+			snapshot.Version, snapshot.CreatedAt.UTC().Format(time.RFC3339), len(snapshot.Data))
+	}
+
+	return nil
+}
+
+// Replay rebuilds an aggregate from its events and prints its state.
+// The aggregate type must be registered with WithAggregate and its events
+// with WithEventRegistry.
+func (a *Admin) Replay(ctx context.Context, aggregateID string) error {
+	events, err := a.loadEvents(ctx, aggregateID)
+	if err != nil {
+		return err
+	}
+
+	aggregateType := events[0].AggregateType
+	factory, ok := a.aggregates[aggregateType]
+	if !ok {
+		return fmt.Errorf("aggregate type %s is not registered (use admin.WithAggregate)", aggregateType)
+	}
+
+	agg := factory(aggregateID)
+	for _, event := range events {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := a.registry.Apply(agg, event); err != nil {
+			return fmt.Errorf("failed to apply event %s (version %d): %w", event.ID, event.Version, err)
+		}
+	}
+
+	state, err := json.MarshalIndent(agg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format aggregate state: %w", err)
+	}
+
+	fmt.Fprintf(a.out, "Aggregate %s (%s), version %d, replayed %d events\n\n",
+		aggregateID, aggregateType, events[len(events)-1].Version, len(events))
+	fmt.Fprintln(a.out, string(state))
+	return nil
+}
+
+// Trace prints the events sharing correlationID in global position order,
+// across aggregates, with the event that caused each: the full fan-out of one
+// user action.
+func (a *Admin) Trace(ctx context.Context, correlationID string) error {
+	events, err := a.eventStore.LoadEventsByCorrelationID(ctx, correlationID)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("no events with correlation ID %s", correlationID)
+	}
+
+	aggregates := make(map[string]bool)
+	for _, event := range events {
+		aggregates[event.AggregateID] = true
+	}
+	fmt.Fprintf(a.out, "Correlation %s: %d events across %d aggregates\n\n", correlationID, len(events), len(aggregates))
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "POSITION\tTIMESTAMP\tAGGREGATE\tVERSION\tEVENT TYPE\tEVENT ID\tCAUSED BY")
+	for _, event := range events {
+		causation := event.Metadata.CausationID
+		if causation == "" {
+			causation = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			event.Position, event.Timestamp.UTC().Format(time.RFC3339), event.AggregateID,
+			event.Version, event.EventType, event.ID, causation)
+	}
+	return w.Flush()
+}
+
+// Export writes an aggregate's events, snapshots and unique constraint claims
+// as JSON (see sqlite.AggregateExport), redacted by WithExportOptions.
+func (a *Admin) Export(ctx context.Context, aggregateID string) error {
+	return a.eventStore.ExportAggregate(ctx, aggregateID, a.out, a.exportOpts...)
+}
+
+// Import loads a bundle written by Export from path, typically into a scratch
+// store opened with a separate -db to reproduce a customer's problem.
+func (a *Admin) Import(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bundle, err := a.eventStore.ImportAggregate(ctx, f)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Imported %s: %d events, %d snapshots\n",
+		bundle.AggregateID, len(bundle.Events), len(bundle.Snapshots))
+	return nil
+}
+
+// ProjectionStatus prints the status and checkpoint of every projection known
+// to the projection database or registered with WithProjection.
+func (a *Admin) ProjectionStatus(ctx context.Context) error {
+	checkpointStore, err := sqlite.NewCheckpointStore(a.projectionDB)
+	if err != nil {
+		return err
+	}
+	checkpoints, err := checkpointStore.LoadAll()
+	if err != nil {
+		return err
+	}
+	statusStore, err := sqlite.NewProjectionStatusStore(a.projectionDB)
+	if err != nil {
+		return err
+	}
+	states, err := statusStore.List()
+	if err != nil {
+		return err
+	}
+	total, err := a.eventStore.CountEvents()
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	byCheckpoint := make(map[string]*store.ProjectionCheckpoint)
+	for _, checkpoint := range checkpoints {
+		names[checkpoint.ProjectionName] = true
+		byCheckpoint[checkpoint.ProjectionName] = checkpoint
+	}
+	byState := make(map[string]*store.ProjectionState)
+	for _, state := range states {
+		names[state.ProjectionName] = true
+		byState[state.ProjectionName] = state
+	}
+	for name := range a.projections {
+		names[name] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	fmt.Fprintf(a.out, "Event store: %d events\n\n", total)
+	if len(sorted) == 0 {
+		fmt.Fprintln(a.out, "No projections found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECTION\tSTATUS\tPOSITION\tLAST EVENT\tUPDATED\tMESSAGE")
+	for _, name := range sorted {
+		status, message := "-", ""
+		if state := byState[name]; state != nil {
+			status, message = string(state.Status), state.Message
+		}
+		position, lastEvent, updated := "-", "-", "-"
+		if checkpoint := byCheckpoint[name]; checkpoint != nil {
+			position = fmt.Sprint(checkpoint.Position)
+			lastEvent = checkpoint.LastEventID
+			updated = checkpoint.UpdatedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, status, position, lastEvent, updated, message)
+	}
+	return w.Flush()
+}
+
+// RebuildProjection rebuilds a projection registered with WithProjection,
+// printing progress when the projection reports it.
+func (a *Admin) RebuildProjection(ctx context.Context, name string) error {
+	projection, ok := a.projections[name]
+	if !ok {
+		return fmt.Errorf("projection %s is not registered (use admin.WithProjection)", name)
+	}
+
+	// Report skipped and quarantined events, unless the caller collects them
+	opts := append([]store.RebuildOption{store.WithRebuildReport(func(report store.RebuildReport) {
+		if summary := report.Summary(); summary != "" {
+			fmt.Fprintf(a.out, "%s: %s\n", name, summary)
+		}
+	})}, a.rebuildOpts...)
+
+	started := time.Now()
+	if p, ok := projection.(progressRebuilder); ok {
+		progress, errs := p.RebuildWithProgress(ctx, opts...)
+		for update := range progress {
+			fmt.Fprintf(a.out, "%s: %d/%d events, %.0f events/s\n",
+				name, update.EventsProcessed, update.TotalEvents, update.EventsPerSecond)
+		}
+		if err := <-errs; err != nil {
+			return fmt.Errorf("failed to rebuild projection %s: %w", name, err)
+		}
+	} else if err := projection.Rebuild(ctx, opts...); err != nil {
+		return fmt.Errorf("failed to rebuild projection %s: %w", name, err)
+	}
+
+	fmt.Fprintf(a.out, "Rebuilt projection %s in %s\n", name, time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// VerifyProjectionSchema compares the tables of the projection called name, or
+// of every projection registered with WithProjection if name is empty, with
+// the schema their migrations describe. It prints the differences and returns
+// an error wrapping migrate.ErrSchemaDrift if there are any.
+func (a *Admin) VerifyProjectionSchema(ctx context.Context, name string) error {
+	names := []string{name}
+	if name == "" {
+		names = names[:0]
+		for name := range a.projections {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		fmt.Fprintln(a.out, "No projections registered")
+		return nil
+	}
+
+	var drifted []string
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		projection, ok := a.projections[name]
+		if !ok {
+			return fmt.Errorf("projection %s is not registered (use admin.WithProjection)", name)
+		}
+		verifier, ok := projection.(schemaVerifier)
+		if !ok {
+			fmt.Fprintf(w, "%s\tschema verification not supported\n", name)
+			continue
+		}
+		drifts, err := verifier.VerifySchema()
+		if err != nil {
+			return fmt.Errorf("failed to verify schema of projection %s: %w", name, err)
+		}
+		if len(drifts) == 0 {
+			fmt.Fprintf(w, "%s\tOK\n", name)
+			continue
+		}
+		drifted = append(drifted, name)
+		for _, drift := range drifts {
+			fmt.Fprintf(w, "%s\t%s\n", name, drift)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(drifted) > 0 {
+		return fmt.Errorf("%w in projections: %s", migrate.ErrSchemaDrift, strings.Join(drifted, ", "))
+	}
+	return nil
+}
+
+// ConstraintStats prints the number of claimed values and the oldest claim of
+// every unique constraint index.
+func (a *Admin) ConstraintStats(ctx context.Context) error {
+	stats, err := a.eventStore.ConstraintStats()
+	if err != nil {
+		return err
+	}
+	if len(stats) == 0 {
+		fmt.Fprintln(a.out, "No unique constraints claimed")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tCLAIMS\tOLDEST CLAIM")
+	for _, index := range stats {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", index.IndexName, index.Claims, index.OldestClaim.UTC().Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// OrphanedConstraints prints the claimed unique values whose owning aggregate
+// has no events. Such values stay taken until released.
+func (a *Admin) OrphanedConstraints(ctx context.Context) error {
+	orphans, err := a.eventStore.FindOrphanedConstraints()
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		fmt.Fprintln(a.out, "No orphaned constraints found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tVALUE\tAGGREGATE\tCLAIMED")
+	for _, orphan := range orphans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", orphan.IndexName, orphan.Value, orphan.AggregateID, orphan.ClaimedAt.UTC().Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// PruneCommands removes expired command idempotency records.
+func (a *Admin) PruneCommands(ctx context.Context) error {
+	removed, err := a.eventStore.CleanExpiredCommands()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "Removed %d expired command records\n", removed)
+	return nil
+}
+
+// Compact reclaims unused space and truncates the WAL, printing the storage
+// footprint before and after.
+func (a *Admin) Compact(ctx context.Context) error {
+	before, err := a.eventStore.Stats()
+	if err != nil {
+		return err
+	}
+	if err := a.eventStore.Compact(ctx); err != nil {
+		return err
+	}
+	after, err := a.eventStore.Stats()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "Database: %d -> %d bytes\n", before.DatabaseSizeBytes, after.DatabaseSizeBytes)
+	fmt.Fprintf(a.out, "WAL: %d -> %d bytes\n", before.WALSizeBytes, after.WALSizeBytes)
+	return nil
+}
+
+// loadEvents loads all events of an aggregate, failing if it has none.
+func (a *Admin) loadEvents(ctx context.Context, aggregateID string) ([]*domain.Event, error) {
+	events, err := a.eventStore.LoadEvents(ctx, aggregateID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: %s", domain.ErrAggregateNotFound, aggregateID)
+	}
+	return events, nil
+}
+
+// formatPayload renders an event payload as JSON when its type is known,
+// either from the registered event registries or the linked proto types.
+func (a *Admin) formatPayload(event *domain.Event) string {
+	var msg proto.Message
+	if decoded, err := a.registry.Decode(event); err == nil {
+		msg = decoded
+	} else if messageType, err := protoregistry.GlobalTypes.FindMessageByName(
+		protoreflect.FullName(domain.CanonicalEventType(event.EventType))); err == nil {
+		msg = messageType.New().Interface()
+		if err := proto.Unmarshal(event.Data, msg); err != nil {
+			msg = nil
+		}
+	}
+
+	if msg == nil {
+		return fmt.Sprintf("<%d bytes>", len(event.Data))
+	}
+	payload, err := protojson.Marshal(msg)
+	if err != nil {
+		return fmt.Sprintf("<%d bytes>", len(event.Data))
+	}
+	return string(payload)
+}
@@ -0,0 +1,141 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store"
+	"google.golang.org/protobuf/proto"
+)
+
+// AuditTrailNotFoundCode is the AppError code returned by the AuditTrail
+// query for an aggregate without events.
+const AuditTrailNotFoundCode = "NOT_FOUND"
+
+// AuditTrailSubject returns the subject of the built-in AuditTrail query of a
+// service, e.g. "account.v1.EventStoreService.AuditTrail".
+func AuditTrailSubject(service string) string {
+	return service + ".EventStoreService.AuditTrail"
+}
+
+// RegisterAuditTrail registers the built-in AuditTrail query on server. opts
+// configure how event payloads are summarized (see eventsourcing.AuditTrail);
+// register the service's event registry so entries carry decoded summaries
+// rather than payload sizes.
+//
+// The endpoint is not authorized by default: any client that can reach the
+// server reads the audit trail of every aggregate in eventStore. Guard it
+// with AuthorizeAuditTrail in ServerConfig.Middleware.
+func RegisterAuditTrail(server Server, service string, eventStore store.EventStore, opts ...eventsourcing.AuditOption) error {
+	return server.RegisterHandler(AuditTrailSubject(service), AuditTrailHandler(eventStore, opts...))
+}
+
+// AuditTrailHandler serves AuditTrailRequest from eventStore, one page of at
+// most limit entries (default 100, at most 1000) per request. Pages are read
+// by version (see store.LoadEventsPage), not by offset.
+func AuditTrailHandler(eventStore store.EventStore, opts ...eventsourcing.AuditOption) HandlerFunc {
+	return func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		req, ok := request.(*eventsourcing.AuditTrailRequest)
+		if !ok {
+			return eventsourcing.NewSimpleErrorResponse("INVALID_REQUEST",
+				fmt.Sprintf("expected AuditTrailRequest, got %T", request)), nil
+		}
+		if req.GetAggregateId() == "" {
+			return eventsourcing.NewSimpleErrorResponse("INVALID_REQUEST", "aggregate_id is required"), nil
+		}
+
+		limit := int(req.GetLimit())
+		if limit <= 0 {
+			limit = defaultReplayLimit
+		}
+		if limit > maxReplayLimit {
+			limit = maxReplayLimit
+		}
+
+		// One entry more than the page tells whether there is a next page
+		trail, err := eventsourcing.AuditTrail(eventStore, req.GetAggregateId(), append(opts[:len(opts):len(opts)],
+			eventsourcing.WithAuditFromVersion(req.GetFromVersion()),
+			eventsourcing.WithAuditLimit(limit+1))...)
+		if errors.Is(err, domain.ErrAggregateNotFound) {
+			return eventsourcing.NewSimpleErrorResponse(AuditTrailNotFoundCode,
+				fmt.Sprintf("aggregate %s not found", req.GetAggregateId())), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		page := &eventsourcing.AuditTrailResponse{}
+		if len(trail) > limit {
+			trail = trail[:limit]
+			page.HasMore = true
+		}
+		for _, entry := range trail {
+			page.Entries = append(page.Entries, eventsourcing.NewAuditTrailEntry(entry))
+		}
+
+		return eventsourcing.NewSuccessResponse(page)
+	}
+}
+
+// AuthorizeAuditTrail returns server middleware that calls authorize before
+// every AuditTrail request and rejects the request with ReplayForbiddenCode
+// when it returns an error. Other requests pass through untouched.
+func AuthorizeAuditTrail(authorize func(ctx context.Context, aggregateID string) error) HandlerMiddleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+			if req, ok := request.(*eventsourcing.AuditTrailRequest); ok {
+				if err := authorize(ctx, req.GetAggregateId()); err != nil {
+					return eventsourcing.NewSimpleErrorResponse(ReplayForbiddenCode, err.Error()), nil
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// AuditTrail fetches the complete audit trail of aggregateID from a service's
+// AuditTrail query, following pages until the last one.
+//
+// Example:
+//
+//	trail, err := cqrs.AuditTrail(ctx, transport, "account.v1", "acc-123")
+//	if err != nil {
+//	    return err
+//	}
+//	for _, entry := range trail {
+//	    fmt.Printf("v%d by %s: %s\n", entry.Version, entry.PrincipalID, entry.Summary)
+//	}
+func AuditTrail(ctx context.Context, transport Transport, service, aggregateID string) ([]eventsourcing.AuditEntry, error) {
+	subject := AuditTrailSubject(service)
+	var (
+		trail       []eventsourcing.AuditEntry
+		fromVersion int64
+	)
+	for {
+		resp, err := transport.Request(ctx, subject, &eventsourcing.AuditTrailRequest{
+			AggregateId: aggregateID,
+			FromVersion: fromVersion,
+		})
+		if err == nil {
+			err = resp.AsError()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("audit trail %s: %w", aggregateID, err)
+		}
+
+		page := &eventsourcing.AuditTrailResponse{}
+		if err := resp.UnpackData(page); err != nil {
+			return nil, fmt.Errorf("audit trail %s: %w", aggregateID, err)
+		}
+		for _, entry := range page.GetEntries() {
+			trail = append(trail, entry.ToAuditEntry())
+			fromVersion = entry.GetVersion()
+		}
+		if !page.GetHasMore() || len(page.GetEntries()) == 0 {
+			return trail, nil
+		}
+	}
+}
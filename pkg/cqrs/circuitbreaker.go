@@ -0,0 +1,175 @@
+package cqrs
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by transports instead of sending a request while
+// the circuit breaker of its subject is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through (normal operation)
+	CircuitClosed CircuitState = iota
+
+	// CircuitHalfOpen lets a single probe request through after the cooldown;
+	// its outcome closes or reopens the circuit
+	CircuitHalfOpen
+
+	// CircuitOpen fails requests fast with ErrCircuitOpen
+	CircuitOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker configures a circuit breaker per subject. When a backend is
+// down, every request would otherwise wait the full timeout; after
+// FailureThreshold consecutive failures the breaker opens and requests fail
+// fast until the cooldown has passed and a probe request succeeds.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failures (timeouts or
+	// transport errors) that opens the circuit (default: 5)
+	FailureThreshold int
+
+	// Cooldown is how long the circuit stays open before a probe request is
+	// let through (default: 30 seconds)
+	Cooldown time.Duration
+
+	// OnStateChange is called after the circuit of a subject changed state,
+	// e.g. to shed load while a backend is down (optional)
+	OnStateChange func(subject string, from, to CircuitState)
+}
+
+// CircuitBreakers tracks one circuit per subject (see CircuitBreaker).
+// Transports call Allow before a request and Record with its outcome.
+type CircuitBreakers struct {
+	config CircuitBreaker
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int       // Consecutive failures while closed
+	openedAt time.Time // When the circuit last opened
+	probing  bool      // A half-open probe is in flight
+}
+
+// NewCircuitBreakers creates the circuits of config, all closed.
+func NewCircuitBreakers(config CircuitBreaker) *CircuitBreakers {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	return &CircuitBreakers{
+		config:   config,
+		circuits: make(map[string]*circuit),
+	}
+}
+
+// Allow reports whether a request to subject may be sent, returning
+// ErrCircuitOpen if not. Once the cooldown of an open circuit has passed, the
+// next request is let through as the probe; others fail until it is recorded.
+func (b *CircuitBreakers) Allow(subject string) error {
+	b.mu.Lock()
+	c := b.circuit(subject)
+	from := c.state
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < b.config.Cooldown {
+			b.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		c.state = CircuitHalfOpen
+		c.probing = true
+	case CircuitHalfOpen:
+		if c.probing {
+			b.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		c.probing = true
+	}
+	to := c.state
+	b.mu.Unlock()
+
+	b.changed(subject, from, to)
+	return nil
+}
+
+// Record reports the outcome of a request Allow let through. A failure opens
+// the circuit once the threshold is reached, or at once for a probe; a
+// success closes it.
+func (b *CircuitBreakers) Record(subject string, failed bool) {
+	b.mu.Lock()
+	c := b.circuit(subject)
+	from := c.state
+	switch {
+	case !failed:
+		c.state = CircuitClosed
+		c.failures = 0
+		c.probing = false
+	case c.state == CircuitHalfOpen:
+		c.state = CircuitOpen
+		c.openedAt = time.Now()
+		c.probing = false
+	case c.state == CircuitClosed:
+		c.failures++
+		if c.failures >= b.config.FailureThreshold {
+			c.state = CircuitOpen
+			c.openedAt = time.Now()
+			c.failures = 0
+		}
+	}
+	to := c.state
+	b.mu.Unlock()
+
+	b.changed(subject, from, to)
+}
+
+// State returns the state of the circuit of subject.
+func (b *CircuitBreakers) State(subject string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.circuits[subject]; ok {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+// circuit returns the circuit of subject, creating it closed. b.mu must be held.
+func (b *CircuitBreakers) circuit(subject string) *circuit {
+	c, ok := b.circuits[subject]
+	if !ok {
+		c = &circuit{}
+		b.circuits[subject] = c
+	}
+	return c
+}
+
+// changed reports a state change to the OnStateChange callback.
+func (b *CircuitBreakers) changed(subject string, from, to CircuitState) {
+	if from != to && b.config.OnStateChange != nil {
+		b.config.OnStateChange(subject, from, to)
+	}
+}
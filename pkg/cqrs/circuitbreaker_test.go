@@ -0,0 +1,82 @@
+package cqrs_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+)
+
+func TestCircuitBreakers(t *testing.T) {
+	var transitions []string
+	breakers := cqrs.NewCircuitBreakers(cqrs.CircuitBreaker{
+		FailureThreshold: 3,
+		Cooldown:         50 * time.Millisecond,
+		OnStateChange: func(subject string, from, to cqrs.CircuitState) {
+			transitions = append(transitions, fmt.Sprintf("%s:%s->%s", subject, from, to))
+		},
+	})
+
+	fail := func(subject string) {
+		t.Helper()
+		if err := breakers.Allow(subject); err != nil {
+			t.Fatalf("expected %s to be allowed, got %v", subject, err)
+		}
+		breakers.Record(subject, true)
+	}
+
+	// A success resets the consecutive failure count
+	fail("a")
+	fail("a")
+	breakers.Record("a", false)
+	fail("a")
+	fail("a")
+	if state := breakers.State("a"); state != cqrs.CircuitClosed {
+		t.Fatalf("expected closed below the threshold, got %s", state)
+	}
+	fail("a")
+	if err := breakers.Allow("a"); !errors.Is(err, cqrs.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	// Subjects have their own circuits
+	if err := breakers.Allow("b"); err != nil {
+		t.Fatalf("expected another subject to be allowed, got %v", err)
+	}
+
+	// After the cooldown a single probe goes through; a failed probe reopens
+	time.Sleep(60 * time.Millisecond)
+	if err := breakers.Allow("a"); err != nil {
+		t.Fatalf("expected the probe to be allowed, got %v", err)
+	}
+	if err := breakers.Allow("a"); !errors.Is(err, cqrs.ErrCircuitOpen) {
+		t.Fatalf("expected requests to wait for the probe, got %v", err)
+	}
+	breakers.Record("a", true)
+	if state := breakers.State("a"); state != cqrs.CircuitOpen {
+		t.Fatalf("expected a failed probe to reopen the circuit, got %s", state)
+	}
+
+	// A successful probe closes it
+	time.Sleep(60 * time.Millisecond)
+	if err := breakers.Allow("a"); err != nil {
+		t.Fatalf("expected the probe to be allowed, got %v", err)
+	}
+	breakers.Record("a", false)
+	if err := breakers.Allow("a"); err != nil {
+		t.Fatalf("expected the closed circuit to allow requests, got %v", err)
+	}
+
+	want := []string{
+		"a:closed->open",
+		"a:open->half-open",
+		"a:half-open->open",
+		"a:open->half-open",
+		"a:half-open->closed",
+	}
+	if fmt.Sprint(transitions) != fmt.Sprint(want) {
+		t.Errorf("expected transitions %v, got %v", want, transitions)
+	}
+}
@@ -0,0 +1,72 @@
+package cqrs
+
+import (
+	"mime"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// HeaderContentType carries the Codec content type of a request or response
+// payload. Payloads without it are protobuf.
+const HeaderContentType = "Content-Type"
+
+// Codec encodes request and response payloads on the wire.
+type Codec interface {
+	// ContentType identifies the encoding in HeaderContentType
+	// (e.g., "application/json")
+	ContentType() string
+
+	// Marshal encodes msg
+	Marshal(msg proto.Message) ([]byte, error)
+
+	// Unmarshal decodes data into msg
+	Unmarshal(data []byte, msg proto.Message) error
+}
+
+var (
+	// ProtoCodec encodes payloads as binary protobuf. It is the default.
+	ProtoCodec Codec = protoCodec{}
+
+	// JSONCodec encodes payloads as protobuf JSON, for debugging and for
+	// clients without protobuf support. Field names use the JSON mapping
+	// (lowerCamelCase); unknown fields are rejected.
+	JSONCodec Codec = jsonCodec{}
+)
+
+// CodecForContentType returns the codec for a HeaderContentType value, ignoring
+// parameters such as charset. An empty content type selects ProtoCodec.
+func CodecForContentType(contentType string) (Codec, bool) {
+	if contentType == "" {
+		return ProtoCodec, true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	switch mediaType {
+	case ProtoCodec.ContentType(), "application/x-protobuf":
+		return ProtoCodec, true
+	case JSONCodec.ContentType():
+		return JSONCodec, true
+	}
+	return nil, false
+}
+
+type protoCodec struct{}
+
+func (protoCodec) ContentType() string { return "application/protobuf" }
+
+func (protoCodec) Marshal(msg proto.Message) ([]byte, error) { return proto.Marshal(msg) }
+
+func (protoCodec) Unmarshal(data []byte, msg proto.Message) error { return proto.Unmarshal(data, msg) }
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Marshal(msg proto.Message) ([]byte, error) { return protojson.Marshal(msg) }
+
+func (jsonCodec) Unmarshal(data []byte, msg proto.Message) error {
+	return protojson.Unmarshal(data, msg)
+}
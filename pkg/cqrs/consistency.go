@@ -0,0 +1,290 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store"
+	"google.golang.org/protobuf/proto"
+)
+
+// Transport headers used to implement read-your-writes consistency.
+const (
+	// HeaderReadAfterPosition carries the global position a query must observe
+	HeaderReadAfterPosition = "Read-After-Position"
+
+	// HeaderResultPosition carries the global position reached by a command
+	HeaderResultPosition = "Result-Position"
+)
+
+// ConsistencyTimeoutCode is the AppError code returned when a projection did not
+// catch up to the requested position within the allowed wait.
+const ConsistencyTimeoutCode = "CONSISTENCY_TIMEOUT"
+
+// ConsistencyMode selects how fresh the data served by a query must be.
+type ConsistencyMode int
+
+const (
+	// ConsistencyEventual serves the query immediately from whatever the
+	// projection has processed so far (default)
+	ConsistencyEventual ConsistencyMode = iota
+
+	// ConsistencyReadYourWrites waits until the projection has processed the
+	// given global position before serving the query
+	ConsistencyReadYourWrites
+)
+
+// QueryConsistency describes the consistency requirement of a query.
+type QueryConsistency struct {
+	Mode          ConsistencyMode
+	AfterPosition int64
+}
+
+// Eventual returns the default, eventually consistent query mode.
+func Eventual() QueryConsistency {
+	return QueryConsistency{Mode: ConsistencyEventual}
+}
+
+// ReadYourWrites returns a consistency requirement that makes the query wait
+// until the projection has processed afterPosition, typically the position
+// returned by a previous command.
+func ReadYourWrites(afterPosition int64) QueryConsistency {
+	return QueryConsistency{Mode: ConsistencyReadYourWrites, AfterPosition: afterPosition}
+}
+
+type consistencyKey struct{}
+
+// WithConsistency attaches a consistency requirement to the context of a query.
+//
+// Example:
+//
+//	tracker := &cqrs.PositionTracker{}
+//	ctx = cqrs.WithPositionTracker(ctx, tracker)
+//	sdk.OpenAccount(ctx, cmd)
+//
+//	qctx := cqrs.WithConsistency(ctx, cqrs.ReadYourWrites(tracker.Position()))
+//	view, _ := sdk.GetAccount(qctx, query)
+func WithConsistency(ctx context.Context, c QueryConsistency) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, c)
+}
+
+// ConsistencyFromContext returns the consistency requirement of the context.
+// Defaults to Eventual.
+func ConsistencyFromContext(ctx context.Context) QueryConsistency {
+	if c, ok := ctx.Value(consistencyKey{}).(QueryConsistency); ok {
+		return c
+	}
+	return Eventual()
+}
+
+// ConsistencyFromHeader parses the HeaderReadAfterPosition value.
+// An empty value yields Eventual.
+func ConsistencyFromHeader(value string) (QueryConsistency, error) {
+	if value == "" {
+		return Eventual(), nil
+	}
+	position, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return Eventual(), fmt.Errorf("invalid %s header %q: %w", HeaderReadAfterPosition, value, err)
+	}
+	return ReadYourWrites(position), nil
+}
+
+// PositionTracker records the highest global position reported by commands.
+// It is safe for concurrent use.
+type PositionTracker struct {
+	mu       sync.Mutex
+	position int64
+}
+
+// Observe records a position, keeping the highest one seen.
+func (t *PositionTracker) Observe(position int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if position > t.position {
+		t.position = position
+	}
+}
+
+// Position returns the highest position observed so far.
+func (t *PositionTracker) Position() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.position
+}
+
+type positionTrackerKey struct{}
+
+// WithPositionTracker attaches a tracker to the context.
+// On the client side, transports report the position returned by each command
+// to it; on the server side, handlers report the position of the events they
+// appended via ReportPosition.
+func WithPositionTracker(ctx context.Context, tracker *PositionTracker) context.Context {
+	return context.WithValue(ctx, positionTrackerKey{}, tracker)
+}
+
+// PositionTrackerFromContext returns the tracker attached to the context, if any.
+func PositionTrackerFromContext(ctx context.Context) (*PositionTracker, bool) {
+	tracker, ok := ctx.Value(positionTrackerKey{}).(*PositionTracker)
+	return tracker, ok
+}
+
+// ReportPosition reports the global position reached by a command to the
+// tracker in the context. It is a no-op when no tracker is attached.
+//
+// Command handlers call it with domain.CommandResult.Position so the position
+// is returned to the client:
+//
+//	result, err := repo.SaveWithCommand(agg, cmdID)
+//	cqrs.ReportPosition(ctx, result.Position)
+func ReportPosition(ctx context.Context, position int64) {
+	if tracker, ok := PositionTrackerFromContext(ctx); ok {
+		tracker.Observe(position)
+	}
+}
+
+// DefaultConsistencyWait bounds WaitForPosition and ConsistentRead when no
+// positive maxWait is given.
+const DefaultConsistencyWait = 5 * time.Second
+
+// ConsistencyOption configures WaitForPosition and ConsistentRead.
+type ConsistencyOption func(*consistencyConfig)
+
+type consistencyConfig struct {
+	eventStore store.EventStore
+	eventTypes map[string]bool
+}
+
+// WithHandledEventTypes declares the event types the projection handles.
+// A projection checkpoint only advances on events the projection handles, so
+// without it a wait for the position of events the projection ignores lasts
+// until maxWait. With it, the wait also completes once eventStore holds no
+// event of these types between the checkpoint and the requested position.
+//
+// Example:
+//
+//	cqrs.ConsistentRead(checkpoints, "accounts", time.Second,
+//		cqrs.WithHandledEventTypes(eventStore, projection.EventTypes()...))
+func WithHandledEventTypes(eventStore store.EventStore, eventTypes ...string) ConsistencyOption {
+	return func(c *consistencyConfig) {
+		c.eventStore = eventStore
+		c.eventTypes = make(map[string]bool, len(eventTypes))
+		for _, eventType := range eventTypes {
+			c.eventTypes[domain.CanonicalEventType(eventType)] = true
+		}
+	}
+}
+
+// WaitForPosition polls the projection checkpoint until it reaches position,
+// or returns an error once maxWait (DefaultConsistencyWait if not positive)
+// elapses or ctx is cancelled.
+func WaitForPosition(ctx context.Context, checkpoints store.CheckpointStore, projectionName string, position int64, maxWait time.Duration, opts ...ConsistencyOption) error {
+	config := &consistencyConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	if maxWait <= 0 {
+		maxWait = DefaultConsistencyWait
+	}
+
+	deadline := time.NewTimer(maxWait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	var current int64
+	var lastErr error
+	for {
+		// A missing checkpoint means the projection has not processed anything yet
+		checkpoint, err := checkpoints.Load(projectionName)
+		if err != nil && !errors.Is(err, store.ErrCheckpointNotFound) {
+			lastErr = err
+		} else {
+			if err == nil {
+				current = checkpoint.Position
+			}
+			if current >= position {
+				return nil
+			}
+			if config.eventStore != nil {
+				pending, err := config.pendingEvents(ctx, current, position)
+				if err != nil {
+					lastErr = err
+				} else if !pending {
+					return nil
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			if lastErr != nil {
+				return fmt.Errorf("projection %s at position %d did not reach %d within %v: %w",
+					projectionName, current, position, maxWait, lastErr)
+			}
+			return fmt.Errorf("projection %s at position %d did not reach %d within %v",
+				projectionName, current, position, maxWait)
+		case <-ticker.C:
+		}
+	}
+}
+
+// pendingEvents reports whether the event store holds an event the projection
+// handles after checkpoint and at or before position.
+func (c *consistencyConfig) pendingEvents(ctx context.Context, checkpoint, position int64) (bool, error) {
+	const batchSize = 100
+
+	from := checkpoint + 1
+	for from <= position {
+		events, err := c.eventStore.LoadAllEvents(ctx, from, batchSize)
+		if err != nil {
+			return false, fmt.Errorf("failed to load events after position %d: %w", checkpoint, err)
+		}
+		if len(events) == 0 {
+			// The position is not in the store (yet)
+			return true, nil
+		}
+		for _, event := range events {
+			if event.Position > position {
+				return false, nil
+			}
+			if c.eventTypes[domain.CanonicalEventType(event.EventType)] {
+				return true, nil
+			}
+		}
+		from = events[len(events)-1].Position + 1
+	}
+	return false, nil
+}
+
+// ConsistentRead returns a middleware for query handlers served from the given
+// projection. Queries carrying a ReadYourWrites requirement wait (up to maxWait,
+// or DefaultConsistencyWait if not positive) for the projection checkpoint to
+// reach the requested position before the handler runs; if it does not, the
+// query fails with ConsistencyTimeoutCode rather than serving stale data.
+// Eventual queries are served immediately.
+//
+// Checkpoints advance on handled events only: pass WithHandledEventTypes so
+// that queries after commands whose events the projection ignores do not wait
+// until maxWait.
+func ConsistentRead(checkpoints store.CheckpointStore, projectionName string, maxWait time.Duration, opts ...ConsistencyOption) func(HandlerFunc) HandlerFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+			c := ConsistencyFromContext(ctx)
+			if c.Mode == ConsistencyReadYourWrites {
+				if err := WaitForPosition(ctx, checkpoints, projectionName, c.AfterPosition, maxWait, opts...); err != nil {
+					return eventsourcing.NewSimpleErrorResponse(ConsistencyTimeoutCode, err.Error()), nil
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package cqrs_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/proto"
+)
+
+// memoryCheckpoints is a minimal in-memory store.CheckpointStore.
+type memoryCheckpoints struct {
+	mu          sync.Mutex
+	checkpoints map[string]*store.ProjectionCheckpoint
+}
+
+func (m *memoryCheckpoints) Save(checkpoint *store.ProjectionCheckpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoints[checkpoint.ProjectionName] = checkpoint
+	return nil
+}
+
+func (m *memoryCheckpoints) Load(name string) (*store.ProjectionCheckpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if cp, ok := m.checkpoints[name]; ok {
+		return cp, nil
+	}
+	return nil, fmt.Errorf("%w for projection %s", store.ErrCheckpointNotFound, name)
+}
+
+func (m *memoryCheckpoints) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.checkpoints, name)
+	return nil
+}
+
+func (m *memoryCheckpoints) MinPosition(context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var minPosition int64
+	first := true
+	for _, cp := range m.checkpoints {
+		if first || cp.Position < minPosition {
+			minPosition, first = cp.Position, false
+		}
+	}
+	return minPosition, nil
+}
+
+func TestConsistentRead(t *testing.T) {
+	checkpoints := &memoryCheckpoints{checkpoints: make(map[string]*store.ProjectionCheckpoint)}
+	served := func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		return &eventsourcing.Response{Success: true}, nil
+	}
+	handler := cqrs.ConsistentRead(checkpoints, "accounts", 200*time.Millisecond)(served)
+
+	t.Run("EventualServesImmediately", func(t *testing.T) {
+		resp, err := handler(context.Background(), nil)
+		if err != nil || !resp.Success {
+			t.Fatalf("expected success, got %v (err %v)", resp, err)
+		}
+	})
+
+	t.Run("ReadYourWritesWaitsForCheckpoint", func(t *testing.T) {
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			checkpoints.Save(&store.ProjectionCheckpoint{ProjectionName: "accounts", Position: 5})
+		}()
+
+		ctx := cqrs.WithConsistency(context.Background(), cqrs.ReadYourWrites(5))
+		resp, err := handler(ctx, nil)
+		if err != nil || !resp.Success {
+			t.Fatalf("expected success once projection caught up, got %v (err %v)", resp, err)
+		}
+	})
+
+	t.Run("ReadYourWritesTimesOut", func(t *testing.T) {
+		ctx := cqrs.WithConsistency(context.Background(), cqrs.ReadYourWrites(100))
+		resp, err := handler(ctx, nil)
+		if err != nil {
+			t.Fatalf("unexpected transport error: %v", err)
+		}
+		if resp.Error == nil || resp.Error.Code != cqrs.ConsistencyTimeoutCode {
+			t.Fatalf("expected %s error, got %v", cqrs.ConsistencyTimeoutCode, resp)
+		}
+	})
+}
+
+func TestConsistentReadHandledEventTypes(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	for i, eventType := range []string{"AccountOpened", "AuditLogged"} {
+		err := eventStore.AppendEvents(ctx, "acc-1", int64(i), []*domain.Event{{
+			ID:            fmt.Sprintf("evt-%02d", i+1),
+			AggregateID:   "acc-1",
+			AggregateType: "Account",
+			EventType:     eventType,
+			Version:       int64(i + 1),
+			Timestamp:     time.Now(),
+			Data:          []byte(`{}`),
+		}})
+		if err != nil {
+			t.Fatalf("failed to append event: %v", err)
+		}
+	}
+	head, err := eventStore.LatestPosition(ctx)
+	if err != nil {
+		t.Fatalf("failed to read latest position: %v", err)
+	}
+
+	// The projection handled AccountOpened but ignores AuditLogged
+	checkpoints := &memoryCheckpoints{checkpoints: map[string]*store.ProjectionCheckpoint{
+		"accounts": {ProjectionName: "accounts", Position: head - 1},
+	}}
+
+	err = cqrs.WaitForPosition(ctx, checkpoints, "accounts", head, 50*time.Millisecond)
+	if err == nil {
+		t.Fatal("expected the checkpoint alone to never reach the position")
+	}
+
+	start := time.Now()
+	err = cqrs.WaitForPosition(ctx, checkpoints, "accounts", head, time.Second,
+		cqrs.WithHandledEventTypes(eventStore, "AccountOpened"))
+	if err != nil {
+		t.Fatalf("expected the projection to be caught up: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected no wait, waited %v", elapsed)
+	}
+
+	// An unhandled event still pending keeps the query waiting
+	err = cqrs.WaitForPosition(ctx, checkpoints, "accounts", head, 50*time.Millisecond,
+		cqrs.WithHandledEventTypes(eventStore, "AccountOpened", "AuditLogged"))
+	if err == nil {
+		t.Error("expected the wait to time out while a handled event is pending")
+	}
+}
+
+func TestPositionTracker(t *testing.T) {
+	tracker := &cqrs.PositionTracker{}
+	ctx := cqrs.WithPositionTracker(context.Background(), tracker)
+
+	cqrs.ReportPosition(ctx, 7)
+	cqrs.ReportPosition(ctx, 3)
+
+	if got := tracker.Position(); got != 7 {
+		t.Errorf("expected highest position 7, got %d", got)
+	}
+
+	// Reporting without a tracker is a no-op
+	cqrs.ReportPosition(context.Background(), 42)
+}
@@ -22,6 +22,26 @@ type Transport interface {
 	Close() error
 }
 
+// HeaderIdempotencyKey carries the business idempotency key of a command (see
+// domain.WithIdempotencyKey). Transports set it from the request context and
+// servers put it back into the handler context.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// HeaderTenantID carries the tenant of a request. Servers apply
+// ServerConfig.PerTenantRateLimit to it unless the request message has a
+// tenant_id field of its own.
+const HeaderTenantID = "Tenant-ID"
+
+// HeaderCorrelationID carries the correlation ID of a command (see
+// domain.WithCorrelationID), set and restored like HeaderIdempotencyKey.
+const HeaderCorrelationID = "Correlation-ID"
+
+// HeaderRequestTimeout carries the per-request timeout of a command (see
+// eventsourcing.WithCommandTimeout) as a Go duration string. Servers use it
+// instead of ServerConfig.HandlerTimeout for the handler context, capped at
+// ServerConfig.MaxHandlerTimeout.
+const HeaderRequestTimeout = "Request-Timeout"
+
 // TransportConfig holds common transport configuration
 type TransportConfig struct {
 	// Timeout for request/reply operations
@@ -33,8 +53,20 @@ type TransportConfig struct {
 	// ReconnectWait time between reconnection attempts
 	ReconnectWait time.Duration
 
+	// ReconnectBackoff replaces the fixed ReconnectWait with exponential
+	// backoff and jitter (nil = fixed ReconnectWait)
+	ReconnectBackoff *ReconnectBackoff
+
 	// MaxRetries for request retry on version conflicts (0 = no retries, default 3)
 	MaxRetries int
+
+	// CircuitBreaker fails requests to an unresponsive subject fast with
+	// ErrCircuitOpen instead of waiting for Timeout (nil = disabled)
+	CircuitBreaker *CircuitBreaker
+
+	// Codec encodes request payloads, announced in HeaderContentType
+	// (nil = ProtoCodec). Responses are decoded by their own content type.
+	Codec Codec
 }
 
 // DefaultTransportConfig returns sensible defaults
@@ -65,24 +97,59 @@ type Server interface {
 	Close() error
 }
 
+// ShuttingDownCode is the AppError code returned for requests a server
+// receives after its shutdown started. Clients should retry, e.g. on another
+// instance of the queue group.
+const ShuttingDownCode = "SHUTTING_DOWN"
+
+// DefaultMaxHandlerTimeout is the ServerConfig.MaxHandlerTimeout used when it
+// is unset.
+const DefaultMaxHandlerTimeout = 5 * time.Minute
+
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	// QueueGroup for load balancing across multiple server instances
 	QueueGroup string
 
-	// MaxConcurrent limits concurrent handler executions
+	// MaxConcurrent limits concurrent handler executions; requests beyond it
+	// wait for a slot until their handler timeout expires
 	MaxConcurrent int
 
 	// Timeout for handler execution
 	HandlerTimeout time.Duration
+
+	// MaxHandlerTimeout caps the per-request timeout clients set with
+	// HeaderRequestTimeout (0 = DefaultMaxHandlerTimeout, negative = no cap)
+	MaxHandlerTimeout time.Duration
+
+	// RateLimit caps the request rate across all callers (nil = unlimited)
+	RateLimit *RateLimit
+
+	// PerTenantRateLimit caps the request rate of each tenant independently,
+	// keyed by the tenant_id field of the request message, or HeaderTenantID
+	// for messages without one (nil = unlimited). Requests without a tenant
+	// are bounded by RateLimit only.
+	PerTenantRateLimit *RateLimit
+
+	// Middleware wraps every registered handler, the first entry outermost,
+	// like net/http middleware (e.g. RecoverHandler, RequestLogger,
+	// AuthorizeReplay)
+	Middleware []HandlerMiddleware
+
+	// Codec decodes requests without a HeaderContentType (nil = ProtoCodec),
+	// such as those from clients that cannot set headers. Requests naming a
+	// content type are decoded with the matching built-in codec or Codec, and
+	// every response uses the codec of its request.
+	Codec Codec
 }
 
 // DefaultServerConfig returns sensible defaults
 func DefaultServerConfig() *ServerConfig {
 	return &ServerConfig{
-		QueueGroup:     "default-handlers",
-		MaxConcurrent:  100,
-		HandlerTimeout: 30 * time.Second,
+		QueueGroup:        "default-handlers",
+		MaxConcurrent:     100,
+		HandlerTimeout:    30 * time.Second,
+		MaxHandlerTimeout: DefaultMaxHandlerTimeout,
 	}
 }
 
@@ -112,5 +179,17 @@ type CommandBus interface {
 	Use(middleware CommandMiddleware)
 }
 
+// ResultCommandBus is a CommandBus that returns the structured result a
+// handler set with domain.SetCommandResult, such as a new balance, alongside
+// the events it produced.
+type ResultCommandBus interface {
+	CommandBus
+
+	// SendWithResult sends a command like Send and unpacks the handler's
+	// result into result, which must be of the message type the handler sets.
+	// result is left untouched if the handler set none.
+	SendWithResult(ctx context.Context, cmd *domain.CommandEnvelope, result proto.Message) error
+}
+
 // CommandMiddleware wraps command handlers with cross-cutting concerns.
 type CommandMiddleware func(CommandHandler) CommandHandler
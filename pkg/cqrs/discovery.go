@@ -0,0 +1,61 @@
+package cqrs
+
+import "context"
+
+// ServiceInfo describes a running service instance and the requests it handles.
+type ServiceInfo struct {
+	// Name, Version and Description identify the service
+	Name        string
+	Version     string
+	Description string
+
+	// ID identifies this instance; replicas share a name but not an ID
+	ID string
+
+	// Metadata is the service's custom metadata
+	Metadata map[string]string
+
+	// Endpoints are the subjects the service handles
+	Endpoints []EndpointInfo
+}
+
+// EndpointInfo describes a subject handled by a service.
+type EndpointInfo struct {
+	// Subject is the request subject (e.g., "account.v1.AccountCommandService.OpenAccount")
+	Subject string
+
+	// RequestType and ResponseType are the full protobuf message names, or
+	// empty when the subject is not a registered protobuf method
+	RequestType  string
+	ResponseType string
+}
+
+// Handles reports whether the service has an endpoint for the given subject
+// or request message type (e.g., "account.v1.OpenAccountCommand").
+func (s ServiceInfo) Handles(subjectOrType string) bool {
+	for _, endpoint := range s.Endpoints {
+		if endpoint.Subject == subjectOrType || endpoint.RequestType == subjectOrType {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceDiscoverer is implemented by transports that can list the services
+// reachable through them.
+type ServiceDiscoverer interface {
+	// DiscoverServices returns the running service instances.
+	DiscoverServices(ctx context.Context) ([]ServiceInfo, error)
+}
+
+// ServicesHandling returns the services that handle the given subject or
+// request message type.
+func ServicesHandling(services []ServiceInfo, subjectOrType string) []ServiceInfo {
+	var handling []ServiceInfo
+	for _, service := range services {
+		if service.Handles(subjectOrType) {
+			handling = append(handling, service)
+		}
+	}
+	return handling
+}
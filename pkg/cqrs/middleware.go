@@ -0,0 +1,100 @@
+package cqrs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+)
+
+// PanicCode is the AppError code of a request whose handler panicked (see
+// RecoverHandler).
+const PanicCode = "HANDLER_PANIC"
+
+// RecoverHandler returns server middleware that turns a panicking handler
+// into a PanicCode error response, logging the panic and its stack trace to
+// logger (nil = slog.Default()). Put it first in ServerConfig.Middleware so it
+// also covers the middleware after it.
+func RecoverHandler(logger *slog.Logger) HandlerMiddleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, request proto.Message) (response *eventsourcing.Response, err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.ErrorContext(ctx, "Request handler panicked",
+						slog.String("message_type", messageType(request)),
+						slog.Any("panic", r),
+						slog.String("stack_trace", string(debug.Stack())),
+					)
+
+					response = eventsourcing.NewSimpleErrorResponse(PanicCode, fmt.Sprintf("handler panicked: %v", r))
+					err = nil
+				}
+			}()
+
+			return next(ctx, request)
+		}
+	}
+}
+
+type loggerKey struct{}
+
+// RequestLogger returns server middleware that puts a request-scoped logger
+// into the handler context, derived from logger (nil = slog.Default()) with
+// the message type, tenant, trace ID, correlation ID and idempotency key of
+// the request.
+// Handlers and later middleware retrieve it with LoggerFromContext.
+func RequestLogger(logger *slog.Logger) HandlerMiddleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+			attrs := []any{slog.String("message_type", messageType(request))}
+			if tenantID, ok := ctx.Value("tenant_id").(string); ok {
+				attrs = append(attrs, slog.String("tenant_id", tenantID))
+			}
+			if traceID, ok := ctx.Value("trace_id").(string); ok {
+				attrs = append(attrs, slog.String("trace_id", traceID))
+			}
+			if correlationID, ok := domain.CorrelationIDFromContext(ctx); ok {
+				attrs = append(attrs, slog.String("correlation_id", correlationID))
+			}
+			if key, ok := domain.IdempotencyKeyFromContext(ctx); ok {
+				attrs = append(attrs, slog.String("idempotency_key", key))
+			}
+
+			return next(WithLogger(ctx, logger.With(attrs...)), request)
+		}
+	}
+}
+
+// WithLogger returns a context carrying logger, as RequestLogger does.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the logger of the context, or slog.Default() if
+// it has none.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// messageType returns the full protobuf name of a request.
+func messageType(request proto.Message) string {
+	if request == nil {
+		return ""
+	}
+	return string(request.ProtoReflect().Descriptor().FullName())
+}
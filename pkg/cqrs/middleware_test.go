@@ -0,0 +1,62 @@
+package cqrs_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestRecoverHandler(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	handler := cqrs.RecoverHandler(logger)(func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		panic("boom")
+	})
+
+	resp, err := handler(context.Background(), wrapperspb.String("ping"))
+	if err != nil {
+		t.Fatalf("expected the panic to become an error response, got %v", err)
+	}
+	if resp.Success || resp.GetError().GetCode() != cqrs.PanicCode {
+		t.Errorf("expected a %s response, got %v", cqrs.PanicCode, resp)
+	}
+	if !strings.Contains(logs.String(), "boom") || !strings.Contains(logs.String(), "google.protobuf.StringValue") {
+		t.Errorf("expected the panic to be logged with the message type, got %q", logs.String())
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	handler := cqrs.RequestLogger(logger)(func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		cqrs.LoggerFromContext(ctx).Info("handling")
+		return eventsourcing.NewSuccessResponse(request)
+	})
+
+	ctx := context.WithValue(context.Background(), "tenant_id", "tenant-a")
+	ctx = domain.WithIdempotencyKey(ctx, "payment-42")
+	ctx = domain.WithCorrelationID(ctx, "transfer-7")
+	if _, err := handler(ctx, wrapperspb.String("ping")); err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+
+	for _, want := range []string{"message_type=google.protobuf.StringValue", "tenant_id=tenant-a", "idempotency_key=payment-42", "correlation_id=transfer-7"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected the request logger to log %s, got %q", want, logs.String())
+		}
+	}
+
+	if cqrs.LoggerFromContext(context.Background()) != slog.Default() {
+		t.Error("expected the default logger without a request logger")
+	}
+}
@@ -0,0 +1,132 @@
+package nats_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAuditTrail(t *testing.T) {
+	t.Parallel()
+
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithInProcess())
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	// More events than fit on the server's default page
+	var events []*domain.Event
+	for i := 1; i <= 120; i++ {
+		events = append(events, &domain.Event{
+			ID:            fmt.Sprintf("evt-%d", i),
+			AggregateID:   "acc-1",
+			AggregateType: "Account",
+			EventType:     "account.v1.Deposited",
+			Version:       int64(i),
+			Timestamp:     time.Now().UTC().Truncate(time.Second),
+			Data:          []byte(fmt.Sprintf("%03d", i)),
+			Metadata: domain.EventMetadata{
+				PrincipalID:   "teller-1",
+				CorrelationID: "corr-1",
+			},
+		})
+	}
+	if err := eventStore.AppendEvents(context.Background(), "acc-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	serverConfig := cqrs.DefaultServerConfig()
+	serverConfig.Middleware = []cqrs.HandlerMiddleware{
+		cqrs.AuthorizeAuditTrail(func(ctx context.Context, aggregateID string) error {
+			if tenant, _ := ctx.Value("tenant_id").(string); tenant != "auditor" {
+				return errors.New("audit trail requires the auditor tenant")
+			}
+			return nil
+		}),
+	}
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig:   serverConfig,
+		URL:            srv.URL(),
+		ConnectOptions: srv.ConnectOptions(),
+		Name:           "AccountService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+	summarize := eventsourcing.WithAuditSummarizer("account.v1.Deposited", func(event *domain.Event, msg proto.Message) string {
+		return "deposit"
+	})
+	if err := cqrs.RegisterAuditTrail(server, "account.v1", eventStore, summarize); err != nil {
+		t.Fatalf("failed to register audit trail: %v", err)
+	}
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		ConnectOptions:  srv.ConnectOptions(),
+		Name:            "audit-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	auditor := context.WithValue(context.Background(), "tenant_id", "auditor")
+
+	t.Run("FollowsPages", func(t *testing.T) {
+		trail, err := cqrs.AuditTrail(auditor, transport, "account.v1", "acc-1")
+		if err != nil {
+			t.Fatalf("audit trail failed: %v", err)
+		}
+		if len(trail) != len(events) {
+			t.Fatalf("expected %d entries, got %d", len(events), len(trail))
+		}
+		for i, entry := range trail {
+			if entry.Version != int64(i+1) || entry.EventID != events[i].ID || entry.PrincipalID != "teller-1" ||
+				entry.CorrelationID != "corr-1" || !entry.Timestamp.Equal(events[i].Timestamp) {
+				t.Fatalf("entry %d: unexpected %+v", i, entry)
+			}
+		}
+		// The summarizer only applies to registered event types
+		if trail[0].Summary != "(3 bytes)" {
+			t.Errorf("expected the payload size, got %q", trail[0].Summary)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		_, err := cqrs.AuditTrail(auditor, transport, "account.v1", "acc-unknown")
+		var respErr *eventsourcing.ResponseError
+		if !errors.As(err, &respErr) || respErr.AppError.Code != cqrs.AuditTrailNotFoundCode {
+			t.Fatalf("expected %s error, got %v", cqrs.AuditTrailNotFoundCode, err)
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		_, err := cqrs.AuditTrail(context.Background(), transport, "account.v1", "acc-1")
+		var respErr *eventsourcing.ResponseError
+		if !errors.As(err, &respErr) || respErr.AppError.Code != cqrs.ReplayForbiddenCode {
+			t.Fatalf("expected %s error, got %v", cqrs.ReplayForbiddenCode, err)
+		}
+	})
+}
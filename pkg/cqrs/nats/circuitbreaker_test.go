@@ -0,0 +1,84 @@
+package nats_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestTransportCircuitBreaker(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const subject = "import.v1.ImportService.Ping"
+
+	states := make(chan cqrs.CircuitState, 10)
+	transportConfig := cqrs.DefaultTransportConfig()
+	transportConfig.CircuitBreaker = &cqrs.CircuitBreaker{
+		FailureThreshold: 2,
+		Cooldown:         100 * time.Millisecond,
+		OnStateChange: func(subject string, from, to cqrs.CircuitState) {
+			states <- to
+		},
+	}
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: transportConfig,
+		URL:             srv.URL(),
+		Name:            "breaker-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	// Without a service, requests fail until the circuit opens
+	for i := 0; i < 2; i++ {
+		_, err := transport.Request(context.Background(), subject, wrapperspb.String("ping"))
+		if err == nil || errors.Is(err, cqrs.ErrCircuitOpen) {
+			t.Fatalf("expected request %d to fail without a service, got %v", i, err)
+		}
+	}
+	if _, err := transport.Request(context.Background(), subject, wrapperspb.String("ping")); !errors.Is(err, cqrs.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	// Once the service is back, the probe after the cooldown closes the circuit
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "ImportService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+	server.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		return eventsourcing.NewSuccessResponse(request)
+	})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	resp, err := transport.Request(context.Background(), subject, wrapperspb.String("ping"))
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the probe to succeed, got %v, %v", resp, err)
+	}
+
+	for _, want := range []cqrs.CircuitState{cqrs.CircuitOpen, cqrs.CircuitHalfOpen, cqrs.CircuitClosed} {
+		if got := <-states; got != want {
+			t.Errorf("expected state %s, got %s", want, got)
+		}
+	}
+}
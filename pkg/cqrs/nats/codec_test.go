@@ -0,0 +1,144 @@
+package nats_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestTransportCodecs(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const subject = "echo.v1.EchoService.Echo"
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "EchoService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	server.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		value := request.(*wrapperspb.StringValue).GetValue()
+		if value == "" {
+			return eventsourcing.NewSimpleErrorResponse("EMPTY", "value is required"), nil
+		}
+		return eventsourcing.NewSuccessResponse(wrapperspb.String(strings.ToUpper(value)))
+	})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	newTransport := func(codec cqrs.Codec) *cqrsnats.Transport {
+		config := cqrs.DefaultTransportConfig()
+		config.Codec = codec
+		transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+			TransportConfig: config,
+			URL:             srv.URL(),
+			Name:            "codec-client",
+		})
+		if err != nil {
+			t.Fatalf("failed to create transport: %v", err)
+		}
+		t.Cleanup(func() { transport.Close() })
+		return transport
+	}
+
+	// Protobuf and JSON clients talk to the same server side by side
+	for name, codec := range map[string]cqrs.Codec{"Protobuf": nil, "JSON": cqrs.JSONCodec} {
+		t.Run(name, func(t *testing.T) {
+			transport := newTransport(codec)
+
+			resp, err := transport.Request(context.Background(), subject, wrapperspb.String("hello"))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			var echoed wrapperspb.StringValue
+			if err := resp.UnpackData(&echoed); err != nil {
+				t.Fatalf("failed to unpack response: %v", err)
+			}
+			if echoed.GetValue() != "HELLO" {
+				t.Errorf("expected HELLO, got %q", echoed.GetValue())
+			}
+
+			resp, err = transport.Request(context.Background(), subject, wrapperspb.String(""))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.GetError().GetCode() != "EMPTY" {
+				t.Errorf("expected EMPTY error, got %v", resp.GetError())
+			}
+		})
+	}
+
+	t.Run("RawJSONClient", func(t *testing.T) {
+		// A client without protobuf support, e.g. a TypeScript service
+		nc, err := nats.Connect(srv.URL())
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		defer nc.Close()
+
+		msg := nats.NewMsg(subject)
+		msg.Header.Set("Message-Type", "google.protobuf.StringValue")
+		msg.Header.Set(cqrs.HeaderContentType, "application/json; charset=utf-8")
+		msg.Data = []byte(`"json"`)
+
+		reply, err := nc.RequestMsg(msg, 5*time.Second)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if got := reply.Header.Get(cqrs.HeaderContentType); got != "application/json" {
+			t.Errorf("expected a JSON response, got content type %q", got)
+		}
+
+		var resp eventsourcing.Response
+		if err := protojson.Unmarshal(reply.Data, &resp); err != nil {
+			t.Fatalf("response is not protobuf JSON: %v (%s)", err, reply.Data)
+		}
+		if !strings.Contains(string(reply.Data), `"JSON"`) {
+			t.Errorf("expected the echoed value in %s", reply.Data)
+		}
+	})
+
+	t.Run("UnsupportedContentType", func(t *testing.T) {
+		nc, err := nats.Connect(srv.URL())
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		defer nc.Close()
+
+		msg := nats.NewMsg(subject)
+		msg.Header.Set("Message-Type", "google.protobuf.StringValue")
+		msg.Header.Set(cqrs.HeaderContentType, "application/xml")
+
+		reply, err := nc.RequestMsg(msg, 5*time.Second)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var resp eventsourcing.Response
+		if err := proto.Unmarshal(reply.Data, &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if resp.GetError().GetCode() != "UNSUPPORTED_CONTENT_TYPE" {
+			t.Errorf("expected UNSUPPORTED_CONTENT_TYPE, got %v", resp.GetError())
+		}
+	})
+}
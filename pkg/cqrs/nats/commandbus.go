@@ -3,6 +3,7 @@ package nats
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -10,11 +11,13 @@ import (
 	"github.com/nats-io/nats.go"
 	"github.com/plaenen/eventstore/pkg/cqrs"
 	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/middleware"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
-// CommandBus is a NATS-based implementation of cqrs.CommandBus.
+// CommandBus is a NATS-based implementation of cqrs.ResultCommandBus.
 // Enables distributed command processing across multiple services.
 type CommandBus struct {
 	nc         *nats.Conn
@@ -22,6 +25,7 @@ type CommandBus struct {
 	middleware []cqrs.CommandMiddleware
 	subs       map[string]*nats.Subscription
 	timeout    time.Duration
+	results    middleware.CommandResults
 	mu         sync.RWMutex
 }
 
@@ -30,11 +34,19 @@ type CommandBusConfig struct {
 	// URL is the NATS server URL
 	URL string
 
+	// ConnectOptions are extra options for the NATS connection (optional)
+	ConnectOptions []nats.Option
+
 	// Timeout is the maximum time to wait for a command response
 	Timeout time.Duration
 
 	// QueueGroup is the queue group name for load balancing handlers
 	QueueGroup string
+
+	// CommandResults looks up processed commands, usually the event store.
+	// When set, handlers of a retried command ID see eventsourcing.IsReplay
+	// (see middleware.IdempotentRetryCommandMiddleware).
+	CommandResults middleware.CommandResults
 }
 
 // DefaultCommandBusConfig returns sensible defaults.
@@ -49,7 +61,7 @@ func DefaultCommandBusConfig() CommandBusConfig {
 // NewCommandBus creates a new NATS-based command bus.
 func NewCommandBus(config CommandBusConfig) (*CommandBus, error) {
 	// Connect to NATS
-	nc, err := nats.Connect(config.URL)
+	nc, err := nats.Connect(config.URL, config.ConnectOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
@@ -60,6 +72,7 @@ func NewCommandBus(config CommandBusConfig) (*CommandBus, error) {
 		middleware: make([]cqrs.CommandMiddleware, 0),
 		subs:       make(map[string]*nats.Subscription),
 		timeout:    config.Timeout,
+		results:    config.CommandResults,
 	}, nil
 }
 
@@ -101,6 +114,13 @@ func (b *CommandBus) Use(middleware cqrs.CommandMiddleware) {
 
 // Send publishes a command to NATS and waits for the response (request-reply pattern).
 func (b *CommandBus) Send(ctx context.Context, cmd *domain.CommandEnvelope) error {
+	return b.SendWithResult(ctx, cmd, nil)
+}
+
+// SendWithResult sends a command like Send and unmarshals the result the
+// handler set with domain.SetCommandResult into result. A nil result discards
+// it.
+func (b *CommandBus) SendWithResult(ctx context.Context, cmd *domain.CommandEnvelope, result proto.Message) error {
 	if cmd == nil {
 		return domain.ErrInvalidCommand
 	}
@@ -132,10 +152,22 @@ func (b *CommandBus) Send(ctx context.Context, cmd *domain.CommandEnvelope) erro
 		return fmt.Errorf("failed to deserialize response: %w", err)
 	}
 
+	if len(response.AppError) > 0 {
+		appErr := &eventsourcing.AppError{}
+		if err := proto.Unmarshal(response.AppError, appErr); err == nil {
+			return fmt.Errorf("command failed: %w", &eventsourcing.ResponseError{AppError: appErr})
+		}
+	}
 	if response.Error != "" {
 		return fmt.Errorf("command failed: %s", response.Error)
 	}
 
+	if result != nil && len(response.Result) > 0 {
+		if err := proto.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("failed to unmarshal command result: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -153,7 +185,7 @@ func (b *CommandBus) handleMessage(msg *nats.Msg, commandType string) {
 	// Get handler
 	b.mu.RLock()
 	handler, exists := b.handlers[commandType]
-	middleware := b.middleware
+	chain := b.middleware
 	b.mu.RUnlock()
 
 	if !exists {
@@ -163,11 +195,19 @@ func (b *CommandBus) handleMessage(msg *nats.Msg, commandType string) {
 
 	// Build middleware chain
 	finalHandler := handler
-	for i := len(middleware) - 1; i >= 0; i-- {
-		finalHandler = middleware[i](finalHandler)
+	for i := len(chain) - 1; i >= 0; i-- {
+		finalHandler = chain[i](finalHandler)
+	}
+	if b.results != nil {
+		finalHandler = middleware.IdempotentRetryCommandMiddleware(b.results)(finalHandler)
 	}
 
-	// Execute handler
+	// Execute handler, with the business key where SaveWithIdempotencyKey
+	// callers look for it
+	if envelope.Metadata.IdempotencyKey != "" {
+		ctx = domain.WithIdempotencyKey(ctx, envelope.Metadata.IdempotencyKey)
+	}
+	ctx, handlerResult := domain.WithCommandResult(ctx)
 	events, err := finalHandler.Handle(ctx, envelope)
 	if err != nil {
 		b.sendErrorResponse(msg, err)
@@ -175,7 +215,7 @@ func (b *CommandBus) handleMessage(msg *nats.Msg, commandType string) {
 	}
 
 	// Send success response
-	b.sendSuccessResponse(msg, events)
+	b.sendSuccessResponse(msg, events, handlerResult())
 }
 
 // serializeCommandEnvelope serializes a command envelope to JSON.
@@ -212,11 +252,19 @@ func (b *CommandBus) deserializeCommandEnvelope(data []byte) (*domain.CommandEnv
 }
 
 // sendSuccessResponse sends a success response back to the command sender.
-func (b *CommandBus) sendSuccessResponse(msg *nats.Msg, events []*domain.Event) {
+func (b *CommandBus) sendSuccessResponse(msg *nats.Msg, events []*domain.Event, result proto.Message) {
 	response := CommandResponse{
 		Success: true,
 		Events:  events,
 	}
+	if result != nil {
+		data, err := proto.Marshal(result)
+		if err != nil {
+			b.sendErrorResponse(msg, fmt.Errorf("failed to marshal command result: %w", err))
+			return
+		}
+		response.Result = data
+	}
 
 	data, _ := json.Marshal(response)
 	msg.Respond(data)
@@ -229,6 +277,12 @@ func (b *CommandBus) sendErrorResponse(msg *nats.Msg, err error) {
 		Error:   err.Error(),
 	}
 
+	// Keep structured errors (codes, field violations) intact for the sender
+	var responseErr *eventsourcing.ResponseError
+	if errors.As(err, &responseErr) && responseErr.AppError != nil {
+		response.AppError, _ = proto.Marshal(responseErr.AppError)
+	}
+
 	data, _ := json.Marshal(response)
 	msg.Respond(data)
 }
@@ -259,7 +313,11 @@ type CommandEnvelopeWire struct {
 type CommandResponse struct {
 	Success bool                   `json:"success"`
 	Error   string                 `json:"error,omitempty"`
+	// AppError is the serialized eventsourcing.AppError of a structured error
+	AppError []byte `json:"app_error,omitempty"`
 	Events  []*domain.Event `json:"events,omitempty"`
+	// Result is the serialized proto result set with domain.SetCommandResult
+	Result []byte `json:"result,omitempty"`
 }
 
 // RawCommand is a placeholder for commands that haven't been deserialized yet.
@@ -0,0 +1,137 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/domain"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"github.com/plaenen/eventstore/pkg/store/memory"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCommandBusResult(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithInProcess())
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	config := cqrsnats.DefaultCommandBusConfig()
+	config.ConnectOptions = srv.ConnectOptions()
+	config.Timeout = 5 * time.Second
+	bus, err := cqrsnats.NewCommandBus(config)
+	if err != nil {
+		t.Fatalf("failed to create command bus: %v", err)
+	}
+	defer bus.Close()
+
+	var _ cqrs.ResultCommandBus = bus
+
+	// The result must survive middleware wrapping the handler
+	bus.Use(func(next cqrs.CommandHandler) cqrs.CommandHandler {
+		return cqrs.CommandHandlerFunc(func(ctx context.Context, cmd *domain.CommandEnvelope) ([]*domain.Event, error) {
+			return next.Handle(ctx, cmd)
+		})
+	})
+	bus.Register("account.v1.Withdraw", cqrs.CommandHandlerFunc(
+		func(ctx context.Context, cmd *domain.CommandEnvelope) ([]*domain.Event, error) {
+			if !domain.SetCommandResult(ctx, wrapperspb.String("950.00")) {
+				t.Error("expected the bus to collect command results")
+			}
+			return nil, nil
+		},
+	))
+	bus.Register("account.v1.Close", cqrs.CommandHandlerFunc(
+		func(ctx context.Context, cmd *domain.CommandEnvelope) ([]*domain.Event, error) {
+			return nil, nil
+		},
+	))
+
+	send := func(commandType string) *domain.CommandEnvelope {
+		return &domain.CommandEnvelope{
+			Command: wrapperspb.String("acc-1"),
+			Metadata: domain.CommandMetadata{
+				CommandID: "cmd-" + commandType,
+				Custom:    map[string]string{"command_type": commandType},
+			},
+		}
+	}
+
+	balance := &wrapperspb.StringValue{}
+	if err := bus.SendWithResult(context.Background(), send("account.v1.Withdraw"), balance); err != nil {
+		t.Fatalf("failed to send command: %v", err)
+	}
+	if balance.GetValue() != "950.00" {
+		t.Errorf("expected the new balance, got %q", balance.GetValue())
+	}
+
+	// Handlers without a result leave it untouched
+	untouched := wrapperspb.String("unchanged")
+	if err := bus.SendWithResult(context.Background(), send("account.v1.Close"), untouched); err != nil {
+		t.Fatalf("failed to send command: %v", err)
+	}
+	if untouched.GetValue() != "unchanged" {
+		t.Errorf("expected the result to be untouched, got %q", untouched.GetValue())
+	}
+
+	// Plain Send discards the result
+	if err := bus.Send(context.Background(), send("account.v1.Withdraw")); err != nil {
+		t.Fatalf("failed to send command: %v", err)
+	}
+}
+
+func TestCommandBusIdempotentRetry(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithInProcess())
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	eventStore := memory.NewEventStore()
+	config := cqrsnats.DefaultCommandBusConfig()
+	config.ConnectOptions = srv.ConnectOptions()
+	config.CommandResults = eventStore
+	bus, err := cqrsnats.NewCommandBus(config)
+	if err != nil {
+		t.Fatalf("failed to create command bus: %v", err)
+	}
+	defer bus.Close()
+
+	var replays []bool
+	bus.Register("account.v1.Deposit", cqrs.CommandHandlerFunc(
+		func(ctx context.Context, cmd *domain.CommandEnvelope) ([]*domain.Event, error) {
+			replays = append(replays, domain.IsReplay(ctx))
+			event := &domain.Event{
+				ID:            "evt-1",
+				AggregateID:   "acc-1",
+				AggregateType: "Account",
+				EventType:     "account.v1.Deposited",
+				Version:       1,
+				Timestamp:     time.Now(),
+			}
+			_, err := eventStore.AppendEventsIdempotent(ctx, "acc-1", 0, []*domain.Event{event}, cmd.Metadata.CommandID, time.Hour)
+			return nil, err
+		},
+	))
+
+	cmd := &domain.CommandEnvelope{
+		Command: wrapperspb.String("acc-1"),
+		Metadata: domain.CommandMetadata{
+			CommandID: "cmd-deposit",
+			Custom:    map[string]string{"command_type": "account.v1.Deposit"},
+		},
+	}
+	// The retry finds the command processed and must see IsReplay
+	for range 2 {
+		if err := bus.Send(context.Background(), cmd); err != nil {
+			t.Fatalf("failed to send command: %v", err)
+		}
+	}
+	if len(replays) != 2 || replays[0] || !replays[1] {
+		t.Errorf("expected only the retry to be a replay, got %v", replays)
+	}
+}
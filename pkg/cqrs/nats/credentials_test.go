@@ -0,0 +1,381 @@
+package nats_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nkeys"
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"github.com/plaenen/eventstore/pkg/security/credentials"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// startEchoService serves an echo handler on subject, connected with provider.
+func startEchoService(t *testing.T, srv *natsserver.EmbeddedServer, subject string, provider credentials.Provider) {
+	t.Helper()
+
+	opts, err := credentials.NATSOptions(context.Background(), provider)
+	if err != nil {
+		t.Fatalf("failed to apply credentials: %v", err)
+	}
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig:   cqrs.DefaultServerConfig(),
+		URL:            srv.URL(),
+		ConnectOptions: opts,
+		Name:           "EchoService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	server.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		return eventsourcing.NewSuccessResponse(request)
+	})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+}
+
+func newUserSeed(t *testing.T) (publicKey string, seed []byte) {
+	t.Helper()
+
+	user, err := nkeys.CreateUser()
+	if err != nil {
+		t.Fatalf("failed to create user key: %v", err)
+	}
+	publicKey, _ = user.PublicKey()
+	seed, _ = user.Seed()
+	return publicKey, seed
+}
+
+func TestTransportNKeyCredentials(t *testing.T) {
+	serviceKey, serviceSeed := newUserSeed(t)
+	clientKey, clientSeed := newUserSeed(t)
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithJetStream(false), func(opts *server.Options) {
+		opts.Nkeys = []*server.NkeyUser{{Nkey: serviceKey}, {Nkey: clientKey}}
+	})
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const subject = "echo.v1.EchoService.Echo"
+	serviceCreds, err := credentials.NewNKeyProvider(string(serviceSeed))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	startEchoService(t, srv, subject, serviceCreds)
+
+	clientCreds, err := credentials.NewNKeyProvider(string(clientSeed))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig:    cqrs.DefaultTransportConfig(),
+		URL:                srv.URL(),
+		Name:               "nkey-client",
+		CredentialProvider: clientCreds,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect with nkey: %v", err)
+	}
+	defer transport.Close()
+
+	resp, err := transport.Request(context.Background(), subject, wrapperspb.String("ping"))
+	if err != nil || !resp.Success {
+		t.Fatalf("request failed: %v, %v", resp, err)
+	}
+
+	// An unknown key is refused
+	_, unknownSeed := newUserSeed(t)
+	unknownCreds, _ := credentials.NewNKeyProvider(string(unknownSeed))
+	if _, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig:    cqrs.DefaultTransportConfig(),
+		URL:                srv.URL(),
+		Name:               "unknown-client",
+		CredentialProvider: unknownCreds,
+	}); err == nil {
+		t.Fatal("expected an unknown nkey to be refused")
+	}
+}
+
+// rotatingJWTProvider hands out a newly issued user JWT on every Rotate,
+// reported to expire expiresIn after it was issued (if set).
+type rotatingJWTProvider struct {
+	issue     func() string
+	seed      string
+	expiresIn time.Duration
+
+	mu      sync.Mutex
+	jwt     string
+	issued  time.Time
+	rotated int
+}
+
+func (p *rotatingJWTProvider) GetCredentials(ctx context.Context) (*credentials.Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	creds := &credentials.Credentials{Type: credentials.CredentialTypeJWT, JWTToken: p.jwt, Seed: p.seed}
+	if p.expiresIn > 0 {
+		expiresAt := p.issued.Add(p.expiresIn)
+		creds.ExpiresAt = &expiresAt
+	}
+	return creds, nil
+}
+
+func (p *rotatingJWTProvider) Rotate(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.jwt = p.issue()
+	p.issued = time.Now()
+	p.rotated++
+	return nil
+}
+
+func (p *rotatingJWTProvider) Type() credentials.CredentialType { return credentials.CredentialTypeJWT }
+
+func (p *rotatingJWTProvider) Close() error { return nil }
+
+func TestTransportJWTCredentialRotation(t *testing.T) {
+	// Operator mode: the server trusts the operator, which signed the account,
+	// which signs the user JWTs
+	operator, _ := nkeys.CreateOperator()
+	operatorKey, _ := operator.PublicKey()
+	account, _ := nkeys.CreateAccount()
+	accountKey, _ := account.PublicKey()
+	accountJWT, err := jwt.NewAccountClaims(accountKey).Encode(operator)
+	if err != nil {
+		t.Fatalf("failed to issue account jwt: %v", err)
+	}
+	resolver := &server.MemAccResolver{}
+	if err := resolver.Store(accountKey, accountJWT); err != nil {
+		t.Fatalf("failed to store account jwt: %v", err)
+	}
+
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithJetStream(false), func(opts *server.Options) {
+		opts.TrustedKeys = []string{operatorKey}
+		opts.AccountResolver = resolver
+	})
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	userKey, userSeed := newUserSeed(t)
+	issue := func() string {
+		claims := jwt.NewUserClaims(userKey)
+		claims.Expires = time.Now().Add(time.Hour).Unix()
+		userJWT, err := claims.Encode(account)
+		if err != nil {
+			t.Errorf("failed to issue user jwt: %v", err)
+		}
+		return userJWT
+	}
+
+	const subject = "echo.v1.EchoService.Echo"
+	serviceCreds, err := credentials.NewJWTProvider(issue(), string(userSeed))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	startEchoService(t, srv, subject, serviceCreds)
+
+	provider := &rotatingJWTProvider{issue: issue, seed: string(userSeed), jwt: issue()}
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig:    cqrs.DefaultTransportConfig(),
+		URL:                srv.URL(),
+		Name:               "jwt-client",
+		CredentialProvider: provider,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect with jwt: %v", err)
+	}
+	defer transport.Close()
+
+	// Requests keep working across a rotation and its reconnect
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		resp, err := transport.Request(ctx, subject, wrapperspb.String("ping"))
+		cancel()
+		if err != nil || !resp.Success {
+			t.Fatalf("request %d failed: %v, %v", i, resp, err)
+		}
+		if i == 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			err := transport.RotateCredentials(ctx)
+			cancel()
+			if err != nil {
+				t.Fatalf("failed to rotate credentials: %v", err)
+			}
+		}
+	}
+	if provider.rotated != 1 {
+		t.Errorf("expected one rotation, got %d", provider.rotated)
+	}
+}
+
+func TestTransportRefreshesCredentialsBeforeExpiry(t *testing.T) {
+	operator, _ := nkeys.CreateOperator()
+	operatorKey, _ := operator.PublicKey()
+	account, _ := nkeys.CreateAccount()
+	accountKey, _ := account.PublicKey()
+	accountJWT, err := jwt.NewAccountClaims(accountKey).Encode(operator)
+	if err != nil {
+		t.Fatalf("failed to issue account jwt: %v", err)
+	}
+	resolver := &server.MemAccResolver{}
+	if err := resolver.Store(accountKey, accountJWT); err != nil {
+		t.Fatalf("failed to store account jwt: %v", err)
+	}
+
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithJetStream(false), func(opts *server.Options) {
+		opts.TrustedKeys = []string{operatorKey}
+		opts.AccountResolver = resolver
+	})
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	userKey, userSeed := newUserSeed(t)
+	issue := func() string {
+		userJWT, err := jwt.NewUserClaims(userKey).Encode(account)
+		if err != nil {
+			t.Errorf("failed to issue user jwt: %v", err)
+		}
+		return userJWT
+	}
+
+	// The credentials expire 2s after they are issued; the transport
+	// refreshes them 1.5s ahead
+	provider := &rotatingJWTProvider{issue: issue, seed: string(userSeed), expiresIn: 2 * time.Second, jwt: issue(), issued: time.Now()}
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig:         cqrs.DefaultTransportConfig(),
+		URL:                     srv.URL(),
+		Name:                    "expiring-client",
+		CredentialProvider:      provider,
+		CredentialRefreshBefore: 1500 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect with jwt: %v", err)
+	}
+	defer transport.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		provider.mu.Lock()
+		rotated := provider.rotated
+		provider.mu.Unlock()
+		if rotated >= 1 && transport.IsConnected() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the credentials to be refreshed before they expire")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestTransportReauthenticatesOnCredentialsFileChange(t *testing.T) {
+	operator, _ := nkeys.CreateOperator()
+	operatorKey, _ := operator.PublicKey()
+	account, _ := nkeys.CreateAccount()
+	accountKey, _ := account.PublicKey()
+	accountJWT, err := jwt.NewAccountClaims(accountKey).Encode(operator)
+	if err != nil {
+		t.Fatalf("failed to issue account jwt: %v", err)
+	}
+	resolver := &server.MemAccResolver{}
+	if err := resolver.Store(accountKey, accountJWT); err != nil {
+		t.Fatalf("failed to store account jwt: %v", err)
+	}
+
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithJetStream(false), func(opts *server.Options) {
+		opts.TrustedKeys = []string{operatorKey}
+		opts.AccountResolver = resolver
+	})
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	// writeCreds writes a .creds file for a new user and returns its key
+	path := filepath.Join(t.TempDir(), "user.creds")
+	writeCreds := func() string {
+		userKey, userSeed := newUserSeed(t)
+		userJWT, err := jwt.NewUserClaims(userKey).Encode(account)
+		if err != nil {
+			t.Fatalf("failed to issue user jwt: %v", err)
+		}
+		config, err := jwt.FormatUserConfig(userJWT, userSeed)
+		if err != nil {
+			t.Fatalf("failed to format creds: %v", err)
+		}
+		next := path + ".new"
+		if err := os.WriteFile(next, config, 0o600); err != nil {
+			t.Fatalf("failed to write creds: %v", err)
+		}
+		if err := os.Rename(next, path); err != nil {
+			t.Fatalf("failed to swap creds: %v", err)
+		}
+		return userKey
+	}
+	firstUser := writeCreds()
+
+	provider, err := credentials.NewFileProvider(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+	defer provider.Close()
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig:    cqrs.DefaultTransportConfig(),
+		URL:                srv.URL(),
+		Name:               "file-client",
+		CredentialProvider: provider,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect with creds file: %v", err)
+	}
+	defer transport.Close()
+
+	// connectedAs returns the users the server sees the client connected as
+	connectedAs := func() []string {
+		connz, err := srv.Server().Connz(&server.ConnzOptions{Username: true})
+		if err != nil {
+			t.Fatalf("failed to list connections: %v", err)
+		}
+		var users []string
+		for _, conn := range connz.Conns {
+			if conn.Name == "file-client" {
+				users = append(users, conn.AuthorizedUser)
+			}
+		}
+		return users
+	}
+	if users := connectedAs(); len(users) != 1 || users[0] != firstUser {
+		t.Fatalf("expected to be connected as %s, got %v", firstUser, users)
+	}
+
+	// The transport reconnects as the new user without waiting for a failure
+	secondUser := writeCreds()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		users := connectedAs()
+		if len(users) == 1 && users[0] == secondUser {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected to reconnect as %s, got %v", secondUser, users)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
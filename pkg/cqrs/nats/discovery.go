@@ -0,0 +1,102 @@
+package nats
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"github.com/plaenen/eventstore/pkg/cqrs"
+)
+
+// discoveryQuietPeriod is how long DiscoverServices waits for further replies
+// once a service has answered.
+const discoveryQuietPeriod = 250 * time.Millisecond
+
+// DiscoverServices asks every NATS micro service reachable through the
+// connection to describe itself, and returns one ServiceInfo per running
+// instance, sorted by name and ID. Endpoints registered by a Server carry the
+// request and response types of their protobuf method, so
+// cqrs.ServicesHandling can find the service behind a command:
+//
+//	services, err := transport.DiscoverServices(ctx)
+//	handlers := cqrs.ServicesHandling(services, "account.v1.OpenAccountCommand")
+//
+// It waits up to the transport timeout for the first reply, then returns once
+// no other service has replied for a short quiet period. Other micro services
+// on the same NATS account are listed too, without message types.
+func (t *Transport) DiscoverServices(ctx context.Context) ([]cqrs.ServiceInfo, error) {
+	subject, err := micro.ControlSubject(micro.InfoVerb, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	inbox := nats.NewInbox()
+	sub, err := t.nc.SubscribeSync(inbox)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe for service info: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	if err := t.nc.PublishRequest(subject, inbox, nil); err != nil {
+		return nil, fmt.Errorf("failed to request service info: %w", err)
+	}
+
+	var services []cqrs.ServiceInfo
+	wait := t.config.Timeout
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, wait)
+		msg, err := sub.NextMsgWithContext(waitCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("service discovery cancelled: %w", ctx.Err())
+			}
+			break
+		}
+
+		// The server answers with a 503 status when no service is listening
+		if len(msg.Data) == 0 && msg.Header.Get("Status") == "503" {
+			break
+		}
+
+		var info micro.Info
+		if err := json.Unmarshal(msg.Data, &info); err != nil {
+			return nil, fmt.Errorf("failed to decode service info: %w", err)
+		}
+		services = append(services, serviceInfo(info))
+		wait = discoveryQuietPeriod
+	}
+
+	slices.SortFunc(services, func(a, b cqrs.ServiceInfo) int {
+		return cmp.Or(strings.Compare(a.Name, b.Name), strings.Compare(a.ID, b.ID))
+	})
+	return services, nil
+}
+
+// serviceInfo converts a micro service description.
+func serviceInfo(info micro.Info) cqrs.ServiceInfo {
+	service := cqrs.ServiceInfo{
+		Name:        info.Name,
+		Version:     info.Version,
+		Description: info.Description,
+		ID:          info.ID,
+		Metadata:    info.Metadata,
+	}
+	for _, endpoint := range info.Endpoints {
+		service.Endpoints = append(service.Endpoints, cqrs.EndpointInfo{
+			Subject:      endpoint.Subject,
+			RequestType:  endpoint.Metadata[requestTypeMetadata],
+			ResponseType: endpoint.Metadata[responseTypeMetadata],
+		})
+	}
+	slices.SortFunc(service.Endpoints, func(a, b cqrs.EndpointInfo) int {
+		return strings.Compare(a.Subject, b.Subject)
+	})
+	return service
+}
@@ -0,0 +1,150 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// registerEchoService registers discovery.v1.EchoService, whose Echo method
+// takes and returns a google.protobuf.StringValue.
+func registerEchoService(t *testing.T) {
+	t.Helper()
+	if _, err := protoregistry.GlobalFiles.FindDescriptorByName("discovery.v1.EchoService"); err == nil {
+		return
+	}
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("discovery/v1/echo.proto"),
+		Package:    proto.String("discovery.v1"),
+		Dependency: []string{"google/protobuf/wrappers.proto"},
+		Syntax:     proto.String("proto3"),
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("EchoService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Echo"),
+				InputType:  proto.String(".google.protobuf.StringValue"),
+				OutputType: proto.String(".google.protobuf.StringValue"),
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build descriptor: %v", err)
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(file); err != nil {
+		t.Fatalf("failed to register descriptor: %v", err)
+	}
+}
+
+func TestDiscoverServices(t *testing.T) {
+	registerEchoService(t)
+
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "discovery-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	ctx := context.Background()
+
+	t.Run("NoServices", func(t *testing.T) {
+		services, err := transport.DiscoverServices(ctx)
+		if err != nil {
+			t.Fatalf("discovery failed: %v", err)
+		}
+		if len(services) != 0 {
+			t.Errorf("expected no services, got %+v", services)
+		}
+	})
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "EchoService",
+		Version:      "2.1.0",
+		Description:  "Echoes strings",
+		Metadata:     map[string]string{"region": "eu-west-1"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	handler := func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		return &eventsourcing.Response{Success: true}, nil
+	}
+	if err := server.RegisterHandler("discovery.v1.EchoService.Echo", handler); err != nil {
+		t.Fatalf("failed to register handler: %v", err)
+	}
+	if err := server.RegisterHandler("discovery.v1.Ping", handler); err != nil {
+		t.Fatalf("failed to register handler: %v", err)
+	}
+	if err := server.Start(ctx); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	t.Run("ListsServiceAndEndpoints", func(t *testing.T) {
+		services, err := transport.DiscoverServices(ctx)
+		if err != nil {
+			t.Fatalf("discovery failed: %v", err)
+		}
+		if len(services) != 1 {
+			t.Fatalf("expected 1 service, got %+v", services)
+		}
+
+		service := services[0]
+		if service.Name != "EchoService" || service.Version != "2.1.0" || service.Description != "Echoes strings" {
+			t.Errorf("unexpected service identity: %+v", service)
+		}
+		if service.Metadata["region"] != "eu-west-1" {
+			t.Errorf("expected region metadata, got %v", service.Metadata)
+		}
+
+		want := []cqrs.EndpointInfo{
+			{Subject: "discovery.v1.EchoService.Echo", RequestType: "google.protobuf.StringValue", ResponseType: "google.protobuf.StringValue"},
+			{Subject: "discovery.v1.Ping"},
+		}
+		if len(service.Endpoints) != len(want) {
+			t.Fatalf("expected endpoints %+v, got %+v", want, service.Endpoints)
+		}
+		for i := range want {
+			if service.Endpoints[i] != want[i] {
+				t.Errorf("endpoint %d: expected %+v, got %+v", i, want[i], service.Endpoints[i])
+			}
+		}
+	})
+
+	t.Run("FindsServiceByRequestType", func(t *testing.T) {
+		services, err := transport.DiscoverServices(ctx)
+		if err != nil {
+			t.Fatalf("discovery failed: %v", err)
+		}
+		if got := cqrs.ServicesHandling(services, "google.protobuf.StringValue"); len(got) != 1 {
+			t.Errorf("expected 1 service handling StringValue, got %d", len(got))
+		}
+		if got := cqrs.ServicesHandling(services, "discovery.v1.Ping"); len(got) != 1 {
+			t.Errorf("expected 1 service handling discovery.v1.Ping, got %d", len(got))
+		}
+		if got := cqrs.ServicesHandling(services, "account.v1.OpenAccountCommand"); len(got) != 0 {
+			t.Errorf("expected no service handling OpenAccountCommand, got %d", len(got))
+		}
+	})
+}
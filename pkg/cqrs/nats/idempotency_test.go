@@ -0,0 +1,73 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestRequestMetadataPropagation(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const subject = "echo.v1.EchoService.Key"
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "EchoService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	// The handler echoes the idempotency key and correlation ID it sees
+	server.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		key, _ := domain.IdempotencyKeyFromContext(ctx)
+		correlationID, _ := domain.CorrelationIDFromContext(ctx)
+		return eventsourcing.NewSuccessResponse(wrapperspb.String(key + "/" + correlationID))
+	})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "idempotency-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	for want, ctx := range map[string]context.Context{
+		"payment-42/":           domain.WithIdempotencyKey(context.Background(), "payment-42"),
+		"/transfer-7":           domain.WithCorrelationID(context.Background(), "transfer-7"),
+		"payment-42/transfer-7": domain.WithCorrelationID(domain.WithIdempotencyKey(context.Background(), "payment-42"), "transfer-7"),
+		"/":                     context.Background(),
+	} {
+		resp, err := transport.Request(ctx, subject, wrapperspb.String("ping"))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var echoed wrapperspb.StringValue
+		if err := resp.UnpackData(&echoed); err != nil {
+			t.Fatalf("failed to unpack response: %v", err)
+		}
+		if echoed.GetValue() != want {
+			t.Errorf("expected handler to see key/correlation ID %q, got %q", want, echoed.GetValue())
+		}
+	}
+}
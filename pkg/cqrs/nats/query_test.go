@@ -0,0 +1,75 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+)
+
+// balanceQueries answers GetAccountBalance from a fixed set of balances.
+type balanceQueries struct {
+	accountv1.AccountQueryServiceHandler // Other queries are not served
+	balances                             map[string]string
+}
+
+func (h *balanceQueries) GetAccountBalance(ctx context.Context, query *accountv1.GetAccountBalanceRequest) (*accountv1.BalanceView, *eventsourcing.AppError) {
+	balance, ok := h.balances[query.AccountId]
+	if !ok {
+		return nil, &eventsourcing.AppError{Code: "NOT_FOUND", Message: "account not found"}
+	}
+	return &accountv1.BalanceView{AccountId: query.AccountId, Balance: balance, Version: 1}, nil
+}
+
+func TestGeneratedQueryRoundTrip(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "AccountQueryService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	queryServer := accountv1.NewAccountQueryServiceServer(server, &balanceQueries{
+		balances: map[string]string{"acc-001": "250.00"},
+	})
+	if err := queryServer.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start query server: %v", err)
+	}
+	defer queryServer.Close()
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "account-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+	client := accountv1.NewAccountClient(transport)
+
+	balance, appErr := client.GetAccountBalance(context.Background(), &accountv1.GetAccountBalanceRequest{AccountId: "acc-001"})
+	if appErr != nil {
+		t.Fatalf("query failed: %v", appErr)
+	}
+	if balance.AccountId != "acc-001" || balance.Balance != "250.00" {
+		t.Errorf("expected balance 250.00 of acc-001, got %v", balance)
+	}
+
+	// Handler errors reach the client as application errors
+	_, appErr = client.GetAccountBalance(context.Background(), &accountv1.GetAccountBalanceRequest{AccountId: "acc-404"})
+	if appErr == nil || appErr.Code != "NOT_FOUND" {
+		t.Errorf("expected NOT_FOUND, got %v", appErr)
+	}
+}
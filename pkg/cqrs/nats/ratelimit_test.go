@@ -0,0 +1,165 @@
+package nats_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// startLimitedServer starts a server with serverConfig whose handler waits
+// delay, and returns a transport connected to it.
+func startLimitedServer(t *testing.T, serverConfig *cqrs.ServerConfig, subjects []string, delay time.Duration) *cqrsnats.Transport {
+	t.Helper()
+
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	t.Cleanup(srv.Shutdown)
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: serverConfig,
+		URL:          srv.URL(),
+		Name:         "LimitedService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	for _, subject := range subjects {
+		server.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+			time.Sleep(delay)
+			return eventsourcing.NewSuccessResponse(wrapperspb.String("ok"))
+		})
+	}
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "limited-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	t.Cleanup(func() { transport.Close() })
+	return transport
+}
+
+func TestServerPerTenantRateLimit(t *testing.T) {
+	const subject = "limited.v1.LimitedService.Do"
+
+	serverConfig := cqrs.DefaultServerConfig()
+	serverConfig.PerTenantRateLimit = &cqrs.RateLimit{PerSecond: 0.001, Burst: 1}
+	transport := startLimitedServer(t, serverConfig, []string{subject}, 0)
+
+	send := func(request proto.Message) *eventsourcing.Response {
+		t.Helper()
+		resp, err := transport.Request(context.Background(), subject, request)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+	rejected := func(resp *eventsourcing.Response) bool {
+		return !resp.Success && resp.GetError().GetCode() == cqrs.RateLimitedCode
+	}
+
+	// The tenant comes from the command's tenant_id
+	acme := &eventsourcing.StoredEventMetadata{TenantId: "acme"}
+	if resp := send(acme); !resp.Success {
+		t.Fatalf("expected the first acme command to pass, got %v", resp.GetError())
+	}
+	if resp := send(acme); !rejected(resp) {
+		t.Fatalf("expected the second acme command to be rate limited, got %v", resp.GetError())
+	}
+
+	// Another tenant has its own bucket
+	if resp := send(&eventsourcing.StoredEventMetadata{TenantId: "globex"}); !resp.Success {
+		t.Errorf("expected globex to be unaffected by acme's limit, got %v", resp.GetError())
+	}
+
+	// Commands without a tenant do not share a bucket
+	for i := 0; i < 3; i++ {
+		if resp := send(wrapperspb.String("anonymous")); !resp.Success {
+			t.Errorf("expected commands without a tenant to pass, got %v", resp.GetError())
+		}
+	}
+}
+
+func TestServerMaxConcurrentQueues(t *testing.T) {
+	// Each endpoint handles its requests in turn, so the cap applies across
+	// endpoints
+	subjects := []string{
+		"limited.v1.LimitedService.A",
+		"limited.v1.LimitedService.B",
+		"limited.v1.LimitedService.C",
+		"limited.v1.LimitedService.D",
+	}
+
+	serverConfig := cqrs.DefaultServerConfig()
+	serverConfig.MaxConcurrent = 1
+	transport := startLimitedServer(t, serverConfig, subjects, 50*time.Millisecond)
+
+	// Requests beyond the cap wait for a slot instead of being rejected
+	var wg sync.WaitGroup
+	errs := make(chan string, len(subjects))
+	for _, subject := range subjects {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := transport.Request(context.Background(), subject, wrapperspb.String("work"))
+			if err != nil {
+				errs <- err.Error()
+			} else if !resp.Success {
+				errs <- resp.GetError().GetMessage()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("expected queued requests to succeed, got %s", err)
+	}
+}
+
+func TestServerMaxConcurrentRejectsAfterTimeout(t *testing.T) {
+	subjects := []string{"limited.v1.LimitedService.A", "limited.v1.LimitedService.B"}
+
+	serverConfig := cqrs.DefaultServerConfig()
+	serverConfig.MaxConcurrent = 1
+	serverConfig.HandlerTimeout = 100 * time.Millisecond
+	transport := startLimitedServer(t, serverConfig, subjects, 300*time.Millisecond)
+
+	results := make(chan *eventsourcing.Response, len(subjects))
+	for _, subject := range subjects {
+		go func() {
+			resp, err := transport.Request(context.Background(), subject, wrapperspb.String("work"))
+			if err != nil {
+				t.Errorf("request failed: %v", err)
+			}
+			results <- resp
+		}()
+	}
+
+	var limited int
+	for range subjects {
+		if resp := <-results; resp != nil && resp.GetError().GetCode() == cqrs.RateLimitedCode {
+			limited++
+		}
+	}
+	if limited != 1 {
+		t.Errorf("expected the request still waiting at its timeout to be rate limited, got %d", limited)
+	}
+}
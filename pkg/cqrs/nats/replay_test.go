@@ -0,0 +1,136 @@
+package nats_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestReplayEvents(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	var events []*domain.Event
+	for i := 1; i <= 7; i++ {
+		events = append(events, &domain.Event{
+			ID:            fmt.Sprintf("evt-%d", i),
+			AggregateID:   "acc-1",
+			AggregateType: "Account",
+			EventType:     "account.v1.Deposited",
+			Version:       int64(i),
+			Timestamp:     time.Now().UTC().Truncate(time.Second),
+			Data:          []byte(fmt.Sprintf("payload-%d", i)),
+			Metadata: domain.EventMetadata{
+				CorrelationID: "corr-1",
+				Custom:        map[string]string{"seq": fmt.Sprint(i)},
+			},
+		})
+	}
+	if err := eventStore.AppendEvents(context.Background(), "acc-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	serverConfig := cqrs.DefaultServerConfig()
+	serverConfig.Middleware = []cqrs.HandlerMiddleware{
+		cqrs.AuthorizeReplay(func(ctx context.Context, aggregateID string) error {
+			if tenant, _ := ctx.Value("tenant_id").(string); tenant != "auditor" {
+				return errors.New("replay requires the auditor tenant")
+			}
+			return nil
+		}),
+	}
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: serverConfig,
+		URL:          srv.URL(),
+		Name:         "AccountService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+	if err := cqrs.RegisterReplayEvents(server, "account.v1", eventStore); err != nil {
+		t.Fatalf("failed to register replay: %v", err)
+	}
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "audit-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	auditor := context.WithValue(context.Background(), "tenant_id", "auditor")
+
+	t.Run("ReassemblesAggregateAcrossPages", func(t *testing.T) {
+		var replayed []*domain.Event
+		for event, err := range cqrs.ReplayEvents(auditor, transport, "account.v1", "acc-1", 0, 3) {
+			if err != nil {
+				t.Fatalf("replay failed: %v", err)
+			}
+			replayed = append(replayed, event)
+		}
+
+		if len(replayed) != len(events) {
+			t.Fatalf("expected %d events, got %d", len(events), len(replayed))
+		}
+		for i, event := range replayed {
+			want := events[i]
+			if event.ID != want.ID || event.Version != want.Version || string(event.Data) != string(want.Data) {
+				t.Errorf("event %d: got %s v%d %q, want %s v%d %q",
+					i, event.ID, event.Version, event.Data, want.ID, want.Version, want.Data)
+			}
+			if !event.Timestamp.Equal(want.Timestamp) {
+				t.Errorf("event %d: timestamp %v, want %v", i, event.Timestamp, want.Timestamp)
+			}
+			if event.Metadata.CorrelationID != "corr-1" || event.Metadata.Custom["seq"] != fmt.Sprint(i+1) {
+				t.Errorf("event %d: metadata not preserved: %+v", i, event.Metadata)
+			}
+		}
+	})
+
+	t.Run("FromVersion", func(t *testing.T) {
+		var versions []int64
+		for event, err := range cqrs.ReplayEvents(auditor, transport, "account.v1", "acc-1", 5, 0) {
+			if err != nil {
+				t.Fatalf("replay failed: %v", err)
+			}
+			versions = append(versions, event.Version)
+		}
+		if fmt.Sprint(versions) != "[6 7]" {
+			t.Errorf("expected versions [6 7], got %v", versions)
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		for _, err := range cqrs.ReplayEvents(context.Background(), transport, "account.v1", "acc-1", 0, 0) {
+			var respErr *eventsourcing.ResponseError
+			if !errors.As(err, &respErr) || respErr.AppError.Code != cqrs.ReplayForbiddenCode {
+				t.Fatalf("expected %s error, got %v", cqrs.ReplayForbiddenCode, err)
+			}
+		}
+	})
+}
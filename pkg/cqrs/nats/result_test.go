@@ -0,0 +1,82 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestServerCommandResult(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const subject = "account.v1.AccountCommandService.Withdraw"
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "AccountService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	server.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		domain.SetCommandResult(ctx, wrapperspb.String("950.00"))
+		return eventsourcing.NewSuccessResponse(&emptypb.Empty{})
+	})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "account-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	balance := &wrapperspb.StringValue{}
+	resp, err := transport.RequestWithOptions(context.Background(), subject, wrapperspb.String("acc-1"),
+		eventsourcing.WithCommandResultInto(balance))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected the command to succeed, got %v", resp.GetError())
+	}
+	if balance.GetValue() != "950.00" {
+		t.Errorf("expected the handler's result, got %q", balance.GetValue())
+	}
+
+	// A result of another type is rejected rather than misread
+	resp, err = transport.RequestWithOptions(context.Background(), subject, wrapperspb.String("acc-1"),
+		eventsourcing.WithCommandResultInto(&wrapperspb.Int64Value{}))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.Success || resp.GetError().GetCode() != "INVALID_RESPONSE" {
+		t.Errorf("expected INVALID_RESPONSE for a result of the wrong type, got %v", resp)
+	}
+
+	// Callers that ask for no result are unaffected
+	resp, err = transport.Request(context.Background(), subject, wrapperspb.String("acc-1"))
+	if err != nil || !resp.Success {
+		t.Errorf("expected the command to succeed without a result target, got %v, %v", resp, err)
+	}
+}
@@ -3,15 +3,19 @@ package nats
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/micro"
 	"github.com/plaenen/eventstore/pkg/cqrs"
+	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/eventsourcing"
 	"github.com/plaenen/eventstore/pkg/observability"
 	"go.opentelemetry.io/otel/propagation"
+	"golang.org/x/time/rate"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
@@ -28,11 +32,24 @@ type Server struct {
 	cancel   context.CancelFunc
 
 	// Service metadata
-	serviceName    string
-	serviceVersion string
+	serviceName        string
+	serviceVersion     string
+	serviceDescription string
+	serviceMetadata    map[string]string
 
 	// Observability (optional)
 	telemetry *observability.Telemetry
+
+	// Admission control (optional)
+	globalLimiter *rate.Limiter
+	tenantLimiter *cqrs.KeyedRateLimiter
+	inflight      chan struct{}
+
+	// Requests being handled, drained by Shutdown
+	requestsMu sync.Mutex
+	closing    bool
+	active     int
+	idle       chan struct{} // Closed once closing and no request is active
 }
 
 // ServerConfig extends the base server config with NATS-specific options
@@ -42,6 +59,10 @@ type ServerConfig struct {
 	// URL is the NATS server URL (e.g., "nats://localhost:4222")
 	URL string
 
+	// ConnectOptions are extra options for the NATS connection, e.g. the
+	// in-process connection of an embedded server (optional)
+	ConnectOptions []nats.Option
+
 	// Name is the service name (e.g., "AccountService")
 	Name string
 
@@ -51,6 +72,10 @@ type ServerConfig struct {
 	// Description is a human-readable service description
 	Description string
 
+	// Metadata is advertised with the service and returned by
+	// Transport.DiscoverServices (e.g., {"region": "eu-west-1"})
+	Metadata map[string]string
+
 	// Credentials for authentication (optional)
 	Token string
 	User  string
@@ -97,6 +122,7 @@ func NewServer(config *ServerConfig) (*Server, error) {
 	}
 
 	// Connect to NATS
+	opts = append(opts, config.ConnectOptions...)
 	nc, err := nats.Connect(config.URL, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
@@ -104,17 +130,33 @@ func NewServer(config *ServerConfig) (*Server, error) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Server{
-		nc:             nc,
-		config:         config.ServerConfig,
-		handlers:       make(map[string]cqrs.HandlerFunc),
-		services:       make([]micro.Service, 0),
-		ctx:            ctx,
-		cancel:         cancel,
-		serviceName:    config.Name,
-		serviceVersion: config.Version,
-		telemetry:      config.Telemetry,
-	}, nil
+	server := &Server{
+		nc:                 nc,
+		config:             config.ServerConfig,
+		handlers:           make(map[string]cqrs.HandlerFunc),
+		services:           make([]micro.Service, 0),
+		ctx:                ctx,
+		cancel:             cancel,
+		serviceName:        config.Name,
+		serviceVersion:     config.Version,
+		serviceDescription: config.Description,
+		serviceMetadata:    config.Metadata,
+		telemetry:          config.Telemetry,
+		idle:               make(chan struct{}),
+	}
+
+	// Configure admission control
+	if rl := config.RateLimit; rl != nil {
+		server.globalLimiter = rate.NewLimiter(rate.Limit(rl.PerSecond), rl.Burst)
+	}
+	if rl := config.PerTenantRateLimit; rl != nil {
+		server.tenantLimiter = cqrs.NewKeyedRateLimiter(*rl, 0)
+	}
+	if config.MaxConcurrent > 0 {
+		server.inflight = make(chan struct{}, config.MaxConcurrent)
+	}
+
+	return server, nil
 }
 
 // RegisterHandler registers a handler for a specific subject
@@ -126,6 +168,11 @@ func (s *Server) RegisterHandler(subject string, handler cqrs.HandlerFunc) error
 		return fmt.Errorf("handler already registered for subject: %s", subject)
 	}
 
+	// Apply server middleware, the first entry outermost
+	for i := len(s.config.Middleware) - 1; i >= 0; i-- {
+		handler = s.config.Middleware[i](handler)
+	}
+
 	// Wrap handler with observability middleware if telemetry is configured
 	if s.telemetry != nil {
 		middleware := observability.HandlerMiddleware(s.telemetry, subject)
@@ -145,11 +192,17 @@ func (s *Server) Start(ctx context.Context) error {
 		return fmt.Errorf("no handlers registered")
 	}
 
+	description := s.serviceDescription
+	if description == "" {
+		description = fmt.Sprintf("Event sourcing service with %d endpoints", len(s.handlers))
+	}
+
 	// Create a single NATS microservice with multiple endpoints
 	config := micro.Config{
 		Name:        s.serviceName,
 		Version:     s.serviceVersion,
-		Description: fmt.Sprintf("Event sourcing service with %d endpoints", len(s.handlers)),
+		Description: description,
+		Metadata:    s.serviceMetadata,
 		QueueGroup:  s.config.QueueGroup,
 	}
 
@@ -166,10 +219,10 @@ func (s *Server) Start(ctx context.Context) error {
 		// Create endpoint name by replacing dots with dashes (endpoint names can't have dots)
 		endpointName := strings.ReplaceAll(subject, ".", "-")
 
-		// Add endpoint with the subject
+		// Add endpoint with the subject, advertising its message types for discovery
 		err = svc.AddEndpoint(endpointName, micro.HandlerFunc(func(req micro.Request) {
 			s.handleMicroRequest(req, h)
-		}), micro.WithEndpointSubject(subject))
+		}), micro.WithEndpointSubject(subject), micro.WithEndpointMetadata(endpointMetadata(subject)))
 		if err != nil {
 			return fmt.Errorf("failed to add endpoint %s: %w", subject, err)
 		}
@@ -182,8 +235,31 @@ func (s *Server) Start(ctx context.Context) error {
 
 // handleMicroRequest processes an incoming micro request
 func (s *Server) handleMicroRequest(req micro.Request, handler cqrs.HandlerFunc) {
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(s.ctx, s.config.HandlerTimeout)
+	if !s.beginRequest() {
+		s.respondMicroWithError(req, cqrs.ShuttingDownCode, "Server is shutting down, retry later")
+		return
+	}
+	defer s.endRequest()
+
+	// Create context with timeout; the client may override it per request, up
+	// to MaxHandlerTimeout
+	timeout := s.config.HandlerTimeout
+	if value := req.Headers().Get(cqrs.HeaderRequestTimeout); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			s.respondMicroWithError(req, "INVALID_REQUEST", fmt.Sprintf("Invalid %s header: %q", cqrs.HeaderRequestTimeout, value))
+			return
+		}
+		limit := s.config.MaxHandlerTimeout
+		if limit == 0 {
+			limit = cqrs.DefaultMaxHandlerTimeout
+		}
+		if limit > 0 {
+			d = min(d, limit)
+		}
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, timeout)
 	defer cancel()
 
 	// Extract trace context from NATS headers for distributed tracing
@@ -193,12 +269,31 @@ func (s *Server) handleMicroRequest(req micro.Request, handler cqrs.HandlerFunc)
 	}
 
 	// Extract metadata from headers
-	if tenantID := req.Headers().Get("Tenant-ID"); tenantID != "" {
+	tenantID := req.Headers().Get(cqrs.HeaderTenantID)
+	if tenantID != "" {
 		ctx = context.WithValue(ctx, "tenant_id", tenantID)
 	}
 	if traceID := req.Headers().Get("Trace-ID"); traceID != "" {
 		ctx = context.WithValue(ctx, "trace_id", traceID)
 	}
+	if key := req.Headers().Get(cqrs.HeaderIdempotencyKey); key != "" {
+		ctx = domain.WithIdempotencyKey(ctx, key)
+	}
+	if correlationID := req.Headers().Get(cqrs.HeaderCorrelationID); correlationID != "" {
+		ctx = domain.WithCorrelationID(ctx, correlationID)
+	}
+	consistency, err := cqrs.ConsistencyFromHeader(req.Headers().Get(cqrs.HeaderReadAfterPosition))
+	if err != nil {
+		s.respondMicroWithError(req, "INVALID_REQUEST", err.Error())
+		return
+	}
+	ctx = cqrs.WithConsistency(ctx, consistency)
+
+	// Let command handlers report the global position they reached, and a
+	// result for the caller
+	tracker := &cqrs.PositionTracker{}
+	ctx = cqrs.WithPositionTracker(ctx, tracker)
+	ctx, handlerResult := domain.WithCommandResult(ctx)
 
 	// Deserialize request based on Message-Type header
 	messageType := req.Headers().Get("Message-Type")
@@ -214,16 +309,34 @@ func (s *Server) handleMicroRequest(req micro.Request, handler cqrs.HandlerFunc)
 		return
 	}
 
-	// Unmarshal request
-	if err := proto.Unmarshal(req.Data(), request); err != nil {
+	// Unmarshal request with the codec the client announced
+	codec, ok := s.requestCodec(req)
+	if !ok {
+		s.respondMicroWithError(req, "UNSUPPORTED_CONTENT_TYPE", fmt.Sprintf("Unsupported content type: %s", req.Headers().Get(cqrs.HeaderContentType)))
+		return
+	}
+	if err := codec.Unmarshal(req.Data(), request); err != nil {
 		s.respondMicroWithError(req, "INVALID_REQUEST", fmt.Sprintf("Failed to unmarshal request: %v", err))
 		return
 	}
 
-	// Call handler
+	// Reject excess load before running the handler
+	tenantID = requestTenantID(request, tenantID)
+	release, reason, ok := s.admit(ctx, tenantID)
+	if !ok {
+		if s.telemetry != nil && s.telemetry.Metrics != nil {
+			s.telemetry.Metrics.RecordCommandThrottled(ctx, req.Subject(), tenantID, reason)
+		}
+		s.respondMicroWithError(req, cqrs.RateLimitedCode, fmt.Sprintf("Request rejected by %s limit, retry later", reason))
+		return
+	}
+	defer release()
+
+	// Call handler, which may stream results before its response
+	ctx = cqrs.WithStreamSender(ctx, s.streamSender(ctx, req, codec))
 	response, err := handler(ctx, request)
 	if err != nil {
-		s.respondMicroWithError(req, "HANDLER_ERROR", err.Error())
+		s.respondMicroWithAppError(req, eventsourcing.AppErrorFromError(err, "HANDLER_ERROR"))
 		return
 	}
 
@@ -232,19 +345,110 @@ func (s *Server) handleMicroRequest(req micro.Request, handler cqrs.HandlerFunc)
 		response = eventsourcing.NewSimpleErrorResponse("HANDLER_ERROR", "Handler returned nil response")
 	}
 
-	// Marshal response
-	responseData, err := proto.Marshal(response)
+	// Marshal response in the request's encoding
+	responseData, err := codec.Marshal(response)
 	if err != nil {
 		s.respondMicroWithError(req, "INTERNAL_ERROR", fmt.Sprintf("Failed to marshal response: %v", err))
 		return
 	}
 
-	// Send response
-	if err := req.Respond(responseData); err != nil {
+	// Send response, with the command's global position and result if the
+	// handler reported them
+	headers := micro.Headers{cqrs.HeaderContentType: []string{codec.ContentType()}}
+	if position := tracker.Position(); position > 0 {
+		headers[cqrs.HeaderResultPosition] = []string{strconv.FormatInt(position, 10)}
+	}
+	if result := handlerResult(); result != nil && response.Success {
+		value, err := cqrs.EncodeCommandResult(result)
+		if err != nil {
+			s.respondMicroWithError(req, "INTERNAL_ERROR", err.Error())
+			return
+		}
+		headers[cqrs.HeaderCommandResult] = []string{value}
+	}
+	if err := req.Respond(responseData, micro.WithHeaders(headers)); err != nil {
 		fmt.Printf("Failed to send response: %v\n", err)
 	}
 }
 
+// requestCodec returns the codec for the request's content type, or the
+// configured codec for requests without one.
+func (s *Server) requestCodec(req micro.Request) (cqrs.Codec, bool) {
+	contentType := req.Headers().Get(cqrs.HeaderContentType)
+	if codec := s.config.Codec; codec != nil && (contentType == "" || contentType == codec.ContentType()) {
+		return codec, true
+	}
+	return cqrs.CodecForContentType(contentType)
+}
+
+// tenantRequest is implemented by requests that carry the tenant of their
+// command, such as protobuf messages with a tenant_id field.
+type tenantRequest interface {
+	GetTenantId() string
+}
+
+// requestTenantID returns the tenant of request: its own tenant_id when it has
+// one, the tenant of the request headers otherwise.
+func requestTenantID(request proto.Message, headerTenantID string) string {
+	if r, ok := request.(tenantRequest); ok && r.GetTenantId() != "" {
+		return r.GetTenantId()
+	}
+	return headerTenantID
+}
+
+// admit applies the concurrency cap and rate limits to a request. Requests
+// beyond MaxConcurrent wait for a slot until ctx is done. Requests without a
+// tenant are exempt from PerTenantRateLimit and bounded by RateLimit only.
+// On success the returned release function must be called when the request is done.
+// On rejection it returns the name of the limit that was hit.
+func (s *Server) admit(ctx context.Context, tenantID string) (release func(), reason string, ok bool) {
+	release = func() {}
+
+	if s.inflight != nil {
+		select {
+		case s.inflight <- struct{}{}:
+			release = func() { <-s.inflight }
+		case <-ctx.Done():
+			return nil, "concurrency", false
+		}
+	}
+
+	if s.tenantLimiter != nil && tenantID != "" && !s.tenantLimiter.Allow(tenantID) {
+		release()
+		return nil, "tenant", false
+	}
+
+	if s.globalLimiter != nil && !s.globalLimiter.Allow() {
+		release()
+		return nil, "global", false
+	}
+
+	return release, "", true
+}
+
+// Endpoint metadata keys advertised for service discovery
+const (
+	requestTypeMetadata  = "request_type"
+	responseTypeMetadata = "response_type"
+)
+
+// endpointMetadata returns the message types of the protobuf method named by
+// subject, or nil if subject is not a registered method.
+func endpointMetadata(subject string) map[string]string {
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(subject))
+	if err != nil {
+		return nil
+	}
+	method, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil
+	}
+	return map[string]string{
+		requestTypeMetadata:  string(method.Input().FullName()),
+		responseTypeMetadata: string(method.Output().FullName()),
+	}
+}
+
 // createMessageInstance creates a proto message instance from a type name
 func (s *Server) createMessageInstance(messageType string) (proto.Message, error) {
 	// Look up message type in proto registry
@@ -259,40 +463,119 @@ func (s *Server) createMessageInstance(messageType string) (proto.Message, error
 
 // respondMicroWithError sends an error response for micro requests
 func (s *Server) respondMicroWithError(req micro.Request, code, message string) {
-	response := eventsourcing.NewSimpleErrorResponse(code, message)
-	responseData, err := proto.Marshal(response)
+	s.respondMicroWithAppError(req, &eventsourcing.AppError{Code: code, Message: message})
+}
+
+// respondMicroWithAppError sends appErr, including its details and field
+// violations, as the response to a micro request. It is encoded like the
+// request, or as protobuf if the request's content type is unsupported.
+func (s *Server) respondMicroWithAppError(req micro.Request, appErr *eventsourcing.AppError) {
+	codec, ok := s.requestCodec(req)
+	if !ok {
+		codec = cqrs.ProtoCodec
+	}
+
+	response := &eventsourcing.Response{Success: false, Error: appErr}
+	responseData, err := codec.Marshal(response)
 	if err != nil {
 		fmt.Printf("Failed to marshal error response: %v\n", err)
 		return
 	}
 
-	if err := req.Respond(responseData); err != nil {
+	headers := micro.Headers{cqrs.HeaderContentType: []string{codec.ContentType()}}
+	if err := req.Respond(responseData, micro.WithHeaders(headers)); err != nil {
 		fmt.Printf("Failed to send error response: %v\n", err)
 	}
 }
 
-// Close stops the server and closes all services
-func (s *Server) Close() error {
+// beginRequest counts a request as in flight, unless the server is shutting
+// down. endRequest must be called when it is done.
+func (s *Server) beginRequest() bool {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	if s.closing {
+		return false
+	}
+	s.active++
+	return true
+}
+
+// endRequest ends a request started with beginRequest.
+func (s *Server) endRequest() {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+	s.active--
+	if s.closing && s.active == 0 {
+		close(s.idle)
+	}
+}
+
+// Shutdown stops the server gracefully: it stops accepting requests (those
+// still arriving fail with cqrs.ShuttingDownCode), waits for the requests in
+// flight to finish or ctx to be done, and only then cancels the handler
+// contexts and closes the NATS connection. It returns ctx.Err() if requests
+// were still running when ctx was done.
+//
+// Shutdown does not listen for signals; wire SIGTERM to it, e.g.:
+//
+//	runner.WaitForShutdownSignal()
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	server.Shutdown(ctx)
+func (s *Server) Shutdown(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Cancel context
-	s.cancel()
+	s.requestsMu.Lock()
+	alreadyClosing := s.closing
+	if !s.closing {
+		s.closing = true
+		if s.active == 0 {
+			close(s.idle)
+		}
+	}
+	s.requestsMu.Unlock()
+	if alreadyClosing {
+		return nil
+	}
 
-	// Stop all services
+	// Stop receiving requests
 	for _, svc := range s.services {
 		if err := svc.Stop(); err != nil {
 			fmt.Printf("Error stopping service: %v\n", err)
 		}
 	}
 
+	// Let the requests in flight finish
+	var err error
+	select {
+	case <-s.idle:
+	case <-ctx.Done():
+		err = fmt.Errorf("requests still in flight: %w", ctx.Err())
+	}
+
+	// Cancel what is left
+	s.cancel()
+
 	// Close NATS connection
 	if s.nc != nil {
 		s.nc.Close()
 	}
 
 	fmt.Println("NATS services stopped")
-	return nil
+	return err
+}
+
+// Close stops the server like Shutdown, giving requests in flight up to the
+// handler timeout (30 seconds if unset) to finish.
+func (s *Server) Close() error {
+	timeout := s.config.HandlerTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.Shutdown(ctx)
 }
 
 // IsConnected returns true if connected to NATS
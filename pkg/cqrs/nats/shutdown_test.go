@@ -0,0 +1,143 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestServerShutdownDrainsRequests(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const subject = "import.v1.ImportService.BulkImport"
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "ImportService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	started := make(chan struct{}, 1)
+	server.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		started <- struct{}{}
+		select {
+		case <-time.After(200 * time.Millisecond):
+			return eventsourcing.NewSuccessResponse(wrapperspb.String("imported"))
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "import-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	type result struct {
+		resp *eventsourcing.Response
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := transport.Request(context.Background(), subject, wrapperspb.String("import"))
+		inFlight <- result{resp, err}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shut down: %v", err)
+	}
+
+	// The request in flight ran to completion
+	r := <-inFlight
+	if r.err != nil || !r.resp.Success {
+		t.Fatalf("expected the request in flight to succeed, got %v (%v)", r.resp, r.err)
+	}
+
+	// New requests are not handled
+	resp, err := transport.Request(context.Background(), subject, wrapperspb.String("import"))
+	if err == nil && resp.Success {
+		t.Error("expected requests after shutdown to fail")
+	}
+}
+
+func TestServerShutdownDeadline(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const subject = "import.v1.ImportService.BulkImport"
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "ImportService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+
+	started := make(chan struct{}, 1)
+	cancelled := make(chan struct{})
+	server.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "import-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	go transport.Request(context.Background(), subject, wrapperspb.String("import"))
+	<-started
+
+	// A handler outliving the shutdown deadline is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); err == nil {
+		t.Error("expected the shutdown to report the request still in flight")
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the handler context to be cancelled")
+	}
+}
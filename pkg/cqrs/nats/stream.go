@@ -0,0 +1,146 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+)
+
+// RegisterQueryStream registers a streaming query for subject. Each result
+// the handler sends is published to the client's reply inbox as its own
+// message, so large result sets never have to fit into one message of at
+// most the NATS MaxPayload. Clients read the results with
+// Transport.RequestStream. Server middleware wraps the stream like any other
+// handler, and ServerConfig.HandlerTimeout bounds the whole stream.
+func (s *Server) RegisterQueryStream(subject string, handler cqrs.StreamHandlerFunc) error {
+	return s.RegisterHandler(subject, cqrs.StreamHandler(handler))
+}
+
+// streamSender returns the function that publishes the chunks of a streamed
+// response to req, encoded like the request.
+func (s *Server) streamSender(ctx context.Context, req micro.Request, codec cqrs.Codec) func(proto.Message) error {
+	var sequence int64
+	return func(result proto.Message) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		response, err := eventsourcing.NewSuccessResponse(result)
+		if err != nil {
+			return err
+		}
+		data, err := codec.Marshal(response)
+		if err != nil {
+			return fmt.Errorf("failed to marshal stream chunk: %w", err)
+		}
+
+		sequence++
+		msg := nats.NewMsg(req.Reply())
+		msg.Data = data
+		msg.Header.Set(cqrs.HeaderContentType, codec.ContentType())
+		msg.Header.Set(cqrs.HeaderStreamSequence, strconv.FormatInt(sequence, 10))
+		if err := s.nc.PublishMsg(msg); err != nil {
+			return fmt.Errorf("failed to send stream chunk: %w", err)
+		}
+		return nil
+	}
+}
+
+// RequestStream sends a streaming query (see Server.RegisterQueryStream) and
+// yields each result as it arrives, as a successful response to unpack with
+// UnpackData. Iteration ends after the last result, or with an error if the
+// query failed, a result was lost, or no result arrived within the request
+// timeout (TransportConfig.Timeout, eventsourcing.WithCommandTimeout or the
+// context deadline). Breaking out of the loop stops listening; the server
+// finishes sending regardless.
+//
+// Example:
+//
+//	for resp, err := range transport.RequestStream(ctx, subject, query) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    event := &accountv1.AccountEvent{}
+//	    if err := resp.UnpackData(event); err != nil {
+//	        return err
+//	    }
+//	    fmt.Println(event)
+//	}
+func (t *Transport) RequestStream(ctx context.Context, subject string, request proto.Message) iter.Seq2[*eventsourcing.Response, error] {
+	return func(yield func(*eventsourcing.Response, error) bool) {
+		msg, codec, timeout, err := t.newRequestMsg(ctx, subject, request)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		// Listen on a reply inbox of our own: the server replies more than once
+		msg.Reply = t.nc.NewInbox()
+		sub, err := t.nc.SubscribeSync(msg.Reply)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to subscribe to reply inbox: %w", err))
+			return
+		}
+		defer sub.Unsubscribe()
+		if err := t.nc.PublishMsg(msg); err != nil {
+			yield(nil, fmt.Errorf("request failed: %w", err))
+			return
+		}
+
+		var sequence int64
+		for {
+			next, err := t.nextStreamMsg(ctx, sub, timeout)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if next.Header.Get("Status") == "503" {
+				yield(nil, fmt.Errorf("request failed: %w", nats.ErrNoResponders))
+				return
+			}
+
+			response, err := decodeResponse(next, codec)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			value := next.Header.Get(cqrs.HeaderStreamSequence)
+			if value == "" {
+				// The end of the stream
+				if !response.Success {
+					yield(nil, response.AsError())
+				}
+				return
+			}
+			sequence++
+			if value != strconv.FormatInt(sequence, 10) {
+				yield(nil, fmt.Errorf("stream chunk %d lost, got chunk %s", sequence, value))
+				return
+			}
+			if !yield(response, nil) {
+				return
+			}
+		}
+	}
+}
+
+// nextStreamMsg waits at most timeout for the next message of a stream.
+func (t *Transport) nextStreamMsg(ctx context.Context, sub *nats.Subscription, timeout time.Duration) (*nats.Msg, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	msg, err := sub.NextMsgWithContext(waitCtx)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return nil, fmt.Errorf("stream timed out: no response within %s", timeout)
+	}
+	return msg, err
+}
@@ -0,0 +1,107 @@
+package nats_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestQueryStream(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const subject = "history.v1.HistoryService.StreamHistory"
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "HistoryService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	// The handler streams as many results as requested; a negative count fails
+	// after two results
+	err = server.RegisterQueryStream(subject, func(ctx context.Context, request proto.Message, send func(proto.Message) error) error {
+		n := request.(*wrapperspb.Int64Value).GetValue()
+		for i := int64(1); i <= max(n, 2); i++ {
+			if err := send(wrapperspb.Int64(i)); err != nil {
+				return err
+			}
+		}
+		if n < 0 {
+			return errors.New("history unavailable")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to register stream: %v", err)
+	}
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "history-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	t.Run("AllResults", func(t *testing.T) {
+		var count int64
+		for resp, err := range transport.RequestStream(context.Background(), subject, wrapperspb.Int64(5000)) {
+			if err != nil {
+				t.Fatalf("stream failed after %d results: %v", count, err)
+			}
+			var result wrapperspb.Int64Value
+			if err := resp.UnpackData(&result); err != nil {
+				t.Fatalf("failed to unpack result: %v", err)
+			}
+			count++
+			if result.GetValue() != count {
+				t.Fatalf("expected result %d, got %d", count, result.GetValue())
+			}
+		}
+		if count != 5000 {
+			t.Errorf("expected 5000 results, got %d", count)
+		}
+	})
+
+	t.Run("HandlerError", func(t *testing.T) {
+		var count int
+		var streamErr error
+		for _, err := range transport.RequestStream(context.Background(), subject, wrapperspb.Int64(-1)) {
+			if err != nil {
+				streamErr = err
+				break
+			}
+			count++
+		}
+		if count != 2 || streamErr == nil {
+			t.Errorf("expected 2 results and an error, got %d results and %v", count, streamErr)
+		}
+	})
+
+	t.Run("NoResponders", func(t *testing.T) {
+		for _, err := range transport.RequestStream(context.Background(), "history.v1.HistoryService.Missing", wrapperspb.Int64(1)) {
+			if err == nil {
+				t.Fatal("expected the stream to fail without a service")
+			}
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCommandTimeoutOverride(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const subject = "import.v1.ImportService.BulkImport"
+
+	serverConfig := cqrs.DefaultServerConfig()
+	serverConfig.HandlerTimeout = 100 * time.Millisecond
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: serverConfig,
+		URL:          srv.URL(),
+		Name:         "ImportService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	// The handler takes longer than the default timeouts and reports how much
+	// time its context had left
+	server.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		deadline, _ := ctx.Deadline()
+		remaining := time.Until(deadline)
+		select {
+		case <-time.After(300 * time.Millisecond):
+			return eventsourcing.NewSuccessResponse(durationpb.New(remaining))
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transportConfig := cqrs.DefaultTransportConfig()
+	transportConfig.Timeout = 100 * time.Millisecond
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: transportConfig,
+		URL:             srv.URL(),
+		Name:            "import-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	resp, err := transport.Request(context.Background(), subject, wrapperspb.String("import"))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("expected the command to time out with the default timeout")
+	}
+
+	resp, err = transport.RequestWithOptions(context.Background(), subject, wrapperspb.String("import"),
+		eventsourcing.WithCommandTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected the command to succeed with a longer timeout, got %v", resp.GetError())
+	}
+	var remaining durationpb.Duration
+	if err := resp.UnpackData(&remaining); err != nil {
+		t.Fatalf("failed to unpack response: %v", err)
+	}
+	if d := remaining.AsDuration(); d < 4*time.Second || d > 5*time.Second {
+		t.Errorf("expected the handler deadline to follow the command timeout, got %v left", d)
+	}
+
+	// A shorter context deadline still wins over the command timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	resp, err = transport.RequestWithOptions(ctx, subject, wrapperspb.String("import"),
+		eventsourcing.WithCommandTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("expected the context deadline to bound the command timeout")
+	}
+}
+
+func TestCommandTimeoutClampedToMaxHandlerTimeout(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const subject = "import.v1.ImportService.BulkImport"
+
+	serverConfig := cqrs.DefaultServerConfig()
+	serverConfig.MaxHandlerTimeout = 200 * time.Millisecond
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: serverConfig,
+		URL:          srv.URL(),
+		Name:         "ImportService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	server.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		deadline, _ := ctx.Deadline()
+		return eventsourcing.NewSuccessResponse(durationpb.New(time.Until(deadline)))
+	})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "import-client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	// The client asks for an hour, the server grants at most MaxHandlerTimeout
+	resp, err := transport.RequestWithOptions(context.Background(), subject, wrapperspb.String("import"),
+		eventsourcing.WithCommandTimeout(time.Hour))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected the command to succeed, got %v", resp.GetError())
+	}
+	var remaining durationpb.Duration
+	if err := resp.UnpackData(&remaining); err != nil {
+		t.Fatalf("failed to unpack response: %v", err)
+	}
+	if d := remaining.AsDuration(); d <= 0 || d > 200*time.Millisecond {
+		t.Errorf("expected the handler deadline to be clamped to 200ms, got %v left", d)
+	}
+}
@@ -3,10 +3,13 @@ package nats
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/plaenen/eventstore/pkg/cqrs"
+	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/eventsourcing"
 	"github.com/plaenen/eventstore/pkg/observability"
 	"github.com/plaenen/eventstore/pkg/security/credentials"
@@ -16,9 +19,17 @@ import (
 
 // Transport implements cqrs.Transport using NATS request/reply
 type Transport struct {
-	nc        *nats.Conn
-	config    *cqrs.TransportConfig
-	telemetry *observability.Telemetry
+	nc          *nats.Conn
+	config      *cqrs.TransportConfig
+	telemetry   *observability.Telemetry
+	breakers    *cqrs.CircuitBreakers // nil = no circuit breaker
+	credentials credentials.Provider  // nil = no credential provider
+	stopWatch   func()                // stops reconnecting on credential changes
+
+	refreshBefore time.Duration
+	refreshMu     sync.Mutex
+	refresh       *time.Timer // re-authenticates ahead of ExpiresAt, if any
+	closed        bool
 }
 
 // TransportConfig extends the base transport config with NATS-specific options
@@ -28,6 +39,10 @@ type TransportConfig struct {
 	// URL is the NATS server URL (e.g., "nats://localhost:4222")
 	URL string
 
+	// ConnectOptions are extra options for the NATS connection, e.g. the
+	// in-process connection of an embedded server (optional)
+	ConnectOptions []nats.Option
+
 	// Name is the client name for connection identification
 	Name string
 
@@ -35,6 +50,11 @@ type TransportConfig struct {
 	// Use this instead of Token/User/Pass for production deployments
 	CredentialProvider credentials.Provider
 
+	// CredentialRefreshBefore is how long before the ExpiresAt of the
+	// provider's credentials the transport rotates them and reconnects, so
+	// the connection never outlives them (default: 1 minute)
+	CredentialRefreshBefore time.Duration
+
 	// Deprecated: Use CredentialProvider instead
 	// Token is a bearer token for authentication (INSECURE - plaintext)
 	// This field will be removed in v1.0.0
@@ -77,55 +97,31 @@ func NewTransport(config *TransportConfig) (*Transport, error) {
 		nats.ReconnectHandler(func(nc *nats.Conn) {
 			fmt.Printf("NATS reconnected to %s\n", nc.ConnectedUrl())
 		}),
+		nats.ReconnectErrHandler(func(nc *nats.Conn, err error) {
+			fmt.Printf("NATS reconnect attempt failed: %v\n", err)
+			if config.Telemetry != nil && config.Telemetry.Metrics != nil {
+				config.Telemetry.Metrics.RecordNATSReconnectAttempt(context.Background(), config.Name)
+			}
+		}),
 	}
 
-	// Add authentication - prefer CredentialProvider over deprecated fields
+	// Back off exponentially once every server has been tried
+	if backoff := config.ReconnectBackoff; backoff != nil {
+		opts = append(opts, nats.CustomReconnectDelay(func(attempts int) time.Duration {
+			delay := backoff.Delay(attempts)
+			fmt.Printf("NATS reconnecting in %s (attempt %d)\n", delay, attempts)
+			return delay
+		}))
+	}
+
+	// Add authentication - prefer CredentialProvider over deprecated fields.
+	// Credentials are fetched again on every reconnect, so rotated ones apply.
 	if config.CredentialProvider != nil {
-		// Use secure credential provider
-		ctx := context.Background()
-		creds, err := config.CredentialProvider.GetCredentials(ctx)
+		credentialOpts, err := credentials.NATSOptions(context.Background(), config.CredentialProvider)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get credentials: %w", err)
-		}
-
-		// Apply credentials based on type
-		switch creds.Type {
-		case credentials.CredentialTypeToken:
-			if creds.Token == "" {
-				return nil, fmt.Errorf("token credential is empty")
-			}
-			opts = append(opts, nats.Token(creds.Token))
-
-		case credentials.CredentialTypeUserPassword:
-			if creds.User == "" || creds.Password == "" {
-				return nil, fmt.Errorf("user/password credentials are incomplete")
-			}
-			opts = append(opts, nats.UserInfo(creds.User, creds.Password))
-
-		case credentials.CredentialTypeNKey:
-			if creds.Seed == "" {
-				return nil, fmt.Errorf("nkey seed is empty")
-			}
-			// Parse NKey seed
-			kp, err := nats.NkeyOptionFromSeed(creds.Seed)
-			if err != nil {
-				return nil, fmt.Errorf("invalid nkey seed: %w", err)
-			}
-			opts = append(opts, kp)
-
-		case credentials.CredentialTypeJWT:
-			// JWT authentication would typically use UserJWT option
-			// This requires a JWT and a signature callback
-			return nil, fmt.Errorf("JWT authentication not yet implemented")
-
-		case credentials.CredentialTypeMTLS:
-			// mTLS would be configured via TLS config, not here
-			return nil, fmt.Errorf("mTLS should be configured via TLS config, not credential provider")
-
-		default:
-			return nil, fmt.Errorf("unsupported credential type: %s", creds.Type)
+			return nil, err
 		}
-
+		opts = append(opts, credentialOpts...)
 	} else {
 		// Fall back to deprecated fields for backward compatibility
 		// Warn user about insecure usage
@@ -139,16 +135,113 @@ func NewTransport(config *TransportConfig) (*Transport, error) {
 	}
 
 	// Connect to NATS
+	opts = append(opts, config.ConnectOptions...)
 	nc, err := nats.Connect(config.URL, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
 
-	return &Transport{
-		nc:        nc,
-		config:    config.TransportConfig,
-		telemetry: config.Telemetry,
-	}, nil
+	transport := &Transport{
+		nc:          nc,
+		config:      config.TransportConfig,
+		telemetry:   config.Telemetry,
+		credentials: config.CredentialProvider,
+
+		refreshBefore: config.CredentialRefreshBefore,
+	}
+	if transport.refreshBefore <= 0 {
+		transport.refreshBefore = time.Minute
+	}
+	if breaker := config.CircuitBreaker; breaker != nil {
+		transport.breakers = cqrs.NewCircuitBreakers(transport.circuitBreaker(*breaker))
+	}
+
+	// Re-authenticate as soon as a provider notices new credentials (e.g., a
+	// rotated credentials file), rather than when the old ones are refused
+	if notifier, ok := config.CredentialProvider.(credentials.ChangeNotifier); ok {
+		transport.stopWatch = notifier.OnChange(func(*credentials.Credentials) {
+			if err := nc.ForceReconnect(); err != nil && !nc.IsClosed() {
+				fmt.Printf("NATS re-authentication failed: %v\n", err)
+			}
+		})
+	}
+	if config.CredentialProvider != nil {
+		transport.scheduleCredentialRefresh()
+	}
+	return transport, nil
+}
+
+// scheduleCredentialRefresh arms a timer that rotates the credentials and
+// reconnects refreshBefore ahead of their ExpiresAt, if they have one. A
+// provider that keeps handing out credentials about to expire is retried at
+// most every refreshBefore/10 (but at least a second apart).
+func (t *Transport) scheduleCredentialRefresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), credentials.ReconnectCredentialsTimeout)
+	creds, err := t.credentials.GetCredentials(ctx)
+	cancel()
+	if err != nil || creds.ExpiresAt == nil {
+		return
+	}
+	delay := max(time.Until(*creds.ExpiresAt)-t.refreshBefore, t.refreshBefore/10, time.Second)
+
+	t.refreshMu.Lock()
+	defer t.refreshMu.Unlock()
+	if !t.closed {
+		t.refresh = time.AfterFunc(delay, t.refreshCredentials)
+	}
+}
+
+// refreshCredentials re-authenticates before the credentials expire, then
+// schedules the next refresh.
+func (t *Transport) refreshCredentials() {
+	ctx, cancel := context.WithTimeout(context.Background(), t.refreshBefore)
+	err := t.RotateCredentials(ctx)
+	cancel()
+	if err != nil && !t.nc.IsClosed() {
+		fmt.Printf("NATS credential refresh failed: %v\n", err)
+	}
+	t.scheduleCredentialRefresh()
+}
+
+// RotateCredentials rotates the credentials of TransportConfig.CredentialProvider
+// and reconnects, so the connection authenticates with the new ones (e.g., a
+// refreshed JWT) before the old ones expire. It returns once reconnected, or
+// with ctx's error; requests in flight during the reconnect may time out.
+// Credentials with an ExpiresAt are rotated this way automatically, see
+// TransportConfig.CredentialRefreshBefore.
+func (t *Transport) RotateCredentials(ctx context.Context) error {
+	if t.credentials == nil {
+		return fmt.Errorf("no credential provider configured")
+	}
+	if err := t.credentials.Rotate(ctx); err != nil {
+		return fmt.Errorf("failed to rotate credentials: %w", err)
+	}
+
+	connected := t.nc.StatusChanged(nats.CONNECTED)
+	defer t.nc.RemoveStatusListener(connected)
+	if err := t.nc.ForceReconnect(); err != nil {
+		return fmt.Errorf("failed to reconnect: %w", err)
+	}
+	select {
+	case <-connected:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to reconnect: %w", ctx.Err())
+	}
+}
+
+// circuitBreaker returns breaker with state changes also recorded as metrics.
+func (t *Transport) circuitBreaker(breaker cqrs.CircuitBreaker) cqrs.CircuitBreaker {
+	onStateChange := breaker.OnStateChange
+	breaker.OnStateChange = func(subject string, from, to cqrs.CircuitState) {
+		if t.telemetry != nil && t.telemetry.Metrics != nil {
+			t.telemetry.Metrics.RecordCircuitBreakerState(context.Background(), subject, int64(to), to.String())
+		}
+		if onStateChange != nil {
+			onStateChange(subject, from, to)
+		}
+	}
+	return breaker
 }
 
 // Request sends a request and waits for a response with automatic retry on version conflicts
@@ -163,6 +256,13 @@ func (t *Transport) Request(ctx context.Context, subject string, request proto.M
 	return t.doRequestWithRetry(ctx, subject, request)
 }
 
+// RequestWithOptions is Request with per-call options, e.g.
+// eventsourcing.WithCommandTimeout to give one command longer than
+// TransportConfig.Timeout.
+func (t *Transport) RequestWithOptions(ctx context.Context, subject string, request proto.Message, opts ...eventsourcing.RequestOption) (*eventsourcing.Response, error) {
+	return t.Request(eventsourcing.WithRequestOptions(ctx, opts...), subject, request)
+}
+
 // doRequestWithRetry wraps doRequest with retry logic for handling version conflicts
 func (t *Transport) doRequestWithRetry(ctx context.Context, subject string, request proto.Message) (*eventsourcing.Response, error) {
 	maxRetries := t.config.MaxRetries
@@ -254,23 +354,84 @@ func findSubstring(s, substr string) bool {
 
 // doRequest performs the actual NATS request
 func (t *Transport) doRequest(ctx context.Context, subject string, request proto.Message) (*eventsourcing.Response, error) {
+	msg, codec, timeout, err := t.newRequestMsg(ctx, subject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail fast while the subject's backend is unresponsive
+	if t.breakers != nil {
+		if err := t.breakers.Allow(subject); err != nil {
+			return nil, fmt.Errorf("request to %s: %w", subject, err)
+		}
+	}
+
+	// Send request and wait for response
+	respMsg, err := t.nc.RequestMsg(msg, timeout)
+	if t.breakers != nil {
+		t.breakers.Record(subject, err != nil)
+	}
+	if err != nil {
+		if err == nats.ErrTimeout {
+			return eventsourcing.NewSimpleErrorResponse("TIMEOUT", "Request timed out"), nil
+		}
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	response, err := decodeResponse(respMsg, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hand the command's global position to the caller for read-your-writes queries
+	if value := respMsg.Header.Get(cqrs.HeaderResultPosition); value != "" {
+		if position, err := strconv.ParseInt(value, 10, 64); err == nil {
+			cqrs.ReportPosition(ctx, position)
+		}
+	}
+
+	// And its result, if the caller asked for one
+	if value := respMsg.Header.Get(cqrs.HeaderCommandResult); value != "" && response.Success {
+		if err := cqrs.DecodeCommandResult(ctx, value); err != nil {
+			return eventsourcing.NewSimpleErrorResponse("INVALID_RESPONSE", err.Error()), nil
+		}
+	}
+
+	return response, nil
+}
+
+// newRequestMsg builds the NATS message of a request, with the metadata of
+// ctx in its headers, and returns the codec of its payload and how long to
+// wait for the reply.
+func (t *Transport) newRequestMsg(ctx context.Context, subject string, request proto.Message) (*nats.Msg, cqrs.Codec, time.Duration, error) {
 	// Serialize request
-	requestData, err := proto.Marshal(request)
+	codec := t.codec()
+	requestData, err := codec.Marshal(request)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, nil, 0, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Create NATS message with metadata
 	msg := nats.NewMsg(subject)
 	msg.Data = requestData
+	msg.Header.Set(cqrs.HeaderContentType, codec.ContentType())
 
 	// Add metadata from context (tenant, trace IDs, etc.)
 	if tenantID, ok := ctx.Value("tenant_id").(string); ok {
-		msg.Header.Set("Tenant-ID", tenantID)
+		msg.Header.Set(cqrs.HeaderTenantID, tenantID)
 	}
 	if traceID, ok := ctx.Value("trace_id").(string); ok {
 		msg.Header.Set("Trace-ID", traceID)
 	}
+	if key, ok := domain.IdempotencyKeyFromContext(ctx); ok {
+		msg.Header.Set(cqrs.HeaderIdempotencyKey, key)
+	}
+	if correlationID, ok := domain.CorrelationIDFromContext(ctx); ok {
+		msg.Header.Set(cqrs.HeaderCorrelationID, correlationID)
+	}
+	if c := cqrs.ConsistencyFromContext(ctx); c.Mode == cqrs.ConsistencyReadYourWrites {
+		msg.Header.Set(cqrs.HeaderReadAfterPosition, strconv.FormatInt(c.AfterPosition, 10))
+	}
 
 	// Inject trace context into NATS headers for distributed tracing
 	if t.telemetry != nil {
@@ -281,30 +442,49 @@ func (t *Transport) doRequest(ctx context.Context, subject string, request proto
 	// Set message type for server-side routing
 	msg.Header.Set("Message-Type", string(request.ProtoReflect().Descriptor().FullName()))
 
-	// Determine timeout from context or use default
+	// Determine timeout from the request options, the context or the default
 	timeout := t.config.Timeout
-	if deadline, ok := ctx.Deadline(); ok {
+	override := eventsourcing.RequestOptionsFromContext(ctx).Timeout
+	if override > 0 {
+		timeout = override
+	}
+	if deadline, ok := ctx.Deadline(); ok && (override <= 0 || time.Until(deadline) < timeout) {
 		timeout = time.Until(deadline)
 	}
-
-	// Send request and wait for response
-	respMsg, err := t.nc.RequestMsg(msg, timeout)
-	if err != nil {
-		if err == nats.ErrTimeout {
-			return eventsourcing.NewSimpleErrorResponse("TIMEOUT", "Request timed out"), nil
-		}
-		return nil, fmt.Errorf("request failed: %w", err)
+	if override > 0 {
+		// Give the server-side handler the same deadline
+		msg.Header.Set(cqrs.HeaderRequestTimeout, timeout.String())
 	}
 
-	// Deserialize response
+	return msg, codec, timeout, nil
+}
+
+// decodeResponse deserializes a response by its content type; older servers
+// send none. codec is the codec of the request.
+func decodeResponse(respMsg *nats.Msg, codec cqrs.Codec) (*eventsourcing.Response, error) {
+	contentType := respMsg.Header.Get(cqrs.HeaderContentType)
+	responseCodec, ok := cqrs.CodecForContentType(contentType)
+	if contentType != "" && contentType == codec.ContentType() {
+		responseCodec, ok = codec, true
+	}
+	if !ok {
+		return nil, fmt.Errorf("unsupported response content type %q", contentType)
+	}
 	response := &eventsourcing.Response{}
-	if err := proto.Unmarshal(respMsg.Data, response); err != nil {
+	if err := responseCodec.Unmarshal(respMsg.Data, response); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-
 	return response, nil
 }
 
+// codec returns the configured request codec.
+func (t *Transport) codec() cqrs.Codec {
+	if t.config.Codec != nil {
+		return t.config.Codec
+	}
+	return cqrs.ProtoCodec
+}
+
 // natsHeaderCarrier adapts NATS headers to propagation.TextMapCarrier
 type natsHeaderCarrier struct {
 	header nats.Header
@@ -328,6 +508,16 @@ func (c *natsHeaderCarrier) Keys() []string {
 
 // Close closes the NATS connection
 func (t *Transport) Close() error {
+	t.refreshMu.Lock()
+	t.closed = true
+	if t.refresh != nil {
+		t.refresh.Stop()
+	}
+	t.refreshMu.Unlock()
+
+	if t.stopWatch != nil {
+		t.stopWatch()
+	}
 	if t.nc != nil {
 		t.nc.Close()
 	}
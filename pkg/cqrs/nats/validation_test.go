@@ -0,0 +1,97 @@
+package nats_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	cqrsnats "github.com/plaenen/eventstore/pkg/cqrs/nats"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestFieldViolationsCrossTransport(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	const (
+		validated = "account.v1.AccountCommandService.OpenAccount"
+		failing   = "account.v1.AccountCommandService.CloseAccount"
+	)
+
+	server, err := cqrsnats.NewServer(&cqrsnats.ServerConfig{
+		ServerConfig: cqrs.DefaultServerConfig(),
+		URL:          srv.URL(),
+		Name:         "AccountService",
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	defer server.Close()
+
+	// Handlers returning a Go error: the structured one must survive wrapping
+	server.RegisterHandler(validated, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		return nil, fmt.Errorf("open account: %w", eventsourcing.NewValidationError("invalid account",
+			&eventsourcing.FieldViolation{Field: "owner.email", Constraint: "email", Message: "must be an email address"},
+			&eventsourcing.FieldViolation{Field: "initial_balance", Constraint: "required", Message: "is required"},
+		))
+	})
+	server.RegisterHandler(failing, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		return nil, errors.New("boom")
+	})
+	if err := server.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+
+	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+		TransportConfig: cqrs.DefaultTransportConfig(),
+		URL:             srv.URL(),
+		Name:            "client",
+	})
+	if err != nil {
+		t.Fatalf("failed to create transport: %v", err)
+	}
+	defer transport.Close()
+
+	t.Run("ValidationError", func(t *testing.T) {
+		resp, err := transport.Request(context.Background(), validated, wrapperspb.String("acc-1"))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+
+		var responseErr *eventsourcing.ResponseError
+		if !errors.As(resp.AsError(), &responseErr) || responseErr.Code() != eventsourcing.ValidationFailedCode {
+			t.Fatalf("expected a %s error, got %v", eventsourcing.ValidationFailedCode, resp.AsError())
+		}
+		violations := responseErr.FieldViolations()
+		if len(violations) != 2 {
+			t.Fatalf("expected 2 field violations, got %v", violations)
+		}
+		if v := violations[0]; v.Field != "owner.email" || v.Constraint != "email" || v.Message != "must be an email address" {
+			t.Errorf("unexpected first violation: %v", v)
+		}
+		if violations[1].Field != "initial_balance" {
+			t.Errorf("unexpected second violation: %v", violations[1])
+		}
+	})
+
+	t.Run("PlainError", func(t *testing.T) {
+		resp, err := transport.Request(context.Background(), failing, wrapperspb.String("acc-1"))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.Error.GetCode() != "HANDLER_ERROR" || resp.Error.GetMessage() != "boom" {
+			t.Errorf("expected HANDLER_ERROR boom, got %v", resp.Error)
+		}
+		if len(resp.Error.GetFieldViolations()) != 0 {
+			t.Errorf("expected no field violations, got %v", resp.Error.GetFieldViolations())
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package cqrs
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitedCode is the AppError code returned when a request is rejected
+// by a rate limit or concurrency cap. Clients should back off and retry.
+const RateLimitedCode = "RATE_LIMITED"
+
+// RateLimit configures a token bucket.
+type RateLimit struct {
+	// PerSecond is the sustained number of requests allowed per second
+	PerSecond float64
+
+	// Burst is the maximum number of requests allowed in a single burst
+	Burst int
+}
+
+// KeyedRateLimiter maintains one token bucket per key (e.g., per tenant).
+// Buckets that have not been used for the idle timeout are evicted so that
+// a large or unbounded key space does not grow memory forever.
+type KeyedRateLimiter struct {
+	limit       RateLimit
+	idleTimeout time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*keyedBucket
+	lastSweep time.Time
+}
+
+type keyedBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewKeyedRateLimiter creates a limiter that applies limit independently per key.
+// Buckets idle for longer than idleTimeout are evicted (default: 10 minutes).
+func NewKeyedRateLimiter(limit RateLimit, idleTimeout time.Duration) *KeyedRateLimiter {
+	if idleTimeout <= 0 {
+		idleTimeout = 10 * time.Minute
+	}
+	return &KeyedRateLimiter{
+		limit:       limit,
+		idleTimeout: idleTimeout,
+		buckets:     make(map[string]*keyedBucket),
+		lastSweep:   time.Now(),
+	}
+}
+
+// Allow reports whether a request for key may proceed, consuming a token if so.
+func (l *KeyedRateLimiter) Allow(key string) bool {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= l.idleTimeout {
+		l.evictIdle(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &keyedBucket{
+			limiter: rate.NewLimiter(rate.Limit(l.limit.PerSecond), l.limit.Burst),
+		}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	return b.limiter.AllowN(now, 1)
+}
+
+// Len returns the number of buckets currently tracked.
+func (l *KeyedRateLimiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// evictIdle removes buckets not used within the idle timeout. Must hold l.mu.
+func (l *KeyedRateLimiter) evictIdle(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) >= l.idleTimeout {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
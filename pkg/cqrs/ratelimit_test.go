@@ -0,0 +1,45 @@
+package cqrs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+)
+
+func TestKeyedRateLimiter(t *testing.T) {
+	t.Run("LimitsEachKeyIndependently", func(t *testing.T) {
+		limiter := cqrs.NewKeyedRateLimiter(cqrs.RateLimit{PerSecond: 1, Burst: 2}, time.Minute)
+
+		for i := 0; i < 2; i++ {
+			if !limiter.Allow("tenant-a") {
+				t.Fatalf("request %d for tenant-a should be allowed within burst", i+1)
+			}
+		}
+		if limiter.Allow("tenant-a") {
+			t.Error("expected tenant-a to be throttled after exhausting its burst")
+		}
+
+		// A noisy tenant must not affect others
+		if !limiter.Allow("tenant-b") {
+			t.Error("expected tenant-b to be allowed")
+		}
+	})
+
+	t.Run("EvictsIdleBuckets", func(t *testing.T) {
+		limiter := cqrs.NewKeyedRateLimiter(cqrs.RateLimit{PerSecond: 1, Burst: 1}, 20*time.Millisecond)
+
+		limiter.Allow("tenant-a")
+		limiter.Allow("tenant-b")
+		if got := limiter.Len(); got != 2 {
+			t.Fatalf("expected 2 buckets, got %d", got)
+		}
+
+		time.Sleep(30 * time.Millisecond)
+		limiter.Allow("tenant-c")
+
+		if got := limiter.Len(); got != 1 {
+			t.Errorf("expected idle buckets to be evicted, got %d buckets", got)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package cqrs
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// ReconnectBackoff spaces out reconnection attempts exponentially. When many
+// clients lose their connection at once (e.g., a cluster restart), a fixed
+// ReconnectWait makes them all retry in lockstep; growing, jittered delays
+// spread the reconnects out.
+type ReconnectBackoff struct {
+	// Base is the delay before the first retry; it doubles with each attempt
+	Base time.Duration
+
+	// Max caps the delay (0 = no cap)
+	Max time.Duration
+
+	// Jitter is the fraction of each delay that is randomized, from 0 (none)
+	// to 1 (anywhere between zero and the full delay)
+	Jitter float64
+}
+
+// Delay returns the wait before reconnection attempt n (starting at 1).
+func (b ReconnectBackoff) Delay(attempt int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempt && delay > 0; i++ {
+		if b.Max > 0 && delay >= b.Max {
+			break
+		}
+		if delay >= time.Duration(1<<62) {
+			break // doubling would overflow
+		}
+		delay *= 2
+	}
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+
+	jitter := min(max(b.Jitter, 0), 1)
+	return delay - time.Duration(rand.Float64()*jitter*float64(delay))
+}
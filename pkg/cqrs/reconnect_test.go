@@ -0,0 +1,53 @@
+package cqrs_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+)
+
+func TestReconnectBackoff_Delay(t *testing.T) {
+	backoff := cqrs.ReconnectBackoff{Base: 100 * time.Millisecond, Max: time.Second}
+
+	for attempt, want := range map[int]time.Duration{
+		1:    100 * time.Millisecond,
+		2:    200 * time.Millisecond,
+		4:    800 * time.Millisecond,
+		5:    time.Second,
+		1000: time.Second,
+	} {
+		if got := backoff.Delay(attempt); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+
+	t.Run("Jitter", func(t *testing.T) {
+		backoff.Jitter = 0.5
+		seen := make(map[time.Duration]bool)
+		for range 100 {
+			delay := backoff.Delay(5)
+			if delay < 500*time.Millisecond || delay > time.Second {
+				t.Fatalf("expected a delay between 500ms and 1s, got %v", delay)
+			}
+			seen[delay] = true
+		}
+		if len(seen) < 2 {
+			t.Error("expected jittered delays to differ")
+		}
+	})
+
+	t.Run("Uncapped", func(t *testing.T) {
+		uncapped := cqrs.ReconnectBackoff{Base: time.Second}
+		if got := uncapped.Delay(100); got <= 0 {
+			t.Errorf("expected a positive delay without overflow, got %v", got)
+		}
+	})
+
+	t.Run("StopsAtOverflowBoundary", func(t *testing.T) {
+		huge := cqrs.ReconnectBackoff{Base: time.Duration(1 << 61)}
+		if got := huge.Delay(3); got != time.Duration(1<<62) {
+			t.Errorf("expected the delay to stop doubling at %v, got %v", time.Duration(1<<62), got)
+		}
+	})
+}
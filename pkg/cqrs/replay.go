@@ -0,0 +1,159 @@
+package cqrs
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store"
+	"google.golang.org/protobuf/proto"
+)
+
+// ReplayForbiddenCode is the AppError code returned when a ReplayEvents or
+// AuditTrail request is rejected by AuthorizeReplay or AuthorizeAuditTrail.
+const ReplayForbiddenCode = "FORBIDDEN"
+
+const (
+	defaultReplayLimit = 100
+	maxReplayLimit     = 1000
+)
+
+// ReplayEventsSubject returns the subject of the built-in ReplayEvents query
+// of a service, e.g. ReplayEventsSubject("account.v1") is
+// "account.v1.EventStoreService.ReplayEvents".
+func ReplayEventsSubject(service string) string {
+	return service + ".EventStoreService.ReplayEvents"
+}
+
+// HandlerMiddleware wraps every handler registered on a server (see
+// ServerConfig.Middleware).
+type HandlerMiddleware func(next HandlerFunc) HandlerFunc
+
+// RegisterReplayEvents registers the built-in ReplayEvents query on server, so
+// remote clients (audit tools, debuggers) can read an aggregate's raw events
+// with ReplayEvents without any generated code. The endpoint is not
+// authorized by default and exposes every event in eventStore: protect it
+// with AuthorizeReplay in ServerConfig.Middleware.
+func RegisterReplayEvents(server Server, service string, eventStore store.EventStore) error {
+	return server.RegisterHandler(ReplayEventsSubject(service), ReplayEventsHandler(eventStore))
+}
+
+// ReplayEventsHandler serves ReplayEventsRequest from eventStore, one page of
+// at most limit events (default 100, at most 1000) per request. Pages are read
+// by version, so with an event store implementing store.EventStreamer (the
+// SQLite store does) each request reads only its page.
+func ReplayEventsHandler(eventStore store.EventStore) HandlerFunc {
+	return func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		req, ok := request.(*eventsourcing.ReplayEventsRequest)
+		if !ok {
+			return eventsourcing.NewSimpleErrorResponse("INVALID_REQUEST",
+				fmt.Sprintf("expected ReplayEventsRequest, got %T", request)), nil
+		}
+		if req.GetAggregateId() == "" {
+			return eventsourcing.NewSimpleErrorResponse("INVALID_REQUEST", "aggregate_id is required"), nil
+		}
+
+		limit := int(req.GetLimit())
+		if limit <= 0 {
+			limit = defaultReplayLimit
+		}
+		if limit > maxReplayLimit {
+			limit = maxReplayLimit
+		}
+
+		// One event more than the page tells whether there is a next page
+		events, err := store.LoadEventsPage(ctx, eventStore, req.GetAggregateId(), req.GetFromVersion(), limit+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load events: %w", err)
+		}
+
+		page := &eventsourcing.ReplayEventsResponse{}
+		if len(events) > limit {
+			events = events[:limit]
+			page.HasMore = true
+		}
+		for _, event := range events {
+			page.Events = append(page.Events, eventsourcing.NewStoredEvent(event))
+		}
+
+		return eventsourcing.NewSuccessResponse(page)
+	}
+}
+
+// AuthorizeReplay returns server middleware that calls authorize before every
+// ReplayEvents request and rejects the request with ReplayForbiddenCode when
+// it returns an error. Other requests pass through untouched.
+//
+// Example:
+//
+//	config.Middleware = append(config.Middleware, cqrs.AuthorizeReplay(
+//	    func(ctx context.Context, aggregateID string) error {
+//	        if !isAuditor(ctx) {
+//	            return errors.New("replay requires the auditor role")
+//	        }
+//	        return nil
+//	    }))
+func AuthorizeReplay(authorize func(ctx context.Context, aggregateID string) error) HandlerMiddleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+			if req, ok := request.(*eventsourcing.ReplayEventsRequest); ok {
+				if err := authorize(ctx, req.GetAggregateId()); err != nil {
+					return eventsourcing.NewSimpleErrorResponse(ReplayForbiddenCode, err.Error()), nil
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// ReplayEvents streams the events of aggregateID after fromVersion from a
+// service's ReplayEvents query, requesting pages of pageSize events (0 = server
+// default) as the caller iterates. Iteration stops at the first error.
+//
+// Example:
+//
+//	for event, err := range cqrs.ReplayEvents(ctx, transport, "account.v1", "acc-123", 0, 0) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    msg, _ := accountv1.EventRegistry.Decode(event)
+//	    fmt.Println(event.Version, msg)
+//	}
+func ReplayEvents(ctx context.Context, transport Transport, service, aggregateID string, fromVersion int64, pageSize int) iter.Seq2[*domain.Event, error] {
+	return func(yield func(*domain.Event, error) bool) {
+		subject := ReplayEventsSubject(service)
+		for {
+			resp, err := transport.Request(ctx, subject, &eventsourcing.ReplayEventsRequest{
+				AggregateId: aggregateID,
+				FromVersion: fromVersion,
+				Limit:       int32(pageSize),
+			})
+			if err == nil {
+				err = resp.AsError()
+			}
+			if err != nil {
+				yield(nil, fmt.Errorf("replay %s: %w", aggregateID, err))
+				return
+			}
+
+			page := &eventsourcing.ReplayEventsResponse{}
+			if err := resp.UnpackData(page); err != nil {
+				yield(nil, fmt.Errorf("replay %s: %w", aggregateID, err))
+				return
+			}
+
+			for _, stored := range page.GetEvents() {
+				event := stored.ToEvent()
+				if !yield(event, nil) {
+					return
+				}
+				fromVersion = event.Version
+			}
+			if !page.GetHasMore() || len(page.GetEvents()) == 0 {
+				return
+			}
+		}
+	}
+}
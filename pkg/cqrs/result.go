@@ -0,0 +1,66 @@
+package cqrs
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// HeaderCommandResult carries the result a command handler set with
+// domain.SetCommandResult, as a base64-encoded google.protobuf.Any. Clients
+// receive it with eventsourcing.WithCommandResultInto.
+const HeaderCommandResult = "Command-Result"
+
+// EncodeCommandResult encodes result for HeaderCommandResult.
+func EncodeCommandResult(result proto.Message) (string, error) {
+	packed, err := anypb.New(result)
+	if err != nil {
+		return "", fmt.Errorf("failed to pack command result: %w", err)
+	}
+	data, err := proto.Marshal(packed)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal command result: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecodeCommandResult unpacks a HeaderCommandResult value into the
+// eventsourcing.WithCommandResultInto target of ctx, if there is one. It
+// fails if the result is not of the target's message type.
+func DecodeCommandResult(ctx context.Context, value string) error {
+	target := eventsourcing.RequestOptionsFromContext(ctx).Result
+	if target == nil {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s header: %w", HeaderCommandResult, err)
+	}
+	packed := &anypb.Any{}
+	if err := proto.Unmarshal(data, packed); err != nil {
+		return fmt.Errorf("invalid %s header: %w", HeaderCommandResult, err)
+	}
+	if want, have := target.ProtoReflect().Descriptor().FullName(), packed.MessageName(); want != have {
+		return fmt.Errorf("command result is a %s, not a %s", have, want)
+	}
+	return packed.UnmarshalTo(target)
+}
+
+// deliverCommandResult copies result into the eventsourcing.WithCommandResultInto
+// target of ctx, if there is one, checking its type like DecodeCommandResult.
+func deliverCommandResult(ctx context.Context, result proto.Message) error {
+	target := eventsourcing.RequestOptionsFromContext(ctx).Result
+	if target == nil {
+		return nil
+	}
+	if want, have := target.ProtoReflect().Descriptor().FullName(), result.ProtoReflect().Descriptor().FullName(); want != have {
+		return fmt.Errorf("command result is a %s, not a %s", have, want)
+	}
+	proto.Reset(target)
+	proto.Merge(target, result)
+	return nil
+}
@@ -0,0 +1,44 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+)
+
+// HeaderStreamSequence numbers the chunks of a streamed query response from
+// 1. The message that ends the stream has none: it is an ordinary response,
+// failed if the stream failed.
+const HeaderStreamSequence = "Stream-Sequence"
+
+// StreamHandlerFunc serves a streaming query, calling send once per result as
+// it is produced instead of building one large response. Returning an error
+// ends the stream with that error after the results already sent.
+type StreamHandlerFunc func(ctx context.Context, request proto.Message, send func(result proto.Message) error) error
+
+type streamSenderKey struct{}
+
+// WithStreamSender returns a context carrying the function that sends a
+// chunk of a streamed response. Servers set it for every request.
+func WithStreamSender(ctx context.Context, send func(result proto.Message) error) context.Context {
+	return context.WithValue(ctx, streamSenderKey{}, send)
+}
+
+// StreamHandler adapts a streaming query to a HandlerFunc, so server
+// middleware wraps streams like any other handler. The HandlerFunc sends the
+// results through the sender of WithStreamSender and returns the response
+// that ends the stream.
+func StreamHandler(stream StreamHandlerFunc) HandlerFunc {
+	return func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		send, ok := ctx.Value(streamSenderKey{}).(func(proto.Message) error)
+		if !ok {
+			return nil, errors.New("server does not support streamed responses")
+		}
+		if err := stream(ctx, request, send); err != nil {
+			return nil, err
+		}
+		return &eventsourcing.Response{Success: true}, nil
+	}
+}
@@ -0,0 +1,113 @@
+package cqrs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrBusClosed is returned when a request is sent to a closed SyncCommandBus.
+var ErrBusClosed = errors.New("command bus is closed")
+
+// SyncCommandBus is an in-process Transport and Server that executes handlers
+// inline, in the caller's goroutine. When Request returns, the handler has run
+// to completion, so tests can assert on state immediately, with no sleeps.
+//
+// Generated servers register their handlers on it exactly like on a NATS server,
+// and generated clients and SDKs use it as their transport:
+//
+//	bus := cqrs.NewSyncCommandBus()
+//	accountv1.NewAccountCommandServiceServer(bus, handler).Start(ctx)
+//	sdk := accountv1.NewAccountSDK(bus)
+//
+// Requests are cloned before being handed to the handler, so handlers never
+// share a message with the caller. Handler errors and nil responses are turned
+// into error responses, and command results delivered, the same way the NATS
+// server and transport do.
+type SyncCommandBus struct {
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	closed   bool
+}
+
+// NewSyncCommandBus creates an empty synchronous command bus.
+func NewSyncCommandBus() *SyncCommandBus {
+	return &SyncCommandBus{
+		handlers: make(map[string]HandlerFunc),
+	}
+}
+
+// RegisterHandler registers a handler for a subject.
+func (b *SyncCommandBus) RegisterHandler(subject string, handler HandlerFunc) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.handlers[subject]; exists {
+		return fmt.Errorf("handler already registered for subject: %s", subject)
+	}
+	b.handlers[subject] = handler
+	return nil
+}
+
+// Start is a no-op: handlers are available as soon as they are registered.
+func (b *SyncCommandBus) Start(ctx context.Context) error {
+	return nil
+}
+
+// Request executes the handler registered for subject and returns its response.
+func (b *SyncCommandBus) Request(ctx context.Context, subject string, request proto.Message) (*eventsourcing.Response, error) {
+	b.mu.RLock()
+	handler, exists := b.handlers[subject]
+	closed := b.closed
+	b.mu.RUnlock()
+
+	if closed {
+		return nil, ErrBusClosed
+	}
+	if !exists {
+		return nil, fmt.Errorf("no handler registered for subject: %s", subject)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	handlerCtx, handlerResult := domain.WithCommandResult(ctx)
+	response, err := handler(handlerCtx, proto.Clone(request))
+	if err != nil {
+		return &eventsourcing.Response{Error: eventsourcing.AppErrorFromError(err, "HANDLER_ERROR")}, nil
+	}
+	if response == nil {
+		return eventsourcing.NewSimpleErrorResponse("HANDLER_ERROR", "Handler returned nil response"), nil
+	}
+	if result := handlerResult(); result != nil && response.Success {
+		if err := deliverCommandResult(ctx, result); err != nil {
+			return eventsourcing.NewSimpleErrorResponse("INVALID_RESPONSE", err.Error()), nil
+		}
+	}
+	return response, nil
+}
+
+// Close marks the bus as closed; subsequent requests fail with ErrBusClosed.
+func (b *SyncCommandBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+// IsCommandSubject reports whether subject addresses a command service, i.e.
+// its service segment ends in "CommandService"
+// (e.g., "account.v1.AccountCommandService.OpenAccount").
+func IsCommandSubject(subject string) bool {
+	i := strings.LastIndex(subject, ".")
+	if i < 0 {
+		return false
+	}
+	return strings.HasSuffix(subject[:i], "CommandService")
+}
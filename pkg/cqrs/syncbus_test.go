@@ -0,0 +1,140 @@
+package cqrs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestSyncCommandBus(t *testing.T) {
+	ctx := context.Background()
+	const subject = "counter.v1.CounterCommandService.Add"
+
+	bus := cqrs.NewSyncCommandBus()
+	total := int64(0)
+	err := bus.RegisterHandler(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		value := request.(*wrapperspb.Int64Value)
+		total += value.Value
+		value.Value = 0 // must not leak back to the caller
+		cqrs.ReportPosition(ctx, total)
+		return &eventsourcing.Response{Success: true}, nil
+	})
+	if err != nil {
+		t.Fatalf("failed to register handler: %v", err)
+	}
+	if err := bus.RegisterHandler(subject, nil); err == nil {
+		t.Error("expected duplicate registration to fail")
+	}
+
+	t.Run("ExecutesInline", func(t *testing.T) {
+		tracker := &cqrs.PositionTracker{}
+		request := wrapperspb.Int64(5)
+
+		resp, err := bus.Request(cqrs.WithPositionTracker(ctx, tracker), subject, request)
+		if err != nil || !resp.Success {
+			t.Fatalf("request failed: %v %v", resp, err)
+		}
+		if total != 5 {
+			t.Errorf("expected handler to have run before Request returned, total=%d", total)
+		}
+		if request.Value != 5 {
+			t.Errorf("handler mutated the caller's request")
+		}
+		if tracker.Position() != 5 {
+			t.Errorf("expected reported position 5, got %d", tracker.Position())
+		}
+	})
+
+	t.Run("HandlerErrorBecomesErrorResponse", func(t *testing.T) {
+		failing := "counter.v1.CounterCommandService.Fail"
+		bus.RegisterHandler(failing, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+			return nil, errors.New("boom")
+		})
+
+		resp, err := bus.Request(ctx, failing, wrapperspb.Int64(1))
+		if err != nil {
+			t.Fatalf("unexpected transport error: %v", err)
+		}
+		if resp.Error == nil || resp.Error.Code != "HANDLER_ERROR" {
+			t.Errorf("expected HANDLER_ERROR response, got %v", resp)
+		}
+	})
+
+	t.Run("ValidationErrorKeepsFieldViolations", func(t *testing.T) {
+		invalid := "counter.v1.CounterCommandService.Invalid"
+		bus.RegisterHandler(invalid, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+			return nil, fmt.Errorf("rejected: %w", eventsourcing.NewValidationError("invalid counter",
+				&eventsourcing.FieldViolation{Field: "value", Constraint: "positive", Message: "must be positive"}))
+		})
+
+		resp, err := bus.Request(ctx, invalid, wrapperspb.Int64(-1))
+		if err != nil {
+			t.Fatalf("unexpected transport error: %v", err)
+		}
+		if resp.Error.GetCode() != eventsourcing.ValidationFailedCode {
+			t.Fatalf("expected %s response, got %v", eventsourcing.ValidationFailedCode, resp)
+		}
+		if violations := resp.Error.FieldViolations; len(violations) != 1 || violations[0].Field != "value" {
+			t.Errorf("expected the value field violation, got %v", violations)
+		}
+	})
+
+	t.Run("CommandResult", func(t *testing.T) {
+		withdraw := "counter.v1.CounterCommandService.Withdraw"
+		bus.RegisterHandler(withdraw, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+			domain.SetCommandResult(ctx, wrapperspb.Int64(42))
+			return &eventsourcing.Response{Success: true}, nil
+		})
+
+		result := &wrapperspb.Int64Value{}
+		resp, err := bus.Request(eventsourcing.WithRequestOptions(ctx, eventsourcing.WithCommandResultInto(result)), withdraw, wrapperspb.Int64(1))
+		if err != nil || !resp.Success {
+			t.Fatalf("request failed: %v %v", resp, err)
+		}
+		if result.GetValue() != 42 {
+			t.Errorf("expected result 42, got %d", result.GetValue())
+		}
+
+		resp, err = bus.Request(eventsourcing.WithRequestOptions(ctx, eventsourcing.WithCommandResultInto(&wrapperspb.StringValue{})), withdraw, wrapperspb.Int64(1))
+		if err != nil {
+			t.Fatalf("unexpected transport error: %v", err)
+		}
+		if resp.GetError().GetCode() != "INVALID_RESPONSE" {
+			t.Errorf("expected INVALID_RESPONSE for a result of the wrong type, got %v", resp)
+		}
+	})
+
+	t.Run("UnknownSubject", func(t *testing.T) {
+		if _, err := bus.Request(ctx, "counter.v1.CounterCommandService.Missing", wrapperspb.Int64(1)); err == nil {
+			t.Error("expected error for unknown subject")
+		}
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		bus.Close()
+		if _, err := bus.Request(ctx, subject, wrapperspb.Int64(1)); !errors.Is(err, cqrs.ErrBusClosed) {
+			t.Errorf("expected ErrBusClosed, got %v", err)
+		}
+	})
+}
+
+func TestIsCommandSubject(t *testing.T) {
+	tests := map[string]bool{
+		"account.v1.AccountCommandService.OpenAccount": true,
+		"account.v1.AccountQueryService.GetAccount":    false,
+		"CommandService":                     false,
+		"account.v1.CommandServiceHelper.Do": false,
+	}
+	for subject, want := range tests {
+		if got := cqrs.IsCommandSubject(subject); got != want {
+			t.Errorf("IsCommandSubject(%q) = %v, want %v", subject, got, want)
+		}
+	}
+}
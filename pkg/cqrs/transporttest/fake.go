@@ -0,0 +1,177 @@
+// Package transporttest provides an in-memory transport for testing code built
+// on the generated SDK without NATS or an event store.
+package transporttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrCircuitOpen is returned for subjects configured with CircuitOpen,
+// simulating a client-side circuit breaker that rejects requests.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrNoHandler is returned for requests to a subject nothing was registered for.
+var ErrNoHandler = errors.New("no handler registered for subject")
+
+// Request is a request recorded by FakeTransport.
+type Request struct {
+	// Subject the request was sent to
+	Subject string
+
+	// Message is a copy of the request message
+	Message proto.Message
+}
+
+// FakeTransport is a deterministic cqrs.Transport (and eventsourcing.Transport)
+// for tests. Each subject is programmed with a handler or a canned outcome;
+// every request is recorded for assertions. Requests run synchronously on the
+// caller's goroutine. It is safe for concurrent use.
+//
+// Example:
+//
+//	transport := transporttest.NewFakeTransport()
+//	transport.Respond("account.v1.AccountCommandService.OpenAccount", &accountv1.OpenAccountResponse{AccountId: "acc-1"})
+//	client := accountv1.NewAccountClient(transport)
+//
+//	// ... exercise the code under test ...
+//
+//	sent := transport.RequestsTo("account.v1.AccountCommandService.OpenAccount")
+//	if len(sent) != 1 || sent[0].Message.(*accountv1.OpenAccountCommand).OwnerName != "Alice" {
+//	    t.Errorf("unexpected commands: %v", sent)
+//	}
+type FakeTransport struct {
+	mu       sync.Mutex
+	handlers map[string]cqrs.HandlerFunc
+	requests []Request
+	closed   bool
+}
+
+// NewFakeTransport creates a transport with no subjects programmed. Requests to
+// an unprogrammed subject fail with ErrNoHandler.
+func NewFakeTransport() *FakeTransport {
+	return &FakeTransport{handlers: make(map[string]cqrs.HandlerFunc)}
+}
+
+// Handle serves subject with handler, replacing anything programmed before.
+// The handler receives a copy of the request, as a remote server would.
+func (f *FakeTransport) Handle(subject string, handler cqrs.HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[subject] = handler
+}
+
+// Respond answers every request to subject with a successful response carrying
+// data (nil for none).
+func (f *FakeTransport) Respond(subject string, data proto.Message) {
+	f.Handle(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		if data == nil {
+			return &eventsourcing.Response{Success: true}, nil
+		}
+		return eventsourcing.NewSuccessResponse(data)
+	})
+}
+
+// RespondError answers every request to subject with an application error,
+// as returned by a failing command or query handler.
+func (f *FakeTransport) RespondError(subject, code, message string) {
+	f.Handle(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		return eventsourcing.NewSimpleErrorResponse(code, message), nil
+	})
+}
+
+// Fail makes every request to subject fail with err at the transport level,
+// e.g. a lost connection.
+func (f *FakeTransport) Fail(subject string, err error) {
+	f.Handle(subject, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		return nil, err
+	})
+}
+
+// Timeout makes every request to subject time out the way the NATS transport
+// reports it: a TIMEOUT error response.
+func (f *FakeTransport) Timeout(subject string) {
+	f.RespondError(subject, "TIMEOUT", "Request timed out")
+}
+
+// CircuitOpen makes every request to subject fail with ErrCircuitOpen.
+func (f *FakeTransport) CircuitOpen(subject string) {
+	f.Fail(subject, ErrCircuitOpen)
+}
+
+// Request records the request and dispatches it to the subject's handler.
+func (f *FakeTransport) Request(ctx context.Context, subject string, request proto.Message) (*eventsourcing.Response, error) {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return nil, cqrs.ErrBusClosed
+	}
+	f.requests = append(f.requests, Request{Subject: subject, Message: proto.Clone(request)})
+	handler, ok := f.handlers[subject]
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoHandler, subject)
+	}
+
+	response, err := handler(ctx, proto.Clone(request))
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return eventsourcing.NewSimpleErrorResponse("HANDLER_ERROR", "Handler returned nil response"), nil
+	}
+	return response, nil
+}
+
+// Requests returns all recorded requests in the order they were sent.
+func (f *FakeTransport) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
+
+// RequestsTo returns the recorded requests sent to subject.
+func (f *FakeTransport) RequestsTo(subject string) []Request {
+	return f.filter(func(r Request) bool { return r.Subject == subject })
+}
+
+// Commands returns the recorded requests sent to command subjects (see
+// cqrs.IsCommandSubject), leaving out queries.
+func (f *FakeTransport) Commands() []Request {
+	return f.filter(func(r Request) bool { return cqrs.IsCommandSubject(r.Subject) })
+}
+
+func (f *FakeTransport) filter(keep func(Request) bool) []Request {
+	var matched []Request
+	for _, r := range f.Requests() {
+		if keep(r) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// Reset forgets the recorded requests; programmed subjects are kept.
+func (f *FakeTransport) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = nil
+}
+
+// Close makes subsequent requests fail with cqrs.ErrBusClosed.
+func (f *FakeTransport) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
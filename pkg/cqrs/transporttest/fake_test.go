@@ -0,0 +1,87 @@
+package transporttest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	"github.com/plaenen/eventstore/pkg/cqrs/transporttest"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	openAccount = "account.v1.AccountCommandService.OpenAccount"
+	deposit     = "account.v1.AccountCommandService.Deposit"
+	getAccount  = "account.v1.AccountQueryService.GetAccount"
+)
+
+func TestFakeTransport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ProgrammedResponsesAndRecording", func(t *testing.T) {
+		transport := transporttest.NewFakeTransport()
+		transport.Respond(openAccount, &accountv1.OpenAccountResponse{AccountId: "acc-1", Version: 1})
+		transport.Handle(getAccount, func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+			query := request.(*accountv1.GetAccountRequest)
+			return eventsourcing.NewSuccessResponse(&accountv1.AccountView{AccountId: query.AccountId, OwnerName: "Alice"})
+		})
+		client := accountv1.NewAccountClient(transport)
+
+		opened, appErr := client.OpenAccount(ctx, &accountv1.OpenAccountCommand{AccountId: "acc-1", OwnerName: "Alice"})
+		if appErr != nil || opened.AccountId != "acc-1" {
+			t.Fatalf("unexpected open result: %v %v", opened, appErr)
+		}
+		view, appErr := client.GetAccount(ctx, &accountv1.GetAccountRequest{AccountId: "acc-1"})
+		if appErr != nil || view.OwnerName != "Alice" {
+			t.Fatalf("unexpected query result: %v %v", view, appErr)
+		}
+
+		if got := len(transport.Requests()); got != 2 {
+			t.Errorf("expected 2 recorded requests, got %d", got)
+		}
+		commands := transport.Commands()
+		if len(commands) != 1 || commands[0].Subject != openAccount {
+			t.Fatalf("expected one OpenAccount command, got %v", commands)
+		}
+		if cmd := commands[0].Message.(*accountv1.OpenAccountCommand); cmd.OwnerName != "Alice" {
+			t.Errorf("recorded command has owner %q", cmd.OwnerName)
+		}
+
+		transport.Reset()
+		if len(transport.Requests()) != 0 {
+			t.Error("expected Reset to clear recorded requests")
+		}
+	})
+
+	t.Run("SimulatedFailures", func(t *testing.T) {
+		transport := transporttest.NewFakeTransport()
+		transport.RespondError(deposit, "INSUFFICIENT_FUNDS", "not enough money")
+		transport.Timeout(getAccount)
+		transport.CircuitOpen(openAccount)
+		client := accountv1.NewAccountClient(transport)
+
+		if _, appErr := client.Deposit(ctx, &accountv1.DepositCommand{}); appErr == nil || appErr.Code != "INSUFFICIENT_FUNDS" {
+			t.Errorf("expected INSUFFICIENT_FUNDS, got %v", appErr)
+		}
+		if _, appErr := client.GetAccount(ctx, &accountv1.GetAccountRequest{}); appErr == nil || appErr.Code != "TIMEOUT" {
+			t.Errorf("expected TIMEOUT, got %v", appErr)
+		}
+		if _, err := transport.Request(ctx, openAccount, &accountv1.OpenAccountCommand{}); !errors.Is(err, transporttest.ErrCircuitOpen) {
+			t.Errorf("expected ErrCircuitOpen, got %v", err)
+		}
+		if _, err := transport.Request(ctx, "account.v1.AccountCommandService.Unknown", &accountv1.OpenAccountCommand{}); !errors.Is(err, transporttest.ErrNoHandler) {
+			t.Errorf("expected ErrNoHandler, got %v", err)
+		}
+		if len(transport.Requests()) != 4 {
+			t.Errorf("expected failed requests to be recorded too, got %d", len(transport.Requests()))
+		}
+
+		transport.Close()
+		if _, err := transport.Request(ctx, deposit, &accountv1.DepositCommand{}); !errors.Is(err, cqrs.ErrBusClosed) {
+			t.Errorf("expected ErrBusClosed after Close, got %v", err)
+		}
+	})
+}
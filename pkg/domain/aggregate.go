@@ -55,6 +55,7 @@ type AggregateRoot struct {
 	version           int64
 	uncommittedEvents []*Event
 	commandID         string // Current command being processed (for deterministic event IDs)
+	partial           bool   // Rebuilt from a subset of its events
 }
 
 // NewAggregateRoot creates a new aggregate root with the given ID and type.
@@ -161,6 +162,18 @@ func (a *AggregateRoot) LoadFromHistory(events []*Event) error {
 	return nil
 }
 
+// MarkPartial records that the aggregate was rebuilt from only some of its
+// events, so its state is incomplete. See store.BaseRepository.LoadPartial.
+func (a *AggregateRoot) MarkPartial() {
+	a.partial = true
+}
+
+// IsPartial returns true if the aggregate was rebuilt from only some of its
+// events.
+func (a *AggregateRoot) IsPartial() bool {
+	return a.partial
+}
+
 // TimeFunc is a function that returns the current time.
 // This can be overridden for testing.
 var TimeFunc = time.Now
@@ -24,6 +24,11 @@ type CommandMetadata struct {
 	// CommandID is the unique identifier for this command (for idempotency)
 	CommandID string
 
+	// IdempotencyKey is an optional business idempotency key supplied by the
+	// caller (e.g., an HTTP Idempotency-Key header). When set, it identifies
+	// the command instead of CommandID; see EffectiveCommandID.
+	IdempotencyKey string
+
 	// CorrelationID is used to trace related commands and events
 	CorrelationID string
 
@@ -54,9 +59,18 @@ type CommandResult struct {
 	// Events are the events produced by the command
 	Events []*Event
 
+	// Deduplicated are the events dropped as duplicates of the aggregate's
+	// preceding events by a store with content deduplication (e.g.,
+	// sqlite.WithContentDedup). Only set when the command is first processed.
+	Deduplicated []*Event
+
 	// AlreadyProcessed indicates if this was a duplicate command
 	AlreadyProcessed bool
 
 	// ProcessedAt is when the command was originally processed
 	ProcessedAt time.Time
+
+	// Position is the global position of the last event produced by the command.
+	// Pass it to a read-your-writes query to observe the command's effects.
+	Position int64
 }
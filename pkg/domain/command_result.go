@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type commandResultKey struct{}
+
+// commandResult holds the result set by a command handler.
+type commandResult struct {
+	result proto.Message
+}
+
+// WithCommandResult returns a context in which a command handler can set a
+// result with SetCommandResult, and a function returning the result set (nil
+// if none). Command buses call it before invoking the handler chain, so the
+// result survives any middleware wrapping the handler.
+func WithCommandResult(ctx context.Context) (context.Context, func() proto.Message) {
+	slot := &commandResult{}
+	return context.WithValue(ctx, commandResultKey{}, slot), func() proto.Message { return slot.result }
+}
+
+// SetCommandResult sets the result of the command being handled, e.g. the new
+// balance after a withdrawal, so the sender gets it back with the command
+// response instead of querying a read model afterwards. It reports whether ctx
+// was prepared with WithCommandResult; without it the result is discarded.
+// Setting a result twice keeps the last one.
+func SetCommandResult(ctx context.Context, result proto.Message) bool {
+	slot, ok := ctx.Value(commandResultKey{}).(*commandResult)
+	if !ok {
+		return false
+	}
+	slot.result = result
+	return true
+}
@@ -0,0 +1,55 @@
+package domain
+
+import "context"
+
+// CompensationKey is the EventMetadata.Custom key that marks an event as a
+// compensation. A compensating event carries the ID of the command it undoes
+// as its CausationID, so it is linked to the original command's events.
+const CompensationKey = "compensation"
+
+// Compensator is implemented by aggregates that can logically undo a command,
+// e.g. reversing an erroneous deposit in a long-running business process.
+//
+// Compensate records compensating events (it never deletes history). It must
+// mark them with CompensationMetadata(originalCommandID, meta) so they can be
+// found with EventStore.LoadCompensations. The events recorded by the original
+// command are available through CompensatedEvents(ctx) when Compensate is
+// called via store.CompensateCommand.
+type Compensator interface {
+	Compensate(ctx context.Context, originalCommandID string, meta EventMetadata) error
+}
+
+// CompensationMetadata returns meta marked as compensating originalCommandID.
+// The caller's CausationID is replaced by originalCommandID; the Custom map is
+// copied, not modified.
+func CompensationMetadata(originalCommandID string, meta EventMetadata) EventMetadata {
+	custom := make(map[string]string, len(meta.Custom)+1)
+	for k, v := range meta.Custom {
+		custom[k] = v
+	}
+	custom[CompensationKey] = "true"
+
+	meta.CausationID = originalCommandID
+	meta.Custom = custom
+	return meta
+}
+
+// IsCompensation reports whether meta marks a compensating event.
+func IsCompensation(meta EventMetadata) bool {
+	return meta.Custom[CompensationKey] == "true"
+}
+
+type compensatedEventsKey struct{}
+
+// WithCompensatedEvents returns a context carrying the events recorded by the
+// command being compensated.
+func WithCompensatedEvents(ctx context.Context, events []*Event) context.Context {
+	return context.WithValue(ctx, compensatedEventsKey{}, events)
+}
+
+// CompensatedEvents returns the events recorded by the command being
+// compensated, or nil if ctx carries none.
+func CompensatedEvents(ctx context.Context) []*Event {
+	events, _ := ctx.Value(compensatedEventsKey{}).([]*Event)
+	return events
+}
@@ -0,0 +1,19 @@
+package domain
+
+import "context"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a context carrying the correlation ID of the
+// process a command belongs to, such as a saga instance. The NATS transport
+// forwards it to the server handling the command, and command handlers copy
+// it into the metadata of the events they emit.
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+// CorrelationIDFromContext returns the ID set by WithCorrelationID.
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	correlationID, _ := ctx.Value(correlationIDKey{}).(string)
+	return correlationID, correlationID != ""
+}
@@ -32,6 +32,15 @@ var (
 
 	// ErrSnapshotNotFound is returned when a snapshot cannot be found.
 	ErrSnapshotNotFound = errors.New("snapshot not found")
+
+	// ErrCompensationNotSupported is returned when an aggregate cannot compensate commands.
+	ErrCompensationNotSupported = errors.New("compensation not supported")
+
+	// ErrAlreadyCompensated is returned when a command has already been compensated.
+	ErrAlreadyCompensated = errors.New("command already compensated")
+
+	// ErrNothingToCompensate is returned when a command recorded no events to compensate.
+	ErrNothingToCompensate = errors.New("no events to compensate")
 )
 
 // UniqueConstraintError provides detailed information about a constraint violation.
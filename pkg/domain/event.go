@@ -39,6 +39,10 @@ type Event struct {
 	// UniqueConstraints are the unique constraints claimed or released by this event.
 	// These are validated atomically with event persistence.
 	UniqueConstraints []UniqueConstraint
+
+	// Position is the global position of the event in the store.
+	// It is assigned by the event store on append (0 = not yet persisted).
+	Position int64
 }
 
 // EventMetadata contains contextual information about an event.
@@ -83,11 +87,49 @@ const (
 )
 
 // EventEnvelope wraps an event with its deserialized payload.
+// Envelopes are fully hydrated whether they come from the event store or the
+// event bus, so the accessors below can be used regardless of the source.
 type EventEnvelope struct {
 	Event
 	Payload proto.Message
 }
 
+// CorrelationID returns the correlation ID of the event.
+func (e *EventEnvelope) CorrelationID() string {
+	return e.Metadata.CorrelationID
+}
+
+// CausationID returns the ID of the command that caused the event.
+func (e *EventEnvelope) CausationID() string {
+	return e.Metadata.CausationID
+}
+
+// PrincipalID returns the principal that triggered the event.
+func (e *EventEnvelope) PrincipalID() string {
+	return e.Metadata.PrincipalID
+}
+
+// TenantID returns the tenant the event belongs to.
+func (e *EventEnvelope) TenantID() string {
+	return e.Metadata.TenantID
+}
+
+// GlobalPosition returns the global position of the event in the store.
+func (e *EventEnvelope) GlobalPosition() int64 {
+	return e.Position
+}
+
+// OccurredAt returns when the event was created.
+func (e *EventEnvelope) OccurredAt() time.Time {
+	return e.Timestamp
+}
+
+// CustomMetadata returns an application-specific metadata value.
+func (e *EventEnvelope) CustomMetadata(key string) (string, bool) {
+	value, ok := e.Metadata.Custom[key]
+	return value, ok
+}
+
 // GenerateDeterministicEventID generates a deterministic event ID from command context.
 // This ensures the same command always produces the same event IDs (idempotency).
 func GenerateDeterministicEventID(commandID, aggregateID string, sequence int) string {
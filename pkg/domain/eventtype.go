@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// EventTypeOf returns the event type string of an event message: its fully
+// qualified proto message name (e.g., "account.v1.AccountOpenedEvent").
+//
+// This is the scheme used by generated code for Event.EventType, the
+// <Event>Type constants, projection handlers and event registries.
+func EventTypeOf(msg proto.Message) string {
+	return string(msg.ProtoReflect().Descriptor().FullName())
+}
+
+var (
+	eventTypeAliasesMu sync.RWMutex
+	eventTypeAliases   = make(map[string]string)
+)
+
+// RegisterEventTypeAlias records alias as another name of eventType, so
+// events stored under alias are dispatched, decoded and applied as eventType.
+//
+// Generated code registers the <go package>.<Message> names written by
+// earlier generator versions (e.g., "accountv1.AccountOpenedEvent") as
+// aliases of the fully qualified names.
+func RegisterEventTypeAlias(alias, eventType string) {
+	eventTypeAliasesMu.Lock()
+	defer eventTypeAliasesMu.Unlock()
+	eventTypeAliases[alias] = eventType
+}
+
+// CanonicalEventType returns the event type an alias was registered for, or
+// eventType itself if it is not an alias.
+func CanonicalEventType(eventType string) string {
+	eventTypeAliasesMu.RLock()
+	defer eventTypeAliasesMu.RUnlock()
+	if canonical, ok := eventTypeAliases[eventType]; ok {
+		return canonical
+	}
+	return eventType
+}
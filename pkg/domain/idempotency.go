@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a context carrying a caller-supplied business
+// idempotency key, such as the Idempotency-Key header of an HTTP request. The
+// NATS transport forwards it to the server handling the command.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the key set by WithIdempotencyKey.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	return key, key != ""
+}
+
+// IdempotencyCommandID maps a business idempotency key to the command ID under
+// which the store records the command, so retries carrying the same key are
+// deduplicated even if the client generated a new command ID. The ID is scoped
+// to the aggregate: the same key sent to two aggregates yields two commands.
+func IdempotencyCommandID(aggregateID, key string) string {
+	// Length-prefixed so that ("ab", "c") and ("a", "bc") differ
+	sum := sha256.Sum256([]byte(strconv.Itoa(len(aggregateID)) + ":" + aggregateID + key))
+	return "idem-" + hex.EncodeToString(sum[:16])
+}
+
+// EffectiveCommandID returns the command ID to record the command under:
+// IdempotencyCommandID for the aggregate when an IdempotencyKey is set,
+// CommandID otherwise.
+func (m CommandMetadata) EffectiveCommandID(aggregateID string) string {
+	if m.IdempotencyKey != "" {
+		return IdempotencyCommandID(aggregateID, m.IdempotencyKey)
+	}
+	return m.CommandID
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// EventDescriptor describes a concrete event type so that generic tooling
+// (rebuild, verification, replay, cross-domain projections) can decode and
+// apply events without compile-time knowledge of the proto message.
+type EventDescriptor struct {
+	// AggregateType is the type of aggregate that emits the event.
+	AggregateType string
+
+	// New returns a new, empty instance of the event message.
+	New func() proto.Message
+
+	// Apply applies a decoded event to an aggregate of AggregateType.
+	Apply func(agg Aggregate, event proto.Message) error
+}
+
+// EventRegistry maps event type strings (Event.EventType) to their descriptors.
+// Generated code exports one registry per proto package as EventRegistry.
+// Lookups resolve event type aliases (see RegisterEventTypeAlias).
+type EventRegistry map[string]EventDescriptor
+
+// Lookup returns the descriptor registered for an event type.
+func (r EventRegistry) Lookup(eventType string) (EventDescriptor, bool) {
+	d, ok := r[CanonicalEventType(eventType)]
+	return d, ok
+}
+
+// Decode unmarshals the event data into a new message of the registered type.
+func (r EventRegistry) Decode(event *Event) (proto.Message, error) {
+	d, ok := r.Lookup(event.EventType)
+	if !ok {
+		return nil, fmt.Errorf("unknown event type: %s", event.EventType)
+	}
+
+	msg := d.New()
+	if err := proto.Unmarshal(event.Data, msg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", event.EventType, err)
+	}
+	return msg, nil
+}
+
+// Apply decodes the event and applies it to the aggregate.
+func (r EventRegistry) Apply(agg Aggregate, event *Event) error {
+	msg, err := r.Decode(event)
+	if err != nil {
+		return err
+	}
+	d, _ := r.Lookup(event.EventType)
+	return d.Apply(agg, msg)
+}
+
+// MergeEventRegistries returns a new registry containing the descriptors of all registries.
+// Later registries win when the same event type is registered more than once.
+func MergeEventRegistries(registries ...EventRegistry) EventRegistry {
+	merged := make(EventRegistry)
+	for _, r := range registries {
+		for eventType, d := range r {
+			merged[eventType] = d
+		}
+	}
+	return merged
+}
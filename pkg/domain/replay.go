@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+type replayKey struct{}
+
+// WithReplay returns a context marking its handler as replaying: re-handling
+// events during a projection rebuild, or a command that was already processed.
+func WithReplay(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replayKey{}, true)
+}
+
+// IsReplay reports whether ctx was marked by WithReplay.
+func IsReplay(ctx context.Context) bool {
+	replay, _ := ctx.Value(replayKey{}).(bool)
+	return replay
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "strings"
+
+// TenantSeparator separates the tenant ID from the aggregate ID in a
+// tenant-scoped aggregate ID, e.g. tenant-abc::acc-123.
+const TenantSeparator = "::"
+
+// DecomposeAggregateID splits a tenant-scoped aggregate ID into tenant ID and aggregate ID
+// Returns (tenantID, aggregateID, nil) or ("", aggregateID, nil) if no tenant prefix
+func DecomposeAggregateID(compositeID string) (string, string, error) {
+	tenantID, aggregateID, found := strings.Cut(compositeID, TenantSeparator)
+	if !found {
+		// No tenant prefix
+		return "", compositeID, nil
+	}
+	return tenantID, aggregateID, nil
+}
+
+// EventTenantID returns the tenant of an event: its TenantID metadata or,
+// for events appended without it, the tenant prefix of its aggregate ID.
+// Returns "" for events of no tenant.
+func EventTenantID(event *Event) string {
+	if event.Metadata.TenantID != "" {
+		return event.Metadata.TenantID
+	}
+	tenantID, _, _ := DecomposeAggregateID(event.AggregateID)
+	return tenantID
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// TimestampClampedKey is the EventMetadata.Custom key set on events whose
+// timestamp an event store moved forward to keep the aggregate's timestamps
+// monotonic. Its value is the original timestamp in RFC 3339 format.
+const TimestampClampedKey = "timestamp_clamped"
+
+// ClampTimestamp sets event.Timestamp to floor if it is earlier, recording the
+// original timestamp under TimestampClampedKey. The Custom map is copied, not
+// modified. It reports whether the timestamp was clamped.
+func ClampTimestamp(event *Event, floor time.Time) bool {
+	if !event.Timestamp.Before(floor) {
+		return false
+	}
+
+	custom := make(map[string]string, len(event.Metadata.Custom)+1)
+	for k, v := range event.Metadata.Custom {
+		custom[k] = v
+	}
+	custom[TimestampClampedKey] = event.Timestamp.Format(time.RFC3339Nano)
+
+	event.Metadata.Custom = custom
+	event.Timestamp = floor
+	return true
+}
+
+// ClampedTimestamp returns the original timestamp of an event whose timestamp
+// was clamped (see ClampTimestamp), and false for events stored as written.
+func ClampedTimestamp(meta EventMetadata) (time.Time, bool) {
+	value, ok := meta.Custom[TimestampClampedKey]
+	if !ok {
+		return time.Time{}, false
+	}
+	original, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return original, true
+}
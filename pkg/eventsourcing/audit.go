@@ -0,0 +1,173 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// AuditEntry is one line of an aggregate's audit trail: who did what, and when.
+type AuditEntry struct {
+	Version       int64
+	EventID       string
+	EventType     string
+	Timestamp     time.Time
+	PrincipalID   string
+	CorrelationID string
+	CausationID   string
+
+	// Summary is a human-readable rendering of the event payload
+	Summary string
+}
+
+// AuditSummarizer renders a decoded event for an audit trail, e.g.
+// "deposited 10.00 EUR".
+type AuditSummarizer func(event *domain.Event, msg proto.Message) string
+
+// AuditOption configures AuditTrail.
+type AuditOption func(*auditConfig)
+
+type auditConfig struct {
+	registry    domain.EventRegistry
+	summarizers map[string]AuditSummarizer
+	fromVersion int64
+	limit       int
+}
+
+// WithAuditRegistry registers event types so their payloads can be decoded
+// and summarized. Can be passed once per proto package.
+func WithAuditRegistry(registry domain.EventRegistry) AuditOption {
+	return func(c *auditConfig) {
+		c.registry = domain.MergeEventRegistries(c.registry, registry)
+	}
+}
+
+// WithAuditSummarizer renders events of eventType with summarize instead of
+// the decoded message's String(). The event type must also be registered
+// with WithAuditRegistry.
+func WithAuditSummarizer(eventType string, summarize AuditSummarizer) AuditOption {
+	return func(c *auditConfig) {
+		c.summarizers[domain.CanonicalEventType(eventType)] = summarize
+	}
+}
+
+// WithAuditFromVersion starts the trail after fromVersion.
+func WithAuditFromVersion(fromVersion int64) AuditOption {
+	return func(c *auditConfig) {
+		c.fromVersion = fromVersion
+	}
+}
+
+// WithAuditLimit returns at most limit entries (0 = all). With an event store
+// implementing store.EventStreamer only those events are read.
+func WithAuditLimit(limit int) AuditOption {
+	return func(c *auditConfig) {
+		c.limit = limit
+	}
+}
+
+// AuditTrail returns the audit trail of an aggregate, one entry per event in
+// version order, joining each event's metadata with a summary of its payload.
+// Events whose type is not registered are summarized by their size only.
+// Returns domain.ErrAggregateNotFound if the aggregate has no events at all.
+//
+// Example:
+//
+//	trail, err := eventsourcing.AuditTrail(eventStore, "acc-123",
+//	    eventsourcing.WithAuditRegistry(accountv1.EventRegistry),
+//	    eventsourcing.WithAuditSummarizer(accountv1.MoneyDepositedEventType,
+//	        func(event *domain.Event, msg proto.Message) string {
+//	            return "deposited " + msg.(*accountv1.MoneyDepositedEvent).Amount
+//	        }))
+//	for _, entry := range trail {
+//	    fmt.Printf("v%d %s %s: %s\n", entry.Version, entry.Timestamp, entry.PrincipalID, entry.Summary)
+//	}
+func AuditTrail(eventStore store.EventStore, aggregateID string, opts ...AuditOption) ([]AuditEntry, error) {
+	config := auditConfig{summarizers: make(map[string]AuditSummarizer)}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	var events []*domain.Event
+	var err error
+	if config.limit > 0 {
+		events, err = store.LoadEventsPage(context.Background(), eventStore, aggregateID, config.fromVersion, config.limit)
+	} else {
+		events, err = eventStore.LoadEvents(context.Background(), aggregateID, config.fromVersion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events: %w", err)
+	}
+	if len(events) == 0 && config.fromVersion == 0 {
+		return nil, domain.ErrAggregateNotFound
+	}
+
+	entries := make([]AuditEntry, len(events))
+	for i, event := range events {
+		entries[i] = AuditEntry{
+			Version:       event.Version,
+			EventID:       event.ID,
+			EventType:     event.EventType,
+			Timestamp:     event.Timestamp,
+			PrincipalID:   event.Metadata.PrincipalID,
+			CorrelationID: event.Metadata.CorrelationID,
+			CausationID:   event.Metadata.CausationID,
+			Summary:       config.summarize(event),
+		}
+	}
+	return entries, nil
+}
+
+// summarize renders an event's payload for the audit trail.
+func (c *auditConfig) summarize(event *domain.Event) string {
+	if _, ok := c.registry.Lookup(event.EventType); !ok {
+		return fmt.Sprintf("(%d bytes)", len(event.Data))
+	}
+	msg, err := c.registry.Decode(event)
+	if err != nil {
+		return fmt.Sprintf("(undecodable: %v)", err)
+	}
+	if summarize, ok := c.summarizers[domain.CanonicalEventType(event.EventType)]; ok {
+		return summarize(event, msg)
+	}
+	if stringer, ok := msg.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%v", msg)
+}
+
+// NewAuditTrailEntry converts an audit entry to its wire form.
+func NewAuditTrailEntry(entry AuditEntry) *AuditTrailEntry {
+	return &AuditTrailEntry{
+		Version:       entry.Version,
+		EventId:       entry.EventID,
+		EventType:     entry.EventType,
+		Timestamp:     timestamppb.New(entry.Timestamp),
+		PrincipalId:   entry.PrincipalID,
+		CorrelationId: entry.CorrelationID,
+		CausationId:   entry.CausationID,
+		Summary:       entry.Summary,
+	}
+}
+
+// ToAuditEntry converts the wire form back to an audit entry.
+func (x *AuditTrailEntry) ToAuditEntry() AuditEntry {
+	entry := AuditEntry{
+		Version:       x.GetVersion(),
+		EventID:       x.GetEventId(),
+		EventType:     x.GetEventType(),
+		PrincipalID:   x.GetPrincipalId(),
+		CorrelationID: x.GetCorrelationId(),
+		CausationID:   x.GetCausationId(),
+		Summary:       x.GetSummary(),
+	}
+	if x.GetTimestamp() != nil {
+		entry.Timestamp = x.GetTimestamp().AsTime()
+	}
+	return entry
+}
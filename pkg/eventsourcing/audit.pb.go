@@ -0,0 +1,322 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: eventsourcing/audit.proto
+
+package eventsourcing
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// AuditTrailRequest asks a service for the audit trail of one aggregate.
+// It is a built-in query served by cqrs.RegisterAuditTrail.
+type AuditTrailRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// aggregate_id is the aggregate whose trail is requested
+	AggregateId string `protobuf:"bytes,1,opt,name=aggregate_id,json=aggregateId,proto3" json:"aggregate_id,omitempty"`
+	// from_version lists the entries after this version (0 = from the first event)
+	FromVersion int64 `protobuf:"varint,2,opt,name=from_version,json=fromVersion,proto3" json:"from_version,omitempty"`
+	// limit caps the number of entries in the response (0 = server default)
+	Limit         int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditTrailRequest) Reset() {
+	*x = AuditTrailRequest{}
+	mi := &file_eventsourcing_audit_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditTrailRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditTrailRequest) ProtoMessage() {}
+
+func (x *AuditTrailRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventsourcing_audit_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditTrailRequest.ProtoReflect.Descriptor instead.
+func (*AuditTrailRequest) Descriptor() ([]byte, []int) {
+	return file_eventsourcing_audit_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AuditTrailRequest) GetAggregateId() string {
+	if x != nil {
+		return x.AggregateId
+	}
+	return ""
+}
+
+func (x *AuditTrailRequest) GetFromVersion() int64 {
+	if x != nil {
+		return x.FromVersion
+	}
+	return 0
+}
+
+func (x *AuditTrailRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// AuditTrailResponse is one page of an aggregate's audit trail, in version order
+type AuditTrailResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Entries []*AuditTrailEntry     `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	// has_more is true when more entries follow; request the next page with
+	// from_version set to the version of the last entry
+	HasMore       bool `protobuf:"varint,2,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditTrailResponse) Reset() {
+	*x = AuditTrailResponse{}
+	mi := &file_eventsourcing_audit_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditTrailResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditTrailResponse) ProtoMessage() {}
+
+func (x *AuditTrailResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_eventsourcing_audit_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditTrailResponse.ProtoReflect.Descriptor instead.
+func (*AuditTrailResponse) Descriptor() ([]byte, []int) {
+	return file_eventsourcing_audit_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AuditTrailResponse) GetEntries() []*AuditTrailEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *AuditTrailResponse) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+// AuditTrailEntry records who did what to an aggregate, and when
+type AuditTrailEntry struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Version int64                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	EventId string                 `protobuf:"bytes,2,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	// event_type is the fully qualified proto name of the payload
+	EventType     string                 `protobuf:"bytes,3,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	PrincipalId   string                 `protobuf:"bytes,5,opt,name=principal_id,json=principalId,proto3" json:"principal_id,omitempty"`
+	CorrelationId string                 `protobuf:"bytes,6,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	CausationId   string                 `protobuf:"bytes,7,opt,name=causation_id,json=causationId,proto3" json:"causation_id,omitempty"`
+	// summary is a human-readable rendering of the event payload
+	Summary       string `protobuf:"bytes,8,opt,name=summary,proto3" json:"summary,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditTrailEntry) Reset() {
+	*x = AuditTrailEntry{}
+	mi := &file_eventsourcing_audit_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditTrailEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditTrailEntry) ProtoMessage() {}
+
+func (x *AuditTrailEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_eventsourcing_audit_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditTrailEntry.ProtoReflect.Descriptor instead.
+func (*AuditTrailEntry) Descriptor() ([]byte, []int) {
+	return file_eventsourcing_audit_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AuditTrailEntry) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *AuditTrailEntry) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *AuditTrailEntry) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *AuditTrailEntry) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *AuditTrailEntry) GetPrincipalId() string {
+	if x != nil {
+		return x.PrincipalId
+	}
+	return ""
+}
+
+func (x *AuditTrailEntry) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *AuditTrailEntry) GetCausationId() string {
+	if x != nil {
+		return x.CausationId
+	}
+	return ""
+}
+
+func (x *AuditTrailEntry) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+var File_eventsourcing_audit_proto protoreflect.FileDescriptor
+
+const file_eventsourcing_audit_proto_rawDesc = "" +
+	"\n" +
+	"\x19eventsourcing/audit.proto\x12\reventsourcing\x1a\x1fgoogle/protobuf/timestamp.proto\"o\n" +
+	"\x11AuditTrailRequest\x12!\n" +
+	"\faggregate_id\x18\x01 \x01(\tR\vaggregateId\x12!\n" +
+	"\ffrom_version\x18\x02 \x01(\x03R\vfromVersion\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"i\n" +
+	"\x12AuditTrailResponse\x128\n" +
+	"\aentries\x18\x01 \x03(\v2\x1e.eventsourcing.AuditTrailEntryR\aentries\x12\x19\n" +
+	"\bhas_more\x18\x02 \x01(\bR\ahasMore\"\xa6\x02\n" +
+	"\x0fAuditTrailEntry\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x03R\aversion\x12\x19\n" +
+	"\bevent_id\x18\x02 \x01(\tR\aeventId\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x03 \x01(\tR\teventType\x128\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12!\n" +
+	"\fprincipal_id\x18\x05 \x01(\tR\vprincipalId\x12%\n" +
+	"\x0ecorrelation_id\x18\x06 \x01(\tR\rcorrelationId\x12!\n" +
+	"\fcausation_id\x18\a \x01(\tR\vcausationId\x12\x18\n" +
+	"\asummary\x18\b \x01(\tR\asummaryB1Z/github.com/plaenen/eventstore/pkg/eventsourcingb\x06proto3"
+
+var (
+	file_eventsourcing_audit_proto_rawDescOnce sync.Once
+	file_eventsourcing_audit_proto_rawDescData []byte
+)
+
+func file_eventsourcing_audit_proto_rawDescGZIP() []byte {
+	file_eventsourcing_audit_proto_rawDescOnce.Do(func() {
+		file_eventsourcing_audit_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_eventsourcing_audit_proto_rawDesc), len(file_eventsourcing_audit_proto_rawDesc)))
+	})
+	return file_eventsourcing_audit_proto_rawDescData
+}
+
+var file_eventsourcing_audit_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_eventsourcing_audit_proto_goTypes = []any{
+	(*AuditTrailRequest)(nil),     // 0: eventsourcing.AuditTrailRequest
+	(*AuditTrailResponse)(nil),    // 1: eventsourcing.AuditTrailResponse
+	(*AuditTrailEntry)(nil),       // 2: eventsourcing.AuditTrailEntry
+	(*timestamppb.Timestamp)(nil), // 3: google.protobuf.Timestamp
+}
+var file_eventsourcing_audit_proto_depIdxs = []int32{
+	2, // 0: eventsourcing.AuditTrailResponse.entries:type_name -> eventsourcing.AuditTrailEntry
+	3, // 1: eventsourcing.AuditTrailEntry.timestamp:type_name -> google.protobuf.Timestamp
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_eventsourcing_audit_proto_init() }
+func file_eventsourcing_audit_proto_init() {
+	if File_eventsourcing_audit_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_eventsourcing_audit_proto_rawDesc), len(file_eventsourcing_audit_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_eventsourcing_audit_proto_goTypes,
+		DependencyIndexes: file_eventsourcing_audit_proto_depIdxs,
+		MessageInfos:      file_eventsourcing_audit_proto_msgTypes,
+	}.Build()
+	File_eventsourcing_audit_proto = out.File
+	file_eventsourcing_audit_proto_goTypes = nil
+	file_eventsourcing_audit_proto_depIdxs = nil
+}
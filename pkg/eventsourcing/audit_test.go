@@ -0,0 +1,121 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestAuditTrail(t *testing.T) {
+	registry := domain.EventRegistry{
+		"test.Renamed": {
+			AggregateType: "Customer",
+			New:           func() proto.Message { return &wrapperspb.StringValue{} },
+		},
+		"test.Scored": {
+			AggregateType: "Customer",
+			New:           func() proto.Message { return &wrapperspb.Int64Value{} },
+		},
+	}
+	payload := func(msg proto.Message) []byte {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatalf("failed to marshal: %v", err)
+		}
+		return data
+	}
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	timestamp := time.Now().UTC().Truncate(time.Second)
+	var events []*domain.Event
+	for i, e := range []struct {
+		eventType string
+		data      []byte
+	}{
+		{"test.Renamed", payload(wrapperspb.String("alice"))},
+		{"test.Scored", payload(wrapperspb.Int64(42))},
+		{"test.Unregistered", []byte("raw")},
+	} {
+		events = append(events, &domain.Event{
+			ID:            fmt.Sprintf("evt-%d", i+1),
+			AggregateID:   "cust-1",
+			AggregateType: "Customer",
+			EventType:     e.eventType,
+			Version:       int64(i + 1),
+			Timestamp:     timestamp,
+			Data:          e.data,
+			Metadata: domain.EventMetadata{
+				PrincipalID:   "user-7",
+				CorrelationID: "corr-1",
+				CausationID:   fmt.Sprintf("cmd-%d", i+1),
+			},
+		})
+	}
+	if err := eventStore.AppendEvents(context.Background(), "cust-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	trail, err := eventsourcing.AuditTrail(eventStore, "cust-1",
+		eventsourcing.WithAuditRegistry(registry),
+		eventsourcing.WithAuditSummarizer("test.Scored", func(event *domain.Event, msg proto.Message) string {
+			return fmt.Sprintf("scored %d", msg.(*wrapperspb.Int64Value).GetValue())
+		}))
+	if err != nil {
+		t.Fatalf("failed to build audit trail: %v", err)
+	}
+	if len(trail) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(trail))
+	}
+
+	first := trail[0]
+	if first.Version != 1 || first.EventID != "evt-1" || first.EventType != "test.Renamed" ||
+		first.PrincipalID != "user-7" || first.CorrelationID != "corr-1" || first.CausationID != "cmd-1" ||
+		!first.Timestamp.Equal(timestamp) {
+		t.Errorf("unexpected entry: %+v", first)
+	}
+	if want := wrapperspb.String("alice").String(); first.Summary != want {
+		t.Errorf("expected the decoded message %q, got %q", want, first.Summary)
+	}
+	if trail[1].Summary != "scored 42" {
+		t.Errorf("expected the registered summarizer, got %q", trail[1].Summary)
+	}
+	if trail[2].Summary != "(3 bytes)" {
+		t.Errorf("expected the payload size for an unregistered type, got %q", trail[2].Summary)
+	}
+
+	// The wire form round-trips
+	got := eventsourcing.NewAuditTrailEntry(first).ToAuditEntry()
+	if !got.Timestamp.Equal(first.Timestamp) {
+		t.Errorf("expected timestamp %v after round trip, got %v", first.Timestamp, got.Timestamp)
+	}
+	got.Timestamp = first.Timestamp
+	if got != first {
+		t.Errorf("expected %+v after round trip, got %+v", first, got)
+	}
+
+	page, err := eventsourcing.AuditTrail(eventStore, "cust-1",
+		eventsourcing.WithAuditFromVersion(1), eventsourcing.WithAuditLimit(1))
+	if err != nil {
+		t.Fatalf("failed to build audit trail page: %v", err)
+	}
+	if len(page) != 1 || page[0].Version != 2 {
+		t.Errorf("expected only version 2, got %+v", page)
+	}
+
+	if _, err := eventsourcing.AuditTrail(eventStore, "cust-unknown"); !errors.Is(err, domain.ErrAggregateNotFound) {
+		t.Errorf("expected ErrAggregateNotFound, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/plaenen/eventstore/pkg/domain"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -25,6 +26,11 @@ type CommandMetadata struct {
 	// CommandID is the unique identifier for this command (for idempotency)
 	CommandID string
 
+	// IdempotencyKey is an optional business idempotency key supplied by the
+	// caller (e.g., an HTTP Idempotency-Key header). When set, it identifies
+	// the command instead of CommandID; see EffectiveCommandID.
+	IdempotencyKey string
+
 	// CorrelationID is used to trace related commands and events
 	CorrelationID string
 
@@ -41,6 +47,12 @@ type CommandMetadata struct {
 	Custom map[string]string
 }
 
+// EffectiveCommandID returns the command ID to record the command under; see
+// domain.CommandMetadata.EffectiveCommandID.
+func (m CommandMetadata) EffectiveCommandID(aggregateID string) string {
+	return domain.CommandMetadata{CommandID: m.CommandID, IdempotencyKey: m.IdempotencyKey}.EffectiveCommandID(aggregateID)
+}
+
 // CommandEnvelope wraps a command with its metadata.
 type CommandEnvelope struct {
 	Command  proto.Message
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"sync"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"google.golang.org/protobuf/proto"
 )
 
 // DefaultCommandBus is a simple in-memory implementation of CommandBus.
@@ -54,6 +57,14 @@ func (b *DefaultCommandBus) Use(middleware CommandMiddleware) {
 
 // Send sends a command to its registered handler.
 func (b *DefaultCommandBus) Send(ctx context.Context, cmd *CommandEnvelope) error {
+	return b.SendWithResult(ctx, cmd, nil)
+}
+
+// SendWithResult sends a command like Send and copies the result the handler
+// set with domain.SetCommandResult into result, which must be of the same
+// message type. result is left untouched if the handler set none; a nil result
+// discards it.
+func (b *DefaultCommandBus) SendWithResult(ctx context.Context, cmd *CommandEnvelope, result proto.Message) error {
 	if cmd == nil {
 		return ErrInvalidCommand
 	}
@@ -80,7 +91,12 @@ func (b *DefaultCommandBus) Send(ctx context.Context, cmd *CommandEnvelope) erro
 		finalHandler = middleware[i](finalHandler)
 	}
 
-	// Execute handler
+	// Execute handler, with the business key where SaveWithIdempotencyKey
+	// callers look for it
+	if cmd.Metadata.IdempotencyKey != "" {
+		ctx = domain.WithIdempotencyKey(ctx, cmd.Metadata.IdempotencyKey)
+	}
+	ctx, handlerResult := domain.WithCommandResult(ctx)
 	events, err := finalHandler.Handle(ctx, cmd)
 	if err != nil {
 		return fmt.Errorf("command handler failed: %w", err)
@@ -93,6 +109,14 @@ func (b *DefaultCommandBus) Send(ctx context.Context, cmd *CommandEnvelope) erro
 		}
 	}
 
+	if got := handlerResult(); result != nil && got != nil {
+		if want, have := result.ProtoReflect().Descriptor().FullName(), got.ProtoReflect().Descriptor().FullName(); want != have {
+			return fmt.Errorf("command result is a %s, not a %s", have, want)
+		}
+		proto.Reset(result)
+		proto.Merge(result, got)
+	}
+
 	return nil
 }
 
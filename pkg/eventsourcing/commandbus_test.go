@@ -5,8 +5,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/eventsourcing"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestCommandBus(t *testing.T) {
@@ -169,4 +171,64 @@ func TestCommandBus(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("Result", func(t *testing.T) {
+		bus := eventsourcing.NewCommandBus()
+		bus.Register("test.ResultCommand", eventsourcing.CommandHandlerFunc(
+			func(ctx context.Context, cmd *eventsourcing.CommandEnvelope) ([]*eventsourcing.Event, error) {
+				domain.SetCommandResult(ctx, wrapperspb.Int64(42))
+				return nil, nil
+			},
+		))
+
+		cmd := &eventsourcing.CommandEnvelope{
+			Command: &emptypb.Empty{},
+			Metadata: eventsourcing.CommandMetadata{
+				CommandID: "cmd-5",
+				Custom: map[string]string{
+					"command_type": "test.ResultCommand",
+				},
+			},
+		}
+
+		result := &wrapperspb.Int64Value{}
+		if err := bus.SendWithResult(context.Background(), cmd, result); err != nil {
+			t.Fatalf("failed to send command: %v", err)
+		}
+		if result.GetValue() != 42 {
+			t.Errorf("expected result 42, got %d", result.GetValue())
+		}
+
+		if err := bus.SendWithResult(context.Background(), cmd, &wrapperspb.StringValue{}); err == nil {
+			t.Error("expected an error for a result of the wrong type")
+		}
+	})
+
+	t.Run("IdempotencyKey", func(t *testing.T) {
+		bus := eventsourcing.NewCommandBus()
+		var key string
+		bus.Register("test.KeyedCommand", eventsourcing.CommandHandlerFunc(
+			func(ctx context.Context, cmd *eventsourcing.CommandEnvelope) ([]*eventsourcing.Event, error) {
+				key, _ = domain.IdempotencyKeyFromContext(ctx)
+				return nil, nil
+			},
+		))
+
+		err := bus.Send(context.Background(), &eventsourcing.CommandEnvelope{
+			Command: &emptypb.Empty{},
+			Metadata: eventsourcing.CommandMetadata{
+				CommandID:      "cmd-6",
+				IdempotencyKey: "payment-42",
+				Custom: map[string]string{
+					"command_type": "test.KeyedCommand",
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to send command: %v", err)
+		}
+		if key != "payment-42" {
+			t.Errorf("expected the handler context to carry the idempotency key, got %q", key)
+		}
+	})
 }
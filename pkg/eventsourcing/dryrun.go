@@ -0,0 +1,151 @@
+package eventsourcing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"google.golang.org/protobuf/proto"
+)
+
+// AggregateCommand executes a command against an aggregate by applying the
+// resulting events (e.g., via the generated Apply<Event> methods), exactly as a
+// command handler does before saving.
+type AggregateCommand[T domain.Aggregate] func(ctx context.Context, agg T, meta domain.EventMetadata) error
+
+// DryRunCommand runs cmd against aggregate and returns the events it would
+// produce, without persisting anything. Use it to debug business-logic changes
+// or to unit test command behavior without an event store.
+//
+// Pass a freshly loaded aggregate (or a new one for creation commands). The
+// aggregate is mutated by the command and should be discarded afterwards.
+//
+// When meta.CausationID is set, it is used as the command ID so event IDs are
+// the same deterministic IDs the real command would produce.
+//
+// Example:
+//
+//	agg, _ := repo.Load("acc-123")
+//	events, err := eventsourcing.DryRunCommand(ctx, agg, func(ctx context.Context, agg *accountv1.AccountAggregate, meta domain.EventMetadata) error {
+//	    return agg.ApplyMoneyDepositedEvent(&accountv1.MoneyDepositedEvent{Amount: "10.00"}, accountv1.WithMetadata(meta))
+//	}, domain.EventMetadata{CausationID: cmdID})
+func DryRunCommand[T domain.Aggregate](ctx context.Context, aggregate T, cmd AggregateCommand[T], meta domain.EventMetadata) ([]*domain.Event, error) {
+	if cmd == nil {
+		return nil, ErrInvalidCommand
+	}
+
+	if meta.CausationID != "" {
+		if setter, ok := any(aggregate).(interface{ SetCommandID(string) }); ok {
+			setter.SetCommandID(meta.CausationID)
+		}
+	}
+
+	// Only report events produced by this command
+	before := len(aggregate.UncommittedEvents())
+	if err := cmd(ctx, aggregate, meta); err != nil {
+		return nil, err
+	}
+
+	produced := aggregate.UncommittedEvents()[before:]
+	events := make([]*domain.Event, len(produced))
+	copy(events, produced)
+	return events, nil
+}
+
+// EventDifference describes one mismatch found by DiffEvents.
+type EventDifference struct {
+	// Index is the position of the event in the compared slices
+	Index int
+
+	// Field is the compared field (EventType, Version, AggregateID, Data,
+	// UniqueConstraints), or "Event" when an event is missing on one side
+	Field string
+
+	// Expected and Actual describe the values on each side
+	Expected string
+	Actual   string
+}
+
+// String formats the difference for test output and logs.
+func (d EventDifference) String() string {
+	return fmt.Sprintf("event %d: %s: expected %s, got %s", d.Index, d.Field, d.Expected, d.Actual)
+}
+
+// DiffEvents compares two event sequences, typically the result of
+// DryRunCommand against the events already stored for the same command, and
+// returns their differences (nil if equivalent).
+//
+// Events are compared position by position on type, version, aggregate ID,
+// payload and unique constraints. IDs, timestamps, positions and metadata are
+// ignored since they legitimately differ between runs. Payloads are compared
+// with proto.Equal when registry knows the event type, and byte-wise otherwise.
+func DiffEvents(expected, actual []*domain.Event, registry domain.EventRegistry) []EventDifference {
+	var diffs []EventDifference
+
+	n := len(expected)
+	if len(actual) > n {
+		n = len(actual)
+	}
+	for i := 0; i < n; i++ {
+		if i >= len(expected) {
+			diffs = append(diffs, EventDifference{Index: i, Field: "Event", Expected: "<none>", Actual: actual[i].EventType})
+			continue
+		}
+		if i >= len(actual) {
+			diffs = append(diffs, EventDifference{Index: i, Field: "Event", Expected: expected[i].EventType, Actual: "<none>"})
+			continue
+		}
+
+		e, a := expected[i], actual[i]
+		if e.EventType != a.EventType {
+			diffs = append(diffs, EventDifference{Index: i, Field: "EventType", Expected: e.EventType, Actual: a.EventType})
+			continue // The remaining fields are not comparable
+		}
+		if e.Version != a.Version {
+			diffs = append(diffs, EventDifference{Index: i, Field: "Version",
+				Expected: fmt.Sprint(e.Version), Actual: fmt.Sprint(a.Version)})
+		}
+		if e.AggregateID != a.AggregateID {
+			diffs = append(diffs, EventDifference{Index: i, Field: "AggregateID", Expected: e.AggregateID, Actual: a.AggregateID})
+		}
+		if d, ok := diffPayload(i, e, a, registry); !ok {
+			diffs = append(diffs, d)
+		}
+		if !reflect.DeepEqual(normalizeConstraints(e.UniqueConstraints), normalizeConstraints(a.UniqueConstraints)) {
+			diffs = append(diffs, EventDifference{Index: i, Field: "UniqueConstraints",
+				Expected: fmt.Sprint(e.UniqueConstraints), Actual: fmt.Sprint(a.UniqueConstraints)})
+		}
+	}
+
+	return diffs
+}
+
+// diffPayload compares event payloads, decoding them when the type is registered.
+func diffPayload(index int, expected, actual *domain.Event, registry domain.EventRegistry) (EventDifference, bool) {
+	if _, ok := registry.Lookup(expected.EventType); ok {
+		e, errE := registry.Decode(expected)
+		a, errA := registry.Decode(actual)
+		if errE == nil && errA == nil {
+			if proto.Equal(e, a) {
+				return EventDifference{}, true
+			}
+			return EventDifference{Index: index, Field: "Data", Expected: fmt.Sprint(e), Actual: fmt.Sprint(a)}, false
+		}
+	}
+
+	if bytes.Equal(expected.Data, actual.Data) {
+		return EventDifference{}, true
+	}
+	return EventDifference{Index: index, Field: "Data",
+		Expected: fmt.Sprintf("%d bytes", len(expected.Data)), Actual: fmt.Sprintf("%d bytes", len(actual.Data))}, false
+}
+
+// normalizeConstraints treats nil and empty constraint lists as equal.
+func normalizeConstraints(constraints []domain.UniqueConstraint) []domain.UniqueConstraint {
+	if len(constraints) == 0 {
+		return nil
+	}
+	return constraints
+}
@@ -0,0 +1,81 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"testing"
+
+	accountdomain "github.com/plaenen/eventstore/examples/bankaccount/domain"
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+)
+
+func deposit(amount string) eventsourcing.AggregateCommand[*accountv1.AccountAggregate] {
+	return func(ctx context.Context, agg *accountv1.AccountAggregate, meta domain.EventMetadata) error {
+		return agg.ApplyMoneyDepositedEvent(&accountv1.MoneyDepositedEvent{
+			AccountId: agg.ID(),
+			Amount:    amount,
+		}, accountv1.WithMetadata(meta))
+	}
+}
+
+func openedAccount(t *testing.T) *accountv1.AccountAggregate {
+	t.Helper()
+	agg := accountdomain.NewAccount("acc-1")
+	if err := agg.ApplyAccountOpenedEvent(&accountv1.AccountOpenedEvent{
+		AccountId:      "acc-1",
+		OwnerName:      "Alice",
+		InitialBalance: "100.00",
+	}); err != nil {
+		t.Fatalf("failed to open account: %v", err)
+	}
+	return agg
+}
+
+func TestDryRunCommand(t *testing.T) {
+	ctx := context.Background()
+	meta := domain.EventMetadata{CausationID: "cmd-1"}
+
+	t.Run("ReturnsOnlyEventsOfTheCommand", func(t *testing.T) {
+		agg := openedAccount(t)
+
+		events, err := eventsourcing.DryRunCommand(ctx, agg, deposit("10.00"), meta)
+		if err != nil {
+			t.Fatalf("dry run failed: %v", err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("expected 1 event, got %d", len(events))
+		}
+		if events[0].EventType != accountv1.MoneyDepositedEventType || events[0].Version != 2 {
+			t.Errorf("unexpected event: %s v%d", events[0].EventType, events[0].Version)
+		}
+	})
+
+	t.Run("DeterministicIDs", func(t *testing.T) {
+		first, _ := eventsourcing.DryRunCommand(ctx, openedAccount(t), deposit("10.00"), meta)
+		second, _ := eventsourcing.DryRunCommand(ctx, openedAccount(t), deposit("10.00"), meta)
+		if first[0].ID != second[0].ID {
+			t.Errorf("expected deterministic event IDs, got %s and %s", first[0].ID, second[0].ID)
+		}
+	})
+
+	t.Run("DiffEvents", func(t *testing.T) {
+		expected, _ := eventsourcing.DryRunCommand(ctx, openedAccount(t), deposit("10.00"), meta)
+		same, _ := eventsourcing.DryRunCommand(ctx, openedAccount(t), deposit("10.00"), meta)
+		changed, _ := eventsourcing.DryRunCommand(ctx, openedAccount(t), deposit("25.00"), meta)
+
+		if diffs := eventsourcing.DiffEvents(expected, same, accountv1.EventRegistry); len(diffs) != 0 {
+			t.Errorf("expected no differences, got %v", diffs)
+		}
+
+		diffs := eventsourcing.DiffEvents(expected, changed, accountv1.EventRegistry)
+		if len(diffs) != 1 || diffs[0].Field != "Data" {
+			t.Errorf("expected a single Data difference, got %v", diffs)
+		}
+
+		diffs = eventsourcing.DiffEvents(expected, nil, nil)
+		if len(diffs) != 1 || diffs[0].Field != "Event" {
+			t.Errorf("expected a missing event difference, got %v", diffs)
+		}
+	})
+}
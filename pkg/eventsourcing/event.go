@@ -6,9 +6,18 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/plaenen/eventstore/pkg/domain"
 	"google.golang.org/protobuf/proto"
 )
 
+// EventTypeOf returns the canonical event type string of an event message,
+// its fully qualified proto name (e.g., "account.v1.AccountOpenedEvent").
+// Use it instead of hand-written strings when registering handlers or
+// filtering events; it always matches the EventType generated code stores.
+func EventTypeOf(msg proto.Message) string {
+	return domain.EventTypeOf(msg)
+}
+
 // Event represents a domain event that has occurred in the system.
 // Events are immutable facts about state changes.
 type Event struct {
@@ -0,0 +1,184 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"testing"
+
+	accountdomain "github.com/plaenen/eventstore/examples/bankaccount/domain"
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	subscriptionv1 "github.com/plaenen/eventstore/examples/pb/subscription/v1"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEventTypeOf(t *testing.T) {
+	eventType := eventsourcing.EventTypeOf(&accountv1.AccountOpenedEvent{})
+	if eventType != "account.v1.AccountOpenedEvent" {
+		t.Errorf("expected fully qualified proto name, got %s", eventType)
+	}
+	if eventType != accountv1.AccountOpenedEventType {
+		t.Errorf("generated constant %s does not match %s", accountv1.AccountOpenedEventType, eventType)
+	}
+
+	agg := openedAccount(t)
+	if got := agg.UncommittedEvents()[0].EventType; got != eventType {
+		t.Errorf("expected stored event type %s, got %s", eventType, got)
+	}
+}
+
+func TestEventRegistry(t *testing.T) {
+	data, err := proto.Marshal(&accountv1.AccountOpenedEvent{
+		AccountId:      "acc-1",
+		OwnerName:      "Alice",
+		InitialBalance: "100.00",
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+	opened := &domain.Event{
+		ID:            "evt-1",
+		AggregateID:   "acc-1",
+		AggregateType: "Account",
+		EventType:     accountv1.AccountOpenedEventType,
+		Version:       1,
+		Data:          data,
+	}
+
+	for _, eventType := range []string{
+		accountv1.AccountOpenedEventType,
+		accountv1.MoneyDepositedEventType,
+		accountv1.MoneyWithdrawnEventType,
+		accountv1.AccountClosedEventType,
+	} {
+		descriptor, ok := accountv1.EventRegistry.Lookup(eventType)
+		if !ok {
+			t.Fatalf("expected %s to be registered", eventType)
+		}
+		if descriptor.AggregateType != "Account" {
+			t.Errorf("expected %s to belong to Account, got %s", eventType, descriptor.AggregateType)
+		}
+		if got := eventsourcing.EventTypeOf(descriptor.New()); got != eventType {
+			t.Errorf("expected New to return a %s, got %s", eventType, got)
+		}
+	}
+
+	t.Run("Apply", func(t *testing.T) {
+		agg := accountdomain.NewAccount("acc-1")
+		if err := accountv1.EventRegistry.Apply(agg, opened); err != nil {
+			t.Fatalf("failed to apply event: %v", err)
+		}
+		if agg.OwnerName != "Alice" {
+			t.Errorf("expected owner Alice, got %q", agg.OwnerName)
+		}
+	})
+
+	t.Run("RejectsOtherAggregates", func(t *testing.T) {
+		agg := subscriptionv1.NewSubscription("sub-1", nil)
+		if err := accountv1.EventRegistry.Apply(agg, opened); err == nil {
+			t.Error("expected an account event to be rejected by a subscription aggregate")
+		}
+	})
+
+	t.Run("UnknownEventType", func(t *testing.T) {
+		unknown := &domain.Event{EventType: "account.v1.Unknown", Data: data}
+		if _, err := accountv1.EventRegistry.Decode(unknown); err == nil {
+			t.Error("expected an unknown event type to fail to decode")
+		}
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		merged := domain.MergeEventRegistries(accountv1.EventRegistry, subscriptionv1.EventRegistry)
+		if len(merged) != len(accountv1.EventRegistry)+len(subscriptionv1.EventRegistry) {
+			t.Errorf("expected %d event types, got %d",
+				len(accountv1.EventRegistry)+len(subscriptionv1.EventRegistry), len(merged))
+		}
+		if _, ok := merged.Lookup(subscriptionv1.SubscriptionCreatedEventType); !ok {
+			t.Error("expected the merged registry to hold subscription events")
+		}
+	})
+}
+
+func TestLegacyEventTypes(t *testing.T) {
+	const legacyType = "accountv1.AccountOpenedEvent"
+
+	data, err := proto.Marshal(&accountv1.AccountOpenedEvent{
+		AccountId:      "acc-1",
+		OwnerName:      "Alice",
+		InitialBalance: "100.00",
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+	legacyEvent := &domain.Event{
+		ID:            "evt-1",
+		AggregateID:   "acc-1",
+		AggregateType: "Account",
+		EventType:     legacyType,
+		Version:       1,
+		Data:          data,
+	}
+
+	if got := domain.CanonicalEventType(legacyType); got != accountv1.AccountOpenedEventType {
+		t.Fatalf("expected %s to be an alias of %s, got %s", legacyType, accountv1.AccountOpenedEventType, got)
+	}
+
+	t.Run("RepositoryLoadsLegacyEvents", func(t *testing.T) {
+		eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer eventStore.Close()
+
+		if err := eventStore.AppendEvents(context.Background(), "acc-1", 0, []*domain.Event{legacyEvent}); err != nil {
+			t.Fatalf("failed to append event: %v", err)
+		}
+
+		repo := accountv1.NewAccountRepository(eventStore, accountdomain.NewAccount)
+		agg, err := repo.Load("acc-1")
+		if err != nil {
+			t.Fatalf("failed to load aggregate: %v", err)
+		}
+		if agg.OwnerName != "Alice" {
+			t.Errorf("expected owner Alice, got %q", agg.OwnerName)
+		}
+	})
+
+	t.Run("ProjectionsHandleLegacyEvents", func(t *testing.T) {
+		var generic, typed int
+		projection := eventsourcing.NewProjectionBuilder("generic").
+			On(accountv1.OnAccountOpened(func(ctx context.Context, event *accountv1.AccountOpenedEvent, envelope *domain.EventEnvelope) error {
+				generic++
+				return nil
+			})).
+			Build()
+		accountProjection := accountv1.NewAccountProjectionBuilder("typed").
+			OnAccountOpened(func(ctx context.Context, event *accountv1.AccountOpenedEvent, envelope *domain.EventEnvelope) error {
+				typed++
+				return nil
+			}).
+			Build()
+
+		envelope := &domain.EventEnvelope{Event: *legacyEvent}
+		if err := projection.Handle(context.Background(), envelope); err != nil {
+			t.Fatalf("generic projection failed: %v", err)
+		}
+		if err := accountProjection.Handle(context.Background(), envelope); err != nil {
+			t.Fatalf("typed projection failed: %v", err)
+		}
+		if generic != 1 || typed != 1 {
+			t.Errorf("expected both projections to handle the legacy event, got generic=%d typed=%d", generic, typed)
+		}
+	})
+
+	t.Run("RegistryDecodesLegacyEvents", func(t *testing.T) {
+		msg, err := accountv1.EventRegistry.Decode(legacyEvent)
+		if err != nil {
+			t.Fatalf("failed to decode legacy event: %v", err)
+		}
+		if _, ok := msg.(*accountv1.AccountOpenedEvent); !ok {
+			t.Errorf("expected *AccountOpenedEvent, got %T", msg)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// ProjectionSink is an external system a projection writes its read model to,
+// such as a search index, a cache or another service's API.
+type ProjectionSink interface {
+	// Apply writes one event to the external system. idempotencyKey is stable
+	// for a given projection and event, so a redelivered event carries the same
+	// key; the sink must use it to ignore duplicates (e.g. as a document ID, an
+	// upsert key or an Idempotency-Key header).
+	Apply(ctx context.Context, idempotencyKey string, envelope *domain.EventEnvelope) error
+
+	// Reset clears the external read model before a rebuild.
+	Reset(ctx context.Context) error
+}
+
+// ExternalProjectionKey returns the idempotency key passed to a ProjectionSink
+// for an event. It includes the projection name so projections sharing a sink
+// do not suppress each other's writes.
+func ExternalProjectionKey(projectionName, eventID string) string {
+	return projectionName + ":" + eventID
+}
+
+// ExternalProjectionBuilder builds an ExternalProjection.
+type ExternalProjectionBuilder struct {
+	name            string
+	sink            ProjectionSink
+	checkpointStore store.CheckpointStore
+	eventStore      store.EventStore
+	eventTypes      map[string]bool
+	limits          store.HandlerLimits
+}
+
+// NewExternalProjectionBuilder creates a builder for a projection whose read
+// model lives outside the event store, in sink.
+//
+// A SQLiteProjection commits the read model update and its checkpoint in one
+// transaction, so every event is applied exactly once. That is impossible when
+// the read model is in another system. An ExternalProjection applies each
+// event in two steps instead: first to the sink, then to the checkpoint in
+// checkpointStore (which may live in a separate database). A crash between the
+// two steps redelivers the event, so delivery is at-least-once; the sink
+// receives the same idempotency key on redelivery and must deduplicate on it.
+//
+// The projection keeps its own checkpoint: feed it from an event bus
+// subscription rather than a ProjectionManager, which would overwrite the
+// checkpoint with its own.
+//
+// Example:
+//
+//	projection := eventsourcing.NewExternalProjectionBuilder("account-search", searchSink, checkpointStore).
+//	    Only(accountv1.AccountOpenedEventType, accountv1.AccountClosedEventType).
+//	    WithEventStore(eventStore).
+//	    Build()
+func NewExternalProjectionBuilder(name string, sink ProjectionSink, checkpointStore store.CheckpointStore) *ExternalProjectionBuilder {
+	return &ExternalProjectionBuilder{
+		name:            name,
+		sink:            sink,
+		checkpointStore: checkpointStore,
+	}
+}
+
+// Only restricts the projection to the given event types. By default every
+// event is sent to the sink.
+func (b *ExternalProjectionBuilder) Only(eventTypes ...string) *ExternalProjectionBuilder {
+	if b.eventTypes == nil {
+		b.eventTypes = make(map[string]bool)
+	}
+	for _, eventType := range eventTypes {
+		b.eventTypes[domain.CanonicalEventType(eventType)] = true
+	}
+	return b
+}
+
+// WithEventStore sets the event store Rebuild replays from.
+func (b *ExternalProjectionBuilder) WithEventStore(eventStore store.EventStore) *ExternalProjectionBuilder {
+	b.eventStore = eventStore
+	return b
+}
+
+// WithHandlerTimeout cancels the sink's context after d. Handle then returns
+// store.ErrHandlerTimeout without saving the checkpoint, so the event is
+// redelivered with the same idempotency key.
+func (b *ExternalProjectionBuilder) WithHandlerTimeout(d time.Duration) *ExternalProjectionBuilder {
+	b.limits.Timeout = d
+	return b
+}
+
+// WithSlowHandlerWarning logs sink calls that take at least threshold and
+// reports them to metrics (may be nil).
+func (b *ExternalProjectionBuilder) WithSlowHandlerWarning(threshold time.Duration, metrics store.ProjectionMetrics) *ExternalProjectionBuilder {
+	b.limits.SlowThreshold = threshold
+	b.limits.Metrics = metrics
+	return b
+}
+
+// WithLogger logs slow-handler warnings to logger (default slog.Default()).
+func (b *ExternalProjectionBuilder) WithLogger(logger *slog.Logger) *ExternalProjectionBuilder {
+	b.limits.Logger = logger
+	return b
+}
+
+// Build creates the projection, resuming from its saved checkpoint if any.
+func (b *ExternalProjectionBuilder) Build() *ExternalProjection {
+	p := &ExternalProjection{
+		name:            b.name,
+		sink:            b.sink,
+		checkpointStore: b.checkpointStore,
+		eventStore:      b.eventStore,
+		eventTypes:      b.eventTypes,
+		limits:          b.limits,
+	}
+
+	// No checkpoint, start from the beginning
+	if checkpoint, err := b.checkpointStore.Load(b.name); err == nil {
+		p.position = checkpoint.Position
+	}
+
+	return p
+}
+
+// ExternalProjection implements Projection for a read model in an external
+// system. See NewExternalProjectionBuilder for its delivery guarantees.
+type ExternalProjection struct {
+	name            string
+	sink            ProjectionSink
+	checkpointStore store.CheckpointStore
+	eventStore      store.EventStore
+	eventTypes      map[string]bool // nil = all event types
+	limits          store.HandlerLimits
+
+	mu       sync.Mutex
+	position int64 // Last checkpointed event position
+}
+
+// Name returns the projection name.
+func (p *ExternalProjection) Name() string {
+	return p.name
+}
+
+// Handle applies an event to the sink and then records the checkpoint.
+//
+// Events at or before the checkpoint are skipped without calling the sink. If
+// the checkpoint cannot be saved after the sink succeeded, Handle returns an
+// error and the event is applied again on redelivery with the same key.
+func (p *ExternalProjection) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
+	if p.eventTypes != nil && !p.eventTypes[domain.CanonicalEventType(envelope.EventType)] {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Position is 0 for events that were never persisted; they cannot be
+	// deduplicated here and always reach the sink
+	if envelope.Position > 0 && envelope.Position <= p.position {
+		return nil
+	}
+
+	key := ExternalProjectionKey(p.name, envelope.ID)
+	err := p.limits.Run(ctx, p.name, envelope.EventType, func(ctx context.Context) error {
+		return p.sink.Apply(ctx, key, envelope)
+	})
+	if err != nil {
+		return fmt.Errorf("sink failed: %w", err)
+	}
+
+	if envelope.Position == 0 {
+		return nil
+	}
+	if err := p.checkpointStore.Save(&store.ProjectionCheckpoint{
+		ProjectionName: p.name,
+		Position:       envelope.Position,
+		LastEventID:    envelope.ID,
+		UpdatedAt:      domain.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	p.position = envelope.Position
+
+	return nil
+}
+
+// Reset clears the sink and deletes the checkpoint.
+func (p *ExternalProjection) Reset(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.sink.Reset(ctx); err != nil {
+		return fmt.Errorf("sink reset failed: %w", err)
+	}
+	if err := p.checkpointStore.Delete(p.name); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	p.position = 0
+
+	return nil
+}
+
+// Rebuild resets the projection and replays every event from the event store
+// set with WithEventStore. opts can skip or quarantine events the sink fails
+// on instead of aborting (see store.OnEventError).
+func (p *ExternalProjection) Rebuild(ctx context.Context, opts ...store.RebuildOption) error {
+	if p.eventStore == nil {
+		return fmt.Errorf("projection %s has no event store to rebuild from", p.name)
+	}
+	options, err := store.NewRebuildOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	if err := p.Reset(ctx); err != nil {
+		return fmt.Errorf("failed to reset projection: %w", err)
+	}
+	if err := options.Start(ctx, p.name); err != nil {
+		return err
+	}
+
+	ctx = WithReplay(ctx)
+	position := int64(0)
+	batchSize := 1000
+	report := store.RebuildReport{ProjectionName: p.name}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("rebuild cancelled: %w", err)
+		}
+
+		events, err := p.eventStore.LoadAllEvents(ctx, position, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to load events: %w", err)
+		}
+
+		for _, event := range events {
+			if err := p.Handle(ctx, &domain.EventEnvelope{Event: *event}); err != nil {
+				if err := options.HandleEventError(ctx, event, err, &report); err != nil {
+					return err
+				}
+			}
+			position = event.Position + 1
+			report.EventsProcessed++
+		}
+
+		if len(events) < batchSize {
+			break
+		}
+	}
+
+	options.Complete(report)
+	return nil
+}
+
+// GetCheckpoint returns the position of the last event recorded as applied.
+func (p *ExternalProjection) GetCheckpoint() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.position
+}
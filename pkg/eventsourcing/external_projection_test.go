@@ -0,0 +1,181 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+// recordingSink is an idempotent sink that records the keys it applied.
+type recordingSink struct {
+	applied  map[string]bool
+	attempts int
+	replays  int
+	resets   int
+}
+
+func (s *recordingSink) Apply(ctx context.Context, key string, envelope *domain.EventEnvelope) error {
+	s.attempts++
+	if eventsourcing.IsReplay(ctx) {
+		s.replays++
+	}
+	s.applied[key] = true
+	return nil
+}
+
+func (s *recordingSink) Reset(ctx context.Context) error {
+	s.resets++
+	s.applied = make(map[string]bool)
+	return nil
+}
+
+// flakyCheckpointStore fails the next Save when failNext is set.
+type flakyCheckpointStore struct {
+	store.CheckpointStore
+	failNext bool
+}
+
+func (s *flakyCheckpointStore) Save(checkpoint *store.ProjectionCheckpoint) error {
+	if s.failNext {
+		s.failNext = false
+		return errors.New("checkpoint store unavailable")
+	}
+	return s.CheckpointStore.Save(checkpoint)
+}
+
+func TestExternalProjection(t *testing.T) {
+	ctx := context.Background()
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	// Checkpoints live in their own database, as they would for an external sink
+	checkpointDB, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint database: %v", err)
+	}
+	defer checkpointDB.Close()
+	sqliteCheckpoints, err := sqlite.NewCheckpointStore(checkpointDB.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+	checkpoints := &flakyCheckpointStore{CheckpointStore: sqliteCheckpoints}
+
+	for i := 1; i <= 3; i++ {
+		eventType := "test.Opened"
+		if i == 3 {
+			eventType = "test.Ignored"
+		}
+		err := eventStore.AppendEvents(ctx, "agg-1", int64(i-1), []*domain.Event{{
+			ID:            fmt.Sprintf("evt-%d", i),
+			AggregateID:   "agg-1",
+			AggregateType: "Test",
+			EventType:     eventType,
+			Version:       int64(i),
+			Timestamp:     time.Now(),
+			Data:          []byte("data"),
+		}})
+		if err != nil {
+			t.Fatalf("failed to append event: %v", err)
+		}
+	}
+	events, err := eventStore.LoadAllEvents(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("failed to load events: %v", err)
+	}
+
+	sink := &recordingSink{applied: make(map[string]bool)}
+	projection := eventsourcing.NewExternalProjectionBuilder("search", sink, checkpoints).
+		Only("test.Opened").
+		WithEventStore(eventStore).
+		Build()
+
+	t.Run("AppliesThenCheckpoints", func(t *testing.T) {
+		if err := projection.Handle(ctx, &domain.EventEnvelope{Event: *events[0]}); err != nil {
+			t.Fatalf("handle failed: %v", err)
+		}
+		if !sink.applied[eventsourcing.ExternalProjectionKey("search", "evt-1")] {
+			t.Errorf("expected evt-1 to reach the sink, got %v", sink.applied)
+		}
+
+		checkpoint, err := sqliteCheckpoints.Load("search")
+		if err != nil {
+			t.Fatalf("failed to load checkpoint: %v", err)
+		}
+		if checkpoint.Position != events[0].Position || checkpoint.LastEventID != "evt-1" {
+			t.Errorf("unexpected checkpoint: %+v", checkpoint)
+		}
+
+		// Redelivery of a checkpointed event does not reach the sink
+		attempts := sink.attempts
+		if err := projection.Handle(ctx, &domain.EventEnvelope{Event: *events[0]}); err != nil {
+			t.Fatalf("handle failed: %v", err)
+		}
+		if sink.attempts != attempts {
+			t.Errorf("expected checkpointed event to be skipped")
+		}
+	})
+
+	t.Run("RedeliversWithSameKeyAfterCheckpointFailure", func(t *testing.T) {
+		checkpoints.failNext = true
+		if err := projection.Handle(ctx, &domain.EventEnvelope{Event: *events[1]}); err == nil {
+			t.Fatal("expected checkpoint failure")
+		}
+		if projection.GetCheckpoint() != events[0].Position {
+			t.Errorf("expected checkpoint to stay at %d, got %d", events[0].Position, projection.GetCheckpoint())
+		}
+
+		attempts := sink.attempts
+		if err := projection.Handle(ctx, &domain.EventEnvelope{Event: *events[1]}); err != nil {
+			t.Fatalf("redelivery failed: %v", err)
+		}
+		if sink.attempts != attempts+1 || len(sink.applied) != 2 {
+			t.Errorf("expected one deduplicated redelivery, got %d attempts and keys %v", sink.attempts, sink.applied)
+		}
+	})
+
+	t.Run("SkipsFilteredEventTypes", func(t *testing.T) {
+		attempts := sink.attempts
+		if err := projection.Handle(ctx, &domain.EventEnvelope{Event: *events[2]}); err != nil {
+			t.Fatalf("handle failed: %v", err)
+		}
+		if sink.attempts != attempts {
+			t.Errorf("expected filtered event to be skipped")
+		}
+	})
+
+	t.Run("ResumesFromCheckpoint", func(t *testing.T) {
+		resumed := eventsourcing.NewExternalProjectionBuilder("search", sink, checkpoints).Build()
+		if resumed.GetCheckpoint() != events[1].Position {
+			t.Errorf("expected to resume at %d, got %d", events[1].Position, resumed.GetCheckpoint())
+		}
+	})
+
+	t.Run("Rebuild", func(t *testing.T) {
+		if sink.replays != 0 {
+			t.Fatalf("expected no replayed applies before rebuild, got %d", sink.replays)
+		}
+		if err := projection.Rebuild(ctx); err != nil {
+			t.Fatalf("rebuild failed: %v", err)
+		}
+		if sink.replays != 2 {
+			t.Errorf("expected 2 applies marked as replay, got %d", sink.replays)
+		}
+		if sink.resets != 1 || len(sink.applied) != 2 {
+			t.Errorf("expected sink reset and 2 events applied, got %d resets and keys %v", sink.resets, sink.applied)
+		}
+		if projection.GetCheckpoint() != events[1].Position {
+			t.Errorf("expected checkpoint %d after rebuild, got %d", events[1].Position, projection.GetCheckpoint())
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package eventsourcing
+
+import (
+	"hash/fnv"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// Partitioner maps events to one of a fixed number of partitions. Events in the
+// same partition are processed in order; different partitions may be processed
+// in parallel. Every component that splits work (the NATS event bus subjects,
+// ProjectionManager.StartPartitioned) must use the same Partitioner, or events
+// of one aggregate could be handled concurrently and out of order.
+//
+// Changing the partitioner or its partition count moves aggregates between
+// partitions. Drain in-flight work (stop publishers and let subscribers catch
+// up) before deploying the change.
+type Partitioner interface {
+	// Partitions returns the number of partitions (at least 1).
+	Partitions() int
+
+	// Partition returns the partition of event, in [0, Partitions()).
+	Partition(event *domain.Event) int
+}
+
+// HashPartitioner returns the default partitioner: the FNV-1a hash of the
+// aggregate ID modulo n, so all events of an aggregate share a partition.
+// n below 1 is treated as 1.
+func HashPartitioner(n int) Partitioner {
+	return KeyPartitioner(n, func(event *domain.Event) string {
+		return event.AggregateID
+	})
+}
+
+// TenantPartitioner hashes the tenant ID from the event metadata instead of the
+// aggregate ID, so all events of a tenant land on the same partition and stay
+// ordered across its aggregates. Events without a tenant fall back to their
+// aggregate ID.
+func TenantPartitioner(n int) Partitioner {
+	return KeyPartitioner(n, func(event *domain.Event) string {
+		if event.Metadata.TenantID != "" {
+			return event.Metadata.TenantID
+		}
+		return event.AggregateID
+	})
+}
+
+// KeyPartitioner returns a partitioner hashing the key returned by key with
+// FNV-1a. Events with equal keys always share a partition.
+func KeyPartitioner(n int, key func(event *domain.Event) string) Partitioner {
+	if n < 1 {
+		n = 1
+	}
+	return &keyPartitioner{n: n, key: key}
+}
+
+type keyPartitioner struct {
+	n   int
+	key func(event *domain.Event) string
+}
+
+func (p *keyPartitioner) Partitions() int {
+	return p.n
+}
+
+func (p *keyPartitioner) Partition(event *domain.Event) int {
+	return PartitionForKey(p.key(event), p.n)
+}
+
+// PartitionForKey returns the partition of key among n partitions, using the
+// same FNV-1a hash as HashPartitioner. Use it to locate the partition of an
+// aggregate (or tenant) outside an event, e.g. to route a query.
+func PartitionForKey(key string, n int) int {
+	if n <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,58 @@
+package eventsourcing_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+)
+
+func TestHashPartitioner(t *testing.T) {
+	partitioner := eventsourcing.HashPartitioner(8)
+	if partitioner.Partitions() != 8 {
+		t.Fatalf("expected 8 partitions, got %d", partitioner.Partitions())
+	}
+
+	counts := make([]int, 8)
+	for i := 0; i < 800; i++ {
+		aggregateID := fmt.Sprintf("acc-%d", i)
+		first := partitioner.Partition(&domain.Event{AggregateID: aggregateID, Version: 1})
+		second := partitioner.Partition(&domain.Event{AggregateID: aggregateID, Version: 2})
+		if first != second {
+			t.Fatalf("events of %s landed on partitions %d and %d", aggregateID, first, second)
+		}
+		if first != eventsourcing.PartitionForKey(aggregateID, 8) {
+			t.Fatalf("PartitionForKey disagrees with HashPartitioner for %s", aggregateID)
+		}
+		counts[first]++
+	}
+	for p, n := range counts {
+		if n < 50 {
+			t.Errorf("partition %d received only %d of 800 aggregates: %v", p, n, counts)
+		}
+	}
+
+	if p := eventsourcing.HashPartitioner(0).Partition(&domain.Event{AggregateID: "acc-1"}); p != 0 {
+		t.Errorf("expected a single partition for n=0, got %d", p)
+	}
+}
+
+func TestTenantPartitioner(t *testing.T) {
+	partitioner := eventsourcing.TenantPartitioner(16)
+
+	tenantEvent := func(aggregateID string) *domain.Event {
+		return &domain.Event{AggregateID: aggregateID, Metadata: domain.EventMetadata{TenantID: "tenant-a"}}
+	}
+	want := partitioner.Partition(tenantEvent("acc-1"))
+	for i := 2; i < 50; i++ {
+		if got := partitioner.Partition(tenantEvent(fmt.Sprintf("acc-%d", i))); got != want {
+			t.Fatalf("expected all tenant-a events on partition %d, acc-%d is on %d", want, i, got)
+		}
+	}
+
+	untenanted := &domain.Event{AggregateID: "acc-1"}
+	if got := partitioner.Partition(untenanted); got != eventsourcing.PartitionForKey("acc-1", 16) {
+		t.Errorf("expected events without a tenant to fall back to the aggregate ID, got %d", got)
+	}
+}
@@ -2,8 +2,11 @@ package eventsourcing
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/messaging"
@@ -38,7 +41,18 @@ type ProjectionManager struct {
 	eventBus        messaging.EventBus   // For real-time
 	mu              sync.RWMutex
 	running         map[string]context.CancelFunc
+	paused          map[string]chan struct{} // Closed on Resume
 	wg              sync.WaitGroup
+
+	lagAlerts        map[string][]*lagAlert
+	lagCheckInterval time.Duration
+	lagMetrics       ProjectionLagMetrics
+	logger           *slog.Logger
+
+	parallelism int // Projections StartAll and RebuildAll handle at once
+
+	statesMu sync.Mutex
+	states   map[string]ProjectionState // What the manager last did with each projection
 }
 
 // NewProjectionManager creates a new projection manager.
@@ -49,6 +63,9 @@ func NewProjectionManager(checkpointStore store.CheckpointStore, eventStore stor
 		eventStore:      eventStore,
 		eventBus:        eventBus,
 		running:         make(map[string]context.CancelFunc),
+		paused:          make(map[string]chan struct{}),
+		lagMetrics:      newOTelLagMetrics(),
+		logger:          slog.Default(),
 	}
 }
 
@@ -61,20 +78,97 @@ func (m *ProjectionManager) Register(projection Projection) {
 }
 
 // Start starts a projection consuming events from EventBus (real-time).
+//
+// The subscription is durable under the projection's name (see
+// messaging.WithDurable): on a bus keeping durable subscriptions, a restarted
+// projection is only sent the events it had not handled yet.
 func (m *ProjectionManager) Start(ctx context.Context, projectionName string) error {
+	return m.start(ctx, projectionName, [][]messaging.SubscribeOption{
+		{messaging.WithDurable(projectionName)},
+	})
+}
+
+// StartPartitioned starts a projection with one event bus subscription per
+// partition, so partitions are handled in parallel while the events of each
+// aggregate stay in order. The event bus must be partitioned (e.g. a NATS bus
+// with Config.Partitioner); workers are assigned with the bus's own
+// Partitioner, the same function events were published with.
+//
+// The projection's Handle is called concurrently from different partitions
+// and must be safe for concurrent use.
+func (m *ProjectionManager) StartPartitioned(ctx context.Context, projectionName string) error {
+	bus, ok := m.eventBus.(interface{ Partitioner() Partitioner })
+	if !ok || bus.Partitioner() == nil {
+		return fmt.Errorf("cannot start %s partitioned: event bus is not partitioned", projectionName)
+	}
+
+	partitions := bus.Partitioner().Partitions()
+	subscriptions := make([][]messaging.SubscribeOption, partitions)
+	for p := range subscriptions {
+		subscriptions[p] = []messaging.SubscribeOption{
+			messaging.WithPartition(p),
+			messaging.WithDurable(fmt.Sprintf("%s-%d", projectionName, p)),
+		}
+	}
+	return m.start(ctx, projectionName, subscriptions)
+}
+
+// StartInGroup starts a projection in the event bus consumer group named
+// after it (see messaging.WithConsumerGroup), so that the same projection
+// started on several instances splits the events between them instead of
+// each handling all of them. The event bus must support consumer groups,
+// e.g. the Kafka bus.
+//
+// The bus tracks which events the group has consumed, so unlike Start the
+// checkpoint is not used to skip redelivered events. The instances share the
+// checkpoint, which holds the highest position any of them handled; with a
+// checkpoint store implementing store.CheckpointAdvancer (such as the SQLite
+// and Postgres stores) an instance never moves it back.
+func (m *ProjectionManager) StartInGroup(ctx context.Context, projectionName string) error {
+	return m.start(ctx, projectionName, [][]messaging.SubscribeOption{
+		{messaging.WithConsumerGroup(projectionName)},
+	})
+}
+
+// start subscribes a projection once per entry of subscriptions, each with
+// its subscribe options. The manager is only locked to claim the projection,
+// so projections can be started concurrently (see StartAll).
+func (m *ProjectionManager) start(ctx context.Context, projectionName string, subscriptions [][]messaging.SubscribeOption) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 
 	projection, exists := m.projections[projectionName]
 	if !exists {
+		m.mu.Unlock()
 		return fmt.Errorf("projection %s not found", projectionName)
 	}
 
 	// Check if already running
 	if _, running := m.running[projectionName]; running {
+		m.mu.Unlock()
 		return fmt.Errorf("projection %s already running", projectionName)
 	}
 
+	// Create cancellable context
+	projCtx, cancel := context.WithCancel(ctx)
+	m.running[projectionName] = cancel
+	m.wg.Add(1)
+
+	alerts := make([]*lagAlert, 0, len(m.lagAlerts[projectionName]))
+	for _, alert := range m.lagAlerts[projectionName] {
+		alerts = append(alerts, &lagAlert{threshold: alert.threshold, cooldown: alert.cooldown, fn: alert.fn})
+	}
+	lagMetrics := m.lagMetrics
+	if _, ok := m.eventStore.(store.LatestPositionReader); !ok {
+		// Lag cannot be measured; only alerts report why
+		lagMetrics = nil
+	}
+	logger := m.logger
+	interval := m.lagCheckInterval
+	if interval <= 0 {
+		interval = defaultLagCheckInterval
+	}
+	m.mu.Unlock()
+
 	// Load checkpoint
 	checkpoint, err := m.checkpointStore.Load(projectionName)
 	if err != nil {
@@ -85,46 +179,98 @@ func (m *ProjectionManager) Start(ctx context.Context, projectionName string) er
 		}
 	}
 
-	// Create cancellable context
-	projCtx, cancel := context.WithCancel(ctx)
-	m.running[projectionName] = cancel
+	// The checkpoint holds the global position of the last event handled.
+	// Partitions share it. Each subscription skips events at or below the
+	// highest position it handled, e.g. redelivered after a pause; an
+	// unpartitioned one starts from the checkpoint, which never exceeds it.
+	var checkpointMu sync.Mutex
+	newHandler := func(handled int64) messaging.EventHandler {
+		return func(event *domain.EventEnvelope) error {
+			if err := m.waitIfPaused(projCtx, projectionName); err != nil {
+				return err
+			}
 
-	// Subscribe to event bus (real-time events)
-	subscription, err := m.eventBus.Subscribe(messaging.EventFilter{}, func(event *domain.EventEnvelope) error {
-		// Process event
-		if err := projection.Handle(projCtx, event); err != nil {
-			return fmt.Errorf("projection %s failed to handle event: %w", projectionName, err)
-		}
+			checkpointMu.Lock()
+			duplicate := event.Position > 0 && event.Position <= handled
+			checkpointMu.Unlock()
+			if duplicate {
+				return nil
+			}
 
-		// Update checkpoint
-		checkpoint.Position++
-		checkpoint.LastEventID = event.Event.ID
-		checkpoint.UpdatedAt = domain.Now()
+			// Process event
+			if err := projection.Handle(projCtx, event); err != nil {
+				err = fmt.Errorf("projection %s failed to handle event: %w", projectionName, err)
+				m.setState(projectionName, ProjectionStatusFailed, err.Error())
+				return err
+			}
+			m.recoverState(projectionName)
 
-		if err := m.checkpointStore.Save(checkpoint); err != nil {
-			return fmt.Errorf("failed to save checkpoint: %w", err)
-		}
+			checkpointMu.Lock()
+			defer checkpointMu.Unlock()
 
-		return nil
-	})
+			// Update checkpoint. Events never persisted have no position
+			// and leave it where it is.
+			handled = max(handled, event.Position)
+			checkpoint.Position = max(checkpoint.Position, event.Position)
+			checkpoint.LastEventID = event.Event.ID
+			checkpoint.UpdatedAt = domain.Now()
 
-	if err != nil {
-		cancel()
-		delete(m.running, projectionName)
-		return fmt.Errorf("failed to subscribe: %w", err)
+			if err := m.saveCheckpoint(checkpoint); err != nil {
+				return fmt.Errorf("failed to save checkpoint: %w", err)
+			}
+
+			return nil
+		}
+	}
+	var handled int64
+	if len(subscriptions) == 1 && messaging.NewSubscribeOptions(subscriptions[0]...).ConsumerGroup == "" {
+		handled = checkpoint.Position
 	}
 
-	// Start projection in background
-	m.wg.Add(1)
+	// Subscribe to event bus (real-time events)
+	var subs []messaging.Subscription
+	for _, opts := range subscriptions {
+		subscription, err := m.eventBus.Subscribe(messaging.EventFilter{}, newHandler(handled), opts...)
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			cancel()
+			m.mu.Lock()
+			delete(m.running, projectionName)
+			m.mu.Unlock()
+			m.wg.Done()
+			m.setState(projectionName, ProjectionStatusFailed, fmt.Sprintf("Failed to subscribe: %v", err))
+			return fmt.Errorf("failed to subscribe: %w", err)
+		}
+		subs = append(subs, subscription)
+	}
+	m.setState(projectionName, ProjectionStatusReady, "Running")
+
+	// Supervise the projection in background
 	go func() {
 		defer m.wg.Done()
+		if len(alerts) > 0 || lagMetrics != nil {
+			m.superviseLag(projCtx, projectionName, alerts, lagMetrics, logger, interval)
+		}
 		<-projCtx.Done()
-		subscription.Unsubscribe()
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
 	}()
 
 	return nil
 }
 
+// saveCheckpoint saves the checkpoint of a running projection, advancing it
+// atomically when the checkpoint store supports it.
+func (m *ProjectionManager) saveCheckpoint(checkpoint *store.ProjectionCheckpoint) error {
+	if advancer, ok := m.checkpointStore.(store.CheckpointAdvancer); ok {
+		return advancer.Advance(checkpoint)
+	}
+	return m.checkpointStore.Save(checkpoint)
+}
+
 // Stop stops a running projection.
 func (m *ProjectionManager) Stop(projectionName string) error {
 	m.mu.Lock()
@@ -137,6 +283,8 @@ func (m *ProjectionManager) Stop(projectionName string) error {
 
 	cancel()
 	delete(m.running, projectionName)
+	delete(m.paused, projectionName)
+	m.setState(projectionName, ProjectionStatusStopped, "Stopped")
 
 	return nil
 }
@@ -146,39 +294,66 @@ func (m *ProjectionManager) Stop(projectionName string) error {
 // - Initial projection build
 // - Recovering from errors
 // - Schema changes in read model
-func (m *ProjectionManager) Rebuild(ctx context.Context, projectionName string) error {
+//
+// An event the projection fails on aborts the rebuild, unless opts choose to
+// skip or quarantine it (see store.OnEventError).
+func (m *ProjectionManager) Rebuild(ctx context.Context, projectionName string, opts ...store.RebuildOption) error {
+	m.setState(projectionName, ProjectionStatusRebuilding, "Rebuilding from event store")
+	processed, err := m.rebuild(ctx, projectionName, opts...)
+	if err != nil {
+		m.setState(projectionName, ProjectionStatusFailed, fmt.Sprintf("Rebuild failed: %v", err))
+		return err
+	}
+	m.setState(projectionName, ProjectionStatusReady, fmt.Sprintf("Rebuild complete - processed %d events", processed))
+	return nil
+}
+
+// rebuild replays the event store into a projection and returns the number of
+// events processed.
+func (m *ProjectionManager) rebuild(ctx context.Context, projectionName string, opts ...store.RebuildOption) (int64, error) {
+	options, err := store.NewRebuildOptions(opts...)
+	if err != nil {
+		return 0, err
+	}
+
 	m.mu.Lock()
 	projection, exists := m.projections[projectionName]
 	if !exists {
 		m.mu.Unlock()
-		return fmt.Errorf("projection %s not found", projectionName)
+		return 0, fmt.Errorf("projection %s not found", projectionName)
 	}
 
 	// Stop if running
 	if cancel, running := m.running[projectionName]; running {
 		cancel()
 		delete(m.running, projectionName)
+		delete(m.paused, projectionName)
 	}
 	m.mu.Unlock()
 
 	// Reset projection
 	if err := projection.Reset(ctx); err != nil {
-		return fmt.Errorf("failed to reset projection: %w", err)
+		return 0, fmt.Errorf("failed to reset projection: %w", err)
 	}
 
 	// Delete checkpoint
 	if err := m.checkpointStore.Delete(projectionName); err != nil {
-		return fmt.Errorf("failed to delete checkpoint: %w", err)
+		return 0, fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	if err := options.Start(ctx, projectionName); err != nil {
+		return 0, err
 	}
 
-	// Replay all events from EventStore
-	position := int64(0)
+	// Replay all events from EventStore, telling handlers to skip side effects
+	ctx = WithReplay(ctx)
+	var from, processed int64
 	batchSize := 1000
+	report := store.RebuildReport{ProjectionName: projectionName}
 
 	for {
-		events, err := m.eventStore.LoadAllEvents(position, batchSize)
+		events, err := m.eventStore.LoadAllEvents(ctx, from, batchSize)
 		if err != nil {
-			return fmt.Errorf("failed to load events: %w", err)
+			return 0, fmt.Errorf("failed to load events: %w", err)
 		}
 
 		if len(events) == 0 {
@@ -188,19 +363,22 @@ func (m *ProjectionManager) Rebuild(ctx context.Context, projectionName string)
 		for _, event := range events {
 			envelope := &domain.EventEnvelope{Event: *event}
 			if err := projection.Handle(ctx, envelope); err != nil {
-				return fmt.Errorf("failed to handle event during rebuild: %w", err)
+				if err := options.HandleEventError(ctx, event, err, &report); err != nil {
+					return 0, err
+				}
 			}
-			position++
+			from = event.Position + 1
+			processed++
 		}
 
 		// Save checkpoint periodically
 		if err := m.checkpointStore.Save(&store.ProjectionCheckpoint{
 			ProjectionName: projectionName,
-			Position:       position,
+			Position:       events[len(events)-1].Position,
 			LastEventID:    events[len(events)-1].ID,
 			UpdatedAt:      domain.Now(),
 		}); err != nil {
-			return fmt.Errorf("failed to save checkpoint: %w", err)
+			return 0, fmt.Errorf("failed to save checkpoint: %w", err)
 		}
 
 		if len(events) < batchSize {
@@ -208,7 +386,9 @@ func (m *ProjectionManager) Rebuild(ctx context.Context, projectionName string)
 		}
 	}
 
-	return nil
+	report.EventsProcessed = processed
+	options.Complete(report)
+	return processed, nil
 }
 
 // StopAll stops all running projections.
@@ -219,6 +399,8 @@ func (m *ProjectionManager) StopAll() {
 	for name, cancel := range m.running {
 		cancel()
 		delete(m.running, name)
+		delete(m.paused, name)
+		m.setState(name, ProjectionStatusStopped, "Stopped")
 	}
 
 	m.wg.Wait()
@@ -228,3 +410,33 @@ func (m *ProjectionManager) StopAll() {
 func (m *ProjectionManager) GetCheckpoint(projectionName string) (*ProjectionCheckpoint, error) {
 	return m.checkpointStore.Load(projectionName)
 }
+
+// SafePruneHorizon returns the global position up to which every consumer
+// has processed the event log: the lowest checkpoint of the registered
+// projections and of any other consumer saving to the same checkpoint store.
+// A registered projection without a checkpoint has processed nothing, so the
+// horizon is then 0. Events at or below the horizon can be archived or pruned
+// without any consumer missing them. A checkpoint that fails to load fails
+// the call rather than risk pruning events still needed.
+func (m *ProjectionManager) SafePruneHorizon(ctx context.Context) (int64, error) {
+	horizon, err := m.checkpointStore.MinPosition(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for name := range m.projections {
+		checkpoint, err := m.checkpointStore.Load(name)
+		if errors.Is(err, store.ErrCheckpointNotFound) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to load checkpoint of %s: %w", name, err)
+		}
+		horizon = min(horizon, checkpoint.Position)
+	}
+
+	return horizon, nil
+}
@@ -0,0 +1,165 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// defaultParallelism is how many projections StartAll and RebuildAll handle at
+// once unless WithParallelism says otherwise.
+const defaultParallelism = 4
+
+// WithParallelism sets how many projections StartAll and RebuildAll handle at
+// once (default 4).
+func (m *ProjectionManager) WithParallelism(n int) *ProjectionManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.parallelism = n
+	return m
+}
+
+// StartAll starts every registered projection that is not running yet, up to
+// WithParallelism at a time. A projection failing to start does not stop the
+// others; the errors of all failed projections are returned joined.
+func (m *ProjectionManager) StartAll(ctx context.Context) error {
+	return m.forAll(ctx, func(name string, running bool) error {
+		if running {
+			return nil
+		}
+		if err := m.Start(ctx, name); err != nil {
+			return fmt.Errorf("failed to start %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// RebuildAll rebuilds every registered projection from the event store, up to
+// WithParallelism at a time, with opts applied to each (see Rebuild). Like
+// Rebuild, it stops running projections; restart them with StartAll. A failed
+// rebuild does not stop the others; the errors of all failed projections are
+// returned joined.
+func (m *ProjectionManager) RebuildAll(ctx context.Context, opts ...store.RebuildOption) error {
+	return m.forAll(ctx, func(name string, running bool) error {
+		if err := m.Rebuild(ctx, name, opts...); err != nil {
+			return fmt.Errorf("failed to rebuild %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// forAll calls fn for every registered projection, in name order, with
+// bounded parallelism, and joins the errors it returns. Projections not yet
+// begun when ctx is done fail with its error.
+func (m *ProjectionManager) forAll(ctx context.Context, fn func(name string, running bool) error) error {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.projections))
+	for name := range m.projections {
+		names = append(names, name)
+	}
+	running := make(map[string]bool, len(m.running))
+	for name := range m.running {
+		running[name] = true
+	}
+	parallelism := m.parallelism
+	m.mu.RUnlock()
+
+	slices.Sort(names)
+	if parallelism <= 0 {
+		parallelism = defaultParallelism
+	}
+
+	errs := make([]error, len(names))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = fmt.Errorf("%s: %w", name, ctx.Err())
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = fn(name, running[name])
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Statuses returns the state of every registered projection by name, with its
+// checkpoint position, e.g. for a health endpoint. The status reflects what
+// the manager last did with the projection: READY while running or after a
+// rebuild, REBUILDING during a rebuild, FAILED after a failed start or rebuild
+// or while events keep failing, PAUSED while paused, and STOPPED when it is not
+// running.
+func (m *ProjectionManager) Statuses() map[string]ProjectionState {
+	m.mu.RLock()
+	names := make([]string, 0, len(m.projections))
+	for name := range m.projections {
+		names = append(names, name)
+	}
+	m.mu.RUnlock()
+
+	statuses := make(map[string]ProjectionState, len(names))
+	m.statesMu.Lock()
+	for _, name := range names {
+		state, ok := m.states[name]
+		if !ok {
+			state = ProjectionState{
+				ProjectionName: name,
+				Status:         ProjectionStatusStopped,
+				Message:        "Not started",
+			}
+		}
+		statuses[name] = state
+	}
+	m.statesMu.Unlock()
+
+	for name, state := range statuses {
+		if checkpoint, err := m.checkpointStore.Load(name); err == nil {
+			state.Position = checkpoint.Position
+			statuses[name] = state
+		}
+	}
+	return statuses
+}
+
+// setState records the status of a projection for Statuses.
+func (m *ProjectionManager) setState(name string, status ProjectionStatus, message string) {
+	m.statesMu.Lock()
+	defer m.statesMu.Unlock()
+
+	if m.states == nil {
+		m.states = make(map[string]ProjectionState)
+	}
+	m.states[name] = ProjectionState{
+		ProjectionName: name,
+		Status:         status,
+		Message:        message,
+		UpdatedAt:      domain.Now(),
+	}
+}
+
+// recoverState marks a running projection that had failed on an event ready
+// again once it handles one.
+func (m *ProjectionManager) recoverState(name string) {
+	m.statesMu.Lock()
+	failed := m.states[name].Status == ProjectionStatusFailed
+	m.statesMu.Unlock()
+
+	if failed {
+		m.setState(name, ProjectionStatusReady, "Running")
+	}
+}
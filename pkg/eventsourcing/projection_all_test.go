@@ -0,0 +1,161 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/messaging"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+// countingProjection counts the events it handles; its Reset fails with
+// resetErr and holds the active counter while it runs.
+type countingProjection struct {
+	name     string
+	handled  atomic.Int64
+	resetErr error
+	active   *atomic.Int32
+	peak     *atomic.Int32
+}
+
+func (p *countingProjection) Name() string { return p.name }
+
+func (p *countingProjection) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
+	p.handled.Add(1)
+	return nil
+}
+
+func (p *countingProjection) Reset(ctx context.Context) error {
+	n := p.active.Add(1)
+	defer p.active.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	p.handled.Store(0)
+	return p.resetErr
+}
+
+// subscriptionBus is an event bus that counts its subscriptions.
+type subscriptionBus struct {
+	mu            sync.Mutex
+	subscriptions int
+}
+
+func (b *subscriptionBus) Publish(events []*domain.Event) error { return nil }
+
+func (b *subscriptionBus) Subscribe(filter messaging.EventFilter, handler messaging.EventHandler, opts ...messaging.SubscribeOption) (messaging.Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscriptions++
+	return capturedSubscription{}, nil
+}
+
+func (b *subscriptionBus) Close() error { return nil }
+
+func TestProjectionManagerAll(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	var events []*domain.Event
+	for i := 1; i <= 3; i++ {
+		events = append(events, &domain.Event{
+			ID: fmt.Sprintf("evt-%d", i), AggregateID: "acc-1", AggregateType: "Account",
+			EventType: "account.v1.Deposited", Version: int64(i), Timestamp: time.Now(), Data: []byte("{}"),
+		})
+	}
+	if err := eventStore.AppendEvents(ctx, "acc-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	bus := &subscriptionBus{}
+	manager := eventsourcing.NewProjectionManager(checkpointStore, eventStore, bus).WithParallelism(2)
+	defer manager.StopAll()
+
+	var active, peak atomic.Int32
+	errBroken := errors.New("read model unavailable")
+	projections := make(map[string]*countingProjection)
+	for i := range 5 {
+		projection := &countingProjection{name: fmt.Sprintf("projection-%d", i), active: &active, peak: &peak}
+		if i == 3 {
+			projection.resetErr = errBroken
+		}
+		projections[projection.name] = projection
+		manager.Register(projection)
+	}
+
+	for name, state := range manager.Statuses() {
+		if state.Status != eventsourcing.ProjectionStatusStopped {
+			t.Errorf("expected %s to be stopped before starting, got %s", name, state.Status)
+		}
+	}
+
+	t.Run("RebuildAll", func(t *testing.T) {
+		err := manager.RebuildAll(ctx)
+		if !errors.Is(err, errBroken) {
+			t.Fatalf("expected the failed rebuild to be returned, got %v", err)
+		}
+		if peak.Load() != 2 {
+			t.Errorf("expected at most 2 concurrent rebuilds, got %d", peak.Load())
+		}
+
+		statuses := manager.Statuses()
+		for name, projection := range projections {
+			state := statuses[name]
+			if projection.resetErr != nil {
+				if state.Status != eventsourcing.ProjectionStatusFailed {
+					t.Errorf("expected %s to have failed, got %+v", name, state)
+				}
+				continue
+			}
+			if projection.handled.Load() != 3 {
+				t.Errorf("expected %s to rebuild from 3 events despite the failure, got %d", name, projection.handled.Load())
+			}
+			if state.Status != eventsourcing.ProjectionStatusReady || state.Position != 3 {
+				t.Errorf("expected %s ready at position 3, got %+v", name, state)
+			}
+		}
+	})
+
+	t.Run("StartAll", func(t *testing.T) {
+		if err := manager.StartAll(ctx); err != nil {
+			t.Fatalf("failed to start projections: %v", err)
+		}
+		if err := manager.StartAll(ctx); err != nil {
+			t.Fatalf("expected starting running projections to be a no-op, got %v", err)
+		}
+		if bus.subscriptions != 5 {
+			t.Errorf("expected one subscription per projection, got %d", bus.subscriptions)
+		}
+		for name, state := range manager.Statuses() {
+			if state.Status != eventsourcing.ProjectionStatusReady {
+				t.Errorf("expected %s to be running, got %+v", name, state)
+			}
+		}
+
+		if err := manager.Stop("projection-0"); err != nil {
+			t.Fatalf("failed to stop projection: %v", err)
+		}
+		if state := manager.Statuses()["projection-0"]; state.Status != eventsourcing.ProjectionStatusStopped {
+			t.Errorf("expected a stopped projection to report STOPPED, got %+v", state)
+		}
+	})
+}
@@ -2,6 +2,8 @@ package eventsourcing
 
 import (
 	"context"
+	"log/slog"
+	"time"
 
 	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/store"
@@ -17,6 +19,10 @@ type GenericProjectionBuilder struct {
 	name      string
 	handlers  map[string]func(context.Context, *domain.EventEnvelope) error
 	resetFunc func(context.Context) error
+	limits    store.HandlerLimits
+	ordering  []OrderingOption
+	ordered   bool
+	policy    OrderingPolicy
 }
 
 // NewProjectionBuilder creates a new generic projection builder.
@@ -45,7 +51,37 @@ func NewProjectionBuilder(name string) *GenericProjectionBuilder {
 //	    return nil
 //	}))
 func (b *GenericProjectionBuilder) On(registration EventHandlerRegistration) *GenericProjectionBuilder {
-	b.handlers[registration.EventType] = registration.Handler
+	b.handlers[domain.CanonicalEventType(registration.EventType)] = registration.Handler
+	return b
+}
+
+// WithHandlerTimeout cancels a handler's context after d; Handle then returns
+// store.ErrHandlerTimeout so the event is retried.
+func (b *GenericProjectionBuilder) WithHandlerTimeout(d time.Duration) *GenericProjectionBuilder {
+	b.limits.Timeout = d
+	return b
+}
+
+// WithSlowHandlerWarning logs handlers that take at least threshold and
+// reports them to metrics (may be nil).
+func (b *GenericProjectionBuilder) WithSlowHandlerWarning(threshold time.Duration, metrics store.ProjectionMetrics) *GenericProjectionBuilder {
+	b.limits.SlowThreshold = threshold
+	b.limits.Metrics = metrics
+	return b
+}
+
+// WithLogger logs slow-handler warnings to logger (default slog.Default()).
+func (b *GenericProjectionBuilder) WithLogger(logger *slog.Logger) *GenericProjectionBuilder {
+	b.limits.Logger = logger
+	return b
+}
+
+// WithOrderingPolicy guards the projection against events delivered out of
+// version order (see NewOrderedProjection).
+func (b *GenericProjectionBuilder) WithOrderingPolicy(policy OrderingPolicy, opts ...OrderingOption) *GenericProjectionBuilder {
+	b.ordered = true
+	b.policy = policy
+	b.ordering = opts
 	return b
 }
 
@@ -57,11 +93,16 @@ func (b *GenericProjectionBuilder) OnReset(resetFunc func(context.Context) error
 
 // Build creates the final Projection implementation.
 func (b *GenericProjectionBuilder) Build() Projection {
-	return &GenericProjection{
+	projection := &GenericProjection{
 		name:      b.name,
 		handlers:  b.handlers,
 		resetFunc: b.resetFunc,
+		limits:    b.limits,
+	}
+	if b.ordered {
+		return NewOrderedProjection(projection, b.policy, b.ordering...)
 	}
+	return projection
 }
 
 // GenericProjection implements Projection with support for multiple domains.
@@ -69,6 +110,7 @@ type GenericProjection struct {
 	name      string
 	handlers  map[string]func(context.Context, *domain.EventEnvelope) error
 	resetFunc func(context.Context) error
+	limits    store.HandlerLimits
 }
 
 // Name returns the projection name.
@@ -78,12 +120,14 @@ func (p *GenericProjection) Name() string {
 
 // Handle dispatches events to registered typed handlers.
 func (p *GenericProjection) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
-	handler, exists := p.handlers[envelope.Event.EventType]
+	handler, exists := p.handlers[domain.CanonicalEventType(envelope.Event.EventType)]
 	if !exists {
 		// No handler registered for this event type - skip it
 		return nil
 	}
-	return handler(ctx, envelope)
+	return p.limits.Run(ctx, p.name, envelope.Event.EventType, func(ctx context.Context) error {
+		return handler(ctx, envelope)
+	})
 }
 
 // Reset resets the projection state.
@@ -0,0 +1,262 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// defaultLagCheckInterval is how often a running projection's lag is checked
+// against its lag alerts, unless set with WithLagCheckInterval.
+const defaultLagCheckInterval = 5 * time.Second
+
+// ProjectionLag is how far a projection is behind the event store, in global
+// positions and in time.
+type ProjectionLag struct {
+	ProjectionName string
+	Checkpoint     int64 // Global position of the last event the projection handled
+	Head           int64 // Global position of the latest event in the store
+	Lag            int64 // Head - Checkpoint
+
+	// Behind is how long the oldest event the projection has not handled
+	// has been in the store; 0 when it is caught up.
+	Behind time.Duration
+}
+
+// ProjectionLagMetrics receives the lag of running projections.
+// *observability.Metrics implements it, as the eventsourcing.projection.lag
+// and eventsourcing.projection.lag.seconds gauges.
+type ProjectionLagMetrics interface {
+	RecordProjectionLagEvents(ctx context.Context, projectionName string, lag int64)
+	RecordProjectionLagSeconds(ctx context.Context, projectionName string, seconds float64)
+}
+
+// otelLagMetrics records projection lag with the global OpenTelemetry meter
+// provider. It is what a ProjectionManager uses unless WithLagMetrics is set.
+type otelLagMetrics struct {
+	lag        metric.Int64Gauge
+	lagSeconds metric.Float64Gauge
+}
+
+// newOTelLagMetrics returns the default lag metrics, or nil if the gauges
+// cannot be created.
+func newOTelLagMetrics() ProjectionLagMetrics {
+	meter := otel.Meter("github.com/plaenen/eventstore/pkg/eventsourcing")
+	lag, err := meter.Int64Gauge(
+		"eventsourcing.projection.lag",
+		metric.WithDescription("Events a projection has yet to process"),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		return nil
+	}
+	lagSeconds, err := meter.Float64Gauge(
+		"eventsourcing.projection.lag.seconds",
+		metric.WithDescription("Projection lag in seconds behind event stream"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil
+	}
+	return &otelLagMetrics{lag: lag, lagSeconds: lagSeconds}
+}
+
+func (m *otelLagMetrics) RecordProjectionLagEvents(ctx context.Context, projectionName string, lag int64) {
+	m.lag.Record(ctx, lag, metric.WithAttributes(attribute.String("projection", projectionName)))
+}
+
+func (m *otelLagMetrics) RecordProjectionLagSeconds(ctx context.Context, projectionName string, seconds float64) {
+	m.lagSeconds.Record(ctx, seconds, metric.WithAttributes(attribute.String("projection", projectionName)))
+}
+
+// LagAlert reports that a projection's lag stayed above a threshold, or that
+// it recovered.
+type LagAlert struct {
+	ProjectionLag
+	Threshold int64
+
+	// Since is when the lag first exceeded the threshold.
+	Since time.Time
+
+	// Recovered is set on the alert sent when the lag dropped back to or
+	// below the threshold after an alert.
+	Recovered bool
+}
+
+// lagAlert is a lag alert registered with WithLagAlert. Every run of the
+// projection evaluates its own copy, starting from a clean state.
+type lagAlert struct {
+	threshold int64
+	cooldown  time.Duration
+	fn        func(LagAlert)
+
+	since    time.Time
+	alerting bool
+}
+
+// WithLagAlert calls fn when the lag of projection name stays above threshold
+// events for at least cooldown, and again with Recovered set once it drops
+// back to or below threshold. A projection that stays behind is reported once,
+// not on every check, so fn can page an operator directly.
+//
+// Lag is checked every few seconds (see WithLagCheckInterval) while the
+// projection runs, and needs an event store implementing
+// store.LatestPositionReader.
+// Register alerts before starting the projection; several alerts (say, a
+// warning and a critical threshold) can be registered for one projection.
+//
+// Example:
+//
+//	manager.WithLagAlert("account-balance", 10_000, 5*time.Minute, func(alert eventsourcing.LagAlert) {
+//	    if alert.Recovered {
+//	        pager.Resolve("projection-lag/" + alert.ProjectionName)
+//	        return
+//	    }
+//	    pager.Trigger("projection-lag/"+alert.ProjectionName, "%d events behind (checkpoint %d, head %d)",
+//	        alert.Lag, alert.Checkpoint, alert.Head)
+//	})
+func (m *ProjectionManager) WithLagAlert(name string, threshold int64, cooldown time.Duration, fn func(LagAlert)) *ProjectionManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.lagAlerts == nil {
+		m.lagAlerts = make(map[string][]*lagAlert)
+	}
+	m.lagAlerts[name] = append(m.lagAlerts[name], &lagAlert{threshold: threshold, cooldown: cooldown, fn: fn})
+	return m
+}
+
+// WithLagMetrics records the lag of every running projection in metrics at
+// each lag check (see WithLagCheckInterval), e.g. with telemetry.Metrics.
+// Configure it before starting projections; nil records nothing.
+//
+// By default the lag is recorded as the eventsourcing.projection.lag gauge,
+// tagged with the projection name, of the global OpenTelemetry meter
+// provider, which a configured Telemetry sets.
+func (m *ProjectionManager) WithLagMetrics(metrics ProjectionLagMetrics) *ProjectionManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lagMetrics = metrics
+	return m
+}
+
+// WithLogger logs failed lag checks of running projections to logger
+// (default slog.Default()). Configure it before starting projections.
+func (m *ProjectionManager) WithLogger(logger *slog.Logger) *ProjectionManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+	m.logger = logger
+	return m
+}
+
+// WithLagCheckInterval sets how often lag alerts are evaluated (default 5s).
+func (m *ProjectionManager) WithLagCheckInterval(d time.Duration) *ProjectionManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lagCheckInterval = d
+	return m
+}
+
+// Lag returns how far projection name is behind the event store: the global
+// position of the latest event in the store minus that of the last event the
+// projection handled, and how long ago the oldest event it has yet to handle
+// was recorded. A projection without a checkpoint has processed nothing. The
+// event store must implement store.LatestPositionReader.
+func (m *ProjectionManager) Lag(name string) (ProjectionLag, error) {
+	reader, ok := m.eventStore.(store.LatestPositionReader)
+	if !ok {
+		return ProjectionLag{}, fmt.Errorf("cannot compute lag of %s: event store cannot report its latest position", name)
+	}
+	head, err := reader.LatestPosition(context.Background())
+	if err != nil {
+		return ProjectionLag{}, fmt.Errorf("failed to read latest position: %w", err)
+	}
+
+	lag := ProjectionLag{ProjectionName: name, Head: head}
+	if checkpoint, err := m.checkpointStore.Load(name); err == nil {
+		lag.Checkpoint = checkpoint.Position
+	}
+	lag.Lag = max(lag.Head-lag.Checkpoint, 0)
+
+	if lag.Lag > 0 {
+		next, err := m.eventStore.LoadAllEvents(context.Background(), lag.Checkpoint+1, 1)
+		if err != nil {
+			return ProjectionLag{}, fmt.Errorf("failed to load oldest unhandled event: %w", err)
+		}
+		if len(next) == 1 {
+			lag.Behind = max(domain.Now().Sub(next[0].Timestamp), 0)
+		}
+	}
+	return lag, nil
+}
+
+// GetLag returns how far projection name is behind the event store: the
+// latest store position minus its checkpoint position. See Lag.
+func (m *ProjectionManager) GetLag(name string) (int64, error) {
+	lag, err := m.Lag(name)
+	if err != nil {
+		return 0, err
+	}
+	return lag.Lag, nil
+}
+
+// superviseLag evaluates the lag alerts of a running projection and records
+// its lag in metrics (may be nil) until ctx is done. Failed checks are logged
+// to logger.
+func (m *ProjectionManager) superviseLag(ctx context.Context, name string, alerts []*lagAlert, metrics ProjectionLagMetrics, logger *slog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			lag, err := m.Lag(name)
+			if err != nil {
+				logger.Error("lag check failed", "projection", name, "error", err)
+				continue
+			}
+			if metrics != nil {
+				metrics.RecordProjectionLagEvents(ctx, name, lag.Lag)
+				metrics.RecordProjectionLagSeconds(ctx, name, lag.Behind.Seconds())
+			}
+			for _, alert := range alerts {
+				alert.check(lag, now)
+			}
+		}
+	}
+}
+
+// check updates the alert state with a lag measured at now, calling the
+// callback on a transition.
+func (a *lagAlert) check(lag ProjectionLag, now time.Time) {
+	if lag.Lag <= a.threshold {
+		if a.alerting {
+			a.fn(LagAlert{ProjectionLag: lag, Threshold: a.threshold, Since: a.since, Recovered: true})
+		}
+		a.since, a.alerting = time.Time{}, false
+		return
+	}
+
+	if a.since.IsZero() {
+		a.since = now
+	}
+	if !a.alerting && now.Sub(a.since) >= a.cooldown {
+		a.alerting = true
+		a.fn(LagAlert{ProjectionLag: lag, Threshold: a.threshold, Since: a.since})
+	}
+}
@@ -0,0 +1,396 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/messaging"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// capturingBus is an event bus whose subscription handler the test calls.
+type capturingBus struct {
+	handler messaging.EventHandler
+}
+
+func (b *capturingBus) Publish(events []*domain.Event) error { return nil }
+
+func (b *capturingBus) Subscribe(filter messaging.EventFilter, handler messaging.EventHandler, opts ...messaging.SubscribeOption) (messaging.Subscription, error) {
+	b.handler = handler
+	return capturedSubscription{}, nil
+}
+
+func (b *capturingBus) Close() error { return nil }
+
+type capturedSubscription struct{}
+
+func (capturedSubscription) Unsubscribe() error { return nil }
+
+func TestProjectionLagAlert(t *testing.T) {
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	var events []*domain.Event
+	for i := 1; i <= 10; i++ {
+		events = append(events, &domain.Event{
+			ID:            fmt.Sprintf("evt-%02d", i),
+			AggregateID:   "acc-1",
+			AggregateType: "Account",
+			EventType:     "account.v1.Deposited",
+			Version:       int64(i),
+			Timestamp:     time.Now(),
+			Data:          []byte("{}"),
+		})
+	}
+	if err := eventStore.AppendEvents(context.Background(), "acc-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	bus := &capturingBus{}
+	alerts := make(chan eventsourcing.LagAlert, 4)
+	manager := eventsourcing.NewProjectionManager(checkpointStore, eventStore, bus).
+		WithLagCheckInterval(5*time.Millisecond).
+		WithLagAlert("recorder", 5, 20*time.Millisecond, func(alert eventsourcing.LagAlert) { alerts <- alert }).
+		WithLagAlert("recorder", 100, 0, func(alert eventsourcing.LagAlert) {
+			t.Errorf("unexpected alert below threshold: %+v", alert)
+		})
+	manager.Register(&versionRecorder{applied: make(map[string][]int64)})
+
+	lag, err := manager.Lag("recorder")
+	if err != nil {
+		t.Fatalf("failed to compute lag: %v", err)
+	}
+	if lag.Lag != 10 || lag.Checkpoint != 0 || lag.Head != 10 {
+		t.Errorf("unexpected lag before start: %+v", lag)
+	}
+
+	start := time.Now()
+	if err := manager.Start(context.Background(), "recorder"); err != nil {
+		t.Fatalf("failed to start projection: %v", err)
+	}
+	defer manager.StopAll()
+
+	receive := func() eventsourcing.LagAlert {
+		t.Helper()
+		select {
+		case alert := <-alerts:
+			return alert
+		case <-time.After(5 * time.Second):
+			t.Fatal("no lag alert")
+			return eventsourcing.LagAlert{}
+		}
+	}
+
+	alert := receive()
+	if alert.Recovered || alert.ProjectionName != "recorder" || alert.Lag != 10 ||
+		alert.Checkpoint != 0 || alert.Head != 10 || alert.Threshold != 5 {
+		t.Errorf("unexpected alert: %+v", alert)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("expected the lag to be sustained for the cooldown, alerted after %s", waited)
+	}
+
+	// Catching up below the threshold recovers the alert
+	for _, event := range events[:6] {
+		if err := bus.handler(&domain.EventEnvelope{Event: *event}); err != nil {
+			t.Fatalf("failed to handle event: %v", err)
+		}
+	}
+	recovered := receive()
+	if !recovered.Recovered || recovered.Lag != 4 || recovered.Checkpoint != 6 || !recovered.Since.Equal(alert.Since) {
+		t.Errorf("unexpected recovery: %+v", recovered)
+	}
+
+	select {
+	case alert := <-alerts:
+		t.Errorf("expected no further alerts, got %+v", alert)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestProjectionLagCheckLogged(t *testing.T) {
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	// Hiding LatestPosition makes every lag check fail
+	var logs lockedBuffer
+	manager := eventsourcing.NewProjectionManager(checkpointStore, struct{ store.EventStore }{eventStore}, &capturingBus{}).
+		WithLagCheckInterval(5*time.Millisecond).
+		WithLagAlert("recorder", 5, 0, func(alert eventsourcing.LagAlert) {}).
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	manager.Register(&versionRecorder{applied: make(map[string][]int64)})
+
+	if err := manager.Start(context.Background(), "recorder"); err != nil {
+		t.Fatalf("failed to start projection: %v", err)
+	}
+	defer manager.StopAll()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(logs.String(), "lag check failed") {
+		if time.Now().After(deadline) {
+			t.Fatal("failed lag check was not logged")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	for _, want := range []string{"level=ERROR", "projection=recorder", "error="} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected the error to contain %q, got %q", want, logs.String())
+		}
+	}
+}
+
+// lagRecorder records the lag reported for each projection.
+type lagRecorder struct {
+	lags chan int64
+}
+
+func (r *lagRecorder) RecordProjectionLagEvents(ctx context.Context, projectionName string, lag int64) {
+	select {
+	case r.lags <- lag:
+	default:
+	}
+}
+
+func (r *lagRecorder) RecordProjectionLagSeconds(ctx context.Context, projectionName string, seconds float64) {
+}
+
+func TestProjectionLagMetrics(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	var events []*domain.Event
+	for i := 1; i <= 8; i++ {
+		events = append(events, &domain.Event{
+			ID: fmt.Sprintf("evt-%02d", i), AggregateID: "acc-1", AggregateType: "Account",
+			EventType: "account.v1.Deposited", Version: int64(i), Timestamp: time.Now(), Data: []byte("{}"),
+		})
+	}
+	if err := eventStore.AppendEvents(ctx, "acc-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	bus := &capturingBus{}
+	metrics := &lagRecorder{lags: make(chan int64, 1)}
+	manager := eventsourcing.NewProjectionManager(checkpointStore, eventStore, bus).
+		WithLagCheckInterval(5 * time.Millisecond).
+		WithLagMetrics(metrics)
+	manager.Register(&versionRecorder{applied: make(map[string][]int64)})
+
+	if err := manager.Start(ctx, "recorder"); err != nil {
+		t.Fatalf("failed to start projection: %v", err)
+	}
+	defer manager.StopAll()
+
+	for _, event := range events[:3] {
+		if err := bus.handler(&domain.EventEnvelope{Event: *event}); err != nil {
+			t.Fatalf("failed to handle event: %v", err)
+		}
+	}
+	lag, err := manager.GetLag("recorder")
+	if err != nil {
+		t.Fatalf("failed to get lag: %v", err)
+	}
+	if lag != 5 {
+		t.Errorf("expected a lag of 5 events, got %d", lag)
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case recorded := <-metrics.lags:
+			if recorded == 5 {
+				return
+			}
+		case <-deadline:
+			t.Fatal("expected the lag to be recorded in the metrics")
+		}
+	}
+}
+
+func TestProjectionLagDefaultGauge(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	previous := otel.GetMeterProvider()
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	defer otel.SetMeterProvider(previous)
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+	if err := eventStore.AppendEvents(ctx, "acc-1", 0, []*domain.Event{{
+		ID: "evt-01", AggregateID: "acc-1", AggregateType: "Account",
+		EventType: "account.v1.Deposited", Version: 1, Timestamp: time.Now(), Data: []byte("{}"),
+	}}); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	// No WithLagMetrics: the gauge goes to the global meter provider
+	manager := eventsourcing.NewProjectionManager(checkpointStore, eventStore, &capturingBus{}).
+		WithLagCheckInterval(5 * time.Millisecond)
+	manager.Register(&versionRecorder{applied: make(map[string][]int64)})
+	if err := manager.Start(ctx, "recorder"); err != nil {
+		t.Fatalf("failed to start projection: %v", err)
+	}
+	defer manager.StopAll()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var data metricdata.ResourceMetrics
+		if err := reader.Collect(ctx, &data); err != nil {
+			t.Fatalf("failed to collect metrics: %v", err)
+		}
+		for _, scope := range data.ScopeMetrics {
+			for _, m := range scope.Metrics {
+				if gauge, ok := m.Data.(metricdata.Gauge[int64]); ok && m.Name == "eventsourcing.projection.lag" &&
+					len(gauge.DataPoints) == 1 && gauge.DataPoints[0].Value == 1 {
+					return
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the lag gauge to be recorded by default")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestProjectionLagBehind(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	now := time.Now()
+	var events []*domain.Event
+	for i, age := range []time.Duration{2 * time.Hour, time.Hour} {
+		events = append(events, &domain.Event{
+			ID: fmt.Sprintf("evt-%02d", i+1), AggregateID: "acc-1", AggregateType: "Account",
+			EventType: "account.v1.Deposited", Version: int64(i + 1), Timestamp: now.Add(-age), Data: []byte("{}"),
+		})
+	}
+	if err := eventStore.AppendEvents(ctx, "acc-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	bus := &capturingBus{}
+	manager := eventsourcing.NewProjectionManager(checkpointStore, eventStore, bus).WithLagMetrics(nil)
+	manager.Register(&versionRecorder{applied: make(map[string][]int64)})
+	if err := manager.Start(ctx, "recorder"); err != nil {
+		t.Fatalf("failed to start projection: %v", err)
+	}
+	defer manager.StopAll()
+
+	// Behind is measured from the oldest event not handled yet
+	lag, err := manager.Lag("recorder")
+	if err != nil {
+		t.Fatalf("failed to compute lag: %v", err)
+	}
+	if lag.Lag != 2 || lag.Behind < 2*time.Hour-time.Minute || lag.Behind > 2*time.Hour+time.Minute {
+		t.Errorf("expected 2 events and about 2h behind, got %+v", lag)
+	}
+
+	if err := bus.handler(&domain.EventEnvelope{Event: *events[0]}); err != nil {
+		t.Fatalf("failed to handle event: %v", err)
+	}
+	if lag, _ := manager.Lag("recorder"); lag.Lag != 1 || lag.Behind < time.Hour-time.Minute || lag.Behind > time.Hour+time.Minute {
+		t.Errorf("expected 1 event and about 1h behind, got %+v", lag)
+	}
+
+	if err := bus.handler(&domain.EventEnvelope{Event: *events[1]}); err != nil {
+		t.Fatalf("failed to handle event: %v", err)
+	}
+	if lag, _ := manager.Lag("recorder"); lag.Lag != 0 || lag.Behind != 0 {
+		t.Errorf("expected no lag once caught up, got %+v", lag)
+	}
+}
+
+func TestProjectionGroupCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	// Two instances of one projection split the events of the group
+	var buses []*capturingBus
+	for range 2 {
+		bus := &capturingBus{}
+		manager := eventsourcing.NewProjectionManager(checkpointStore, eventStore, bus).WithLagMetrics(nil)
+		manager.Register(&versionRecorder{applied: make(map[string][]int64)})
+		if err := manager.StartInGroup(ctx, "recorder"); err != nil {
+			t.Fatalf("failed to start projection: %v", err)
+		}
+		defer manager.StopAll()
+		buses = append(buses, bus)
+	}
+
+	deliver := func(bus *capturingBus, position int64) {
+		t.Helper()
+		if err := bus.handler(&domain.EventEnvelope{Event: domain.Event{
+			ID: fmt.Sprintf("evt-%02d", position), AggregateID: "acc-1", EventType: "account.v1.Deposited",
+			Version: position, Position: position,
+		}}); err != nil {
+			t.Fatalf("failed to handle event: %v", err)
+		}
+	}
+	deliver(buses[0], 5)
+	deliver(buses[1], 3)
+
+	// The instance behind does not move the shared checkpoint back
+	checkpoint, err := checkpointStore.Load("recorder")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if checkpoint.Position != 5 {
+		t.Errorf("expected the checkpoint to stay at 5, got %d", checkpoint.Position)
+	}
+}
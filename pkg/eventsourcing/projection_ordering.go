@@ -0,0 +1,399 @@
+package eventsourcing
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// ErrReorderBufferFull is returned by OrderedProjection.Handle when an
+// aggregate already holds the maximum number of out-of-order events. The event
+// bus redelivers the event later.
+var ErrReorderBufferFull = errors.New("reorder buffer full")
+
+// OrderingPolicy selects how an OrderedProjection treats events that arrive
+// out of version order.
+type OrderingPolicy int
+
+const (
+	// BestEffort applies events as they arrive but drops any event at or
+	// below the aggregate's last applied version, so a late redelivery never
+	// rolls the read model back. Events skipped over by a gap are lost.
+	BestEffort OrderingPolicy = iota
+
+	// StrictPerAggregate applies each aggregate's events in version order.
+	// An event ahead of the next expected version is held until its
+	// predecessors arrive; if they do not arrive within the gap timeout, the
+	// gap is reported and the held events are applied anyway.
+	StrictPerAggregate
+)
+
+// String returns the policy name.
+func (p OrderingPolicy) String() string {
+	switch p {
+	case BestEffort:
+		return "BestEffort"
+	case StrictPerAggregate:
+		return "StrictPerAggregate"
+	default:
+		return fmt.Sprintf("OrderingPolicy(%d)", int(p))
+	}
+}
+
+// OrderingGap describes missing versions of an aggregate that a
+// StrictPerAggregate projection gave up waiting for.
+type OrderingGap struct {
+	ProjectionName string
+	AggregateID    string
+	Expected       int64         // First missing version
+	Next           int64         // First held version, applied next
+	Held           int           // Number of held events
+	Waited         time.Duration // Time since the gap was detected
+}
+
+// OrderingOption configures an OrderedProjection.
+type OrderingOption func(*orderingConfig)
+
+type orderingConfig struct {
+	bufferSize    int
+	maxTracked    int
+	gapTimeout    time.Duration
+	onGap         func(OrderingGap)
+	logger        *slog.Logger
+	versionLookup func(ctx context.Context, aggregateID string) (int64, error)
+}
+
+// WithReorderBuffer holds at most size out-of-order events per aggregate
+// (default 100). Further events are rejected with ErrReorderBufferFull.
+func WithReorderBuffer(size int) OrderingOption {
+	return func(c *orderingConfig) {
+		c.bufferSize = max(size, 1)
+	}
+}
+
+// WithTrackedAggregates tracks the version of at most n aggregates (default
+// 10000). Beyond that the least recently seen aggregates that hold no events
+// are forgotten; their next event is treated like the first one after a
+// restart (see WithVersionLookup).
+func WithTrackedAggregates(n int) OrderingOption {
+	return func(c *orderingConfig) {
+		c.maxTracked = max(n, 1)
+	}
+}
+
+// WithGapTimeout waits at most d for missing versions (default 30s).
+func WithGapTimeout(d time.Duration) OrderingOption {
+	return func(c *orderingConfig) {
+		c.gapTimeout = d
+	}
+}
+
+// WithGapHandler calls onGap when a gap times out, after the held events were
+// applied, e.g. to raise an alert. By default gaps are logged.
+func WithGapHandler(onGap func(OrderingGap)) OrderingOption {
+	return func(c *orderingConfig) {
+		c.onGap = onGap
+	}
+}
+
+// WithOrderingLogger logs gaps and failures to apply held events to logger
+// (default slog.Default()).
+func WithOrderingLogger(logger *slog.Logger) OrderingOption {
+	return func(c *orderingConfig) {
+		c.logger = logger
+	}
+}
+
+// WithVersionLookup seeds the last applied version of an aggregate the
+// projection has not seen since it started, typically from a version column
+// of the read model. Without it, the first event received for an aggregate
+// is applied as is, so ordering is only guaranteed from there on.
+func WithVersionLookup(lookup func(ctx context.Context, aggregateID string) (int64, error)) OrderingOption {
+	return func(c *orderingConfig) {
+		c.versionLookup = lookup
+	}
+}
+
+// OrderedProjection guards a projection fed by a bus that may deliver an
+// aggregate's events out of order, such as after a redelivery. It tracks the
+// last applied version of every aggregate and applies the OrderingPolicy to
+// events that are not its successor.
+//
+// Tracking is in memory: held events are acknowledged to the bus, so they are
+// lost if the process stops before they are applied, and a restarted
+// projection relies on WithVersionLookup to know where each aggregate was.
+// The number of tracked aggregates is bounded by WithTrackedAggregates.
+// Events without an aggregate ID or version pass through unchecked.
+type OrderedProjection struct {
+	projection Projection
+	policy     OrderingPolicy
+	config     orderingConfig
+
+	mu         sync.Mutex
+	aggregates map[string]*aggregateOrder
+	order      *list.List // Front = most recently seen
+}
+
+// aggregateOrder is the ordering state of one aggregate.
+type aggregateOrder struct {
+	mu      sync.Mutex
+	applied int64 // Last applied version, -1 until known
+	held    map[int64]*domain.EventEnvelope
+	since   time.Time
+	timer   *time.Timer
+
+	// Guarded by OrderedProjection.mu
+	elem  *list.Element
+	users int // Handle calls using the state; it is not evicted while > 0
+}
+
+// NewOrderedProjection wraps projection with an ordering guard.
+//
+// Example:
+//
+//	projection := eventsourcing.NewOrderedProjection(balances, eventsourcing.StrictPerAggregate,
+//	    eventsourcing.WithGapTimeout(time.Minute),
+//	    eventsourcing.WithGapHandler(func(gap eventsourcing.OrderingGap) {
+//	        alerts.Send("missing events of %s from v%d", gap.AggregateID, gap.Expected)
+//	    }))
+func NewOrderedProjection(projection Projection, policy OrderingPolicy, opts ...OrderingOption) *OrderedProjection {
+	config := orderingConfig{
+		bufferSize: 100,
+		maxTracked: 10000,
+		gapTimeout: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.logger == nil {
+		config.logger = slog.Default()
+	}
+	if config.onGap == nil {
+		logger := config.logger
+		config.onGap = func(gap OrderingGap) {
+			logger.Warn("skipped missing versions",
+				"projection", gap.ProjectionName,
+				"aggregate_id", gap.AggregateID,
+				"expected", gap.Expected,
+				"next", gap.Next,
+				"held", gap.Held,
+				"waited", gap.Waited.Round(time.Millisecond))
+		}
+	}
+	return &OrderedProjection{
+		projection: projection,
+		policy:     policy,
+		config:     config,
+		aggregates: make(map[string]*aggregateOrder),
+		order:      list.New(),
+	}
+}
+
+// Name returns the name of the wrapped projection.
+func (p *OrderedProjection) Name() string {
+	return p.projection.Name()
+}
+
+// Handle applies, holds or drops the event according to the ordering policy.
+func (p *OrderedProjection) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
+	aggregateID, version := envelope.Event.AggregateID, envelope.Event.Version
+	if aggregateID == "" || version <= 0 {
+		return p.projection.Handle(ctx, envelope)
+	}
+
+	state := p.aggregate(aggregateID)
+	defer p.release(state)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.applied < 0 {
+		if p.config.versionLookup == nil {
+			state.applied = version - 1
+		} else {
+			applied, err := p.config.versionLookup(ctx, aggregateID)
+			if err != nil {
+				return fmt.Errorf("failed to look up version of %s: %w", aggregateID, err)
+			}
+			state.applied = applied
+		}
+	}
+
+	switch {
+	case version <= state.applied:
+		// Duplicate or late redelivery
+		return nil
+	case version == state.applied+1 || p.policy == BestEffort:
+		if err := p.apply(ctx, state, envelope); err != nil {
+			return err
+		}
+		return p.drain(ctx, state)
+	}
+
+	if _, held := state.held[version]; held {
+		return nil
+	}
+	if len(state.held) >= p.config.bufferSize {
+		return fmt.Errorf("%w: %s holds %d events waiting for version %d",
+			ErrReorderBufferFull, aggregateID, len(state.held), state.applied+1)
+	}
+	state.held[version] = envelope
+	if state.timer == nil {
+		state.since = time.Now()
+		p.armTimer(context.WithoutCancel(ctx), aggregateID, state)
+	}
+	return nil
+}
+
+// Reset drops all ordering state and resets the wrapped projection.
+func (p *OrderedProjection) Reset(ctx context.Context) error {
+	p.mu.Lock()
+	for _, state := range p.aggregates {
+		state.mu.Lock()
+		if state.timer != nil {
+			state.timer.Stop()
+			state.timer = nil
+		}
+		state.mu.Unlock()
+	}
+	p.aggregates = make(map[string]*aggregateOrder)
+	p.order.Init()
+	p.mu.Unlock()
+
+	return p.projection.Reset(ctx)
+}
+
+// Held returns the number of events held for aggregateID.
+func (p *OrderedProjection) Held(aggregateID string) int {
+	p.mu.Lock()
+	state, ok := p.aggregates[aggregateID]
+	p.mu.Unlock()
+	if !ok {
+		return 0
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return len(state.held)
+}
+
+// aggregate returns the ordering state of aggregateID for a Handle call,
+// which must hand it back with release.
+func (p *OrderedProjection) aggregate(aggregateID string) *aggregateOrder {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, ok := p.aggregates[aggregateID]
+	if ok {
+		p.order.MoveToFront(state.elem)
+	} else {
+		state = &aggregateOrder{applied: -1, held: make(map[int64]*domain.EventEnvelope)}
+		state.elem = p.order.PushFront(aggregateID)
+		p.aggregates[aggregateID] = state
+	}
+	state.users++
+	p.evict()
+	return state
+}
+
+// release ends a Handle call's use of state.
+func (p *OrderedProjection) release(state *aggregateOrder) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	state.users--
+}
+
+// evict forgets the least recently seen aggregates beyond the tracking limit.
+// Aggregates in use or holding events are kept. Must hold p.mu.
+func (p *OrderedProjection) evict() {
+	for elem := p.order.Back(); elem != nil && p.order.Len() > p.config.maxTracked; {
+		prev := elem.Prev()
+		aggregateID := elem.Value.(string)
+		state := p.aggregates[aggregateID]
+		if state.users == 0 && state.mu.TryLock() {
+			if len(state.held) == 0 {
+				p.order.Remove(elem)
+				delete(p.aggregates, aggregateID)
+			}
+			state.mu.Unlock()
+		}
+		elem = prev
+	}
+}
+
+// apply hands an event to the wrapped projection. The caller holds state.mu.
+func (p *OrderedProjection) apply(ctx context.Context, state *aggregateOrder, envelope *domain.EventEnvelope) error {
+	if err := p.projection.Handle(ctx, envelope); err != nil {
+		return err
+	}
+	state.applied = envelope.Event.Version
+	return nil
+}
+
+// drain applies the held events that now follow the last applied version,
+// and discards those a BestEffort gap left behind. The caller holds state.mu.
+func (p *OrderedProjection) drain(ctx context.Context, state *aggregateOrder) error {
+	for version := range state.held {
+		if version <= state.applied {
+			delete(state.held, version)
+		}
+	}
+	for {
+		envelope, ok := state.held[state.applied+1]
+		if !ok {
+			break
+		}
+		if err := p.apply(ctx, state, envelope); err != nil {
+			return err
+		}
+		delete(state.held, envelope.Event.Version)
+	}
+	if len(state.held) == 0 && state.timer != nil {
+		state.timer.Stop()
+		state.timer = nil
+	}
+	return nil
+}
+
+// armTimer reports the current gap of an aggregate after the gap timeout and
+// moves past it. The caller holds state.mu.
+func (p *OrderedProjection) armTimer(ctx context.Context, aggregateID string, state *aggregateOrder) {
+	var timer *time.Timer
+	timer = time.AfterFunc(p.config.gapTimeout, func() {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		if state.timer != timer {
+			return // Gap closed or projection reset meanwhile
+		}
+		state.timer = nil
+
+		versions := slices.Sorted(maps.Keys(state.held))
+		gap := OrderingGap{
+			ProjectionName: p.projection.Name(),
+			AggregateID:    aggregateID,
+			Expected:       state.applied + 1,
+			Next:           versions[0],
+			Held:           len(versions),
+			Waited:         time.Since(state.since),
+		}
+
+		state.applied = versions[0] - 1
+		if err := p.drain(ctx, state); err != nil {
+			p.config.logger.Error("failed to apply held events",
+				"projection", p.projection.Name(), "aggregate_id", aggregateID, "error", err)
+		}
+		p.config.onGap(gap)
+		if len(state.held) > 0 && state.timer == nil {
+			// Wait again for the next gap, or to retry after a failure
+			state.since = time.Now()
+			p.armTimer(ctx, aggregateID, state)
+		}
+	})
+	state.timer = timer
+}
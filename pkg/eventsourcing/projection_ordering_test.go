@@ -0,0 +1,241 @@
+package eventsourcing_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+)
+
+// versionRecorder records the versions it handled per aggregate.
+type versionRecorder struct {
+	mu      sync.Mutex
+	applied map[string][]int64
+}
+
+func (r *versionRecorder) Name() string { return "recorder" }
+
+func (r *versionRecorder) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applied[envelope.Event.AggregateID] = append(r.applied[envelope.Event.AggregateID], envelope.Event.Version)
+	return nil
+}
+
+func (r *versionRecorder) Reset(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applied = make(map[string][]int64)
+	return nil
+}
+
+func (r *versionRecorder) versions(aggregateID string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fmt.Sprint(r.applied[aggregateID])
+}
+
+// lockedBuffer is a bytes.Buffer that is safe to log to from the gap timer.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestOrderedProjection(t *testing.T) {
+	envelope := func(aggregateID string, version int64) *domain.EventEnvelope {
+		return &domain.EventEnvelope{Event: domain.Event{
+			ID:          fmt.Sprintf("%s-%d", aggregateID, version),
+			AggregateID: aggregateID,
+			Version:     version,
+		}}
+	}
+	deliver := func(t *testing.T, projection eventsourcing.Projection, aggregateID string, versions ...int64) {
+		t.Helper()
+		for _, version := range versions {
+			if err := projection.Handle(context.Background(), envelope(aggregateID, version)); err != nil {
+				t.Fatalf("failed to handle version %d: %v", version, err)
+			}
+		}
+	}
+	fromStart := eventsourcing.WithVersionLookup(func(ctx context.Context, aggregateID string) (int64, error) {
+		return 0, nil
+	})
+
+	t.Run("StrictReorders", func(t *testing.T) {
+		recorder := &versionRecorder{applied: make(map[string][]int64)}
+		projection := eventsourcing.NewOrderedProjection(recorder, eventsourcing.StrictPerAggregate, fromStart)
+
+		deliver(t, projection, "acc-1", 1, 3, 4, 3)
+		if got := recorder.versions("acc-1"); got != "[1]" {
+			t.Fatalf("expected only version 1 applied, got %s", got)
+		}
+		if projection.Held("acc-1") != 2 {
+			t.Errorf("expected 2 held events, got %d", projection.Held("acc-1"))
+		}
+
+		// The redelivered predecessor releases the held events; duplicates are dropped
+		deliver(t, projection, "acc-1", 2, 2, 1)
+		if got := recorder.versions("acc-1"); got != "[1 2 3 4]" {
+			t.Errorf("expected versions [1 2 3 4], got %s", got)
+		}
+		if projection.Held("acc-1") != 0 {
+			t.Errorf("expected no held events, got %d", projection.Held("acc-1"))
+		}
+	})
+
+	t.Run("BestEffortDropsLateEvents", func(t *testing.T) {
+		recorder := &versionRecorder{applied: make(map[string][]int64)}
+		projection := eventsourcing.NewOrderedProjection(recorder, eventsourcing.BestEffort, fromStart)
+
+		deliver(t, projection, "acc-1", 1, 3, 2, 4)
+		if got := recorder.versions("acc-1"); got != "[1 3 4]" {
+			t.Errorf("expected versions [1 3 4], got %s", got)
+		}
+	})
+
+	t.Run("BufferFull", func(t *testing.T) {
+		recorder := &versionRecorder{applied: make(map[string][]int64)}
+		projection := eventsourcing.NewOrderedProjection(recorder, eventsourcing.StrictPerAggregate,
+			fromStart, eventsourcing.WithReorderBuffer(2))
+
+		deliver(t, projection, "acc-1", 2, 3)
+		err := projection.Handle(context.Background(), envelope("acc-1", 4))
+		if !errors.Is(err, eventsourcing.ErrReorderBufferFull) {
+			t.Fatalf("expected ErrReorderBufferFull, got %v", err)
+		}
+		// Other aggregates have their own buffer
+		deliver(t, projection, "acc-2", 2)
+	})
+
+	t.Run("EvictsIdleAggregates", func(t *testing.T) {
+		recorder := &versionRecorder{applied: make(map[string][]int64)}
+		projection := eventsourcing.NewOrderedProjection(recorder, eventsourcing.StrictPerAggregate,
+			eventsourcing.WithTrackedAggregates(1))
+
+		// acc-1 holds an event, so tracking acc-2 cannot evict it
+		deliver(t, projection, "acc-1", 1, 3)
+		deliver(t, projection, "acc-2", 1)
+		if projection.Held("acc-1") != 1 {
+			t.Fatalf("expected acc-1 to keep its held event, got %d", projection.Held("acc-1"))
+		}
+		deliver(t, projection, "acc-1", 2)
+
+		// Now idle, acc-1 is forgotten and its next event starts afresh
+		deliver(t, projection, "acc-2", 2)
+		deliver(t, projection, "acc-1", 2)
+		if got := recorder.versions("acc-1"); got != "[1 2 3 2]" {
+			t.Errorf("expected acc-1 forgotten after eviction, got %s", got)
+		}
+	})
+
+	t.Run("GapTimeout", func(t *testing.T) {
+		recorder := &versionRecorder{applied: make(map[string][]int64)}
+		gaps := make(chan eventsourcing.OrderingGap, 1)
+		projection := eventsourcing.NewOrderedProjection(recorder, eventsourcing.StrictPerAggregate,
+			fromStart,
+			eventsourcing.WithGapTimeout(20*time.Millisecond),
+			eventsourcing.WithGapHandler(func(gap eventsourcing.OrderingGap) { gaps <- gap }))
+
+		deliver(t, projection, "acc-1", 1, 4, 5)
+		select {
+		case gap := <-gaps:
+			if gap.ProjectionName != "recorder" || gap.AggregateID != "acc-1" ||
+				gap.Expected != 2 || gap.Next != 4 || gap.Held != 2 {
+				t.Errorf("unexpected gap: %+v", gap)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("gap was not reported")
+		}
+		if got := recorder.versions("acc-1"); got != "[1 4 5]" {
+			t.Errorf("expected the held events applied after the gap, got %s", got)
+		}
+
+		// The missing versions are too late now
+		deliver(t, projection, "acc-1", 2, 6)
+		if got := recorder.versions("acc-1"); got != "[1 4 5 6]" {
+			t.Errorf("expected versions [1 4 5 6], got %s", got)
+		}
+	})
+
+	t.Run("GapLogged", func(t *testing.T) {
+		recorder := &versionRecorder{applied: make(map[string][]int64)}
+		var logs lockedBuffer
+		projection := eventsourcing.NewOrderedProjection(recorder, eventsourcing.StrictPerAggregate,
+			fromStart,
+			eventsourcing.WithGapTimeout(20*time.Millisecond),
+			eventsourcing.WithOrderingLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+		deliver(t, projection, "acc-1", 1, 4)
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(logs.String(), "skipped missing versions") {
+			if time.Now().After(deadline) {
+				t.Fatal("gap was not logged")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		for _, want := range []string{"level=WARN", "projection=recorder", "aggregate_id=acc-1", "expected=2", "next=4", "held=1"} {
+			if !strings.Contains(logs.String(), want) {
+				t.Errorf("expected the gap warning to contain %q, got %q", want, logs.String())
+			}
+		}
+	})
+
+	t.Run("BuilderAndReset", func(t *testing.T) {
+		var handled []int64
+		projection := eventsourcing.NewProjectionBuilder("balances").
+			On(eventsourcing.EventHandlerRegistration{
+				EventType: "account.v1.Deposited",
+				Handler: func(ctx context.Context, envelope *domain.EventEnvelope) error {
+					handled = append(handled, envelope.Event.Version)
+					return nil
+				},
+			}).
+			WithOrderingPolicy(eventsourcing.StrictPerAggregate, fromStart).
+			Build()
+
+		deposited := func(version int64) *domain.EventEnvelope {
+			e := envelope("acc-1", version)
+			e.Event.EventType = "account.v1.Deposited"
+			return e
+		}
+		for _, version := range []int64{2, 1} {
+			if err := projection.Handle(context.Background(), deposited(version)); err != nil {
+				t.Fatalf("failed to handle version %d: %v", version, err)
+			}
+		}
+		if fmt.Sprint(handled) != "[1 2]" {
+			t.Errorf("expected versions [1 2], got %v", handled)
+		}
+
+		// A rebuild starts over from version 1
+		if err := projection.Reset(context.Background()); err != nil {
+			t.Fatalf("failed to reset: %v", err)
+		}
+		if err := projection.Handle(context.Background(), deposited(1)); err != nil {
+			t.Fatalf("failed to handle version 1 after reset: %v", err)
+		}
+		if fmt.Sprint(handled) != "[1 2 1]" {
+			t.Errorf("expected version 1 applied again after reset, got %v", handled)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+)
+
+// Pause stops a running projection from handling events while keeping its
+// event bus subscriptions, e.g. while the store behind its read model is under
+// maintenance. Events arriving meanwhile wait in the bus instead of being
+// dropped: the projection's handler holds them until Resume. On a bus with
+// delivery timeouts (such as NATS JetStream) they may be redelivered; the
+// projection skips those it already handled.
+//
+// Stop, StopAll and Rebuild end a pause; events held by it are returned to the
+// bus unhandled.
+func (m *ProjectionManager) Pause(projectionName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, running := m.running[projectionName]; !running {
+		return fmt.Errorf("projection %s not running", projectionName)
+	}
+	if _, paused := m.paused[projectionName]; paused {
+		return fmt.Errorf("projection %s already paused", projectionName)
+	}
+
+	m.paused[projectionName] = make(chan struct{})
+	m.setState(projectionName, ProjectionStatusPaused, "Paused")
+	return nil
+}
+
+// Resume continues a paused projection from its checkpoint, handling the
+// events that arrived during the pause.
+func (m *ProjectionManager) Resume(projectionName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	resume, paused := m.paused[projectionName]
+	if !paused {
+		return fmt.Errorf("projection %s not paused", projectionName)
+	}
+
+	delete(m.paused, projectionName)
+	m.setState(projectionName, ProjectionStatusReady, "Running")
+	close(resume)
+	return nil
+}
+
+// IsPaused reports whether a projection is paused.
+func (m *ProjectionManager) IsPaused(projectionName string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, paused := m.paused[projectionName]
+	return paused
+}
+
+// waitIfPaused blocks while a projection is paused. It returns an error, so
+// the bus redelivers the event, once the projection has stopped.
+func (m *ProjectionManager) waitIfPaused(ctx context.Context, projectionName string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("projection %s stopped: %w", projectionName, err)
+	}
+
+	m.mu.RLock()
+	resume, paused := m.paused[projectionName]
+	m.mu.RUnlock()
+	if !paused {
+		return nil
+	}
+
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("projection %s stopped while paused: %w", projectionName, ctx.Err())
+	}
+}
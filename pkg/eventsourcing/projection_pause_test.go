@@ -0,0 +1,209 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestProjectionPauseResume(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	bus := &capturingBus{}
+	manager := eventsourcing.NewProjectionManager(checkpointStore, eventStore, bus)
+	projection := &countingProjection{name: "counter"}
+	manager.Register(projection)
+
+	if err := manager.Pause("counter"); err == nil {
+		t.Error("expected pausing a projection that is not running to fail")
+	}
+	if err := manager.Start(ctx, "counter"); err != nil {
+		t.Fatalf("failed to start projection: %v", err)
+	}
+	defer manager.StopAll()
+
+	deliver := func(position int64) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			done <- bus.handler(&domain.EventEnvelope{Event: domain.Event{
+				ID: fmt.Sprintf("evt-%d", position), AggregateID: "acc-1", EventType: "account.v1.Deposited",
+				Version: position, Position: position,
+			}})
+		}()
+		return done
+	}
+	if err := <-deliver(1); err != nil {
+		t.Fatalf("failed to handle event: %v", err)
+	}
+
+	if err := manager.Pause("counter"); err != nil {
+		t.Fatalf("failed to pause: %v", err)
+	}
+	if err := manager.Pause("counter"); err == nil {
+		t.Error("expected pausing twice to fail")
+	}
+	if state := manager.Statuses()["counter"]; state.Status != eventsourcing.ProjectionStatusPaused {
+		t.Errorf("expected the projection to be paused, got %+v", state)
+	}
+
+	// Events arriving during the pause wait in the bus
+	held := deliver(2)
+	select {
+	case err := <-held:
+		t.Fatalf("expected the event to be held while paused, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if n := projection.handled.Load(); n != 1 {
+		t.Errorf("expected no events handled while paused, got %d", n)
+	}
+
+	if err := manager.Resume("counter"); err != nil {
+		t.Fatalf("failed to resume: %v", err)
+	}
+	if err := <-held; err != nil {
+		t.Fatalf("failed to handle the held event: %v", err)
+	}
+	if n := projection.handled.Load(); n != 2 {
+		t.Errorf("expected the held event to be handled on resume, got %d events", n)
+	}
+	if state := manager.Statuses()["counter"]; state.Status != eventsourcing.ProjectionStatusReady || state.Position != 2 {
+		t.Errorf("expected the projection running at position 2, got %+v", state)
+	}
+
+	// Redeliveries of checkpointed events are skipped
+	if err := <-deliver(2); err != nil {
+		t.Fatalf("failed to handle redelivered event: %v", err)
+	}
+	if n := projection.handled.Load(); n != 2 {
+		t.Errorf("expected the redelivered event to be skipped, got %d events", n)
+	}
+
+	t.Run("StopWhilePaused", func(t *testing.T) {
+		if err := manager.Pause("counter"); err != nil {
+			t.Fatalf("failed to pause: %v", err)
+		}
+		held := deliver(3)
+		time.Sleep(20 * time.Millisecond)
+		if err := manager.Stop("counter"); err != nil {
+			t.Fatalf("failed to stop: %v", err)
+		}
+		if err := <-held; err == nil {
+			t.Error("expected the held event to be returned to the bus")
+		}
+		if manager.IsPaused("counter") {
+			t.Error("expected stopping to end the pause")
+		}
+		if state := manager.Statuses()["counter"]; state.Status != eventsourcing.ProjectionStatusStopped {
+			t.Errorf("expected a stopped projection not to look paused, got %+v", state)
+		}
+	})
+}
+
+func TestProjectionRestartResumesFromPosition(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	bus := &capturingBus{}
+	manager := eventsourcing.NewProjectionManager(checkpointStore, eventStore, bus)
+	projection := &countingProjection{name: "counter"}
+	manager.Register(projection)
+
+	deliver := func(position int64) {
+		t.Helper()
+		if err := bus.handler(&domain.EventEnvelope{Event: domain.Event{
+			ID: fmt.Sprintf("evt-%d", position), AggregateID: "acc-1", EventType: "account.v1.Deposited", Position: position,
+		}}); err != nil {
+			t.Fatalf("failed to handle event: %v", err)
+		}
+	}
+
+	// Positions need not be contiguous, e.g. with filtered subscriptions
+	if err := manager.Start(ctx, "counter"); err != nil {
+		t.Fatalf("failed to start projection: %v", err)
+	}
+	deliver(10)
+	deliver(20)
+	manager.StopAll()
+
+	checkpoint, err := manager.GetCheckpoint("counter")
+	if err != nil || checkpoint.Position != 20 {
+		t.Fatalf("expected the checkpoint at position 20, got %+v (%v)", checkpoint, err)
+	}
+
+	if err := manager.Start(ctx, "counter"); err != nil {
+		t.Fatalf("failed to restart projection: %v", err)
+	}
+	defer manager.StopAll()
+	deliver(15)
+	deliver(20)
+	if n := projection.handled.Load(); n != 2 {
+		t.Errorf("expected events up to the checkpoint to be skipped after a restart, got %d handled", n)
+	}
+	deliver(25)
+	if n := projection.handled.Load(); n != 3 {
+		t.Errorf("expected the next event to be handled, got %d handled", n)
+	}
+}
+
+func TestProjectionRebuildPagesByPosition(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	// More events than one rebuild batch
+	const total = 1001
+	events := make([]*domain.Event, total)
+	for i := range events {
+		events[i] = &domain.Event{
+			ID: fmt.Sprintf("evt-%04d", i+1), AggregateID: "acc-1", AggregateType: "Account",
+			EventType: "account.v1.Deposited", Version: int64(i + 1), Timestamp: time.Now(), Data: []byte("{}"),
+		}
+	}
+	if err := eventStore.AppendEvents(ctx, "acc-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	manager := eventsourcing.NewProjectionManager(checkpointStore, eventStore, &capturingBus{})
+	recorder := &versionRecorder{applied: make(map[string][]int64)}
+	manager.Register(recorder)
+	if err := manager.Rebuild(ctx, "recorder"); err != nil {
+		t.Fatalf("failed to rebuild: %v", err)
+	}
+
+	if n := len(recorder.applied["acc-1"]); n != total {
+		t.Errorf("expected each of the %d events to be handled once, got %d", total, n)
+	}
+	checkpoint, err := manager.GetCheckpoint("recorder")
+	if err != nil || checkpoint.Position != events[total-1].Position {
+		t.Errorf("expected the checkpoint at position %d, got %+v (%v)", events[total-1].Position, checkpoint, err)
+	}
+}
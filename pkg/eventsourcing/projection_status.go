@@ -17,6 +17,10 @@ const (
 
 	// ProjectionStatusPaused indicates the projection is paused (not processing events)
 	ProjectionStatusPaused ProjectionStatus = "PAUSED"
+
+	// ProjectionStatusStopped indicates the projection is not running: it was
+	// stopped, or never started
+	ProjectionStatusStopped ProjectionStatus = "STOPPED"
 )
 
 // ProjectionState tracks the operational state of a projection.
@@ -26,6 +30,7 @@ type ProjectionState struct {
 	Message        string // Optional status message (e.g., error details)
 	UpdatedAt      time.Time
 	Progress       *RebuildProgress // Optional progress info during rebuild
+	Position       int64            // Checkpoint position, set by ProjectionManager.Statuses
 }
 
 // RebuildProgress tracks progress during a projection rebuild.
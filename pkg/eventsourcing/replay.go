@@ -0,0 +1,54 @@
+package eventsourcing
+
+import (
+	"github.com/plaenen/eventstore/pkg/domain"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewStoredEvent converts a stored event to its wire form. Unique constraints
+// are not included: they are an append-time concern.
+func NewStoredEvent(event *domain.Event) *StoredEvent {
+	return &StoredEvent{
+		Id:            event.ID,
+		AggregateId:   event.AggregateID,
+		AggregateType: event.AggregateType,
+		EventType:     event.EventType,
+		Version:       event.Version,
+		Position:      event.Position,
+		Timestamp:     timestamppb.New(event.Timestamp),
+		Data:          event.Data,
+		Metadata: &StoredEventMetadata{
+			CausationId:   event.Metadata.CausationID,
+			CorrelationId: event.Metadata.CorrelationID,
+			PrincipalId:   event.Metadata.PrincipalID,
+			TenantId:      event.Metadata.TenantID,
+			Custom:        event.Metadata.Custom,
+		},
+	}
+}
+
+// ToEvent converts the wire form back to a domain event.
+func (x *StoredEvent) ToEvent() *domain.Event {
+	event := &domain.Event{
+		ID:            x.GetId(),
+		AggregateID:   x.GetAggregateId(),
+		AggregateType: x.GetAggregateType(),
+		EventType:     x.GetEventType(),
+		Version:       x.GetVersion(),
+		Position:      x.GetPosition(),
+		Data:          x.GetData(),
+	}
+	if x.GetTimestamp() != nil {
+		event.Timestamp = x.GetTimestamp().AsTime()
+	}
+	if m := x.GetMetadata(); m != nil {
+		event.Metadata = domain.EventMetadata{
+			CausationID:   m.GetCausationId(),
+			CorrelationID: m.GetCorrelationId(),
+			PrincipalID:   m.GetPrincipalId(),
+			TenantID:      m.GetTenantId(),
+			Custom:        m.GetCustom(),
+		}
+	}
+	return event
+}
@@ -0,0 +1,423 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: eventsourcing/replay.proto
+
+package eventsourcing
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// ReplayEventsRequest asks a service for the stored events of one aggregate.
+// It is a built-in query served by cqrs.RegisterReplayEvents.
+type ReplayEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// aggregate_id is the aggregate whose events are replayed
+	AggregateId string `protobuf:"bytes,1,opt,name=aggregate_id,json=aggregateId,proto3" json:"aggregate_id,omitempty"`
+	// from_version replays the events after this version (0 = from the first event)
+	FromVersion int64 `protobuf:"varint,2,opt,name=from_version,json=fromVersion,proto3" json:"from_version,omitempty"`
+	// limit caps the number of events in the response (0 = server default)
+	Limit         int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplayEventsRequest) Reset() {
+	*x = ReplayEventsRequest{}
+	mi := &file_eventsourcing_replay_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplayEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplayEventsRequest) ProtoMessage() {}
+
+func (x *ReplayEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_eventsourcing_replay_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplayEventsRequest.ProtoReflect.Descriptor instead.
+func (*ReplayEventsRequest) Descriptor() ([]byte, []int) {
+	return file_eventsourcing_replay_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ReplayEventsRequest) GetAggregateId() string {
+	if x != nil {
+		return x.AggregateId
+	}
+	return ""
+}
+
+func (x *ReplayEventsRequest) GetFromVersion() int64 {
+	if x != nil {
+		return x.FromVersion
+	}
+	return 0
+}
+
+func (x *ReplayEventsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// ReplayEventsResponse is one page of an aggregate's events, in version order
+type ReplayEventsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// events are the stored events of the page
+	Events []*StoredEvent `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	// has_more is true when more events follow; request the next page with
+	// from_version set to the version of the last event
+	HasMore       bool `protobuf:"varint,2,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplayEventsResponse) Reset() {
+	*x = ReplayEventsResponse{}
+	mi := &file_eventsourcing_replay_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplayEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplayEventsResponse) ProtoMessage() {}
+
+func (x *ReplayEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_eventsourcing_replay_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplayEventsResponse.ProtoReflect.Descriptor instead.
+func (*ReplayEventsResponse) Descriptor() ([]byte, []int) {
+	return file_eventsourcing_replay_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ReplayEventsResponse) GetEvents() []*StoredEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *ReplayEventsResponse) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+// StoredEvent is an event as persisted in the event store
+type StoredEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	AggregateId   string                 `protobuf:"bytes,2,opt,name=aggregate_id,json=aggregateId,proto3" json:"aggregate_id,omitempty"`
+	AggregateType string                 `protobuf:"bytes,3,opt,name=aggregate_type,json=aggregateType,proto3" json:"aggregate_type,omitempty"`
+	// event_type is the fully qualified proto name of the payload
+	EventType string `protobuf:"bytes,4,opt,name=event_type,json=eventType,proto3" json:"event_type,omitempty"`
+	Version   int64  `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`
+	// position is the global position of the event in the store
+	Position  int64                  `protobuf:"varint,6,opt,name=position,proto3" json:"position,omitempty"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// data is the serialized protobuf payload
+	Data          []byte               `protobuf:"bytes,8,opt,name=data,proto3" json:"data,omitempty"`
+	Metadata      *StoredEventMetadata `protobuf:"bytes,9,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoredEvent) Reset() {
+	*x = StoredEvent{}
+	mi := &file_eventsourcing_replay_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoredEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoredEvent) ProtoMessage() {}
+
+func (x *StoredEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_eventsourcing_replay_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoredEvent.ProtoReflect.Descriptor instead.
+func (*StoredEvent) Descriptor() ([]byte, []int) {
+	return file_eventsourcing_replay_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StoredEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *StoredEvent) GetAggregateId() string {
+	if x != nil {
+		return x.AggregateId
+	}
+	return ""
+}
+
+func (x *StoredEvent) GetAggregateType() string {
+	if x != nil {
+		return x.AggregateType
+	}
+	return ""
+}
+
+func (x *StoredEvent) GetEventType() string {
+	if x != nil {
+		return x.EventType
+	}
+	return ""
+}
+
+func (x *StoredEvent) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *StoredEvent) GetPosition() int64 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *StoredEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *StoredEvent) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *StoredEvent) GetMetadata() *StoredEventMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// StoredEventMetadata is the contextual information recorded with an event
+type StoredEventMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CausationId   string                 `protobuf:"bytes,1,opt,name=causation_id,json=causationId,proto3" json:"causation_id,omitempty"`
+	CorrelationId string                 `protobuf:"bytes,2,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	PrincipalId   string                 `protobuf:"bytes,3,opt,name=principal_id,json=principalId,proto3" json:"principal_id,omitempty"`
+	TenantId      string                 `protobuf:"bytes,4,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	Custom        map[string]string      `protobuf:"bytes,5,rep,name=custom,proto3" json:"custom,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoredEventMetadata) Reset() {
+	*x = StoredEventMetadata{}
+	mi := &file_eventsourcing_replay_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoredEventMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoredEventMetadata) ProtoMessage() {}
+
+func (x *StoredEventMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_eventsourcing_replay_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoredEventMetadata.ProtoReflect.Descriptor instead.
+func (*StoredEventMetadata) Descriptor() ([]byte, []int) {
+	return file_eventsourcing_replay_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StoredEventMetadata) GetCausationId() string {
+	if x != nil {
+		return x.CausationId
+	}
+	return ""
+}
+
+func (x *StoredEventMetadata) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *StoredEventMetadata) GetPrincipalId() string {
+	if x != nil {
+		return x.PrincipalId
+	}
+	return ""
+}
+
+func (x *StoredEventMetadata) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *StoredEventMetadata) GetCustom() map[string]string {
+	if x != nil {
+		return x.Custom
+	}
+	return nil
+}
+
+var File_eventsourcing_replay_proto protoreflect.FileDescriptor
+
+const file_eventsourcing_replay_proto_rawDesc = "" +
+	"\n" +
+	"\x1aeventsourcing/replay.proto\x12\reventsourcing\x1a\x1fgoogle/protobuf/timestamp.proto\"q\n" +
+	"\x13ReplayEventsRequest\x12!\n" +
+	"\faggregate_id\x18\x01 \x01(\tR\vaggregateId\x12!\n" +
+	"\ffrom_version\x18\x02 \x01(\x03R\vfromVersion\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"e\n" +
+	"\x14ReplayEventsResponse\x122\n" +
+	"\x06events\x18\x01 \x03(\v2\x1a.eventsourcing.StoredEventR\x06events\x12\x19\n" +
+	"\bhas_more\x18\x02 \x01(\bR\ahasMore\"\xca\x02\n" +
+	"\vStoredEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12!\n" +
+	"\faggregate_id\x18\x02 \x01(\tR\vaggregateId\x12%\n" +
+	"\x0eaggregate_type\x18\x03 \x01(\tR\raggregateType\x12\x1d\n" +
+	"\n" +
+	"event_type\x18\x04 \x01(\tR\teventType\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\x03R\aversion\x12\x1a\n" +
+	"\bposition\x18\x06 \x01(\x03R\bposition\x128\n" +
+	"\ttimestamp\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x12\n" +
+	"\x04data\x18\b \x01(\fR\x04data\x12>\n" +
+	"\bmetadata\x18\t \x01(\v2\".eventsourcing.StoredEventMetadataR\bmetadata\"\xa2\x02\n" +
+	"\x13StoredEventMetadata\x12!\n" +
+	"\fcausation_id\x18\x01 \x01(\tR\vcausationId\x12%\n" +
+	"\x0ecorrelation_id\x18\x02 \x01(\tR\rcorrelationId\x12!\n" +
+	"\fprincipal_id\x18\x03 \x01(\tR\vprincipalId\x12\x1b\n" +
+	"\ttenant_id\x18\x04 \x01(\tR\btenantId\x12F\n" +
+	"\x06custom\x18\x05 \x03(\v2..eventsourcing.StoredEventMetadata.CustomEntryR\x06custom\x1a9\n" +
+	"\vCustomEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B1Z/github.com/plaenen/eventstore/pkg/eventsourcingb\x06proto3"
+
+var (
+	file_eventsourcing_replay_proto_rawDescOnce sync.Once
+	file_eventsourcing_replay_proto_rawDescData []byte
+)
+
+func file_eventsourcing_replay_proto_rawDescGZIP() []byte {
+	file_eventsourcing_replay_proto_rawDescOnce.Do(func() {
+		file_eventsourcing_replay_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_eventsourcing_replay_proto_rawDesc), len(file_eventsourcing_replay_proto_rawDesc)))
+	})
+	return file_eventsourcing_replay_proto_rawDescData
+}
+
+var file_eventsourcing_replay_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_eventsourcing_replay_proto_goTypes = []any{
+	(*ReplayEventsRequest)(nil),   // 0: eventsourcing.ReplayEventsRequest
+	(*ReplayEventsResponse)(nil),  // 1: eventsourcing.ReplayEventsResponse
+	(*StoredEvent)(nil),           // 2: eventsourcing.StoredEvent
+	(*StoredEventMetadata)(nil),   // 3: eventsourcing.StoredEventMetadata
+	nil,                           // 4: eventsourcing.StoredEventMetadata.CustomEntry
+	(*timestamppb.Timestamp)(nil), // 5: google.protobuf.Timestamp
+}
+var file_eventsourcing_replay_proto_depIdxs = []int32{
+	2, // 0: eventsourcing.ReplayEventsResponse.events:type_name -> eventsourcing.StoredEvent
+	5, // 1: eventsourcing.StoredEvent.timestamp:type_name -> google.protobuf.Timestamp
+	3, // 2: eventsourcing.StoredEvent.metadata:type_name -> eventsourcing.StoredEventMetadata
+	4, // 3: eventsourcing.StoredEventMetadata.custom:type_name -> eventsourcing.StoredEventMetadata.CustomEntry
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_eventsourcing_replay_proto_init() }
+func file_eventsourcing_replay_proto_init() {
+	if File_eventsourcing_replay_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_eventsourcing_replay_proto_rawDesc), len(file_eventsourcing_replay_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_eventsourcing_replay_proto_goTypes,
+		DependencyIndexes: file_eventsourcing_replay_proto_depIdxs,
+		MessageInfos:      file_eventsourcing_replay_proto_msgTypes,
+	}.Build()
+	File_eventsourcing_replay_proto = out.File
+	file_eventsourcing_replay_proto_goTypes = nil
+	file_eventsourcing_replay_proto_depIdxs = nil
+}
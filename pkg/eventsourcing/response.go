@@ -1,6 +1,7 @@
 package eventsourcing
 
 import (
+	"errors"
 	"fmt"
 
 	"google.golang.org/protobuf/proto"
@@ -38,6 +39,38 @@ func NewSimpleErrorResponse(code, message string) *Response {
 	return NewErrorResponse(code, message, "", nil)
 }
 
+// ValidationFailedCode is the AppError code of requests rejected because of
+// invalid fields; the fields are listed in AppError.FieldViolations.
+const ValidationFailedCode = "VALIDATION_FAILED"
+
+// NewValidationAppError creates a VALIDATION_FAILED AppError listing the
+// invalid fields of a request.
+func NewValidationAppError(message string, violations ...*FieldViolation) *AppError {
+	return &AppError{
+		Code:            ValidationFailedCode,
+		Message:         message,
+		Solution:        "Correct the listed fields and retry",
+		FieldViolations: violations,
+	}
+}
+
+// NewValidationError is NewValidationAppError as a Go error, for handlers and
+// middleware that return error. Servers send its AppError to the client as is.
+func NewValidationError(message string, violations ...*FieldViolation) error {
+	return &ResponseError{AppError: NewValidationAppError(message, violations...)}
+}
+
+// AppErrorFromError returns the AppError carried by a *ResponseError in err's
+// chain, or a new AppError with code and err's message if there is none.
+// Servers use it to keep structured errors intact across the transport.
+func AppErrorFromError(err error, code string) *AppError {
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) && responseErr.AppError != nil {
+		return responseErr.AppError
+	}
+	return &AppError{Code: code, Message: err.Error()}
+}
+
 // UnpackData unpacks the response data into the target message
 func (r *Response) UnpackData(target proto.Message) error {
 	if !r.Success {
@@ -92,3 +125,8 @@ func (e *ResponseError) Solution() string {
 func (e *ResponseError) Details() map[string]string {
 	return e.AppError.Details
 }
+
+// FieldViolations returns the invalid fields of a rejected request
+func (e *ResponseError) FieldViolations() []*FieldViolation {
+	return e.AppError.FieldViolations
+}
@@ -98,9 +98,12 @@ type AppError struct {
 	// solution suggests how to fix the error
 	Solution string `protobuf:"bytes,3,opt,name=solution,proto3" json:"solution,omitempty"`
 	// details provides additional context (field names, values, etc.)
-	Details       map[string]string `protobuf:"bytes,4,rep,name=details,proto3" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	Details map[string]string `protobuf:"bytes,4,rep,name=details,proto3" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// field_violations lists the invalid fields of a rejected request
+	// (code "VALIDATION_FAILED"), so clients can point at each one
+	FieldViolations []*FieldViolation `protobuf:"bytes,5,rep,name=field_violations,json=fieldViolations,proto3" json:"field_violations,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *AppError) Reset() {
@@ -161,6 +164,77 @@ func (x *AppError) GetDetails() map[string]string {
 	return nil
 }
 
+func (x *AppError) GetFieldViolations() []*FieldViolation {
+	if x != nil {
+		return x.FieldViolations
+	}
+	return nil
+}
+
+// FieldViolation describes one field that failed validation
+type FieldViolation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// field is the path of the field, e.g. "owner.email" or "items[2].quantity"
+	Field string `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	// constraint is a machine-readable name of the failed rule (e.g., "required")
+	Constraint string `protobuf:"bytes,2,opt,name=constraint,proto3" json:"constraint,omitempty"`
+	// message is a human-readable description of the violation
+	Message       string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldViolation) Reset() {
+	*x = FieldViolation{}
+	mi := &file_eventsourcing_response_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldViolation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldViolation) ProtoMessage() {}
+
+func (x *FieldViolation) ProtoReflect() protoreflect.Message {
+	mi := &file_eventsourcing_response_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldViolation.ProtoReflect.Descriptor instead.
+func (*FieldViolation) Descriptor() ([]byte, []int) {
+	return file_eventsourcing_response_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FieldViolation) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *FieldViolation) GetConstraint() string {
+	if x != nil {
+		return x.Constraint
+	}
+	return ""
+}
+
+func (x *FieldViolation) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 var File_eventsourcing_response_proto protoreflect.FileDescriptor
 
 const file_eventsourcing_response_proto_rawDesc = "" +
@@ -169,15 +243,22 @@ const file_eventsourcing_response_proto_rawDesc = "" +
 	"\bResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12-\n" +
 	"\x05error\x18\x02 \x01(\v2\x17.eventsourcing.AppErrorR\x05error\x12(\n" +
-	"\x04data\x18\x03 \x01(\v2\x14.google.protobuf.AnyR\x04data\"\xd0\x01\n" +
+	"\x04data\x18\x03 \x01(\v2\x14.google.protobuf.AnyR\x04data\"\x9a\x02\n" +
 	"\bAppError\x12\x12\n" +
 	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1a\n" +
 	"\bsolution\x18\x03 \x01(\tR\bsolution\x12>\n" +
-	"\adetails\x18\x04 \x03(\v2$.eventsourcing.AppError.DetailsEntryR\adetails\x1a:\n" +
+	"\adetails\x18\x04 \x03(\v2$.eventsourcing.AppError.DetailsEntryR\adetails\x12H\n" +
+	"\x10field_violations\x18\x05 \x03(\v2\x1d.eventsourcing.FieldViolationR\x0ffieldViolations\x1a:\n" +
 	"\fDetailsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B?Z=github.com/plaenen/eventstore/pkg/eventsourcing;eventsourcingb\x06proto3"
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"`\n" +
+	"\x0eFieldViolation\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x1e\n" +
+	"\n" +
+	"constraint\x18\x02 \x01(\tR\n" +
+	"constraint\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessageB?Z=github.com/plaenen/eventstore/pkg/eventsourcing;eventsourcingb\x06proto3"
 
 var (
 	file_eventsourcing_response_proto_rawDescOnce sync.Once
@@ -191,22 +272,24 @@ func file_eventsourcing_response_proto_rawDescGZIP() []byte {
 	return file_eventsourcing_response_proto_rawDescData
 }
 
-var file_eventsourcing_response_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_eventsourcing_response_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_eventsourcing_response_proto_goTypes = []any{
-	(*Response)(nil),  // 0: eventsourcing.Response
-	(*AppError)(nil),  // 1: eventsourcing.AppError
-	nil,               // 2: eventsourcing.AppError.DetailsEntry
-	(*anypb.Any)(nil), // 3: google.protobuf.Any
+	(*Response)(nil),       // 0: eventsourcing.Response
+	(*AppError)(nil),       // 1: eventsourcing.AppError
+	(*FieldViolation)(nil), // 2: eventsourcing.FieldViolation
+	nil,                    // 3: eventsourcing.AppError.DetailsEntry
+	(*anypb.Any)(nil),      // 4: google.protobuf.Any
 }
 var file_eventsourcing_response_proto_depIdxs = []int32{
 	1, // 0: eventsourcing.Response.error:type_name -> eventsourcing.AppError
-	3, // 1: eventsourcing.Response.data:type_name -> google.protobuf.Any
-	2, // 2: eventsourcing.AppError.details:type_name -> eventsourcing.AppError.DetailsEntry
-	3, // [3:3] is the sub-list for method output_type
-	3, // [3:3] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	4, // 1: eventsourcing.Response.data:type_name -> google.protobuf.Any
+	3, // 2: eventsourcing.AppError.details:type_name -> eventsourcing.AppError.DetailsEntry
+	2, // 3: eventsourcing.AppError.field_violations:type_name -> eventsourcing.FieldViolation
+	4, // [4:4] is the sub-list for method output_type
+	4, // [4:4] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_eventsourcing_response_proto_init() }
@@ -220,7 +303,7 @@ func file_eventsourcing_response_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_eventsourcing_response_proto_rawDesc), len(file_eventsourcing_response_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
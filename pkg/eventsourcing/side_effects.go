@@ -0,0 +1,44 @@
+package eventsourcing
+
+import (
+	"context"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// IsReplay reports whether a handler is re-handling work that already
+// happened, so it must not repeat side effects such as sending an email or
+// calling a payment API. It is true:
+//   - in projection handlers during a rebuild (ProjectionManager.Rebuild,
+//     ExternalProjection.Rebuild and the SQLite projection rebuilds)
+//   - in command handlers for a retried command ID, when the command bus uses
+//     middleware.IdempotentRetryMiddleware, or the NATS command bus has
+//     CommandResults set
+//   - in server handlers for a retried Idempotency-Key, when the server uses
+//     middleware.IdempotentRetryHandler
+//
+// Read model writes are replay-safe and should always run; only the side
+// effects belong behind the check:
+//
+//	func (p *WelcomeMailer) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
+//	    if err := p.recordSignup(ctx, envelope); err != nil {
+//	        return err
+//	    }
+//	    if eventsourcing.IsReplay(ctx) {
+//	        return nil // the mail went out the first time
+//	    }
+//	    return p.mailer.SendWelcome(ctx, envelope.AggregateID)
+//	}
+//
+// Events redelivered after a crash are not marked: whether the side effect
+// already ran is unknown, so such handlers still need an idempotency key.
+func IsReplay(ctx context.Context) bool {
+	return domain.IsReplay(ctx)
+}
+
+// WithReplay marks ctx as replaying for the handlers it is passed to. Command
+// handlers calling SaveWithCommand can use it once the result reports
+// AlreadyProcessed, before running post-save side effects.
+func WithReplay(ctx context.Context) context.Context {
+	return domain.WithReplay(ctx)
+}
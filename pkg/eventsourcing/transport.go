@@ -20,6 +20,61 @@ type Transport interface {
 	Close() error
 }
 
+// RequestOption configures a single request, e.g. a call of a generated
+// client method.
+type RequestOption func(*RequestOptions)
+
+// RequestOptions holds the settings of a single request.
+type RequestOptions struct {
+	// Timeout overrides the transport's request timeout (0 = transport default)
+	Timeout time.Duration
+
+	// Result receives the result the command handler set with
+	// domain.SetCommandResult (nil = discard it)
+	Result proto.Message
+}
+
+// WithCommandTimeout overrides the transport's request timeout for one
+// command, e.g. a bulk import that legitimately takes longer than usual.
+// Transports pass the timeout on to the server, so the handler's context
+// gets the same deadline.
+func WithCommandTimeout(d time.Duration) RequestOption {
+	return func(o *RequestOptions) {
+		o.Timeout = d
+	}
+}
+
+// WithCommandResultInto unpacks the result the command handler set with
+// domain.SetCommandResult, such as a new balance, into result, which must be
+// of the message type the handler sets: transports turn any other type into
+// an INVALID_RESPONSE error. result is left untouched if the handler set none.
+func WithCommandResultInto(result proto.Message) RequestOption {
+	return func(o *RequestOptions) {
+		o.Result = result
+	}
+}
+
+type requestOptionsKey struct{}
+
+// WithRequestOptions returns a context carrying opts to Transport.Request.
+// Generated clients call it with the options passed to each method.
+func WithRequestOptions(ctx context.Context, opts ...RequestOption) context.Context {
+	if len(opts) == 0 {
+		return ctx
+	}
+	options := RequestOptionsFromContext(ctx)
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return context.WithValue(ctx, requestOptionsKey{}, options)
+}
+
+// RequestOptionsFromContext returns the request options of the context.
+func RequestOptionsFromContext(ctx context.Context) RequestOptions {
+	options, _ := ctx.Value(requestOptionsKey{}).(RequestOptions)
+	return options
+}
+
 // TransportConfig holds common transport configuration
 type TransportConfig struct {
 	// Timeout for request/reply operations
@@ -0,0 +1,78 @@
+package kafka
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+)
+
+// EmbeddedCluster wraps an in-process fake Kafka cluster for testing.
+//
+// The cluster speaks the Kafka protocol on local ports, so any Kafka client
+// can connect to Brokers(). It keeps all data in memory and loses it on
+// shutdown. It is not meant for production.
+type EmbeddedCluster struct {
+	cluster      *kfake.Cluster
+	shutdownOnce sync.Once
+}
+
+// clusterOptions holds the embedded cluster configuration.
+type clusterOptions struct {
+	brokers    int
+	partitions int
+}
+
+// Option is a functional option for configuring the embedded Kafka cluster.
+type Option func(*clusterOptions)
+
+// WithBrokers sets the number of brokers in the cluster.
+// Default is 1.
+func WithBrokers(n int) Option {
+	return func(opts *clusterOptions) {
+		opts.brokers = n
+	}
+}
+
+// WithPartitions sets the number of partitions of automatically created
+// topics. Consumer groups split work by partition, so a group never has more
+// busy members than a topic has partitions.
+// Default is 4.
+func WithPartitions(n int) Option {
+	return func(opts *clusterOptions) {
+		opts.partitions = n
+	}
+}
+
+// StartEmbeddedCluster starts an in-process Kafka cluster on random local
+// ports. Topics are created automatically on first use.
+func StartEmbeddedCluster(opts ...Option) (*EmbeddedCluster, error) {
+	options := &clusterOptions{
+		brokers:    1,
+		partitions: 4,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	cluster, err := kfake.NewCluster(
+		kfake.NumBrokers(options.brokers),
+		kfake.DefaultNumPartitions(options.partitions),
+		kfake.AllowAutoTopicCreation(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start embedded Kafka cluster: %w", err)
+	}
+
+	return &EmbeddedCluster{cluster: cluster}, nil
+}
+
+// Brokers returns the addresses clients connect to.
+func (c *EmbeddedCluster) Brokers() []string {
+	return c.cluster.ListenAddrs()
+}
+
+// Shutdown stops the cluster. It is safe to call more than once.
+func (c *EmbeddedCluster) Shutdown() {
+	c.shutdownOnce.Do(c.cluster.Close)
+}
@@ -1,7 +1,9 @@
 package nats
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
@@ -13,6 +15,8 @@ import (
 type EmbeddedServer struct {
 	server       *server.Server
 	url          string
+	inProcess    bool
+	tempDir      string // JetStream store created for this server, removed on shutdown
 	shutdownOnce sync.Once
 }
 
@@ -27,6 +31,21 @@ func WithPort(port int) Option {
 	}
 }
 
+// WithInProcess runs the server without a TCP listener. Clients connect
+// through an in-memory connection instead, using ConnectToEmbedded or
+// EmbeddedServer.ConnectOptions, so tests need no free port and can run in
+// parallel without port conflicts. Clients connecting by URL cannot reach the
+// server.
+//
+// Unless WithStoreDir is given, the server keeps its JetStream data in a
+// temporary directory of its own, removed on shutdown, instead of the shared
+// default, so parallel servers do not see each other's streams.
+func WithInProcess() Option {
+	return func(opts *server.Options) {
+		opts.DontListen = true
+	}
+}
+
 // WithHost sets the host address for the NATS server.
 // Default is "127.0.0.1".
 func WithHost(host string) Option {
@@ -143,9 +162,19 @@ func StartEmbeddedServer(options ...Option) (*EmbeddedServer, error) {
 		opt(opts)
 	}
 
+	var tempDir string
+	if opts.DontListen && opts.JetStream && opts.StoreDir == "" {
+		dir, err := os.MkdirTemp("", "nats-in-process-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create store directory: %w", err)
+		}
+		tempDir, opts.StoreDir = dir, dir
+	}
+
 	// Create server
 	s, err := server.NewServer(opts)
 	if err != nil {
+		removeDir(tempDir)
 		return nil, fmt.Errorf("failed to create embedded server: %w", err)
 	}
 
@@ -154,32 +183,69 @@ func StartEmbeddedServer(options ...Option) (*EmbeddedServer, error) {
 
 	// Wait for server to be ready
 	if !s.ReadyForConnections(5 * time.Second) {
+		s.Shutdown()
+		removeDir(tempDir)
 		return nil, fmt.Errorf("server not ready within 5 seconds")
 	}
 
 	url := s.ClientURL()
+	if opts.DontListen {
+		// There is no listener to advertise; the URL only needs to parse,
+		// since in-process clients never dial it
+		url = inProcessURL
+	}
 
 	return &EmbeddedServer{
-		server: s,
-		url:    url,
+		server:    s,
+		url:       url,
+		inProcess: opts.DontListen,
+		tempDir:   tempDir,
 	}, nil
 }
 
+// inProcessURL is the URL of servers started WithInProcess.
+const inProcessURL = "nats://in-process"
+
 // Server returns the underlying NATS server.
 // Useful for advanced configuration or monitoring.
 func (e *EmbeddedServer) Server() *server.Server {
 	return e.server
 }
 
-// URL returns the connection URL for the embedded server.
+// URL returns the connection URL for the embedded server. Servers started
+// WithInProcess cannot be dialed: connect with ConnectOptions as well.
 func (e *EmbeddedServer) URL() string {
 	return e.url
 }
 
+// InProcess reports whether the server was started WithInProcess.
+func (e *EmbeddedServer) InProcess() bool {
+	return e.inProcess
+}
+
+// ConnectOptions returns the client options needed to connect to the server,
+// to pass along with URL to nats.Connect or to the NATS-based event bus and
+// CQRS configs. For servers started WithInProcess it selects the in-memory
+// connection; otherwise it is empty.
+//
+// Example:
+//
+//	srv, _ := StartEmbeddedServer(WithInProcess())
+//	config := natseventbus.DefaultConfig()
+//	config.URL = srv.URL()
+//	config.ConnectOptions = srv.ConnectOptions()
+func (e *EmbeddedServer) ConnectOptions() []nats.Option {
+	if !e.inProcess {
+		return nil
+	}
+	return []nats.Option{nats.InProcessServer(e.server)}
+}
+
 // Shutdown stops the embedded server gracefully with a timeout.
 // Safe to call multiple times - only the first call will perform shutdown.
 func (e *EmbeddedServer) Shutdown() {
 	e.shutdownOnce.Do(func() {
+		defer removeDir(e.tempDir)
 		if e.server != nil {
 			// Shutdown the server
 			e.server.Shutdown()
@@ -210,6 +276,7 @@ func (e *EmbeddedServer) Shutdown() {
 // Safe to call multiple times - only the first call will perform shutdown.
 func (e *EmbeddedServer) ShutdownWithTimeout(timeout time.Duration) {
 	e.shutdownOnce.Do(func() {
+		defer removeDir(e.tempDir)
 		if e.server != nil {
 			// Shutdown the server
 			e.server.Shutdown()
@@ -234,6 +301,72 @@ func (e *EmbeddedServer) ShutdownWithTimeout(timeout time.Duration) {
 	})
 }
 
+// ShutdownGracefully stops the embedded server without losing JetStream data.
+//
+// Shutdown proceeds in order:
+//  1. Wait for clients to disconnect, so subscribers can drain in-flight
+//     messages and deliver their acks.
+//  2. Disable JetStream, which flushes file-backed streams and consumer state
+//     to disk.
+//  3. Shut down the server.
+//
+// Every step is bounded by ctx. When ctx expires the remaining steps still run
+// immediately and ctx.Err() is returned. Safe to call multiple times - only the
+// first call will perform shutdown.
+func (e *EmbeddedServer) ShutdownGracefully(ctx context.Context) error {
+	var err error
+	e.shutdownOnce.Do(func() {
+		defer removeDir(e.tempDir)
+		if e.server == nil {
+			return
+		}
+
+		// Give clients the chance to drain before we pull the server away
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+	waitClients:
+		for e.server.NumClients() > 0 {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				break waitClients
+			case <-ticker.C:
+			}
+		}
+
+		// Flush and close JetStream stores before the server goes down
+		if e.server.JetStreamEnabled() {
+			if jsErr := e.server.DisableJetStream(); jsErr != nil && err == nil {
+				err = fmt.Errorf("failed to disable JetStream: %w", jsErr)
+			}
+		}
+
+		e.server.Shutdown()
+
+		shutdownDone := make(chan struct{})
+		go func() {
+			e.server.WaitForShutdown()
+			close(shutdownDone)
+		}()
+
+		select {
+		case <-shutdownDone:
+		case <-ctx.Done():
+			if err == nil {
+				err = ctx.Err()
+			}
+		}
+	})
+	return err
+}
+
+// removeDir removes a temporary store directory, if any.
+func removeDir(dir string) {
+	if dir != "" {
+		_ = os.RemoveAll(dir)
+	}
+}
+
 // ConnectToEmbedded connects to an embedded NATS server and returns a client.
 // Useful for testing.
 //
@@ -248,7 +381,7 @@ func (e *EmbeddedServer) ShutdownWithTimeout(timeout time.Duration) {
 //	}
 //	defer nc.Close()
 func ConnectToEmbedded(srv *EmbeddedServer) (*nats.Conn, error) {
-	return nats.Connect(srv.URL())
+	return nats.Connect(srv.URL(), srv.ConnectOptions()...)
 }
 
 // ConnectToEmbeddedWithOptions connects to an embedded NATS server with custom options.
@@ -264,5 +397,5 @@ func ConnectToEmbedded(srv *EmbeddedServer) (*nats.Conn, error) {
 //	    nats.ReconnectWait(time.Second),
 //	)
 func ConnectToEmbeddedWithOptions(srv *EmbeddedServer, opts ...nats.Option) (*nats.Conn, error) {
-	return nats.Connect(srv.URL(), opts...)
+	return nats.Connect(srv.URL(), append(srv.ConnectOptions(), opts...)...)
 }
@@ -1,6 +1,8 @@
 package nats
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -160,6 +162,100 @@ func TestConcurrentShutdowns(t *testing.T) {
 	})
 }
 
+func TestEmbeddedServer_ShutdownGracefully(t *testing.T) {
+	storeDir := t.TempDir()
+	const published = 50
+
+	srv, err := StartEmbeddedServer(WithStoreDir(storeDir))
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+
+	nc, err := ConnectToEmbedded(srv)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("failed to create JetStream context: %v", err)
+	}
+	if _, err := js.AddStream(&nats.StreamConfig{
+		Name:     "SHUTDOWN_TEST",
+		Subjects: []string{"shutdown.>"},
+		Storage:  nats.FileStorage,
+	}); err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+	for i := 0; i < published; i++ {
+		if _, err := js.Publish("shutdown.event", []byte(fmt.Sprintf("event-%d", i))); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+	}
+
+	// Close the client in the background: shutdown must wait for it
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		nc.Drain()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.ShutdownGracefully(ctx); err != nil {
+		t.Fatalf("graceful shutdown failed: %v", err)
+	}
+
+	// Restart on the same store and verify nothing was lost
+	srv, err = StartEmbeddedServer(WithStoreDir(storeDir))
+	if err != nil {
+		t.Fatalf("failed to restart embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	nc, err = ConnectToEmbedded(srv)
+	if err != nil {
+		t.Fatalf("failed to reconnect: %v", err)
+	}
+	defer nc.Close()
+	js, err = nc.JetStream()
+	if err != nil {
+		t.Fatalf("failed to create JetStream context: %v", err)
+	}
+
+	info, err := js.StreamInfo("SHUTDOWN_TEST")
+	if err != nil {
+		t.Fatalf("stream not recovered after restart: %v", err)
+	}
+	if info.State.Msgs != published {
+		t.Errorf("expected %d messages after restart, got %d", published, info.State.Msgs)
+	}
+}
+
+func TestEmbeddedServer_ShutdownGracefullyTimeout(t *testing.T) {
+	srv, err := StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+
+	// A client that never disconnects must not block shutdown past the deadline
+	nc, err := ConnectToEmbedded(srv)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer nc.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = srv.ShutdownGracefully(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("shutdown took too long: %v", elapsed)
+	}
+}
+
 func TestEmbeddedServer_URL(t *testing.T) {
 	srv, err := StartEmbeddedServer()
 	if err != nil {
@@ -220,3 +316,49 @@ func BenchmarkEmbeddedServer_Shutdown(b *testing.B) {
 	}
 }
 
+
+func TestEmbeddedServer_InProcess(t *testing.T) {
+	// Servers without listeners cannot conflict, so these run in parallel
+	for i := 0; i < 4; i++ {
+		t.Run(fmt.Sprintf("server %d", i), func(t *testing.T) {
+			t.Parallel()
+
+			srv, err := StartEmbeddedServer(WithInProcess())
+			if err != nil {
+				t.Fatalf("failed to start embedded server: %v", err)
+			}
+			defer srv.Shutdown()
+
+			if !srv.InProcess() {
+				t.Error("expected in-process server")
+			}
+			if _, err := nats.Connect(srv.URL(), nats.Timeout(100*time.Millisecond), nats.NoReconnect()); err == nil {
+				t.Error("expected connecting by URL alone to fail")
+			}
+
+			nc, err := ConnectToEmbedded(srv)
+			if err != nil {
+				t.Fatalf("failed to connect in-process: %v", err)
+			}
+			defer nc.Close()
+
+			js, err := nc.JetStream()
+			if err != nil {
+				t.Fatalf("failed to create JetStream context: %v", err)
+			}
+			if _, err := js.AddStream(&nats.StreamConfig{Name: "TEST", Subjects: []string{"test.>"}}); err != nil {
+				t.Fatalf("failed to add stream: %v", err)
+			}
+			if _, err := js.Publish("test.hello", []byte("world")); err != nil {
+				t.Fatalf("failed to publish: %v", err)
+			}
+			msg, err := js.GetLastMsg("TEST", "test.hello")
+			if err != nil {
+				t.Fatalf("failed to get message: %v", err)
+			}
+			if string(msg.Data) != "world" {
+				t.Errorf("expected %q, got %q", "world", msg.Data)
+			}
+		})
+	}
+}
@@ -9,7 +9,7 @@ type EventBus interface {
 
 	// Subscribe subscribes to events matching the filter.
 	// The handler is called for each event.
-	Subscribe(filter EventFilter, handler EventHandler) (Subscription, error)
+	Subscribe(filter EventFilter, handler EventHandler, opts ...SubscribeOption) (Subscription, error)
 
 	// Close closes the event bus and releases resources.
 	Close() error
@@ -25,6 +25,11 @@ type EventFilter struct {
 
 	// FromPosition starts consuming from this position (0 = from beginning)
 	FromPosition int64
+
+	// TenantID filters by tenant (empty = all tenants). An event's tenant is
+	// its TenantID metadata, or else the tenant prefix of its aggregate ID
+	// (see domain.EventTenantID).
+	TenantID string
 }
 
 // EventHandler processes an event.
@@ -36,3 +41,98 @@ type Subscription interface {
 	// Unsubscribe stops receiving events and cleans up resources.
 	Unsubscribe() error
 }
+
+// SubscribeOptions configures a subscription.
+type SubscribeOptions struct {
+	// MaxInFlight bounds the number of events received but not yet handled
+	// (0 = unbounded). When the limit is reached the subscription stops pulling
+	// events from the bus until the handler catches up.
+	MaxInFlight int
+
+	// Partition restricts the subscription to one partition of a partitioned
+	// bus (nil = all partitions)
+	Partition *int
+
+	// ConsumerGroup shares the subscription's events with the other
+	// subscriptions of the same group, in this process or another: each event
+	// goes to one member of the group ("" = every event goes to this
+	// subscription). Buses without consumer groups reject it.
+	ConsumerGroup string
+
+	// Durable names the subscription's progress on the bus, so a later
+	// subscription with the same name, e.g. after a restart, resumes after
+	// the last event the previous one handled ("" = not durable). Buses
+	// without durable subscriptions ignore it.
+	Durable string
+}
+
+// SubscribeOption configures a subscription.
+type SubscribeOption func(*SubscribeOptions)
+
+// WithMaxInFlight limits the number of events buffered for a slow handler.
+// Use it for subscribers that may not keep up with the publish rate, such as
+// projections, so a burst of events cannot grow memory without bound.
+func WithMaxInFlight(n int) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.MaxInFlight = n
+	}
+}
+
+// WithPartition subscribes to a single partition of a bus configured with a
+// partitioner. Events of one partition are delivered in order, so running one
+// subscriber per partition processes partitions in parallel without
+// reordering any aggregate's events.
+func WithPartition(partition int) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.Partition = &partition
+	}
+}
+
+// WithConsumerGroup joins a consumer group, so that processes subscribing
+// with the same group split the events between them instead of each handling
+// all of them. Use it to scale a consumer out across instances.
+func WithConsumerGroup(group string) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.ConsumerGroup = group
+	}
+}
+
+// WithDurable makes the subscription durable under name, on buses that
+// support it: events it handled are not delivered again to a subscription
+// with the same name, while events it had not handled when it stopped are.
+func WithDurable(name string) SubscribeOption {
+	return func(o *SubscribeOptions) {
+		o.Durable = name
+	}
+}
+
+// NewSubscribeOptions applies opts to the default subscribe options.
+func NewSubscribeOptions(opts ...SubscribeOption) SubscribeOptions {
+	var options SubscribeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// SubscriptionStats describes the flow control state of a subscription.
+type SubscriptionStats struct {
+	// InFlight is the number of events received but not yet handled
+	InFlight int
+
+	// MaxInFlight is the configured limit (0 = unbounded)
+	MaxInFlight int
+
+	// Paused is true while the subscription has stopped pulling events
+	// because InFlight reached MaxInFlight
+	Paused bool
+
+	// Pauses counts how often the subscription paused
+	Pauses int64
+}
+
+// StatsReporter is implemented by subscriptions that expose flow control
+// statistics, e.g., to export the in-flight depth as a metric.
+type StatsReporter interface {
+	Stats() SubscriptionStats
+}
@@ -0,0 +1,33 @@
+package kafka
+
+import (
+	kafkaserver "github.com/plaenen/eventstore/pkg/infrastructure/kafka"
+)
+
+// NewEmbeddedEventBus starts an in-process Kafka cluster and returns an event
+// bus connected to it, for tests and local development without a broker.
+// config.Brokers is ignored; closing the bus shuts the cluster down.
+//
+// Example:
+//
+//	bus, err := kafka.NewEmbeddedEventBus(kafka.DefaultConfig())
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer bus.Close()
+func NewEmbeddedEventBus(config Config, opts ...kafkaserver.Option) (*EventBus, error) {
+	cluster, err := kafkaserver.StartEmbeddedCluster(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Brokers = cluster.Brokers()
+	bus, err := NewEventBus(config)
+	if err != nil {
+		cluster.Shutdown()
+		return nil, err
+	}
+	bus.onClose = cluster.Shutdown
+
+	return bus, nil
+}
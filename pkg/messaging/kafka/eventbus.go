@@ -0,0 +1,362 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/messaging"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// EventBus is a Kafka-based implementation of messaging.EventBus.
+//
+// Events are published to one topic per aggregate type:
+//
+//	<TopicPrefix>.<AggregateType>
+//
+// keyed by aggregate ID, so all events of an aggregate go to the same
+// partition and are consumed in the order they were published. Delivery is
+// at-least-once.
+type EventBus struct {
+	client  *kgo.Client // Producer, shared by all publishes
+	config  Config
+	mu      sync.Mutex
+	subs    map[*subscription]struct{}
+	onClose func() // Stops the embedded cluster of NewEmbeddedEventBus, if any
+}
+
+// Config holds configuration for the Kafka event bus.
+type Config struct {
+	// Brokers are the seed brokers to connect to
+	Brokers []string
+
+	// ClientOptions are extra options for every Kafka client the bus creates,
+	// e.g. TLS or SASL. They are applied last and override the bus's own.
+	ClientOptions []kgo.Opt
+
+	// TopicPrefix is the first part of every topic name (default: "events").
+	// Services sharing a Kafka cluster should each use their own prefix.
+	TopicPrefix string
+
+	// PublishTimeout bounds how long Publish waits for the brokers to
+	// acknowledge the events (default: 30 seconds)
+	PublishTimeout time.Duration
+
+	// RetryBackoff is how long a subscription waits before handing an event
+	// to the handler again after the handler failed (default: 1 second)
+	RetryBackoff time.Duration
+
+	// TopicRefreshInterval is how often subscriptions to all aggregate types
+	// look for new topics, i.e. aggregate types published for the first time
+	// (default: 10 seconds). Events published to a new topic before it is
+	// found are not lost, only delivered late.
+	TopicRefreshInterval time.Duration
+}
+
+// DefaultConfig returns sensible defaults for the Kafka event bus.
+func DefaultConfig() Config {
+	return Config{
+		Brokers:              []string{"localhost:9092"},
+		TopicPrefix:          "events",
+		PublishTimeout:       30 * time.Second,
+		RetryBackoff:         time.Second,
+		TopicRefreshInterval: 10 * time.Second,
+	}
+}
+
+// NewEventBus creates a new Kafka-based event bus. Topics are created on
+// first publish, so the brokers must allow automatic topic creation, or
+// the topics of every aggregate type must be created beforehand.
+func NewEventBus(config Config) (*EventBus, error) {
+	defaults := DefaultConfig()
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("no Kafka brokers configured")
+	}
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = defaults.TopicPrefix
+	}
+	if config.PublishTimeout <= 0 {
+		config.PublishTimeout = defaults.PublishTimeout
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = defaults.RetryBackoff
+	}
+	if config.TopicRefreshInterval <= 0 {
+		config.TopicRefreshInterval = defaults.TopicRefreshInterval
+	}
+
+	bus := &EventBus{
+		config: config,
+		subs:   make(map[*subscription]struct{}),
+	}
+
+	client, err := kgo.NewClient(bus.clientOptions(kgo.AllowAutoTopicCreation())...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka client: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.PublishTimeout)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Kafka: %w", err)
+	}
+	bus.client = client
+
+	return bus, nil
+}
+
+// clientOptions returns the options of a client of the bus, with opts
+// applied before Config.ClientOptions.
+func (b *EventBus) clientOptions(opts ...kgo.Opt) []kgo.Opt {
+	refresh := b.config.TopicRefreshInterval
+	options := []kgo.Opt{
+		kgo.SeedBrokers(b.config.Brokers...),
+		kgo.MetadataMaxAge(refresh),
+		kgo.MetadataMinAge(min(refresh, 5*time.Second)),
+	}
+	options = append(options, opts...)
+	return append(options, b.config.ClientOptions...)
+}
+
+// Publish publishes events to Kafka and waits until the brokers stored all
+// of them. Events of the same aggregate keep their order.
+func (b *EventBus) Publish(events []*domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	records := make([]*kgo.Record, len(events))
+	for i, event := range events {
+		eventJSON, err := b.serializeEvent(event)
+		if err != nil {
+			return fmt.Errorf("failed to serialize event %s: %w", event.ID, err)
+		}
+		records[i] = &kgo.Record{
+			Topic: b.topic(event.AggregateType),
+			Key:   []byte(event.AggregateID),
+			Value: eventJSON,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.PublishTimeout)
+	defer cancel()
+	if err := b.client.ProduceSync(ctx, records...).FirstErr(); err != nil {
+		return fmt.Errorf("failed to publish events: %w", err)
+	}
+	return nil
+}
+
+// Subscribe subscribes to events matching the filter.
+//
+// The subscription consumes the topics of filter.AggregateTypes, or every
+// topic of the bus if there are none; event types are filtered in the
+// subscription. A new subscription starts at the oldest event the brokers
+// retain. Tenants (filter.TenantID) are filtered in the subscription too.
+//
+// With messaging.WithConsumerGroup, subscriptions of the same group split the
+// topics' partitions between them, and an event is marked consumed once its
+// handler succeeds: a restarted member resumes after the last consumed event.
+// Without a group, every subscription receives every event.
+//
+// A failing handler gets the event again after Config.RetryBackoff, until it
+// succeeds or the subscription ends; later events of the same partition wait
+// meanwhile, so an aggregate's events are never handled out of order. Records
+// that are not events of this bus are skipped.
+func (b *EventBus) Subscribe(filter messaging.EventFilter, handler messaging.EventHandler, opts ...messaging.SubscribeOption) (messaging.Subscription, error) {
+	options := messaging.NewSubscribeOptions(opts...)
+	if options.Partition != nil {
+		return nil, fmt.Errorf("cannot subscribe to partition %d: Kafka assigns partitions to consumer group members (use messaging.WithConsumerGroup)", *options.Partition)
+	}
+
+	consumerOpts := []kgo.Opt{kgo.ConsumeResetOffset(kgo.NewOffset().AtStart())}
+	if len(filter.AggregateTypes) == 0 {
+		consumerOpts = append(consumerOpts, kgo.ConsumeRegex(), kgo.ConsumeTopics("^"+regexp.QuoteMeta(b.config.TopicPrefix+".")))
+	} else {
+		topics := make([]string, len(filter.AggregateTypes))
+		for i, aggregateType := range filter.AggregateTypes {
+			topics[i] = b.topic(aggregateType)
+		}
+		consumerOpts = append(consumerOpts, kgo.ConsumeTopics(topics...))
+	}
+	if options.ConsumerGroup != "" {
+		consumerOpts = append(consumerOpts,
+			kgo.ConsumerGroup(b.groupID(options.ConsumerGroup)),
+			kgo.AutoCommitMarks(),
+			kgo.BlockRebalanceOnPoll(),
+		)
+	}
+
+	client, err := kgo.NewClient(b.clientOptions(consumerOpts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := &subscription{
+		bus:          b,
+		client:       client,
+		handler:      handler,
+		eventTypes:   filter.EventTypes,
+		tenantID:     filter.TenantID,
+		group:        options.ConsumerGroup != "",
+		maxInFlight:  options.MaxInFlight,
+		retryBackoff: b.config.RetryBackoff,
+		cancel:       cancel,
+		done:         make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go sub.run(ctx)
+	return sub, nil
+}
+
+// topic returns the topic of an aggregate type. Characters Kafka does not
+// allow in topic names are replaced with underscores.
+func (b *EventBus) topic(aggregateType string) string {
+	return b.config.TopicPrefix + "." + invalidTopicChars.ReplaceAllString(aggregateType, "_")
+}
+
+var invalidTopicChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// groupID returns the Kafka consumer group ID of a subscription group.
+func (b *EventBus) groupID(group string) string {
+	return b.config.TopicPrefix + "." + group
+}
+
+// serializeEvent serializes an event to JSON.
+func (b *EventBus) serializeEvent(event *domain.Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// deserializeEvent deserializes an event from JSON.
+func (b *EventBus) deserializeEvent(data []byte) (*domain.Event, error) {
+	var event domain.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Close ends all subscriptions, waiting for their running handlers, and
+// closes the producer once buffered events are flushed.
+func (b *EventBus) Close() error {
+	b.mu.Lock()
+	subs := make([]*subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+	b.client.Close()
+
+	if b.onClose != nil {
+		b.onClose()
+	}
+	return nil
+}
+
+// subscription implements messaging.Subscription with its own Kafka client.
+type subscription struct {
+	bus          *EventBus
+	client       *kgo.Client
+	handler      messaging.EventHandler
+	eventTypes   []string
+	tenantID     string
+	group        bool
+	maxInFlight  int // Records taken per poll; 0 = all fetched
+	retryBackoff time.Duration
+	cancel       context.CancelFunc
+	done         chan struct{} // Closed when run returns
+	closeOnce    sync.Once
+}
+
+// run polls records and hands their events to the handler until ctx is
+// canceled. In a group, records are marked for commit once handled and the
+// group may only rebalance between polls, so a revoked partition never has
+// an event in progress.
+func (s *subscription) run(ctx context.Context) {
+	defer close(s.done)
+
+	for {
+		fetches := s.client.PollRecords(ctx, s.maxInFlight)
+		if fetches.IsClientClosed() || ctx.Err() != nil {
+			return
+		}
+
+		iter := fetches.RecordIter()
+		for !iter.Done() {
+			record := iter.Next()
+			if !s.handle(ctx, record) {
+				return
+			}
+			if s.group {
+				s.client.MarkCommitRecords(record)
+			}
+		}
+		if s.group {
+			s.client.AllowRebalance()
+		}
+	}
+}
+
+// handle passes the event of a record to the handler until it succeeds. It
+// returns false if ctx was canceled first.
+func (s *subscription) handle(ctx context.Context, record *kgo.Record) bool {
+	event, err := s.bus.deserializeEvent(record.Value)
+	if err != nil {
+		// Not an event; it would fail again on every delivery
+		return true
+	}
+	if len(s.eventTypes) > 0 && !slices.Contains(s.eventTypes, event.EventType) {
+		return true
+	}
+	if s.tenantID != "" && domain.EventTenantID(event) != s.tenantID {
+		return true
+	}
+
+	// Create event envelope (payload will be deserialized by handler if needed)
+	envelope := &domain.EventEnvelope{
+		Event: *event,
+	}
+
+	for {
+		if err := s.handler(envelope); err == nil {
+			return true
+		}
+
+		select {
+		case <-time.After(s.retryBackoff):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Unsubscribe stops the subscription after the running handler returns. In
+// a group, handled events are committed and the group is left, so the
+// remaining members take over the subscription's partitions.
+func (s *subscription) Unsubscribe() error {
+	s.closeOnce.Do(func() {
+		s.cancel()
+		<-s.done
+		s.client.CloseAllowingRebalance()
+
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+	})
+	return nil
+}
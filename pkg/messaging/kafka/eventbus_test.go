@@ -0,0 +1,353 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	kafkaserver "github.com/plaenen/eventstore/pkg/infrastructure/kafka"
+	"github.com/plaenen/eventstore/pkg/messaging"
+	"github.com/plaenen/eventstore/pkg/messaging/kafka"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func testEvent(id, aggregateType, aggregateID, eventType string, version int64) *domain.Event {
+	return &domain.Event{
+		ID:            id,
+		AggregateID:   aggregateID,
+		AggregateType: aggregateType,
+		EventType:     eventType,
+		Version:       version,
+		Timestamp:     time.Now(),
+		Data:          []byte("test data"),
+		Metadata: domain.EventMetadata{
+			PrincipalID: "test-user",
+		},
+	}
+}
+
+func testConfig() kafka.Config {
+	config := kafka.DefaultConfig()
+	config.RetryBackoff = 10 * time.Millisecond
+	config.TopicRefreshInterval = 100 * time.Millisecond
+	return config
+}
+
+func TestEmbeddedKafkaEventBus(t *testing.T) {
+	bus, err := kafka.NewEmbeddedEventBus(testConfig())
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+	defer bus.Close()
+
+	t.Run("PublishAndSubscribe", func(t *testing.T) {
+		received := make(chan *domain.Event, 10)
+		sub, err := bus.Subscribe(messaging.EventFilter{
+			AggregateTypes: []string{"TestAggregate"},
+			EventTypes:     []string{"test.Created"},
+		}, func(envelope *domain.EventEnvelope) error {
+			received <- &envelope.Event
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		defer sub.Unsubscribe()
+
+		err = bus.Publish([]*domain.Event{
+			testEvent("other-1", "OtherAggregate", "other-1", "test.Created", 1),
+			testEvent("test-2", "TestAggregate", "agg-1", "test.Renamed", 2),
+			testEvent("test-1", "TestAggregate", "agg-1", "test.Created", 1),
+		})
+		if err != nil {
+			t.Fatalf("failed to publish events: %v", err)
+		}
+
+		select {
+		case evt := <-received:
+			if evt.ID != "test-1" || evt.AggregateID != "agg-1" || evt.Metadata.PrincipalID != "test-user" {
+				t.Errorf("unexpected event %+v", evt)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+		select {
+		case evt := <-received:
+			t.Errorf("expected filtered events to be skipped, got %s", evt.ID)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("AllAggregateTypes", func(t *testing.T) {
+		received := make(chan string, 10)
+		sub, err := bus.Subscribe(messaging.EventFilter{}, func(envelope *domain.EventEnvelope) error {
+			if envelope.Event.AggregateType == "NewAggregate" {
+				received <- envelope.Event.ID
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		defer sub.Unsubscribe()
+
+		// A topic created after subscribing is picked up too
+		if err := bus.Publish([]*domain.Event{testEvent("new-1", "NewAggregate", "new-1", "test.Created", 1)}); err != nil {
+			t.Fatalf("failed to publish event: %v", err)
+		}
+
+		select {
+		case id := <-received:
+			if id != "new-1" {
+				t.Errorf("expected new-1, got %s", id)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+	})
+
+	t.Run("PerAggregateOrdering", func(t *testing.T) {
+		const versions = 50
+		var (
+			mu   sync.Mutex
+			seen = map[string][]int64{}
+			done = make(chan struct{})
+		)
+		sub, err := bus.Subscribe(messaging.EventFilter{
+			AggregateTypes: []string{"OrderedAggregate"},
+		}, func(envelope *domain.EventEnvelope) error {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[envelope.Event.AggregateID] = append(seen[envelope.Event.AggregateID], envelope.Event.Version)
+			if len(seen["ord-1"]) == versions && len(seen["ord-2"]) == versions {
+				close(done)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		defer sub.Unsubscribe()
+
+		var events []*domain.Event
+		for v := int64(1); v <= versions; v++ {
+			for _, id := range []string{"ord-1", "ord-2"} {
+				events = append(events, testEvent(fmt.Sprintf("%s-%d", id, v), "OrderedAggregate", id, "test.Changed", v))
+			}
+		}
+		if err := bus.Publish(events); err != nil {
+			t.Fatalf("failed to publish events: %v", err)
+		}
+
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for events")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for id, got := range seen {
+			for i, v := range got {
+				if v != int64(i+1) {
+					t.Fatalf("events of %s out of order: %v", id, got)
+				}
+			}
+		}
+	})
+
+	t.Run("HandlerErrorRetries", func(t *testing.T) {
+		var attempts int
+		received := make(chan int, 1)
+		sub, err := bus.Subscribe(messaging.EventFilter{
+			AggregateTypes: []string{"RetryAggregate"},
+		}, func(envelope *domain.EventEnvelope) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("temporary failure")
+			}
+			received <- attempts
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		defer sub.Unsubscribe()
+
+		if err := bus.Publish([]*domain.Event{testEvent("retry-1", "RetryAggregate", "retry-1", "test.Created", 1)}); err != nil {
+			t.Fatalf("failed to publish event: %v", err)
+		}
+
+		select {
+		case n := <-received:
+			if n != 3 {
+				t.Errorf("expected the event to succeed on attempt 3, got %d", n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+	})
+
+	t.Run("PartitionRejected", func(t *testing.T) {
+		_, err := bus.Subscribe(messaging.EventFilter{}, func(*domain.EventEnvelope) error { return nil }, messaging.WithPartition(0))
+		if err == nil {
+			t.Fatal("expected subscribing to a partition to fail")
+		}
+	})
+}
+
+func TestKafkaConsumerGroup(t *testing.T) {
+	cluster, err := kafkaserver.StartEmbeddedCluster()
+	if err != nil {
+		t.Fatalf("failed to start embedded cluster: %v", err)
+	}
+	defer cluster.Shutdown()
+
+	// Two instances of one service, each with its own bus
+	var (
+		mu       sync.Mutex
+		handled  = map[string]int{} // Event ID -> times handled
+		byMember = [2]int{}
+	)
+	for member := range 2 {
+		config := testConfig()
+		config.Brokers = cluster.Brokers()
+		bus, err := kafka.NewEventBus(config)
+		if err != nil {
+			t.Fatalf("failed to create event bus: %v", err)
+		}
+		defer bus.Close()
+
+		_, err = bus.Subscribe(messaging.EventFilter{
+			AggregateTypes: []string{"GroupAggregate"},
+		}, func(envelope *domain.EventEnvelope) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled[envelope.Event.ID]++
+			byMember[member]++
+			return nil
+		}, messaging.WithConsumerGroup("balances"))
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+	}
+
+	config := testConfig()
+	config.Brokers = cluster.Brokers()
+	publisher, err := kafka.NewEventBus(config)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+	defer publisher.Close()
+
+	// Publish until both members got events: until the group has balanced,
+	// one member may hold all partitions
+	published := 0
+	deadline := time.Now().Add(15 * time.Second)
+	for {
+		var events []*domain.Event
+		for i := range 20 {
+			id := fmt.Sprintf("grp-%d", published+i)
+			events = append(events, testEvent(id, "GroupAggregate", fmt.Sprintf("agg-%d", i), "test.Created", 1))
+		}
+		if err := publisher.Publish(events); err != nil {
+			t.Fatalf("failed to publish events: %v", err)
+		}
+		published += len(events)
+		time.Sleep(200 * time.Millisecond)
+
+		mu.Lock()
+		balanced := byMember[0] > 0 && byMember[1] > 0
+		mu.Unlock()
+		if balanced {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected both group members to handle events, got %v", byMember)
+		}
+	}
+
+	// Every event is handled, once unless redelivered around a rebalance
+	deadline = time.Now().Add(10 * time.Second)
+	for {
+		mu.Lock()
+		got := len(handled)
+		mu.Unlock()
+		if got == published {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d events handled, got %d", published, got)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// recordingProjection reports the ID of every event it handles.
+type recordingProjection struct {
+	name     string
+	received chan<- string
+}
+
+func (p *recordingProjection) Name() string { return p.name }
+
+func (p *recordingProjection) Handle(ctx context.Context, event *domain.EventEnvelope) error {
+	p.received <- event.Event.ID
+	return nil
+}
+
+func (p *recordingProjection) Reset(ctx context.Context) error { return nil }
+
+func TestProjectionManagerStartInGroup(t *testing.T) {
+	cluster, err := kafkaserver.StartEmbeddedCluster()
+	if err != nil {
+		t.Fatalf("failed to start embedded cluster: %v", err)
+	}
+	defer cluster.Shutdown()
+
+	config := testConfig()
+	config.Brokers = cluster.Brokers()
+	bus, err := kafka.NewEventBus(config)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+	defer bus.Close()
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpoints, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	received := make(chan string, 10)
+	projection := &recordingProjection{name: "balances", received: received}
+
+	manager := eventsourcing.NewProjectionManager(checkpoints, eventStore, bus)
+	manager.Register(projection)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := manager.StartInGroup(ctx, "balances"); err != nil {
+		t.Fatalf("failed to start projection: %v", err)
+	}
+
+	if err := bus.Publish([]*domain.Event{testEvent("proj-1", "Account", "acc-1", "test.Created", 1)}); err != nil {
+		t.Fatalf("failed to publish event: %v", err)
+	}
+
+	select {
+	case id := <-received:
+		if id != "proj-1" {
+			t.Errorf("expected proj-1, got %s", id)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for projection to handle event")
+	}
+}
@@ -0,0 +1,127 @@
+package nats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// ErrEventTooLarge is returned by Publish for an event that doesn't fit in one
+// message under the server's max payload.
+var ErrEventTooLarge = errors.New("event exceeds the NATS max payload")
+
+// headerBatchSize marks a message carrying a batch of events (a JSON array)
+// and holds the number of events in it.
+const headerBatchSize = "Events-Batch-Size"
+
+// batchOverhead is kept free in every message for the headers and the JSON
+// array framing, which count against the max payload too.
+const batchOverhead = 1024
+
+// publishBatched publishes events in batch messages of at most
+// MaxPublishBatchBytes (capped at the server's max payload). Only consecutive
+// events with the same subject share a batch, so subject filters keep working,
+// and batches are published one after another, preserving the order of events.
+func (b *EventBus) publishBatched(events []*domain.Event) error {
+	limit := min(int64(b.maxBatchBytes), b.nc.MaxPayload()) - batchOverhead
+
+	var (
+		subject string
+		batch   []*domain.Event
+		data    [][]byte
+		size    int64
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		defer func() { batch, data, size = batch[:0], data[:0], 0 }()
+
+		if len(batch) == 1 {
+			return b.publishOne(subject, batch[0], data[0])
+		}
+
+		first, last := batch[0], batch[len(batch)-1]
+		msg := nats.NewMsg(subject)
+		msg.Header.Set(headerBatchSize, strconv.Itoa(len(batch)))
+		msg.Data = append(append([]byte{'['}, bytes.Join(data, []byte{','})...), ']')
+
+		// The batch ID deduplicates a republished batch like a message ID
+		// deduplicates a single event
+		if _, err := b.js.PublishMsg(msg, nats.MsgId(first.ID+".."+last.ID)); err != nil {
+			return fmt.Errorf("failed to publish events %s to %s: %w", first.ID, last.ID, err)
+		}
+		return nil
+	}
+
+	for _, event := range events {
+		eventJSON, err := b.serializeEvent(event)
+		if err != nil {
+			return fmt.Errorf("failed to serialize event %s: %w", event.ID, err)
+		}
+		if err := b.checkPayload(event, eventJSON); err != nil {
+			return err
+		}
+
+		eventSubject := b.eventSubject(b.eventScope(event), event.AggregateType, event.EventType)
+		if eventSubject != subject || size+int64(len(eventJSON))+1 > limit {
+			if err := flush(); err != nil {
+				return err
+			}
+			subject = eventSubject
+		}
+
+		batch = append(batch, event)
+		data = append(data, eventJSON)
+		size += int64(len(eventJSON)) + 1
+	}
+	return flush()
+}
+
+// publishOne publishes a single event, deduplicated by its ID.
+func (b *EventBus) publishOne(subject string, event *domain.Event, eventJSON []byte) error {
+	if _, err := b.js.Publish(subject, eventJSON, nats.MsgId(event.ID)); err != nil {
+		return fmt.Errorf("failed to publish event %s: %w", event.ID, err)
+	}
+	return nil
+}
+
+// checkPayload fails with ErrEventTooLarge if eventJSON can't be published
+// under the server's max payload.
+func (b *EventBus) checkPayload(event *domain.Event, eventJSON []byte) error {
+	if maxPayload := b.nc.MaxPayload(); int64(len(eventJSON))+batchOverhead > maxPayload {
+		return fmt.Errorf("%w: event %s is %d bytes, max payload is %d bytes",
+			ErrEventTooLarge, event.ID, len(eventJSON), maxPayload)
+	}
+	return nil
+}
+
+// decodeMsg returns the events carried by a message: one, or a whole batch.
+func (b *EventBus) decodeMsg(msg *nats.Msg) ([]*domain.Event, error) {
+	if msg.Header.Get(headerBatchSize) == "" {
+		event, err := b.deserializeEvent(msg.Data)
+		if err != nil {
+			return nil, err
+		}
+		return []*domain.Event{event}, nil
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(msg.Data, &batch); err != nil {
+		return nil, err
+	}
+	events := make([]*domain.Event, len(batch))
+	for i, data := range batch {
+		event, err := b.deserializeEvent(data)
+		if err != nil {
+			return nil, err
+		}
+		events[i] = event
+	}
+	return events, nil
+}
@@ -0,0 +1,130 @@
+package nats_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"github.com/plaenen/eventstore/pkg/messaging"
+	natspkg "github.com/plaenen/eventstore/pkg/messaging/nats"
+)
+
+func TestPublishBatching(t *testing.T) {
+	const maxPayload = 64 * 1024
+
+	// In-process, so the stream starts empty on every run
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithMaxPayload(maxPayload), natsserver.WithInProcess())
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	config := natspkg.DefaultConfig()
+	config.URL = srv.URL()
+	config.ConnectOptions = srv.ConnectOptions()
+	config.StreamName = "BATCH_EVENTS"
+	config.SubjectPrefix = "batch"
+	config.MaxPublishBatchBytes = 1024 * 1024 // Capped at the server's 64KB
+	bus, err := natspkg.NewEventBus(config)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+	defer bus.Close()
+
+	// A bulk import of about 1MB, alternating between two aggregates every
+	// 100 events so batches are split by subject as well as by size
+	const total = 2000
+	events := make([]*domain.Event, total)
+	for i := range events {
+		aggregateType := "Order"
+		if i/100%2 == 1 {
+			aggregateType = "Invoice"
+		}
+		events[i] = &domain.Event{
+			ID:            fmt.Sprintf("import-%04d", i),
+			AggregateID:   aggregateType + "-1",
+			AggregateType: aggregateType,
+			EventType:     "Imported",
+			Version:       int64(i + 1),
+			Timestamp:     time.Now(),
+			Data:          []byte(strings.Repeat("x", 256)),
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	allReceived := make(chan struct{})
+	sub, err := bus.Subscribe(messaging.EventFilter{}, func(envelope *domain.EventEnvelope) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, envelope.Event.ID)
+		if len(received) == total {
+			close(allReceived)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if err := bus.Publish(events); err != nil {
+		t.Fatalf("failed to publish batch: %v", err)
+	}
+
+	select {
+	case <-allReceived:
+	case <-time.After(10 * time.Second):
+		mu.Lock()
+		defer mu.Unlock()
+		t.Fatalf("timeout: received %d of %d events", len(received), total)
+	}
+
+	mu.Lock()
+	for i, id := range received {
+		if id != events[i].ID {
+			t.Fatalf("event %d: expected %s, got %s", i, events[i].ID, id)
+		}
+	}
+	mu.Unlock()
+
+	// Far fewer messages than events, but more than the 1MB fits in one
+	nc, err := natsserver.ConnectToEmbedded(srv)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer nc.Close()
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("failed to create JetStream context: %v", err)
+	}
+	info, err := js.StreamInfo("BATCH_EVENTS")
+	if err != nil {
+		t.Fatalf("failed to get stream info: %v", err)
+	}
+	if msgs := info.State.LastSeq; msgs < 20 || msgs > 100 {
+		t.Errorf("expected 20-100 batch messages, got %d", msgs)
+	}
+
+	t.Run("EventTooLarge", func(t *testing.T) {
+		err := bus.Publish([]*domain.Event{{
+			ID:            "huge",
+			AggregateID:   "Order-2",
+			AggregateType: "Order",
+			EventType:     "Imported",
+			Version:       1,
+			Timestamp:     time.Now(),
+			Data:          make([]byte, maxPayload),
+		}})
+		if !errors.Is(err, natspkg.ErrEventTooLarge) {
+			t.Errorf("expected ErrEventTooLarge, got %v", err)
+		}
+	})
+}
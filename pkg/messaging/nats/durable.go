@@ -0,0 +1,58 @@
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/nats-io/nats.go"
+)
+
+// invalidConsumerChars matches characters NATS does not allow in consumer names.
+var invalidConsumerChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// durableConsumerName returns the consumer name of a durable subscription.
+func durableConsumerName(name string) string {
+	return "durable_" + invalidConsumerChars.ReplaceAllString(name, "_")
+}
+
+// ensureDurableConsumer creates the kept consumer of a durable subscription,
+// or checks that an existing one consumes the same subject the same way. A
+// push consumer delivers to a queue group named after it, so subscriptions
+// sharing it split the events; a pull consumer (maxInFlight > 0) bounds
+// unacked events to maxInFlight.
+func (b *EventBus) ensureDurableConsumer(subject, consumerName string, maxInFlight int) error {
+	info, err := b.js.ConsumerInfo(b.streamName, consumerName)
+	if errors.Is(err, nats.ErrConsumerNotFound) {
+		config := &nats.ConsumerConfig{
+			Durable:       consumerName,
+			FilterSubject: subject,
+			DeliverPolicy: nats.DeliverAllPolicy,
+			AckPolicy:     nats.AckExplicitPolicy,
+			AckWait:       b.ackWait,
+		}
+		if maxInFlight > 0 {
+			config.MaxAckPending = maxInFlight
+		} else {
+			config.DeliverSubject = nats.NewInbox()
+			config.DeliverGroup = consumerName
+			config.MaxAckPending = b.maxAckPending
+		}
+		if _, err := b.js.AddConsumer(b.streamName, config); err != nil {
+			return fmt.Errorf("failed to create durable consumer %s: %w", consumerName, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up durable consumer %s: %w", consumerName, err)
+	}
+
+	if info.Config.FilterSubject != subject {
+		return fmt.Errorf("durable consumer %s is incompatible: subject is %s, want %s",
+			consumerName, info.Config.FilterSubject, subject)
+	}
+	if pull := info.Config.DeliverSubject == ""; pull != (maxInFlight > 0) {
+		return fmt.Errorf("durable consumer %s is incompatible: it was created with a different messaging.WithMaxInFlight setting", consumerName)
+	}
+	return nil
+}
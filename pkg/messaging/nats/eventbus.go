@@ -1,14 +1,22 @@
 package nats
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
 	"github.com/plaenen/eventstore/pkg/messaging"
+	"github.com/plaenen/eventstore/pkg/observability"
+	"go.opentelemetry.io/otel/metric"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
@@ -17,22 +25,58 @@ import (
 // EventBus is a NATS-based implementation of domain.EventBus.
 // Uses JetStream for durable event streaming with at-least-once delivery.
 type EventBus struct {
-	nc         *nats.Conn
-	js         nats.JetStreamContext
-	streamName string
-	mu         sync.RWMutex
-	subs       map[string]*nats.Subscription
+	nc            *nats.Conn
+	js            nats.JetStreamContext
+	streamName    string
+	prefix        string
+	partitioner   eventsourcing.Partitioner // nil = unpartitioned subjects
+	tenants       bool                      // Tenant token in subjects
+	maxBatchBytes int                       // 0 = one message per event
+	durable       bool
+	ackWait       time.Duration
+	maxInFlight   int // Default messaging.WithMaxInFlight limit
+	maxAckPending int
+	publishBuffer *publishBuffer
+	metrics       metric.Registration // nil without Config.Telemetry
+	mu            sync.RWMutex
+	subs          map[string]*nats.Subscription
+	flow          map[string]*flowControlledSubscription
+	closed        chan struct{} // Closed once the NATS connection is fully closed
 }
 
 // Config holds configuration for the NATS event bus.
+//
+// Events are published on subjects laid out as:
+//
+//	<SubjectPrefix>.<AggregateType>.<EventType>
+//
+// or, with a Partitioner, as
+//
+//	<SubjectPrefix>.<Partition>.<AggregateType>.<EventType>
+//
+// TenantSubjects adds the tenant after the partition, if any:
+//
+//	<SubjectPrefix>.<Tenant>.<AggregateType>.<EventType>
+//
+// Services sharing a NATS deployment should each use their own SubjectPrefix
+// and StreamName so their streams do not overlap.
 type Config struct {
 	// URL is the NATS server URL
 	URL string
 
+	// ConnectOptions are extra options for the NATS connection, e.g. the
+	// in-process connection of an embedded server
+	// (EmbeddedServer.ConnectOptions)
+	ConnectOptions []nats.Option
+
 	// StreamName is the JetStream stream name for events
 	StreamName string
 
-	// StreamSubjects are the subjects to publish events to (default: "events.*")
+	// SubjectPrefix is the first token of every event subject (default: "events")
+	SubjectPrefix string
+
+	// StreamSubjects are the subjects captured by the stream
+	// (default: "<SubjectPrefix>.>")
 	StreamSubjects []string
 
 	// MaxAge is how long to retain events in the stream
@@ -40,23 +84,101 @@ type Config struct {
 
 	// MaxBytes is the maximum bytes the stream can store
 	MaxBytes int64
+
+	// Partitioner adds the event's partition to its subject so subscribers can
+	// consume one partition each (messaging.WithPartition). Use the same
+	// partitioner everywhere work is split, and drain the stream before
+	// changing it: events already published keep their old partition.
+	Partitioner eventsourcing.Partitioner
+
+	// TenantSubjects adds the tenant of each event to its subject, so a
+	// subscription filtered to one tenant (EventFilter.TenantID) receives no
+	// other tenant's events. Events without a tenant use the token "_", and
+	// characters not allowed in a subject token are replaced with "_". As
+	// with Partitioner, drain the stream before changing it.
+	TenantSubjects bool
+
+	// MaxPublishBatchBytes packs consecutive events published on the same
+	// subject into batch messages of up to this many bytes, so bulk publishes
+	// (e.g., an import of thousands of events) take a fraction of the
+	// JetStream round trips. The size is capped at the server's max payload.
+	// Subscribers unpack batches and handle their events in order; a batch is
+	// acked once all its events are handled and redelivered whole otherwise.
+	// 0 publishes one message per event.
+	MaxPublishBatchBytes int
+
+	// Durable keeps the consumers of subscriptions named with
+	// messaging.WithDurable on the server, under that name, when they
+	// unsubscribe or the process stops. Subscribing again with the name
+	// resumes after the last acked event: events acked before are not
+	// redelivered, those in flight or not yet delivered are. Subscriptions
+	// sharing the name share the consumer, each event going to one of them.
+	// Without Durable, every subscription gets a consumer of its own that is
+	// deleted on Unsubscribe.
+	Durable bool
+
+	// AckWait is how long the server waits for a delivered event to be acked
+	// before delivering it again (default: 30 seconds)
+	AckWait time.Duration
+
+	// MaxInFlight is the messaging.WithMaxInFlight limit of subscriptions
+	// that do not set one (0 = unbounded), so every subscriber of the bus
+	// holds at most this many events in memory.
+	MaxInFlight int
+
+	// MaxAckPending caps the events the server delivers to a push
+	// subscription (one without a max in-flight limit) before the handler
+	// acks them; further events wait on the server (0 = server default).
+	MaxAckPending int
+
+	// PublishBufferSize is the number of events PublishAsync and
+	// PublishWithBackpressure buffer before publishing them (default: 1000)
+	PublishBufferSize int
+
+	// OnPublishError is called with the events a PublishAsync or
+	// PublishWithBackpressure call failed to publish, and the error. The
+	// events are dropped otherwise.
+	OnPublishError func(events []*domain.Event, err error)
+
+	// Telemetry exports the in-flight events of every subscription and the
+	// publish buffer depth as observable gauges (optional)
+	Telemetry *observability.Telemetry
 }
 
 // DefaultConfig returns sensible defaults for NATS event bus.
 func DefaultConfig() Config {
 	return Config{
-		URL:            nats.DefaultURL,
-		StreamName:     "EVENTS",
-		StreamSubjects: []string{"events.>"},
-		MaxAge:         7 * 24 * time.Hour, // 7 days
-		MaxBytes:       1024 * 1024 * 1024, // 1 GB
+		URL:               nats.DefaultURL,
+		StreamName:        "EVENTS",
+		SubjectPrefix:     "events",
+		MaxAge:            7 * 24 * time.Hour, // 7 days
+		MaxBytes:          1024 * 1024 * 1024, // 1 GB
+		AckWait:           30 * time.Second,
+		PublishBufferSize: 1000,
 	}
 }
 
 // NewEventBus creates a new NATS-based event bus.
 func NewEventBus(config Config) (*EventBus, error) {
+	if config.SubjectPrefix == "" {
+		config.SubjectPrefix = "events"
+	}
+	if len(config.StreamSubjects) == 0 {
+		config.StreamSubjects = []string{config.SubjectPrefix + ".>"}
+	}
+	if config.AckWait <= 0 {
+		config.AckWait = 30 * time.Second
+	}
+	if config.PublishBufferSize <= 0 {
+		config.PublishBufferSize = 1000
+	}
+
 	// Connect to NATS
-	nc, err := nats.Connect(config.URL)
+	closed := make(chan struct{})
+	opts := append([]nats.Option{nats.ClosedHandler(func(*nats.Conn) {
+		close(closed)
+	})}, config.ConnectOptions...)
+	nc, err := nats.Connect(config.URL, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
@@ -69,10 +191,20 @@ func NewEventBus(config Config) (*EventBus, error) {
 	}
 
 	bus := &EventBus{
-		nc:         nc,
-		js:         js,
-		streamName: config.StreamName,
-		subs:       make(map[string]*nats.Subscription),
+		nc:            nc,
+		js:            js,
+		streamName:    config.StreamName,
+		prefix:        config.SubjectPrefix,
+		partitioner:   config.Partitioner,
+		tenants:       config.TenantSubjects,
+		maxBatchBytes: config.MaxPublishBatchBytes,
+		durable:       config.Durable,
+		ackWait:       config.AckWait,
+		maxInFlight:   config.MaxInFlight,
+		maxAckPending: config.MaxAckPending,
+		subs:          make(map[string]*nats.Subscription),
+		flow:          make(map[string]*flowControlledSubscription),
+		closed:        closed,
 	}
 
 	// Create or update stream
@@ -80,11 +212,24 @@ func NewEventBus(config Config) (*EventBus, error) {
 		nc.Close()
 		return nil, fmt.Errorf("failed to ensure stream: %w", err)
 	}
+	bus.publishBuffer = startPublishBuffer(config.PublishBufferSize, config.OnPublishError, bus.Publish)
+
+	if config.Telemetry != nil {
+		bus.metrics, err = bus.registerMetrics(config.Telemetry.Meter("eventsourcing"))
+		if err != nil {
+			bus.Close()
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+	}
 
 	return bus, nil
 }
 
-// ensureStream creates or updates the JetStream stream.
+// ensureStream creates the JetStream stream, or reconciles an existing one.
+//
+// An existing stream is never deleted. Limits (MaxAge, MaxBytes) are updated in
+// place; a stream whose subjects, storage or retention differ is reported as
+// incompatible, since it most likely belongs to another application.
 func (b *EventBus) ensureStream(config Config) error {
 	streamConfig := &nats.StreamConfig{
 		Name:      config.StreamName,
@@ -98,7 +243,7 @@ func (b *EventBus) ensureStream(config Config) error {
 
 	// Try to get existing stream
 	stream, err := b.js.StreamInfo(config.StreamName)
-	if err != nil {
+	if errors.Is(err, nats.ErrStreamNotFound) {
 		// Stream doesn't exist, create it
 		_, err = b.js.AddStream(streamConfig)
 		if err != nil {
@@ -106,6 +251,13 @@ func (b *EventBus) ensureStream(config Config) error {
 		}
 		return nil
 	}
+	if err != nil {
+		return fmt.Errorf("failed to look up stream %s: %w", config.StreamName, err)
+	}
+
+	if err := checkStreamCompatible(stream.Config, *streamConfig); err != nil {
+		return err
+	}
 
 	// Update existing stream if needed
 	if stream.Config.MaxAge != config.MaxAge || stream.Config.MaxBytes != config.MaxBytes {
@@ -118,7 +270,33 @@ func (b *EventBus) ensureStream(config Config) error {
 	return nil
 }
 
-// Publish publishes events to NATS JetStream.
+// checkStreamCompatible reports whether an existing stream can be reused as-is
+// (apart from its limits) for the desired configuration.
+func checkStreamCompatible(existing, desired nats.StreamConfig) error {
+	if existing.Storage != desired.Storage {
+		return fmt.Errorf("stream %s is incompatible: storage is %s, want %s",
+			desired.Name, existing.Storage, desired.Storage)
+	}
+	if existing.Retention != desired.Retention {
+		return fmt.Errorf("stream %s is incompatible: retention is %s, want %s",
+			desired.Name, existing.Retention, desired.Retention)
+	}
+	if !slices.Equal(sortedCopy(existing.Subjects), sortedCopy(desired.Subjects)) {
+		return fmt.Errorf("stream %s is incompatible: subjects are %v, want %v (use a different StreamName or SubjectPrefix)",
+			desired.Name, existing.Subjects, desired.Subjects)
+	}
+	return nil
+}
+
+func sortedCopy(values []string) []string {
+	out := slices.Clone(values)
+	slices.Sort(out)
+	return out
+}
+
+// Publish publishes events to NATS JetStream, in order. Events are published
+// one message each, or in batches with Config.MaxPublishBatchBytes. An event
+// too large for the server's max payload fails with ErrEventTooLarge.
 func (b *EventBus) Publish(events []*domain.Event) error {
 	if len(events) == 0 {
 		return nil
@@ -127,20 +305,26 @@ func (b *EventBus) Publish(events []*domain.Event) error {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
+	if b.maxBatchBytes > 0 {
+		return b.publishBatched(events)
+	}
+
 	for _, event := range events {
 		// Serialize event to JSON
 		eventJSON, err := b.serializeEvent(event)
 		if err != nil {
 			return fmt.Errorf("failed to serialize event %s: %w", event.ID, err)
 		}
+		if err := b.checkPayload(event, eventJSON); err != nil {
+			return err
+		}
 
 		// Determine subject based on aggregate type and event type
-		subject := fmt.Sprintf("events.%s.%s", event.AggregateType, event.EventType)
+		subject := b.eventSubject(b.eventScope(event), event.AggregateType, event.EventType)
 
 		// Publish to JetStream with event ID as message ID (deduplication)
-		_, err = b.js.Publish(subject, eventJSON, nats.MsgId(event.ID))
-		if err != nil {
-			return fmt.Errorf("failed to publish event %s: %w", event.ID, err)
+		if err := b.publishOne(subject, event, eventJSON); err != nil {
+			return err
 		}
 	}
 
@@ -148,47 +332,74 @@ func (b *EventBus) Publish(events []*domain.Event) error {
 }
 
 // Subscribe subscribes to events matching the filter.
-func (b *EventBus) Subscribe(filter messaging.EventFilter, handler messaging.EventHandler) (messaging.Subscription, error) {
+//
+// By default events are pushed to the handler as fast as NATS delivers them and
+// buffered in memory while the handler is busy. Pass messaging.WithMaxInFlight
+// to pull events on demand instead, bounding the buffer (see flowControlledSubscription).
+func (b *EventBus) Subscribe(filter messaging.EventFilter, handler messaging.EventHandler, opts ...messaging.SubscribeOption) (messaging.Subscription, error) {
+	options := messaging.NewSubscribeOptions(opts...)
+	if options.MaxInFlight == 0 {
+		options.MaxInFlight = b.maxInFlight
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
+	partition := "*"
+	if options.Partition != nil {
+		if b.partitioner == nil {
+			return nil, fmt.Errorf("cannot subscribe to partition %d: event bus is not partitioned", *options.Partition)
+		}
+		if *options.Partition < 0 || *options.Partition >= b.partitioner.Partitions() {
+			return nil, fmt.Errorf("partition %d out of range [0, %d)", *options.Partition, b.partitioner.Partitions())
+		}
+		partition = strconv.Itoa(*options.Partition)
+	}
+	if options.ConsumerGroup != "" {
+		return nil, fmt.Errorf("cannot subscribe in consumer group %s: not supported by the NATS event bus", options.ConsumerGroup)
+	}
+
 	// Build NATS subject from filter
-	subject := b.buildSubject(filter)
+	subject := b.buildSubject(filter, partition)
+	if filter.TenantID != "" {
+		handler = tenantHandler(filter.TenantID, handler)
+	}
 
 	// Create consumer name based on filter
 	consumerName := fmt.Sprintf("consumer_%s", domain.GenerateID()[:8])
+	bound := b.durable && options.Durable != ""
+	if bound {
+		consumerName = durableConsumerName(options.Durable)
+		if _, active := b.subs[consumerName]; active {
+			return nil, fmt.Errorf("durable subscription %s is already active on this event bus", options.Durable)
+		}
+		if err := b.ensureDurableConsumer(subject, consumerName, options.MaxInFlight); err != nil {
+			return nil, err
+		}
+	}
 
-	// Create durable consumer
+	if options.MaxInFlight > 0 {
+		return b.subscribeFlowControlled(subject, consumerName, handler, options.MaxInFlight, bound)
+	}
+
+	// Create durable consumer, or bind to a kept one: the client deletes
+	// consumers it created on Unsubscribe, but not those it was bound to
+	consumerOpts := []nats.SubOpt{nats.ManualAck()}
+	if bound {
+		consumerOpts = append(consumerOpts, nats.Bind(b.streamName, consumerName))
+	} else {
+		consumerOpts = append(consumerOpts, nats.Durable(consumerName), nats.AckExplicit(), nats.AckWait(b.ackWait))
+		if b.maxAckPending > 0 {
+			consumerOpts = append(consumerOpts, nats.MaxAckPending(b.maxAckPending))
+		}
+	}
 	sub, err := b.js.QueueSubscribe(
 		subject,
 		consumerName,
 		func(msg *nats.Msg) {
-			// Deserialize event
-			event, err := b.deserializeEvent(msg.Data)
-			if err != nil {
-				// Log error and nack
-				msg.Nak()
-				return
-			}
-
-			// Create event envelope (payload will be deserialized by handler if needed)
-			envelope := &domain.EventEnvelope{
-				Event: *event,
-			}
-
-			// Call handler
-			if err := handler(envelope); err != nil {
-				// Handler failed, nack for retry
-				msg.Nak()
-				return
-			}
-
-			// Handler succeeded, ack
-			msg.Ack()
+			b.handleMsg(msg, handler)
 		},
-		nats.Durable(consumerName),
-		nats.ManualAck(),
-		nats.AckExplicit(),
+		consumerOpts...,
 	)
 
 	if err != nil {
@@ -205,22 +416,126 @@ func (b *EventBus) Subscribe(filter messaging.EventFilter, handler messaging.Eve
 	}, nil
 }
 
-// buildSubject builds a NATS subject from an event filter.
-func (b *EventBus) buildSubject(filter messaging.EventFilter) string {
+// handleMsg passes the events of a message to the handler in order and acks
+// it, or nacks it for redelivery when it cannot be decoded or the handler
+// fails. A batch is redelivered whole, including events already handled.
+func (b *EventBus) handleMsg(msg *nats.Msg, handler messaging.EventHandler) {
+	// Deserialize events
+	events, err := b.decodeMsg(msg)
+	if err != nil {
+		// Log error and nack
+		msg.Nak()
+		return
+	}
+
+	for _, event := range events {
+		// Create event envelope (payload will be deserialized by handler if needed)
+		envelope := &domain.EventEnvelope{
+			Event: *event,
+		}
+
+		// Call handler
+		if err := handler(envelope); err != nil {
+			// Handler failed, nack for retry
+			msg.Nak()
+			return
+		}
+	}
+
+	// Handler succeeded, ack
+	msg.Ack()
+}
+
+// tenantHandler skips events of tenants other than tenantID, which reach a
+// tenant-filtered subscription on a bus without TenantSubjects, or whose
+// tenant IDs map to the same subject token.
+func tenantHandler(tenantID string, handler messaging.EventHandler) messaging.EventHandler {
+	return func(envelope *domain.EventEnvelope) error {
+		if domain.EventTenantID(&envelope.Event) != tenantID {
+			return nil
+		}
+		return handler(envelope)
+	}
+}
+
+// Partitioner returns the partitioner the bus was configured with, or nil.
+func (b *EventBus) Partitioner() eventsourcing.Partitioner {
+	return b.partitioner
+}
+
+// buildSubject builds a NATS subject from an event filter. partition is the
+// partition token ("*" for all partitions); it is ignored on an unpartitioned
+// bus.
+func (b *EventBus) buildSubject(filter messaging.EventFilter, partition string) string {
+	tenant := "*"
+	if filter.TenantID != "" {
+		tenant = tenantToken(filter.TenantID)
+	}
+	scope := b.subjectScope(partition, tenant)
+	prefix := b.prefix + scope
+
 	if len(filter.AggregateTypes) == 0 && len(filter.EventTypes) == 0 {
-		return "events.>" // All events
+		return prefix + ".>" // All events
 	}
 
 	if len(filter.AggregateTypes) == 1 && len(filter.EventTypes) == 0 {
-		return fmt.Sprintf("events.%s.>", filter.AggregateTypes[0])
+		return fmt.Sprintf("%s.%s.>", prefix, filter.AggregateTypes[0])
 	}
 
 	if len(filter.AggregateTypes) == 1 && len(filter.EventTypes) == 1 {
-		return fmt.Sprintf("events.%s.%s", filter.AggregateTypes[0], filter.EventTypes[0])
+		return b.eventSubject(scope, filter.AggregateTypes[0], filter.EventTypes[0])
 	}
 
 	// For complex filters, subscribe to all and filter in handler
-	return "events.>"
+	return prefix + ".>"
+}
+
+// eventSubject returns the subject of events of an aggregate and event type
+// within scope (see subjectScope).
+func (b *EventBus) eventSubject(scope, aggregateType, eventType string) string {
+	return fmt.Sprintf("%s%s.%s.%s", b.prefix, scope, aggregateType, eventType)
+}
+
+// subjectScope returns the subject tokens between the prefix and the
+// aggregate type, each with its leading dot: the partition and tenant tokens
+// if the bus is partitioned or has TenantSubjects, "" otherwise.
+func (b *EventBus) subjectScope(partition, tenant string) string {
+	var scope string
+	if b.partitioner != nil {
+		scope += "." + partition
+	}
+	if b.tenants {
+		scope += "." + tenant
+	}
+	return scope
+}
+
+// eventScope returns the subject scope an event is published in.
+func (b *EventBus) eventScope(event *domain.Event) string {
+	var tenant string
+	if b.tenants {
+		tenant = tenantToken(domain.EventTenantID(event))
+	}
+	return b.subjectScope(b.partitionToken(event), tenant)
+}
+
+// invalidTokenChars matches characters that cannot appear in a subject token.
+var invalidTokenChars = regexp.MustCompile(`[.*>\s]`)
+
+// tenantToken returns the subject token of a tenant, "_" for no tenant.
+func tenantToken(tenantID string) string {
+	if tenantID == "" {
+		return "_"
+	}
+	return invalidTokenChars.ReplaceAllString(tenantID, "_")
+}
+
+// partitionToken returns the subject token of an event's partition.
+func (b *EventBus) partitionToken(event *domain.Event) string {
+	if b.partitioner == nil {
+		return ""
+	}
+	return strconv.Itoa(b.partitioner.Partition(event))
 }
 
 // serializeEvent serializes an event to JSON.
@@ -238,21 +553,56 @@ func (b *EventBus) deserializeEvent(data []byte) (*domain.Event, error) {
 }
 
 // Close closes the event bus and all subscriptions.
+// The connection is drained first: in-flight handlers complete and their acks
+// reach the server before Close returns, so no message is redelivered on restart
+// just because shutdown raced with processing. A wedged handler keeps Close
+// waiting; use CloseContext to bound it.
 func (b *EventBus) Close() error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	if b.metrics != nil {
+		b.metrics.Unregister()
+	}
+
+	// Publish what PublishAsync buffered while the connection is still open
+	b.publishBuffer.close()
 
-	// Unsubscribe all
-	for _, sub := range b.subs {
-		sub.Unsubscribe()
+	b.mu.Lock()
+	b.subs = make(map[string]*nats.Subscription)
+	flow := b.flow
+	b.flow = make(map[string]*flowControlledSubscription)
+	b.mu.Unlock()
+
+	// Pull-based subscriptions finish their current event before draining
+	for _, sub := range flow {
+		sub.shutdown()
 	}
 
-	// Close NATS connection
-	b.nc.Close()
+	// Drain outside the lock so handlers that publish can still finish
+	if err := b.nc.Drain(); err != nil {
+		b.nc.Close()
+	}
+	<-b.closed
 
 	return nil
 }
 
+// CloseContext is Close bounded by ctx. If ctx ends first, it closes the
+// connection without waiting for the drain and returns ctx's error; events
+// still buffered or being handled are then redelivered on restart.
+func (b *EventBus) CloseContext(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Close()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		b.nc.Close()
+		return fmt.Errorf("event bus did not drain in time: %w", ctx.Err())
+	}
+}
+
 // subscription implements messaging.Subscription.
 type subscription struct {
 	bus          *EventBus
@@ -260,6 +610,17 @@ type subscription struct {
 	consumerName string
 }
 
+// Stats reports the events NATS delivered to the subscription that wait in
+// the client for the handler. The server stops delivering at
+// Config.MaxAckPending unacked events.
+func (s *subscription) Stats() messaging.SubscriptionStats {
+	pending, _, _ := s.sub.Pending()
+	return messaging.SubscriptionStats{
+		InFlight:    pending,
+		MaxInFlight: s.bus.maxAckPending,
+	}
+}
+
 func (s *subscription) Unsubscribe() error {
 	s.bus.mu.Lock()
 	defer s.bus.mu.Unlock()
@@ -277,7 +638,7 @@ func DeserializeEventPayload(event *domain.Event, msg proto.Message) error {
 // DeserializeEventPayloadDynamic dynamically deserializes an event payload based on event type.
 func DeserializeEventPayloadDynamic(event *domain.Event) (proto.Message, error) {
 	// Look up message type in proto registry
-	messageType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(event.EventType))
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(domain.CanonicalEventType(event.EventType)))
 	if err != nil {
 		return nil, fmt.Errorf("message type %s not found in registry: %w", event.EventType, err)
 	}
@@ -1,13 +1,19 @@
 package nats_test
 
 import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
 	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
 	"github.com/plaenen/eventstore/pkg/messaging"
 	natspkg "github.com/plaenen/eventstore/pkg/messaging/nats"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
 )
 
 func TestEmbeddedNATSEventBus(t *testing.T) {
@@ -198,3 +204,672 @@ func TestEmbeddedNATSEventBus(t *testing.T) {
 		}
 	})
 }
+
+func TestEnvelopeMetadataParity(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	config := natspkg.DefaultConfig()
+	config.URL = srv.URL()
+	bus, err := natspkg.NewEventBus(config)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+	defer bus.Close()
+
+	store, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer store.Close()
+
+	received := make(chan *domain.EventEnvelope, 1)
+	sub, err := bus.Subscribe(messaging.EventFilter{
+		AggregateTypes: []string{"ParityAggregate"},
+	}, func(envelope *domain.EventEnvelope) error {
+		received <- envelope
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	time.Sleep(100 * time.Millisecond)
+
+	event := &domain.Event{
+		ID:            "parity-event-1",
+		AggregateID:   "parity-1",
+		AggregateType: "ParityAggregate",
+		EventType:     "test.Created",
+		Version:       1,
+		Timestamp:     time.Unix(1700000000, 0),
+		Data:          []byte("payload"),
+		Metadata: domain.EventMetadata{
+			CausationID:   "cmd-1",
+			CorrelationID: "corr-1",
+			PrincipalID:   "user-1",
+			TenantID:      "tenant-1",
+			Custom:        map[string]string{"source": "test"},
+		},
+	}
+
+	// Append then publish, the way the command pipeline does
+	if err := store.AppendEvents(context.Background(), event.AggregateID, 0, []*domain.Event{event}); err != nil {
+		t.Fatalf("failed to append event: %v", err)
+	}
+	if event.Position == 0 {
+		t.Fatal("expected append to assign a global position")
+	}
+	if err := bus.Publish([]*domain.Event{event}); err != nil {
+		t.Fatalf("failed to publish event: %v", err)
+	}
+
+	loaded, err := store.LoadAllEvents(context.Background(), 0, 10)
+	if err != nil {
+		t.Fatalf("failed to load events: %v", err)
+	}
+	if len(loaded) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(loaded))
+	}
+	fromStore := &domain.EventEnvelope{Event: *loaded[0]}
+
+	var fromBus *domain.EventEnvelope
+	select {
+	case fromBus = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	if !reflect.DeepEqual(fromStore.Metadata, fromBus.Metadata) {
+		t.Errorf("metadata mismatch:\nstore: %+v\nbus:   %+v", fromStore.Metadata, fromBus.Metadata)
+	}
+	if fromStore.GlobalPosition() != fromBus.GlobalPosition() {
+		t.Errorf("position mismatch: store %d, bus %d", fromStore.GlobalPosition(), fromBus.GlobalPosition())
+	}
+	if !fromStore.OccurredAt().Equal(fromBus.OccurredAt()) {
+		t.Errorf("timestamp mismatch: store %v, bus %v", fromStore.OccurredAt(), fromBus.OccurredAt())
+	}
+	if fromBus.TenantID() != "tenant-1" || fromBus.CorrelationID() != "corr-1" ||
+		fromBus.CausationID() != "cmd-1" || fromBus.PrincipalID() != "user-1" {
+		t.Errorf("unexpected metadata accessors on bus envelope: %+v", fromBus.Metadata)
+	}
+	if source, ok := fromBus.CustomMetadata("source"); !ok || source != "test" {
+		t.Errorf("expected custom metadata source=test, got %q", source)
+	}
+}
+
+func TestEventBusSubjectPrefix(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	newBus := func(t *testing.T, stream, prefix string) *natspkg.EventBus {
+		t.Helper()
+		config := natspkg.DefaultConfig()
+		config.URL = srv.URL()
+		config.StreamName = stream
+		config.SubjectPrefix = prefix
+		bus, err := natspkg.NewEventBus(config)
+		if err != nil {
+			t.Fatalf("failed to create event bus %s: %v", stream, err)
+		}
+		return bus
+	}
+
+	billing := newBus(t, "BILLING_EVENTS", "billing")
+	defer billing.Close()
+	shipping := newBus(t, "SHIPPING_EVENTS", "shipping")
+	defer shipping.Close()
+
+	t.Run("ServicesDoNotSeeEachOthersEvents", func(t *testing.T) {
+		received := make(chan string, 10)
+		sub, err := shipping.Subscribe(messaging.EventFilter{}, func(envelope *domain.EventEnvelope) error {
+			received <- envelope.ID
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		defer sub.Unsubscribe()
+
+		time.Sleep(100 * time.Millisecond)
+
+		publish := func(bus *natspkg.EventBus, id string) {
+			err := bus.Publish([]*domain.Event{{
+				ID:            id,
+				AggregateID:   "agg-1",
+				AggregateType: "Order",
+				EventType:     "OrderPlaced",
+				Version:       1,
+				Timestamp:     time.Now(),
+				Data:          []byte("data"),
+			}})
+			if err != nil {
+				t.Fatalf("failed to publish %s: %v", id, err)
+			}
+		}
+		publish(billing, "billing-event")
+		publish(shipping, "shipping-event")
+
+		select {
+		case id := <-received:
+			if id != "shipping-event" {
+				t.Fatalf("expected shipping-event, got %s", id)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timeout waiting for event")
+		}
+
+		select {
+		case id := <-received:
+			t.Fatalf("received event from another service: %s", id)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("ExistingStreamIsReused", func(t *testing.T) {
+		again := newBus(t, "BILLING_EVENTS", "billing")
+		again.Close()
+	})
+
+	t.Run("IncompatibleStreamIsRejected", func(t *testing.T) {
+		config := natspkg.DefaultConfig()
+		config.URL = srv.URL()
+		config.StreamName = "BILLING_EVENTS"
+		config.SubjectPrefix = "invoicing"
+		if _, err := natspkg.NewEventBus(config); err == nil {
+			t.Fatal("expected error for stream with different subjects")
+		}
+	})
+}
+
+func TestSubscribeMaxInFlight(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	config := natspkg.DefaultConfig()
+	config.URL = srv.URL()
+	config.StreamName = "FLOW_EVENTS"
+	config.SubjectPrefix = "flow"
+	bus, err := natspkg.NewEventBus(config)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+	defer bus.Close()
+
+	const (
+		maxInFlight = 5
+		total       = 200
+	)
+
+	// The handler blocks until the producer is done, then handles events slowly
+	release := make(chan struct{})
+	var (
+		mu          sync.Mutex
+		received    int
+		maxObserved int
+		stats       messaging.StatsReporter
+	)
+	allReceived := make(chan struct{})
+	sub, err := bus.Subscribe(messaging.EventFilter{}, func(envelope *domain.EventEnvelope) error {
+		<-release
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if inFlight := stats.Stats().InFlight; inFlight > maxObserved {
+			maxObserved = inFlight
+		}
+		received++
+		if received == total {
+			close(allReceived)
+		}
+		return nil
+	}, messaging.WithMaxInFlight(maxInFlight))
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	var ok bool
+	stats, ok = sub.(messaging.StatsReporter)
+	if !ok {
+		t.Fatalf("expected subscription to report stats, got %T", sub)
+	}
+
+	// Fast producer
+	for i := 0; i < total; i++ {
+		err := bus.Publish([]*domain.Event{{
+			ID:            fmt.Sprintf("flow-event-%d", i),
+			AggregateID:   "agg-1",
+			AggregateType: "Order",
+			EventType:     "OrderPlaced",
+			Version:       int64(i + 1),
+			Timestamp:     time.Now(),
+			Data:          []byte("data"),
+		}})
+		if err != nil {
+			t.Fatalf("failed to publish event %d: %v", i, err)
+		}
+	}
+
+	// The stuck handler fills the queue, which pauses fetching
+	deadline := time.Now().Add(2 * time.Second)
+	for !stats.Stats().Paused && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s := stats.Stats(); !s.Paused || s.InFlight != maxInFlight {
+		t.Fatalf("expected paused subscription with %d in flight, got %+v", maxInFlight, s)
+	}
+
+	// Nothing beyond the limit is buffered while paused
+	time.Sleep(100 * time.Millisecond)
+	if s := stats.Stats(); s.InFlight > maxInFlight {
+		t.Fatalf("in-flight depth %d exceeds limit %d", s.InFlight, maxInFlight)
+	}
+
+	close(release)
+	select {
+	case <-allReceived:
+	case <-time.After(10 * time.Second):
+		mu.Lock()
+		defer mu.Unlock()
+		t.Fatalf("timeout: received %d of %d events", received, total)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if maxObserved > maxInFlight {
+		t.Errorf("in-flight depth reached %d, limit is %d", maxObserved, maxInFlight)
+	}
+	if s := stats.Stats(); s.Pauses == 0 {
+		t.Errorf("expected subscription to have paused, got %+v", s)
+	}
+}
+
+func TestPartitionedEventBus(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer()
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	partitioner := eventsourcing.HashPartitioner(2)
+	config := natspkg.DefaultConfig()
+	config.URL = srv.URL()
+	config.StreamName = "PARTITIONED_EVENTS"
+	config.SubjectPrefix = "partitioned"
+	config.Partitioner = partitioner
+	bus, err := natspkg.NewEventBus(config)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+	defer bus.Close()
+
+	type delivery struct {
+		partition int
+		event     domain.Event
+	}
+	received := make(chan delivery, 100)
+	for p := 0; p < 2; p++ {
+		sub, err := bus.Subscribe(messaging.EventFilter{}, func(envelope *domain.EventEnvelope) error {
+			received <- delivery{partition: p, event: envelope.Event}
+			return nil
+		}, messaging.WithPartition(p))
+		if err != nil {
+			t.Fatalf("failed to subscribe to partition %d: %v", p, err)
+		}
+		defer sub.Unsubscribe()
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	var events []*domain.Event
+	for i := 0; i < 20; i++ {
+		events = append(events, &domain.Event{
+			ID:            fmt.Sprintf("evt-%d", i),
+			AggregateID:   fmt.Sprintf("agg-%d", i%10),
+			AggregateType: "Order",
+			EventType:     "OrderPlaced",
+			Version:       int64(i/10 + 1),
+			Timestamp:     time.Now(),
+			Data:          []byte("data"),
+		})
+	}
+	if err := bus.Publish(events); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	perPartition := make(map[int]int)
+	for i := 0; i < len(events); i++ {
+		select {
+		case d := <-received:
+			if want := partitioner.Partition(&d.event); d.partition != want {
+				t.Errorf("event %s of %s delivered to partition %d, want %d", d.event.ID, d.event.AggregateID, d.partition, want)
+			}
+			perPartition[d.partition]++
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout after %d of %d events", i, len(events))
+		}
+	}
+	if perPartition[0] == 0 || perPartition[1] == 0 {
+		t.Errorf("expected events on both partitions, got %v", perPartition)
+	}
+
+	t.Run("UnpartitionedBusRejectsPartitionSubscription", func(t *testing.T) {
+		config := natspkg.DefaultConfig()
+		config.URL = srv.URL()
+		plain, err := natspkg.NewEventBus(config)
+		if err != nil {
+			t.Fatalf("failed to create event bus: %v", err)
+		}
+		defer plain.Close()
+
+		_, err = plain.Subscribe(messaging.EventFilter{}, func(*domain.EventEnvelope) error { return nil }, messaging.WithPartition(0))
+		if err == nil {
+			t.Fatal("expected an error subscribing to a partition of an unpartitioned bus")
+		}
+	})
+}
+
+func TestInProcessEventBus(t *testing.T) {
+	// Each bus gets its own in-process server with the default stream name;
+	// without ports or shared storage they can run side by side
+	for i := 0; i < 3; i++ {
+		t.Run(fmt.Sprintf("bus %d", i), func(t *testing.T) {
+			t.Parallel()
+
+			srv, err := natsserver.StartEmbeddedServer(natsserver.WithInProcess())
+			if err != nil {
+				t.Fatalf("failed to start embedded server: %v", err)
+			}
+			defer srv.Shutdown()
+
+			config := natspkg.DefaultConfig()
+			config.URL = srv.URL()
+			config.ConnectOptions = srv.ConnectOptions()
+			bus, err := natspkg.NewEventBus(config)
+			if err != nil {
+				t.Fatalf("failed to create event bus: %v", err)
+			}
+			defer bus.Close()
+
+			received := make(chan string, 1)
+			sub, err := bus.Subscribe(messaging.EventFilter{}, func(envelope *domain.EventEnvelope) error {
+				received <- envelope.Event.ID
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("failed to subscribe: %v", err)
+			}
+			defer sub.Unsubscribe()
+
+			id := fmt.Sprintf("evt-%d", i)
+			err = bus.Publish([]*domain.Event{{
+				ID:            id,
+				AggregateID:   "agg-1",
+				AggregateType: "TestAggregate",
+				EventType:     "test.Created",
+				Version:       1,
+				Timestamp:     time.Now(),
+				Data:          []byte("test data"),
+			}})
+			if err != nil {
+				t.Fatalf("failed to publish event: %v", err)
+			}
+
+			select {
+			case got := <-received:
+				if got != id {
+					t.Errorf("expected %s, got %s", id, got)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timeout waiting for event")
+			}
+		})
+	}
+}
+
+// crashingProjection records the events it handles and fails every event
+// after the first failAfter, like a process dying mid-stream.
+type crashingProjection struct {
+	mu        sync.Mutex
+	handled   []string
+	failAfter int // 0 = never fail
+}
+
+func (p *crashingProjection) Name() string { return "balances" }
+
+func (p *crashingProjection) Handle(ctx context.Context, event *domain.EventEnvelope) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failAfter > 0 && len(p.handled) >= p.failAfter {
+		return fmt.Errorf("crashed")
+	}
+	p.handled = append(p.handled, event.Event.ID)
+	return nil
+}
+
+func (p *crashingProjection) Reset(ctx context.Context) error { return nil }
+
+func (p *crashingProjection) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.handled)
+}
+
+func TestDurableProjectionResume(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithInProcess())
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	config := natspkg.DefaultConfig()
+	config.URL = srv.URL()
+	config.ConnectOptions = srv.ConnectOptions()
+	config.Durable = true
+	config.AckWait = time.Second // Events in flight at the crash come back after this
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	checkpoints, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	startInstance := func(projection *crashingProjection) (*natspkg.EventBus, *eventsourcing.ProjectionManager) {
+		t.Helper()
+		bus, err := natspkg.NewEventBus(config)
+		if err != nil {
+			t.Fatalf("failed to create event bus: %v", err)
+		}
+		manager := eventsourcing.NewProjectionManager(checkpoints, eventStore, bus)
+		manager.Register(projection)
+		if err := manager.Start(context.Background(), projection.Name()); err != nil {
+			t.Fatalf("failed to start projection: %v", err)
+		}
+		return bus, manager
+	}
+	waitFor := func(projection *crashingProjection, n int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for projection.count() < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("timeout: handled %d of %d events", projection.count(), n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// The first instance handles 20 of 50 events, then crashes
+	first := &crashingProjection{failAfter: 20}
+	bus, manager := startInstance(first)
+
+	var events []*domain.Event
+	for i := 0; i < 50; i++ {
+		events = append(events, &domain.Event{
+			ID:            fmt.Sprintf("evt-%d", i),
+			AggregateID:   fmt.Sprintf("acc-%d", i%5),
+			AggregateType: "Account",
+			EventType:     "Deposited",
+			Version:       int64(i/5 + 1),
+			Timestamp:     time.Now(),
+			Data:          []byte("data"),
+		})
+	}
+	if err := bus.Publish(events); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	waitFor(first, 20)
+	manager.Stop(first.Name())
+	bus.Close()
+
+	// A restarted instance resumes with the unacked events only
+	second := &crashingProjection{}
+	bus, manager = startInstance(second)
+	defer bus.Close()
+	defer manager.Stop(second.Name())
+	waitFor(second, 30)
+	time.Sleep(200 * time.Millisecond)
+
+	seen := make(map[string]bool)
+	for _, id := range append(first.handled, second.handled...) {
+		if seen[id] {
+			t.Errorf("event %s handled by both instances", id)
+		}
+		seen[id] = true
+	}
+	for _, event := range events {
+		if !seen[event.ID] {
+			t.Errorf("event %s lost across the restart", event.ID)
+		}
+	}
+	if second.count() != 30 {
+		t.Errorf("expected the restarted projection to handle the 30 unacked events, got %d", second.count())
+	}
+}
+
+func TestTenantSubjects(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithInProcess())
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	events := []*domain.Event{
+		{ID: "a-metadata", AggregateID: "acc-1", Metadata: domain.EventMetadata{TenantID: "tenant-a"}},
+		{ID: "b-metadata", AggregateID: "acc-2", Metadata: domain.EventMetadata{TenantID: "tenant-b"}},
+		{ID: "a-composite", AggregateID: "tenant-a::acc-3"},
+		{ID: "no-tenant", AggregateID: "acc-4"},
+	}
+	for i, event := range events {
+		event.AggregateType = "Account"
+		event.EventType = "Deposited"
+		event.Version = int64(i + 1)
+		event.Timestamp = time.Now()
+	}
+
+	for _, tenantSubjects := range []bool{true, false} {
+		t.Run(fmt.Sprintf("TenantSubjects=%v", tenantSubjects), func(t *testing.T) {
+			config := natspkg.DefaultConfig()
+			config.URL = srv.URL()
+			config.ConnectOptions = srv.ConnectOptions()
+			config.StreamName = fmt.Sprintf("TENANT_EVENTS_%v", tenantSubjects)
+			config.SubjectPrefix = fmt.Sprintf("tenants%v", tenantSubjects)
+			config.TenantSubjects = tenantSubjects
+			bus, err := natspkg.NewEventBus(config)
+			if err != nil {
+				t.Fatalf("failed to create event bus: %v", err)
+			}
+			defer bus.Close()
+
+			received := make(chan string, len(events))
+			sub, err := bus.Subscribe(messaging.EventFilter{TenantID: "tenant-a"}, func(envelope *domain.EventEnvelope) error {
+				received <- envelope.Event.ID
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("failed to subscribe: %v", err)
+			}
+			defer sub.Unsubscribe()
+
+			// Without tenant subjects the subscription filters other tenants'
+			// events out itself
+			delivered := make(chan string, len(events))
+			all, err := bus.Subscribe(messaging.EventFilter{}, func(envelope *domain.EventEnvelope) error {
+				delivered <- envelope.Event.ID
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("failed to subscribe: %v", err)
+			}
+			defer all.Unsubscribe()
+
+			// The layout itself: tenant events on tenant subjects
+			nc, err := natsserver.ConnectToEmbedded(srv)
+			if err != nil {
+				t.Fatalf("failed to connect: %v", err)
+			}
+			defer nc.Close()
+			raw, err := nc.SubscribeSync(config.SubjectPrefix + ".>")
+			if err != nil {
+				t.Fatalf("failed to subscribe: %v", err)
+			}
+			nc.Flush()
+
+			if err := bus.Publish(events); err != nil {
+				t.Fatalf("failed to publish: %v", err)
+			}
+			for _, tenant := range []string{"tenant-a", "tenant-b", "tenant-a", "_"} {
+				msg, err := raw.NextMsg(5 * time.Second)
+				if err != nil {
+					t.Fatalf("failed to receive message: %v", err)
+				}
+				subject := config.SubjectPrefix + ".Account.Deposited"
+				if tenantSubjects {
+					subject = config.SubjectPrefix + "." + tenant + ".Account.Deposited"
+				}
+				if msg.Subject != subject {
+					t.Errorf("expected subject %s, got %s", subject, msg.Subject)
+				}
+			}
+			for range events {
+				select {
+				case <-delivered:
+				case <-time.After(5 * time.Second):
+					t.Fatal("timeout waiting for events")
+				}
+			}
+
+			var got []string
+			for len(got) < 2 {
+				select {
+				case id := <-received:
+					got = append(got, id)
+				case <-time.After(5 * time.Second):
+					t.Fatalf("timeout waiting for tenant events, got %v", got)
+				}
+			}
+			select {
+			case id := <-received:
+				t.Errorf("expected only tenant-a events, also got %s", id)
+			case <-time.After(100 * time.Millisecond):
+			}
+			if fmt.Sprint(got) != "[a-metadata a-composite]" {
+				t.Errorf("expected the tenant-a events in order, got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package nats
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/plaenen/eventstore/pkg/messaging"
+)
+
+const (
+	// fetchWait bounds how long a fetch waits for new events, and thus how
+	// long Unsubscribe waits for the fetch loop to notice it should stop.
+	fetchWait = 250 * time.Millisecond
+
+	// fetchRetryDelay is the backoff after a failed fetch.
+	fetchRetryDelay = 100 * time.Millisecond
+)
+
+// flowControlledSubscription pulls events from a JetStream pull consumer into
+// a bounded queue that a single worker hands to the handler, one at a time.
+//
+// Only as many events are fetched as there are free slots, so at most
+// maxInFlight events are ever held in memory (the consumer's MaxAckPending is
+// set to the same limit on the server side). When the queue is full the
+// subscription pauses fetching, and it resumes once the handler has drained
+// the queue to half its capacity.
+//
+// Queued events count against the consumer's AckWait (30s by default), so keep
+// maxInFlight times the handler latency below it to avoid redeliveries.
+type flowControlledSubscription struct {
+	bus          *EventBus
+	sub          *nats.Subscription
+	consumerName string
+	handler      messaging.EventHandler
+	maxInFlight  int
+	resumeAt     int
+
+	queue    chan *nats.Msg
+	drained  chan struct{} // Signaled when in-flight drops to resumeAt
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{} // Closed when the worker has exited
+
+	inFlight atomic.Int64
+	paused   atomic.Bool
+	pauses   atomic.Int64
+}
+
+// subscribeFlowControlled creates a pull-based subscription, on the kept
+// consumer consumerName if bound. Must be called with b.mu held.
+func (b *EventBus) subscribeFlowControlled(subject, consumerName string, handler messaging.EventHandler, maxInFlight int, bound bool) (*flowControlledSubscription, error) {
+	opts := []nats.SubOpt{nats.ManualAck()}
+	if bound {
+		opts = append(opts, nats.Bind(b.streamName, consumerName))
+	} else {
+		opts = append(opts, nats.AckExplicit(), nats.AckWait(b.ackWait), nats.MaxAckPending(maxInFlight))
+	}
+	sub, err := b.js.PullSubscribe(subject, consumerName, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	s := &flowControlledSubscription{
+		bus:          b,
+		sub:          sub,
+		consumerName: consumerName,
+		handler:      handler,
+		maxInFlight:  maxInFlight,
+		resumeAt:     maxInFlight / 2,
+		queue:        make(chan *nats.Msg, maxInFlight),
+		drained:      make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	b.subs[consumerName] = sub
+	b.flow[consumerName] = s
+
+	go s.fetch()
+	go s.work()
+
+	return s, nil
+}
+
+// fetch pulls events into the queue while there is room for them.
+func (s *flowControlledSubscription) fetch() {
+	defer close(s.queue)
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		free := s.maxInFlight - int(s.inFlight.Load())
+		if free <= 0 {
+			s.paused.Store(true)
+			s.pauses.Add(1)
+			select {
+			case <-s.stop:
+				return
+			case <-s.drained:
+			}
+			s.paused.Store(false)
+			continue
+		}
+
+		msgs, err := s.sub.Fetch(free, nats.MaxWait(fetchWait))
+		if err != nil {
+			if errors.Is(err, nats.ErrTimeout) {
+				continue
+			}
+			if !s.sub.IsValid() || s.bus.nc.IsClosed() || s.bus.nc.IsDraining() {
+				return
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(fetchRetryDelay):
+			}
+			continue
+		}
+
+		for _, msg := range msgs {
+			s.inFlight.Add(1)
+			s.queue <- msg // Never blocks: at most free messages were fetched
+		}
+	}
+}
+
+// work hands queued events to the handler. Events still queued when the
+// subscription stops are nacked so they are redelivered right away.
+func (s *flowControlledSubscription) work() {
+	defer close(s.done)
+
+	for msg := range s.queue {
+		select {
+		case <-s.stop:
+			msg.Nak()
+		default:
+			s.bus.handleMsg(msg, s.handler)
+		}
+
+		if s.inFlight.Add(-1) <= int64(s.resumeAt) {
+			select {
+			case s.drained <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// shutdown stops fetching and waits for the event being handled, if any.
+func (s *flowControlledSubscription) shutdown() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+// Stats returns the current in-flight depth and pause state.
+func (s *flowControlledSubscription) Stats() messaging.SubscriptionStats {
+	return messaging.SubscriptionStats{
+		InFlight:    int(s.inFlight.Load()),
+		MaxInFlight: s.maxInFlight,
+		Paused:      s.paused.Load(),
+		Pauses:      s.pauses.Load(),
+	}
+}
+
+// Unsubscribe stops the subscription and deletes its consumer, unless it is
+// durable (see Config.Durable). It waits for the handler to finish its
+// current event, so it must not be called from within the handler.
+func (s *flowControlledSubscription) Unsubscribe() error {
+	s.shutdown()
+
+	s.bus.mu.Lock()
+	delete(s.bus.subs, s.consumerName)
+	delete(s.bus.flow, s.consumerName)
+	s.bus.mu.Unlock()
+
+	return s.sub.Unsubscribe()
+}
@@ -0,0 +1,75 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/messaging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// registerMetrics registers observable instruments on meter reporting, at
+// every collection, the events each subscription holds in flight, whether and
+// how often MaxInFlight subscriptions paused (as Stats does) and the events
+// waiting in the publish buffer (as PublishStats does).
+func (b *EventBus) registerMetrics(meter metric.Meter) (metric.Registration, error) {
+	inFlight, err := meter.Int64ObservableGauge(
+		"eventsourcing.eventbus.inflight",
+		metric.WithDescription("Events received by a subscription but not yet handled"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating eventbus.inflight: %w", err)
+	}
+	paused, err := meter.Int64ObservableGauge(
+		"eventsourcing.eventbus.paused",
+		metric.WithDescription("1 while a subscription has stopped pulling events because it reached MaxInFlight"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating eventbus.paused: %w", err)
+	}
+	pauses, err := meter.Int64ObservableCounter(
+		"eventsourcing.eventbus.pauses",
+		metric.WithDescription("Times a subscription stopped pulling events because it reached MaxInFlight"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating eventbus.pauses: %w", err)
+	}
+	buffered, err := meter.Int64ObservableGauge(
+		"eventsourcing.eventbus.publish.buffered",
+		metric.WithDescription("Events accepted by PublishAsync or PublishWithBackpressure but not yet published"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating eventbus.publish.buffered: %w", err)
+	}
+
+	return meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		b.mu.RLock()
+		counts := make(map[string]int64, len(b.subs))
+		flowStats := make(map[string]messaging.SubscriptionStats, len(b.flow))
+		for consumer, sub := range b.subs {
+			if flow, ok := b.flow[consumer]; ok {
+				flowStats[consumer] = flow.Stats()
+				counts[consumer] = int64(flowStats[consumer].InFlight)
+			} else if pending, _, err := sub.Pending(); err == nil {
+				counts[consumer] = int64(pending)
+			}
+		}
+		b.mu.RUnlock()
+
+		for consumer, count := range counts {
+			o.ObserveInt64(inFlight, count, metric.WithAttributes(attribute.String("consumer", consumer)))
+		}
+		for consumer, stats := range flowStats {
+			attrs := metric.WithAttributes(attribute.String("consumer", consumer))
+			var isPaused int64
+			if stats.Paused {
+				isPaused = 1
+			}
+			o.ObserveInt64(paused, isPaused, attrs)
+			o.ObserveInt64(pauses, stats.Pauses, attrs)
+		}
+		o.ObserveInt64(buffered, int64(b.PublishStats().Buffered))
+		return nil
+	}, inFlight, paused, pauses, buffered)
+}
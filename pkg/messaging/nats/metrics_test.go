@@ -0,0 +1,95 @@
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"github.com/plaenen/eventstore/pkg/messaging"
+	"github.com/plaenen/eventstore/pkg/observability"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestEventBusMetrics(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithInProcess())
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	reader := sdkmetric.NewManualReader()
+	config := DefaultConfig()
+	config.URL = srv.URL()
+	config.ConnectOptions = srv.ConnectOptions()
+	config.MaxInFlight = 3
+	config.Telemetry = &observability.Telemetry{MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))}
+	bus, err := NewEventBus(config)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+	defer bus.Close()
+
+	// The handler holds every event until released, keeping them in flight
+	release := make(chan struct{})
+	sub, err := bus.Subscribe(messaging.EventFilter{}, func(*domain.EventEnvelope) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+	defer close(release)
+
+	if err := bus.Publish(asyncTestEvents(0, 3)); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	gauge := func(name string) (int64, bool) {
+		var data metricdata.ResourceMetrics
+		if err := reader.Collect(context.Background(), &data); err != nil {
+			t.Fatalf("failed to collect metrics: %v", err)
+		}
+		for _, scope := range data.ScopeMetrics {
+			for _, m := range scope.Metrics {
+				if m.Name != name {
+					continue
+				}
+				switch points := m.Data.(type) {
+				case metricdata.Gauge[int64]:
+					if len(points.DataPoints) > 0 {
+						return points.DataPoints[0].Value, true
+					}
+				case metricdata.Sum[int64]:
+					if len(points.DataPoints) > 0 {
+						return points.DataPoints[0].Value, true
+					}
+				}
+			}
+		}
+		return 0, false
+	}
+
+	// Reaching MaxInFlight pauses the subscription
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		inFlight, _ := gauge("eventsourcing.eventbus.inflight")
+		paused, _ := gauge("eventsourcing.eventbus.paused")
+		if inFlight == 3 && paused == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 3 events in flight and a paused subscription in the metrics, got %d in flight, paused=%d", inFlight, paused)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pauses, ok := gauge("eventsourcing.eventbus.pauses"); !ok || pauses < 1 {
+		t.Errorf("expected at least one pause in the metrics, got %d (reported %v)", pauses, ok)
+	}
+	if buffered, ok := gauge("eventsourcing.eventbus.publish.buffered"); !ok || buffered != 0 {
+		t.Errorf("expected an empty publish buffer in the metrics, got %d (reported %v)", buffered, ok)
+	}
+}
@@ -0,0 +1,186 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// ErrPublishBufferFull is returned by PublishAsync when the publish buffer
+// has no room for the events.
+var ErrPublishBufferFull = errors.New("publish buffer full")
+
+// errBusClosed is returned by the buffered publishes after Close.
+var errBusClosed = errors.New("event bus closed")
+
+// PublishStats describes the state of the buffer of PublishAsync and
+// PublishWithBackpressure.
+type PublishStats struct {
+	// Buffered is the number of events accepted but not yet published
+	Buffered int
+
+	// BufferSize is the configured capacity (Config.PublishBufferSize)
+	BufferSize int
+
+	// Rejected counts the PublishAsync calls that failed with
+	// ErrPublishBufferFull
+	Rejected int64
+
+	// Failed counts the events the background publisher failed to publish
+	Failed int64
+}
+
+// publishBuffer holds events accepted by PublishAsync and
+// PublishWithBackpressure until a background goroutine publishes them, in
+// the order they were accepted. At most capacity events are held.
+type publishBuffer struct {
+	capacity int
+	onError  func(events []*domain.Event, err error)
+
+	mu       sync.Mutex
+	buffered int
+	closed   bool
+	freed    chan struct{} // Closed (and replaced) whenever events leave the buffer
+
+	queue chan []*domain.Event // Never blocks: holds at most one batch per buffered event
+	done  chan struct{}        // Closed when the publisher has drained the queue
+
+	rejected atomic.Int64
+	failed   atomic.Int64
+}
+
+// startPublishBuffer starts publishing buffered events with publish.
+func startPublishBuffer(capacity int, onError func([]*domain.Event, error), publish func([]*domain.Event) error) *publishBuffer {
+	pb := &publishBuffer{
+		capacity: capacity,
+		onError:  onError,
+		freed:    make(chan struct{}),
+		queue:    make(chan []*domain.Event, capacity),
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(pb.done)
+		for events := range pb.queue {
+			if err := publish(events); err != nil {
+				pb.failed.Add(int64(len(events)))
+				if pb.onError != nil {
+					pb.onError(events, err)
+				}
+			}
+			pb.release(len(events))
+		}
+	}()
+	return pb
+}
+
+// tryEnqueue buffers events if there is room for all of them. It returns
+// the channel closed when room is freed otherwise.
+func (pb *publishBuffer) tryEnqueue(events []*domain.Event) (bool, <-chan struct{}, error) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	if pb.closed {
+		return false, nil, errBusClosed
+	}
+	if len(events) > pb.capacity {
+		return false, nil, fmt.Errorf("%d events exceed the publish buffer size %d", len(events), pb.capacity)
+	}
+	if pb.buffered+len(events) > pb.capacity {
+		return false, pb.freed, nil
+	}
+	pb.buffered += len(events)
+	pb.queue <- events
+	return true, nil, nil
+}
+
+// release frees the room of events that left the buffer.
+func (pb *publishBuffer) release(n int) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	pb.buffered -= n
+	close(pb.freed)
+	pb.freed = make(chan struct{})
+}
+
+// close stops accepting events and waits until the buffered ones are published.
+func (pb *publishBuffer) close() {
+	pb.mu.Lock()
+	if !pb.closed {
+		pb.closed = true
+		close(pb.queue)
+	}
+	pb.mu.Unlock()
+
+	<-pb.done
+}
+
+// stats returns the current buffer state.
+func (pb *publishBuffer) stats() PublishStats {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	return PublishStats{
+		Buffered:   pb.buffered,
+		BufferSize: pb.capacity,
+		Rejected:   pb.rejected.Load(),
+		Failed:     pb.failed.Load(),
+	}
+}
+
+// PublishAsync buffers events to be published in the background and returns
+// without waiting for NATS. Events are published in the order they were
+// accepted, by Publish; failures are reported to Config.OnPublishError.
+//
+// If the buffer (Config.PublishBufferSize events) has no room for all the
+// events, none are buffered and ErrPublishBufferFull is returned, so a
+// caller under a burst notices instead of growing memory without bound.
+func (b *EventBus) PublishAsync(events []*domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ok, _, err := b.publishBuffer.tryEnqueue(events)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		b.publishBuffer.rejected.Add(1)
+		return ErrPublishBufferFull
+	}
+	return nil
+}
+
+// PublishWithBackpressure is PublishAsync that waits for room in the buffer
+// instead of failing when it is full, slowing the caller down to the rate
+// NATS accepts events. It returns ctx's error if ctx ends first, in which
+// case no event was buffered.
+func (b *EventBus) PublishWithBackpressure(ctx context.Context, events []*domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	for {
+		ok, freed, err := b.publishBuffer.tryEnqueue(events)
+		if err != nil || ok {
+			return err
+		}
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// PublishStats returns the state of the publish buffer, e.g. to export the
+// number of buffered events as a metric.
+func (b *EventBus) PublishStats() PublishStats {
+	return b.publishBuffer.stats()
+}
@@ -0,0 +1,190 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
+	"github.com/plaenen/eventstore/pkg/messaging"
+)
+
+func asyncTestEvents(from, n int) []*domain.Event {
+	events := make([]*domain.Event, n)
+	for i := range events {
+		events[i] = &domain.Event{ID: fmt.Sprintf("evt-%d", from+i), AggregateID: "agg-1", AggregateType: "Test", EventType: "test.Created"}
+	}
+	return events
+}
+
+func TestPublishBuffer(t *testing.T) {
+	// The publisher blocks until unblocked, like NATS stalling under load
+	var (
+		mu        sync.Mutex
+		published []string
+	)
+	unblock := make(chan struct{})
+	publish := func(events []*domain.Event) error {
+		<-unblock
+		mu.Lock()
+		defer mu.Unlock()
+		for _, event := range events {
+			published = append(published, event.ID)
+		}
+		if events[0].ID == "evt-4" {
+			return errors.New("publish failed")
+		}
+		return nil
+	}
+
+	var failed []*domain.Event
+	bus := &EventBus{publishBuffer: startPublishBuffer(4, func(events []*domain.Event, err error) {
+		failed = append(failed, events...)
+	}, publish)}
+
+	if err := bus.PublishAsync(asyncTestEvents(0, 4)); err != nil {
+		t.Fatalf("expected the events to fit the buffer: %v", err)
+	}
+	if err := bus.PublishAsync(asyncTestEvents(4, 1)); !errors.Is(err, ErrPublishBufferFull) {
+		t.Fatalf("expected ErrPublishBufferFull, got %v", err)
+	}
+	if err := bus.PublishAsync(asyncTestEvents(4, 5)); err == nil || errors.Is(err, ErrPublishBufferFull) {
+		t.Fatalf("expected a batch larger than the buffer to be rejected for good, got %v", err)
+	}
+	if stats := bus.PublishStats(); stats.Buffered != 4 || stats.BufferSize != 4 || stats.Rejected != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// A backpressured publish waits for room, or gives up with its context
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := bus.PublishWithBackpressure(ctx, asyncTestEvents(4, 1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to pass while the buffer is full, got %v", err)
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		accepted <- bus.PublishWithBackpressure(context.Background(), asyncTestEvents(4, 2))
+	}()
+	select {
+	case err := <-accepted:
+		t.Fatalf("expected the publish to block while the buffer is full, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(unblock)
+	if err := <-accepted; err != nil {
+		t.Fatalf("failed to publish with backpressure: %v", err)
+	}
+
+	// Close publishes what is still buffered, in order
+	bus.publishBuffer.close()
+	want := []string{"evt-0", "evt-1", "evt-2", "evt-3", "evt-4", "evt-5"}
+	if fmt.Sprint(published) != fmt.Sprint(want) {
+		t.Errorf("expected events published in order %v, got %v", want, published)
+	}
+	if len(failed) != 2 || bus.PublishStats().Failed != 2 {
+		t.Errorf("expected the failed batch to be reported, got %d events", len(failed))
+	}
+	if err := bus.PublishAsync(asyncTestEvents(6, 1)); err == nil {
+		t.Error("expected PublishAsync to fail after close")
+	}
+}
+
+func TestConfigMaxInFlight(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithInProcess())
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	config := DefaultConfig()
+	config.URL = srv.URL()
+	config.ConnectOptions = srv.ConnectOptions()
+	config.MaxInFlight = 5
+	config.MaxAckPending = 10
+	bus, err := NewEventBus(config)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+	defer bus.Close()
+
+	received := make(chan string, 10)
+	handler := func(envelope *domain.EventEnvelope) error {
+		received <- envelope.Event.ID
+		return nil
+	}
+
+	// Subscriptions without a limit of their own get the configured one
+	sub, err := bus.Subscribe(messaging.EventFilter{}, handler)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+	if stats := sub.(messaging.StatsReporter).Stats(); stats.MaxInFlight != 5 {
+		t.Errorf("expected the configured max in-flight, got %+v", stats)
+	}
+
+	if err := bus.PublishAsync(asyncTestEvents(0, 3)); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case id := <-received:
+			if want := fmt.Sprintf("evt-%d", i); id != want {
+				t.Errorf("expected %s, got %s", want, id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout after %d of 3 events", i)
+		}
+	}
+}
+
+func TestEventBusCloseContext(t *testing.T) {
+	srv, err := natsserver.StartEmbeddedServer(natsserver.WithInProcess())
+	if err != nil {
+		t.Fatalf("failed to start embedded server: %v", err)
+	}
+	defer srv.Shutdown()
+
+	config := DefaultConfig()
+	config.URL = srv.URL()
+	config.ConnectOptions = srv.ConnectOptions()
+	bus, err := NewEventBus(config)
+	if err != nil {
+		t.Fatalf("failed to create event bus: %v", err)
+	}
+
+	// A handler that is stuck until the test ends
+	handling := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	if _, err := bus.Subscribe(messaging.EventFilter{}, func(*domain.EventEnvelope) error {
+		handling <- struct{}{}
+		<-release
+		return nil
+	}, messaging.WithMaxInFlight(1)); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := bus.Publish(asyncTestEvents(0, 1)); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	select {
+	case <-handling:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler was not called")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	if err := bus.CloseContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the close to give up at the deadline, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("expected close to return at the deadline, took %s", elapsed)
+	}
+}
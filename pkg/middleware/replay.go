@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/plaenen/eventstore/pkg/cqrs"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"google.golang.org/protobuf/proto"
+)
+
+// CommandResults looks up previously processed commands. Every
+// store.EventStore implements it.
+type CommandResults interface {
+	GetCommandResult(commandID string) (*domain.CommandResult, error)
+}
+
+// IdempotentRetryMiddleware marks the handler context with
+// eventsourcing.WithReplay when the command ID was already processed, so the
+// handler can skip side effects while SaveWithCommand returns the original
+// result. Commands without an ID, or whose lookup fails, run normally. See
+// IdempotentRetryCommandMiddleware for the command buses of package cqrs and
+// IdempotentRetryHandler for servers.
+func IdempotentRetryMiddleware(results CommandResults) eventsourcing.CommandMiddleware {
+	return func(next eventsourcing.CommandHandler) eventsourcing.CommandHandler {
+		return eventsourcing.CommandHandlerFunc(func(ctx context.Context, cmd *eventsourcing.CommandEnvelope) ([]*eventsourcing.Event, error) {
+			if commandID := cmd.Metadata.CommandID; commandID != "" {
+				if result, err := results.GetCommandResult(commandID); err == nil && result != nil {
+					ctx = eventsourcing.WithReplay(ctx)
+				}
+			}
+			return next.Handle(ctx, cmd)
+		})
+	}
+}
+
+// IdempotentRetryCommandMiddleware is IdempotentRetryMiddleware for the
+// command buses of package cqrs, such as the NATS command bus, which applies
+// it itself when configured with CommandBusConfig.CommandResults.
+func IdempotentRetryCommandMiddleware(results CommandResults) cqrs.CommandMiddleware {
+	return func(next cqrs.CommandHandler) cqrs.CommandHandler {
+		return cqrs.CommandHandlerFunc(func(ctx context.Context, cmd *domain.CommandEnvelope) ([]*domain.Event, error) {
+			if commandID := cmd.Metadata.CommandID; commandID != "" {
+				if result, err := results.GetCommandResult(commandID); err == nil && result != nil {
+					ctx = domain.WithReplay(ctx)
+				}
+			}
+			return next.Handle(ctx, cmd)
+		})
+	}
+}
+
+// IdempotentRetryHandler is IdempotentRetryMiddleware for servers (see
+// cqrs.ServerConfig.Middleware), whose requests carry no command ID: a
+// request is a retry when its Idempotency-Key was already recorded for the
+// aggregate aggregateID returns. Requests without a key, or whose aggregate
+// is unknown (""), run normally.
+//
+// Example:
+//
+//	config.Middleware = append(config.Middleware, middleware.IdempotentRetryHandler(eventStore,
+//	    func(request proto.Message) string {
+//	        if cmd, ok := request.(interface{ GetAccountId() string }); ok {
+//	            return cmd.GetAccountId()
+//	        }
+//	        return ""
+//	    }))
+func IdempotentRetryHandler(results CommandResults, aggregateID func(request proto.Message) string) cqrs.HandlerMiddleware {
+	return func(next cqrs.HandlerFunc) cqrs.HandlerFunc {
+		return func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+			if key, ok := domain.IdempotencyKeyFromContext(ctx); ok {
+				if id := aggregateID(request); id != "" {
+					if result, err := results.GetCommandResult(domain.IdempotencyCommandID(id, key)); err == nil && result != nil {
+						ctx = domain.WithReplay(ctx)
+					}
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
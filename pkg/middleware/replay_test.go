@@ -0,0 +1,86 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/middleware"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// processedCommands is a CommandResults holding a fixed set of command IDs.
+type processedCommands map[string]bool
+
+func (p processedCommands) GetCommandResult(commandID string) (*domain.CommandResult, error) {
+	if !p[commandID] {
+		return nil, errors.New("command not found")
+	}
+	return &domain.CommandResult{CommandID: commandID, AlreadyProcessed: true}, nil
+}
+
+func TestIdempotentRetryMiddleware(t *testing.T) {
+	var sawReplay bool
+	handler := middleware.IdempotentRetryMiddleware(processedCommands{"cmd-1": true})(
+		eventsourcing.CommandHandlerFunc(func(ctx context.Context, cmd *eventsourcing.CommandEnvelope) ([]*eventsourcing.Event, error) {
+			sawReplay = eventsourcing.IsReplay(ctx)
+			return nil, nil
+		}),
+	)
+
+	tests := []struct {
+		commandID string
+		replay    bool
+	}{
+		{commandID: "cmd-1", replay: true},
+		{commandID: "cmd-2", replay: false},
+		{commandID: "", replay: false},
+	}
+	for _, tt := range tests {
+		cmd := &eventsourcing.CommandEnvelope{Metadata: eventsourcing.CommandMetadata{CommandID: tt.commandID}}
+		if _, err := handler.Handle(context.Background(), cmd); err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		if sawReplay != tt.replay {
+			t.Errorf("command %q: expected IsReplay %v, got %v", tt.commandID, tt.replay, sawReplay)
+		}
+	}
+}
+
+func TestIdempotentRetryHandler(t *testing.T) {
+	var sawReplay bool
+	handler := middleware.IdempotentRetryHandler(
+		processedCommands{domain.IdempotencyCommandID("acc-1", "key-1"): true},
+		func(request proto.Message) string { return request.(*wrapperspb.StringValue).GetValue() },
+	)(func(ctx context.Context, request proto.Message) (*eventsourcing.Response, error) {
+		sawReplay = eventsourcing.IsReplay(ctx)
+		return nil, nil
+	})
+
+	tests := []struct {
+		aggregateID string
+		key         string
+		replay      bool
+	}{
+		{aggregateID: "acc-1", key: "key-1", replay: true},
+		{aggregateID: "acc-2", key: "key-1", replay: false},
+		{aggregateID: "acc-1", key: "key-2", replay: false},
+		{aggregateID: "acc-1", key: "", replay: false},
+		{aggregateID: "", key: "key-1", replay: false},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.key != "" {
+			ctx = domain.WithIdempotencyKey(ctx, tt.key)
+		}
+		if _, err := handler(ctx, wrapperspb.String(tt.aggregateID)); err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		if sawReplay != tt.replay {
+			t.Errorf("aggregate %q, key %q: expected IsReplay %v, got %v", tt.aggregateID, tt.key, tt.replay, sawReplay)
+		}
+	}
+}
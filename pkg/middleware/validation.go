@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/plaenen/eventstore/pkg/eventsourcing"
@@ -14,12 +15,18 @@ type Validator interface {
 }
 
 // ValidationMiddleware validates commands before they are handled.
+//
+// A failed validation is returned as a VALIDATION_FAILED error (see
+// eventsourcing.NewValidationError) listing the invalid fields, which the
+// transports pass on to the client. The fields are taken from the validator's
+// error, see FieldViolations.
 func ValidationMiddleware(validator Validator) eventsourcing.CommandMiddleware {
 	return func(next eventsourcing.CommandHandler) eventsourcing.CommandHandler {
 		return eventsourcing.CommandHandlerFunc(func(ctx context.Context, cmd *eventsourcing.CommandEnvelope) ([]*eventsourcing.Event, error) {
 			// Validate the command payload
 			if err := validator.Validate(cmd.Command); err != nil {
-				return nil, fmt.Errorf("command validation failed: %w", err)
+				return nil, eventsourcing.NewValidationError(
+					fmt.Sprintf("command validation failed: %v", err), FieldViolations(err)...)
 			}
 
 			// Proceed to next handler
@@ -70,3 +77,61 @@ func (v *ProtobufValidator) Validate(cmd interface{}) error {
 	// No validation available, pass through
 	return nil
 }
+
+// pgvError is the error protoc-gen-validate generates for a single field.
+type pgvError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// multiError is implemented by errors aggregating several validation errors,
+// such as protoc-gen-validate's ValidateAll errors.
+type multiError interface {
+	AllErrors() []error
+}
+
+// FieldViolations extracts the invalid fields from a validation error. It
+// understands:
+//   - errors carrying an AppError with field violations (eventsourcing.NewValidationError)
+//   - protoc-gen-validate errors, from Validate and ValidateAll; the field
+//     names it reports for embedded messages are joined with dots
+//   - errors.Join of any of the above
+//
+// Other errors yield no violations.
+func FieldViolations(err error) []*eventsourcing.FieldViolation {
+	var responseErr *eventsourcing.ResponseError
+	if errors.As(err, &responseErr) && responseErr.AppError != nil {
+		return responseErr.AppError.FieldViolations
+	}
+	return collectViolations("", err)
+}
+
+func collectViolations(prefix string, err error) []*eventsourcing.FieldViolation {
+	switch e := err.(type) {
+	case multiError:
+		var violations []*eventsourcing.FieldViolation
+		for _, inner := range e.AllErrors() {
+			violations = append(violations, collectViolations(prefix, inner)...)
+		}
+		return violations
+	case interface{ Unwrap() []error }:
+		var violations []*eventsourcing.FieldViolation
+		for _, inner := range e.Unwrap() {
+			violations = append(violations, collectViolations(prefix, inner)...)
+		}
+		return violations
+	case pgvError:
+		field := prefix + e.Field()
+		// An embedded message failed: report its fields instead
+		if nested := collectViolations(field+".", e.Cause()); len(nested) > 0 {
+			return nested
+		}
+		return []*eventsourcing.FieldViolation{{
+			Field:      field,
+			Constraint: "invalid",
+			Message:    e.Reason(),
+		}}
+	}
+	return nil
+}
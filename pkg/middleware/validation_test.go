@@ -0,0 +1,90 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/middleware"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// pgvError mimics the errors protoc-gen-validate generates for a field.
+type pgvError struct {
+	field  string
+	reason string
+	cause  error
+}
+
+func (e pgvError) Error() string  { return e.field + ": " + e.reason }
+func (e pgvError) Field() string  { return e.field }
+func (e pgvError) Reason() string { return e.reason }
+func (e pgvError) Cause() error   { return e.cause }
+
+// pgvMultiError mimics the error returned by protoc-gen-validate's ValidateAll.
+type pgvMultiError []error
+
+func (m pgvMultiError) Error() string      { return "multiple errors" }
+func (m pgvMultiError) AllErrors() []error { return m }
+
+type validatorFunc func(cmd interface{}) error
+
+func (f validatorFunc) Validate(cmd interface{}) error { return f(cmd) }
+
+func TestValidationMiddleware(t *testing.T) {
+	invalid := pgvMultiError{
+		pgvError{field: "AccountId", reason: "value length must be at least 1 runes"},
+		pgvError{
+			field:  "Owner",
+			reason: "embedded message failed validation",
+			cause:  pgvError{field: "Email", reason: "value must be a valid email address"},
+		},
+	}
+	validator := validatorFunc(func(cmd interface{}) error { return invalid })
+
+	handlerCalled := false
+	handler := middleware.ValidationMiddleware(validator)(eventsourcing.CommandHandlerFunc(
+		func(ctx context.Context, cmd *eventsourcing.CommandEnvelope) ([]*eventsourcing.Event, error) {
+			handlerCalled = true
+			return nil, nil
+		}))
+
+	_, err := handler.Handle(context.Background(), &eventsourcing.CommandEnvelope{Command: wrapperspb.String("cmd")})
+	if handlerCalled {
+		t.Error("expected an invalid command not to reach the handler")
+	}
+
+	var responseErr *eventsourcing.ResponseError
+	if !errors.As(err, &responseErr) || responseErr.Code() != eventsourcing.ValidationFailedCode {
+		t.Fatalf("expected a %s error, got %v", eventsourcing.ValidationFailedCode, err)
+	}
+
+	violations := responseErr.FieldViolations()
+	want := []struct{ field, message string }{
+		{"AccountId", "value length must be at least 1 runes"},
+		{"Owner.Email", "value must be a valid email address"},
+	}
+	if len(violations) != len(want) {
+		t.Fatalf("expected %d violations, got %v", len(want), violations)
+	}
+	for i, w := range want {
+		if violations[i].Field != w.field || violations[i].Message != w.message {
+			t.Errorf("violation %d: expected %s %q, got %v", i, w.field, w.message, violations[i])
+		}
+	}
+}
+
+func TestFieldViolations(t *testing.T) {
+	joined := errors.Join(
+		pgvError{field: "Amount", reason: "value must be greater than 0"},
+		errors.New("not a field error"),
+	)
+	if violations := middleware.FieldViolations(joined); len(violations) != 1 || violations[0].Field != "Amount" {
+		t.Errorf("expected the Amount violation from a joined error, got %v", violations)
+	}
+
+	if violations := middleware.FieldViolations(errors.New("flat message")); len(violations) != 0 {
+		t.Errorf("expected no violations from a plain error, got %v", violations)
+	}
+}
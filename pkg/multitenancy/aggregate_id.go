@@ -2,12 +2,13 @@ package multitenancy
 
 import (
 	"fmt"
-	"strings"
+
+	"github.com/plaenen/eventstore/pkg/domain"
 )
 
 const (
 	// TenantSeparator is used to separate tenant ID from aggregate ID
-	TenantSeparator = "::"
+	TenantSeparator = domain.TenantSeparator
 )
 
 // ComposeAggregateID creates a tenant-scoped aggregate ID
@@ -20,21 +21,16 @@ func ComposeAggregateID(tenantID, aggregateID string) string {
 	return fmt.Sprintf("%s%s%s", tenantID, TenantSeparator, aggregateID)
 }
 
-// DecomposeAggregateID splits a tenant-scoped aggregate ID into tenant ID and aggregate ID
-// Returns (tenantID, aggregateID, nil) or ("", aggregateID, nil) if no tenant prefix
+// DecomposeAggregateID splits a tenant-scoped aggregate ID into tenant ID and aggregate ID.
+// It forwards to domain.DecomposeAggregateID.
 func DecomposeAggregateID(compositeID string) (string, string, error) {
-	parts := strings.SplitN(compositeID, TenantSeparator, 2)
-
-	if len(parts) == 1 {
-		// No tenant prefix
-		return "", parts[0], nil
-	}
-
-	if len(parts) == 2 {
-		return parts[0], parts[1], nil
-	}
+	return domain.DecomposeAggregateID(compositeID)
+}
 
-	return "", "", fmt.Errorf("invalid composite aggregate ID: %s", compositeID)
+// EventTenantID returns the tenant of an event. It forwards to
+// domain.EventTenantID.
+func EventTenantID(event *domain.Event) string {
+	return domain.EventTenantID(event)
 }
 
 // ExtractTenantID extracts just the tenant ID from a composite aggregate ID
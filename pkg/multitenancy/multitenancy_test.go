@@ -2,12 +2,21 @@ package multitenancy
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
 	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store"
 )
 
 // testApplier is a simple applier for testing that actually updates the aggregate state
@@ -162,6 +171,101 @@ func TestSharedDatabaseTenantIsolation(t *testing.T) {
 	}
 }
 
+func TestSharedDatabaseAutoPrefix(t *testing.T) {
+	multiStore, err := NewMultiTenantEventStore(MultiTenantConfig{
+		Strategy:   SharedDatabase,
+		SharedDSN:  ":memory:",
+		WALMode:    true,
+		AutoPrefix: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create multi-tenant store: %v", err)
+	}
+	defer multiStore.Close()
+
+	if _, err := multiStore.GetStore(context.Background()); err == nil {
+		t.Fatal("Expected error when getting store without tenant in context")
+	}
+
+	applier := &testApplier{}
+	openAccount := func(tenantID, owner string) {
+		t.Helper()
+		eventStore, err := multiStore.GetStore(WithTenantID(context.Background(), tenantID))
+		if err != nil {
+			t.Fatalf("Failed to get store for %s: %v", tenantID, err)
+		}
+		repo := accountv1.NewAccountRepository(eventStore, func(id string) *accountv1.AccountAggregate {
+			return accountv1.NewAccount(id, applier)
+		})
+
+		account := accountv1.NewAccount("acc-001", applier) // Bare local ID
+		commandID := eventsourcing.GenerateID()
+		account.SetCommandID(commandID)
+		err = account.ApplyAccountOpenedEvent(&accountv1.AccountOpenedEvent{
+			AccountId:      "acc-001",
+			OwnerName:      owner,
+			InitialBalance: "100.00",
+		}, accountv1.WithMetadata(domain.EventMetadata{CausationID: commandID}))
+		if err != nil {
+			t.Fatalf("Failed to apply event for %s: %v", tenantID, err)
+		}
+		if _, err := repo.SaveWithCommand(account, commandID); err != nil {
+			t.Fatalf("Failed to save account for %s: %v", tenantID, err)
+		}
+	}
+	openAccount("tenant-a", "Alice")
+	openAccount("tenant-b", "Bob")
+
+	for tenantID, owner := range map[string]string{"tenant-a": "Alice", "tenant-b": "Bob"} {
+		eventStore, err := multiStore.GetStore(WithTenantID(context.Background(), tenantID))
+		if err != nil {
+			t.Fatalf("Failed to get store for %s: %v", tenantID, err)
+		}
+		repo := accountv1.NewAccountRepository(eventStore, func(id string) *accountv1.AccountAggregate {
+			return accountv1.NewAccount(id, applier)
+		})
+
+		loaded, err := repo.Load("acc-001")
+		if err != nil {
+			t.Fatalf("Failed to load account for %s: %v", tenantID, err)
+		}
+		if loaded.OwnerName != owner {
+			t.Errorf("%s: expected owner %s, got %s", tenantID, owner, loaded.OwnerName)
+		}
+		if loaded.ID() != "acc-001" {
+			t.Errorf("%s: expected local aggregate ID acc-001, got %s", tenantID, loaded.ID())
+		}
+
+		events, err := eventStore.LoadAllEvents(context.Background(), 0, 100)
+		if err != nil {
+			t.Fatalf("Failed to load all events for %s: %v", tenantID, err)
+		}
+		if len(events) != 1 || events[0].AggregateID != "acc-001" || events[0].Metadata.TenantID != tenantID {
+			t.Errorf("%s: expected only its own event, got %+v", tenantID, events)
+		}
+	}
+
+	// The shared database holds tenant-prefixed IDs
+	version, err := multiStore.sharedStore.GetAggregateVersion("tenant-a::acc-001")
+	if err != nil || version != 1 {
+		t.Errorf("Expected tenant-a::acc-001 at version 1 in shared store, got %d (%v)", version, err)
+	}
+
+	// Events claiming another tenant are rejected
+	storeA, _ := multiStore.GetStore(WithTenantID(context.Background(), "tenant-a"))
+	err = storeA.AppendEvents(context.Background(), "acc-002", 0, []*domain.Event{{
+		ID:            eventsourcing.GenerateID(),
+		AggregateID:   "acc-002",
+		AggregateType: "Account",
+		EventType:     "test.Event",
+		Version:       1,
+		Metadata:      domain.EventMetadata{TenantID: "tenant-b"},
+	}})
+	if err == nil {
+		t.Error("Expected error when appending an event of another tenant")
+	}
+}
+
 func TestComposeDecomposeAggregateID(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -214,6 +318,38 @@ func TestComposeDecomposeAggregateID(t *testing.T) {
 	}
 }
 
+func TestEventTenantID(t *testing.T) {
+	tests := []struct {
+		name  string
+		event domain.Event
+		want  string
+	}{
+		{
+			name:  "Tenant metadata",
+			event: domain.Event{AggregateID: "tenant-b::acc-123", Metadata: domain.EventMetadata{TenantID: "tenant-a"}},
+			want:  "tenant-a",
+		},
+		{
+			name:  "Composite aggregate ID",
+			event: domain.Event{AggregateID: "tenant-b::acc-123"},
+			want:  "tenant-b",
+		},
+		{
+			name:  "No tenant",
+			event: domain.Event{AggregateID: "acc-123"},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EventTenantID(&tt.event); got != tt.want {
+				t.Errorf("EventTenantID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidateTenantID(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -418,3 +554,466 @@ func TestDatabasePerTenant(t *testing.T) {
 		t.Errorf("Expected owner Yolanda, got %s", loadedY.OwnerName)
 	}
 }
+
+func TestValidateTenant(t *testing.T) {
+	dir := t.TempDir()
+	multiStore, err := NewMultiTenantEventStore(MultiTenantConfig{
+		Strategy:             DatabasePerTenant,
+		DatabasePathTemplate: filepath.Join(dir, "tenant_%s.db"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create multi-tenant store: %v", err)
+	}
+	defer multiStore.Close()
+
+	if err := multiStore.ValidateTenant("tenant-a"); err != nil {
+		t.Errorf("Expected tenant-a to be valid, got %v", err)
+	}
+
+	for _, tenantID := range []string{"../../etc/x", "a/b", `..\x`, "x?_pragma=foo", ""} {
+		if err := multiStore.ValidateTenant(tenantID); !errors.Is(err, ErrInvalidTenant) {
+			t.Errorf("Expected %q to be rejected with ErrInvalidTenant, got %v", tenantID, err)
+		}
+	}
+
+	// GetStore must refuse to open the malicious path
+	ctx := WithTenantID(context.Background(), "../../etc/x")
+	if _, err := multiStore.GetStore(ctx); !errors.Is(err, ErrInvalidTenant) {
+		t.Errorf("Expected GetStore to reject a traversing tenant ID, got %v", err)
+	}
+
+	missing, err := NewMultiTenantEventStore(MultiTenantConfig{
+		Strategy:             DatabasePerTenant,
+		DatabasePathTemplate: filepath.Join(dir, "missing", "tenant_%s.db"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create multi-tenant store: %v", err)
+	}
+	if err := missing.ValidateTenant("tenant-a"); err == nil {
+		t.Error("Expected a missing directory to be reported")
+	}
+
+	for _, template := range []string{filepath.Join(dir, "tenant.db"), filepath.Join(dir, "%s_%s.db"), filepath.Join(dir, "%d.db")} {
+		if _, err := NewMultiTenantEventStore(MultiTenantConfig{
+			Strategy:             DatabasePerTenant,
+			DatabasePathTemplate: template,
+		}); err == nil {
+			t.Errorf("Expected template %q to be rejected", template)
+		}
+	}
+}
+
+// openTestAccount saves an opened account with aggregate ID id to eventStore.
+func openTestAccount(t *testing.T, eventStore store.EventStore, id, owner string) {
+	t.Helper()
+	applier := &testApplier{}
+	repo := accountv1.NewAccountRepository(eventStore, func(id string) *accountv1.AccountAggregate {
+		return accountv1.NewAccount(id, applier)
+	})
+
+	account := accountv1.NewAccount(id, applier)
+	commandID := eventsourcing.GenerateID()
+	account.SetCommandID(commandID)
+	err := account.ApplyAccountOpenedEvent(&accountv1.AccountOpenedEvent{
+		AccountId:      id,
+		OwnerName:      owner,
+		InitialBalance: "100.00",
+	}, accountv1.WithMetadata(domain.EventMetadata{CausationID: commandID}))
+	if err != nil {
+		t.Fatalf("Failed to apply event for %s: %v", id, err)
+	}
+	if _, err := repo.SaveWithCommand(account, commandID); err != nil {
+		t.Fatalf("Failed to save account %s: %v", id, err)
+	}
+}
+
+func TestListAndDeleteTenants(t *testing.T) {
+	dir := t.TempDir()
+	multiStore, err := NewMultiTenantEventStore(MultiTenantConfig{
+		Strategy:             DatabasePerTenant,
+		DatabasePathTemplate: filepath.Join(dir, "tenant_%s.db"),
+		WALMode:              true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create multi-tenant store: %v", err)
+	}
+	defer multiStore.Close()
+
+	for _, tenantID := range []string{"tenant-y", "tenant-x"} {
+		eventStore, err := multiStore.GetStore(WithTenantID(context.Background(), tenantID))
+		if err != nil {
+			t.Fatalf("Failed to get store for %s: %v", tenantID, err)
+		}
+		openTestAccount(t, eventStore, "acc-001", tenantID)
+	}
+	// Files not matching the template are not tenants
+	if err := os.WriteFile(filepath.Join(dir, "backup.db"), nil, 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tenants, err := multiStore.ListTenants()
+	if err != nil {
+		t.Fatalf("Failed to list tenants: %v", err)
+	}
+	if !slices.Equal(tenants, []string{"tenant-x", "tenant-y"}) {
+		t.Errorf("Expected tenants [tenant-x tenant-y], got %v", tenants)
+	}
+
+	if err := multiStore.DeleteTenant(context.Background(), "tenant-x"); err != nil {
+		t.Fatalf("Failed to delete tenant: %v", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "tenant_tenant-x.db*")); len(matches) != 0 {
+		t.Errorf("Expected the database files of tenant-x to be removed, got %v", matches)
+	}
+	tenants, err = multiStore.ListTenants()
+	if err != nil || !slices.Equal(tenants, []string{"tenant-y"}) {
+		t.Errorf("Expected tenants [tenant-y] after deletion, got %v (%v)", tenants, err)
+	}
+
+	if err := multiStore.DeleteTenant(context.Background(), "tenant-x"); !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("Expected ErrTenantNotFound deleting a deleted tenant, got %v", err)
+	}
+	if err := multiStore.DeleteTenant(context.Background(), "../tenant-y"); !errors.Is(err, ErrInvalidTenant) {
+		t.Errorf("Expected ErrInvalidTenant for a path tenant ID, got %v", err)
+	}
+
+	// A deleted tenant starts over with an empty database
+	eventStore, err := multiStore.GetStore(WithTenantID(context.Background(), "tenant-x"))
+	if err != nil {
+		t.Fatalf("Failed to get store for tenant-x: %v", err)
+	}
+	if version, err := eventStore.GetAggregateVersion("acc-001"); err != nil || version != 0 {
+		t.Errorf("Expected no events for tenant-x, got version %d (%v)", version, err)
+	}
+}
+
+func TestDeleteSharedTenant(t *testing.T) {
+	multiStore, err := NewMultiTenantEventStore(MultiTenantConfig{
+		Strategy:   SharedDatabase,
+		SharedDSN:  ":memory:",
+		AutoPrefix: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create multi-tenant store: %v", err)
+	}
+	defer multiStore.Close()
+
+	// tenant-ab shares tenant-a's prefix but not its separator
+	for _, tenantID := range []string{"tenant-a", "tenant-ab"} {
+		eventStore, err := multiStore.GetStore(WithTenantID(context.Background(), tenantID))
+		if err != nil {
+			t.Fatalf("Failed to get store for %s: %v", tenantID, err)
+		}
+		openTestAccount(t, eventStore, "acc-001", tenantID)
+		openTestAccount(t, eventStore, "acc-002", tenantID)
+	}
+
+	if err := multiStore.DeleteTenant(context.Background(), "tenant-a"); err != nil {
+		t.Fatalf("Failed to delete tenant: %v", err)
+	}
+
+	for tenantID, want := range map[string]int{"tenant-a": 0, "tenant-ab": 2} {
+		eventStore, _ := multiStore.GetStore(WithTenantID(context.Background(), tenantID))
+		events, err := eventStore.LoadAllEvents(context.Background(), 0, 100)
+		if err != nil {
+			t.Fatalf("Failed to load events of %s: %v", tenantID, err)
+		}
+		if len(events) != want {
+			t.Errorf("%s: expected %d events, got %d", tenantID, want, len(events))
+		}
+	}
+
+	if err := multiStore.DeleteTenant(context.Background(), "tenant-a"); !errors.Is(err, ErrTenantNotFound) {
+		t.Errorf("Expected ErrTenantNotFound deleting a deleted tenant, got %v", err)
+	}
+	if _, err := multiStore.ListTenants(); err == nil {
+		t.Error("Expected listing tenants of a shared database to fail")
+	}
+}
+
+// openTenantsRecorder records the most tenant stores reported open at once.
+type openTenantsRecorder struct {
+	mu   sync.Mutex
+	peak int64
+}
+
+func (r *openTenantsRecorder) RecordOpenTenantStores(ctx context.Context, open int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.peak = max(r.peak, open)
+}
+
+// openTenantFiles returns the tenants whose database files under dir the
+// process holds file descriptors for.
+func openTenantFiles(t *testing.T, dir string) map[string]bool {
+	t.Helper()
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skipf("cannot list file descriptors: %v", err)
+	}
+	tenants := make(map[string]bool)
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join("/proc/self/fd", fd.Name()))
+		if err != nil || filepath.Dir(target) != dir {
+			continue
+		}
+		if name, ok := strings.CutPrefix(filepath.Base(target), "tenant_"); ok {
+			name, _, _ = strings.Cut(name, ".db")
+			tenants[name] = true
+		}
+	}
+	return tenants
+}
+
+func TestMaxOpenTenants(t *testing.T) {
+	const (
+		maxOpen    = 4
+		tenants    = 30
+		workers    = 16
+		iterations = 40
+	)
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	recorder := &openTenantsRecorder{}
+	multiStore, err := NewMultiTenantEventStore(MultiTenantConfig{
+		Strategy:             DatabasePerTenant,
+		DatabasePathTemplate: filepath.Join(dir, "tenant_%s.db"),
+		WALMode:              true,
+		MaxOpenTenants:       maxOpen,
+		Metrics:              recorder,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create multi-tenant store: %v", err)
+	}
+	defer multiStore.Close()
+	openTenantFiles(t, dir) // Skips without /proc
+
+	// Sample the open database files while the workers thrash the tenants
+	done := make(chan struct{})
+	sampled := make(chan int)
+	go func() {
+		most := 0
+		for {
+			select {
+			case <-done:
+				sampled <- most
+				return
+			default:
+				// Tenants being opened or closed keep their slot, so the
+				// descriptors never outnumber the slots held under the lock
+				multiStore.tenantStoresMu.Lock()
+				most = max(most, len(openTenantFiles(t, dir)))
+				multiStore.tenantStoresMu.Unlock()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				tenantID := fmt.Sprintf("tenant-%02d", (w*7+i)%tenants)
+				eventStore, err := multiStore.GetStore(WithTenantID(context.Background(), tenantID))
+				if err != nil {
+					errs <- err
+					return
+				}
+				aggregateID := fmt.Sprintf("acc-%d-%d", w, i)
+				err = eventStore.AppendEvents(context.Background(), aggregateID, 0, []*domain.Event{{
+					ID:            eventsourcing.GenerateID(),
+					AggregateID:   aggregateID,
+					AggregateType: "Account",
+					EventType:     "test.AccountOpened",
+					Version:       1,
+					Timestamp:     time.Now(),
+					Data:          []byte("{}"),
+				}})
+				if err != nil {
+					errs <- fmt.Errorf("%s: %w", tenantID, err)
+					return
+				}
+				if events, err := eventStore.LoadEvents(context.Background(), aggregateID, 0); err != nil || len(events) != 1 {
+					errs <- fmt.Errorf("%s: expected 1 event, got %d (%v)", tenantID, len(events), err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	mostFiles := <-sampled
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if mostFiles > maxOpen {
+		t.Errorf("Expected at most %d tenant databases open, saw %d", maxOpen, mostFiles)
+	}
+	if recorder.peak > maxOpen || recorder.peak == 0 {
+		t.Errorf("Expected the open tenant metric to peak at 1..%d, got %d", maxOpen, recorder.peak)
+	}
+	if open := multiStore.OpenTenants(); open > maxOpen {
+		t.Errorf("Expected at most %d open tenants, got %d", maxOpen, open)
+	}
+
+	// Evicted tenants reopen with all their events
+	var total int
+	for i := 0; i < tenants; i++ {
+		eventStore, err := multiStore.GetStore(WithTenantID(context.Background(), fmt.Sprintf("tenant-%02d", i)))
+		if err != nil {
+			t.Fatalf("Failed to get store: %v", err)
+		}
+		events, err := eventStore.LoadAllEvents(context.Background(), 0, workers*iterations)
+		if err != nil {
+			t.Fatalf("Failed to load events: %v", err)
+		}
+		total += len(events)
+	}
+	if total != workers*iterations {
+		t.Errorf("Expected %d events across tenants, got %d", workers*iterations, total)
+	}
+}
+
+func TestMaxOpenTenantsWaitHonoursContext(t *testing.T) {
+	multiStore, err := NewMultiTenantEventStore(MultiTenantConfig{
+		Strategy:             DatabasePerTenant,
+		DatabasePathTemplate: filepath.Join(t.TempDir(), "tenant_%s.db"),
+		MaxOpenTenants:       1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create multi-tenant store: %v", err)
+	}
+	defer multiStore.Close()
+
+	_, release, err := multiStore.acquire(context.Background(), "tenant-a")
+	if err != nil {
+		t.Fatalf("Failed to acquire tenant-a: %v", err)
+	}
+
+	// Every slot is in use, so tenant-b waits until its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := multiStore.acquire(ctx, "tenant-b"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded while all stores are busy, got %v", err)
+	}
+
+	// Releasing tenant-a lets a waiting tenant-b evict it
+	acquired := make(chan error, 1)
+	go func() {
+		_, releaseB, err := multiStore.acquire(context.Background(), "tenant-b")
+		if err == nil {
+			releaseB()
+		}
+		acquired <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatalf("Failed to acquire tenant-b: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tenant-b was not acquired after tenant-a was released")
+	}
+	if open := multiStore.OpenTenants(); open != 1 {
+		t.Errorf("Expected 1 open tenant, got %d", open)
+	}
+}
+
+func TestPooledTenantStoreForwardsInterfaces(t *testing.T) {
+	multiStore, err := NewMultiTenantEventStore(MultiTenantConfig{
+		Strategy:             DatabasePerTenant,
+		DatabasePathTemplate: filepath.Join(t.TempDir(), "tenant_%s.db"),
+		MaxOpenTenants:       1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create multi-tenant store: %v", err)
+	}
+	defer multiStore.Close()
+
+	ctx := context.Background()
+	eventStore, err := multiStore.GetStore(WithTenantID(ctx, "tenant-a"))
+	if err != nil {
+		t.Fatalf("Failed to get store: %v", err)
+	}
+
+	appender, ok := eventStore.(store.MultiAggregateAppender)
+	if !ok {
+		t.Fatalf("Expected %T to implement store.MultiAggregateAppender", eventStore)
+	}
+	newEvent := func(aggregateID string) *domain.Event {
+		return &domain.Event{
+			ID:            eventsourcing.GenerateID(),
+			AggregateID:   aggregateID,
+			AggregateType: "Account",
+			EventType:     "test.AccountOpened",
+			Version:       1,
+			Timestamp:     time.Now(),
+			Data:          []byte("{}"),
+		}
+	}
+	err = appender.AppendEventsMulti(ctx, []store.AggregateAppend{
+		{AggregateID: "acc-1", Events: []*domain.Event{newEvent("acc-1")}},
+		{AggregateID: "acc-2", Events: []*domain.Event{newEvent("acc-2")}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to append to two aggregates: %v", err)
+	}
+
+	// Evict tenant-a so every call below has to reopen it
+	other, err := multiStore.GetStore(WithTenantID(ctx, "tenant-b"))
+	if err != nil {
+		t.Fatalf("Failed to get store: %v", err)
+	}
+	if _, err := other.GetAggregateVersion("acc-1"); err != nil {
+		t.Fatalf("Failed to open tenant-b: %v", err)
+	}
+
+	var streamed int
+	for _, err := range eventStore.(store.EventStreamer).StreamEvents("acc-1", 0, 10) {
+		if err != nil {
+			t.Fatalf("Failed to stream events: %v", err)
+		}
+		streamed++
+	}
+	if streamed != 1 {
+		t.Errorf("Expected 1 streamed event, got %d", streamed)
+	}
+	if position, err := eventStore.(store.LatestPositionReader).LatestPosition(ctx); err != nil || position == 0 {
+		t.Errorf("Expected a latest position, got %d (%v)", position, err)
+	}
+	if count, err := eventStore.(store.EventCounter).CountEvents(); err != nil || count != 2 {
+		t.Errorf("Expected 2 events, got %d (%v)", count, err)
+	}
+
+	snapshots, ok := eventStore.(store.SnapshotStore)
+	if !ok {
+		t.Fatalf("Expected %T to implement store.SnapshotStore", eventStore)
+	}
+	err = snapshots.SaveSnapshot(&store.Snapshot{
+		AggregateID:   "acc-1",
+		AggregateType: "Account",
+		Version:       1,
+		Data:          []byte("state"),
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+	if snapshot, err := snapshots.GetLatestSnapshot("acc-1"); err != nil || string(snapshot.Data) != "state" {
+		t.Errorf("Expected the saved snapshot, got %+v (%v)", snapshot, err)
+	}
+
+	db, ok := eventStore.(interface{ DB() *sql.DB })
+	if !ok || db.DB() == nil {
+		t.Error("Expected the pooled store to expose the tenant database")
+	}
+}
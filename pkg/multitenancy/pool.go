@@ -0,0 +1,460 @@
+package multitenancy
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+// tenantStore is the open database of one tenant (DatabasePerTenant).
+type tenantStore struct {
+	tenantID string
+	store    *sqlite.EventStore
+	inUse    int           // Calls in flight; the store is not evicted while > 0
+	elem     *list.Element // Position in the LRU list
+
+	// pending is closed once the store, being opened or closed outside
+	// tenantStoresMu, is settled (nil = settled). The entry keeps its slot
+	// meanwhile, so MaxOpenTenants also bounds the files being opened.
+	pending chan struct{}
+}
+
+// OpenTenants returns the number of tenant databases currently open
+// (DatabasePerTenant only).
+func (m *MultiTenantEventStore) OpenTenants() int {
+	m.tenantStoresMu.RLock()
+	defer m.tenantStoresMu.RUnlock()
+	return len(m.tenantStores)
+}
+
+// acquire returns the open store of tenantID, opening it if needed, and marks
+// it in use until release is called. With MaxOpenTenants reached, it first
+// closes the least recently used idle store, or waits for one to fall idle
+// until ctx is done. Stores are opened and closed without holding
+// tenantStoresMu, so a slow open of one tenant does not stall the others.
+func (m *MultiTenantEventStore) acquire(ctx context.Context, tenantID string) (eventStore *sqlite.EventStore, release func(), err error) {
+	if err := m.ValidateTenant(tenantID); err != nil {
+		return nil, nil, err
+	}
+
+	m.tenantStoresMu.Lock()
+	for {
+		if m.closed {
+			m.tenantStoresMu.Unlock()
+			return nil, nil, ErrStoreClosed
+		}
+		if ts, open := m.tenantStores[tenantID]; open {
+			if ts.pending != nil {
+				if err := m.wait(ctx, ts.pending); err != nil {
+					m.tenantStoresMu.Unlock()
+					return nil, nil, err
+				}
+				continue
+			}
+			ts.inUse++
+			m.lru.MoveToFront(ts.elem)
+			m.tenantStoresMu.Unlock()
+			return ts.store, func() { m.release(ts) }, nil
+		}
+
+		limit := m.config.MaxOpenTenants
+		if limit <= 0 || len(m.tenantStores) < limit {
+			break
+		}
+		if idle := m.beginEvict(); idle != nil {
+			m.tenantStoresMu.Unlock()
+			closeErr := m.closeStore(idle)
+			m.tenantStoresMu.Lock()
+			m.endClose(idle)
+			if closeErr != nil {
+				m.tenantStoresMu.Unlock()
+				return nil, nil, closeErr
+			}
+			continue
+		}
+		if err := m.wait(ctx, m.released); err != nil {
+			m.tenantStoresMu.Unlock()
+			return nil, nil, err
+		}
+	}
+
+	// Hold the tenant's slot while its database is opened
+	ts := &tenantStore{tenantID: tenantID, inUse: 1, pending: make(chan struct{})}
+	ts.elem = m.lru.PushFront(ts)
+	m.tenantStores[tenantID] = ts
+	m.recordOpenTenants()
+	m.tenantStoresMu.Unlock()
+
+	// Create new tenant database
+	dsn := fmt.Sprintf(m.config.DatabasePathTemplate, tenantID)
+	opened, err := sqlite.NewEventStore(
+		sqlite.WithDSN(dsn),
+		sqlite.WithWALMode(m.config.WALMode),
+	)
+
+	m.tenantStoresMu.Lock()
+	defer m.tenantStoresMu.Unlock()
+	if err != nil {
+		m.endClose(ts)
+		return nil, nil, fmt.Errorf("failed to create tenant store for %s: %w", tenantID, err)
+	}
+	if m.closed {
+		opened.Close()
+		m.endClose(ts)
+		return nil, nil, ErrStoreClosed
+	}
+	ts.store = opened
+	close(ts.pending)
+	ts.pending = nil
+	return ts.store, func() { m.release(ts) }, nil
+}
+
+// release marks one call on ts as done.
+func (m *MultiTenantEventStore) release(ts *tenantStore) {
+	m.tenantStoresMu.Lock()
+	defer m.tenantStoresMu.Unlock()
+
+	ts.inUse--
+	if ts.inUse == 0 {
+		m.broadcastReleased()
+	}
+}
+
+// wait releases tenantStoresMu until ch is closed or ctx is done, and takes
+// it again. The caller holds tenantStoresMu.
+func (m *MultiTenantEventStore) wait(ctx context.Context, ch <-chan struct{}) error {
+	m.tenantStoresMu.Unlock()
+	defer m.tenantStoresMu.Lock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// broadcastReleased wakes the callers waiting for a tenant store to fall idle
+// or be closed. The caller holds tenantStoresMu.
+func (m *MultiTenantEventStore) broadcastReleased() {
+	close(m.released)
+	m.released = make(chan struct{})
+}
+
+// beginEvict marks the least recently used store not in use as closing and
+// returns it, or nil if every open store is in use. The caller holds
+// tenantStoresMu, closes the store without it, then calls endClose.
+func (m *MultiTenantEventStore) beginEvict() *tenantStore {
+	for elem := m.lru.Back(); elem != nil; elem = elem.Prev() {
+		if ts := elem.Value.(*tenantStore); ts.inUse == 0 && ts.pending == nil {
+			ts.pending = make(chan struct{})
+			return ts
+		}
+	}
+	return nil
+}
+
+// endClose forgets ts, whose store was closed or failed to open, and wakes
+// the callers waiting for it. The caller holds tenantStoresMu.
+func (m *MultiTenantEventStore) endClose(ts *tenantStore) {
+	delete(m.tenantStores, ts.tenantID)
+	m.lru.Remove(ts.elem)
+	m.recordOpenTenants()
+	close(ts.pending)
+	ts.pending = nil
+	m.broadcastReleased()
+}
+
+// closeStore checkpoints the WAL of ts into its database file and closes it.
+// The caller does not hold tenantStoresMu.
+func (m *MultiTenantEventStore) closeStore(ts *tenantStore) error {
+	if m.config.WALMode {
+		if _, err := ts.store.CheckpointWAL(); err != nil {
+			ts.store.Close()
+			return fmt.Errorf("failed to checkpoint store for tenant %s: %w", ts.tenantID, err)
+		}
+	}
+	if err := ts.store.Close(); err != nil {
+		return fmt.Errorf("failed to close store for tenant %s: %w", ts.tenantID, err)
+	}
+	return nil
+}
+
+// recordOpenTenants reports the number of open tenant stores to the metrics.
+// The caller holds tenantStoresMu.
+func (m *MultiTenantEventStore) recordOpenTenants() {
+	if m.config.Metrics != nil {
+		m.config.Metrics.RecordOpenTenantStores(context.Background(), int64(len(m.tenantStores)))
+	}
+}
+
+// pooledTenantStore is the store GetStore returns with MaxOpenTenants set. It
+// holds no database itself: each call acquires the tenant's store from the
+// pool, reopening it if it was evicted. It forwards the optional interfaces
+// of the SQLite store.
+type pooledTenantStore struct {
+	owner    *MultiTenantEventStore
+	tenantID string
+}
+
+// AppendEvents appends events to the tenant's store.
+func (s *pooledTenantStore) AppendEvents(ctx context.Context, aggregateID string, expectedVersion int64, events []*domain.Event) error {
+	eventStore, release, err := s.owner.acquire(ctx, s.tenantID)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return eventStore.AppendEvents(ctx, aggregateID, expectedVersion, events)
+}
+
+// AppendEventsIdempotent appends events with command-level idempotency to the tenant's store.
+func (s *pooledTenantStore) AppendEventsIdempotent(
+	ctx context.Context,
+	aggregateID string,
+	expectedVersion int64,
+	events []*domain.Event,
+	commandID string,
+	ttl time.Duration,
+) (*domain.CommandResult, error) {
+	eventStore, release, err := s.owner.acquire(ctx, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return eventStore.AppendEventsIdempotent(ctx, aggregateID, expectedVersion, events, commandID, ttl)
+}
+
+// GetCommandResult returns the result of a processed command.
+func (s *pooledTenantStore) GetCommandResult(commandID string) (*domain.CommandResult, error) {
+	eventStore, release, err := s.owner.acquire(context.Background(), s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return eventStore.GetCommandResult(commandID)
+}
+
+// LoadEvents loads the events of an aggregate after afterVersion.
+func (s *pooledTenantStore) LoadEvents(ctx context.Context, aggregateID string, afterVersion int64) ([]*domain.Event, error) {
+	eventStore, release, err := s.owner.acquire(ctx, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return eventStore.LoadEvents(ctx, aggregateID, afterVersion)
+}
+
+// LoadAllEvents loads the tenant's events from fromPosition.
+func (s *pooledTenantStore) LoadAllEvents(ctx context.Context, fromPosition int64, limit int) ([]*domain.Event, error) {
+	eventStore, release, err := s.owner.acquire(ctx, s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return eventStore.LoadAllEvents(ctx, fromPosition, limit)
+}
+
+// LoadCompensations loads the events caused by a command.
+func (s *pooledTenantStore) LoadCompensations(commandID string) ([]*domain.Event, error) {
+	eventStore, release, err := s.owner.acquire(context.Background(), s.tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return eventStore.LoadCompensations(commandID)
+}
+
+// GetAggregateVersion returns the current version of an aggregate.
+func (s *pooledTenantStore) GetAggregateVersion(aggregateID string) (int64, error) {
+	eventStore, release, err := s.owner.acquire(context.Background(), s.tenantID)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	return eventStore.GetAggregateVersion(aggregateID)
+}
+
+// CheckUniqueness checks whether a unique constraint value is available.
+func (s *pooledTenantStore) CheckUniqueness(indexName, value string) (bool, string, error) {
+	eventStore, release, err := s.owner.acquire(context.Background(), s.tenantID)
+	if err != nil {
+		return false, "", err
+	}
+	defer release()
+	return eventStore.CheckUniqueness(indexName, value)
+}
+
+// GetConstraintOwner returns the aggregate owning a unique constraint value.
+func (s *pooledTenantStore) GetConstraintOwner(indexName, value string) (string, error) {
+	eventStore, release, err := s.owner.acquire(context.Background(), s.tenantID)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return eventStore.GetConstraintOwner(indexName, value)
+}
+
+// RebuildConstraints rebuilds the tenant's unique constraint index.
+func (s *pooledTenantStore) RebuildConstraints() error {
+	eventStore, release, err := s.owner.acquire(context.Background(), s.tenantID)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return eventStore.RebuildConstraints()
+}
+
+// Close is a no-op: the MultiTenantEventStore owns the tenant databases and
+// closes them on eviction and in its own Close.
+func (s *pooledTenantStore) Close() error {
+	return nil
+}
+
+// AppendEventsMulti appends the events of several aggregates atomically to
+// the tenant's store.
+func (s *pooledTenantStore) AppendEventsMulti(ctx context.Context, appends []store.AggregateAppend) error {
+	eventStore, release, err := s.owner.acquire(ctx, s.tenantID)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return eventStore.AppendEventsMulti(ctx, appends)
+}
+
+// StreamEvents yields the events of an aggregate after afterVersion. The
+// tenant's store stays acquired until iteration ends.
+func (s *pooledTenantStore) StreamEvents(aggregateID string, afterVersion int64, batchSize int) iter.Seq2[*domain.Event, error] {
+	return func(yield func(*domain.Event, error) bool) {
+		eventStore, release, err := s.owner.acquire(context.Background(), s.tenantID)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer release()
+		for event, err := range eventStore.StreamEvents(aggregateID, afterVersion, batchSize) {
+			if !yield(event, err) {
+				return
+			}
+		}
+	}
+}
+
+// StreamAllEvents yields the tenant's events from fromPosition. The tenant's
+// store stays acquired until iteration ends.
+func (s *pooledTenantStore) StreamAllEvents(ctx context.Context, fromPosition int64) iter.Seq2[*domain.Event, error] {
+	return func(yield func(*domain.Event, error) bool) {
+		eventStore, release, err := s.owner.acquire(ctx, s.tenantID)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer release()
+		for event, err := range eventStore.StreamAllEvents(ctx, fromPosition) {
+			if !yield(event, err) {
+				return
+			}
+		}
+	}
+}
+
+// LatestPosition returns the position of the tenant's latest event.
+func (s *pooledTenantStore) LatestPosition(ctx context.Context) (int64, error) {
+	eventStore, release, err := s.owner.acquire(ctx, s.tenantID)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	return eventStore.LatestPosition(ctx)
+}
+
+// CountEvents returns the number of events in the tenant's store.
+func (s *pooledTenantStore) CountEvents() (int64, error) {
+	eventStore, release, err := s.owner.acquire(context.Background(), s.tenantID)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	return eventStore.CountEvents()
+}
+
+// snapshots runs fn with the snapshot store of the tenant's database.
+func (s *pooledTenantStore) snapshots(fn func(*sqlite.SnapshotStore) error) error {
+	eventStore, release, err := s.owner.acquire(context.Background(), s.tenantID)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn(sqlite.NewSnapshotStore(eventStore.DB(), sqlite.WithSnapshotTablePrefix(eventStore.TablePrefix())))
+}
+
+// SaveSnapshot saves a snapshot in the tenant's store.
+func (s *pooledTenantStore) SaveSnapshot(snapshot *store.Snapshot) error {
+	return s.snapshots(func(snapshots *sqlite.SnapshotStore) error {
+		return snapshots.SaveSnapshot(snapshot)
+	})
+}
+
+// GetLatestSnapshot returns the latest snapshot of an aggregate.
+func (s *pooledTenantStore) GetLatestSnapshot(aggregateID string) (snapshot *store.Snapshot, err error) {
+	err = s.snapshots(func(snapshots *sqlite.SnapshotStore) error {
+		snapshot, err = snapshots.GetLatestSnapshot(aggregateID)
+		return err
+	})
+	return snapshot, err
+}
+
+// GetSnapshotBeforeVersion returns the latest snapshot of an aggregate at or
+// before version.
+func (s *pooledTenantStore) GetSnapshotBeforeVersion(aggregateID string, version int64) (snapshot *store.Snapshot, err error) {
+	err = s.snapshots(func(snapshots *sqlite.SnapshotStore) error {
+		snapshot, err = snapshots.GetSnapshotBeforeVersion(aggregateID, version)
+		return err
+	})
+	return snapshot, err
+}
+
+// DeleteOldSnapshots removes the snapshots of an aggregate older than
+// olderThanVersion.
+func (s *pooledTenantStore) DeleteOldSnapshots(aggregateID string, olderThanVersion int64) error {
+	return s.snapshots(func(snapshots *sqlite.SnapshotStore) error {
+		return snapshots.DeleteOldSnapshots(aggregateID, olderThanVersion)
+	})
+}
+
+// GetSnapshotStats returns statistics about the tenant's snapshots.
+func (s *pooledTenantStore) GetSnapshotStats() (stats *store.SnapshotStats, err error) {
+	err = s.snapshots(func(snapshots *sqlite.SnapshotStore) error {
+		stats, err = snapshots.GetSnapshotStats()
+		return err
+	})
+	return stats, err
+}
+
+// DB returns the database of the tenant's store, opening it if needed, or nil
+// if it cannot be opened. The database is closed when the tenant's store is
+// evicted, so use it only for short-lived work, e.g. to set up projections.
+func (s *pooledTenantStore) DB() *sql.DB {
+	eventStore, release, err := s.owner.acquire(context.Background(), s.tenantID)
+	if err != nil {
+		return nil
+	}
+	defer release()
+	return eventStore.DB()
+}
+
+var (
+	_ store.EventStore             = (*pooledTenantStore)(nil)
+	_ store.MultiAggregateAppender = (*pooledTenantStore)(nil)
+	_ store.EventStreamer          = (*pooledTenantStore)(nil)
+	_ store.AllEventsStreamer      = (*pooledTenantStore)(nil)
+	_ store.LatestPositionReader   = (*pooledTenantStore)(nil)
+	_ store.EventCounter           = (*pooledTenantStore)(nil)
+	_ store.SnapshotStore          = (*pooledTenantStore)(nil)
+)
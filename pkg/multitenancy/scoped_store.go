@@ -0,0 +1,315 @@
+package multitenancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// TenantScopedStore is an event store bound to a single tenant of a shared
+// database. Callers use bare local aggregate IDs; the store transparently
+// prefixes them with the tenant ID on the way in (see ComposeAggregateID) and
+// strips the prefix from everything it returns.
+//
+// Besides aggregate IDs, the store also scopes:
+//   - command IDs, so idempotency never matches another tenant's command
+//   - unique constraint values, so uniqueness is enforced per tenant
+//   - LoadAllEvents, which only returns the tenant's own events
+//
+// Events are stamped with the tenant ID in their metadata; appending an event
+// whose metadata names a different tenant is rejected.
+type TenantScopedStore struct {
+	inner    store.EventStore
+	tenantID string
+}
+
+// NewTenantScopedStore scopes inner to tenantID.
+func NewTenantScopedStore(inner store.EventStore, tenantID string) (*TenantScopedStore, error) {
+	if tenantID == "" {
+		return nil, fmt.Errorf("tenant ID is required")
+	}
+	if strings.Contains(tenantID, TenantSeparator) {
+		return nil, fmt.Errorf("tenant ID %q must not contain %q", tenantID, TenantSeparator)
+	}
+	return &TenantScopedStore{inner: inner, tenantID: tenantID}, nil
+}
+
+// ScopeToTenant scopes inner to the tenant in the context.
+// Returns an error if the context carries no tenant ID.
+func ScopeToTenant(ctx context.Context, inner store.EventStore) (*TenantScopedStore, error) {
+	tenantID, err := GetTenantID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewTenantScopedStore(inner, tenantID)
+}
+
+// TenantID returns the tenant the store is scoped to.
+func (s *TenantScopedStore) TenantID() string {
+	return s.tenantID
+}
+
+// AppendEvents appends events to the tenant-scoped aggregate stream.
+func (s *TenantScopedStore) AppendEvents(ctx context.Context, aggregateID string, expectedVersion int64, events []*domain.Event) error {
+	scoped, err := s.scopeEvents(events)
+	if err != nil {
+		return err
+	}
+	if err := s.inner.AppendEvents(ctx, s.compose(aggregateID), expectedVersion, scoped); err != nil {
+		return s.unscopeError(err)
+	}
+	copyPositions(events, scoped)
+	return nil
+}
+
+// AppendEventsIdempotent appends events with command-level idempotency scoped to the tenant.
+func (s *TenantScopedStore) AppendEventsIdempotent(
+	ctx context.Context,
+	aggregateID string,
+	expectedVersion int64,
+	events []*domain.Event,
+	commandID string,
+	ttl time.Duration,
+) (*domain.CommandResult, error) {
+	scoped, err := s.scopeEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.inner.AppendEventsIdempotent(ctx, s.compose(aggregateID), expectedVersion, scoped, s.compose(commandID), ttl)
+	if err != nil {
+		return nil, s.unscopeError(err)
+	}
+	copyPositions(events, scoped)
+	return s.unscopeResult(result), nil
+}
+
+// AppendEventsMulti appends to several tenant aggregates atomically (see
+// store.MultiAggregateAppender). It fails if the inner store cannot.
+func (s *TenantScopedStore) AppendEventsMulti(ctx context.Context, appends []store.AggregateAppend) error {
+	appender, ok := s.inner.(store.MultiAggregateAppender)
+	if !ok {
+		return errors.New("inner event store does not support multi-aggregate appends")
+	}
+
+	scoped := make([]store.AggregateAppend, len(appends))
+	for i, aggregate := range appends {
+		events, err := s.scopeEvents(aggregate.Events)
+		if err != nil {
+			return err
+		}
+		scoped[i] = store.AggregateAppend{
+			AggregateID:     s.compose(aggregate.AggregateID),
+			ExpectedVersion: aggregate.ExpectedVersion,
+			Events:          events,
+		}
+	}
+
+	if err := appender.AppendEventsMulti(ctx, scoped); err != nil {
+		return s.unscopeError(err)
+	}
+	for i, aggregate := range appends {
+		copyPositions(aggregate.Events, scoped[i].Events)
+	}
+	return nil
+}
+
+// GetCommandResult retrieves the result of a command processed for the tenant.
+func (s *TenantScopedStore) GetCommandResult(commandID string) (*domain.CommandResult, error) {
+	result, err := s.inner.GetCommandResult(s.compose(commandID))
+	if err != nil || result == nil {
+		return result, err
+	}
+	return s.unscopeResult(result), nil
+}
+
+// LoadEvents loads the events of a tenant aggregate.
+func (s *TenantScopedStore) LoadEvents(ctx context.Context, aggregateID string, afterVersion int64) ([]*domain.Event, error) {
+	events, err := s.inner.LoadEvents(ctx, s.compose(aggregateID), afterVersion)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		s.unscopeEvent(event)
+	}
+	return events, nil
+}
+
+// StreamEvents streams the events of a tenant aggregate in batches (see
+// store.EventStreamer). It fails if the inner store cannot stream.
+func (s *TenantScopedStore) StreamEvents(aggregateID string, afterVersion int64, batchSize int) iter.Seq2[*domain.Event, error] {
+	return func(yield func(*domain.Event, error) bool) {
+		streamer, ok := s.inner.(store.EventStreamer)
+		if !ok {
+			yield(nil, errors.New("inner event store does not support streaming"))
+			return
+		}
+		for event, err := range streamer.StreamEvents(s.compose(aggregateID), afterVersion, batchSize) {
+			if err == nil {
+				s.unscopeEvent(event)
+			}
+			if !yield(event, err) {
+				return
+			}
+		}
+	}
+}
+
+// LoadAllEvents loads up to limit events of the tenant, starting at the given
+// global position. Events of other tenants are skipped, so the positions of the
+// returned events are not contiguous; continue from the last event's Position + 1.
+func (s *TenantScopedStore) LoadAllEvents(ctx context.Context, fromPosition int64, limit int) ([]*domain.Event, error) {
+	var result []*domain.Event
+	position := fromPosition
+	for len(result) < limit {
+		batch, err := s.inner.LoadAllEvents(ctx, position, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range batch {
+			if !s.owns(event.AggregateID) {
+				continue
+			}
+			s.unscopeEvent(event)
+			result = append(result, event)
+			if len(result) == limit {
+				break
+			}
+		}
+		if len(batch) < limit {
+			break
+		}
+		position = batch[len(batch)-1].Position + 1
+	}
+	return result, nil
+}
+
+// LoadCompensations returns the tenant's compensating events for a command.
+func (s *TenantScopedStore) LoadCompensations(commandID string) ([]*domain.Event, error) {
+	events, err := s.inner.LoadCompensations(commandID)
+	if err != nil {
+		return nil, err
+	}
+	var result []*domain.Event
+	for _, event := range events {
+		if !s.owns(event.AggregateID) {
+			continue
+		}
+		s.unscopeEvent(event)
+		result = append(result, event)
+	}
+	return result, nil
+}
+
+// GetAggregateVersion returns the current version of a tenant aggregate.
+func (s *TenantScopedStore) GetAggregateVersion(aggregateID string) (int64, error) {
+	return s.inner.GetAggregateVersion(s.compose(aggregateID))
+}
+
+// CheckUniqueness checks if a value is available within the tenant.
+func (s *TenantScopedStore) CheckUniqueness(indexName, value string) (bool, string, error) {
+	available, ownerID, err := s.inner.CheckUniqueness(indexName, s.compose(value))
+	return available, s.strip(ownerID), err
+}
+
+// GetConstraintOwner returns the tenant aggregate that owns a unique value.
+func (s *TenantScopedStore) GetConstraintOwner(indexName, value string) (string, error) {
+	ownerID, err := s.inner.GetConstraintOwner(indexName, s.compose(value))
+	return s.strip(ownerID), err
+}
+
+// RebuildConstraints rebuilds the unique constraint index of the shared store.
+func (s *TenantScopedStore) RebuildConstraints() error {
+	return s.inner.RebuildConstraints()
+}
+
+// Close is a no-op: the shared store is owned by whoever created it.
+func (s *TenantScopedStore) Close() error {
+	return nil
+}
+
+func (s *TenantScopedStore) compose(id string) string {
+	return ComposeAggregateID(s.tenantID, id)
+}
+
+// owns reports whether a composite ID belongs to the tenant.
+func (s *TenantScopedStore) owns(compositeID string) bool {
+	return strings.HasPrefix(compositeID, s.tenantID+TenantSeparator)
+}
+
+// strip removes the tenant prefix from a composite ID of this tenant.
+func (s *TenantScopedStore) strip(compositeID string) string {
+	return strings.TrimPrefix(compositeID, s.tenantID+TenantSeparator)
+}
+
+// scopeEvents returns copies of events with tenant-scoped IDs and constraint
+// values, leaving the caller's events untouched.
+func (s *TenantScopedStore) scopeEvents(events []*domain.Event) ([]*domain.Event, error) {
+	scoped := make([]*domain.Event, len(events))
+	for i, event := range events {
+		if event.Metadata.TenantID != "" && event.Metadata.TenantID != s.tenantID {
+			return nil, fmt.Errorf("tenant mismatch: event %s belongs to tenant %s, store is scoped to %s",
+				event.ID, event.Metadata.TenantID, s.tenantID)
+		}
+
+		c := *event
+		c.AggregateID = s.compose(event.AggregateID)
+		c.Metadata.TenantID = s.tenantID
+		if len(event.UniqueConstraints) > 0 {
+			c.UniqueConstraints = make([]domain.UniqueConstraint, len(event.UniqueConstraints))
+			for j, constraint := range event.UniqueConstraints {
+				constraint.Value = s.compose(constraint.Value)
+				c.UniqueConstraints[j] = constraint
+			}
+		}
+		scoped[i] = &c
+	}
+	return scoped, nil
+}
+
+// copyPositions hands the global positions assigned by the store back to the
+// caller's events.
+func copyPositions(events, scoped []*domain.Event) {
+	for i, event := range events {
+		event.Position = scoped[i].Position
+	}
+}
+
+// unscopeEvent strips the tenant prefix from a stored event in place.
+func (s *TenantScopedStore) unscopeEvent(event *domain.Event) {
+	event.AggregateID = s.strip(event.AggregateID)
+	for i := range event.UniqueConstraints {
+		event.UniqueConstraints[i].Value = s.strip(event.UniqueConstraints[i].Value)
+	}
+}
+
+func (s *TenantScopedStore) unscopeResult(result *domain.CommandResult) *domain.CommandResult {
+	c := *result
+	c.CommandID = s.strip(result.CommandID)
+	for _, event := range c.Events {
+		s.unscopeEvent(event)
+	}
+	for _, event := range c.Deduplicated {
+		s.unscopeEvent(event)
+	}
+	return &c
+}
+
+// unscopeError strips tenant prefixes from unique constraint violations.
+func (s *TenantScopedStore) unscopeError(err error) error {
+	var ucErr *domain.UniqueConstraintError
+	if errors.As(err, &ucErr) {
+		return &domain.UniqueConstraintError{
+			IndexName: ucErr.IndexName,
+			Value:     s.strip(ucErr.Value),
+			OwnerID:   s.strip(ucErr.OwnerID),
+		}
+	}
+	return err
+}
@@ -1,8 +1,14 @@
 package multitenancy
 
 import (
+	"container/list"
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/plaenen/eventstore/pkg/store"
@@ -20,11 +26,24 @@ const (
 	DatabasePerTenant
 )
 
+// ErrInvalidTenant is returned when a tenant ID cannot be mapped to a safe
+// per-tenant database path.
+var ErrInvalidTenant = errors.New("invalid tenant")
+
+// ErrTenantNotFound is returned when deleting a tenant that has no data.
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// ErrStoreClosed is returned when opening a tenant store after Close.
+var ErrStoreClosed = errors.New("multi-tenant event store is closed")
+
 // MultiTenantEventStore wraps an event store with multi-tenancy support
 type MultiTenantEventStore struct {
 	strategy       TenantStoreStrategy
 	sharedStore    store.EventStore // Used for SharedDatabase strategy
-	tenantStores   map[string]store.EventStore
+	tenantStores   map[string]*tenantStore
+	lru            *list.List    // Open tenant stores, most recently used first
+	released       chan struct{} // Closed and replaced when a tenant store falls idle or closes
+	closed         bool
 	tenantStoresMu sync.RWMutex
 	config         MultiTenantConfig
 }
@@ -36,18 +55,51 @@ type MultiTenantConfig struct {
 	SharedDSN string
 	WALMode   bool
 
+	// AutoPrefix makes GetStore return a TenantScopedStore for the tenant in
+	// the context (SharedDatabase only). Application code then uses bare local
+	// aggregate IDs instead of calling ComposeAggregateID, and every GetStore
+	// call must carry a tenant ID.
+	AutoPrefix bool
+
 	// For DatabasePerTenant strategy
-	DatabasePathTemplate string // e.g., "./data/tenant_%s.db"
+	DatabasePathTemplate string // e.g., "./data/tenant_%s.db", exactly one %s
+
+	// MaxOpenTenants bounds the tenant databases open at once
+	// (DatabasePerTenant only, 0 = unbounded). Beyond it, the least recently
+	// used idle tenant store is closed, and reopened on its next use; callers
+	// wait, until their context is done, while all open stores are in use.
+	// GetStore then returns a handle that opens the tenant's store for each
+	// call. It forwards the optional interfaces of the SQLite store, such as
+	// store.MultiAggregateAppender and store.SnapshotStore.
+	MaxOpenTenants int
+
+	// Metrics receives the number of open tenant stores (optional)
+	Metrics TenantStoreMetrics
+}
+
+// TenantStoreMetrics receives tenant store pool signals.
+// *observability.Metrics implements it.
+type TenantStoreMetrics interface {
+	// RecordOpenTenantStores records the number of open tenant databases.
+	RecordOpenTenantStores(ctx context.Context, open int64)
 }
 
 // NewMultiTenantEventStore creates a new multi-tenant event store
 func NewMultiTenantEventStore(config MultiTenantConfig) (*MultiTenantEventStore, error) {
 	mtStore := &MultiTenantEventStore{
 		strategy:     config.Strategy,
-		tenantStores: make(map[string]store.EventStore),
+		tenantStores: make(map[string]*tenantStore),
+		lru:          list.New(),
+		released:     make(chan struct{}),
 		config:       config,
 	}
 
+	if config.Strategy == DatabasePerTenant {
+		if err := validatePathTemplate(config.DatabasePathTemplate); err != nil {
+			return nil, err
+		}
+	}
+
 	if config.Strategy == SharedDatabase {
 		// Create single shared store
 		sharedStore, err := sqlite.NewEventStore(
@@ -66,6 +118,9 @@ func NewMultiTenantEventStore(config MultiTenantConfig) (*MultiTenantEventStore,
 // GetStore returns the event store for a specific tenant
 func (m *MultiTenantEventStore) GetStore(ctx context.Context) (store.EventStore, error) {
 	if m.strategy == SharedDatabase {
+		if m.config.AutoPrefix {
+			return ScopeToTenant(ctx, m.sharedStore)
+		}
 		return m.sharedStore, nil
 	}
 
@@ -75,42 +130,252 @@ func (m *MultiTenantEventStore) GetStore(ctx context.Context) (store.EventStore,
 		return nil, err
 	}
 
-	return m.getOrCreateTenantStore(tenantID)
+	return m.getOrCreateTenantStore(ctx, tenantID)
 }
 
 // getOrCreateTenantStore gets or creates a per-tenant database
-func (m *MultiTenantEventStore) getOrCreateTenantStore(tenantID string) (store.EventStore, error) {
+func (m *MultiTenantEventStore) getOrCreateTenantStore(ctx context.Context, tenantID string) (store.EventStore, error) {
 	// Try read lock first
 	m.tenantStoresMu.RLock()
-	eventStore, exists := m.tenantStores[tenantID]
+	ts, exists := m.tenantStores[tenantID]
+	settled := exists && ts.pending == nil
 	m.tenantStoresMu.RUnlock()
 
-	if exists {
-		return eventStore, nil
+	if m.config.MaxOpenTenants > 0 {
+		// Fail fast on tenants whose store could not be opened
+		if !exists {
+			if err := m.ValidateTenant(tenantID); err != nil {
+				return nil, err
+			}
+		}
+		return &pooledTenantStore{owner: m, tenantID: tenantID}, nil
+	}
+	if settled {
+		return ts.store, nil
+	}
+
+	// Unbounded stores are never closed, so need not stay acquired
+	eventStore, release, err := m.acquire(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	release()
+	return eventStore, nil
+}
+
+// ValidateTenant checks, without opening anything, that a store for tenantID
+// could be provisioned: for DatabasePerTenant the template must contain exactly
+// one %s, the computed database path must stay in the template's directory
+// (no path separators or ".." in the tenant ID), and that directory must exist
+// and be writable. Use it to vet tenant IDs at signup or to check the
+// deployment at startup; GetStore calls it before creating a tenant database.
+func (m *MultiTenantEventStore) ValidateTenant(tenantID string) error {
+	if tenantID == "" {
+		return fmt.Errorf("%w: empty tenant ID", ErrInvalidTenant)
+	}
+	if m.strategy != DatabasePerTenant {
+		return nil
+	}
+
+	template := m.config.DatabasePathTemplate
+	if err := validatePathTemplate(template); err != nil {
+		return err
+	}
+
+	if strings.ContainsAny(tenantID, "/\\?#\x00") || strings.Contains(tenantID, "..") {
+		return fmt.Errorf("%w: tenant ID %q contains path or DSN characters", ErrInvalidTenant, tenantID)
+	}
+
+	// The tenant ID must only change the file name, never the directory
+	path := filepath.Clean(fmt.Sprintf(template, tenantID))
+	dir := filepath.Dir(filepath.Clean(fmt.Sprintf(template, "tenant")))
+	if filepath.Dir(path) != dir {
+		return fmt.Errorf("%w: path %s for tenant %q escapes %s", ErrInvalidTenant, path, tenantID, dir)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("tenant database directory %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("tenant database directory %s is not a directory", dir)
+	}
+	probe, err := os.CreateTemp(dir, ".tenant-probe-*")
+	if err != nil {
+		return fmt.Errorf("tenant database directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return nil
+}
+
+// validatePathTemplate checks that template has exactly one %s and no other
+// formatting verbs.
+func validatePathTemplate(template string) error {
+	if strings.Count(template, "%s") != 1 || strings.Count(strings.ReplaceAll(template, "%%", ""), "%") != 1 {
+		return fmt.Errorf("DatabasePathTemplate %q must contain exactly one %%s", template)
+	}
+	return nil
+}
+
+// ListTenants returns the sorted IDs of the tenants that have a database:
+// the files in the DatabasePathTemplate directory whose names match the
+// template (DatabasePerTenant only). SQLite's -wal, -shm and -journal files
+// next to a tenant database are not reported as tenants of their own.
+func (m *MultiTenantEventStore) ListTenants() ([]string, error) {
+	if m.strategy != DatabasePerTenant {
+		return nil, fmt.Errorf("listing tenants requires the DatabasePerTenant strategy")
 	}
 
-	// Need to create store - acquire write lock
+	dir, prefix, suffix, err := splitPathTemplate(m.config.DatabasePathTemplate)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant database directory %s: %w", dir, err)
+	}
+
+	tenants := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || len(name) <= len(prefix)+len(suffix) ||
+			!strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		tenants[name[len(prefix):len(name)-len(suffix)]] = true
+	}
+	for tenantID := range tenants {
+		for _, sidecar := range sqliteSidecarSuffixes {
+			if base, ok := strings.CutSuffix(tenantID, sidecar); ok && tenants[base] {
+				delete(tenants, tenantID)
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(tenants))
+	for tenantID := range tenants {
+		ids = append(ids, tenantID)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// DeleteTenant permanently removes all data of tenantID. For DatabasePerTenant
+// it closes the tenant's store and removes its database file (with its -wal,
+// -shm and -journal files). For SharedDatabase it deletes, in one transaction,
+// every aggregate whose ID carries the tenant prefix (see ComposeAggregateID),
+// with its snapshots, unique constraints and processed commands.
+//
+// Returns ErrTenantNotFound if the tenant has no data. Projections built from
+// the tenant's events are not touched.
+func (m *MultiTenantEventStore) DeleteTenant(ctx context.Context, tenantID string) error {
+	if m.strategy == SharedDatabase {
+		return m.deleteSharedTenant(ctx, tenantID)
+	}
+
+	if err := m.ValidateTenant(tenantID); err != nil {
+		return err
+	}
+
+	// Wait for calls in flight on the tenant's store, then hold its slot
+	// while closing the store and removing its files, so it is not reopened
 	m.tenantStoresMu.Lock()
-	defer m.tenantStoresMu.Unlock()
+	var ts *tenantStore
+	for ts == nil {
+		open, exists := m.tenantStores[tenantID]
+		switch {
+		case !exists:
+			ts = &tenantStore{tenantID: tenantID}
+			ts.elem = m.lru.PushBack(ts)
+			m.tenantStores[tenantID] = ts
+		case open.pending != nil:
+			if err := m.wait(ctx, open.pending); err != nil {
+				m.tenantStoresMu.Unlock()
+				return err
+			}
+		case open.inUse == 0:
+			ts = open
+		default:
+			if err := m.wait(ctx, m.released); err != nil {
+				m.tenantStoresMu.Unlock()
+				return err
+			}
+		}
+	}
+	ts.pending = make(chan struct{})
+	m.tenantStoresMu.Unlock()
 
-	// Double-check after acquiring write lock
-	eventStore, exists = m.tenantStores[tenantID]
-	if exists {
-		return eventStore, nil
+	defer func() {
+		m.tenantStoresMu.Lock()
+		m.endClose(ts)
+		m.tenantStoresMu.Unlock()
+	}()
+	if ts.store != nil {
+		if err := m.closeStore(ts); err != nil {
+			return err
+		}
 	}
 
-	// Create new tenant database
-	dsn := fmt.Sprintf(m.config.DatabasePathTemplate, tenantID)
-	tenantStore, err := sqlite.NewEventStore(
-		sqlite.WithDSN(dsn),
-		sqlite.WithWALMode(m.config.WALMode),
-	)
+	path := fmt.Sprintf(m.config.DatabasePathTemplate, tenantID)
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrTenantNotFound, tenantID)
+		}
+		return fmt.Errorf("failed to remove database of tenant %s: %w", tenantID, err)
+	}
+	for _, sidecar := range sqliteSidecarSuffixes {
+		if err := os.Remove(path + sidecar); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove database of tenant %s: %w", tenantID, err)
+		}
+	}
+	return nil
+}
+
+// deleteSharedTenant deletes the tenant-prefixed aggregates of the shared store.
+func (m *MultiTenantEventStore) deleteSharedTenant(ctx context.Context, tenantID string) error {
+	if tenantID == "" {
+		return fmt.Errorf("%w: empty tenant ID", ErrInvalidTenant)
+	}
+	if strings.Contains(tenantID, TenantSeparator) {
+		return fmt.Errorf("%w: tenant ID %q must not contain %q", ErrInvalidTenant, tenantID, TenantSeparator)
+	}
+
+	sharedStore, ok := m.sharedStore.(*sqlite.EventStore)
+	if !ok {
+		return fmt.Errorf("shared store %T does not support deleting tenants", m.sharedStore)
+	}
+	deleted, err := sharedStore.DeleteAggregatesWithPrefix(ctx, ComposeAggregateID(tenantID, ""))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create tenant store for %s: %w", tenantID, err)
+		return fmt.Errorf("failed to delete tenant %s: %w", tenantID, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s", ErrTenantNotFound, tenantID)
 	}
+	return nil
+}
 
-	m.tenantStores[tenantID] = tenantStore
-	return tenantStore, nil
+// sqliteSidecarSuffixes are the suffixes of the files SQLite keeps next to a
+// database file.
+var sqliteSidecarSuffixes = []string{"-wal", "-shm", "-journal"}
+
+// splitPathTemplate splits a DatabasePathTemplate into its directory and the
+// file name parts before and after the tenant ID.
+func splitPathTemplate(template string) (dir, prefix, suffix string, err error) {
+	if err := validatePathTemplate(template); err != nil {
+		return "", "", "", err
+	}
+	dir, name := filepath.Split(template)
+	prefix, suffix, found := strings.Cut(name, "%s")
+	if !found {
+		return "", "", "", fmt.Errorf("DatabasePathTemplate %q must have its %%s in the file name", template)
+	}
+	if dir == "" {
+		dir = "."
+	}
+	unescape := func(s string) string { return strings.ReplaceAll(s, "%%", "%") }
+	return unescape(dir), unescape(prefix), unescape(suffix), nil
 }
 
 // Close closes all tenant stores
@@ -124,8 +389,13 @@ func (m *MultiTenantEventStore) Close() error {
 	m.tenantStoresMu.Lock()
 	defer m.tenantStoresMu.Unlock()
 
-	for tenantID, store := range m.tenantStores {
-		if err := store.Close(); err != nil {
+	m.closed = true
+	m.broadcastReleased()
+	for tenantID, ts := range m.tenantStores {
+		if ts.pending != nil {
+			continue // Closed by the call opening or evicting it
+		}
+		if err := ts.store.Close(); err != nil {
 			return fmt.Errorf("failed to close store for tenant %s: %w", tenantID, err)
 		}
 	}
@@ -12,9 +12,10 @@ import (
 // Metrics holds all metric instruments for the event sourcing framework
 type Metrics struct {
 	// Command metrics
-	CommandDuration metric.Float64Histogram
-	CommandTotal    metric.Int64Counter
-	CommandErrors   metric.Int64Counter
+	CommandDuration  metric.Float64Histogram
+	CommandTotal     metric.Int64Counter
+	CommandErrors    metric.Int64Counter
+	CommandThrottled metric.Int64Counter
 
 	// Event metrics
 	EventsAppended    metric.Int64Counter
@@ -22,13 +23,18 @@ type Metrics struct {
 	EventStoreLatency metric.Float64Histogram
 
 	// Aggregate metrics
-	AggregateLoads metric.Int64Counter
-	SnapshotHits   metric.Int64Counter
-	SnapshotMisses metric.Int64Counter
+	AggregateLoads     metric.Int64Counter
+	SnapshotHits       metric.Int64Counter
+	SnapshotMisses     metric.Int64Counter
+	SnapshotRejections metric.Int64Counter
+	SnapshotsSaved     metric.Int64Counter
+	SnapshotLoadBytes  metric.Int64Histogram
 
 	// Projection metrics
-	ProjectionLag    metric.Float64Gauge
-	ProjectionErrors metric.Int64Counter
+	ProjectionLag         metric.Int64Gauge
+	ProjectionLagSeconds  metric.Float64Gauge
+	ProjectionErrors      metric.Int64Counter
+	ProjectionHandlerSlow metric.Float64Histogram
 
 	// Repository metrics
 	RepositorySaves metric.Int64Counter
@@ -37,6 +43,14 @@ type Metrics struct {
 	// NATS metrics
 	NATSPublishLatency metric.Float64Histogram
 	NATSMessages       metric.Int64Counter
+	NATSReconnects     metric.Int64Counter
+
+	// Transport metrics
+	CircuitBreakerState       metric.Int64Gauge
+	CircuitBreakerTransitions metric.Int64Counter
+
+	// Multi-tenancy metrics
+	OpenTenantStores metric.Int64Gauge
 }
 
 // NewMetrics creates all metric instruments
@@ -70,6 +84,14 @@ func NewMetrics(meter metric.Meter) (*Metrics, error) {
 		return nil, fmt.Errorf("creating command.errors: %w", err)
 	}
 
+	m.CommandThrottled, err = meter.Int64Counter(
+		"eventsourcing.command.throttled",
+		metric.WithDescription("Total commands rejected by rate limits or concurrency caps"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating command.throttled: %w", err)
+	}
+
 	// Event metrics
 	m.EventsAppended, err = meter.Int64Counter(
 		"eventsourcing.events.appended",
@@ -121,14 +143,48 @@ func NewMetrics(meter metric.Meter) (*Metrics, error) {
 		return nil, fmt.Errorf("creating snapshot.misses: %w", err)
 	}
 
+	m.SnapshotRejections, err = meter.Int64Counter(
+		"eventsourcing.snapshot.rejections",
+		metric.WithDescription("Snapshots rejected as inconsistent with the event stream"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating snapshot.rejections: %w", err)
+	}
+
+	m.SnapshotsSaved, err = meter.Int64Counter(
+		"eventsourcing.snapshot.saved",
+		metric.WithDescription("Snapshots written"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating snapshot.saved: %w", err)
+	}
+
+	m.SnapshotLoadBytes, err = meter.Int64Histogram(
+		"eventsourcing.snapshot.load.bytes",
+		metric.WithDescription("Size of loaded snapshots in bytes"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating snapshot.load.bytes: %w", err)
+	}
+
 	// Projection metrics
-	m.ProjectionLag, err = meter.Float64Gauge(
+	m.ProjectionLag, err = meter.Int64Gauge(
 		"eventsourcing.projection.lag",
+		metric.WithDescription("Events a projection has yet to process"),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating projection.lag: %w", err)
+	}
+
+	m.ProjectionLagSeconds, err = meter.Float64Gauge(
+		"eventsourcing.projection.lag.seconds",
 		metric.WithDescription("Projection lag in seconds behind event stream"),
 		metric.WithUnit("s"),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("creating projection.lag: %w", err)
+		return nil, fmt.Errorf("creating projection.lag.seconds: %w", err)
 	}
 
 	m.ProjectionErrors, err = meter.Int64Counter(
@@ -139,6 +195,15 @@ func NewMetrics(meter metric.Meter) (*Metrics, error) {
 		return nil, fmt.Errorf("creating projection.errors: %w", err)
 	}
 
+	m.ProjectionHandlerSlow, err = meter.Float64Histogram(
+		"eventsourcing.projection.handler.slow",
+		metric.WithDescription("Duration of projection handler calls that exceeded the slow-handler threshold"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating projection.handler.slow: %w", err)
+	}
+
 	// Repository metrics
 	m.RepositorySaves, err = meter.Int64Counter(
 		"eventsourcing.repository.saves",
@@ -174,6 +239,40 @@ func NewMetrics(meter metric.Meter) (*Metrics, error) {
 		return nil, fmt.Errorf("creating nats.messages: %w", err)
 	}
 
+	m.NATSReconnects, err = meter.Int64Counter(
+		"eventsourcing.nats.reconnect.attempts",
+		metric.WithDescription("Failed NATS reconnection attempts"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating nats.reconnect.attempts: %w", err)
+	}
+
+	// Transport metrics
+	m.CircuitBreakerState, err = meter.Int64Gauge(
+		"eventsourcing.transport.circuit_breaker.state",
+		metric.WithDescription("Circuit breaker state per subject (0 = closed, 1 = half-open, 2 = open)"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating transport.circuit_breaker.state: %w", err)
+	}
+
+	m.CircuitBreakerTransitions, err = meter.Int64Counter(
+		"eventsourcing.transport.circuit_breaker.transitions",
+		metric.WithDescription("Circuit breaker state changes"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating transport.circuit_breaker.transitions: %w", err)
+	}
+
+	// Multi-tenancy metrics
+	m.OpenTenantStores, err = meter.Int64Gauge(
+		"eventsourcing.multitenancy.open_tenant_stores",
+		metric.WithDescription("Tenant databases currently open"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating multitenancy.open_tenant_stores: %w", err)
+	}
+
 	return m, nil
 }
 
@@ -199,6 +298,18 @@ func (m *Metrics) RecordCommand(ctx context.Context, commandType string, duratio
 	}
 }
 
+// RecordCommandThrottled records a command rejected by a server-side limit.
+// reason identifies the limit that rejected it ("global", "tenant" or "concurrency").
+func (m *Metrics) RecordCommandThrottled(ctx context.Context, subject, tenantID, reason string) {
+	attrs := []attribute.KeyValue{
+		attribute.String("subject", subject),
+		attribute.String("tenant_id", tenantID),
+		attribute.String("reason", reason),
+	}
+
+	m.CommandThrottled.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
+
 // RecordEventStoreOperation records event store operation metrics
 func (m *Metrics) RecordEventStoreOperation(ctx context.Context, operation string, duration time.Duration, eventCount int) {
 	attrs := []attribute.KeyValue{
@@ -227,6 +338,35 @@ func (m *Metrics) RecordAggregateLoad(ctx context.Context, aggregateType string,
 	}
 }
 
+// RecordSnapshotRejected records a snapshot that failed validation during load
+// and was replaced by a full replay. It satisfies store.SnapshotMetrics.
+func (m *Metrics) RecordSnapshotRejected(ctx context.Context, aggregateType, reason string) {
+	attrs := []attribute.KeyValue{
+		attribute.String("aggregate_type", aggregateType),
+		attribute.String("reason", reason),
+	}
+
+	m.SnapshotRejections.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
+
+// RecordSnapshotSaved records a snapshot write
+func (m *Metrics) RecordSnapshotSaved(ctx context.Context, aggregateType string) {
+	attrs := []attribute.KeyValue{
+		attribute.String("aggregate_type", aggregateType),
+	}
+
+	m.SnapshotsSaved.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
+
+// RecordSnapshotLoad records the size of a loaded snapshot
+func (m *Metrics) RecordSnapshotLoad(ctx context.Context, aggregateType string, sizeBytes int) {
+	attrs := []attribute.KeyValue{
+		attribute.String("aggregate_type", aggregateType),
+	}
+
+	m.SnapshotLoadBytes.Record(ctx, int64(sizeBytes), metric.WithAttributes(attrs...))
+}
+
 // RecordRepositoryOperation records repository operations
 func (m *Metrics) RecordRepositoryOperation(ctx context.Context, operation string, aggregateType string) {
 	attrs := []attribute.KeyValue{
@@ -241,13 +381,33 @@ func (m *Metrics) RecordRepositoryOperation(ctx context.Context, operation strin
 	}
 }
 
-// RecordProjectionLag records how far behind a projection is
-func (m *Metrics) RecordProjectionLag(ctx context.Context, projectionName string, lagSeconds float64) {
+// RecordProjectionLagEvents records how many events a projection is behind
+// the event store. It implements eventsourcing.ProjectionLagMetrics.
+func (m *Metrics) RecordProjectionLagEvents(ctx context.Context, projectionName string, lag int64) {
 	attrs := []attribute.KeyValue{
 		attribute.String("projection", projectionName),
 	}
 
-	m.ProjectionLag.Record(ctx, lagSeconds, metric.WithAttributes(attrs...))
+	m.ProjectionLag.Record(ctx, lag, metric.WithAttributes(attrs...))
+}
+
+// RecordProjectionLagSeconds records how long the oldest event a projection
+// has yet to handle has been in the event store. It implements
+// eventsourcing.ProjectionLagMetrics.
+func (m *Metrics) RecordProjectionLagSeconds(ctx context.Context, projectionName string, seconds float64) {
+	attrs := []attribute.KeyValue{
+		attribute.String("projection", projectionName),
+	}
+
+	m.ProjectionLagSeconds.Record(ctx, seconds, metric.WithAttributes(attrs...))
+}
+
+// RecordProjectionLag records how far behind a projection is, in seconds.
+//
+// Deprecated: the eventsourcing.projection.lag gauge now counts events (see
+// RecordProjectionLagEvents); use RecordProjectionLagSeconds.
+func (m *Metrics) RecordProjectionLag(ctx context.Context, projectionName string, lagSeconds float64) {
+	m.RecordProjectionLagSeconds(ctx, projectionName, lagSeconds)
 }
 
 // RecordProjectionError records projection processing errors
@@ -260,6 +420,17 @@ func (m *Metrics) RecordProjectionError(ctx context.Context, projectionName stri
 	m.ProjectionErrors.Add(ctx, 1, metric.WithAttributes(attrs...))
 }
 
+// RecordProjectionHandlerSlow records a projection handler call that exceeded
+// the slow-handler threshold. It satisfies store.ProjectionMetrics.
+func (m *Metrics) RecordProjectionHandlerSlow(ctx context.Context, projectionName, eventType string, duration time.Duration) {
+	attrs := []attribute.KeyValue{
+		attribute.String("projection", projectionName),
+		AttrEventType.String(eventType),
+	}
+
+	m.ProjectionHandlerSlow.Record(ctx, duration.Seconds(), metric.WithAttributes(attrs...))
+}
+
 // RecordNATSPublish records NATS publish metrics
 func (m *Metrics) RecordNATSPublish(ctx context.Context, subject string, duration time.Duration, messageCount int) {
 	attrs := []attribute.KeyValue{
@@ -270,3 +441,31 @@ func (m *Metrics) RecordNATSPublish(ctx context.Context, subject string, duratio
 	m.NATSPublishLatency.Record(ctx, duration.Seconds(), metric.WithAttributes(attrs...))
 	m.NATSMessages.Add(ctx, int64(messageCount), metric.WithAttributes(attrs...))
 }
+
+// RecordNATSReconnectAttempt records a failed attempt by a NATS client to
+// reconnect after losing its connection
+func (m *Metrics) RecordNATSReconnectAttempt(ctx context.Context, clientName string) {
+	attrs := []attribute.KeyValue{
+		attribute.String("client", clientName),
+	}
+
+	m.NATSReconnects.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
+
+// RecordCircuitBreakerState records a state change of the client-side
+// circuit breaker of a subject. state is the numeric value of the new state
+// and name its name.
+func (m *Metrics) RecordCircuitBreakerState(ctx context.Context, subject string, state int64, name string) {
+	attrs := []attribute.KeyValue{
+		attribute.String("subject", subject),
+	}
+
+	m.CircuitBreakerState.Record(ctx, state, metric.WithAttributes(attrs...))
+	m.CircuitBreakerTransitions.Add(ctx, 1, metric.WithAttributes(append(attrs, attribute.String("state", name))...))
+}
+
+// RecordOpenTenantStores records the number of open tenant databases of a
+// database-per-tenant store. It satisfies multitenancy.TenantStoreMetrics.
+func (m *Metrics) RecordOpenTenantStores(ctx context.Context, open int64) {
+	m.OpenTenantStores.Record(ctx, open)
+}
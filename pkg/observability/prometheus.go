@@ -0,0 +1,232 @@
+package observability
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PrometheusHandlerConfig configures the handler returned by NewPrometheusHandler.
+type PrometheusHandlerConfig struct {
+	// MetricsTable is the table written by SQLiteMetricExporter (default: "otel_metrics")
+	MetricsTable string
+
+	// MaxAge skips series not exported within this window, e.g. those of
+	// instruments no longer recorded (default: 5 minutes)
+	MaxAge time.Duration
+}
+
+// DefaultPrometheusHandlerConfig returns sensible defaults
+func DefaultPrometheusHandlerConfig() *PrometheusHandlerConfig {
+	return &PrometheusHandlerConfig{
+		MetricsTable: "otel_metrics",
+		MaxAge:       5 * time.Minute,
+	}
+}
+
+// NewPrometheusHandler returns an http.Handler serving the latest value of
+// every series stored by SQLiteMetricExporter in the Prometheus text
+// exposition format, so a single-binary deployment can expose /metrics to an
+// existing Prometheus without an OTLP collector. A nil config uses the
+// defaults.
+//
+// Metric names have their dots replaced (eventsourcing.commands.total becomes
+// eventsourcing_commands_total) and attributes become labels. Gauges and
+// non-monotonic sums (UpDownCounters) map to gauges, monotonic sums to
+// counters with a _total suffix, and histograms to histograms with their
+// buckets, sum and count. Histograms stored before bucket counts were kept
+// only get a +Inf bucket, and sums stored before monotonicity was kept are
+// taken for counters.
+//
+// Example:
+//
+//	http.Handle("/metrics", observability.NewPrometheusHandler(db, nil))
+func NewPrometheusHandler(db *sql.DB, config *PrometheusHandlerConfig) http.Handler {
+	defaults := DefaultPrometheusHandlerConfig()
+	if config == nil {
+		config = defaults
+	}
+	table, maxAge := config.MetricsTable, config.MaxAge
+	if table == "" {
+		table = defaults.MetricsTable
+	}
+	if maxAge <= 0 {
+		maxAge = defaults.MaxAge
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		series, err := latestSeries(r.Context(), db, table, time.Now().Add(-maxAge))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writePrometheus(w, series)
+	})
+}
+
+// storedSeries is the latest row of one metric and attribute combination.
+type storedSeries struct {
+	name        string
+	description string
+	kind        string // gauge, sum or histogram
+	monotonic   bool   // Whether a sum only increases, i.e. is a counter
+	value       float64
+	count       int64
+	sum         float64
+	bounds      []float64 // Histogram bucket upper bounds, if stored
+	buckets     []uint64  // Histogram bucket counts, if stored
+	labels      string    // Rendered label pairs, without braces
+}
+
+// latestSeries loads the newest row of every series exported since since.
+func latestSeries(ctx context.Context, db *sql.DB, table string, since time.Time) ([]storedSeries, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT name, COALESCE(description, ''), type, COALESCE(value, 0),
+		       COALESCE(count, 0), COALESCE(sum, 0), COALESCE(attributes, ''),
+		       COALESCE(bounds, ''), COALESCE(bucket_counts, ''), COALESCE(monotonic, 1)
+		FROM %s
+		WHERE id IN (
+			SELECT MAX(id) FROM %s WHERE timestamp >= ? GROUP BY name, attributes
+		)
+		ORDER BY name
+	`, table, table), since.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	var series []storedSeries
+	for rows.Next() {
+		var (
+			s                   storedSeries
+			attrs, bounds, hist string
+		)
+		if err := rows.Scan(&s.name, &s.description, &s.kind, &s.value, &s.count, &s.sum, &attrs, &bounds, &hist, &s.monotonic); err != nil {
+			return nil, fmt.Errorf("scan metric: %w", err)
+		}
+		s.labels = prometheusLabels(attrs)
+		if bounds != "" && hist != "" {
+			if json.Unmarshal([]byte(bounds), &s.bounds) != nil || json.Unmarshal([]byte(hist), &s.buckets) != nil {
+				s.bounds, s.buckets = nil, nil
+			}
+		}
+		series = append(series, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query metrics: %w", err)
+	}
+	return series, nil
+}
+
+// writePrometheus renders series, grouped by metric, in the text exposition format.
+func writePrometheus(w io.Writer, series []storedSeries) {
+	var current string
+	for _, s := range series {
+		name := prometheusName(s.name)
+		promType := s.kind
+		if s.kind == "sum" {
+			promType = "gauge"
+			if s.monotonic {
+				promType = "counter"
+				if !strings.HasSuffix(name, "_total") {
+					name += "_total"
+				}
+			}
+		}
+		if name != current {
+			current = name
+			if s.description != "" {
+				fmt.Fprintf(w, "# HELP %s %s\n", name, helpEscaper.Replace(s.description))
+			}
+			fmt.Fprintf(w, "# TYPE %s %s\n", name, promType)
+		}
+
+		switch s.kind {
+		case "histogram":
+			// Prometheus buckets are cumulative
+			var cumulative uint64
+			for i, bound := range s.bounds {
+				if i >= len(s.buckets) {
+					break
+				}
+				cumulative += s.buckets[i]
+				le := fmt.Sprintf(`le="%s"`, formatFloat(bound))
+				fmt.Fprintf(w, "%s_bucket{%s} %d\n", name, joinLabels(s.labels, le), cumulative)
+			}
+			fmt.Fprintf(w, "%s_bucket{%s} %d\n", name, joinLabels(s.labels, `le="+Inf"`), s.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", name, braced(s.labels), formatFloat(s.sum))
+			fmt.Fprintf(w, "%s_count%s %d\n", name, braced(s.labels), s.count)
+		default:
+			fmt.Fprintf(w, "%s%s %s\n", name, braced(s.labels), formatFloat(s.value))
+		}
+	}
+}
+
+var (
+	invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	invalidLabelChars  = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	valueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// prometheusName converts an OpenTelemetry metric name to a valid Prometheus one.
+func prometheusName(name string) string {
+	name = invalidMetricChars.ReplaceAllString(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// prometheusLabels renders the JSON attributes of a row as sorted label pairs.
+func prometheusLabels(attrsJSON string) string {
+	var attrs map[string]any
+	if attrsJSON == "" || json.Unmarshal([]byte(attrsJSON), &attrs) != nil {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(attrs))
+	for key, value := range attrs {
+		label := invalidLabelChars.ReplaceAllString(key, "_")
+		if label != "" && label[0] >= '0' && label[0] <= '9' {
+			label = "_" + label
+		}
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, label, valueEscaper.Replace(fmt.Sprint(value))))
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// joinLabels joins rendered label pairs, skipping empty ones.
+func joinLabels(labels ...string) string {
+	var nonEmpty []string
+	for _, l := range labels {
+		if l != "" {
+			nonEmpty = append(nonEmpty, l)
+		}
+	}
+	return strings.Join(nonEmpty, ",")
+}
+
+// braced wraps labels in braces, or returns "" when there are none.
+func braced(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+// formatFloat formats a sample value as Prometheus expects.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,107 @@
+package observability_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	_ "modernc.org/sqlite"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	exporter, err := observability.NewSQLiteMetricExporter(observability.DefaultSQLiteExporterConfig(db))
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+
+	export := func(commands int64) {
+		attrs := attribute.NewSet(attribute.String("command.type", `Open"Account`))
+		err := exporter.Export(ctx, &metricdata.ResourceMetrics{
+			Resource: resource.Empty(),
+			ScopeMetrics: []metricdata.ScopeMetrics{{
+				Scope: instrumentation.Scope{Name: "test"},
+				Metrics: []metricdata.Metrics{
+					{
+						Name:        "eventsourcing.commands.total",
+						Description: "Commands handled",
+						Data: metricdata.Sum[int64]{IsMonotonic: true, DataPoints: []metricdata.DataPoint[int64]{
+							{Attributes: attrs, Value: commands},
+						}},
+					},
+					{
+						Name: "eventsourcing.commands.inflight",
+						Data: metricdata.Sum[int64]{IsMonotonic: false, DataPoints: []metricdata.DataPoint[int64]{
+							{Value: 2},
+						}},
+					},
+					{
+						Name: "eventsourcing.projection.lag",
+						Data: metricdata.Gauge[int64]{DataPoints: []metricdata.DataPoint[int64]{
+							{Attributes: attribute.NewSet(attribute.String("projection", "balances")), Value: 7},
+						}},
+					},
+					{
+						Name: "eventsourcing.command.duration",
+						Data: metricdata.Histogram[float64]{DataPoints: []metricdata.HistogramDataPoint[float64]{
+							{Count: 3, Sum: 1.5, Bounds: []float64{0.25, 0.5}, BucketCounts: []uint64{1, 1, 1}},
+						}},
+					},
+				},
+			}},
+		})
+		if err != nil {
+			t.Fatalf("failed to export metrics: %v", err)
+		}
+	}
+	export(1)
+	export(5)
+
+	recorder := httptest.NewRecorder()
+	observability.NewPrometheusHandler(db, nil).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body)
+	}
+	body := recorder.Body.String()
+
+	for _, want := range []string{
+		"# HELP eventsourcing_commands_total Commands handled\n",
+		"# TYPE eventsourcing_commands_total counter\n",
+		`eventsourcing_commands_total{command_type="Open\"Account"} 5` + "\n",
+		"# TYPE eventsourcing_commands_inflight gauge\n",
+		"eventsourcing_commands_inflight 2\n",
+		"# TYPE eventsourcing_projection_lag gauge\n",
+		`eventsourcing_projection_lag{projection="balances"} 7` + "\n",
+		"# TYPE eventsourcing_command_duration histogram\n",
+		`eventsourcing_command_duration_bucket{le="0.25"} 1` + "\n",
+		`eventsourcing_command_duration_bucket{le="0.5"} 2` + "\n",
+		`eventsourcing_command_duration_bucket{le="+Inf"} 3` + "\n",
+		"eventsourcing_command_duration_sum 1.5\n",
+		"eventsourcing_command_duration_count 3\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "eventsourcing_commands_inflight_total") {
+		t.Errorf("expected an UpDownCounter not to be exported as a counter, got:\n%s", body)
+	}
+	if strings.Contains(body, `Account"} 1`) {
+		t.Errorf("expected only the latest value of each series, got:\n%s", body)
+	}
+}
@@ -0,0 +1,133 @@
+package observability
+
+import (
+	"context"
+	"errors"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentedSnapshotStore wraps a store.SnapshotStore with tracing and
+// metrics. Saves and latest-snapshot loads produce "snapshot.save" and
+// "snapshot.load" spans carrying the aggregate type, version, payload size and
+// compression ratio, and feed the eventsourcing.snapshot.saved and
+// eventsourcing.snapshot.load.bytes metrics.
+//
+// Pass it wherever the plain store is used, e.g.
+//
+//	snapshots := observability.InstrumentSnapshotStore(tel, sqlite.NewSnapshotStore(db))
+//	repo := accountv1.NewAccountRepository(eventStore, accountdomain.NewAccount,
+//	    store.WithSnapshotStore(snapshots))
+type InstrumentedSnapshotStore struct {
+	next   store.SnapshotStore
+	tel    *Telemetry
+	tracer trace.Tracer
+}
+
+// InstrumentSnapshotStore wraps next with tracing and metrics from tel.
+func InstrumentSnapshotStore(tel *Telemetry, next store.SnapshotStore) *InstrumentedSnapshotStore {
+	return &InstrumentedSnapshotStore{
+		next:   next,
+		tel:    tel,
+		tracer: tel.Tracer("eventsourcing.snapshot"),
+	}
+}
+
+// SaveSnapshot persists a snapshot inside a "snapshot.save" span.
+func (s *InstrumentedSnapshotStore) SaveSnapshot(snapshot *store.Snapshot) error {
+	ctx, span := s.tracer.Start(context.Background(), "snapshot.save",
+		trace.WithAttributes(AggregateAttrs(snapshot.AggregateID, snapshot.AggregateType, snapshot.Version)...),
+	)
+	defer span.End()
+	span.SetAttributes(snapshotAttrs(snapshot)...)
+
+	err := s.next.SaveSnapshot(snapshot)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "")
+	if s.tel.Metrics != nil {
+		s.tel.Metrics.RecordSnapshotSaved(ctx, snapshot.AggregateType)
+	}
+	return nil
+}
+
+// GetLatestSnapshot loads the most recent snapshot inside a "snapshot.load"
+// span. A missing snapshot is recorded as a miss, not an error.
+func (s *InstrumentedSnapshotStore) GetLatestSnapshot(aggregateID string) (*store.Snapshot, error) {
+	ctx, span := s.tracer.Start(context.Background(), "snapshot.load",
+		trace.WithAttributes(AttrAggregateID.String(aggregateID)),
+	)
+	defer span.End()
+
+	snapshot, err := s.next.GetLatestSnapshot(aggregateID)
+	if errors.Is(err, domain.ErrSnapshotNotFound) {
+		span.SetAttributes(AttrSnapshotHit.Bool(false))
+		span.SetStatus(codes.Ok, "")
+		return nil, err
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(
+		AttrSnapshotHit.Bool(true),
+		AttrAggregateType.String(snapshot.AggregateType),
+		AttrVersion.Int64(snapshot.Version),
+	)
+	span.SetAttributes(snapshotAttrs(snapshot)...)
+	span.SetStatus(codes.Ok, "")
+	if s.tel.Metrics != nil {
+		s.tel.Metrics.RecordSnapshotLoad(ctx, snapshot.AggregateType, len(snapshot.Data))
+	}
+	return snapshot, nil
+}
+
+// GetSnapshotBeforeVersion delegates to the wrapped store.
+func (s *InstrumentedSnapshotStore) GetSnapshotBeforeVersion(aggregateID string, version int64) (*store.Snapshot, error) {
+	return s.next.GetSnapshotBeforeVersion(aggregateID, version)
+}
+
+// DeleteOldSnapshots delegates to the wrapped store.
+func (s *InstrumentedSnapshotStore) DeleteOldSnapshots(aggregateID string, olderThanVersion int64) error {
+	return s.next.DeleteOldSnapshots(aggregateID, olderThanVersion)
+}
+
+// DeleteSnapshot removes a single snapshot when the wrapped store supports it
+// and is a no-op otherwise.
+func (s *InstrumentedSnapshotStore) DeleteSnapshot(aggregateID string, version int64) error {
+	deleter, ok := s.next.(interface {
+		DeleteSnapshot(aggregateID string, version int64) error
+	})
+	if !ok {
+		return nil
+	}
+	return deleter.DeleteSnapshot(aggregateID, version)
+}
+
+// GetSnapshotStats delegates to the wrapped store.
+func (s *InstrumentedSnapshotStore) GetSnapshotStats() (*store.SnapshotStats, error) {
+	return s.next.GetSnapshotStats()
+}
+
+// snapshotAttrs returns the payload size and, when the metadata records the
+// uncompressed size, the compression ratio (uncompressed / stored).
+func snapshotAttrs(snapshot *store.Snapshot) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		AttrSnapshotSize.Int(len(snapshot.Data)),
+	}
+	if snapshot.Metadata != nil && snapshot.Metadata.Size > 0 && len(snapshot.Data) > 0 {
+		ratio := float64(snapshot.Metadata.Size) / float64(len(snapshot.Data))
+		attrs = append(attrs, AttrSnapshotCompressionRatio.Float64(ratio))
+	}
+	return attrs
+}
@@ -35,24 +35,30 @@ type SQLiteExporterConfig struct {
 
 	// RetentionDays removes data older than this (0 = keep forever)
 	RetentionDays int
+
+	// CleanupInterval is how often data past RetentionDays is removed
+	// (default: 1h)
+	CleanupInterval time.Duration
 }
 
 // DefaultSQLiteExporterConfig returns sensible defaults
 func DefaultSQLiteExporterConfig(db *sql.DB) *SQLiteExporterConfig {
 	return &SQLiteExporterConfig{
-		DB:            db,
-		TracesTable:   "otel_traces",
-		SpansTable:    "otel_spans",
-		MetricsTable:  "otel_metrics",
-		MaxBatchSize:  100,
-		RetentionDays: 7, // Keep 1 week by default
+		DB:              db,
+		TracesTable:     "otel_traces",
+		SpansTable:      "otel_spans",
+		MetricsTable:    "otel_metrics",
+		MaxBatchSize:    100,
+		RetentionDays:   7, // Keep 1 week by default
+		CleanupInterval: time.Hour,
 	}
 }
 
 // SQLiteTraceExporter exports traces to SQLite
 type SQLiteTraceExporter struct {
-	config *SQLiteExporterConfig
-	mu     sync.Mutex
+	config    *SQLiteExporterConfig
+	mu        sync.Mutex
+	retention *retentionLoop // Nil without RetentionDays
 }
 
 // NewSQLiteTraceExporter creates a new SQLite trace exporter
@@ -73,6 +79,11 @@ func NewSQLiteTraceExporter(config *SQLiteExporterConfig) (*SQLiteTraceExporter,
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
+	// Cleanup old data if retention is configured
+	if config.RetentionDays > 0 {
+		exporter.retention = startRetentionLoop(config.CleanupInterval, exporter.cleanup)
+	}
+
 	return exporter, nil
 }
 
@@ -107,7 +118,10 @@ func (e *SQLiteTraceExporter) createTables() error {
 		)
 	`, e.config.SpansTable, e.config.TracesTable)
 
-	// Create indexes for efficient queries
+	// Create indexes for efficient queries. Attributes filtered on often
+	// (TraceQuery.AttributeFilters) deserve an index of their own, such as
+	//   CREATE INDEX idx_spans_attr_aggregate_id ON otel_spans(json_extract(attributes, '$."aggregate.id"'))
+	// which AttributeIndexSQL generates
 	indexSQL := fmt.Sprintf(`
 		CREATE INDEX IF NOT EXISTS idx_spans_trace_id ON %s(trace_id);
 		CREATE INDEX IF NOT EXISTS idx_spans_start_time ON %s(start_time);
@@ -217,18 +231,21 @@ func (e *SQLiteTraceExporter) ExportSpans(ctx context.Context, spans []sdktrace.
 		return fmt.Errorf("commit transaction: %w", err)
 	}
 
-	// Cleanup old data if retention is configured
-	if e.config.RetentionDays > 0 {
-		go e.cleanup()
-	}
-
 	return nil
 }
 
-// Shutdown implements sdktrace.SpanExporter
+// Shutdown implements sdktrace.SpanExporter. It stops the retention cleanup;
+// the SQLite connection is managed externally.
 func (e *SQLiteTraceExporter) Shutdown(ctx context.Context) error {
-	// SQLite connection is managed externally, nothing to do
-	return nil
+	return e.retention.stop(ctx)
+}
+
+// Vacuum reclaims the space left by removed traces, e.g. after lowering
+// RetentionDays. It rewrites the whole database, so run it off-peak.
+func (e *SQLiteTraceExporter) Vacuum() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return vacuum(e.config.DB)
 }
 
 // cleanup removes old traces based on retention policy
@@ -257,8 +274,9 @@ func (e *SQLiteTraceExporter) cleanup() {
 
 // SQLiteMetricExporter exports metrics to SQLite
 type SQLiteMetricExporter struct {
-	config *SQLiteExporterConfig
-	mu     sync.Mutex
+	config    *SQLiteExporterConfig
+	mu        sync.Mutex
+	retention *retentionLoop // Nil without RetentionDays
 }
 
 // NewSQLiteMetricExporter creates a new SQLite metric exporter
@@ -279,6 +297,11 @@ func NewSQLiteMetricExporter(config *SQLiteExporterConfig) (*SQLiteMetricExporte
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
+	// Cleanup old data if retention is configured
+	if config.RetentionDays > 0 {
+		exporter.retention = startRetentionLoop(config.CleanupInterval, exporter.cleanupMetrics)
+	}
+
 	return exporter, nil
 }
 
@@ -298,7 +321,10 @@ func (e *SQLiteMetricExporter) createTables() error {
 			min REAL,
 			max REAL,
 			attributes TEXT,
-			resource_attributes TEXT
+			resource_attributes TEXT,
+			bounds TEXT,
+			bucket_counts TEXT,
+			monotonic INTEGER
 		)
 	`, e.config.MetricsTable)
 
@@ -314,6 +340,9 @@ func (e *SQLiteMetricExporter) createTables() error {
 	if _, err := e.config.DB.Exec(metricsSQL); err != nil {
 		return fmt.Errorf("creating metrics table: %w", err)
 	}
+	if err := e.addMissingColumns(); err != nil {
+		return err
+	}
 	if _, err := e.config.DB.Exec(indexSQL); err != nil {
 		return fmt.Errorf("creating indexes: %w", err)
 	}
@@ -321,6 +350,46 @@ func (e *SQLiteMetricExporter) createTables() error {
 	return nil
 }
 
+// addMissingColumns adds the histogram bucket and monotonic columns to
+// metrics tables created before they existed.
+func (e *SQLiteMetricExporter) addMissingColumns() error {
+	rows, err := e.config.DB.Query(fmt.Sprintf("PRAGMA table_info(%s)", e.config.MetricsTable))
+	if err != nil {
+		return fmt.Errorf("reading metrics table columns: %w", err)
+	}
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("reading metrics table columns: %w", err)
+		}
+		columns[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading metrics table columns: %w", err)
+	}
+
+	for _, column := range []struct{ name, sqlType string }{
+		{"bounds", "TEXT"},
+		{"bucket_counts", "TEXT"},
+		{"monotonic", "INTEGER"},
+	} {
+		if columns[column.name] {
+			continue
+		}
+		if _, err := e.config.DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", e.config.MetricsTable, column.name, column.sqlType)); err != nil {
+			return fmt.Errorf("adding column %s: %w", column.name, err)
+		}
+	}
+	return nil
+}
+
 // Export implements sdkmetric.Exporter
 func (e *SQLiteMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
 	e.mu.Lock()
@@ -335,8 +404,9 @@ func (e *SQLiteMetricExporter) Export(ctx context.Context, rm *metricdata.Resour
 	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
 		INSERT INTO %s (
 			name, description, unit, type, timestamp,
-			value, count, sum, min, max, attributes, resource_attributes
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			value, count, sum, min, max, attributes, resource_attributes,
+			bounds, bucket_counts, monotonic
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, e.config.MetricsTable))
 	if err != nil {
 		return fmt.Errorf("prepare statement: %w", err)
@@ -358,11 +428,6 @@ func (e *SQLiteMetricExporter) Export(ctx context.Context, rm *metricdata.Resour
 		return fmt.Errorf("commit transaction: %w", err)
 	}
 
-	// Cleanup old data if retention is configured
-	if e.config.RetentionDays > 0 {
-		go e.cleanupMetrics()
-	}
-
 	return nil
 }
 
@@ -374,7 +439,7 @@ func (e *SQLiteMetricExporter) exportMetric(ctx context.Context, stmt *sql.Stmt,
 			attrs, _ := json.Marshal(attributeSetToMap(dp.Attributes))
 			if _, err := stmt.ExecContext(ctx,
 				m.Name, m.Description, m.Unit, "gauge", timestamp,
-				float64(dp.Value), nil, nil, nil, nil, string(attrs), resourceAttrs,
+				float64(dp.Value), nil, nil, nil, nil, string(attrs), resourceAttrs, nil, nil, nil,
 			); err != nil {
 				return err
 			}
@@ -384,7 +449,7 @@ func (e *SQLiteMetricExporter) exportMetric(ctx context.Context, stmt *sql.Stmt,
 			attrs, _ := json.Marshal(attributeSetToMap(dp.Attributes))
 			if _, err := stmt.ExecContext(ctx,
 				m.Name, m.Description, m.Unit, "gauge", timestamp,
-				dp.Value, nil, nil, nil, nil, string(attrs), resourceAttrs,
+				dp.Value, nil, nil, nil, nil, string(attrs), resourceAttrs, nil, nil, nil,
 			); err != nil {
 				return err
 			}
@@ -394,7 +459,7 @@ func (e *SQLiteMetricExporter) exportMetric(ctx context.Context, stmt *sql.Stmt,
 			attrs, _ := json.Marshal(attributeSetToMap(dp.Attributes))
 			if _, err := stmt.ExecContext(ctx,
 				m.Name, m.Description, m.Unit, "sum", timestamp,
-				float64(dp.Value), nil, nil, nil, nil, string(attrs), resourceAttrs,
+				float64(dp.Value), nil, nil, nil, nil, string(attrs), resourceAttrs, nil, nil, data.IsMonotonic,
 			); err != nil {
 				return err
 			}
@@ -404,7 +469,7 @@ func (e *SQLiteMetricExporter) exportMetric(ctx context.Context, stmt *sql.Stmt,
 			attrs, _ := json.Marshal(attributeSetToMap(dp.Attributes))
 			if _, err := stmt.ExecContext(ctx,
 				m.Name, m.Description, m.Unit, "sum", timestamp,
-				dp.Value, nil, nil, nil, nil, string(attrs), resourceAttrs,
+				dp.Value, nil, nil, nil, nil, string(attrs), resourceAttrs, nil, nil, data.IsMonotonic,
 			); err != nil {
 				return err
 			}
@@ -412,6 +477,7 @@ func (e *SQLiteMetricExporter) exportMetric(ctx context.Context, stmt *sql.Stmt,
 	case metricdata.Histogram[int64]:
 		for _, dp := range data.DataPoints {
 			attrs, _ := json.Marshal(attributeSetToMap(dp.Attributes))
+			bounds, counts := bucketsJSON(dp.Bounds, dp.BucketCounts)
 			var minVal, maxVal *float64
 			if minV, ok := dp.Min.Value(); ok {
 				v := float64(minV)
@@ -424,6 +490,7 @@ func (e *SQLiteMetricExporter) exportMetric(ctx context.Context, stmt *sql.Stmt,
 			if _, err := stmt.ExecContext(ctx,
 				m.Name, m.Description, m.Unit, "histogram", timestamp,
 				nil, dp.Count, float64(dp.Sum), minVal, maxVal, string(attrs), resourceAttrs,
+				bounds, counts, nil,
 			); err != nil {
 				return err
 			}
@@ -431,6 +498,7 @@ func (e *SQLiteMetricExporter) exportMetric(ctx context.Context, stmt *sql.Stmt,
 	case metricdata.Histogram[float64]:
 		for _, dp := range data.DataPoints {
 			attrs, _ := json.Marshal(attributeSetToMap(dp.Attributes))
+			bounds, counts := bucketsJSON(dp.Bounds, dp.BucketCounts)
 			var minVal, maxVal *float64
 			if minV, ok := dp.Min.Value(); ok {
 				minVal = &minV
@@ -441,6 +509,7 @@ func (e *SQLiteMetricExporter) exportMetric(ctx context.Context, stmt *sql.Stmt,
 			if _, err := stmt.ExecContext(ctx,
 				m.Name, m.Description, m.Unit, "histogram", timestamp,
 				nil, dp.Count, dp.Sum, minVal, maxVal, string(attrs), resourceAttrs,
+				bounds, counts, nil,
 			); err != nil {
 				return err
 			}
@@ -449,6 +518,14 @@ func (e *SQLiteMetricExporter) exportMetric(ctx context.Context, stmt *sql.Stmt,
 	return nil
 }
 
+// bucketsJSON encodes the explicit bucket boundaries and per-bucket counts of
+// a histogram data point for the bounds and bucket_counts columns.
+func bucketsJSON(bounds []float64, counts []uint64) (string, string) {
+	boundsJSON, _ := json.Marshal(bounds)
+	countsJSON, _ := json.Marshal(counts)
+	return string(boundsJSON), string(countsJSON)
+}
+
 // Temporality implements sdkmetric.Exporter
 func (e *SQLiteMetricExporter) Temporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
 	return metricdata.CumulativeTemporality
@@ -464,9 +541,17 @@ func (e *SQLiteMetricExporter) ForceFlush(ctx context.Context) error {
 	return nil
 }
 
-// Shutdown implements sdkmetric.Exporter
+// Shutdown implements sdkmetric.Exporter. It stops the retention cleanup.
 func (e *SQLiteMetricExporter) Shutdown(ctx context.Context) error {
-	return nil
+	return e.retention.stop(ctx)
+}
+
+// Vacuum reclaims the space left by removed metrics (see
+// SQLiteTraceExporter.Vacuum).
+func (e *SQLiteMetricExporter) Vacuum() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return vacuum(e.config.DB)
 }
 
 // cleanupMetrics removes old metrics based on retention policy
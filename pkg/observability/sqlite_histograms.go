@@ -0,0 +1,182 @@
+package observability
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// ErrNoHistogramData is returned by GetHistogramQuantile when no observations
+// of the histogram were recorded in the requested range.
+var ErrNoHistogramData = errors.New("no histogram data")
+
+// histogramRow is a stored histogram data point.
+type histogramRow struct {
+	bounds   []float64
+	counts   []uint64
+	min, max sql.NullFloat64
+}
+
+// GetHistogramQuantile estimates the q-quantile (0 ≤ q ≤ 1, e.g. 0.99 for
+// p99) of the histogram name over the observations recorded between since and
+// until, interpolating linearly within the bucket the quantile falls in, as
+// Prometheus' histogram_quantile does. Zero times leave the range open.
+//
+// Stored histograms are cumulative, so each series (attribute set) contributes
+// its bucket counts at until minus those last stored before since; a series
+// whose counts went down was restarted and contributes its counts at until.
+// All series must share their bucket boundaries. The recorded min and max, if
+// any, tighten the first and last buckets.
+func (q *SQLiteObservabilityQueries) GetHistogramQuantile(name string, quantile float64, since, until time.Time) (float64, error) {
+	if quantile < 0 || quantile > 1 || math.IsNaN(quantile) {
+		return 0, fmt.Errorf("quantile %v out of range [0, 1]", quantile)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT timestamp, min, max, attributes, resource_attributes, bounds, bucket_counts
+		FROM %s
+		WHERE name = ? AND type = 'histogram' AND bounds IS NOT NULL
+	`, q.metricsTable)
+	args := []interface{}{name}
+	if !until.IsZero() {
+		query += " AND timestamp <= ?"
+		args = append(args, until.Unix())
+	}
+	query += " ORDER BY id"
+
+	rows, err := q.db.Query(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query histogram: %w", err)
+	}
+	defer rows.Close()
+
+	baseline := make(map[string]histogramRow) // Last row before since, per series
+	latest := make(map[string]histogramRow)   // Last row in range, per series
+	for rows.Next() {
+		var (
+			timestamp                    int64
+			row                          histogramRow
+			attrs, resourceAttrs         sql.NullString
+			boundsJSON, bucketCountsJSON string
+		)
+		if err := rows.Scan(&timestamp, &row.min, &row.max, &attrs, &resourceAttrs, &boundsJSON, &bucketCountsJSON); err != nil {
+			return 0, fmt.Errorf("scan histogram: %w", err)
+		}
+		if err := json.Unmarshal([]byte(boundsJSON), &row.bounds); err != nil {
+			return 0, fmt.Errorf("unmarshal bounds: %w", err)
+		}
+		if err := json.Unmarshal([]byte(bucketCountsJSON), &row.counts); err != nil {
+			return 0, fmt.Errorf("unmarshal bucket counts: %w", err)
+		}
+
+		series := attrs.String + "\x00" + resourceAttrs.String
+		if !since.IsZero() && timestamp < since.Unix() {
+			baseline[series] = row
+		} else {
+			latest[series] = row
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("query histogram: %w", err)
+	}
+
+	var merged *histogramRow
+	for series, row := range latest {
+		counts := row.counts
+		if base, ok := baseline[series]; ok && slices.Equal(base.bounds, row.bounds) {
+			if delta, ok := subtractCounts(row.counts, base.counts); ok {
+				counts = delta
+			}
+		}
+
+		if merged == nil {
+			merged = &histogramRow{bounds: row.bounds, counts: slices.Clone(counts), min: row.min, max: row.max}
+			continue
+		}
+		if !slices.Equal(merged.bounds, row.bounds) || len(merged.counts) != len(counts) {
+			return 0, fmt.Errorf("histogram %s has series with different bucket boundaries", name)
+		}
+		for i, count := range counts {
+			merged.counts[i] += count
+		}
+		if row.min.Valid && (!merged.min.Valid || row.min.Float64 < merged.min.Float64) {
+			merged.min = row.min
+		}
+		if row.max.Valid && (!merged.max.Valid || row.max.Float64 > merged.max.Float64) {
+			merged.max = row.max
+		}
+	}
+	if merged == nil {
+		return 0, fmt.Errorf("%w for %s", ErrNoHistogramData, name)
+	}
+	return bucketQuantile(quantile, *merged, name)
+}
+
+// subtractCounts returns counts minus base, or false if a count went down.
+func subtractCounts(counts, base []uint64) ([]uint64, bool) {
+	if len(counts) != len(base) {
+		return nil, false
+	}
+	delta := make([]uint64, len(counts))
+	for i := range counts {
+		if counts[i] < base[i] {
+			return nil, false
+		}
+		delta[i] = counts[i] - base[i]
+	}
+	return delta, true
+}
+
+// bucketQuantile interpolates the quantile from the bucket counts of h.
+func bucketQuantile(quantile float64, h histogramRow, name string) (float64, error) {
+	var total uint64
+	for _, count := range h.counts {
+		total += count
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("%w for %s", ErrNoHistogramData, name)
+	}
+
+	rank := quantile * float64(total)
+	var cumulative uint64
+	for i, count := range h.counts {
+		previous := cumulative
+		cumulative += count
+		if count == 0 || float64(cumulative) < rank {
+			continue
+		}
+
+		// Bucket i holds (bounds[i-1], bounds[i]]; the first and last are
+		// open-ended, bounded by the recorded min and max where known
+		var lower, upper float64
+		switch {
+		case i > 0:
+			lower = h.bounds[i-1]
+		case h.min.Valid:
+			lower = h.min.Float64
+		case len(h.bounds) > 0:
+			lower = math.Min(0, h.bounds[0])
+		}
+		switch {
+		case i < len(h.bounds):
+			upper = h.bounds[i]
+		case h.max.Valid:
+			upper = h.max.Float64
+		default:
+			return lower, nil
+		}
+		if h.min.Valid && h.min.Float64 > lower {
+			lower = h.min.Float64
+		}
+		if h.max.Valid && h.max.Float64 < upper {
+			upper = h.max.Float64
+		}
+
+		return lower + (upper-lower)*(rank-float64(previous))/float64(count), nil
+	}
+	return 0, fmt.Errorf("%w for %s", ErrNoHistogramData, name)
+}
@@ -0,0 +1,84 @@
+package observability_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/observability"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	_ "modernc.org/sqlite"
+)
+
+func TestGetHistogramQuantile(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	config := observability.DefaultSQLiteExporterConfig(db)
+	exporter, err := observability.NewSQLiteMetricExporter(config)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	err = exporter.Export(ctx, &metricdata.ResourceMetrics{
+		Resource: resource.Empty(),
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Metrics: []metricdata.Metrics{{
+				Name: "command.duration",
+				Data: metricdata.Histogram[float64]{DataPoints: []metricdata.HistogramDataPoint[float64]{{
+					Count:        100,
+					Sum:          5000,
+					Bounds:       []float64{10, 100, 1000},
+					BucketCounts: []uint64{50, 40, 10, 0},
+					Min:          metricdata.NewExtrema(1.0),
+					Max:          metricdata.NewExtrema(900.0),
+				}}},
+			}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to export histogram: %v", err)
+	}
+
+	queries := observability.NewSQLiteObservabilityQueries(db, config)
+	for _, tc := range []struct {
+		quantile, want float64
+	}{
+		{0.5, 10},   // End of the first bucket
+		{0.7, 55},   // Halfway through (10, 100]
+		{0.95, 500}, // Halfway through (100, 1000], capped at the max of 900
+	} {
+		got, err := queries.GetHistogramQuantile("command.duration", tc.quantile, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("failed to get quantile %v: %v", tc.quantile, err)
+		}
+		if math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("expected quantile %v to be %v, got %v", tc.quantile, tc.want, got)
+		}
+	}
+
+	metrics, err := queries.QueryMetrics(observability.MetricQuery{Name: "command.duration"})
+	if err != nil {
+		t.Fatalf("failed to query metrics: %v", err)
+	}
+	if len(metrics) != 1 || len(metrics[0].Bounds) != 3 || len(metrics[0].BucketCounts) != 4 {
+		t.Errorf("expected the stored buckets to be returned, got %+v", metrics)
+	}
+
+	// Nothing was recorded after the only export
+	_, err = queries.GetHistogramQuantile("command.duration", 0.5, time.Now().Add(time.Hour), time.Time{})
+	if !errors.Is(err, observability.ErrNoHistogramData) {
+		t.Errorf("expected ErrNoHistogramData for an empty range, got %v", err)
+	}
+	if _, err := queries.GetHistogramQuantile("command.duration", 1.5, time.Time{}, time.Time{}); err == nil {
+		t.Error("expected an out of range quantile to be rejected")
+	}
+}
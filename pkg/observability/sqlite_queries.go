@@ -4,6 +4,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -21,6 +25,11 @@ type TraceQuery struct {
 	// Name filters spans by name (exact match or LIKE pattern)
 	Name string
 
+	// AttributeFilters filters spans by attribute values, e.g.
+	// {"aggregate.id": "acc-123"}. Values match string attributes; see
+	// AttributeIndexSQL for indexing frequently filtered keys
+	AttributeFilters map[string]string
+
 	// Since filters spans that started after this time
 	Since time.Time
 
@@ -101,6 +110,8 @@ type MetricDataPoint struct {
 	Max                *float64
 	Attributes         map[string]interface{}
 	ResourceAttributes map[string]interface{}
+	Bounds             []float64 // Histogram bucket upper bounds
+	BucketCounts       []uint64  // Histogram counts, one more than Bounds
 }
 
 // SQLiteObservabilityQueries provides helper methods for querying observability data
@@ -151,6 +162,15 @@ func (q *SQLiteObservabilityQueries) QuerySpans(query TraceQuery) ([]Span, error
 		args = append(args, query.Name)
 	}
 
+	for _, key := range slices.Sorted(maps.Keys(query.AttributeFilters)) {
+		path, err := attributePath(key)
+		if err != nil {
+			return nil, err
+		}
+		sql += fmt.Sprintf(" AND json_extract(attributes, '%s') = ?", path)
+		args = append(args, query.AttributeFilters[key])
+	}
+
 	if !query.Since.IsZero() {
 		sql += " AND start_time >= ?"
 		args = append(args, query.Since.UnixNano())
@@ -320,7 +340,8 @@ func (q *SQLiteObservabilityQueries) QueryMetrics(query MetricQuery) ([]MetricDa
 	sql := fmt.Sprintf(`
 		SELECT
 			id, name, description, unit, type, timestamp,
-			value, count, sum, min, max, attributes, resource_attributes
+			value, count, sum, min, max, attributes, resource_attributes,
+			bounds, bucket_counts
 		FROM %s
 		WHERE 1=1
 	`, q.metricsTable)
@@ -521,6 +542,7 @@ func (q *SQLiteObservabilityQueries) scanMetric(rows *sql.Rows) (MetricDataPoint
 	var metric MetricDataPoint
 	var timestamp int64
 	var attrsJSON, resourceAttrsJSON string
+	var boundsJSON, countsJSON sql.NullString
 
 	err := rows.Scan(
 		&metric.ID,
@@ -536,6 +558,8 @@ func (q *SQLiteObservabilityQueries) scanMetric(rows *sql.Rows) (MetricDataPoint
 		&metric.Max,
 		&attrsJSON,
 		&resourceAttrsJSON,
+		&boundsJSON,
+		&countsJSON,
 	)
 	if err != nil {
 		return metric, err
@@ -549,10 +573,49 @@ func (q *SQLiteObservabilityQueries) scanMetric(rows *sql.Rows) (MetricDataPoint
 	if err := json.Unmarshal([]byte(resourceAttrsJSON), &metric.ResourceAttributes); err != nil {
 		return metric, fmt.Errorf("unmarshal resource attributes: %w", err)
 	}
+	if boundsJSON.Valid && countsJSON.Valid {
+		if err := json.Unmarshal([]byte(boundsJSON.String), &metric.Bounds); err != nil {
+			return metric, fmt.Errorf("unmarshal bounds: %w", err)
+		}
+		if err := json.Unmarshal([]byte(countsJSON.String), &metric.BucketCounts); err != nil {
+			return metric, fmt.Errorf("unmarshal bucket counts: %w", err)
+		}
+	}
 
 	return metric, nil
 }
 
+// attributeKeyPattern matches the attribute keys TraceQuery.AttributeFilters
+// accepts, which are embedded in SQL as JSON paths.
+var attributeKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]+$`)
+
+// attributePath returns the JSON path of attribute key in the attributes column.
+// Keys are quoted, as OpenTelemetry keys contain dots.
+func attributePath(key string) (string, error) {
+	if !attributeKeyPattern.MatchString(key) {
+		return "", fmt.Errorf("invalid attribute key %q", key)
+	}
+	return `$."` + key + `"`, nil
+}
+
+// AttributeIndexSQL returns a CREATE INDEX statement for the spans table that
+// serves QuerySpans filters on attribute key, so looking up e.g. all spans of
+// one aggregate does not scan every span. The expression matches the one
+// QuerySpans generates, which SQLite requires to use the index.
+//
+// Example:
+//
+//	stmt, _ := observability.AttributeIndexSQL("otel_spans", "aggregate.id")
+//	_, err := db.Exec(stmt)
+func AttributeIndexSQL(spansTable, key string) (string, error) {
+	path, err := attributePath(key)
+	if err != nil {
+		return "", err
+	}
+	index := "idx_spans_attr_" + strings.NewReplacer(".", "_", "-", "_").Replace(key)
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s(json_extract(attributes, '%s'))", index, spansTable, path), nil
+}
+
 // containsWildcard checks if a string contains SQL wildcard characters
 func containsWildcard(s string) bool {
 	return len(s) > 0 && (s[0] == '%' || s[len(s)-1] == '%' || s[0] == '_')
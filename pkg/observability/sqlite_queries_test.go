@@ -0,0 +1,97 @@
+package observability_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/observability"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	_ "modernc.org/sqlite"
+)
+
+func TestQuerySpansByAttribute(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	config := observability.DefaultSQLiteExporterConfig(db)
+	exporter, err := observability.NewSQLiteTraceExporter(config)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(ctx)
+
+	for _, span := range []struct{ name, aggregate, command string }{
+		{"deposit-1", "acc-123", "Deposit"},
+		{"withdraw-1", "acc-123", "Withdraw"},
+		{"deposit-2", "acc-456", "Deposit"},
+	} {
+		_, s := tp.Tracer("test").Start(ctx, span.name)
+		s.SetAttributes(
+			attribute.String("aggregate.id", span.aggregate),
+			attribute.String("command.type", span.command),
+		)
+		s.End()
+	}
+
+	index, err := observability.AttributeIndexSQL(config.SpansTable, "aggregate.id")
+	if err != nil {
+		t.Fatalf("failed to generate index: %v", err)
+	}
+	if _, err := db.Exec(index); err != nil {
+		t.Fatalf("failed to create index: %v", err)
+	}
+
+	queries := observability.NewSQLiteObservabilityQueries(db, config)
+	names := func(filters map[string]string) []string {
+		t.Helper()
+		spans, err := queries.QuerySpans(observability.TraceQuery{AttributeFilters: filters})
+		if err != nil {
+			t.Fatalf("failed to query spans: %v", err)
+		}
+		var names []string
+		for _, span := range spans {
+			names = append(names, span.Name)
+		}
+		return names
+	}
+
+	if got := names(map[string]string{"aggregate.id": "acc-123"}); len(got) != 2 {
+		t.Errorf("expected both spans of acc-123, got %v", got)
+	}
+	got := names(map[string]string{"aggregate.id": "acc-123", "command.type": "Deposit"})
+	if len(got) != 1 || got[0] != "deposit-1" {
+		t.Errorf("expected only deposit-1, got %v", got)
+	}
+
+	// The filter uses the index
+	var plan strings.Builder
+	rows, err := db.Query(`EXPLAIN QUERY PLAN SELECT span_id FROM otel_spans WHERE json_extract(attributes, '$."aggregate.id"') = ?`, "acc-123")
+	if err != nil {
+		t.Fatalf("failed to explain query: %v", err)
+	}
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatalf("failed to scan plan: %v", err)
+		}
+		plan.WriteString(detail)
+	}
+	rows.Close()
+	if !strings.Contains(plan.String(), "idx_spans_attr_aggregate_id") {
+		t.Errorf("expected the query to use the attribute index, got plan %q", plan.String())
+	}
+
+	if _, err := queries.QuerySpans(observability.TraceQuery{AttributeFilters: map[string]string{"bad'key": "x"}}); err == nil {
+		t.Error("expected an invalid attribute key to be rejected")
+	}
+}
@@ -0,0 +1,74 @@
+package observability
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// retentionLoop runs an exporter's retention cleanup in one background
+// goroutine, so cleanups never overlap each other however often data is
+// exported.
+type retentionLoop struct {
+	done     chan struct{} // Closed when the loop exits
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+// startRetentionLoop runs cleanup now and then every interval (1h if not
+// positive) until stop is called.
+func startRetentionLoop(interval time.Duration, cleanup func()) *retentionLoop {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	l := &retentionLoop{
+		done: make(chan struct{}),
+		quit: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(l.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			cleanup()
+			select {
+			case <-ticker.C:
+			case <-l.quit:
+				return
+			}
+		}
+	}()
+	return l
+}
+
+// stop ends the loop and waits for a running cleanup to finish, or for ctx.
+// It is a no-op on a nil loop.
+func (l *retentionLoop) stop(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.quitOnce.Do(func() { close(l.quit) })
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// vacuum rebuilds db to return the pages freed by deletions to the file
+// system, then truncates the WAL (if any) the rebuild went through.
+func vacuum(db *sql.DB) error {
+	if _, err := db.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("checkpoint WAL: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package observability_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/observability"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLiteMetricExporterRetention(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	config := observability.DefaultSQLiteExporterConfig(db)
+	config.CleanupInterval = 10 * time.Millisecond
+	exporter, err := observability.NewSQLiteMetricExporter(config)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+
+	// A row past retention, written behind the exporter's back
+	old := time.Now().AddDate(0, 0, -config.RetentionDays-1).Unix()
+	if _, err := db.Exec("INSERT INTO otel_metrics (name, type, timestamp, value) VALUES ('old', 'gauge', ?, 1)", old); err != nil {
+		t.Fatalf("failed to insert metric: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM otel_metrics").Scan(&count); err != nil {
+			t.Fatalf("failed to count metrics: %v", err)
+		}
+		if count == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the background cleanup to remove the expired metric")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shut down: %v", err)
+	}
+	if err := exporter.Shutdown(ctx); err != nil {
+		t.Fatalf("expected a second shutdown to succeed: %v", err)
+	}
+	if err := exporter.Vacuum(); err != nil {
+		t.Fatalf("failed to vacuum: %v", err)
+	}
+}
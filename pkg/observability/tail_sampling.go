@@ -0,0 +1,304 @@
+package observability
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TailSamplingPolicy decides whether to keep a finished trace, given all of
+// its spans that ended in this process.
+type TailSamplingPolicy func(spans []sdktrace.ReadOnlySpan) bool
+
+// TailSamplingConfig configures tail-based sampling (see Config.TailSampling).
+type TailSamplingConfig struct {
+	// Policies decide which traces to keep: a trace is exported if any
+	// policy keeps it, and dropped otherwise
+	Policies []TailSamplingPolicy
+
+	// DecisionWait bounds how long spans of a trace are buffered waiting for
+	// its root span, e.g. when the root ends in another process, and how long
+	// a decision is remembered for spans ending after it (default: 30s)
+	DecisionWait time.Duration
+
+	// MaxTraces bounds the number of traces buffered at once; beyond it the
+	// oldest trace is decided early (default: 10000)
+	MaxTraces int
+}
+
+// KeepErrors keeps traces with a span whose status is Error.
+func KeepErrors() TailSamplingPolicy {
+	return func(spans []sdktrace.ReadOnlySpan) bool {
+		for _, span := range spans {
+			if span.Status().Code == codes.Error {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// KeepSlowerThan keeps traces with a span that took longer than threshold.
+func KeepSlowerThan(threshold time.Duration) TailSamplingPolicy {
+	return func(spans []sdktrace.ReadOnlySpan) bool {
+		for _, span := range spans {
+			if span.EndTime().Sub(span.StartTime()) > threshold {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// KeepAttribute keeps traces with a span carrying the attribute kv, e.g.
+// attribute.String("tenant.id", "acme") while debugging one tenant.
+func KeepAttribute(kv attribute.KeyValue) TailSamplingPolicy {
+	return func(spans []sdktrace.ReadOnlySpan) bool {
+		for _, span := range spans {
+			for _, attr := range span.Attributes() {
+				if attr == kv {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// TailSamplingProcessor is a span processor that buffers the spans of each
+// trace until its root span ends, then exports all of them if a policy keeps
+// the trace and drops them otherwise. Kept spans are batched to the exporter.
+// Spans ending after their trace was decided follow that decision, and a
+// background sweep decides traces whose root never ends here.
+//
+// Unlike head sampling (Config.TraceSampleRate), which decides before a trace
+// is known to be interesting, this never loses failed or slow traces while
+// keeping the exporter's storage, such as a SQLiteTraceExporter database,
+// small.
+type TailSamplingProcessor struct {
+	next     sdktrace.SpanProcessor
+	policies []TailSamplingPolicy
+	wait     time.Duration
+	max      int
+
+	mu           sync.Mutex
+	traces       map[trace.TraceID]*bufferedTrace
+	order        []trace.TraceID // Buffered traces, oldest first
+	decided      map[trace.TraceID]decidedTrace
+	decidedOrder []trace.TraceID // Remembered decisions, oldest first
+
+	stopSweep chan struct{}
+	sweepDone chan struct{}
+	stopOnce  sync.Once
+}
+
+// bufferedTrace holds the ended spans of an undecided trace.
+type bufferedTrace struct {
+	spans   []sdktrace.ReadOnlySpan
+	started time.Time
+}
+
+// decidedTrace remembers the decision on a trace for its late spans.
+type decidedTrace struct {
+	keep bool
+	at   time.Time
+}
+
+// pendingTrace is a trace taken out of the buffer to be decided.
+type pendingTrace struct {
+	id    trace.TraceID
+	spans []sdktrace.ReadOnlySpan
+}
+
+// NewTailSamplingProcessor creates a tail-sampling processor exporting the
+// kept traces to exporter.
+//
+// Example:
+//
+//	exporter, _ := observability.NewSQLiteTraceExporter(observability.DefaultSQLiteExporterConfig(db))
+//	processor := observability.NewTailSamplingProcessor(exporter, observability.TailSamplingConfig{
+//	    Policies: []observability.TailSamplingPolicy{
+//	        observability.KeepErrors(),
+//	        observability.KeepSlowerThan(500 * time.Millisecond),
+//	    },
+//	})
+//	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+func NewTailSamplingProcessor(exporter sdktrace.SpanExporter, config TailSamplingConfig) *TailSamplingProcessor {
+	if config.DecisionWait <= 0 {
+		config.DecisionWait = 30 * time.Second
+	}
+	if config.MaxTraces <= 0 {
+		config.MaxTraces = 10000
+	}
+	p := &TailSamplingProcessor{
+		next:      sdktrace.NewBatchSpanProcessor(exporter),
+		policies:  config.Policies,
+		wait:      config.DecisionWait,
+		max:       config.MaxTraces,
+		traces:    make(map[trace.TraceID]*bufferedTrace),
+		decided:   make(map[trace.TraceID]decidedTrace),
+		stopSweep: make(chan struct{}),
+		sweepDone: make(chan struct{}),
+	}
+	go p.sweepPeriodically()
+	return p
+}
+
+// OnStart implements sdktrace.SpanProcessor.
+func (p *TailSamplingProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd implements sdktrace.SpanProcessor. It buffers span and decides its
+// trace once span is the trace's local root.
+func (p *TailSamplingProcessor) OnEnd(span sdktrace.ReadOnlySpan) {
+	traceID := span.SpanContext().TraceID()
+	now := time.Now()
+
+	p.mu.Lock()
+	// A span ending after its trace was decided follows the decision
+	if decided, ok := p.decided[traceID]; ok {
+		p.mu.Unlock()
+		if decided.keep {
+			p.next.OnEnd(span)
+		}
+		return
+	}
+
+	buffered, ok := p.traces[traceID]
+	if !ok {
+		buffered = &bufferedTrace{started: now}
+		p.traces[traceID] = buffered
+		p.order = append(p.order, traceID)
+	}
+	buffered.spans = append(buffered.spans, span)
+
+	var decide []pendingTrace
+	if parent := span.Parent(); !parent.IsValid() || parent.IsRemote() {
+		decide = append(decide, pendingTrace{id: traceID, spans: p.remove(traceID)})
+	}
+	decide = append(decide, p.expired(now)...)
+	p.mu.Unlock()
+
+	for _, pending := range decide {
+		p.decide(pending)
+	}
+}
+
+// expired takes the traces that waited too long for their root, or exceed
+// MaxTraces, out of the buffer, and forgets decisions older than
+// DecisionWait. The caller holds p.mu.
+func (p *TailSamplingProcessor) expired(now time.Time) []pendingTrace {
+	var decide []pendingTrace
+	for len(p.order) > 0 {
+		oldest := p.traces[p.order[0]]
+		if len(p.order) <= p.max && now.Sub(oldest.started) < p.wait {
+			break
+		}
+		id := p.order[0]
+		decide = append(decide, pendingTrace{id: id, spans: p.remove(id)})
+	}
+	for len(p.decidedOrder) > 0 {
+		oldest := p.decidedOrder[0]
+		if len(p.decidedOrder) <= p.max && now.Sub(p.decided[oldest].at) < p.wait {
+			break
+		}
+		delete(p.decided, oldest)
+		p.decidedOrder = p.decidedOrder[1:]
+	}
+	return decide
+}
+
+// sweepPeriodically decides expired traces until Shutdown, so they do not
+// wait for the next span to end.
+func (p *TailSamplingProcessor) sweepPeriodically() {
+	defer close(p.sweepDone)
+	ticker := time.NewTicker(max(p.wait/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopSweep:
+			return
+		case now := <-ticker.C:
+			p.mu.Lock()
+			decide := p.expired(now)
+			p.mu.Unlock()
+			for _, pending := range decide {
+				p.decide(pending)
+			}
+		}
+	}
+}
+
+// remove takes the spans of traceID out of the buffer. The caller holds p.mu.
+func (p *TailSamplingProcessor) remove(traceID trace.TraceID) []sdktrace.ReadOnlySpan {
+	buffered := p.traces[traceID]
+	delete(p.traces, traceID)
+	for i, id := range p.order {
+		if id == traceID {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+	return buffered.spans
+}
+
+// decide hands the spans of a trace to the exporter if a policy keeps it,
+// and remembers the decision for spans of the trace that end later.
+func (p *TailSamplingProcessor) decide(pending pendingTrace) {
+	keep := false
+	for _, policy := range p.policies {
+		if policy(pending.spans) {
+			keep = true
+			break
+		}
+	}
+
+	p.mu.Lock()
+	if _, ok := p.decided[pending.id]; !ok {
+		p.decidedOrder = append(p.decidedOrder, pending.id)
+	}
+	p.decided[pending.id] = decidedTrace{keep: keep, at: time.Now()}
+	// Spans that ended while the policies ran started a new buffer
+	spans := pending.spans
+	if _, ok := p.traces[pending.id]; ok {
+		spans = append(spans, p.remove(pending.id)...)
+	}
+	p.mu.Unlock()
+
+	if keep {
+		for _, span := range spans {
+			p.next.OnEnd(span)
+		}
+	}
+}
+
+// ForceFlush decides all buffered traces and exports the kept spans.
+func (p *TailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	p.mu.Lock()
+	var decide []pendingTrace
+	for len(p.order) > 0 {
+		id := p.order[0]
+		decide = append(decide, pendingTrace{id: id, spans: p.remove(id)})
+	}
+	p.mu.Unlock()
+
+	for _, pending := range decide {
+		p.decide(pending)
+	}
+	return p.next.ForceFlush(ctx)
+}
+
+// Shutdown stops the sweep, decides the buffered traces and shuts down the
+// exporter.
+func (p *TailSamplingProcessor) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		close(p.stopSweep)
+		<-p.sweepDone
+	})
+	return errors.Join(p.ForceFlush(ctx), p.next.Shutdown(ctx))
+}
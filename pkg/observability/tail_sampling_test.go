@@ -0,0 +1,124 @@
+package observability_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTailSamplingProcessor(t *testing.T) {
+	ctx := context.Background()
+	exporter := tracetest.NewInMemoryExporter()
+	processor := observability.NewTailSamplingProcessor(exporter, observability.TailSamplingConfig{
+		Policies: []observability.TailSamplingPolicy{
+			observability.KeepErrors(),
+			observability.KeepSlowerThan(time.Hour),
+			observability.KeepAttribute(attribute.String("tenant.id", "acme")),
+		},
+	})
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+	defer tp.Shutdown(ctx)
+	tracer := tp.Tracer("test")
+
+	// run runs a root span with one child, marking the child with mark.
+	run := func(name string, mark func(child trace.Span)) {
+		rootCtx, root := tracer.Start(ctx, name)
+		_, child := tracer.Start(rootCtx, name+"-child")
+		mark(child)
+		child.End()
+		root.End()
+	}
+	run("ok", func(trace.Span) {})
+	run("failed", func(child trace.Span) { child.SetStatus(codes.Error, "boom") })
+	run("tenant", func(child trace.Span) { child.SetAttributes(attribute.String("tenant.id", "acme")) })
+
+	if err := processor.ForceFlush(ctx); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	exported := make(map[string]bool)
+	for _, span := range exporter.GetSpans() {
+		exported[span.Name] = true
+	}
+	for _, name := range []string{"failed", "failed-child", "tenant", "tenant-child"} {
+		if !exported[name] {
+			t.Errorf("expected span %s of a kept trace to be exported, got %v", name, exported)
+		}
+	}
+	if exported["ok"] || exported["ok-child"] {
+		t.Errorf("expected the unremarkable trace to be dropped, got %v", exported)
+	}
+
+	t.Run("UnfinishedTraceDecidedOnFlush", func(t *testing.T) {
+		exporter.Reset()
+		rootCtx, root := tracer.Start(ctx, "open")
+		_, child := tracer.Start(rootCtx, "open-child")
+		child.RecordError(errors.New("boom"))
+		child.SetStatus(codes.Error, "boom")
+		child.End()
+
+		if err := processor.ForceFlush(ctx); err != nil {
+			t.Fatalf("failed to flush: %v", err)
+		}
+		if spans := exporter.GetSpans(); len(spans) != 1 || spans[0].Name != "open-child" {
+			t.Errorf("expected the buffered child to be exported, got %v", spans)
+		}
+		root.End()
+	})
+
+	t.Run("LateSpanFollowsDecision", func(t *testing.T) {
+		exporter.Reset()
+		rootCtx, root := tracer.Start(ctx, "late")
+		_, child := tracer.Start(rootCtx, "late-child")
+		root.SetStatus(codes.Error, "boom")
+		root.End()
+		child.End() // e.g. an async step outliving its request
+
+		if err := processor.ForceFlush(ctx); err != nil {
+			t.Fatalf("failed to flush: %v", err)
+		}
+		exported := make(map[string]bool)
+		for _, span := range exporter.GetSpans() {
+			exported[span.Name] = true
+		}
+		if !exported["late"] || !exported["late-child"] {
+			t.Errorf("expected the late child to join its kept trace, got %v", exported)
+		}
+	})
+}
+
+func TestTailSamplingProcessorSweepsExpiredTraces(t *testing.T) {
+	ctx := context.Background()
+	exporter := tracetest.NewInMemoryExporter()
+	processor := observability.NewTailSamplingProcessor(exporter, observability.TailSamplingConfig{
+		Policies:     []observability.TailSamplingPolicy{observability.KeepErrors()},
+		DecisionWait: 200 * time.Millisecond,
+	})
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+	defer tp.Shutdown(ctx)
+	tracer := tp.Tracer("test")
+
+	// The child waits for its root longer than DecisionWait, so the sweep
+	// decides the trace without it, and the root, ending while the decision
+	// is remembered, follows it
+	rootCtx, root := tracer.Start(ctx, "slow")
+	_, child := tracer.Start(rootCtx, "slow-child")
+	child.End()
+	time.Sleep(350 * time.Millisecond)
+	root.SetStatus(codes.Error, "boom")
+	root.End()
+
+	if err := processor.ForceFlush(ctx); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("expected the sweep to have dropped the trace before its root ended, got %v", spans)
+	}
+}
@@ -28,6 +28,12 @@ type Config struct {
 	TraceExporter  sdktrace.SpanExporter // Pluggable exporter (OTLP, Jaeger, stdout, etc)
 	TraceSampleRate float64               // 0.0 to 1.0 (1.0 = trace everything)
 
+	// TailSampling buffers each trace until its root span ends and exports it
+	// only if a policy keeps it (nil = export every sampled span). Head
+	// sampling still applies first, so set TraceSampleRate to 1.0 to let the
+	// policies see every trace.
+	TailSampling *TailSamplingConfig
+
 	// Metrics
 	MetricReader   sdkmetric.Reader      // Pluggable reader (Prometheus, OTLP, stdout, etc)
 
@@ -142,10 +148,18 @@ func setupTracing(res *resource.Resource, cfg Config) (trace.TracerProvider, fun
 		sampler = sdktrace.TraceIDRatioBased(cfg.TraceSampleRate)
 	}
 
+	// Batch spans for efficiency, after tail sampling if configured
+	var processor sdktrace.SpanProcessor
+	if cfg.TailSampling != nil {
+		processor = NewTailSamplingProcessor(cfg.TraceExporter, *cfg.TailSampling)
+	} else {
+		processor = sdktrace.NewBatchSpanProcessor(cfg.TraceExporter)
+	}
+
 	// Create trace provider
 	tp := sdktrace.NewTracerProvider(
 		sdktrace.WithResource(res),
-		sdktrace.WithBatcher(cfg.TraceExporter), // Batches spans for efficiency
+		sdktrace.WithSpanProcessor(processor),
 		sdktrace.WithSampler(sampler),
 	)
 
@@ -105,7 +105,9 @@ var (
 	AttrOperation = attribute.Key("repository.operation")
 
 	// Snapshot attributes
-	AttrSnapshotHit = attribute.Key("snapshot.hit")
+	AttrSnapshotHit              = attribute.Key("snapshot.hit")
+	AttrSnapshotSize             = attribute.Key("snapshot.size_bytes")
+	AttrSnapshotCompressionRatio = attribute.Key("snapshot.compression_ratio")
 
 	// Error attributes
 	AttrErrorType = attribute.Key("error.type")
@@ -3,7 +3,6 @@ package runner
 import (
 	"context"
 	"fmt"
-	"sync"
 	"time"
 )
 
@@ -115,6 +114,9 @@ func (r *Runner) Run(ctx context.Context) error {
 }
 
 // stopServices stops all services in reverse order with timeout.
+// Services are stopped one at a time so that a service never goes away while
+// a service started after it (and possibly depending on it) is still running,
+// e.g. subscribers are stopped before the NATS server they are connected to.
 func (r *Runner) stopServices(services []Service) error {
 	if len(services) == 0 {
 		return nil
@@ -123,16 +125,11 @@ func (r *Runner) stopServices(services []Service) error {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), r.shutdownTimeout)
 	defer cancel()
 
-	// Stop in reverse order
-	var wg sync.WaitGroup
-	errCh := make(chan error, len(services))
-
-	for i := len(services) - 1; i >= 0; i-- {
-		service := services[i]
-
-		wg.Add(1)
-		go func(svc Service) {
-			defer wg.Done()
+	done := make(chan []error, 1)
+	go func() {
+		var errs []error
+		for i := len(services) - 1; i >= 0; i-- {
+			svc := services[i]
 
 			r.logger.Info("stopping service", "service", svc.Name())
 
@@ -140,29 +137,18 @@ func (r *Runner) stopServices(services []Service) error {
 				r.logger.Error("error stopping service",
 					"service", svc.Name(),
 					"error", err)
-				errCh <- fmt.Errorf("stop %s: %w", svc.Name(), err)
-				return
+				errs = append(errs, fmt.Errorf("stop %s: %w", svc.Name(), err))
+				continue
 			}
 
 			r.logger.Info("service stopped", "service", svc.Name())
-		}(service)
-	}
-
-	// Wait for all services to stop or timeout
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
+		}
+		done <- errs
 	}()
 
+	// Wait for all services to stop or timeout
 	select {
-	case <-done:
-		close(errCh)
-		// Collect any errors
-		var errs []error
-		for err := range errCh {
-			errs = append(errs, err)
-		}
+	case errs := <-done:
 		if len(errs) > 0 {
 			return fmt.Errorf("shutdown errors: %v", errs)
 		}
@@ -5,10 +5,13 @@ package embeddednats
 import (
 	"context"
 	"fmt"
+	"time"
 
+	natsgo "github.com/nats-io/nats.go"
 	"github.com/plaenen/eventstore/pkg/infrastructure/nats"
 	"github.com/plaenen/eventstore/pkg/observability"
 	"github.com/plaenen/eventstore/pkg/runner"
+	"github.com/plaenen/eventstore/pkg/security/credentials"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"go.opentelemetry.io/otel/trace/noop"
@@ -26,6 +29,8 @@ type Service struct {
 	logger      runner.Logger
 	tracer      trace.Tracer
 	natsOptions []nats.Option
+	credentials credentials.Provider
+	stopTimeout time.Duration
 }
 
 // Option configures the NATS service.
@@ -63,11 +68,29 @@ func WithNATSOptions(opts ...nats.Option) Option {
 	}
 }
 
+// WithCredentials sets the credentials the service's own connections (health
+// checks, ConnectOptions) authenticate with, for servers that require
+// authentication, e.g. NKey users configured through WithNATSOptions.
+func WithCredentials(provider credentials.Provider) Option {
+	return func(s *Service) {
+		s.credentials = provider
+	}
+}
+
+// WithStopTimeout bounds Stop when its context has no earlier deadline
+// (default: 30 seconds). Clients still connected then are cut off.
+func WithStopTimeout(timeout time.Duration) Option {
+	return func(s *Service) {
+		s.stopTimeout = timeout
+	}
+}
+
 // New creates a new embedded NATS service for use with runner.
 func New(opts ...Option) *Service {
 	s := &Service{
-		logger: runner.NewNoopLogger(),
-		tracer: noop.NewTracerProvider().Tracer("embeddednats"),
+		logger:      runner.NewNoopLogger(),
+		tracer:      noop.NewTracerProvider().Tracer("embeddednats"),
+		stopTimeout: 30 * time.Second,
 	}
 
 	for _, opt := range opts {
@@ -110,14 +133,24 @@ func (s *Service) Start(ctx context.Context) error {
 }
 
 // Stop gracefully shuts down the embedded NATS server.
+// It waits for clients to disconnect and JetStream to flush before shutting the
+// server down, bounded by ctx (the runner's shutdown timeout) and
+// WithStopTimeout, so it returns even if a client never disconnects.
 func (s *Service) Stop(ctx context.Context) error {
 	ctx, span := s.tracer.Start(ctx, "embeddednats.Stop")
 	defer span.End()
 
+	ctx, cancel := context.WithTimeout(ctx, s.stopTimeout)
+	defer cancel()
+
 	s.logger.Info("stopping embedded NATS server")
 
 	if s.server != nil {
-		s.server.Shutdown()
+		if err := s.server.ShutdownGracefully(ctx); err != nil {
+			observability.SetSpanError(ctx, err)
+			s.logger.Error("embedded NATS server did not shut down cleanly", "error", err)
+			return fmt.Errorf("failed to shut down embedded NATS: %w", err)
+		}
 		s.logger.Info("embedded NATS server stopped")
 	}
 
@@ -136,7 +169,12 @@ func (s *Service) HealthCheck(ctx context.Context) error {
 	}
 
 	// Try to connect to verify server is responsive
-	nc, err := nats.ConnectToEmbedded(s.server)
+	opts, err := s.ConnectOptions(ctx)
+	if err != nil {
+		observability.SetSpanError(ctx, err)
+		return err
+	}
+	nc, err := nats.ConnectToEmbeddedWithOptions(s.server, opts...)
 	if err != nil {
 		observability.SetSpanError(ctx, err)
 		return fmt.Errorf("nats server not responsive: %w", err)
@@ -156,6 +194,21 @@ func (s *Service) URL() string {
 	return s.server.URL()
 }
 
+// ConnectOptions returns the authentication options for connections to the
+// server, from the credentials of WithCredentials (none without). Combine
+// them with Server().ConnectOptions() for an in-process server. Credentials
+// are fetched again on every reconnect, so rotated ones apply.
+func (s *Service) ConnectOptions(ctx context.Context) ([]natsgo.Option, error) {
+	if s.credentials == nil {
+		return nil, nil
+	}
+	opts, err := credentials.NATSOptions(ctx, s.credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply credentials: %w", err)
+	}
+	return opts, nil
+}
+
 // Server returns the underlying embedded server.
 // Only available after Start() succeeds.
 func (s *Service) Server() *nats.EmbeddedServer {
@@ -7,8 +7,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+	natsserver "github.com/plaenen/eventstore/pkg/infrastructure/nats"
 	"github.com/plaenen/eventstore/pkg/runner"
+	"github.com/plaenen/eventstore/pkg/security/credentials"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -83,6 +87,30 @@ func TestService_Lifecycle(t *testing.T) {
 		}
 	})
 
+	t.Run("stop is bounded by the stop timeout", func(t *testing.T) {
+		service := New(WithStopTimeout(100 * time.Millisecond))
+		ctx := context.Background()
+
+		if err := service.Start(ctx); err != nil {
+			t.Fatalf("failed to start service: %v", err)
+		}
+
+		// A client that never disconnects
+		nc, err := nats.Connect(service.URL(), nats.NoReconnect())
+		if err != nil {
+			t.Fatalf("failed to connect to service: %v", err)
+		}
+		defer nc.Close()
+
+		started := time.Now()
+		if err := service.Stop(ctx); err == nil {
+			t.Error("expected stop to report the clients it cut off")
+		}
+		if elapsed := time.Since(started); elapsed > 5*time.Second {
+			t.Errorf("expected stop to return after the timeout, took %s", elapsed)
+		}
+	})
+
 	t.Run("url returns empty before start", func(t *testing.T) {
 		service := New()
 		if service.URL() != "" {
@@ -142,6 +170,53 @@ func TestService_HealthCheck(t *testing.T) {
 	})
 }
 
+func TestService_WithCredentials(t *testing.T) {
+	user, err := nkeys.CreateUser()
+	if err != nil {
+		t.Fatalf("failed to create user key: %v", err)
+	}
+	publicKey, _ := user.PublicKey()
+	seed, _ := user.Seed()
+	provider, err := credentials.NewNKeyProvider(string(seed))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+
+	requireNKey := func(opts *server.Options) {
+		opts.Nkeys = []*server.NkeyUser{{Nkey: publicKey}}
+	}
+	ctx := context.Background()
+
+	// Without credentials the health check cannot connect
+	service := New(WithNATSOptions(natsserver.WithJetStream(false), requireNKey))
+	if err := service.Start(ctx); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	defer service.Stop(ctx)
+	if err := service.HealthCheck(ctx); err == nil {
+		t.Error("expected health check to fail without credentials")
+	}
+
+	service = New(WithNATSOptions(natsserver.WithJetStream(false), requireNKey), WithCredentials(provider))
+	if err := service.Start(ctx); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	defer service.Stop(ctx)
+	if err := service.HealthCheck(ctx); err != nil {
+		t.Errorf("expected healthy service with credentials, got error: %v", err)
+	}
+
+	opts, err := service.ConnectOptions(ctx)
+	if err != nil {
+		t.Fatalf("failed to get connect options: %v", err)
+	}
+	nc, err := nats.Connect(service.URL(), opts...)
+	if err != nil {
+		t.Fatalf("failed to connect with connect options: %v", err)
+	}
+	nc.Close()
+}
+
 func TestService_Connection(t *testing.T) {
 	t.Run("can connect to started service", func(t *testing.T) {
 		service := New()
@@ -6,7 +6,6 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"time"
 
 	natseventbus "github.com/plaenen/eventstore/pkg/messaging/nats"
 	"github.com/plaenen/eventstore/pkg/infrastructure/nats"
@@ -41,24 +40,34 @@ import (
 //	})
 //	runner.Run(ctx)
 type Service struct {
-	config natseventbus.Config
-	server *nats.EmbeddedServer
-	bus    *natseventbus.EventBus
-	logger *slog.Logger
-	tracer trace.Tracer
+	config      natseventbus.Config
+	natsOptions []nats.Option
+	server      *nats.EmbeddedServer
+	bus         *natseventbus.EventBus
+	logger      *slog.Logger
+	tracer      trace.Tracer
 }
 
 // Option configures the EventBus service.
 type Option func(*Service)
 
 // WithConfig sets the NATS configuration.
-// The URL in the config is ignored and replaced with the embedded server URL.
+// The URL in the config is ignored and replaced with the embedded server URL,
+// and the embedded server's connect options are added to ConnectOptions.
 func WithConfig(config natseventbus.Config) Option {
 	return func(s *Service) {
 		s.config = config
 	}
 }
 
+// WithNATSOptions sets the embedded NATS server options, e.g.
+// nats.WithInProcess() to run tests without binding a port.
+func WithNATSOptions(opts ...nats.Option) Option {
+	return func(s *Service) {
+		s.natsOptions = opts
+	}
+}
+
 // WithLogger sets the logger for the service.
 func WithLogger(logger *slog.Logger) Option {
 	return func(s *Service) {
@@ -102,7 +111,7 @@ func (s *Service) Start(ctx context.Context) error {
 
 	// Start embedded NATS server
 	s.logger.Debug("starting embedded NATS server")
-	srv, err := nats.StartEmbeddedServer()
+	srv, err := nats.StartEmbeddedServer(s.natsOptions...)
 	if err != nil {
 		observability.SetSpanError(ctx, err)
 		s.logger.Error("failed to start embedded NATS", "error", err)
@@ -112,11 +121,12 @@ func (s *Service) Start(ctx context.Context) error {
 
 	// Update config to use embedded server URL
 	s.config.URL = srv.URL()
+	s.config.ConnectOptions = append(srv.ConnectOptions(), s.config.ConnectOptions...)
 
 	// Create EventBus connected to embedded server
 	s.logger.Debug("creating event bus",
 		"stream", s.config.StreamName,
-		"subjects", s.config.StreamSubjects)
+		"subject_prefix", s.config.SubjectPrefix)
 
 	bus, err := natseventbus.NewEventBus(s.config)
 	if err != nil {
@@ -142,7 +152,8 @@ func (s *Service) Start(ctx context.Context) error {
 }
 
 // Stop gracefully shuts down the EventBus and embedded NATS server.
-// Uses the proper shutdown order: EventBus first, then server.
+// Uses the proper shutdown order: subscribers are drained first, then JetStream
+// is flushed, then the server is shut down. The whole sequence is bounded by ctx.
 func (s *Service) Stop(ctx context.Context) error {
 	ctx, span := s.tracer.Start(ctx, "eventbus.Stop")
 	defer span.End()
@@ -150,22 +161,22 @@ func (s *Service) Stop(ctx context.Context) error {
 	s.logger.Info("stopping eventbus service")
 
 	if s.bus != nil {
-		// Close EventBus first (closes all connections)
-		s.logger.Debug("closing event bus")
-		if err := s.bus.Close(); err != nil {
+		// Close EventBus first (drains subscriptions and closes the connection)
+		s.logger.Debug("draining event bus")
+		if err := s.bus.CloseContext(ctx); err != nil {
 			s.logger.Warn("error closing event bus", "error", err)
 			// Continue with shutdown even if close fails
 		}
-
-		// Give connections time to drain
-		s.logger.Debug("draining connections")
-		time.Sleep(100 * time.Millisecond)
 	}
 
 	if s.server != nil {
-		// Then shutdown server
+		// Then flush JetStream and shutdown server
 		s.logger.Debug("shutting down NATS server")
-		s.server.Shutdown()
+		if err := s.server.ShutdownGracefully(ctx); err != nil {
+			observability.SetSpanError(ctx, err)
+			s.logger.Error("NATS server did not shut down cleanly", "error", err)
+			return fmt.Errorf("failed to shut down NATS server: %w", err)
+		}
 	}
 
 	s.logger.Info("eventbus service stopped")
@@ -0,0 +1,123 @@
+package saga
+
+import (
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// startStep is the name under which the StartWith step is recorded.
+const startStep = "start"
+
+// step is one step of a saga: the command it dispatches, how to undo it, and
+// whether it ends the saga.
+type step struct {
+	name       string
+	action     Action
+	compensate Action
+	completes  bool
+}
+
+// Builder provides a fluent API for defining sagas.
+type Builder struct {
+	name      string
+	transport eventsourcing.Transport
+	sagas     store.SagaStore
+	start     *step
+	steps     map[string]*step
+	correlate func(*domain.EventEnvelope) string
+	timeout   time.Duration
+}
+
+// NewBuilder creates a builder for the saga called name, which dispatches its
+// commands through transport and keeps its instances in sagas.
+//
+// Example:
+//
+//	transfer := saga.NewBuilder("money-transfer", transport, sagaStore).
+//	    StartWith(withdrawFromSource).Compensate(refundSource).
+//	    On(accountv1.MoneyWithdrawnEventType).Then(depositToTarget).
+//	    On(accountv1.MoneyDepositedEventType).Complete().
+//	    WithTimeout(30 * time.Second).
+//	    Build()
+func NewBuilder(name string, transport eventsourcing.Transport, sagas store.SagaStore) *Builder {
+	return &Builder{
+		name:      name,
+		transport: transport,
+		sagas:     sagas,
+		steps:     make(map[string]*step),
+		correlate: (*domain.EventEnvelope).CorrelationID,
+	}
+}
+
+// StartWith sets the step Saga.Begin takes for a new instance, with a nil
+// envelope. Without it, Begin only records the instance.
+func (b *Builder) StartWith(action Action) *StepBuilder {
+	b.start = &step{name: startStep, action: action}
+	return &StepBuilder{Builder: b, step: b.start}
+}
+
+// On adds the step taken when an instance receives an event of eventType.
+// Each step is taken at most once per instance.
+func (b *Builder) On(eventType string) *StepBuilder {
+	eventType = domain.CanonicalEventType(eventType)
+	s := &step{name: eventType}
+	b.steps[eventType] = s
+	return &StepBuilder{Builder: b, step: s}
+}
+
+// CorrelateBy sets how events are matched to instances: fn returns the ID of
+// the instance an event belongs to, or "" for none. By default events belong
+// to the instance named by their correlation ID, which commands dispatched by
+// the saga carry (see domain.WithCorrelationID).
+func (b *Builder) CorrelateBy(fn func(*domain.EventEnvelope) string) *Builder {
+	b.correlate = fn
+	return b
+}
+
+// WithTimeout compensates instances still running d after they began.
+func (b *Builder) WithTimeout(d time.Duration) *Builder {
+	b.timeout = d
+	return b
+}
+
+// Build creates the saga.
+func (b *Builder) Build() *Saga {
+	return &Saga{
+		name:      b.name,
+		transport: b.transport,
+		sagas:     b.sagas,
+		start:     b.start,
+		steps:     b.steps,
+		correlate: b.correlate,
+		timeout:   b.timeout,
+	}
+}
+
+// StepBuilder configures the step added last. It embeds the Builder, so the
+// next step can be chained directly.
+type StepBuilder struct {
+	*Builder
+	step *step
+}
+
+// Then sets the action of the step.
+func (s *StepBuilder) Then(action Action) *StepBuilder {
+	s.step.action = action
+	return s
+}
+
+// Compensate sets the action undoing the step, taken when a later command is
+// rejected or the saga times out. Compensations run in reverse step order.
+func (s *StepBuilder) Compensate(action Action) *StepBuilder {
+	s.step.compensate = action
+	return s
+}
+
+// Complete makes the step end the saga once its action succeeded.
+func (s *StepBuilder) Complete() *StepBuilder {
+	s.step.completes = true
+	return s
+}
@@ -0,0 +1,318 @@
+// Package saga coordinates processes that span several aggregates, such as a
+// money transfer between two accounts. A saga reacts to events from the
+// EventBus by dispatching follow-up commands through a Transport, keeps the
+// state of each run in a store.SagaStore, and undoes the steps it took with
+// compensating commands when a command is rejected or the run times out.
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/messaging"
+	"github.com/plaenen/eventstore/pkg/store"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrCommandRejected is returned when a command dispatched by a saga
+	// fails; the instance has been compensated.
+	ErrCommandRejected = errors.New("saga command rejected")
+
+	// ErrAlreadyStarted is returned by Begin for an instance ID in use.
+	ErrAlreadyStarted = errors.New("saga instance already started")
+)
+
+// Command is a command dispatched by a saga step.
+type Command struct {
+	// Subject is the subject of the command handler, e.g.
+	// "account.v1.AccountCommandService.Deposit"
+	Subject string
+
+	// Message is the command
+	Message proto.Message
+}
+
+// Action returns the command a step dispatches, or nil to dispatch none. It
+// may update instance.Data, which is saved with the step. envelope is the
+// event the step reacts to, nil for the start step and compensations.
+//
+// An error leaves the instance unchanged; the event is retried.
+type Action func(ctx context.Context, instance *store.SagaInstance, envelope *domain.EventEnvelope) (*Command, error)
+
+// Saga is a running process manager built by a Builder. Its instances are
+// handled one at a time.
+type Saga struct {
+	name      string
+	transport eventsourcing.Transport
+	sagas     store.SagaStore
+	start     *step
+	steps     map[string]*step
+	correlate func(*domain.EventEnvelope) string
+	timeout   time.Duration
+
+	mu sync.Mutex // Serializes instance updates
+
+	// Lifecycle
+	cancel        context.CancelFunc
+	subscription  messaging.Subscription
+	watcherDone   chan struct{}
+	lifecycleLock sync.Mutex
+}
+
+// Name returns the saga name.
+func (s *Saga) Name() string {
+	return s.name
+}
+
+// Start subscribes the saga to bus, durably under its name, and starts
+// compensating timed out instances if it has a timeout. opts are added to
+// the subscription.
+func (s *Saga) Start(ctx context.Context, bus messaging.EventBus, opts ...messaging.SubscribeOption) error {
+	s.lifecycleLock.Lock()
+	defer s.lifecycleLock.Unlock()
+	if s.cancel != nil {
+		return fmt.Errorf("saga %s already running", s.name)
+	}
+
+	sagaCtx, cancel := context.WithCancel(ctx)
+	subscription, err := bus.Subscribe(messaging.EventFilter{}, func(envelope *domain.EventEnvelope) error {
+		return s.Handle(sagaCtx, envelope)
+	}, append([]messaging.SubscribeOption{messaging.WithDurable(s.name)}, opts...)...)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to subscribe saga %s: %w", s.name, err)
+	}
+
+	s.cancel = cancel
+	s.subscription = subscription
+	if s.timeout > 0 {
+		s.watcherDone = make(chan struct{})
+		go s.watchTimeouts(sagaCtx)
+	}
+	return nil
+}
+
+// Stop unsubscribes the saga and stops compensating timed out instances.
+func (s *Saga) Stop() error {
+	s.lifecycleLock.Lock()
+	defer s.lifecycleLock.Unlock()
+	if s.cancel == nil {
+		return nil
+	}
+
+	s.cancel()
+	err := s.subscription.Unsubscribe()
+	if s.watcherDone != nil {
+		<-s.watcherDone
+	}
+	s.cancel, s.subscription, s.watcherDone = nil, nil, nil
+	return err
+}
+
+// Begin starts the instance id with data and takes the start step (see
+// Builder.StartWith). It returns ErrAlreadyStarted if id is in use, and
+// ErrCommandRejected if the start command was rejected.
+func (s *Saga) Begin(ctx context.Context, id string, data map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.sagas.Load(ctx, s.name, id); err == nil {
+		return fmt.Errorf("%w: %s/%s", ErrAlreadyStarted, s.name, id)
+	} else if !errors.Is(err, store.ErrSagaNotFound) {
+		return err
+	}
+
+	now := domain.Now()
+	instance := &store.SagaInstance{
+		SagaName:  s.name,
+		ID:        id,
+		Status:    store.SagaStatusRunning,
+		Data:      make(map[string]string, len(data)),
+		UpdatedAt: now,
+	}
+	maps.Copy(instance.Data, data)
+	if s.timeout > 0 {
+		instance.Deadline = now.Add(s.timeout)
+	}
+
+	if s.start == nil {
+		return s.save(ctx, instance)
+	}
+	return s.take(ctx, instance, s.start, nil)
+}
+
+// Handle takes the step for envelope's event type in the running instance
+// the event belongs to. Events without a step or a running instance, and
+// events for steps already taken, are skipped.
+func (s *Saga) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
+	step, exists := s.steps[domain.CanonicalEventType(envelope.EventType)]
+	if !exists {
+		return nil
+	}
+	id := s.correlate(envelope)
+	if id == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	instance, err := s.sagas.Load(ctx, s.name, id)
+	if errors.Is(err, store.ErrSagaNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if instance.Status != store.SagaStatusRunning || slices.Contains(instance.Steps, step.name) {
+		return nil
+	}
+
+	// A rejected command ends the instance; it is not worth a redelivery
+	if err := s.take(ctx, instance, step, envelope); err != nil && !errors.Is(err, ErrCommandRejected) {
+		return err
+	}
+	return nil
+}
+
+// Instance loads the instance id.
+func (s *Saga) Instance(ctx context.Context, id string) (*store.SagaInstance, error) {
+	return s.sagas.Load(ctx, s.name, id)
+}
+
+// take runs the action of step and dispatches its command. On success the
+// step is recorded; on rejection the instance is compensated. The caller
+// holds mu.
+func (s *Saga) take(ctx context.Context, instance *store.SagaInstance, step *step, envelope *domain.EventEnvelope) error {
+	if step.action != nil {
+		command, err := step.action(ctx, instance, envelope)
+		if err != nil {
+			return fmt.Errorf("saga %s step %s failed: %w", s.name, step.name, err)
+		}
+		if command != nil {
+			if err := s.dispatch(ctx, instance, step.name, command); err != nil {
+				if !errors.Is(err, ErrCommandRejected) {
+					return err
+				}
+				if compErr := s.compensate(ctx, instance, err.Error()); compErr != nil {
+					return compErr
+				}
+				return err
+			}
+		}
+	}
+
+	instance.Steps = append(instance.Steps, step.name)
+	if step.completes {
+		instance.Status = store.SagaStatusCompleted
+	}
+	instance.UpdatedAt = domain.Now()
+	return s.save(ctx, instance)
+}
+
+// dispatch sends command under the instance's correlation ID, with an
+// idempotency key unique to the instance and step so that retries are
+// recorded once by handlers that honor it.
+func (s *Saga) dispatch(ctx context.Context, instance *store.SagaInstance, stepName string, command *Command) error {
+	ctx = domain.WithCorrelationID(ctx, instance.ID)
+	ctx = domain.WithIdempotencyKey(ctx, s.name+"/"+instance.ID+"/"+stepName)
+
+	resp, err := s.transport.Request(ctx, command.Subject, command.Message)
+	if err != nil {
+		return fmt.Errorf("failed to dispatch %s: %w", command.Subject, err)
+	}
+	if !resp.Success {
+		appErr := resp.GetError()
+		return fmt.Errorf("%w: %s: %s: %s", ErrCommandRejected, command.Subject, appErr.GetCode(), appErr.GetMessage())
+	}
+	return nil
+}
+
+// compensate dispatches the compensations of the steps taken, last step
+// first, and saves the instance as compensated, or as failed at the first
+// compensation that fails. The caller holds mu.
+func (s *Saga) compensate(ctx context.Context, instance *store.SagaInstance, reason string) error {
+	instance.Status = store.SagaStatusCompensated
+	instance.Error = reason
+
+	for i := len(instance.Steps) - 1; i >= 0; i-- {
+		step := s.step(instance.Steps[i])
+		if step == nil || step.compensate == nil {
+			continue
+		}
+		command, err := step.compensate(ctx, instance, nil)
+		if err == nil && command != nil {
+			err = s.dispatch(ctx, instance, "compensate/"+step.name, command)
+		}
+		if err != nil {
+			instance.Status = store.SagaStatusFailed
+			instance.Error = fmt.Sprintf("%s; compensating step %s failed: %v", reason, step.name, err)
+			break
+		}
+	}
+
+	instance.UpdatedAt = domain.Now()
+	return s.save(ctx, instance)
+}
+
+// step returns the step called name, or nil if the saga no longer has it.
+func (s *Saga) step(name string) *step {
+	if name == startStep {
+		return s.start
+	}
+	return s.steps[name]
+}
+
+// save saves instance.
+func (s *Saga) save(ctx context.Context, instance *store.SagaInstance) error {
+	if err := s.sagas.Save(ctx, instance); err != nil {
+		return fmt.Errorf("failed to save saga %s instance %s: %w", s.name, instance.ID, err)
+	}
+	return nil
+}
+
+// watchTimeouts compensates the instances past their deadline, checking ten
+// times per timeout (at most every second) until ctx is done.
+func (s *Saga) watchTimeouts(ctx context.Context) {
+	defer close(s.watcherDone)
+
+	interval := min(max(s.timeout/10, 10*time.Millisecond), time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// A failed check is retried on the next tick
+			_ = s.compensateExpired(ctx)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// compensateExpired compensates the running instances past their deadline.
+func (s *Saga) compensateExpired(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired, err := s.sagas.ListExpired(ctx, s.name, domain.Now())
+	if err != nil {
+		return err
+	}
+	for _, instance := range expired {
+		if err := s.compensate(ctx, instance, fmt.Sprintf("timed out after %s", s.timeout)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
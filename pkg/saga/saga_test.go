@@ -0,0 +1,290 @@
+package saga_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/messaging"
+	"github.com/plaenen/eventstore/pkg/saga"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/proto"
+)
+
+// accounts is a transport serving Withdraw and Deposit from in-memory
+// balances. The events of accepted commands queue up until delivered.
+type accounts struct {
+	mu       sync.Mutex
+	balances map[string]int
+	closed   map[string]bool
+	pending  []*domain.EventEnvelope
+	nextID   int
+}
+
+func newAccounts(balances map[string]int) *accounts {
+	return &accounts{balances: balances, closed: make(map[string]bool)}
+}
+
+func (a *accounts) Request(ctx context.Context, subject string, request proto.Message) (*eventsourcing.Response, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var accountID, amount, eventType string
+	switch cmd := request.(type) {
+	case *accountv1.WithdrawCommand:
+		accountID, amount, eventType = cmd.AccountId, cmd.Amount, accountv1.MoneyWithdrawnEventType
+	case *accountv1.DepositCommand:
+		accountID, amount, eventType = cmd.AccountId, cmd.Amount, accountv1.MoneyDepositedEventType
+	default:
+		return nil, fmt.Errorf("unexpected command %T", request)
+	}
+	n, _ := strconv.Atoi(amount)
+	if a.closed[accountID] {
+		return eventsourcing.NewErrorResponse("ACCOUNT_CLOSED", "account "+accountID+" is closed", "", nil), nil
+	}
+	if eventType == accountv1.MoneyWithdrawnEventType {
+		if a.balances[accountID] < n {
+			return eventsourcing.NewErrorResponse("INSUFFICIENT_FUNDS", "insufficient funds", "", nil), nil
+		}
+		n = -n
+	}
+	a.balances[accountID] += n
+
+	correlationID, _ := domain.CorrelationIDFromContext(ctx)
+	a.nextID++
+	a.pending = append(a.pending, &domain.EventEnvelope{Event: domain.Event{
+		ID:          fmt.Sprintf("evt-%d", a.nextID),
+		AggregateID: accountID,
+		EventType:   eventType,
+		Metadata:    domain.EventMetadata{CorrelationID: correlationID},
+	}})
+	return eventsourcing.NewSuccessResponse(&accountv1.DepositResponse{})
+}
+
+func (a *accounts) Close() error { return nil }
+
+// deliver hands the pending events to handler until none are left.
+func (a *accounts) deliver(t *testing.T, handler func(*domain.EventEnvelope) error) {
+	t.Helper()
+	for {
+		a.mu.Lock()
+		if len(a.pending) == 0 {
+			a.mu.Unlock()
+			return
+		}
+		envelope := a.pending[0]
+		a.pending = a.pending[1:]
+		a.mu.Unlock()
+
+		if err := handler(envelope); err != nil {
+			t.Fatalf("failed to handle %s: %v", envelope.EventType, err)
+		}
+	}
+}
+
+func (a *accounts) balance(accountID string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.balances[accountID]
+}
+
+// newTransfer builds a saga moving data["amount"] from data["from"] to
+// data["to"], refunding the source if the deposit is rejected.
+func newTransfer(t *testing.T, transport eventsourcing.Transport, timeout time.Duration) *saga.Saga {
+	t.Helper()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	t.Cleanup(func() { eventStore.Close() })
+	sagas, err := sqlite.NewSagaStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create saga store: %v", err)
+	}
+
+	deposit := func(key string) saga.Action {
+		return func(ctx context.Context, instance *store.SagaInstance, envelope *domain.EventEnvelope) (*saga.Command, error) {
+			return &saga.Command{
+				Subject: "account.v1.AccountCommandService.Deposit",
+				Message: &accountv1.DepositCommand{AccountId: instance.Data[key], Amount: instance.Data["amount"]},
+			}, nil
+		}
+	}
+	return saga.NewBuilder("money-transfer", transport, sagas).
+		StartWith(func(ctx context.Context, instance *store.SagaInstance, envelope *domain.EventEnvelope) (*saga.Command, error) {
+			return &saga.Command{
+				Subject: "account.v1.AccountCommandService.Withdraw",
+				Message: &accountv1.WithdrawCommand{AccountId: instance.Data["from"], Amount: instance.Data["amount"]},
+			}, nil
+		}).Compensate(deposit("from")).
+		On(accountv1.MoneyWithdrawnEventType).Then(deposit("to")).
+		On(accountv1.MoneyDepositedEventType).Complete().
+		WithTimeout(timeout).
+		Build()
+}
+
+func transferData(amount int) map[string]string {
+	return map[string]string{"from": "acc-a", "to": "acc-b", "amount": strconv.Itoa(amount)}
+}
+
+func TestSagaCompletes(t *testing.T) {
+	ctx := context.Background()
+	accounts := newAccounts(map[string]int{"acc-a": 100})
+	transfer := newTransfer(t, accounts, 0)
+	handle := func(envelope *domain.EventEnvelope) error { return transfer.Handle(ctx, envelope) }
+
+	if err := transfer.Begin(ctx, "t-1", transferData(30)); err != nil {
+		t.Fatalf("failed to begin transfer: %v", err)
+	}
+	accounts.deliver(t, handle)
+
+	instance, err := transfer.Instance(ctx, "t-1")
+	if err != nil {
+		t.Fatalf("failed to load instance: %v", err)
+	}
+	if instance.Status != store.SagaStatusCompleted {
+		t.Errorf("expected the transfer to complete, got %s (%s)", instance.Status, instance.Error)
+	}
+	want := []string{"start", accountv1.MoneyWithdrawnEventType, accountv1.MoneyDepositedEventType}
+	if !slices.Equal(instance.Steps, want) {
+		t.Errorf("expected steps %v, got %v", want, instance.Steps)
+	}
+	if accounts.balance("acc-a") != 70 || accounts.balance("acc-b") != 30 {
+		t.Errorf("expected balances 70/30, got %d/%d", accounts.balance("acc-a"), accounts.balance("acc-b"))
+	}
+
+	// A redelivered event does not take its step again
+	redelivered := &domain.EventEnvelope{Event: domain.Event{
+		ID:        "evt-1",
+		EventType: accountv1.MoneyWithdrawnEventType,
+		Metadata:  domain.EventMetadata{CorrelationID: "t-1"},
+	}}
+	if err := transfer.Handle(ctx, redelivered); err != nil {
+		t.Fatalf("failed to handle redelivered event: %v", err)
+	}
+	if accounts.balance("acc-b") != 30 {
+		t.Errorf("expected no second deposit, got balance %d", accounts.balance("acc-b"))
+	}
+
+	if err := transfer.Begin(ctx, "t-1", transferData(30)); !errors.Is(err, saga.ErrAlreadyStarted) {
+		t.Errorf("expected ErrAlreadyStarted, got %v", err)
+	}
+}
+
+func TestSagaCompensatesRejectedCommand(t *testing.T) {
+	ctx := context.Background()
+	accounts := newAccounts(map[string]int{"acc-a": 100})
+	accounts.closed["acc-b"] = true
+	transfer := newTransfer(t, accounts, 0)
+	handle := func(envelope *domain.EventEnvelope) error { return transfer.Handle(ctx, envelope) }
+
+	// The deposit is rejected, so the withdrawal is refunded
+	if err := transfer.Begin(ctx, "t-1", transferData(30)); err != nil {
+		t.Fatalf("failed to begin transfer: %v", err)
+	}
+	accounts.deliver(t, handle)
+
+	instance, _ := transfer.Instance(ctx, "t-1")
+	if instance.Status != store.SagaStatusCompensated || !strings.Contains(instance.Error, "ACCOUNT_CLOSED") {
+		t.Errorf("expected the transfer to be compensated after ACCOUNT_CLOSED, got %s (%s)", instance.Status, instance.Error)
+	}
+	if accounts.balance("acc-a") != 100 || accounts.balance("acc-b") != 0 {
+		t.Errorf("expected balances 100/0, got %d/%d", accounts.balance("acc-a"), accounts.balance("acc-b"))
+	}
+
+	// A rejected start command has nothing to compensate
+	err := transfer.Begin(ctx, "t-2", transferData(500))
+	if !errors.Is(err, saga.ErrCommandRejected) {
+		t.Fatalf("expected ErrCommandRejected, got %v", err)
+	}
+	if instance, _ := transfer.Instance(ctx, "t-2"); instance.Status != store.SagaStatusCompensated || len(instance.Steps) != 0 {
+		t.Errorf("expected t-2 to be compensated without steps, got %s %v", instance.Status, instance.Steps)
+	}
+
+	// A rejected compensation leaves the instance failed
+	if err := transfer.Begin(ctx, "t-3", transferData(30)); err != nil {
+		t.Fatalf("failed to begin transfer: %v", err)
+	}
+	accounts.closed["acc-a"] = true
+	accounts.deliver(t, handle)
+	if instance, _ := transfer.Instance(ctx, "t-3"); instance.Status != store.SagaStatusFailed || !strings.Contains(instance.Error, "compensating step start failed") {
+		t.Errorf("expected t-3 to fail compensating, got %s (%s)", instance.Status, instance.Error)
+	}
+}
+
+// capturingBus is an event bus that hands the test its subscription handler.
+type capturingBus struct {
+	handler messaging.EventHandler
+	durable string
+}
+
+func (b *capturingBus) Publish(events []*domain.Event) error { return nil }
+
+func (b *capturingBus) Subscribe(filter messaging.EventFilter, handler messaging.EventHandler, opts ...messaging.SubscribeOption) (messaging.Subscription, error) {
+	b.handler = handler
+	b.durable = messaging.NewSubscribeOptions(opts...).Durable
+	return capturedSubscription{}, nil
+}
+
+func (b *capturingBus) Close() error { return nil }
+
+type capturedSubscription struct{}
+
+func (capturedSubscription) Unsubscribe() error { return nil }
+
+func TestSagaTimeout(t *testing.T) {
+	ctx := context.Background()
+	accounts := newAccounts(map[string]int{"acc-a": 100})
+	transfer := newTransfer(t, accounts, 50*time.Millisecond)
+
+	bus := &capturingBus{}
+	if err := transfer.Start(ctx, bus); err != nil {
+		t.Fatalf("failed to start saga: %v", err)
+	}
+	defer transfer.Stop()
+	if bus.durable != "money-transfer" {
+		t.Errorf("expected a durable subscription named after the saga, got %q", bus.durable)
+	}
+
+	// The withdrawal is never delivered, so the deposit is never made
+	if err := transfer.Begin(ctx, "t-1", transferData(30)); err != nil {
+		t.Fatalf("failed to begin transfer: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		instance, err := transfer.Instance(ctx, "t-1")
+		if err != nil {
+			t.Fatalf("failed to load instance: %v", err)
+		}
+		if instance.Status == store.SagaStatusCompensated {
+			if !strings.Contains(instance.Error, "timed out") {
+				t.Errorf("expected a timeout, got %q", instance.Error)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the transfer to time out, got %s", instance.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if accounts.balance("acc-a") != 100 {
+		t.Errorf("expected the withdrawal to be refunded, got balance %d", accounts.balance("acc-a"))
+	}
+
+	// The late withdrawal event no longer moves money
+	accounts.deliver(t, bus.handler)
+	if accounts.balance("acc-b") != 0 {
+		t.Errorf("expected no deposit after the timeout, got balance %d", accounts.balance("acc-b"))
+	}
+}
@@ -0,0 +1,262 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nkeys"
+)
+
+// DefaultFilePollInterval is how often a FileProvider checks its file when no
+// poll interval is given.
+const DefaultFilePollInterval = 10 * time.Second
+
+// FileProvider provides credentials from a file that is rotated in place,
+// such as a Kubernetes secret mounted as a volume. The file is checked every
+// poll interval and on every GetCredentials, and re-read when it changed.
+//
+// The file holds either a NATS credentials file (.creds, a decorated user JWT
+// and NKey seed as generated by nsc) or the JSON encoding of Credentials:
+//
+//	{"type": "token", "token": "s3cr3t"}
+type FileProvider struct {
+	path string
+
+	mu       sync.RWMutex
+	creds    *Credentials
+	raw      []byte
+	info     os.FileInfo
+	watchers map[int]func(*Credentials)
+	nextID   int
+
+	// Lifecycle
+	closed    bool
+	closeOnce sync.Once
+	pollStop  chan struct{}
+	pollDone  chan struct{}
+}
+
+// NewFileProvider creates a provider reading credentials from the file at
+// path, checking it for changes every pollInterval (DefaultFilePollInterval
+// if zero).
+//
+// Example:
+//
+//	provider, err := credentials.NewFileProvider("/var/run/secrets/nats/user.creds", 0)
+//	transport, err := cqrsnats.NewTransport(&cqrsnats.TransportConfig{
+//		URL:                natsURL,
+//		CredentialProvider: provider,
+//	})
+func NewFileProvider(path string, pollInterval time.Duration) (*FileProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("credentials file path is required")
+	}
+	if pollInterval <= 0 {
+		pollInterval = DefaultFilePollInterval
+	}
+
+	provider := &FileProvider{
+		path:     path,
+		watchers: make(map[int]func(*Credentials)),
+		pollStop: make(chan struct{}),
+		pollDone: make(chan struct{}),
+	}
+	if err := provider.reload(true); err != nil {
+		return nil, fmt.Errorf("failed to load initial credentials: %w", err)
+	}
+
+	go provider.poll(pollInterval)
+	return provider, nil
+}
+
+// GetCredentials returns the credentials of the file, re-reading it first if
+// it changed since it was last read.
+func (p *FileProvider) GetCredentials(ctx context.Context) (*Credentials, error) {
+	if err := p.reload(false); err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.creds.IsExpired() {
+		return nil, ErrCredentialsExpired
+	}
+	return p.creds, nil
+}
+
+// Rotate re-reads the file. Rotation itself is up to whoever writes the file.
+func (p *FileProvider) Rotate(ctx context.Context) error {
+	return p.reload(true)
+}
+
+// Type returns the credential type of the file
+func (p *FileProvider) Type() CredentialType {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.creds.Type
+}
+
+// OnChange registers fn to be called with the new credentials whenever the
+// file changes. fn runs on its own goroutine, so it may block or reconnect.
+func (p *FileProvider) OnChange(fn func(*Credentials)) (cancel func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := p.nextID
+	p.nextID++
+	p.watchers[id] = fn
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.watchers, id)
+	}
+}
+
+// Close stops watching the file
+func (p *FileProvider) Close() error {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+
+		close(p.pollStop)
+		<-p.pollDone
+	})
+	return nil
+}
+
+// poll checks the file for changes every interval until the provider closes.
+func (p *FileProvider) poll(interval time.Duration) {
+	defer close(p.pollDone)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// A missing or half-written file keeps the last good credentials;
+			// the next poll tries again
+			_ = p.reload(false)
+
+		case <-p.pollStop:
+			return
+		}
+	}
+}
+
+// reload re-reads the file if force is set or its file info changed (a new
+// modification time, size, or file, as when Kubernetes swaps the symlink of
+// a mounted secret), and notifies the watchers if the credentials changed.
+func (p *FileProvider) reload(force bool) error {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat credentials file: %w", err)
+	}
+
+	p.mu.RLock()
+	closed := p.closed
+	unchanged := p.info != nil && os.SameFile(p.info, info) &&
+		p.info.ModTime().Equal(info.ModTime()) && p.info.Size() == info.Size()
+	p.mu.RUnlock()
+	if closed {
+		return ErrProviderClosed
+	}
+	if unchanged && !force {
+		return nil
+	}
+
+	raw, err := os.ReadFile(p.path)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	p.mu.Lock()
+	if bytes.Equal(raw, p.raw) {
+		p.info = info
+		p.mu.Unlock()
+		return nil
+	}
+	creds, err := parseCredentialsFile(raw)
+	if err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	notify := p.creds != nil
+	p.creds, p.raw, p.info = creds, raw, info
+	watchers := make([]func(*Credentials), 0, len(p.watchers))
+	for _, fn := range p.watchers {
+		watchers = append(watchers, fn)
+	}
+	p.mu.Unlock()
+
+	if notify {
+		for _, fn := range watchers {
+			go fn(creds)
+		}
+	}
+	return nil
+}
+
+// parseCredentialsFile parses a NATS .creds file or JSON-encoded Credentials.
+func parseCredentialsFile(raw []byte) (*Credentials, error) {
+	var creds *Credentials
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &creds); err != nil {
+			return nil, fmt.Errorf("%w: malformed credentials file: %v", ErrInvalidCredentials, err)
+		}
+	} else {
+		var err error
+		if creds, err = parseNATSCredsFile(raw); err != nil {
+			return nil, err
+		}
+	}
+	if err := creds.Validate(); err != nil {
+		return nil, err
+	}
+
+	if creds.Metadata == nil {
+		creds.Metadata = make(map[string]string)
+	}
+	creds.Metadata["provider"] = "file"
+	return creds, nil
+}
+
+// parseNATSCredsFile parses the user JWT and NKey seed of a NATS .creds file.
+func parseNATSCredsFile(raw []byte) (*Credentials, error) {
+	userJWT, err := nkeys.ParseDecoratedJWT(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed creds file: %v", ErrInvalidCredentials, err)
+	}
+	kp, err := nkeys.ParseDecoratedUserNKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: creds file has no user nkey seed: %v", ErrInvalidCredentials, err)
+	}
+	defer kp.Wipe()
+	seed, err := kp.Seed()
+	if err != nil {
+		return nil, fmt.Errorf("%w: creds file has no user nkey seed: %v", ErrInvalidCredentials, err)
+	}
+	publicKey, err := kp.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("%w: creds file has no user nkey seed: %v", ErrInvalidCredentials, err)
+	}
+	expiresAt, err := jwtExpiry(userJWT)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credentials{
+		Type:      CredentialTypeJWT,
+		JWTToken:  userJWT,
+		PublicKey: publicKey,
+		Seed:      string(seed), // a copy, as Wipe overwrites the seed,
+		ExpiresAt: expiresAt,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package credentials
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nats.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "token", "token": "first"}`), 0o600))
+
+	provider, err := NewFileProvider(path, 10*time.Millisecond)
+	require.NoError(t, err)
+	defer provider.Close()
+	assert.Equal(t, CredentialTypeToken, provider.Type())
+
+	changed := make(chan *Credentials, 1)
+	cancel := provider.OnChange(func(creds *Credentials) { changed <- creds })
+	defer cancel()
+
+	creds, err := provider.GetCredentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "first", creds.Token)
+	assert.Equal(t, "file", creds.Metadata["provider"])
+
+	// Rewrite the file the way Kubernetes does: write a new file, swap it in
+	next := filepath.Join(t.TempDir(), "nats.json")
+	require.NoError(t, os.WriteFile(next, []byte(`{"type": "token", "token": "second"}`), 0o600))
+	require.NoError(t, os.Rename(next, path))
+
+	select {
+	case creds := <-changed:
+		assert.Equal(t, "second", creds.Token)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a change notification")
+	}
+	creds, err = provider.GetCredentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "second", creds.Token)
+
+	// A broken rewrite is refused; the last good credentials stay cached
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "token"}`), 0o600))
+	assert.ErrorIs(t, provider.Rotate(context.Background()), ErrInvalidCredentials)
+	assert.Equal(t, "second", provider.creds.Token)
+
+	require.NoError(t, provider.Close())
+	_, err = provider.GetCredentials(context.Background())
+	assert.ErrorIs(t, err, ErrProviderClosed)
+}
+
+func TestFileProviderNATSCreds(t *testing.T) {
+	account, err := nkeys.CreateAccount()
+	require.NoError(t, err)
+	user, err := nkeys.CreateUser()
+	require.NoError(t, err)
+	userSeed, err := user.Seed()
+	require.NoError(t, err)
+	userPublicKey, err := user.PublicKey()
+	require.NoError(t, err)
+
+	claims := jwt.NewUserClaims(userPublicKey)
+	claims.Expires = time.Now().Add(time.Hour).Unix()
+	userJWT, err := claims.Encode(account)
+	require.NoError(t, err)
+	config, err := jwt.FormatUserConfig(userJWT, userSeed)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "user.creds")
+	require.NoError(t, os.WriteFile(path, config, 0o600))
+
+	provider, err := NewFileProvider(path, 0)
+	require.NoError(t, err)
+	defer provider.Close()
+
+	creds, err := provider.GetCredentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, CredentialTypeJWT, creds.Type)
+	assert.Equal(t, userJWT, creds.JWTToken)
+	assert.Equal(t, string(userSeed), creds.Seed)
+	assert.Equal(t, userPublicKey, creds.PublicKey)
+	require.NotNil(t, creds.ExpiresAt)
+	assert.Equal(t, claims.Expires, creds.ExpiresAt.Unix())
+
+	_, err = NewFileProvider(filepath.Join(t.TempDir(), "missing.creds"), 0)
+	assert.Error(t, err)
+}
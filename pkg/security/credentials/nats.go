@@ -0,0 +1,112 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nkeys"
+)
+
+// NATSOptions returns the NATS connect options authenticating with the
+// credentials of provider: a token, user/password, NKey or user JWT.
+//
+// The credentials are fetched and validated once here, and fetched again on
+// every (re)connect, so rotated credentials (e.g., a refreshed JWT) are used
+// as soon as the connection reconnects. If the provider fails on reconnect,
+// or takes longer than ReconnectCredentialsTimeout, the last credentials it
+// returned are used.
+//
+// Example:
+//
+//	provider, _ := credentials.NewJWTProvider(userJWT, userSeed)
+//	opts, err := credentials.NATSOptions(ctx, provider)
+//	nc, err := nats.Connect(url, opts...)
+func NATSOptions(ctx context.Context, provider Provider) ([]nats.Option, error) {
+	creds, err := provider.GetCredentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+	if err := creds.Validate(); err != nil {
+		return nil, err
+	}
+	current := &natsCredentials{provider: provider, last: creds}
+
+	switch creds.Type {
+	case CredentialTypeToken:
+		return []nats.Option{nats.TokenHandler(func() string {
+			return current.get().Token
+		})}, nil
+
+	case CredentialTypeUserPassword:
+		return []nats.Option{nats.UserInfoHandler(func() (string, string) {
+			creds := current.get()
+			return creds.User, creds.Password
+		})}, nil
+
+	case CredentialTypeNKey:
+		if _, err := nkeys.FromSeed([]byte(creds.Seed)); err != nil {
+			return nil, fmt.Errorf("%w: invalid nkey seed: %v", ErrInvalidCredentials, err)
+		}
+		// The server knows the public key; only the seed can change
+		return []nats.Option{nats.Nkey(creds.PublicKey, func(nonce []byte) ([]byte, error) {
+			return signNonce(current.get().Seed, nonce)
+		})}, nil
+
+	case CredentialTypeJWT:
+		if _, err := nkeys.FromSeed([]byte(creds.Seed)); err != nil {
+			return nil, fmt.Errorf("%w: jwt credentials need a valid nkey seed to sign with: %v", ErrInvalidCredentials, err)
+		}
+		return []nats.Option{nats.UserJWT(func() (string, error) {
+			return current.get().JWTToken, nil
+		}, func(nonce []byte) ([]byte, error) {
+			return signNonce(current.get().Seed, nonce)
+		})}, nil
+
+	case CredentialTypeMTLS:
+		return nil, fmt.Errorf("mTLS should be configured via TLS config, not credential provider")
+
+	default:
+		return nil, fmt.Errorf("unsupported credential type: %s", creds.Type)
+	}
+}
+
+// ReconnectCredentialsTimeout bounds how long a (re)connect waits for the
+// provider of NATSOptions before falling back to the last credentials, so a
+// hung provider cannot stall reconnects.
+var ReconnectCredentialsTimeout = 5 * time.Second
+
+// natsCredentials keeps the last credentials a provider returned.
+type natsCredentials struct {
+	provider Provider
+
+	mu   sync.Mutex
+	last *Credentials
+}
+
+// get returns the provider's current credentials, or the last ones if the
+// provider fails or does not answer within ReconnectCredentialsTimeout.
+func (c *natsCredentials) get() *Credentials {
+	ctx, cancel := context.WithTimeout(context.Background(), ReconnectCredentialsTimeout)
+	defer cancel()
+	creds, err := c.provider.GetCredentials(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil && creds.Validate() == nil {
+		c.last = creds
+	}
+	return c.last
+}
+
+// signNonce signs the connection nonce of a NATS server with an NKey seed.
+func signNonce(seed string, nonce []byte) ([]byte, error) {
+	kp, err := nkeys.FromSeed([]byte(seed))
+	if err != nil {
+		return nil, fmt.Errorf("invalid nkey seed: %w", err)
+	}
+	defer kp.Wipe()
+	return kp.Sign(nonce)
+}
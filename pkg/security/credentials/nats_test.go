@@ -0,0 +1,32 @@
+package credentials
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// hangingProvider blocks GetCredentials until the context ends.
+type hangingProvider struct {
+	StaticProvider
+}
+
+func (p *hangingProvider) GetCredentials(ctx context.Context) (*Credentials, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestNATSCredentialsTimeout(t *testing.T) {
+	previous := ReconnectCredentialsTimeout
+	ReconnectCredentialsTimeout = 20 * time.Millisecond
+	defer func() { ReconnectCredentialsTimeout = previous }()
+
+	last := &Credentials{Type: CredentialTypeToken, Token: "last"}
+	current := &natsCredentials{provider: &hangingProvider{}, last: last}
+
+	start := time.Now()
+	assert.Same(t, last, current.get(), "a hung provider falls back to the last credentials")
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,92 @@
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nkeys"
+)
+
+// NewNKeyProvider creates a provider with a static NATS NKey seed (the
+// "SU..." private seed of a user). The public key is derived from the seed.
+// Keep the seed in secure storage; prefer a SecretProvider in production.
+func NewNKeyProvider(seed string) (*StaticProvider, error) {
+	kp, err := nkeys.FromSeed([]byte(seed))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid nkey seed: %v", ErrInvalidCredentials, err)
+	}
+	publicKey, err := kp.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid nkey seed: %v", ErrInvalidCredentials, err)
+	}
+
+	return &StaticProvider{
+		creds: &Credentials{
+			Type:      CredentialTypeNKey,
+			PublicKey: publicKey,
+			Seed:      seed,
+			Metadata: map[string]string{
+				"provider": "static",
+			},
+		},
+	}, nil
+}
+
+// NewJWTProvider creates a provider with a static NATS user JWT and the NKey
+// seed that signs the server's connection nonce. The credentials expire when
+// the JWT does. Rotating a JWT takes a provider that can reload it, such as a
+// SecretProvider.
+func NewJWTProvider(jwt, seedForSigning string) (*StaticProvider, error) {
+	kp, err := nkeys.FromSeed([]byte(seedForSigning))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid nkey seed: %v", ErrInvalidCredentials, err)
+	}
+	publicKey, err := kp.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid nkey seed: %v", ErrInvalidCredentials, err)
+	}
+	expiresAt, err := jwtExpiry(jwt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StaticProvider{
+		creds: &Credentials{
+			Type:      CredentialTypeJWT,
+			JWTToken:  jwt,
+			PublicKey: publicKey,
+			Seed:      seedForSigning,
+			ExpiresAt: expiresAt,
+			Metadata: map[string]string{
+				"provider": "static",
+			},
+		},
+	}, nil
+}
+
+// jwtExpiry returns the expiry ("exp" claim) of a JWT, or nil if it never
+// expires. The signature is not verified: that is the NATS server's job.
+func jwtExpiry(jwt string) (*time.Time, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed jwt", ErrInvalidCredentials)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed jwt payload: %v", ErrInvalidCredentials, err)
+	}
+	var claims struct {
+		Expires int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed jwt claims: %v", ErrInvalidCredentials, err)
+	}
+	if claims.Expires == 0 {
+		return nil, nil
+	}
+	expiresAt := time.Unix(claims.Expires, 0)
+	return &expiresAt, nil
+}
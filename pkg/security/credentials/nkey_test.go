@@ -0,0 +1,74 @@
+package credentials
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nkeys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNKeyProvider(t *testing.T) {
+	user, err := nkeys.CreateUser()
+	require.NoError(t, err)
+	seed, err := user.Seed()
+	require.NoError(t, err)
+	publicKey, err := user.PublicKey()
+	require.NoError(t, err)
+
+	provider, err := NewNKeyProvider(string(seed))
+	require.NoError(t, err)
+	assert.Equal(t, CredentialTypeNKey, provider.Type())
+
+	creds, err := provider.GetCredentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, creds.PublicKey)
+	assert.NoError(t, creds.Validate())
+
+	_, err = NewNKeyProvider("not-a-seed")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestJWTProvider(t *testing.T) {
+	account, err := nkeys.CreateAccount()
+	require.NoError(t, err)
+	user, err := nkeys.CreateUser()
+	require.NoError(t, err)
+	userSeed, err := user.Seed()
+	require.NoError(t, err)
+	userPublicKey, err := user.PublicKey()
+	require.NoError(t, err)
+
+	claims := jwt.NewUserClaims(userPublicKey)
+	claims.Expires = time.Now().Add(time.Hour).Unix()
+	userJWT, err := claims.Encode(account)
+	require.NoError(t, err)
+
+	provider, err := NewJWTProvider(userJWT, string(userSeed))
+	require.NoError(t, err)
+	assert.Equal(t, CredentialTypeJWT, provider.Type())
+
+	creds, err := provider.GetCredentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, userJWT, creds.JWTToken)
+	assert.Equal(t, userPublicKey, creds.PublicKey)
+	require.NotNil(t, creds.ExpiresAt)
+	assert.Equal(t, claims.Expires, creds.ExpiresAt.Unix())
+
+	_, err = NewJWTProvider("not.a-jwt", string(userSeed))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = NewJWTProvider(userJWT, "not-a-seed")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// Expired JWTs are refused like other expired credentials
+	claims.Expires = time.Now().Add(-time.Minute).Unix()
+	expiredJWT, err := claims.Encode(account)
+	require.NoError(t, err)
+	provider, err = NewJWTProvider(expiredJWT, string(userSeed))
+	require.NoError(t, err)
+	_, err = provider.GetCredentials(context.Background())
+	assert.ErrorIs(t, err, ErrCredentialsExpired)
+}
@@ -169,6 +169,15 @@ type Provider interface {
 	Close() error
 }
 
+// ChangeNotifier is implemented by providers that notice when their
+// credentials change outside the process, such as a FileProvider. Long-lived
+// connections use it to re-authenticate as soon as new credentials arrive.
+type ChangeNotifier interface {
+	// OnChange registers fn to be called with the new credentials after each
+	// change. The returned function unregisters it.
+	OnChange(fn func(*Credentials)) (cancel func())
+}
+
 // SecretData represents the structure stored in the secret backend
 type SecretData struct {
 	Credentials *Credentials          `json:"credentials"`
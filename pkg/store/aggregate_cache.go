@@ -0,0 +1,110 @@
+package store
+
+import (
+	"container/list"
+	"log/slog"
+	"sync"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// repositoryConfig holds optional repository configuration.
+type repositoryConfig struct {
+	// cacheSize is the maximum number of aggregates kept in the write-through
+	// cache (0 = caching disabled)
+	cacheSize int
+
+	// snapshots, when set, lets Load start from the latest snapshot
+	snapshots       SnapshotStore
+	snapshotRepair  bool
+	snapshotMetrics SnapshotMetrics
+	logger          *slog.Logger
+
+	// maxLoadEvents limits the events Load replays (0 = unlimited);
+	// streamBatchSize > 0 streams oversized aggregates instead
+	maxLoadEvents   int
+	streamBatchSize int
+}
+
+// RepositoryOption configures a BaseRepository.
+type RepositoryOption func(*repositoryConfig)
+
+// WithAggregateCache enables an in-process, write-through aggregate cache
+// holding up to maxEntries aggregates (least recently saved are evicted first).
+//
+// After a successful save the aggregate is cached at its new version. Load
+// serves a cached aggregate only if its version still matches the store (one
+// cheap GetAggregateVersion query instead of a full replay); otherwise it falls
+// back to replaying events. A cached aggregate is handed out to a single caller:
+// Load removes it from the cache and the next successful Save puts it back, so
+// concurrent callers never share an instance.
+//
+// This pays off for hot aggregates written by a single process. Aggregates
+// written elsewhere are still correct, they just miss the cache.
+func WithAggregateCache(maxEntries int) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.cacheSize = maxEntries
+	}
+}
+
+// aggregateCache is a bounded LRU cache of aggregates keyed by ID.
+type aggregateCache[T domain.Aggregate] struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	order   *list.List // Front = most recently used
+	entries map[string]*list.Element
+}
+
+type cacheEntry[T domain.Aggregate] struct {
+	id        string
+	aggregate T
+}
+
+func newAggregateCache[T domain.Aggregate](maxEntries int) *aggregateCache[T] {
+	return &aggregateCache[T]{
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// put caches an aggregate, evicting the least recently used entry if full.
+func (c *aggregateCache[T]) put(aggregate T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := aggregate.ID()
+	if elem, ok := c.entries[id]; ok {
+		elem.Value.(*cacheEntry[T]).aggregate = aggregate
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[id] = c.order.PushFront(&cacheEntry[T]{id: id, aggregate: aggregate})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry[T]).id)
+	}
+}
+
+// take removes and returns the cached aggregate for id.
+func (c *aggregateCache[T]) take(id string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[id]
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	c.order.Remove(elem)
+	delete(c.entries, id)
+	return elem.Value.(*cacheEntry[T]).aggregate, true
+}
+
+// invalidate drops the cached aggregate for id, if any.
+func (c *aggregateCache[T]) invalidate(id string) {
+	c.take(id)
+}
@@ -1,11 +1,19 @@
 package store
 
-import "time"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrCheckpointNotFound is returned by CheckpointStore.Load for a projection
+// without a checkpoint.
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
 
 // ProjectionCheckpoint tracks the progress of a projection.
 type ProjectionCheckpoint struct {
 	ProjectionName string
-	Position       int64
+	Position       int64 // Global position of the last event handled
 	LastEventID    string
 	UpdatedAt      time.Time
 }
@@ -15,9 +23,24 @@ type CheckpointStore interface {
 	// Save saves a checkpoint.
 	Save(checkpoint *ProjectionCheckpoint) error
 
-	// Load loads a checkpoint for a projection.
+	// Load loads a checkpoint for a projection. It returns
+	// ErrCheckpointNotFound if the projection has none.
 	Load(projectionName string) (*ProjectionCheckpoint, error)
 
 	// Delete deletes a checkpoint (for rebuilding).
 	Delete(projectionName string) error
+
+	// MinPosition returns the lowest position across all checkpoints, or 0
+	// when there are none. Events at or below it have been processed by every
+	// checkpointed consumer.
+	MinPosition(ctx context.Context) (int64, error)
+}
+
+// CheckpointAdvancer is implemented by checkpoint stores that can move a
+// checkpoint forward atomically. Consumers sharing a checkpoint, such as the
+// instances of a projection in one consumer group, use it so that an instance
+// behind the others never moves the checkpoint back.
+type CheckpointAdvancer interface {
+	// Advance saves checkpoint unless the saved one is at a higher position.
+	Advance(checkpoint *ProjectionCheckpoint) error
 }
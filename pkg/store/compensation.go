@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// CompensableAggregate is an aggregate that can logically undo commands.
+type CompensableAggregate interface {
+	domain.Aggregate
+	domain.Compensator
+}
+
+// CompensateCommand logically undoes originalCommandID on one aggregate: it
+// loads the aggregate, calls its Compensate with the events the command
+// recorded on it (available through domain.CompensatedEvents(ctx)), and saves
+// the compensating events.
+//
+// Returns domain.ErrAlreadyCompensated if the command was already compensated
+// on this aggregate, and domain.ErrNothingToCompensate if the command recorded
+// no events on it. Every event recorded by Compensate must be marked with
+// domain.CompensationMetadata; otherwise nothing is saved.
+func CompensateCommand[T CompensableAggregate](
+	ctx context.Context,
+	eventStore EventStore,
+	repo Repository[T],
+	aggregateID, originalCommandID string,
+	meta domain.EventMetadata,
+) error {
+	compensations, err := eventStore.LoadCompensations(originalCommandID)
+	if err != nil {
+		return fmt.Errorf("failed to load compensations: %w", err)
+	}
+	for _, event := range compensations {
+		if event.AggregateID == aggregateID {
+			return fmt.Errorf("%w: %s on %s", domain.ErrAlreadyCompensated, originalCommandID, aggregateID)
+		}
+	}
+
+	history, err := eventStore.LoadEvents(ctx, aggregateID, 0)
+	if err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+	var original []*domain.Event
+	for _, event := range history {
+		if event.Metadata.CausationID == originalCommandID && !domain.IsCompensation(event.Metadata) {
+			original = append(original, event)
+		}
+	}
+	if len(original) == 0 {
+		return fmt.Errorf("%w: %s on %s", domain.ErrNothingToCompensate, originalCommandID, aggregateID)
+	}
+
+	aggregate, err := repo.Load(aggregateID)
+	if err != nil {
+		return err
+	}
+
+	if err := aggregate.Compensate(domain.WithCompensatedEvents(ctx, original), originalCommandID, meta); err != nil {
+		return err
+	}
+
+	for _, event := range aggregate.UncommittedEvents() {
+		if !domain.IsCompensation(event.Metadata) || event.Metadata.CausationID != originalCommandID {
+			return fmt.Errorf("compensation of %s recorded event %s without compensation metadata", originalCommandID, event.EventType)
+		}
+	}
+
+	return repo.Save(aggregate)
+}
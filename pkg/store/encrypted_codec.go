@@ -0,0 +1,273 @@
+package store
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+const (
+	// EncryptionSubjectKey is the custom metadata key naming the subject whose
+	// key encrypted an event's data (see EncryptedEventCodec).
+	EncryptionSubjectKey = "encryption_subject"
+
+	// EncryptionKeyIDKey is the custom metadata key identifying which of the
+	// subject's keys encrypted an event's data, so events encrypted before a
+	// subject was forgotten stay redacted when the subject gets a new key.
+	EncryptionKeyIDKey = "encryption_key_id"
+
+	// RedactedKey is the custom metadata key set to "true" on events whose
+	// data could not be decrypted because their subject was forgotten.
+	RedactedKey = "redacted"
+)
+
+// ErrSubjectKeyNotFound is returned by KeyStore.LoadKey when a subject has no
+// key, either because none was created yet or because it was forgotten.
+var ErrSubjectKeyNotFound = errors.New("subject key not found")
+
+// KeyStore keeps the per-subject data keys of an EncryptedEventCodec.
+// Deleting a key is what makes the subject's events unreadable, so the keys
+// must not be backed up alongside the events.
+type KeyStore interface {
+	// LoadKey returns the key of subjectID, or ErrSubjectKeyNotFound.
+	LoadKey(ctx context.Context, subjectID string) ([]byte, error)
+
+	// CreateKey stores key for subjectID unless the subject has a key
+	// already, and returns the subject's key.
+	CreateKey(ctx context.Context, subjectID string, key []byte) ([]byte, error)
+
+	// DeleteKey deletes the key of subjectID, if any.
+	DeleteKey(ctx context.Context, subjectID string) error
+}
+
+// SubjectByAggregate selects the aggregate as the data subject of an event.
+func SubjectByAggregate(event *domain.Event) string {
+	return event.AggregateID
+}
+
+// SubjectByPrincipal selects the principal that triggered an event as its
+// data subject. Events without a principal are stored unencrypted.
+func SubjectByPrincipal(event *domain.Event) string {
+	return event.Metadata.PrincipalID
+}
+
+// EncryptedEventCodec is an EventCodec for crypto-shredding: it encrypts the
+// data of each event with AES-256-GCM under a key of the event's data subject,
+// so deleting that key (ForgetSubject) erases the subject's events from the
+// immutable log without rewriting it.
+//
+// Envelopes stay readable: IDs, types, versions, metadata and unique
+// constraints are not encrypted, so keep personal data out of them. The
+// subject is recorded in the EncryptionSubjectKey metadata of each event;
+// events appended before encryption was enabled carry none and are loaded
+// as stored. The inner store only sees ciphertext, so payload validation such
+// as sqlite.WithEventValidation cannot be combined with the codec.
+//
+// Events of a forgotten subject load as tombstones instead of failing: their
+// data is empty and their RedactedKey metadata is set (see IsRedacted). An
+// empty payload decodes as the zero value of any proto message, so
+// aggregates and projections replay them with the personal fields blank.
+type EncryptedEventCodec struct {
+	keys    KeyStore
+	subject func(*domain.Event) string
+
+	mu          sync.Mutex
+	forgetHooks []func(ctx context.Context, subjectID string) error
+}
+
+// EncryptionOption configures an EncryptedEventCodec.
+type EncryptionOption func(*EncryptedEventCodec)
+
+// WithEncryptionSubject sets the function selecting the data subject of an
+// event (default SubjectByAggregate). Events it returns "" for are stored
+// unencrypted.
+func WithEncryptionSubject(subject func(*domain.Event) string) EncryptionOption {
+	return func(c *EncryptedEventCodec) {
+		c.subject = subject
+	}
+}
+
+// NewEncryptedEventCodec creates a codec keeping its subject keys in keys.
+func NewEncryptedEventCodec(keys KeyStore, opts ...EncryptionOption) *EncryptedEventCodec {
+	c := &EncryptedEventCodec{keys: keys, subject: SubjectByAggregate}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// MarshalEvent encrypts the event's data under its subject's key, creating
+// the key on the subject's first event.
+func (c *EncryptedEventCodec) MarshalEvent(ctx context.Context, event *domain.Event) (*domain.Event, error) {
+	subjectID := c.subject(event)
+	if subjectID == "" {
+		return event, nil
+	}
+
+	key, err := c.subjectKey(ctx, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(event.Data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	encrypted := withCustomMetadata(event, EncryptionSubjectKey, subjectID)
+	encrypted.Metadata.Custom[EncryptionKeyIDKey] = keyID(key)
+	// The event ID is authenticated, so a payload cannot be moved to another event
+	encrypted.Data = aead.Seal(nonce, nonce, event.Data, []byte(event.ID))
+	return encrypted, nil
+}
+
+// UnmarshalEvent decrypts the event's data, or returns a tombstone if its
+// subject was forgotten. Data that fails to decrypt with an existing key is
+// an error.
+func (c *EncryptedEventCodec) UnmarshalEvent(ctx context.Context, event *domain.Event) (*domain.Event, error) {
+	subjectID := event.Metadata.Custom[EncryptionSubjectKey]
+	if subjectID == "" {
+		return event, nil
+	}
+
+	key, err := c.keys.LoadKey(ctx, subjectID)
+	if err != nil && !errors.Is(err, ErrSubjectKeyNotFound) {
+		return nil, fmt.Errorf("failed to load key of subject %s: %w", subjectID, err)
+	}
+	if err != nil || event.Metadata.Custom[EncryptionKeyIDKey] != keyID(key) {
+		// The key that encrypted the event was deleted
+		redacted := withCustomMetadata(event, RedactedKey, "true")
+		redacted.Data = nil
+		return redacted, nil
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(event.Data) < aead.NonceSize() {
+		return nil, fmt.Errorf("failed to decrypt event %s: data too short", event.ID)
+	}
+	nonce, sealed := event.Data[:aead.NonceSize()], event.Data[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, sealed, []byte(event.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt event %s: %w", event.ID, err)
+	}
+
+	decrypted := *event
+	decrypted.Data = data
+	return &decrypted, nil
+}
+
+// ForgetSubject deletes the key of subjectID. Its events then load as
+// tombstones; events appended for the subject afterwards get a new key, which
+// does not make the earlier events readable again.
+//
+// It then runs the hooks registered with OnForgetSubject, which erase the
+// plaintext the event log does not hold, such as snapshots and cached
+// aggregates. Read models built before keep their state until they are
+// rebuilt.
+func (c *EncryptedEventCodec) ForgetSubject(ctx context.Context, subjectID string) error {
+	if err := c.keys.DeleteKey(ctx, subjectID); err != nil {
+		return fmt.Errorf("failed to delete key of subject %s: %w", subjectID, err)
+	}
+
+	c.mu.Lock()
+	hooks := c.forgetHooks
+	c.mu.Unlock()
+
+	var errs []error
+	for _, hook := range hooks {
+		if err := hook(ctx, subjectID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to erase plaintext of subject %s: %w", subjectID, err)
+	}
+	return nil
+}
+
+// OnForgetSubject registers hook to run when ForgetSubject has deleted a
+// subject's key. Hooks run in registration order; ForgetSubject returns their
+// errors after running all of them. With SubjectByAggregate, register each
+// repository's ForgetAggregate so snapshots and cached aggregates are erased
+// too:
+//
+//	codec := store.NewEncryptedEventCodec(keys)
+//	repo := store.NewRepository(store.NewCodecStore(eventStore, codec), ...,
+//	    store.WithSnapshotStore(snapshots), store.WithAggregateCache(1000))
+//	codec.OnForgetSubject(repo.ForgetAggregate)
+func (c *EncryptedEventCodec) OnForgetSubject(hook func(ctx context.Context, subjectID string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.forgetHooks = append(c.forgetHooks, hook)
+}
+
+// subjectKey returns the key of subjectID, creating it if needed.
+func (c *EncryptedEventCodec) subjectKey(ctx context.Context, subjectID string) ([]byte, error) {
+	key, err := c.keys.LoadKey(ctx, subjectID)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, ErrSubjectKeyNotFound) {
+		return nil, fmt.Errorf("failed to load key of subject %s: %w", subjectID, err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	// A concurrent writer may have created the key first; use whichever won
+	key, err = c.keys.CreateKey(ctx, subjectID, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create key of subject %s: %w", subjectID, err)
+	}
+	return key, nil
+}
+
+// keyID returns a short, non-secret identifier of key.
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// newAEAD returns the AES-GCM cipher for key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// withCustomMetadata returns a copy of event with a custom metadata entry
+// added, leaving the original's map untouched.
+func withCustomMetadata(event *domain.Event, key, value string) *domain.Event {
+	copied := *event
+	copied.Metadata.Custom = maps.Clone(event.Metadata.Custom)
+	if copied.Metadata.Custom == nil {
+		copied.Metadata.Custom = make(map[string]string, 1)
+	}
+	copied.Metadata.Custom[key] = value
+	return &copied
+}
+
+// IsRedacted reports whether event is the tombstone of an event whose subject
+// was forgotten.
+func IsRedacted(event *domain.Event) bool {
+	return event.Metadata.Custom[RedactedKey] == "true"
+}
@@ -0,0 +1,270 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestEncryptedEventCodec(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	keys, err := sqlite.NewSubjectKeyStore(sqliteStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create key store: %v", err)
+	}
+	es := store.NewCodecStore(sqliteStore, store.NewEncryptedEventCodec(keys))
+	repo := store.NewRepository[*counter](es, "Counter", newCounter, applyCounterEvent)
+
+	save := func(id string, values ...int64) {
+		t.Helper()
+		agg, err := repo.Load(id)
+		if err != nil {
+			agg = newCounter(id)
+		}
+		for _, value := range values {
+			if err := agg.add(value); err != nil {
+				t.Fatalf("failed to add: %v", err)
+			}
+		}
+		if err := repo.Save(agg); err != nil {
+			t.Fatalf("failed to save %s: %v", id, err)
+		}
+	}
+	save("alice", 5, 7)
+	save("bob", 3)
+
+	t.Run("StoresCiphertext", func(t *testing.T) {
+		plaintext, _ := proto.Marshal(wrapperspb.Int64(5))
+
+		stored, err := sqliteStore.LoadEvents(ctx, "alice", 0)
+		if err != nil {
+			t.Fatalf("failed to load stored events: %v", err)
+		}
+		if bytes.Equal(stored[0].Data, plaintext) {
+			t.Error("expected the stored payload to be encrypted")
+		}
+		if stored[0].Metadata.Custom[store.EncryptionSubjectKey] != "alice" {
+			t.Errorf("expected subject alice, got %v", stored[0].Metadata.Custom)
+		}
+
+		decoded, err := es.LoadEvents(ctx, "alice", 0)
+		if err != nil {
+			t.Fatalf("failed to load events: %v", err)
+		}
+		if !bytes.Equal(decoded[0].Data, plaintext) {
+			t.Error("expected the loaded payload to be decrypted")
+		}
+
+		alice, err := repo.Load("alice")
+		if err != nil {
+			t.Fatalf("failed to load alice: %v", err)
+		}
+		if alice.total != 12 {
+			t.Errorf("expected total 12, got %d", alice.total)
+		}
+	})
+
+	t.Run("StreamsDecodedEvents", func(t *testing.T) {
+		plaintext, _ := proto.Marshal(wrapperspb.Int64(5))
+
+		var streamed []*domain.Event
+		for event, err := range es.StreamEvents("alice", 0, 1) {
+			if err != nil {
+				t.Fatalf("failed to stream events: %v", err)
+			}
+			streamed = append(streamed, event)
+		}
+		if len(streamed) != 2 || !bytes.Equal(streamed[0].Data, plaintext) {
+			t.Errorf("expected alice's 2 events decrypted, got %d", len(streamed))
+		}
+
+		var all []*domain.Event
+		for event, err := range store.StreamAllEvents(ctx, es, 1, 1) {
+			if err != nil {
+				t.Fatalf("failed to stream all events: %v", err)
+			}
+			all = append(all, event)
+		}
+		loaded, err := es.LoadAllEvents(ctx, 1, 10)
+		if err != nil {
+			t.Fatalf("failed to load all events: %v", err)
+		}
+		if len(all) != len(loaded) {
+			t.Fatalf("expected %d events streamed, got %d", len(loaded), len(all))
+		}
+		for i := range all {
+			if all[i].ID != loaded[i].ID || !bytes.Equal(all[i].Data, loaded[i].Data) {
+				t.Errorf("expected streamed event %d decrypted like the loaded one", i)
+			}
+		}
+
+		if count, err := es.CountEvents(); err != nil || count != 3 {
+			t.Errorf("expected 3 events counted, got %d (%v)", count, err)
+		}
+		if position, err := es.LatestPosition(ctx); err != nil || position != all[len(all)-1].Position {
+			t.Errorf("expected the latest position %d, got %d (%v)", all[len(all)-1].Position, position, err)
+		}
+	})
+
+	t.Run("ReplayAfterForgetYieldsRedactedEvents", func(t *testing.T) {
+		if err := es.ForgetSubject(ctx, "alice"); err != nil {
+			t.Fatalf("failed to forget alice: %v", err)
+		}
+
+		events, err := es.LoadEvents(ctx, "alice", 0)
+		if err != nil {
+			t.Fatalf("expected redacted events, got error: %v", err)
+		}
+		if len(events) != 2 {
+			t.Fatalf("expected 2 events, got %d", len(events))
+		}
+		for _, event := range events {
+			if !store.IsRedacted(event) || len(event.Data) != 0 {
+				t.Errorf("expected event %s to be redacted, got %d bytes", event.ID, len(event.Data))
+			}
+		}
+
+		alice, err := repo.Load("alice")
+		if err != nil {
+			t.Fatalf("expected replay of redacted events to succeed: %v", err)
+		}
+		if alice.total != 0 || alice.Version() != 2 {
+			t.Errorf("expected blank state at version 2, got total=%d version=%d", alice.total, alice.Version())
+		}
+
+		all, err := es.LoadAllEvents(ctx, 1, 100)
+		if err != nil {
+			t.Fatalf("failed to load all events: %v", err)
+		}
+		redacted := 0
+		for _, event := range all {
+			if store.IsRedacted(event) {
+				redacted++
+			}
+		}
+		if redacted != 2 {
+			t.Errorf("expected 2 redacted events in the global stream, got %d", redacted)
+		}
+	})
+
+	t.Run("OtherSubjectsStayReadable", func(t *testing.T) {
+		bob, err := repo.Load("bob")
+		if err != nil {
+			t.Fatalf("failed to load bob: %v", err)
+		}
+		if bob.total != 3 {
+			t.Errorf("expected total 3, got %d", bob.total)
+		}
+	})
+
+	t.Run("NewKeyDoesNotRestoreForgottenEvents", func(t *testing.T) {
+		save("alice", 100)
+
+		events, err := es.LoadEvents(ctx, "alice", 0)
+		if err != nil {
+			t.Fatalf("failed to load events: %v", err)
+		}
+		if len(events) != 3 {
+			t.Fatalf("expected 3 events, got %d", len(events))
+		}
+		if !store.IsRedacted(events[0]) || !store.IsRedacted(events[1]) || store.IsRedacted(events[2]) {
+			t.Error("expected only the events appended before forgetting to be redacted")
+		}
+
+		alice, err := repo.Load("alice")
+		if err != nil {
+			t.Fatalf("failed to load alice: %v", err)
+		}
+		if alice.total != 100 {
+			t.Errorf("expected total 100, got %d", alice.total)
+		}
+	})
+
+	t.Run("IdempotentRetryReturnsPlaintext", func(t *testing.T) {
+		agg := newCounter("carol")
+		agg.SetCommandID("cmd-carol")
+		agg.add(9)
+
+		first, err := es.AppendEventsIdempotent(ctx, "carol", 0, agg.UncommittedEvents(), "cmd-carol", domain.DefaultCommandTTL)
+		if err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+		retry, err := es.AppendEventsIdempotent(ctx, "carol", 0, agg.UncommittedEvents(), "cmd-carol", domain.DefaultCommandTTL)
+		if err != nil {
+			t.Fatalf("failed to retry: %v", err)
+		}
+		if !retry.AlreadyProcessed {
+			t.Fatal("expected the retry to be deduplicated")
+		}
+		if !bytes.Equal(retry.Events[0].Data, first.Events[0].Data) {
+			t.Error("expected the cached result to carry the decrypted payload")
+		}
+		if retry.Events[0].Position != agg.UncommittedEvents()[0].Position {
+			t.Errorf("expected position %d, got %d", agg.UncommittedEvents()[0].Position, retry.Events[0].Position)
+		}
+	})
+}
+
+func TestEncryptedEventCodecForgetErasesCopies(t *testing.T) {
+	ctx := context.Background()
+	sqliteStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	keys, err := sqlite.NewSubjectKeyStore(sqliteStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create key store: %v", err)
+	}
+	snapshots := sqlite.NewSnapshotStore(sqliteStore.DB())
+	codec := store.NewEncryptedEventCodec(keys)
+	es := store.NewCodecStore(sqliteStore, codec)
+	repo := store.NewRepository[*counter](es, "Counter", newCounter, applyCounterEvent,
+		store.WithSnapshotStore(snapshots), store.WithAggregateCache(10))
+	codec.OnForgetSubject(repo.ForgetAggregate)
+
+	alice := newCounter("alice")
+	for _, value := range []int64{5, 7} {
+		if err := alice.add(value); err != nil {
+			t.Fatalf("failed to add: %v", err)
+		}
+	}
+	if err := repo.Save(alice); err != nil {
+		t.Fatalf("failed to save alice: %v", err)
+	}
+	data, _ := alice.MarshalSnapshot()
+	if err := snapshots.SaveSnapshot(&store.Snapshot{
+		AggregateID: "alice", AggregateType: "Counter", Version: 2, Data: data,
+	}); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+
+	if err := es.ForgetSubject(ctx, "alice"); err != nil {
+		t.Fatalf("failed to forget alice: %v", err)
+	}
+
+	if _, err := snapshots.GetLatestSnapshot("alice"); !errors.Is(err, domain.ErrSnapshotNotFound) {
+		t.Errorf("expected the snapshot to be deleted, got %v", err)
+	}
+	loaded, err := repo.Load("alice")
+	if err != nil {
+		t.Fatalf("failed to load alice: %v", err)
+	}
+	if loaded == alice || loaded.total != 0 || loaded.Version() != 2 {
+		t.Errorf("expected a replay of the redacted events, got total=%d version=%d", loaded.total, loaded.Version())
+	}
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// Error taxonomy shared by all event store implementations. Backends translate
+// driver errors into these so callers can classify failures with errors.Is
+// instead of matching driver-specific messages.
+var (
+	// ErrConcurrencyConflict is returned when another writer appended to the
+	// aggregate first. Reload the aggregate and retry the command.
+	ErrConcurrencyConflict = domain.ErrConcurrencyConflict
+
+	// ErrConstraintViolation is returned when a write violates a database
+	// constraint other than the aggregate version (e.g., a foreign key).
+	// Not retryable.
+	ErrConstraintViolation = errors.New("constraint violation")
+
+	// ErrBusy is returned when the database is locked by another connection or
+	// process. The operation can be retried after a short backoff.
+	ErrBusy = errors.New("database busy")
+
+	// ErrCorrupt is returned when the database file is corrupt or is not a
+	// database. Not retryable.
+	ErrCorrupt = errors.New("database corrupt")
+)
+
+// Error is a classified store error. It matches its Kind with errors.Is and
+// unwraps to the underlying driver error for errors.As.
+type Error struct {
+	// Kind is one of the error taxonomy sentinels (ErrBusy, ErrCorrupt, ...)
+	Kind error
+
+	// Err is the original error
+	Err error
+}
+
+// Error returns the message of the original error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Is reports whether target is the error kind.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the original error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether an operation that failed with err may succeed
+// when retried: concurrency conflicts (after reloading the aggregate), busy
+// databases and timed-out projection handlers.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrConcurrencyConflict) || errors.Is(err, ErrBusy) || errors.Is(err, ErrHandlerTimeout)
+}
@@ -0,0 +1,324 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// ErrForgetUnsupported is returned by CodecStore.ForgetSubject when its codec
+// keeps no per-subject state to forget.
+var ErrForgetUnsupported = errors.New("event codec cannot forget subjects")
+
+// EventCodec transforms events between the form the application works with
+// and the form persisted in the event store, e.g. to encrypt their payloads
+// (see EncryptedEventCodec). Both methods return a transformed copy and leave
+// their argument unchanged.
+type EventCodec interface {
+	// MarshalEvent returns the stored form of an event about to be appended.
+	MarshalEvent(ctx context.Context, event *domain.Event) (*domain.Event, error)
+
+	// UnmarshalEvent returns the application form of a loaded event.
+	UnmarshalEvent(ctx context.Context, event *domain.Event) (*domain.Event, error)
+}
+
+// SubjectForgetter is implemented by event codecs that can make the events of
+// a data subject permanently unreadable.
+type SubjectForgetter interface {
+	ForgetSubject(ctx context.Context, subjectID string) error
+}
+
+// CodecStore is an event store that passes every appended event through an
+// EventCodec's MarshalEvent and every loaded event through its
+// UnmarshalEvent. Version checks, unique constraints and idempotency are
+// left to the inner store, which only ever sees the stored form.
+//
+// The optional EventStreamer, AllEventsStreamer, MultiAggregateAppender,
+// LatestPositionReader and EventCounter methods forward to the inner store and
+// fail if it does not implement them.
+type CodecStore struct {
+	inner EventStore
+	codec EventCodec
+}
+
+// NewCodecStore wraps inner so that events are encoded with codec.
+//
+// Example:
+//
+//	keys, _ := sqlite.NewSubjectKeyStore(eventStore.DB())
+//	encrypted := store.NewCodecStore(eventStore, store.NewEncryptedEventCodec(keys))
+//	repo := accountv1.NewAccountRepository(encrypted, domain.NewAccount)
+func NewCodecStore(inner EventStore, codec EventCodec) *CodecStore {
+	return &CodecStore{inner: inner, codec: codec}
+}
+
+// ForgetSubject makes the events of subjectID unreadable if the codec is a
+// SubjectForgetter, and returns ErrForgetUnsupported otherwise.
+func (s *CodecStore) ForgetSubject(ctx context.Context, subjectID string) error {
+	forgetter, ok := s.codec.(SubjectForgetter)
+	if !ok {
+		return ErrForgetUnsupported
+	}
+	return forgetter.ForgetSubject(ctx, subjectID)
+}
+
+// AppendEvents encodes events and appends them to the inner store.
+func (s *CodecStore) AppendEvents(ctx context.Context, aggregateID string, expectedVersion int64, events []*domain.Event) error {
+	encoded, err := s.marshalEvents(ctx, events)
+	if err != nil {
+		return err
+	}
+	if err := s.inner.AppendEvents(ctx, aggregateID, expectedVersion, encoded); err != nil {
+		return err
+	}
+	copyPositions(events, encoded)
+	return nil
+}
+
+// AppendEventsIdempotent encodes events and appends them with command-level
+// idempotency. The result carries the events as the caller passed them or, for
+// an already processed command, decoded from the store.
+func (s *CodecStore) AppendEventsIdempotent(
+	ctx context.Context,
+	aggregateID string,
+	expectedVersion int64,
+	events []*domain.Event,
+	commandID string,
+	ttl time.Duration,
+) (*domain.CommandResult, error) {
+	encoded, err := s.marshalEvents(ctx, events)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.inner.AppendEventsIdempotent(ctx, aggregateID, expectedVersion, encoded, commandID, ttl)
+	if err != nil || result == nil {
+		return result, err
+	}
+	if result.AlreadyProcessed {
+		return s.unmarshalResult(ctx, result)
+	}
+
+	copyPositions(events, encoded)
+	decoded := *result
+	decoded.Events = originals(events, encoded, result.Events)
+	decoded.Deduplicated = originals(events, encoded, result.Deduplicated)
+	return &decoded, nil
+}
+
+// AppendEventsMulti encodes the events of several aggregates and appends them
+// atomically to the inner store (see MultiAggregateAppender).
+func (s *CodecStore) AppendEventsMulti(ctx context.Context, appends []AggregateAppend) error {
+	appender, ok := s.inner.(MultiAggregateAppender)
+	if !ok {
+		return errors.New("inner event store does not support multi-aggregate appends")
+	}
+
+	encoded := make([]AggregateAppend, len(appends))
+	for i, aggregate := range appends {
+		events, err := s.marshalEvents(ctx, aggregate.Events)
+		if err != nil {
+			return err
+		}
+		encoded[i] = AggregateAppend{
+			AggregateID:     aggregate.AggregateID,
+			ExpectedVersion: aggregate.ExpectedVersion,
+			Events:          events,
+		}
+	}
+
+	if err := appender.AppendEventsMulti(ctx, encoded); err != nil {
+		return err
+	}
+	for i, aggregate := range appends {
+		copyPositions(aggregate.Events, encoded[i].Events)
+	}
+	return nil
+}
+
+// GetCommandResult retrieves the result of a previously processed command.
+func (s *CodecStore) GetCommandResult(commandID string) (*domain.CommandResult, error) {
+	result, err := s.inner.GetCommandResult(commandID)
+	if err != nil || result == nil {
+		return result, err
+	}
+	return s.unmarshalResult(context.Background(), result)
+}
+
+// LoadEvents loads and decodes the events of an aggregate.
+func (s *CodecStore) LoadEvents(ctx context.Context, aggregateID string, afterVersion int64) ([]*domain.Event, error) {
+	events, err := s.inner.LoadEvents(ctx, aggregateID, afterVersion)
+	if err != nil {
+		return nil, err
+	}
+	return s.unmarshalEvents(ctx, events)
+}
+
+// StreamEvents streams and decodes the events of an aggregate (see
+// EventStreamer).
+func (s *CodecStore) StreamEvents(aggregateID string, afterVersion int64, batchSize int) iter.Seq2[*domain.Event, error] {
+	streamer, ok := s.inner.(EventStreamer)
+	if !ok {
+		return failedStream(errors.New("inner event store does not support streaming"))
+	}
+	return s.unmarshalStream(context.Background(), streamer.StreamEvents(aggregateID, afterVersion, batchSize))
+}
+
+// LoadAllEvents loads and decodes events from all aggregates.
+func (s *CodecStore) LoadAllEvents(ctx context.Context, fromPosition int64, limit int) ([]*domain.Event, error) {
+	events, err := s.inner.LoadAllEvents(ctx, fromPosition, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.unmarshalEvents(ctx, events)
+}
+
+// StreamAllEvents streams and decodes the events of all aggregates from
+// fromPosition (see AllEventsStreamer).
+func (s *CodecStore) StreamAllEvents(ctx context.Context, fromPosition int64) iter.Seq2[*domain.Event, error] {
+	streamer, ok := s.inner.(AllEventsStreamer)
+	if !ok {
+		return failedStream(errors.New("inner event store does not support streaming all events"))
+	}
+	return s.unmarshalStream(ctx, streamer.StreamAllEvents(ctx, fromPosition))
+}
+
+// LatestPosition returns the position of the inner store's latest event (see
+// LatestPositionReader).
+func (s *CodecStore) LatestPosition(ctx context.Context) (int64, error) {
+	reader, ok := s.inner.(LatestPositionReader)
+	if !ok {
+		return 0, errors.New("inner event store cannot report its latest position")
+	}
+	return reader.LatestPosition(ctx)
+}
+
+// CountEvents returns the number of events in the inner store (see
+// EventCounter).
+func (s *CodecStore) CountEvents() (int64, error) {
+	counter, ok := s.inner.(EventCounter)
+	if !ok {
+		return 0, errors.New("inner event store cannot count its events")
+	}
+	return counter.CountEvents()
+}
+
+// LoadCompensations loads and decodes the compensating events of a command.
+func (s *CodecStore) LoadCompensations(commandID string) ([]*domain.Event, error) {
+	events, err := s.inner.LoadCompensations(commandID)
+	if err != nil {
+		return nil, err
+	}
+	return s.unmarshalEvents(context.Background(), events)
+}
+
+// GetAggregateVersion returns the current version of an aggregate.
+func (s *CodecStore) GetAggregateVersion(aggregateID string) (int64, error) {
+	return s.inner.GetAggregateVersion(aggregateID)
+}
+
+// CheckUniqueness checks if a value is available for claiming.
+func (s *CodecStore) CheckUniqueness(indexName, value string) (bool, string, error) {
+	return s.inner.CheckUniqueness(indexName, value)
+}
+
+// GetConstraintOwner returns the aggregate ID that owns a unique value.
+func (s *CodecStore) GetConstraintOwner(indexName, value string) (string, error) {
+	return s.inner.GetConstraintOwner(indexName, value)
+}
+
+// RebuildConstraints rebuilds the unique constraint index of the inner store.
+func (s *CodecStore) RebuildConstraints() error {
+	return s.inner.RebuildConstraints()
+}
+
+// Close closes the inner store.
+func (s *CodecStore) Close() error {
+	return s.inner.Close()
+}
+
+// marshalEvents returns the stored form of events.
+func (s *CodecStore) marshalEvents(ctx context.Context, events []*domain.Event) ([]*domain.Event, error) {
+	encoded := make([]*domain.Event, len(events))
+	for i, event := range events {
+		var err error
+		if encoded[i], err = s.codec.MarshalEvent(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+	return encoded, nil
+}
+
+// unmarshalEvents returns the application form of loaded events.
+func (s *CodecStore) unmarshalEvents(ctx context.Context, events []*domain.Event) ([]*domain.Event, error) {
+	decoded := make([]*domain.Event, len(events))
+	for i, event := range events {
+		var err error
+		if decoded[i], err = s.codec.UnmarshalEvent(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+	return decoded, nil
+}
+
+// unmarshalStream decodes the events of a stream, stopping at the first error.
+func (s *CodecStore) unmarshalStream(ctx context.Context, events iter.Seq2[*domain.Event, error]) iter.Seq2[*domain.Event, error] {
+	return func(yield func(*domain.Event, error) bool) {
+		for event, err := range events {
+			if err == nil {
+				event, err = s.codec.UnmarshalEvent(ctx, event)
+			}
+			if !yield(event, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// failedStream yields err and ends.
+func failedStream(err error) iter.Seq2[*domain.Event, error] {
+	return func(yield func(*domain.Event, error) bool) {
+		yield(nil, err)
+	}
+}
+
+// unmarshalResult decodes the events of a loaded command result.
+func (s *CodecStore) unmarshalResult(ctx context.Context, result *domain.CommandResult) (*domain.CommandResult, error) {
+	decoded := *result
+	var err error
+	if decoded.Events, err = s.unmarshalEvents(ctx, result.Events); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
+
+// copyPositions copies the positions assigned to the encoded events back to
+// the caller's events.
+func copyPositions(events, encoded []*domain.Event) {
+	for i := range events {
+		events[i].Position = encoded[i].Position
+	}
+}
+
+// originals maps encoded events returned by the inner store back to the
+// caller's events they were encoded from.
+func originals(events, encoded, returned []*domain.Event) []*domain.Event {
+	if returned == nil {
+		return nil
+	}
+	byEncoded := make(map[*domain.Event]*domain.Event, len(encoded))
+	for i, event := range encoded {
+		byEncoded[event] = events[i]
+	}
+	mapped := make([]*domain.Event, len(returned))
+	for i, event := range returned {
+		if original, ok := byEncoded[event]; ok {
+			mapped[i] = original
+		} else {
+			mapped[i] = event
+		}
+	}
+	return mapped
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"google.golang.org/protobuf/proto"
+)
+
+// MappingMode is what a RowMapping does with its table row.
+type MappingMode int
+
+const (
+	// MappingUpsert inserts the row, or updates it if a row with the same
+	// key exists (the default).
+	MappingUpsert MappingMode = iota
+
+	// MappingUpdate updates the existing row with the key; no row is
+	// inserted if there is none.
+	MappingUpdate
+
+	// MappingDelete deletes the row with the key.
+	MappingDelete
+)
+
+// ColumnValue is a column of a MappedRow and its value.
+type ColumnValue struct {
+	Column string
+	Value  any
+}
+
+// MappedRow is the row change an event maps to.
+type MappedRow struct {
+	Table string
+	Mode  MappingMode
+	Key   []ColumnValue // Columns identifying the row
+	Set   []ColumnValue // Columns set to Value
+	Add   []ColumnValue // Numeric columns incremented by Value
+}
+
+// RowMapping maps events of one type onto a table row declaratively, so a
+// projection backend can generate the SQL instead of every handler writing
+// it (see sqlite.SQLiteProjectionBuilder.Map). Build one with MapEvent.
+type RowMapping interface {
+	// EventType returns the event type the mapping applies to.
+	EventType() string
+
+	// Validate checks that the mapping is complete.
+	Validate() error
+
+	// MapRow decodes envelope and returns the row change it maps to.
+	MapRow(envelope *domain.EventEnvelope) (*MappedRow, error)
+}
+
+// mappedColumn is a column of an EventMapping and the accessor reading its
+// value from the event.
+type mappedColumn[E proto.Message] struct {
+	name  string
+	value func(E) any
+}
+
+// EventMapping is a typed RowMapping for events of message type E. Code
+// generated by protoc-gen-eventsourcing provides a constructor per event,
+// such as accountv1.MapAccountOpened().
+//
+// Example:
+//
+//	accountv1.MapAccountOpened().
+//	    ToTable("account_balance").
+//	    Key("account_id", func(e *accountv1.AccountOpenedEvent) any { return e.AccountId }).
+//	    Columns(map[string]func(*accountv1.AccountOpenedEvent) any{
+//	        "owner_name": func(e *accountv1.AccountOpenedEvent) any { return e.OwnerName },
+//	        "balance":    func(e *accountv1.AccountOpenedEvent) any { return e.InitialBalance },
+//	    })
+type EventMapping[E proto.Message] struct {
+	eventType string
+	table     string
+	mode      MappingMode
+	key       []mappedColumn[E]
+	set       []mappedColumn[E]
+	add       []mappedColumn[E]
+}
+
+// MapEvent starts a mapping of events of eventType, whose payload is the
+// message type E, onto a table row.
+func MapEvent[E proto.Message](eventType string) *EventMapping[E] {
+	return &EventMapping[E]{eventType: eventType}
+}
+
+// ToTable sets the table the event maps to.
+func (m *EventMapping[E]) ToTable(table string) *EventMapping[E] {
+	m.table = table
+	return m
+}
+
+// Key adds a column identifying the row. Upserts need a primary key or
+// unique index over the key columns.
+func (m *EventMapping[E]) Key(column string, value func(E) any) *EventMapping[E] {
+	m.key = append(m.key, mappedColumn[E]{name: column, value: value})
+	return m
+}
+
+// Column sets column to the value read from the event.
+func (m *EventMapping[E]) Column(column string, value func(E) any) *EventMapping[E] {
+	m.set = append(m.set, mappedColumn[E]{name: column, value: value})
+	return m
+}
+
+// Columns sets several columns, in column name order.
+func (m *EventMapping[E]) Columns(columns map[string]func(E) any) *EventMapping[E] {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m.Column(name, columns[name])
+	}
+	return m
+}
+
+// Increment adds the value read from the event to a numeric column, e.g. a
+// transaction counter. An inserted row starts at the value.
+func (m *EventMapping[E]) Increment(column string, value func(E) any) *EventMapping[E] {
+	m.add = append(m.add, mappedColumn[E]{name: column, value: value})
+	return m
+}
+
+// Update makes the mapping update the existing row instead of upserting it.
+func (m *EventMapping[E]) Update() *EventMapping[E] {
+	m.mode = MappingUpdate
+	return m
+}
+
+// Delete makes the mapping delete the row with the key.
+func (m *EventMapping[E]) Delete() *EventMapping[E] {
+	m.mode = MappingDelete
+	return m
+}
+
+// EventType implements RowMapping.
+func (m *EventMapping[E]) EventType() string {
+	return m.eventType
+}
+
+// Validate implements RowMapping: the mapping needs a table and key, and
+// columns to write unless it deletes.
+func (m *EventMapping[E]) Validate() error {
+	switch {
+	case m.table == "":
+		return fmt.Errorf("mapping of %s has no table (use ToTable)", m.eventType)
+	case len(m.key) == 0:
+		return fmt.Errorf("mapping of %s has no key column (use Key)", m.eventType)
+	case m.mode != MappingDelete && len(m.set) == 0 && len(m.add) == 0:
+		return fmt.Errorf("mapping of %s sets no columns", m.eventType)
+	}
+	return nil
+}
+
+// MapRow implements RowMapping, decoding the event payload as E.
+func (m *EventMapping[E]) MapRow(envelope *domain.EventEnvelope) (*MappedRow, error) {
+	var zero E
+	event, ok := zero.ProtoReflect().Type().New().Interface().(E)
+	if !ok {
+		return nil, errors.New("mapping event type is not a generated message pointer")
+	}
+	if err := proto.Unmarshal(envelope.Data, event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", m.eventType, err)
+	}
+
+	row := &MappedRow{Table: m.table, Mode: m.mode}
+	row.Key = mapColumns(m.key, event)
+	row.Set = mapColumns(m.set, event)
+	row.Add = mapColumns(m.add, event)
+	return row, nil
+}
+
+// mapColumns reads the values of columns from event.
+func mapColumns[E proto.Message](columns []mappedColumn[E], event E) []ColumnValue {
+	if len(columns) == 0 {
+		return nil
+	}
+	values := make([]ColumnValue, len(columns))
+	for i, column := range columns {
+		values[i] = ColumnValue{Column: column.name, Value: column.value(event)}
+	}
+	return values
+}
@@ -1,23 +1,30 @@
 package store
 
 import (
+	"context"
+	"iter"
 	"time"
 
 	"github.com/plaenen/eventstore/pkg/domain"
 )
 
 // EventStore defines the interface for persisting and retrieving events.
+//
+// Appends and loads take the caller's context, so command timeouts and
+// cancellation reach the database: a cancelled append is rolled back and
+// returns ctx.Err().
 type EventStore interface {
 	// AppendEvents appends events to an aggregate's stream atomically.
 	// Validates unique constraints before persisting.
 	// Returns domain.ErrConcurrencyConflict if expectedVersion doesn't match current version.
 	// Returns domain.ErrUniqueConstraintViolation if any constraint would be violated.
-	AppendEvents(aggregateID string, expectedVersion int64, events []*domain.Event) error
+	AppendEvents(ctx context.Context, aggregateID string, expectedVersion int64, events []*domain.Event) error
 
 	// AppendEventsIdempotent appends events with command-level idempotency.
 	// If commandID was already processed, returns cached result without appending.
 	// TTL specifies how long to remember processed commands (default 7 days).
 	AppendEventsIdempotent(
+		ctx context.Context,
 		aggregateID string,
 		expectedVersion int64,
 		events []*domain.Event,
@@ -30,11 +37,15 @@ type EventStore interface {
 	GetCommandResult(commandID string) (*domain.CommandResult, error)
 
 	// LoadEvents loads all events for an aggregate starting from afterVersion.
-	LoadEvents(aggregateID string, afterVersion int64) ([]*domain.Event, error)
+	LoadEvents(ctx context.Context, aggregateID string, afterVersion int64) ([]*domain.Event, error)
 
 	// LoadAllEvents loads all events from all aggregates for projection building.
 	// Returns events in the order they were appended.
-	LoadAllEvents(fromPosition int64, limit int) ([]*domain.Event, error)
+	LoadAllEvents(ctx context.Context, fromPosition int64, limit int) ([]*domain.Event, error)
+
+	// LoadCompensations returns the compensating events recorded for a command
+	// (see domain.Compensator), in the order they were appended.
+	LoadCompensations(commandID string) ([]*domain.Event, error)
 
 	// GetAggregateVersion returns the current version of an aggregate.
 	// Returns 0 if the aggregate doesn't exist.
@@ -56,3 +67,78 @@ type EventStore interface {
 	// Close closes the event store and releases resources.
 	Close() error
 }
+
+// EventCounter is implemented by event stores that can count their events.
+// Rebuilds use it, when available, to report total progress and an ETA.
+type EventCounter interface {
+	// CountEvents returns the total number of events in the store.
+	CountEvents() (int64, error)
+}
+
+// LatestPositionReader is implemented by event stores that can report the
+// global position of their latest event, the head projections catch up to.
+type LatestPositionReader interface {
+	// LatestPosition returns the position of the latest event (0 = none).
+	LatestPosition(ctx context.Context) (int64, error)
+}
+
+// AllEventsStreamer is implemented by event stores that can read the global
+// event stream one event at a time, so a rebuild of a large store holds a
+// single event in memory instead of a batch.
+type AllEventsStreamer interface {
+	// StreamAllEvents yields the events at or after fromPosition in position
+	// order. Iteration stops at the first error, which is ctx.Err() once ctx
+	// is cancelled.
+	StreamAllEvents(ctx context.Context, fromPosition int64) iter.Seq2[*domain.Event, error]
+}
+
+// StreamAllEvents yields the events of eventStore at or after fromPosition in
+// position order. It uses the store's AllEventsStreamer when available and
+// otherwise pages through LoadAllEvents, batchSize events at a time.
+func StreamAllEvents(ctx context.Context, eventStore EventStore, fromPosition int64, batchSize int) iter.Seq2[*domain.Event, error] {
+	if streamer, ok := eventStore.(AllEventsStreamer); ok {
+		return streamer.StreamAllEvents(ctx, fromPosition)
+	}
+	return func(yield func(*domain.Event, error) bool) {
+		position := fromPosition
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			batch, err := eventStore.LoadAllEvents(ctx, position, batchSize)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, event := range batch {
+				if !yield(event, nil) {
+					return
+				}
+			}
+			if len(batch) < batchSize {
+				return
+			}
+			position = batch[len(batch)-1].Position + 1
+		}
+	}
+}
+
+// AggregateAppend is one aggregate's part of a multi-aggregate append.
+type AggregateAppend struct {
+	AggregateID     string
+	ExpectedVersion int64
+	Events          []*domain.Event
+}
+
+// MultiAggregateAppender is implemented by event stores that can append to
+// several aggregates in one transaction, e.g. to debit one account and credit
+// another without a saga.
+type MultiAggregateAppender interface {
+	// AppendEventsMulti appends the events of every aggregate atomically:
+	// either all appends commit or none does. Each aggregate's version is
+	// checked as with AppendEvents (domain.ErrConcurrencyConflict), and unique
+	// constraints are validated across the whole batch, so two aggregates of
+	// the batch cannot claim the same value.
+	AppendEventsMulti(ctx context.Context, appends []AggregateAppend) error
+}
@@ -0,0 +1,403 @@
+// Package memory provides an in-memory implementation of store.EventStore for
+// unit tests. Nothing is persisted; a store lives as long as its value.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// EventStore is an in-memory implementation of store.EventStore backed by
+// maps and slices. It is safe for concurrent use and follows the SQLite store
+// for everything callers can observe: version checks, unique constraints,
+// idempotent appends and global positions in append order. Events are copied
+// on the way in and out, so callers may reuse or modify them.
+type EventStore struct {
+	mu          sync.RWMutex
+	events      []*domain.Event            // Global stream; events[i] has position i+1
+	aggregates  map[string][]*domain.Event // Events of each aggregate in append order
+	byID        map[string]*domain.Event
+	constraints map[constraintKey]string // Owner of each claimed value
+	commands    map[string]*processedCommand
+}
+
+// constraintKey identifies a unique value.
+type constraintKey struct {
+	indexName string
+	value     string
+}
+
+// aggregateVersion identifies an event within its aggregate.
+type aggregateVersion struct {
+	aggregateID string
+	version     int64
+}
+
+// processedCommand is the idempotency record of a command.
+type processedCommand struct {
+	eventIDs    []string
+	processedAt time.Time
+	expiresAt   time.Time
+}
+
+// NewEventStore creates an empty in-memory event store.
+func NewEventStore() *EventStore {
+	return &EventStore{
+		aggregates:  make(map[string][]*domain.Event),
+		byID:        make(map[string]*domain.Event),
+		constraints: make(map[constraintKey]string),
+		commands:    make(map[string]*processedCommand),
+	}
+}
+
+// AppendEvents appends events to an aggregate's stream atomically.
+func (s *EventStore) AppendEvents(ctx context.Context, aggregateID string, expectedVersion int64, events []*domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.appendLocked(aggregateID, expectedVersion, events)
+}
+
+// appendLocked checks and appends events. It changes nothing unless all
+// checks pass.
+func (s *EventStore) appendLocked(aggregateID string, expectedVersion int64, events []*domain.Event) error {
+	if s.versionLocked(aggregateID) != expectedVersion {
+		return domain.ErrConcurrencyConflict
+	}
+
+	// The events table of the SQLite store is unique on event ID and on
+	// aggregate ID and version
+	batchIDs := make(map[string]bool, len(events))
+	batchVersions := make(map[aggregateVersion]bool, len(events))
+	for _, event := range events {
+		if _, ok := s.byID[event.ID]; ok || batchIDs[event.ID] {
+			return &store.Error{
+				Kind: store.ErrConcurrencyConflict,
+				Err:  fmt.Errorf("event %s already exists", event.ID),
+			}
+		}
+		version := aggregateVersion{aggregateID: event.AggregateID, version: event.Version}
+		if s.hasVersionLocked(version) || batchVersions[version] {
+			return &store.Error{
+				Kind: store.ErrConcurrencyConflict,
+				Err:  fmt.Errorf("version %d of aggregate %s already exists", event.Version, event.AggregateID),
+			}
+		}
+		batchIDs[event.ID] = true
+		batchVersions[version] = true
+	}
+
+	// Validate unique constraints against an overlay of the batch's changes,
+	// so a violation midway leaves the claims untouched
+	overlay := make(map[constraintKey]bool) // true = claimed, false = released
+	for _, event := range events {
+		if err := s.checkConstraintsLocked(overlay, event.UniqueConstraints, aggregateID); err != nil {
+			return err
+		}
+	}
+	for key, claimed := range overlay {
+		if claimed {
+			s.constraints[key] = aggregateID
+		} else {
+			delete(s.constraints, key)
+		}
+	}
+
+	for _, event := range events {
+		stored := cloneEvent(event)
+		// Timestamps are stored with second precision, as by the SQLite store
+		stored.Timestamp = time.Unix(event.Timestamp.Unix(), 0)
+		stored.Position = int64(len(s.events)) + 1
+		event.Position = stored.Position
+
+		// Like the SQLite store, file events under their own aggregate ID
+		s.events = append(s.events, stored)
+		s.aggregates[stored.AggregateID] = append(s.aggregates[stored.AggregateID], stored)
+		s.byID[stored.ID] = stored
+	}
+	return nil
+}
+
+// checkConstraintsLocked records in overlay the values constraints claim and
+// release for aggregateID, on top of the store's claims and the earlier
+// changes in overlay. Claiming a value owned by another aggregate fails.
+func (s *EventStore) checkConstraintsLocked(overlay map[constraintKey]bool, constraints []domain.UniqueConstraint, aggregateID string) error {
+	for _, constraint := range constraints {
+		key := constraintKey{indexName: constraint.IndexName, value: constraint.Value}
+		owner, claimed := s.constraints[key]
+		if changed, ok := overlay[key]; ok {
+			owner, claimed = aggregateID, changed
+		}
+		switch constraint.Operation {
+		case domain.ConstraintClaim:
+			if claimed && owner != aggregateID {
+				return domain.NewUniqueConstraintError(constraint.IndexName, constraint.Value, owner)
+			}
+			overlay[key] = true
+		case domain.ConstraintRelease:
+			if claimed && owner == aggregateID {
+				overlay[key] = false
+			}
+		}
+	}
+	return nil
+}
+
+// applyConstraints claims and releases the given constraints for aggregateID
+// in claims, without checking the current owners.
+func applyConstraints(claims map[constraintKey]string, constraints []domain.UniqueConstraint, aggregateID string) {
+	for _, constraint := range constraints {
+		key := constraintKey{indexName: constraint.IndexName, value: constraint.Value}
+		switch constraint.Operation {
+		case domain.ConstraintClaim:
+			claims[key] = aggregateID
+		case domain.ConstraintRelease:
+			if claims[key] == aggregateID {
+				delete(claims, key)
+			}
+		}
+	}
+}
+
+// AppendEventsIdempotent appends events with command-level idempotency.
+func (s *EventStore) AppendEventsIdempotent(
+	ctx context.Context,
+	aggregateID string,
+	expectedVersion int64,
+	events []*domain.Event,
+	commandID string,
+	ttl time.Duration,
+) (*domain.CommandResult, error) {
+	if commandID == "" {
+		return nil, domain.ErrInvalidCommand
+	}
+
+	if len(events) == 0 {
+		return &domain.CommandResult{
+			CommandID: commandID,
+			Events:    nil,
+		}, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check if command already processed
+	if result := s.commandResultLocked(commandID); result != nil {
+		return result, nil // Idempotent return
+	}
+
+	if err := s.appendLocked(aggregateID, expectedVersion, events); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	command := &processedCommand{
+		eventIDs:    make([]string, len(events)),
+		processedAt: now,
+		expiresAt:   now.Add(ttl),
+	}
+	for i, event := range events {
+		command.eventIDs[i] = event.ID
+	}
+	s.commands[commandID] = command
+
+	return &domain.CommandResult{
+		CommandID:        commandID,
+		Events:           events,
+		AlreadyProcessed: false,
+		ProcessedAt:      now,
+		Position:         events[len(events)-1].Position,
+	}, nil
+}
+
+// GetCommandResult retrieves the result of a previously processed command.
+// Returns nil if the command hasn't been processed or its TTL expired.
+func (s *EventStore) GetCommandResult(commandID string) (*domain.CommandResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.commandResultLocked(commandID), nil
+}
+
+// commandResultLocked returns the unexpired result of commandID, or nil.
+func (s *EventStore) commandResultLocked(commandID string) *domain.CommandResult {
+	command, ok := s.commands[commandID]
+	if !ok || !command.expiresAt.After(domain.Now()) {
+		return nil
+	}
+
+	result := &domain.CommandResult{
+		CommandID:        commandID,
+		AlreadyProcessed: true,
+		ProcessedAt:      command.processedAt.Truncate(time.Second),
+	}
+	for _, eventID := range command.eventIDs {
+		event := cloneEvent(s.byID[eventID])
+		result.Events = append(result.Events, event)
+		result.Position = max(result.Position, event.Position)
+	}
+	return result
+}
+
+// LoadEvents loads all events for an aggregate starting from afterVersion.
+func (s *EventStore) LoadEvents(ctx context.Context, aggregateID string, afterVersion int64) ([]*domain.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make([]*domain.Event, 0)
+	for _, event := range s.aggregates[aggregateID] {
+		if event.Version > afterVersion {
+			events = append(events, cloneEvent(event))
+		}
+	}
+	slices.SortFunc(events, func(a, b *domain.Event) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return events, nil
+}
+
+// LoadAllEvents loads up to limit events from all aggregates, starting at
+// fromPosition, in append order.
+func (s *EventStore) LoadAllEvents(ctx context.Context, fromPosition int64, limit int) ([]*domain.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := max(fromPosition, 1) - 1
+	if start >= int64(len(s.events)) || limit <= 0 {
+		return []*domain.Event{}, nil
+	}
+	end := min(start+int64(limit), int64(len(s.events)))
+
+	events := make([]*domain.Event, 0, end-start)
+	for _, event := range s.events[start:end] {
+		events = append(events, cloneEvent(event))
+	}
+	return events, nil
+}
+
+// LoadCompensations returns the compensating events recorded for commandID,
+// across all aggregates, in global position order. See domain.Compensator.
+func (s *EventStore) LoadCompensations(commandID string) ([]*domain.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []*domain.Event
+	for _, event := range s.events {
+		if event.Metadata.CausationID == commandID && domain.IsCompensation(event.Metadata) {
+			events = append(events, cloneEvent(event))
+		}
+	}
+	return events, nil
+}
+
+// CountEvents returns the total number of events in the store.
+func (s *EventStore) CountEvents() (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.events)), nil
+}
+
+// LatestPosition returns the position of the latest event (0 = none).
+func (s *EventStore) LatestPosition(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Positions are assigned in append order from 1
+	return int64(len(s.events)), nil
+}
+
+// GetAggregateVersion returns the current version of an aggregate.
+func (s *EventStore) GetAggregateVersion(aggregateID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.versionLocked(aggregateID), nil
+}
+
+// versionLocked returns the highest version of the aggregate, or 0.
+func (s *EventStore) versionLocked(aggregateID string) int64 {
+	var version int64
+	for _, event := range s.aggregates[aggregateID] {
+		version = max(version, event.Version)
+	}
+	return version
+}
+
+// hasVersionLocked reports whether an event with the given version exists.
+func (s *EventStore) hasVersionLocked(v aggregateVersion) bool {
+	return slices.ContainsFunc(s.aggregates[v.aggregateID], func(event *domain.Event) bool {
+		return event.Version == v.version
+	})
+}
+
+// CheckUniqueness checks if a value is available for claiming.
+func (s *EventStore) CheckUniqueness(indexName, value string) (available bool, ownerID string, error error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ownerID, claimed := s.constraints[constraintKey{indexName: indexName, value: value}]
+	return !claimed, ownerID, nil
+}
+
+// GetConstraintOwner returns the aggregate ID that owns a unique value.
+func (s *EventStore) GetConstraintOwner(indexName, value string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.constraints[constraintKey{indexName: indexName, value: value}], nil
+}
+
+// RebuildConstraints rebuilds the unique constraint index from the event stream.
+func (s *EventStore) RebuildConstraints() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claims := make(map[constraintKey]string)
+	for _, event := range s.events {
+		applyConstraints(claims, event.UniqueConstraints, event.AggregateID)
+	}
+	s.constraints = claims
+	return nil
+}
+
+// Close is a no-op; the events live as long as the store.
+func (s *EventStore) Close() error {
+	return nil
+}
+
+// cloneEvent returns a deep copy of event.
+func cloneEvent(event *domain.Event) *domain.Event {
+	cloned := *event
+	cloned.Data = slices.Clone(event.Data)
+	cloned.Metadata.Custom = maps.Clone(event.Metadata.Custom)
+	cloned.UniqueConstraints = slices.Clone(event.UniqueConstraints)
+	return &cloned
+}
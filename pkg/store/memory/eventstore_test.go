@@ -0,0 +1,102 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/memory"
+	"github.com/plaenen/eventstore/pkg/store/storetest"
+)
+
+func TestEventStoreConformance(t *testing.T) {
+	storetest.RunEventStoreTests(t, func(t *testing.T) store.EventStore {
+		return memory.NewEventStore()
+	})
+}
+
+func TestEventStoreCopiesEvents(t *testing.T) {
+	ctx := context.Background()
+	es := memory.NewEventStore()
+
+	event := &domain.Event{
+		ID:          "evt-1",
+		AggregateID: "agg-1",
+		EventType:   "test.Happened",
+		Version:     1,
+		Timestamp:   time.Now(),
+		Data:        []byte("original"),
+	}
+	if err := es.AppendEvents(ctx, "agg-1", 0, []*domain.Event{event}); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	event.Data[0] = 'X'
+
+	loaded, err := es.LoadEvents(ctx, "agg-1", 0)
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if string(loaded[0].Data) != "original" {
+		t.Errorf("expected the stored event to be unaffected by the caller, got %q", loaded[0].Data)
+	}
+
+	loaded[0].Data[0] = 'Y'
+	again, _ := es.LoadEvents(ctx, "agg-1", 0)
+	if string(again[0].Data) != "original" {
+		t.Errorf("expected loaded events to be copies, got %q", again[0].Data)
+	}
+}
+
+func TestEventStoreConstraintBatches(t *testing.T) {
+	ctx := context.Background()
+	es := memory.NewEventStore()
+	constraint := func(value string, operation domain.ConstraintOperation) []domain.UniqueConstraint {
+		return []domain.UniqueConstraint{{IndexName: "email", Value: value, Operation: operation}}
+	}
+	event := func(aggregateID string, version int64, constraints []domain.UniqueConstraint) *domain.Event {
+		return &domain.Event{
+			ID:                fmt.Sprintf("%s-%d", aggregateID, version),
+			AggregateID:       aggregateID,
+			EventType:         "test.Happened",
+			Version:           version,
+			Timestamp:         time.Now(),
+			UniqueConstraints: constraints,
+		}
+	}
+
+	if err := es.AppendEvents(ctx, "user-1", 0, []*domain.Event{event("user-1", 1, constraint("a", domain.ConstraintClaim))}); err != nil {
+		t.Fatalf("failed to claim: %v", err)
+	}
+
+	// A violation late in the batch drops the batch's earlier claims
+	err := es.AppendEvents(ctx, "user-2", 0, []*domain.Event{
+		event("user-2", 1, constraint("b", domain.ConstraintClaim)),
+		event("user-2", 2, constraint("a", domain.ConstraintClaim)),
+	})
+	if !errors.Is(err, domain.ErrUniqueConstraintViolation) {
+		t.Fatalf("expected a unique constraint violation, got %v", err)
+	}
+	if owner, _ := es.GetConstraintOwner("email", "b"); owner != "" {
+		t.Errorf("expected b unclaimed after the rejected batch, got owner %q", owner)
+	}
+
+	// Later events of a batch see the claims and releases of earlier ones
+	err = es.AppendEvents(ctx, "user-1", 1, []*domain.Event{
+		event("user-1", 2, constraint("a", domain.ConstraintRelease)),
+		event("user-1", 3, constraint("c", domain.ConstraintClaim)),
+		event("user-1", 4, constraint("c", domain.ConstraintRelease)),
+		event("user-1", 5, constraint("d", domain.ConstraintClaim)),
+	})
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	for value, want := range map[string]string{"a": "", "c": "", "d": "user-1"} {
+		if owner, _ := es.GetConstraintOwner("email", value); owner != want {
+			t.Errorf("expected %s owned by %q, got %q", value, want, owner)
+		}
+	}
+}
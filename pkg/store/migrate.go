@@ -0,0 +1,432 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// ErrMigrationMismatch is returned by Migrate when the verification pass finds
+// aggregates that differ between the source and the destination.
+var ErrMigrationMismatch = errors.New("migrated event store differs from source")
+
+// EventImporter is implemented by event stores that can append events with
+// their original IDs, timestamps and metadata, such as sqlite.EventStore.
+// Migrate writes to the destination through it.
+type EventImporter interface {
+	ImportEvents(aggregateID string, expectedVersion int64, events []*domain.Event) error
+}
+
+// ProcessedCommand is the idempotency record of a processed command.
+type ProcessedCommand struct {
+	CommandID   string
+	AggregateID string
+	EventIDs    []string
+	ProcessedAt time.Time
+	ExpiresAt   time.Time
+}
+
+// ProcessedCommandCopier is implemented by event stores whose command
+// idempotency records can be listed and restored. Migrate copies them when
+// both stores implement it, so retried commands stay deduplicated after a
+// migration.
+type ProcessedCommandCopier interface {
+	// ListProcessedCommands returns up to limit unexpired records with a
+	// command ID after afterCommandID, ordered by command ID.
+	ListProcessedCommands(ctx context.Context, afterCommandID string, limit int) ([]*ProcessedCommand, error)
+
+	// ImportProcessedCommands stores records, replacing those with the same
+	// command ID.
+	ImportProcessedCommands(ctx context.Context, commands []*ProcessedCommand) error
+}
+
+// MigrateOptions configures Migrate.
+type MigrateOptions struct {
+	// BatchSize is how many events are read from the source at a time
+	// (default 1000).
+	BatchSize int
+
+	// CatchUp continues an earlier migration into the same destination:
+	// events at or before FromPosition are not copied again, and the
+	// destination need not be empty. Without it the destination must be
+	// empty, so a live store is never migrated into by mistake.
+	CatchUp bool
+
+	// FromPosition is the source position already copied, the LastPosition
+	// of an earlier MigrateReport or MigrateProgress. Only used with CatchUp.
+	FromPosition int64
+
+	// SourceSnapshots and DestSnapshots, when both set, copy the latest
+	// snapshot of every aggregate whose events were copied.
+	SourceSnapshots SnapshotStore
+	DestSnapshots   SnapshotStore
+
+	// Verify compares the event count and checksum of every aggregate in
+	// both stores after copying. Run it once writes to the source stopped.
+	Verify bool
+
+	// OnProgress is called after every batch of events, e.g. to persist
+	// LastPosition so an interrupted migration can resume with CatchUp.
+	OnProgress func(MigrateProgress)
+}
+
+// MigrateProgress reports the progress of a running Migrate.
+type MigrateProgress struct {
+	EventsCopied int64
+	TotalEvents  int64 // 0 if the source cannot count its events
+	LastPosition int64 // Source position copied up to
+}
+
+// MigrateReport summarizes a completed Migrate.
+type MigrateReport struct {
+	EventsCopied    int64
+	EventsSkipped   int64 // Events the destination already had (on resume)
+	Aggregates      int   // Aggregates with copied events
+	SnapshotsCopied int64
+	CommandsCopied  int64
+	LastPosition    int64 // Pass as MigrateOptions.FromPosition to catch up
+	StartedAt       time.Time
+	Duration        time.Duration
+
+	// Verification is set when MigrateOptions.Verify was set.
+	Verification *MigrateVerification
+}
+
+// MigrateVerification is the result of comparing the migrated stores.
+type MigrateVerification struct {
+	Aggregates int
+	Events     int64
+	Mismatches []AggregateMismatch
+}
+
+// AggregateMismatch describes an aggregate that differs between the source
+// and the destination of a migration.
+type AggregateMismatch struct {
+	AggregateID    string
+	SourceEvents   int64
+	DestEvents     int64
+	SourceChecksum string
+	DestChecksum   string
+}
+
+// Migrate copies an event store into another one, typically to move from
+// SQLite to a server-backed store as an application outgrows it. Events are
+// streamed in global position order and imported with their IDs, versions,
+// timestamps, metadata and unique constraint claims; a destination assigning
+// positions in append order thereby keeps the source positions, which Verify
+// checks. Snapshots and processed-command records are copied when configured
+// and supported (see MigrateOptions and ProcessedCommandCopier).
+//
+// The destination must implement EventImporter. Events the destination
+// already has are skipped, so an interrupted migration can be resumed with
+// CatchUp and the last reported position.
+//
+// To keep downtime short, run a bulk copy while the source is live, then stop
+// writers and copy the delta:
+//
+//	report, err := store.Migrate(ctx, src, dst, store.MigrateOptions{})
+//	// ... stop writers ...
+//	final, err := store.Migrate(ctx, src, dst, store.MigrateOptions{
+//	    CatchUp:      true,
+//	    FromPosition: report.LastPosition,
+//	    Verify:       true,
+//	})
+func Migrate(ctx context.Context, src, dst EventStore, opts MigrateOptions) (MigrateReport, error) {
+	report := MigrateReport{StartedAt: time.Now(), LastPosition: opts.FromPosition}
+	if !opts.CatchUp {
+		report.LastPosition = 0
+	}
+
+	importer, ok := dst.(EventImporter)
+	if !ok {
+		return report, errors.New("destination event store cannot import events")
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	if !opts.CatchUp {
+		existing, err := dst.LoadAllEvents(ctx, 0, 1)
+		if err != nil {
+			return report, fmt.Errorf("failed to check destination: %w", err)
+		}
+		if len(existing) > 0 {
+			return report, errors.New("destination event store is not empty (use CatchUp to continue a migration)")
+		}
+	}
+
+	var total int64
+	if counter, ok := src.(EventCounter); ok {
+		if count, err := counter.CountEvents(); err == nil {
+			total = count
+		}
+	}
+
+	copied := make(map[string]bool)
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, fmt.Errorf("migration cancelled: %w", err)
+		}
+
+		events, err := src.LoadAllEvents(ctx, report.LastPosition+1, batchSize)
+		if err != nil {
+			return report, fmt.Errorf("failed to load events: %w", err)
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		// Import runs of consecutive events of one aggregate together
+		for start := 0; start < len(events); {
+			end := start + 1
+			for end < len(events) && events[end].AggregateID == events[start].AggregateID {
+				end++
+			}
+			imported, skipped, err := importRun(dst, importer, events[start:end])
+			if err != nil {
+				return report, err
+			}
+			if imported > 0 {
+				copied[events[start].AggregateID] = true
+			}
+			report.EventsCopied += imported
+			report.EventsSkipped += skipped
+			start = end
+		}
+
+		report.LastPosition = events[len(events)-1].Position
+		if opts.OnProgress != nil {
+			opts.OnProgress(MigrateProgress{
+				EventsCopied: report.EventsCopied,
+				TotalEvents:  total,
+				LastPosition: report.LastPosition,
+			})
+		}
+		if len(events) < batchSize {
+			break
+		}
+	}
+	report.Aggregates = len(copied)
+
+	if opts.SourceSnapshots != nil && opts.DestSnapshots != nil {
+		n, err := migrateSnapshots(opts.SourceSnapshots, opts.DestSnapshots, copied)
+		report.SnapshotsCopied = n
+		if err != nil {
+			return report, err
+		}
+	}
+
+	srcCommands, srcOK := src.(ProcessedCommandCopier)
+	dstCommands, dstOK := dst.(ProcessedCommandCopier)
+	if srcOK && dstOK {
+		n, err := migrateCommands(ctx, srcCommands, dstCommands, batchSize)
+		report.CommandsCopied = n
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if opts.Verify {
+		verification, err := verifyMigration(ctx, src, dst, batchSize)
+		if err != nil {
+			return report, err
+		}
+		report.Verification = verification
+		if len(verification.Mismatches) > 0 {
+			report.Duration = time.Since(report.StartedAt)
+			return report, fmt.Errorf("%w: %d of %d aggregates differ",
+				ErrMigrationMismatch, len(verification.Mismatches), verification.Aggregates)
+		}
+	}
+
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
+
+// importRun imports events of a single aggregate, skipping the versions the
+// destination already has.
+func importRun(dst EventStore, importer EventImporter, events []*domain.Event) (imported, skipped int64, err error) {
+	aggregateID := events[0].AggregateID
+	version, err := dst.GetAggregateVersion(aggregateID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load destination version of %s: %w", aggregateID, err)
+	}
+
+	pending := events
+	for len(pending) > 0 && pending[0].Version <= version {
+		pending = pending[1:]
+	}
+	skipped = int64(len(events) - len(pending))
+	if len(pending) == 0 {
+		return 0, skipped, nil
+	}
+
+	if err := importer.ImportEvents(aggregateID, pending[0].Version-1, pending); err != nil {
+		return 0, skipped, fmt.Errorf("failed to import events of %s: %w", aggregateID, err)
+	}
+	return int64(len(pending)), skipped, nil
+}
+
+// migrateSnapshots copies the latest snapshot of each aggregate.
+func migrateSnapshots(src, dst SnapshotStore, aggregates map[string]bool) (int64, error) {
+	ids := make([]string, 0, len(aggregates))
+	for id := range aggregates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var copied int64
+	for _, id := range ids {
+		snapshot, err := src.GetLatestSnapshot(id)
+		if err != nil || snapshot == nil {
+			continue // No snapshot for this aggregate
+		}
+		if err := dst.SaveSnapshot(snapshot); err != nil {
+			return copied, fmt.Errorf("failed to copy snapshot of %s: %w", id, err)
+		}
+		copied++
+	}
+	return copied, nil
+}
+
+// migrateCommands copies all unexpired processed-command records.
+func migrateCommands(ctx context.Context, src, dst ProcessedCommandCopier, batchSize int) (int64, error) {
+	var copied int64
+	after := ""
+	for {
+		commands, err := src.ListProcessedCommands(ctx, after, batchSize)
+		if err != nil {
+			return copied, fmt.Errorf("failed to list processed commands: %w", err)
+		}
+		if len(commands) == 0 {
+			return copied, nil
+		}
+		if err := dst.ImportProcessedCommands(ctx, commands); err != nil {
+			return copied, fmt.Errorf("failed to import processed commands: %w", err)
+		}
+		copied += int64(len(commands))
+		after = commands[len(commands)-1].CommandID
+		if len(commands) < batchSize {
+			return copied, nil
+		}
+	}
+}
+
+// aggregateDigest accumulates the event count and checksum of an aggregate.
+type aggregateDigest struct {
+	events   int64
+	checksum [sha256.Size]byte
+}
+
+// verifyMigration compares the per-aggregate event counts and checksums of
+// both stores.
+func verifyMigration(ctx context.Context, src, dst EventStore, batchSize int) (*MigrateVerification, error) {
+	srcDigests, err := digestEvents(ctx, src, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to checksum source: %w", err)
+	}
+	dstDigests, err := digestEvents(ctx, dst, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to checksum destination: %w", err)
+	}
+
+	ids := make([]string, 0, len(srcDigests))
+	for id := range srcDigests {
+		ids = append(ids, id)
+	}
+	for id := range dstDigests {
+		if _, ok := srcDigests[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	verification := &MigrateVerification{Aggregates: len(ids)}
+	for _, id := range ids {
+		s, d := srcDigests[id], dstDigests[id]
+		verification.Events += s.events
+		if s != d {
+			verification.Mismatches = append(verification.Mismatches, AggregateMismatch{
+				AggregateID:    id,
+				SourceEvents:   s.events,
+				DestEvents:     d.events,
+				SourceChecksum: hex.EncodeToString(s.checksum[:]),
+				DestChecksum:   hex.EncodeToString(d.checksum[:]),
+			})
+		}
+	}
+	return verification, nil
+}
+
+// digestEvents computes the digest of every aggregate in eventStore. Event
+// checksums are combined order-independently, so a store returning an
+// aggregate's events in a different global order still matches.
+func digestEvents(ctx context.Context, eventStore EventStore, batchSize int) (map[string]aggregateDigest, error) {
+	digests := make(map[string]aggregateDigest)
+	position := int64(0)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		events, err := eventStore.LoadAllEvents(ctx, position+1, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			sum, err := eventChecksum(event)
+			if err != nil {
+				return nil, err
+			}
+			digest := digests[event.AggregateID]
+			digest.events++
+			for i := range digest.checksum {
+				digest.checksum[i] ^= sum[i]
+			}
+			digests[event.AggregateID] = digest
+			position = event.Position
+		}
+		if len(events) < batchSize {
+			return digests, nil
+		}
+	}
+}
+
+// eventChecksum hashes the parts of an event a migration must preserve.
+// Timestamps are compared at second precision, the coarsest a store keeps.
+func eventChecksum(event *domain.Event) ([sha256.Size]byte, error) {
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to encode metadata of event %s: %w", event.ID, err)
+	}
+	constraints, err := json.Marshal(event.UniqueConstraints)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to encode constraints of event %s: %w", event.ID, err)
+	}
+
+	h := sha256.New()
+	var buf [8]byte
+	for _, field := range [][]byte{
+		[]byte(event.ID), []byte(event.AggregateID), []byte(event.AggregateType), []byte(event.EventType),
+		event.Data, metadata, constraints,
+	} {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(field)))
+		h.Write(buf[:])
+		h.Write(field)
+	}
+	for _, n := range []int64{event.Version, event.Position, event.Timestamp.Unix()} {
+		binary.BigEndian.PutUint64(buf[:], uint64(n))
+		h.Write(buf[:])
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
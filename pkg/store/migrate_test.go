@@ -0,0 +1,159 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	newStore := func(t *testing.T) *sqlite.EventStore {
+		t.Helper()
+		eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		t.Cleanup(func() { eventStore.Close() })
+		return eventStore
+	}
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	seq := 0
+	event := func(aggregateID string, version int64) *domain.Event {
+		seq++
+		return &domain.Event{
+			ID:            fmt.Sprintf("evt-%03d", seq),
+			AggregateID:   aggregateID,
+			AggregateType: "Account",
+			EventType:     "account.v1.Deposited",
+			Version:       version,
+			Timestamp:     base.Add(time.Duration(seq) * time.Second),
+			Data:          []byte(fmt.Sprintf("amount=%d", seq)),
+			Metadata:      domain.EventMetadata{PrincipalID: "alice", Custom: map[string]string{"seq": fmt.Sprint(seq)}},
+		}
+	}
+
+	src := newStore(t)
+	for version := int64(1); version <= 4; version++ {
+		for _, id := range []string{"acc-1", "acc-2", "acc-3"} {
+			e := event(id, version)
+			if id == "acc-1" && version == 1 {
+				e.UniqueConstraints = []domain.UniqueConstraint{
+					{IndexName: "account_email", Value: "alice@example.com", Operation: domain.ConstraintClaim},
+				}
+			}
+			if err := src.AppendEvents(ctx, id, version-1, []*domain.Event{e}); err != nil {
+				t.Fatalf("failed to append event: %v", err)
+			}
+		}
+	}
+	if _, err := src.AppendEventsIdempotent(ctx, "acc-2", 4, []*domain.Event{event("acc-2", 5)}, "cmd-1", time.Hour); err != nil {
+		t.Fatalf("failed to append idempotently: %v", err)
+	}
+	srcSnapshots := sqlite.NewSnapshotStore(src.DB())
+	if err := srcSnapshots.SaveSnapshot(&store.Snapshot{
+		AggregateID: "acc-1", AggregateType: "Account", Version: 3, Data: []byte("state@3"), CreatedAt: base,
+	}); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+
+	dst := newStore(t)
+	dstSnapshots := sqlite.NewSnapshotStore(dst.DB())
+
+	var progress []store.MigrateProgress
+	report, err := store.Migrate(ctx, src, dst, store.MigrateOptions{
+		BatchSize:       5,
+		SourceSnapshots: srcSnapshots,
+		DestSnapshots:   dstSnapshots,
+		Verify:          true,
+		OnProgress:      func(p store.MigrateProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if report.EventsCopied != 13 || report.Aggregates != 3 || report.SnapshotsCopied != 1 ||
+		report.CommandsCopied != 1 || report.LastPosition != 13 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if v := report.Verification; v == nil || v.Aggregates != 3 || v.Events != 13 || len(v.Mismatches) != 0 {
+		t.Errorf("unexpected verification: %+v", report.Verification)
+	}
+	if len(progress) != 3 || progress[2].EventsCopied != 13 || progress[2].TotalEvents != 13 {
+		t.Errorf("unexpected progress: %+v", progress)
+	}
+
+	// Events keep their IDs, versions, positions and metadata
+	want, err := src.LoadAllEvents(ctx, 0, 100)
+	if err != nil {
+		t.Fatalf("failed to load source events: %v", err)
+	}
+	got, err := dst.LoadAllEvents(ctx, 0, 100)
+	if err != nil {
+		t.Fatalf("failed to load migrated events: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("migrated events differ:\ngot  %+v\nwant %+v", got, want)
+	}
+	if owner, err := dst.GetConstraintOwner("account_email", "alice@example.com"); err != nil || owner != "acc-1" {
+		t.Errorf("expected the claim to be migrated, got %q (%v)", owner, err)
+	}
+	if snapshot, err := dstSnapshots.GetLatestSnapshot("acc-1"); err != nil || string(snapshot.Data) != "state@3" {
+		t.Errorf("expected the snapshot to be migrated, got %+v (%v)", snapshot, err)
+	}
+	if result, err := dst.GetCommandResult("cmd-1"); err != nil || len(result.Events) != 1 {
+		t.Errorf("expected the processed command to be migrated, got %+v (%v)", result, err)
+	}
+
+	// A second bulk copy would duplicate the store
+	if _, err := store.Migrate(ctx, src, dst, store.MigrateOptions{}); err == nil {
+		t.Error("expected migrating into a non-empty store to fail")
+	}
+
+	// Catch up with the events appended since the bulk copy
+	if err := src.AppendEvents(ctx, "acc-3", 4, []*domain.Event{event("acc-3", 5)}); err != nil {
+		t.Fatalf("failed to append event: %v", err)
+	}
+	if err := src.AppendEvents(ctx, "acc-4", 0, []*domain.Event{event("acc-4", 1)}); err != nil {
+		t.Fatalf("failed to append event: %v", err)
+	}
+	final, err := store.Migrate(ctx, src, dst, store.MigrateOptions{
+		CatchUp:      true,
+		FromPosition: report.LastPosition,
+		Verify:       true,
+	})
+	if err != nil {
+		t.Fatalf("failed to catch up: %v", err)
+	}
+	if final.EventsCopied != 2 || final.Aggregates != 2 || final.LastPosition != 15 || len(final.Verification.Mismatches) != 0 {
+		t.Errorf("unexpected catch-up report: %+v", final)
+	}
+
+	// Resuming from an earlier position skips what was already copied
+	resumed, err := store.Migrate(ctx, src, dst, store.MigrateOptions{CatchUp: true, FromPosition: 10})
+	if err != nil {
+		t.Fatalf("failed to resume: %v", err)
+	}
+	if resumed.EventsCopied != 0 || resumed.EventsSkipped != 5 {
+		t.Errorf("unexpected resumed report: %+v", resumed)
+	}
+
+	// Verification catches stores that diverged
+	if err := dst.AppendEvents(ctx, "acc-5", 0, []*domain.Event{event("acc-5", 1)}); err != nil {
+		t.Fatalf("failed to append event: %v", err)
+	}
+	diverged, err := store.Migrate(ctx, src, dst, store.MigrateOptions{CatchUp: true, FromPosition: final.LastPosition, Verify: true})
+	if !errors.Is(err, store.ErrMigrationMismatch) {
+		t.Fatalf("expected ErrMigrationMismatch, got %v", err)
+	}
+	if m := diverged.Verification.Mismatches; len(m) != 1 || m[0].AggregateID != "acc-5" || m[0].SourceEvents != 0 || m[0].DestEvents != 1 {
+		t.Errorf("unexpected mismatches: %+v", m)
+	}
+}
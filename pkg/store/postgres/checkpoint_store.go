@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// CheckpointStore is a PostgreSQL-based implementation of store.CheckpointStore.
+// Use SaveInTx to update a checkpoint in the transaction that updates its
+// projection, so both commit or roll back together.
+type CheckpointStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewCheckpointStore creates a checkpoint store on pool, creating its
+// projection_checkpoints table if needed. Pass the event store's Pool() to keep
+// checkpoints in the events database, or a pool of its own to keep read models
+// elsewhere.
+func NewCheckpointStore(pool *pgxpool.Pool) (*CheckpointStore, error) {
+	_, err := pool.Exec(context.Background(), `
+		CREATE TABLE IF NOT EXISTS projection_checkpoints (
+			projection_name TEXT PRIMARY KEY,
+			position BIGINT NOT NULL,
+			last_event_id TEXT NOT NULL,
+			updated_at BIGINT NOT NULL
+		)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create projection_checkpoints table: %w", classifyError(err))
+	}
+	return &CheckpointStore{pool: pool}, nil
+}
+
+// Pool returns the underlying connection pool for creating transactions.
+func (s *CheckpointStore) Pool() *pgxpool.Pool {
+	return s.pool
+}
+
+// saveCheckpointSQL upserts a checkpoint.
+const saveCheckpointSQL = `
+	INSERT INTO projection_checkpoints (projection_name, position, last_event_id, updated_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (projection_name) DO UPDATE SET
+		position = excluded.position,
+		last_event_id = excluded.last_event_id,
+		updated_at = excluded.updated_at`
+
+// Save saves a checkpoint in its own transaction.
+// WARNING: For atomic projection updates, use SaveInTx instead to avoid dual-write issues.
+func (s *CheckpointStore) Save(checkpoint *store.ProjectionCheckpoint) error {
+	_, err := s.pool.Exec(context.Background(), saveCheckpointSQL,
+		checkpoint.ProjectionName, checkpoint.Position, checkpoint.LastEventID, checkpoint.UpdatedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", classifyError(err))
+	}
+	return nil
+}
+
+// SaveInTx saves a checkpoint within tx, atomically with the projection
+// updates made in it.
+func (s *CheckpointStore) SaveInTx(ctx context.Context, tx pgx.Tx, checkpoint *store.ProjectionCheckpoint) error {
+	_, err := tx.Exec(ctx, saveCheckpointSQL,
+		checkpoint.ProjectionName, checkpoint.Position, checkpoint.LastEventID, checkpoint.UpdatedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint in transaction: %w", contextError(ctx, err))
+	}
+	return nil
+}
+
+// Advance saves checkpoint unless the saved one is at a higher position. It
+// implements store.CheckpointAdvancer.
+func (s *CheckpointStore) Advance(checkpoint *store.ProjectionCheckpoint) error {
+	_, err := s.pool.Exec(context.Background(), saveCheckpointSQL+`
+		WHERE excluded.position > projection_checkpoints.position`,
+		checkpoint.ProjectionName, checkpoint.Position, checkpoint.LastEventID, checkpoint.UpdatedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to advance checkpoint: %w", classifyError(err))
+	}
+	return nil
+}
+
+// Load loads a checkpoint for a projection.
+func (s *CheckpointStore) Load(projectionName string) (*store.ProjectionCheckpoint, error) {
+	checkpoint := store.ProjectionCheckpoint{ProjectionName: projectionName}
+	var updatedAt int64
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT position, last_event_id, updated_at
+		FROM projection_checkpoints
+		WHERE projection_name = $1`, projectionName).
+		Scan(&checkpoint.Position, &checkpoint.LastEventID, &updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w for projection %s", store.ErrCheckpointNotFound, projectionName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", classifyError(err))
+	}
+
+	checkpoint.UpdatedAt = domain.TimeFromUnix(updatedAt)
+	return &checkpoint, nil
+}
+
+// LoadAll returns the checkpoints of all projections, ordered by projection
+// name.
+func (s *CheckpointStore) LoadAll() ([]*store.ProjectionCheckpoint, error) {
+	rows, err := s.pool.Query(context.Background(), `
+		SELECT projection_name, position, last_event_id, updated_at
+		FROM projection_checkpoints
+		ORDER BY projection_name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var checkpoints []*store.ProjectionCheckpoint
+	for rows.Next() {
+		var (
+			checkpoint store.ProjectionCheckpoint
+			updatedAt  int64
+		)
+		if err := rows.Scan(&checkpoint.ProjectionName, &checkpoint.Position, &checkpoint.LastEventID, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint: %w", err)
+		}
+		checkpoint.UpdatedAt = domain.TimeFromUnix(updatedAt)
+		checkpoints = append(checkpoints, &checkpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", classifyError(err))
+	}
+	return checkpoints, nil
+}
+
+// MinPosition returns the lowest position across all checkpoints, or 0 when
+// there are none.
+func (s *CheckpointStore) MinPosition(ctx context.Context) (int64, error) {
+	var position int64
+	err := s.pool.QueryRow(ctx, "SELECT COALESCE(MIN(position), 0) FROM projection_checkpoints").Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get minimum checkpoint position: %w", contextError(ctx, err))
+	}
+	return position, nil
+}
+
+// Delete deletes a checkpoint (for rebuilding).
+func (s *CheckpointStore) Delete(projectionName string) error {
+	_, err := s.pool.Exec(context.Background(), "DELETE FROM projection_checkpoints WHERE projection_name = $1", projectionName)
+	if err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", classifyError(err))
+	}
+	return nil
+}
+
+// Reset deletes the checkpoint of projectionName so the projection replays
+// the event store from the start, keeping its data (see
+// sqlite.CheckpointStore.Reset).
+func (s *CheckpointStore) Reset(projectionName string) error {
+	if err := s.Delete(projectionName); err != nil {
+		return fmt.Errorf("failed to reset checkpoint of %s: %w", projectionName, err)
+	}
+	return nil
+}
+
+// DeleteInTx deletes a checkpoint within tx, e.g. while resetting its
+// projection.
+func (s *CheckpointStore) DeleteInTx(ctx context.Context, tx pgx.Tx, projectionName string) error {
+	_, err := tx.Exec(ctx, "DELETE FROM projection_checkpoints WHERE projection_name = $1", projectionName)
+	if err != nil {
+		return fmt.Errorf("failed to delete checkpoint in transaction: %w", contextError(ctx, err))
+	}
+	return nil
+}
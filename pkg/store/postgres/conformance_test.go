@@ -0,0 +1,16 @@
+package postgres_test
+
+import (
+	"testing"
+
+	storelib "github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/storetest"
+)
+
+// TestEventStoreConformance runs the storetest suite against PostgreSQL. Like
+// the other tests of this package it is skipped unless dsnEnv is set.
+func TestEventStoreConformance(t *testing.T) {
+	storetest.RunEventStoreTests(t, func(t *testing.T) storelib.EventStore {
+		return newTestStore(t)
+	})
+}
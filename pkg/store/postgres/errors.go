@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// PostgreSQL error codes the store classifies (see Appendix A of the
+// PostgreSQL manual).
+const (
+	codeUniqueViolation      = "23505"
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+	codeLockNotAvailable     = "55P03"
+	codeDataCorrupted        = "XX001"
+	codeIndexCorrupted       = "XX002"
+)
+
+// contextError returns ctx.Err() if ctx is done, so a statement aborted by a
+// cancelled or expired context reports the cancellation instead of the driver
+// error it surfaced as, and classifies err otherwise.
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return classifyError(err)
+}
+
+// classifyError maps PostgreSQL error codes to the store error taxonomy.
+// Errors that are already classified, or that do not come from the server, are
+// returned unchanged.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var classified *store.Error
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch pgErr.Code {
+	case codeSerializationFailure, codeDeadlockDetected, codeLockNotAvailable:
+		return &store.Error{Kind: store.ErrBusy, Err: err}
+	case codeDataCorrupted, codeIndexCorrupted:
+		return &store.Error{Kind: store.ErrCorrupt, Err: err}
+	}
+	// Class 23 holds the integrity constraint violations
+	if strings.HasPrefix(pgErr.Code, "23") {
+		return &store.Error{Kind: store.ErrConstraintViolation, Err: err}
+	}
+	return err
+}
+
+// classifyInsertEventError classifies a failed event insert. The events table
+// is unique on event ID and on (aggregate_id, version), so a uniqueness
+// violation means another writer appended the same version first.
+func classifyInsertEventError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == codeUniqueViolation {
+		return &store.Error{Kind: store.ErrConcurrencyConflict, Err: err}
+	}
+	return classifyError(err)
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+)
+
+// InvalidEventPayloadCode is the AppError code returned when an appended
+// event's data does not decode as its declared event type. It matches
+// sqlite.InvalidEventPayloadCode.
+const InvalidEventPayloadCode = "INVALID_EVENT_PAYLOAD"
+
+// ErrMissingTimestamp is returned by ImportEvents for events without a timestamp.
+var ErrMissingTimestamp = errors.New("imported event has no timestamp")
+
+// WithEventValidation decodes every appended event with registry and rejects
+// the append if any event's type is unknown or its data is not a valid
+// message of that type, as sqlite.WithEventValidation does. The error is an
+// *eventsourcing.ResponseError with code INVALID_EVENT_PAYLOAD; nothing from
+// the batch is stored.
+func WithEventValidation(registry domain.EventRegistry) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.eventRegistry = registry
+	}
+}
+
+// validateEvents checks the payload of every event when validation is enabled.
+func (s *EventStore) validateEvents(events []*domain.Event) error {
+	if s.eventRegistry == nil {
+		return nil
+	}
+
+	for _, event := range events {
+		if _, err := s.eventRegistry.Decode(event); err != nil {
+			return &eventsourcing.ResponseError{AppError: &eventsourcing.AppError{
+				Code:     InvalidEventPayloadCode,
+				Message:  fmt.Sprintf("event %s has an invalid payload: %v", event.ID, err),
+				Solution: "Marshal the event data from the proto message registered for its event type",
+				Details: map[string]string{
+					"event_id":     event.ID,
+					"event_type":   event.EventType,
+					"aggregate_id": event.AggregateID,
+				},
+			}}
+		}
+	}
+	return nil
+}
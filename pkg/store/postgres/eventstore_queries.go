@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// eventColumns are the columns scanned by scanEvents, in order.
+const eventColumns = `event_id, aggregate_id, aggregate_type, event_type,
+	version, timestamp, data, metadata, constraints, position`
+
+// GetCommandResult retrieves the result of a previously processed command.
+// Returns nil if the command hasn't been processed or its TTL expired.
+func (s *EventStore) GetCommandResult(commandID string) (*domain.CommandResult, error) {
+	return s.commandResult(context.Background(), s.pool, commandID)
+}
+
+// commandResult loads the unexpired result of commandID through q, or nil.
+func (s *EventStore) commandResult(ctx context.Context, q querier, commandID string) (*domain.CommandResult, error) {
+	var (
+		processedAt  int64
+		eventIDsJSON []byte
+	)
+	err := q.QueryRow(ctx, `
+		SELECT processed_at, event_ids FROM processed_commands
+		WHERE command_id = $1 AND expires_at > $2`,
+		commandID, domain.Now().Unix()).Scan(&processedAt, &eventIDsJSON)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query command: %w", contextError(ctx, err))
+	}
+
+	var eventIDs []string
+	if err := json.Unmarshal(eventIDsJSON, &eventIDs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event IDs: %w", err)
+	}
+
+	rows, err := q.Query(ctx, `SELECT `+eventColumns+`
+		FROM events WHERE event_id = ANY($1) ORDER BY position ASC`, eventIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load command events: %w", contextError(ctx, err))
+	}
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var position int64
+	if len(events) > 0 {
+		position = events[len(events)-1].Position
+	}
+	return &domain.CommandResult{
+		CommandID:        commandID,
+		Events:           events,
+		AlreadyProcessed: true,
+		ProcessedAt:      time.Unix(processedAt, 0),
+		Position:         position,
+	}, nil
+}
+
+// LoadEvents loads all events for an aggregate starting from afterVersion.
+func (s *EventStore) LoadEvents(ctx context.Context, aggregateID string, afterVersion int64) ([]*domain.Event, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+eventColumns+`
+		FROM events
+		WHERE aggregate_id = $1 AND version > $2
+		ORDER BY version ASC`, aggregateID, afterVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", contextError(ctx, err))
+	}
+	return scanEvents(rows)
+}
+
+// LoadAllEvents loads all events from all aggregates for projection building.
+func (s *EventStore) LoadAllEvents(ctx context.Context, fromPosition int64, limit int) ([]*domain.Event, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+eventColumns+`
+		FROM events
+		WHERE position >= $1
+		ORDER BY position ASC
+		LIMIT $2`, fromPosition, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query all events: %w", contextError(ctx, err))
+	}
+	return scanEvents(rows)
+}
+
+// LoadCompensations returns the compensating events recorded for commandID,
+// across all aggregates, in global position order. See domain.Compensator.
+func (s *EventStore) LoadCompensations(commandID string) ([]*domain.Event, error) {
+	rows, err := s.pool.Query(context.Background(), `SELECT `+eventColumns+`
+		FROM events
+		WHERE causation_id = $1
+		  AND metadata->'Custom'->>'`+domain.CompensationKey+`' = 'true'
+		ORDER BY position ASC`, commandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query compensations: %w", classifyError(err))
+	}
+	return scanEvents(rows)
+}
+
+// scanEvents reads events selected with eventColumns and closes rows.
+func scanEvents(rows pgx.Rows) ([]*domain.Event, error) {
+	defer rows.Close()
+
+	var events []*domain.Event
+	for rows.Next() {
+		var (
+			event       domain.Event
+			timestamp   int64
+			metadata    []byte
+			constraints []byte
+		)
+		err := rows.Scan(&event.ID, &event.AggregateID, &event.AggregateType, &event.EventType,
+			&event.Version, &timestamp, &event.Data, &metadata, &constraints, &event.Position)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", classifyError(err))
+		}
+
+		event.Timestamp = time.Unix(timestamp, 0)
+		json.Unmarshal(metadata, &event.Metadata)
+		if len(constraints) > 0 {
+			json.Unmarshal(constraints, &event.UniqueConstraints)
+		}
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", classifyError(err))
+	}
+	return events, nil
+}
+
+// CountEvents returns the total number of events in the store.
+func (s *EventStore) CountEvents() (int64, error) {
+	var count int64
+	if err := s.pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM events").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count events: %w", classifyError(err))
+	}
+	return count, nil
+}
+
+// LatestPosition returns the position of the latest event (0 = none).
+func (s *EventStore) LatestPosition(ctx context.Context) (int64, error) {
+	var position int64
+	if err := s.pool.QueryRow(ctx, "SELECT COALESCE(MAX(position), 0) FROM events").Scan(&position); err != nil {
+		return 0, fmt.Errorf("failed to read latest position: %w", classifyError(err))
+	}
+	return position, nil
+}
+
+// GetAggregateVersion returns the current version of an aggregate.
+func (s *EventStore) GetAggregateVersion(aggregateID string) (int64, error) {
+	version, err := aggregateVersion(context.Background(), s.pool, aggregateID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get aggregate version: %w", classifyError(err))
+	}
+	return version, nil
+}
+
+// CheckUniqueness checks if a value is available for claiming.
+func (s *EventStore) CheckUniqueness(indexName, value string) (available bool, ownerID string, error error) {
+	ownerID, err := constraintOwner(context.Background(), s.pool, indexName, value)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to check uniqueness: %w", classifyError(err))
+	}
+	return ownerID == "", ownerID, nil
+}
+
+// GetConstraintOwner returns the aggregate ID that owns a unique value.
+func (s *EventStore) GetConstraintOwner(indexName, value string) (string, error) {
+	ownerID, err := constraintOwner(context.Background(), s.pool, indexName, value)
+	if err != nil {
+		return "", fmt.Errorf("failed to get constraint owner: %w", classifyError(err))
+	}
+	return ownerID, nil
+}
+
+// RebuildConstraints rebuilds the unique constraint index from the event stream.
+func (s *EventStore) RebuildConstraints() error {
+	ctx := context.Background()
+	tx, err := s.beginAppend(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Clear existing constraints
+	if _, err := tx.Exec(ctx, "DELETE FROM unique_constraints"); err != nil {
+		return fmt.Errorf("failed to clear constraints: %w", classifyError(err))
+	}
+
+	// Replay all events in order
+	rows, err := tx.Query(ctx, `SELECT `+eventColumns+`
+		FROM events
+		WHERE constraints IS NOT NULL
+		ORDER BY position ASC`)
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", classifyError(err))
+	}
+	events, err := scanEvents(rows)
+	if err != nil {
+		return err
+	}
+
+	// Replay claims and releases in append order
+	for _, event := range events {
+		for _, constraint := range event.UniqueConstraints {
+			switch constraint.Operation {
+			case domain.ConstraintClaim:
+				_, err = tx.Exec(ctx, `
+					INSERT INTO unique_constraints (index_name, value, aggregate_id, created_at)
+					VALUES ($1, $2, $3, $4)
+					ON CONFLICT (index_name, value) DO UPDATE SET
+						aggregate_id = EXCLUDED.aggregate_id,
+						created_at = EXCLUDED.created_at`,
+					constraint.IndexName, constraint.Value, event.AggregateID, domain.Now().Unix())
+				if err != nil {
+					return fmt.Errorf("failed to rebuild constraint: %w", classifyError(err))
+				}
+			case domain.ConstraintRelease:
+				_, err = tx.Exec(ctx, `
+					DELETE FROM unique_constraints
+					WHERE index_name = $1 AND value = $2 AND aggregate_id = $3`,
+					constraint.IndexName, constraint.Value, event.AggregateID)
+				if err != nil {
+					return fmt.Errorf("failed to rebuild constraint release: %w", classifyError(err))
+				}
+			}
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CleanExpiredCommands removes expired command records (maintenance operation).
+func (s *EventStore) CleanExpiredCommands() (int64, error) {
+	tag, err := s.pool.Exec(context.Background(),
+		"DELETE FROM processed_commands WHERE expires_at < $1", domain.Now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to clean expired commands: %w", classifyError(err))
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Pool returns the underlying connection pool for direct SQL queries (e.g., projections).
+func (s *EventStore) Pool() *pgxpool.Pool {
+	return s.pool
+}
+
+// Close closes the event store and releases resources. A pool provided with
+// WithPool stays open.
+func (s *EventStore) Close() error {
+	if s.ownsPool {
+		s.pool.Close()
+	}
+	return nil
+}
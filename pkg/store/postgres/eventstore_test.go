@@ -0,0 +1,358 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/plaenen/eventstore/pkg/domain"
+	storelib "github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/postgres"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+// dsnEnv names the environment variable holding the connection string of the
+// PostgreSQL server the tests run against. Without it they are skipped.
+const dsnEnv = "EVENTSTORE_POSTGRES_DSN"
+
+var (
+	_ storelib.EventStore             = (*postgres.EventStore)(nil)
+	_ storelib.EventCounter           = (*postgres.EventStore)(nil)
+	_ storelib.EventImporter          = (*postgres.EventStore)(nil)
+	_ storelib.ProcessedCommandCopier = (*postgres.EventStore)(nil)
+)
+
+// newTestStore opens a store in a fresh schema that is dropped when the test
+// ends.
+func newTestStore(t *testing.T) *postgres.EventStore {
+	t.Helper()
+
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", dsnEnv)
+	}
+	ctx := context.Background()
+
+	admin, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(admin.Close)
+
+	schema := fmt.Sprintf("eventstore_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("failed to parse DSN: %v", err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	store, err := postgres.NewEventStore(postgres.WithPool(pool))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// testEvent returns event version of aggregateID.
+func testEvent(aggregateID string, version int64) *domain.Event {
+	return &domain.Event{
+		ID:            fmt.Sprintf("%s-%d", aggregateID, version),
+		AggregateID:   aggregateID,
+		AggregateType: "TestAggregate",
+		EventType:     "test.Changed",
+		Version:       version,
+		Timestamp:     time.Unix(1700000000+version, 0),
+		Data:          []byte("test data"),
+		Metadata: domain.EventMetadata{
+			CorrelationID: "corr-1",
+			PrincipalID:   "test-user",
+			Custom:        map[string]string{"source": "test"},
+		},
+	}
+}
+
+func TestEventStore(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	t.Run("AppendAndLoadEvents", func(t *testing.T) {
+		events := []*domain.Event{testEvent("agg-1", 1), testEvent("agg-1", 2)}
+		if err := store.AppendEvents(ctx, "agg-1", 0, events); err != nil {
+			t.Fatalf("failed to append events: %v", err)
+		}
+		if events[0].Position == 0 || events[1].Position != events[0].Position+1 {
+			t.Errorf("expected consecutive positions, got %d and %d", events[0].Position, events[1].Position)
+		}
+
+		loaded, err := store.LoadEvents(ctx, "agg-1", 0)
+		if err != nil {
+			t.Fatalf("failed to load events: %v", err)
+		}
+		if len(loaded) != 2 {
+			t.Fatalf("expected 2 events, got %d", len(loaded))
+		}
+		got := loaded[1]
+		if got.ID != "agg-1-2" || got.Version != 2 || string(got.Data) != "test data" {
+			t.Errorf("unexpected event %+v", got)
+		}
+		if !got.Timestamp.Equal(events[1].Timestamp) || got.Position != events[1].Position {
+			t.Errorf("expected timestamp %v and position %d, got %v and %d",
+				events[1].Timestamp, events[1].Position, got.Timestamp, got.Position)
+		}
+		if got.Metadata.CorrelationID != "corr-1" || got.Metadata.Custom["source"] != "test" {
+			t.Errorf("unexpected metadata %+v", got.Metadata)
+		}
+
+		version, err := store.GetAggregateVersion("agg-1")
+		if err != nil || version != 2 {
+			t.Errorf("expected version 2, got %d (%v)", version, err)
+		}
+	})
+
+	t.Run("ConcurrencyConflict", func(t *testing.T) {
+		if err := store.AppendEvents(ctx, "agg-2", 0, []*domain.Event{testEvent("agg-2", 1)}); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+		err := store.AppendEvents(ctx, "agg-2", 0, []*domain.Event{testEvent("agg-2", 1)})
+		if !errors.Is(err, domain.ErrConcurrencyConflict) {
+			t.Errorf("expected concurrency conflict, got %v", err)
+		}
+	})
+
+	t.Run("ConcurrentAppends", func(t *testing.T) {
+		const writers = 8
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			succeeded int
+		)
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := store.AppendEvents(ctx, "agg-race", 0, []*domain.Event{
+					{
+						ID:            fmt.Sprintf("race-%d", i),
+						AggregateID:   "agg-race",
+						AggregateType: "TestAggregate",
+						EventType:     "test.Created",
+						Version:       1,
+						Timestamp:     time.Now(),
+						Data:          []byte("race"),
+					},
+				})
+				if err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				} else if !storelib.IsRetryable(err) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if succeeded != 1 {
+			t.Errorf("expected exactly one writer to succeed, got %d", succeeded)
+		}
+	})
+
+	t.Run("UniqueConstraints", func(t *testing.T) {
+		claim := testEvent("user-1", 1)
+		claim.UniqueConstraints = []domain.UniqueConstraint{
+			{IndexName: "email", Value: "a@example.com", Operation: domain.ConstraintClaim},
+		}
+		if err := store.AppendEvents(ctx, "user-1", 0, []*domain.Event{claim}); err != nil {
+			t.Fatalf("failed to claim: %v", err)
+		}
+
+		conflict := testEvent("user-2", 1)
+		conflict.UniqueConstraints = claim.UniqueConstraints
+		err := store.AppendEvents(ctx, "user-2", 0, []*domain.Event{conflict})
+		if !errors.Is(err, domain.ErrUniqueConstraintViolation) {
+			t.Fatalf("expected unique constraint violation, got %v", err)
+		}
+		if version, _ := store.GetAggregateVersion("user-2"); version != 0 {
+			t.Errorf("expected rejected append to be rolled back, got version %d", version)
+		}
+
+		available, owner, err := store.CheckUniqueness("email", "a@example.com")
+		if err != nil || available || owner != "user-1" {
+			t.Errorf("expected value owned by user-1, got available=%v owner=%q (%v)", available, owner, err)
+		}
+
+		// Re-claiming one's own value is allowed
+		reclaim := testEvent("user-1", 2)
+		reclaim.UniqueConstraints = claim.UniqueConstraints
+		if err := store.AppendEvents(ctx, "user-1", 1, []*domain.Event{reclaim}); err != nil {
+			t.Fatalf("failed to re-claim: %v", err)
+		}
+
+		release := testEvent("user-1", 3)
+		release.UniqueConstraints = []domain.UniqueConstraint{
+			{IndexName: "email", Value: "a@example.com", Operation: domain.ConstraintRelease},
+		}
+		if err := store.AppendEvents(ctx, "user-1", 2, []*domain.Event{release}); err != nil {
+			t.Fatalf("failed to release: %v", err)
+		}
+		if err := store.AppendEvents(ctx, "user-2", 0, []*domain.Event{conflict}); err != nil {
+			t.Fatalf("failed to claim released value: %v", err)
+		}
+
+		if err := store.RebuildConstraints(); err != nil {
+			t.Fatalf("failed to rebuild constraints: %v", err)
+		}
+		owner, err = store.GetConstraintOwner("email", "a@example.com")
+		if err != nil || owner != "user-2" {
+			t.Errorf("expected user-2 to own the value after rebuild, got %q (%v)", owner, err)
+		}
+	})
+
+	t.Run("Idempotency", func(t *testing.T) {
+		events := []*domain.Event{testEvent("agg-3", 1)}
+		first, err := store.AppendEventsIdempotent(ctx, "agg-3", 0, events, "cmd-1", time.Hour)
+		if err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+		if first.AlreadyProcessed {
+			t.Error("expected first append to be processed")
+		}
+
+		second, err := store.AppendEventsIdempotent(ctx, "agg-3", 0, []*domain.Event{testEvent("agg-3", 1)}, "cmd-1", time.Hour)
+		if err != nil {
+			t.Fatalf("failed to retry: %v", err)
+		}
+		if !second.AlreadyProcessed || len(second.Events) != 1 || second.Position != first.Position {
+			t.Errorf("expected cached result, got %+v", second)
+		}
+
+		result, err := store.GetCommandResult("cmd-1")
+		if err != nil || result == nil || result.Events[0].ID != "agg-3-1" {
+			t.Errorf("expected stored command result, got %+v (%v)", result, err)
+		}
+	})
+
+	t.Run("LoadAllEvents", func(t *testing.T) {
+		count, err := store.CountEvents()
+		if err != nil {
+			t.Fatalf("failed to count events: %v", err)
+		}
+
+		all, err := store.LoadAllEvents(ctx, 1, 1000)
+		if err != nil {
+			t.Fatalf("failed to load all events: %v", err)
+		}
+		if int64(len(all)) != count {
+			t.Fatalf("expected %d events, got %d", count, len(all))
+		}
+		for i, event := range all {
+			if event.Position != int64(i+1) {
+				t.Fatalf("expected position %d, got %d", i+1, event.Position)
+			}
+		}
+
+		page, err := store.LoadAllEvents(ctx, 2, 2)
+		if err != nil {
+			t.Fatalf("failed to load page: %v", err)
+		}
+		if len(page) != 2 || page[0].Position != 2 {
+			t.Errorf("expected 2 events from position 2, got %d", len(page))
+		}
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		err := store.AppendEvents(cancelled, "agg-4", 0, []*domain.Event{testEvent("agg-4", 1)})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+		if version, _ := store.GetAggregateVersion("agg-4"); version != 0 {
+			t.Errorf("expected nothing appended, got version %d", version)
+		}
+	})
+
+	t.Run("FailedAppendAssignsNoPositions", func(t *testing.T) {
+		// The second event reuses the ID of an event of agg-1, so its insert
+		// fails after the first one was inserted
+		duplicate := testEvent("agg-5", 2)
+		duplicate.ID = "agg-1-1"
+		events := []*domain.Event{testEvent("agg-5", 1), duplicate}
+		if err := store.AppendEvents(ctx, "agg-5", 0, events); err == nil {
+			t.Fatal("expected the append to fail")
+		}
+		if events[0].Position != 0 || events[1].Position != 0 {
+			t.Errorf("expected no positions after the rollback, got %d and %d", events[0].Position, events[1].Position)
+		}
+	})
+}
+
+func TestMigrateToPostgres(t *testing.T) {
+	ctx := context.Background()
+	dst := newTestStore(t)
+
+	src, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create source store: %v", err)
+	}
+	defer src.Close()
+	if err := src.AppendEvents(ctx, "agg-1", 0, []*domain.Event{testEvent("agg-1", 1), testEvent("agg-1", 2)}); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+	if _, err := src.AppendEventsIdempotent(ctx, "agg-2", 0, []*domain.Event{testEvent("agg-2", 1)}, "cmd-1", time.Hour); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	report, err := storelib.Migrate(ctx, src, dst, storelib.MigrateOptions{Verify: true})
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if report.EventsCopied != 3 || report.CommandsCopied != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	if v := report.Verification; v == nil || len(v.Mismatches) != 0 {
+		t.Errorf("unexpected verification: %+v", report.Verification)
+	}
+	result, err := dst.GetCommandResult("cmd-1")
+	if err != nil || result == nil || len(result.Events) != 1 {
+		t.Errorf("expected the command record to be migrated, got %+v (%v)", result, err)
+	}
+
+	// Imported events keep their timestamps
+	events, err := dst.LoadEvents(ctx, "agg-1", 0)
+	if err != nil {
+		t.Fatalf("failed to load events: %v", err)
+	}
+	if len(events) != 2 || !events[1].Timestamp.Equal(time.Unix(1700000002, 0)) {
+		t.Errorf("expected imported timestamps to be kept, got %+v", events)
+	}
+	if err := dst.ImportEvents("agg-3", 0, []*domain.Event{{ID: "no-time", AggregateID: "agg-3", Version: 1}}); !errors.Is(err, postgres.ErrMissingTimestamp) {
+		t.Errorf("expected ErrMissingTimestamp, got %v", err)
+	}
+}
+
+func TestNewEventStoreInvalidDSN(t *testing.T) {
+	_, err := postgres.NewEventStore(postgres.WithDSN("postgres://%zz"))
+	if err == nil {
+		t.Fatal("expected an invalid DSN to fail")
+	}
+}
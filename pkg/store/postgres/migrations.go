@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockKey is the session advisory lock held while migrations run, so
+// instances starting at the same time do not apply a migration twice.
+const migrationLockKey int64 = 0x65766d6967 // "evmig"
+
+// migration is one schema migration, loaded from a file named like
+// 000001_name.up.sql. Down scripts sit next to it for manual rollbacks.
+type migration struct {
+	version int
+	name    string
+	up      string
+}
+
+// loadMigrations returns the up migrations in dir of fsys in version order.
+func loadMigrations(fsys fs.FS, dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration directory: %w", err)
+	}
+
+	var migrations []migration
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+
+		prefix, rest, ok := strings.Cut(name, "_")
+		if !ok {
+			continue
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file %s: %w", name, err)
+		}
+		migrations = append(migrations, migration{
+			version: version,
+			name:    strings.TrimSuffix(rest, ".up.sql"),
+			up:      string(content),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// runMigrations applies all pending event store migrations, each in its own
+// transaction.
+func runMigrations(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := loadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return migrateSchema(ctx, pool, migrations, "schema_migrations")
+}
+
+// migrateSchema applies the migrations not yet recorded in table, which it
+// creates if needed.
+func migrateSchema(ctx context.Context, pool *pgxpool.Pool, migrations []migration, table string) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+	_, err = conn.Exec(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at BIGINT NOT NULL
+		)`, table))
+	if err != nil {
+		return fmt.Errorf("failed to create table %s: %w", table, err)
+	}
+
+	var current int
+	if err := conn.QueryRow(ctx, fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", table)).Scan(&current); err != nil {
+		return fmt.Errorf("failed to get current version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, conn.Conn(), m, table); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", m.version, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration applies a single migration and records it in table.
+func applyMigration(ctx context.Context, conn *pgx.Conn, m migration, table string) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Without arguments Exec uses the simple protocol, which runs every
+	// statement of the script
+	if _, err := tx.Exec(ctx, m.up); err != nil {
+		return fmt.Errorf("failed to execute migration SQL: %w", err)
+	}
+
+	_, err = tx.Exec(ctx,
+		fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES ($1, $2, $3)", table),
+		m.version, m.name, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,74 @@
+-- Initial schema for event sourcing on PostgreSQL. Timestamps are Unix
+-- seconds, as in the SQLite backend, so events read back identically from
+-- either store.
+
+-- Events table: append-only log of all events
+CREATE TABLE IF NOT EXISTS events (
+    event_id TEXT PRIMARY KEY,
+    aggregate_id TEXT NOT NULL,
+    aggregate_type TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    version BIGINT NOT NULL,
+    timestamp BIGINT NOT NULL,
+    data BYTEA NOT NULL,
+    metadata JSONB NOT NULL,
+    constraints JSONB,
+    position BIGINT NOT NULL,
+    correlation_id TEXT,
+    causation_id TEXT,
+    principal_id TEXT,
+    tenant_id TEXT,
+    UNIQUE (aggregate_id, version)
+);
+
+-- Index for event type filtering
+CREATE INDEX IF NOT EXISTS idx_events_type
+    ON events(event_type);
+
+-- Global event stream
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_position
+    ON events(position);
+
+-- Index for business-time ordered reads
+CREATE INDEX IF NOT EXISTS idx_events_timestamp_position
+    ON events(timestamp, position);
+
+-- Indexes for metadata lookups
+CREATE INDEX IF NOT EXISTS idx_events_causation
+    ON events(causation_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_events_correlation
+    ON events(correlation_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_events_principal
+    ON events(principal_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_events_tenant
+    ON events(tenant_id, position);
+
+-- Unique constraints table: the primary key is the unique index that
+-- enforces claimed values
+CREATE TABLE IF NOT EXISTS unique_constraints (
+    index_name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    created_at BIGINT NOT NULL,
+    PRIMARY KEY (index_name, value)
+);
+
+-- Index for looking up constraint owner
+CREATE INDEX IF NOT EXISTS idx_constraints_aggregate
+    ON unique_constraints(aggregate_id);
+
+-- Processed commands table: idempotency tracking
+CREATE TABLE IF NOT EXISTS processed_commands (
+    command_id TEXT PRIMARY KEY,
+    aggregate_id TEXT NOT NULL,
+    processed_at BIGINT NOT NULL,
+    expires_at BIGINT NOT NULL,
+    event_ids JSONB NOT NULL
+);
+
+-- Index for command expiration cleanup
+CREATE INDEX IF NOT EXISTS idx_commands_expires
+    ON processed_commands(expires_at);
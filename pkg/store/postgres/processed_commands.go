@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// ListProcessedCommands returns up to limit unexpired command idempotency
+// records with a command ID after afterCommandID, ordered by command ID. It
+// implements store.ProcessedCommandCopier.
+func (s *EventStore) ListProcessedCommands(ctx context.Context, afterCommandID string, limit int) ([]*store.ProcessedCommand, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT command_id, aggregate_id, processed_at, expires_at, event_ids
+		FROM processed_commands
+		WHERE command_id > $1 AND expires_at > $2
+		ORDER BY command_id
+		LIMIT $3`, afterCommandID, domain.Now().Unix(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processed commands: %w", contextError(ctx, err))
+	}
+	defer rows.Close()
+
+	var commands []*store.ProcessedCommand
+	for rows.Next() {
+		var (
+			record                 = &store.ProcessedCommand{}
+			processedAt, expiresAt int64
+			eventIDs               []byte
+		)
+		if err := rows.Scan(&record.CommandID, &record.AggregateID, &processedAt, &expiresAt, &eventIDs); err != nil {
+			return nil, fmt.Errorf("failed to scan processed command: %w", classifyError(err))
+		}
+		if err := json.Unmarshal(eventIDs, &record.EventIDs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event IDs of command %s: %w", record.CommandID, err)
+		}
+		record.ProcessedAt = time.Unix(processedAt, 0)
+		record.ExpiresAt = time.Unix(expiresAt, 0)
+		commands = append(commands, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list processed commands: %w", contextError(ctx, err))
+	}
+
+	return commands, nil
+}
+
+// ImportProcessedCommands stores command idempotency records, e.g. copied
+// from another store by store.Migrate, replacing records with the same
+// command ID. It implements store.ProcessedCommandCopier.
+func (s *EventStore) ImportProcessedCommands(ctx context.Context, commands []*store.ProcessedCommand) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", contextError(ctx, err))
+	}
+	defer tx.Rollback(context.Background())
+
+	for _, command := range commands {
+		eventIDs, err := json.Marshal(command.EventIDs)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event IDs of command %s: %w", command.CommandID, err)
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO processed_commands (command_id, aggregate_id, processed_at, expires_at, event_ids)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (command_id) DO UPDATE SET
+				aggregate_id = EXCLUDED.aggregate_id,
+				processed_at = EXCLUDED.processed_at,
+				expires_at = EXCLUDED.expires_at,
+				event_ids = EXCLUDED.event_ids`,
+			command.CommandID, command.AggregateID, command.ProcessedAt.Unix(), command.ExpiresAt.Unix(), string(eventIDs))
+		if err != nil {
+			return fmt.Errorf("failed to import command %s: %w", command.CommandID, contextError(ctx, err))
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", contextError(ctx, err))
+	}
+	return nil
+}
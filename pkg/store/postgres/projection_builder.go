@@ -0,0 +1,394 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// TransactionalEventHandler is a handler that receives a transaction to work with.
+// The transaction, checkpoint update, and commit are handled automatically.
+type TransactionalEventHandler func(ctx context.Context, tx pgx.Tx, envelope *domain.EventEnvelope) error
+
+// PostgresProjectionBuilder provides a high-level builder for PostgreSQL
+// projections with automatic transaction handling, checkpoint management, and
+// rebuild support. It mirrors sqlite.SQLiteProjectionBuilder, so a projection
+// moves between the backends by swapping the builder and its SQL.
+type PostgresProjectionBuilder struct {
+	name            string
+	pool            *pgxpool.Pool
+	checkpointStore *CheckpointStore
+	statusStore     *ProjectionStatusStore
+	eventStore      store.EventStore
+	handlers        map[string][]TransactionalEventHandler // In registration order
+	resetFunc       func(context.Context, pgx.Tx) error
+	migrationsFS    fs.FS
+	migrationsPath  string
+}
+
+// NewPostgresProjectionBuilder creates a new PostgreSQL-specific projection
+// builder. The projection's tables live in pool's database, which must also
+// hold checkpointStore's table so checkpoints commit with the read model.
+//
+// Example:
+//
+//	projection, err := postgres.NewPostgresProjectionBuilder("account-balance", pool, checkpointStore, eventStore).
+//	    WithMigrations(migrationsFS, "migrations").
+//	    On(accountv1.OnAccountOpened(func(ctx context.Context, event *accountv1.AccountOpenedEvent, envelope *domain.EventEnvelope) error {
+//	        tx, _ := postgres.TxFromContext(ctx)
+//	        _, err := tx.Exec(ctx, "INSERT INTO accounts (id, balance) VALUES ($1, $2)", event.AccountId, event.InitialBalance)
+//	        return err
+//	    })).
+//	    OnReset(func(ctx context.Context, tx pgx.Tx) error {
+//	        _, err := tx.Exec(ctx, "DELETE FROM accounts")
+//	        return err
+//	    }).
+//	    Build()
+func NewPostgresProjectionBuilder(
+	name string,
+	pool *pgxpool.Pool,
+	checkpointStore *CheckpointStore,
+	eventStore store.EventStore,
+) *PostgresProjectionBuilder {
+	return &PostgresProjectionBuilder{
+		name:            name,
+		pool:            pool,
+		checkpointStore: checkpointStore,
+		eventStore:      eventStore,
+		handlers:        make(map[string][]TransactionalEventHandler),
+	}
+}
+
+// WithStatusStore makes the projection record its status in statusStore
+// instead of a store of its own on the projection's pool.
+func (b *PostgresProjectionBuilder) WithStatusStore(statusStore *ProjectionStatusStore) *PostgresProjectionBuilder {
+	b.statusStore = statusStore
+	return b
+}
+
+// WithMigrations registers versioned schema migrations for the projection,
+// applied during Build. Files in path are named like 000001_name.up.sql, as
+// for the event store; each runs in its own transaction. Applied versions are
+// tracked per projection in a projection_{name}_schema_migrations table.
+func (b *PostgresProjectionBuilder) WithMigrations(migrationsFS fs.FS, path string) *PostgresProjectionBuilder {
+	b.migrationsFS = migrationsFS
+	b.migrationsPath = path
+	return b
+}
+
+// On registers a typed event handler. The handler finds the event's
+// transaction with TxFromContext. Several handlers may be registered for one
+// event type; they run in registration order in the same transaction.
+func (b *PostgresProjectionBuilder) On(registration store.EventHandlerRegistration) *PostgresProjectionBuilder {
+	b.addHandler(domain.CanonicalEventType(registration.EventType), func(ctx context.Context, tx pgx.Tx, envelope *domain.EventEnvelope) error {
+		return registration.Handler(context.WithValue(ctx, txContextKey{}, tx), envelope)
+	})
+	return b
+}
+
+// OnWithTx registers a handler that directly receives the transaction.
+// Like On, it adds to the handlers already registered for eventType.
+func (b *PostgresProjectionBuilder) OnWithTx(eventType string, handler TransactionalEventHandler) *PostgresProjectionBuilder {
+	b.addHandler(domain.CanonicalEventType(eventType), handler)
+	return b
+}
+
+// addHandler registers handler after the handlers of eventType.
+func (b *PostgresProjectionBuilder) addHandler(eventType string, handler TransactionalEventHandler) {
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// OnReset registers a function to reset the projection state.
+// The function receives a transaction to perform the reset.
+func (b *PostgresProjectionBuilder) OnReset(resetFunc func(context.Context, pgx.Tx) error) *PostgresProjectionBuilder {
+	b.resetFunc = resetFunc
+	return b
+}
+
+// Build applies the projection's migrations and creates the projection.
+func (b *PostgresProjectionBuilder) Build() (*PostgresProjection, error) {
+	if b.checkpointStore == nil {
+		return nil, fmt.Errorf("projection %s needs a checkpoint store", b.name)
+	}
+	ctx := context.Background()
+
+	if b.migrationsFS != nil {
+		if err := runProjectionMigrations(ctx, b.pool, b.migrationsFS, b.migrationsPath, b.name); err != nil {
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
+	}
+
+	statusStore := b.statusStore
+	if statusStore == nil {
+		var err error
+		if statusStore, err = NewProjectionStatusStore(b.pool); err != nil {
+			return nil, err
+		}
+	}
+
+	handlers := make(map[string]TransactionalEventHandler, len(b.handlers))
+	for eventType, registered := range b.handlers {
+		handlers[eventType] = func(ctx context.Context, tx pgx.Tx, envelope *domain.EventEnvelope) error {
+			for _, handler := range registered {
+				if err := handler(ctx, tx, envelope); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	projection := &PostgresProjection{
+		name:            b.name,
+		pool:            b.pool,
+		checkpointStore: b.checkpointStore,
+		statusStore:     statusStore,
+		eventStore:      b.eventStore,
+		handlers:        handlers,
+		resetFunc:       b.resetFunc,
+	}
+
+	// Set initial status to READY
+	_ = statusStore.Save(&store.ProjectionState{
+		ProjectionName: b.name,
+		Status:         store.ProjectionStatusReady,
+		UpdatedAt:      domain.Now(),
+	})
+
+	return projection, nil
+}
+
+// PostgresProjection implements store.Projection on PostgreSQL: each event is
+// applied in a transaction that also moves the projection's checkpoint.
+type PostgresProjection struct {
+	name            string
+	pool            *pgxpool.Pool
+	checkpointStore *CheckpointStore
+	statusStore     *ProjectionStatusStore
+	eventStore      store.EventStore
+	handlers        map[string]TransactionalEventHandler
+	resetFunc       func(context.Context, pgx.Tx) error
+}
+
+// Name returns the projection name.
+func (p *PostgresProjection) Name() string {
+	return p.name
+}
+
+// Handle processes an event with automatic transaction and checkpoint management.
+func (p *PostgresProjection) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
+	handler, exists := p.handlers[domain.CanonicalEventType(envelope.EventType)]
+	if !exists {
+		// No handler registered for this event type - skip it
+		return nil
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", contextError(ctx, err))
+	}
+	defer tx.Rollback(context.Background()) // Rollback if we don't commit
+
+	if err := handler(ctx, tx, envelope); err != nil {
+		return fmt.Errorf("handler failed: %w", err)
+	}
+
+	position := envelope.Position
+	if position == 0 {
+		// Event was never persisted (e.g., handled directly in tests)
+		position = envelope.Version
+	}
+	err = p.checkpointStore.SaveInTx(ctx, tx, &store.ProjectionCheckpoint{
+		ProjectionName: p.name,
+		Position:       position,
+		LastEventID:    envelope.ID,
+		UpdatedAt:      domain.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", contextError(ctx, err))
+	}
+	return nil
+}
+
+// Reset runs the OnReset function and deletes the checkpoint in one
+// transaction. Without an OnReset function it does nothing.
+func (p *PostgresProjection) Reset(ctx context.Context) error {
+	if p.resetFunc == nil {
+		return nil // No reset function registered
+	}
+
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", contextError(ctx, err))
+	}
+	defer tx.Rollback(context.Background())
+
+	if err := p.resetFunc(ctx, tx); err != nil {
+		return fmt.Errorf("reset failed: %w", err)
+	}
+	if err := p.checkpointStore.DeleteInTx(ctx, tx, p.name); err != nil {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit reset: %w", contextError(ctx, err))
+	}
+	return nil
+}
+
+// Rebuild resets the projection and replays all events from the event store,
+// tracking its status as SQLiteProjection.Rebuild does: REBUILDING with
+// progress every 100 events, then READY, or FAILED with the cause. By default
+// an event a handler fails on aborts the rebuild; use store.OnEventError to
+// skip or quarantine such events instead.
+func (p *PostgresProjection) Rebuild(ctx context.Context, opts ...store.RebuildOption) error {
+	options, err := store.NewRebuildOptions(opts...)
+	if err != nil {
+		return err
+	}
+	if err := options.Start(ctx, p.name); err != nil {
+		return err
+	}
+	failures := store.RebuildReport{ProjectionName: p.name}
+	startedAt := domain.Now()
+
+	var totalEvents int64
+	if counter, ok := p.eventStore.(store.EventCounter); ok {
+		if count, err := counter.CountEvents(); err == nil {
+			totalEvents = count
+		}
+	}
+
+	err = p.statusStore.Save(&store.ProjectionState{
+		ProjectionName: p.name,
+		Status:         store.ProjectionStatusRebuilding,
+		Message:        "Starting rebuild from event store",
+		UpdatedAt:      domain.Now(),
+		Progress: &store.RebuildProgress{
+			TotalEvents: totalEvents,
+			StartedAt:   startedAt,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save rebuilding status: %w", err)
+	}
+	fail := func(message string) {
+		_ = p.statusStore.Save(&store.ProjectionState{
+			ProjectionName: p.name,
+			Status:         store.ProjectionStatusFailed,
+			Message:        message,
+			UpdatedAt:      domain.Now(),
+		})
+	}
+
+	if err := p.Reset(ctx); err != nil {
+		fail(fmt.Sprintf("Reset failed: %v", err))
+		return fmt.Errorf("failed to reset projection: %w", err)
+	}
+
+	// Replay all events; handlers see domain.IsReplay(ctx)
+	ctx = domain.WithReplay(ctx)
+	var eventsProcessed int64
+	for event, err := range store.StreamAllEvents(ctx, p.eventStore, 1, rebuildBatchSize) {
+		if err != nil {
+			if ctx.Err() != nil {
+				fail(fmt.Sprintf("Rebuild cancelled: %v", ctx.Err()))
+				return fmt.Errorf("rebuild cancelled: %w", ctx.Err())
+			}
+			fail(fmt.Sprintf("Failed to load events: %v", err))
+			return fmt.Errorf("failed to load events: %w", err)
+		}
+
+		if err := p.Handle(ctx, &domain.EventEnvelope{Event: *event}); err != nil {
+			if err := options.HandleEventError(ctx, event, err, &failures); err != nil {
+				fail(fmt.Sprintf("Failed to handle event: %v", err))
+				return err
+			}
+		}
+
+		eventsProcessed++
+		if eventsProcessed%100 == 0 {
+			_ = p.statusStore.UpdateProgress(p.name, &store.RebuildProgress{
+				EventsProcessed: eventsProcessed,
+				TotalEvents:     totalEvents,
+				StartedAt:       startedAt,
+			})
+		}
+	}
+
+	message := fmt.Sprintf("Rebuild complete - processed %d events", eventsProcessed)
+	if summary := failures.Summary(); summary != "" {
+		message += ", " + summary
+	}
+	_ = p.statusStore.Save(&store.ProjectionState{
+		ProjectionName: p.name,
+		Status:         store.ProjectionStatusReady,
+		Message:        message,
+		UpdatedAt:      domain.Now(),
+	})
+
+	failures.EventsProcessed = eventsProcessed
+	options.Complete(failures)
+	return nil
+}
+
+// rebuildBatchSize is how many events a rebuild loads at a time from event
+// stores that cannot stream them one by one.
+const rebuildBatchSize = 1000
+
+// GetCheckpoint returns the current checkpoint position.
+func (p *PostgresProjection) GetCheckpoint(ctx context.Context) (*store.ProjectionCheckpoint, error) {
+	return p.checkpointStore.Load(p.name)
+}
+
+// GetStatus returns the current projection status.
+func (p *PostgresProjection) GetStatus(ctx context.Context) (*store.ProjectionState, error) {
+	return p.statusStore.Load(p.name)
+}
+
+// IsReady returns true if the projection is ready to serve queries.
+func (p *PostgresProjection) IsReady(ctx context.Context) bool {
+	status, err := p.GetStatus(ctx)
+	if err != nil {
+		return false
+	}
+	return status.Status == store.ProjectionStatusReady
+}
+
+// txContextKey is used to pass the transaction through context
+type txContextKey struct{}
+
+// TxFromContext extracts the transaction of the event being handled from a
+// context passed to a handler registered with On. pgx.Tx is an interface, so
+// unlike sqlite.TxFromContext it returns the transaction itself rather than a
+// pointer.
+func TxFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txContextKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// unsafeIdentifierChars matches characters replaced in projection names to
+// form table names.
+var unsafeIdentifierChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// runProjectionMigrations applies the migrations in path of migrationsFS,
+// tracking them in a projection_{name}_schema_migrations table so they are
+// kept apart from the event store's and other projections' migrations.
+func runProjectionMigrations(ctx context.Context, pool *pgxpool.Pool, migrationsFS fs.FS, path string, projectionName string) error {
+	migrations, err := loadMigrations(migrationsFS, path)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	table := fmt.Sprintf("projection_%s_schema_migrations", unsafeIdentifierChars.ReplaceAllString(projectionName, "_"))
+	return migrateSchema(ctx, pool, migrations, table)
+}
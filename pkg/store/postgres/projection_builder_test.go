@@ -0,0 +1,178 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/plaenen/eventstore/pkg/domain"
+	storelib "github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/postgres"
+)
+
+var (
+	_ storelib.CheckpointStore       = (*postgres.CheckpointStore)(nil)
+	_ storelib.ProjectionStatusStore = (*postgres.ProjectionStatusStore)(nil)
+	_ storelib.Projection            = (*postgres.PostgresProjection)(nil)
+)
+
+// counterMigrations creates the table of the test projection.
+var counterMigrations = fstest.MapFS{
+	"migrations/000001_counters.up.sql": {Data: []byte(
+		"CREATE TABLE counters (aggregate_id TEXT PRIMARY KEY, count BIGINT NOT NULL);")},
+}
+
+// newCounterProjection builds a projection counting events per aggregate.
+// Events with the data "fail" make its handler fail.
+func newCounterProjection(t *testing.T, store *postgres.EventStore) *postgres.PostgresProjection {
+	t.Helper()
+
+	checkpointStore, err := postgres.NewCheckpointStore(store.Pool())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+	projection, err := postgres.NewPostgresProjectionBuilder("test-counter", store.Pool(), checkpointStore, store).
+		WithMigrations(counterMigrations, "migrations").
+		On(storelib.EventHandlerRegistration{
+			EventType: "test.Changed",
+			Handler: func(ctx context.Context, envelope *domain.EventEnvelope) error {
+				if string(envelope.Data) == "fail" {
+					return errors.New("bad event")
+				}
+				tx, ok := postgres.TxFromContext(ctx)
+				if !ok {
+					return errors.New("no transaction in context")
+				}
+				_, err := tx.Exec(ctx, `
+					INSERT INTO counters (aggregate_id, count) VALUES ($1, 1)
+					ON CONFLICT (aggregate_id) DO UPDATE SET count = counters.count + 1`, envelope.AggregateID)
+				return err
+			},
+		}).
+		OnReset(func(ctx context.Context, tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, "DELETE FROM counters")
+			return err
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build projection: %v", err)
+	}
+	return projection
+}
+
+// counterOf returns the count of aggregateID, or 0 if it has none.
+func counterOf(t *testing.T, store *postgres.EventStore, aggregateID string) int64 {
+	t.Helper()
+	var count int64
+	err := store.Pool().QueryRow(context.Background(), "SELECT count FROM counters WHERE aggregate_id = $1", aggregateID).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0
+	}
+	if err != nil {
+		t.Fatalf("failed to read counter: %v", err)
+	}
+	return count
+}
+
+func TestPostgresProjection(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	projection := newCounterProjection(t, store)
+
+	events := []*domain.Event{testEvent("agg-1", 1), testEvent("agg-1", 2)}
+	if err := store.AppendEvents(ctx, "agg-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	t.Run("Handle", func(t *testing.T) {
+		for _, event := range events {
+			if err := projection.Handle(ctx, &domain.EventEnvelope{Event: *event}); err != nil {
+				t.Fatalf("failed to handle event: %v", err)
+			}
+		}
+		if count := counterOf(t, store, "agg-1"); count != 2 {
+			t.Errorf("expected count 2, got %d", count)
+		}
+		checkpoint, err := projection.GetCheckpoint(ctx)
+		if err != nil {
+			t.Fatalf("failed to load checkpoint: %v", err)
+		}
+		if checkpoint.Position != events[1].Position || checkpoint.LastEventID != events[1].ID {
+			t.Errorf("expected checkpoint at %d (%s), got %+v", events[1].Position, events[1].ID, checkpoint)
+		}
+	})
+
+	t.Run("HandlerErrorRollsBack", func(t *testing.T) {
+		bad := testEvent("agg-1", 3)
+		bad.Data = []byte("fail")
+		bad.Position = events[1].Position + 1
+		if err := projection.Handle(ctx, &domain.EventEnvelope{Event: *bad}); err == nil {
+			t.Fatal("expected the handler error to be returned")
+		}
+		checkpoint, err := projection.GetCheckpoint(ctx)
+		if err != nil {
+			t.Fatalf("failed to load checkpoint: %v", err)
+		}
+		if checkpoint.Position != events[1].Position {
+			t.Errorf("expected the checkpoint to stay at %d, got %d", events[1].Position, checkpoint.Position)
+		}
+	})
+
+	t.Run("Rebuild", func(t *testing.T) {
+		if err := store.AppendEvents(ctx, "agg-2", 0, []*domain.Event{testEvent("agg-2", 1)}); err != nil {
+			t.Fatalf("failed to append event: %v", err)
+		}
+		if err := projection.Rebuild(ctx); err != nil {
+			t.Fatalf("failed to rebuild: %v", err)
+		}
+		if a, b := counterOf(t, store, "agg-1"), counterOf(t, store, "agg-2"); a != 2 || b != 1 {
+			t.Errorf("expected counts 2 and 1 after rebuild, got %d and %d", a, b)
+		}
+		status, err := projection.GetStatus(ctx)
+		if err != nil {
+			t.Fatalf("failed to load status: %v", err)
+		}
+		if status.Status != storelib.ProjectionStatusReady {
+			t.Errorf("expected READY after rebuild, got %+v", status)
+		}
+	})
+
+	t.Run("RebuildFailure", func(t *testing.T) {
+		bad := testEvent("agg-3", 1)
+		bad.Data = []byte("fail")
+		if err := store.AppendEvents(ctx, "agg-3", 0, []*domain.Event{bad}); err != nil {
+			t.Fatalf("failed to append event: %v", err)
+		}
+		if err := projection.Rebuild(ctx); err == nil {
+			t.Fatal("expected the rebuild to fail")
+		}
+		if projection.IsReady(ctx) {
+			t.Error("expected the projection not to be ready after a failed rebuild")
+		}
+
+		if err := projection.Rebuild(ctx, storelib.OnEventError(storelib.RebuildSkip)); err != nil {
+			t.Fatalf("failed to rebuild skipping bad events: %v", err)
+		}
+		if !projection.IsReady(ctx) {
+			t.Error("expected the projection to be ready again")
+		}
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		if err := projection.Reset(ctx); err != nil {
+			t.Fatalf("failed to reset: %v", err)
+		}
+		if count := counterOf(t, store, "agg-1"); count != 0 {
+			t.Errorf("expected the read model cleared, got count %d", count)
+		}
+		if _, err := projection.GetCheckpoint(ctx); err == nil {
+			t.Error("expected the checkpoint to be deleted")
+		}
+	})
+
+	t.Run("MigrationsApplyOnce", func(t *testing.T) {
+		newCounterProjection(t, store)
+	})
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// ProjectionStatusStore implements store.ProjectionStatusStore for PostgreSQL.
+type ProjectionStatusStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewProjectionStatusStore creates a projection status store on pool,
+// creating its projection_status table if needed.
+func NewProjectionStatusStore(pool *pgxpool.Pool) (*ProjectionStatusStore, error) {
+	_, err := pool.Exec(context.Background(), `
+		CREATE TABLE IF NOT EXISTS projection_status (
+			projection_name TEXT PRIMARY KEY,
+			status TEXT NOT NULL,
+			message TEXT,
+			updated_at BIGINT NOT NULL,
+			progress_json JSONB
+		)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create projection_status table: %w", classifyError(err))
+	}
+	return &ProjectionStatusStore{pool: pool}, nil
+}
+
+// Save saves the projection status.
+func (s *ProjectionStatusStore) Save(state *store.ProjectionState) error {
+	var progressJSON []byte
+	if state.Progress != nil {
+		data, err := json.Marshal(state.Progress)
+		if err != nil {
+			return fmt.Errorf("failed to marshal progress: %w", err)
+		}
+		progressJSON = data
+	}
+
+	_, err := s.pool.Exec(context.Background(), `
+		INSERT INTO projection_status (projection_name, status, message, updated_at, progress_json)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (projection_name) DO UPDATE SET
+			status = excluded.status,
+			message = excluded.message,
+			updated_at = excluded.updated_at,
+			progress_json = excluded.progress_json`,
+		state.ProjectionName, string(state.Status), state.Message, state.UpdatedAt.Unix(), progressJSON)
+	if err != nil {
+		return fmt.Errorf("failed to save projection status: %w", classifyError(err))
+	}
+	return nil
+}
+
+// Load loads the projection status. A projection without a saved status is
+// reported as ready.
+func (s *ProjectionStatusStore) Load(projectionName string) (*store.ProjectionState, error) {
+	var (
+		status       string
+		message      *string
+		updatedAt    int64
+		progressJSON []byte
+	)
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT status, message, updated_at, progress_json
+		FROM projection_status
+		WHERE projection_name = $1`, projectionName).
+		Scan(&status, &message, &updatedAt, &progressJSON)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &store.ProjectionState{
+			ProjectionName: projectionName,
+			Status:         store.ProjectionStatusReady,
+			UpdatedAt:      domain.Now(),
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load projection status: %w", classifyError(err))
+	}
+
+	state := &store.ProjectionState{
+		ProjectionName: projectionName,
+		Status:         store.ProjectionStatus(status),
+		UpdatedAt:      domain.TimeFromUnix(updatedAt),
+	}
+	if message != nil {
+		state.Message = *message
+	}
+	if progressJSON != nil {
+		var progress store.RebuildProgress
+		if err := json.Unmarshal(progressJSON, &progress); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal progress: %w", err)
+		}
+		state.Progress = &progress
+	}
+	return state, nil
+}
+
+// UpdateProgress updates rebuild progress.
+func (s *ProjectionStatusStore) UpdateProgress(projectionName string, progress *store.RebuildProgress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress: %w", err)
+	}
+
+	_, err = s.pool.Exec(context.Background(), `
+		UPDATE projection_status
+		SET progress_json = $1, updated_at = $2
+		WHERE projection_name = $3`, data, domain.Now().Unix(), projectionName)
+	if err != nil {
+		return fmt.Errorf("failed to update progress: %w", classifyError(err))
+	}
+	return nil
+}
@@ -35,6 +35,10 @@ const (
 
 	// ProjectionStatusPaused indicates the projection is paused (not processing events)
 	ProjectionStatusPaused ProjectionStatus = "PAUSED"
+
+	// ProjectionStatusStopped indicates the projection is not running: it was
+	// stopped, or never started
+	ProjectionStatusStopped ProjectionStatus = "STOPPED"
 )
 
 // ProjectionState tracks the operational state of a projection.
@@ -51,6 +55,7 @@ type RebuildProgress struct {
 	EventsProcessed int64
 	TotalEvents     int64 // 0 if unknown
 	StartedAt       time.Time
+	EventsPerSecond float64    // Average processing rate since StartedAt
 	EstimatedETA    *time.Time // nil if can't estimate
 }
 
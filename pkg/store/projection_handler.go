@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrHandlerTimeout is returned when a projection handler exceeds its
+// timeout. It is retryable: the event should be redelivered.
+var ErrHandlerTimeout = errors.New("projection handler timed out")
+
+// ProjectionMetrics receives projection handler health signals.
+// *observability.Metrics implements it.
+type ProjectionMetrics interface {
+	// RecordProjectionHandlerSlow records a handler call that took at least
+	// the slow-handler threshold.
+	RecordProjectionHandlerSlow(ctx context.Context, projectionName, eventType string, duration time.Duration)
+}
+
+// HandlerLimits bounds projection handler calls. The zero value imposes no
+// limits. Projection builders configure it through WithHandlerTimeout and
+// WithSlowHandlerWarning.
+type HandlerLimits struct {
+	// Timeout cancels the handler's context after this long and fails the
+	// call with ErrHandlerTimeout (0 = no timeout)
+	Timeout time.Duration
+
+	// SlowThreshold logs a warning and reports to Metrics when a call takes
+	// at least this long (0 = no slow-handler detection)
+	SlowThreshold time.Duration
+
+	// Metrics receives slow-handler reports (optional)
+	Metrics ProjectionMetrics
+
+	// Logger receives slow-handler warnings (nil = slog.Default())
+	Logger *slog.Logger
+}
+
+// Run calls handler for one event within the limits.
+//
+// On timeout, Run returns ErrHandlerTimeout without waiting for the handler,
+// so a handler blocked on I/O that ignores its context cannot stall the
+// projection; it keeps running in the background until it returns. Handlers
+// should honor ctx so a timed-out call stops its work.
+func (l HandlerLimits) Run(ctx context.Context, projectionName, eventType string, handler func(context.Context) error) error {
+	if l.Timeout <= 0 && l.SlowThreshold <= 0 {
+		return handler(ctx)
+	}
+
+	started := time.Now()
+	var err error
+	if l.Timeout > 0 {
+		err = runWithTimeout(ctx, l.Timeout, handler)
+	} else {
+		err = handler(ctx)
+	}
+
+	if elapsed := time.Since(started); l.SlowThreshold > 0 && elapsed >= l.SlowThreshold {
+		logger := l.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Warn("slow projection handler",
+			"projection", projectionName, "event_type", eventType,
+			"duration", elapsed.Round(time.Millisecond), "threshold", l.SlowThreshold)
+		if l.Metrics != nil {
+			l.Metrics.RecordProjectionHandlerSlow(ctx, projectionName, eventType, elapsed)
+		}
+	}
+
+	return err
+}
+
+// runWithTimeout runs handler with a deadline, returning as soon as the
+// deadline passes.
+func runWithTimeout(parent context.Context, timeout time.Duration, handler func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %s: %w", ErrHandlerTimeout, timeout, err)
+		}
+		return err
+	case <-ctx.Done():
+		if err := parent.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w after %s", ErrHandlerTimeout, timeout)
+	}
+}
@@ -0,0 +1,34 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+func TestHandlerLimits_SlowHandlerLogger(t *testing.T) {
+	var logs bytes.Buffer
+	limits := store.HandlerLimits{
+		SlowThreshold: time.Millisecond,
+		Logger:        slog.New(slog.NewTextHandler(&logs, nil)),
+	}
+
+	err := limits.Run(context.Background(), "balances", "account.v1.Deposited", func(ctx context.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+
+	for _, want := range []string{"level=WARN", "projection=balances", "event_type=account.v1.Deposited", "duration=", "threshold=1ms"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected the warning to contain %q, got %q", want, logs.String())
+		}
+	}
+}
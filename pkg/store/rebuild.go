@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// RebuildErrorPolicy decides what a rebuild does with an event a projection
+// handler fails on.
+type RebuildErrorPolicy int
+
+const (
+	// RebuildFail aborts the rebuild on the first failing event (the default).
+	RebuildFail RebuildErrorPolicy = iota
+
+	// RebuildSkip logs the failing event and continues past it (see
+	// WithRebuildLogger).
+	RebuildSkip
+
+	// RebuildQuarantine records the failing event in a QuarantineStore for
+	// later inspection and continues past it.
+	RebuildQuarantine
+)
+
+// String returns the policy name.
+func (p RebuildErrorPolicy) String() string {
+	switch p {
+	case RebuildFail:
+		return "fail"
+	case RebuildSkip:
+		return "skip"
+	case RebuildQuarantine:
+		return "quarantine"
+	default:
+		return fmt.Sprintf("RebuildErrorPolicy(%d)", int(p))
+	}
+}
+
+// QuarantinedEvent is an event a projection failed to handle during a rebuild
+// with the RebuildQuarantine policy.
+type QuarantinedEvent struct {
+	ProjectionName string
+	EventID        string
+	Position       int64
+	AggregateID    string
+	EventType      string
+	Error          string
+	QuarantinedAt  time.Time
+}
+
+// QuarantineStore keeps the events projections failed to handle during a
+// rebuild.
+type QuarantineStore interface {
+	// Quarantine records a failed event. Quarantining the same event for the
+	// same projection again replaces the earlier record.
+	Quarantine(ctx context.Context, event *QuarantinedEvent) error
+
+	// ListQuarantined returns the quarantined events of projectionName in
+	// event store order.
+	ListQuarantined(ctx context.Context, projectionName string) ([]*QuarantinedEvent, error)
+
+	// ClearQuarantine removes the quarantined events of projectionName.
+	ClearQuarantine(ctx context.Context, projectionName string) error
+}
+
+// RebuildReport summarizes the events a rebuild could not apply.
+type RebuildReport struct {
+	ProjectionName  string
+	EventsProcessed int64
+	Skipped         []string // IDs of skipped events
+	Quarantined     []string // IDs of quarantined events
+}
+
+// RebuildOptions configures how a projection rebuild handles failing events.
+// Build it with NewRebuildOptions.
+type RebuildOptions struct {
+	policy     RebuildErrorPolicy
+	quarantine QuarantineStore
+	report     func(RebuildReport)
+	logger     *slog.Logger
+}
+
+// RebuildOption configures a projection rebuild.
+type RebuildOption func(*RebuildOptions)
+
+// OnEventError sets what the rebuild does with an event a handler fails on.
+// With RebuildSkip or RebuildQuarantine a single bad historical event, such
+// as one an upcaster cannot read, no longer makes the projection
+// unrebuildable; RebuildQuarantine also needs WithQuarantineStore.
+//
+// Cancellation of the rebuild context always aborts the rebuild.
+func OnEventError(policy RebuildErrorPolicy) RebuildOption {
+	return func(o *RebuildOptions) {
+		o.policy = policy
+	}
+}
+
+// WithQuarantineStore sets where RebuildQuarantine records failing events.
+// The projection's earlier quarantine records are cleared when the rebuild
+// starts, so afterwards the store holds exactly the events this rebuild
+// could not apply.
+func WithQuarantineStore(quarantine QuarantineStore) RebuildOption {
+	return func(o *RebuildOptions) {
+		o.quarantine = quarantine
+	}
+}
+
+// WithRebuildReport calls fn with the rebuild's report once it completes.
+func WithRebuildReport(fn func(RebuildReport)) RebuildOption {
+	return func(o *RebuildOptions) {
+		o.report = fn
+	}
+}
+
+// WithRebuildLogger logs the events RebuildSkip skips to logger (default
+// slog.Default()).
+func WithRebuildLogger(logger *slog.Logger) RebuildOption {
+	return func(o *RebuildOptions) {
+		o.logger = logger
+	}
+}
+
+// NewRebuildOptions applies opts, checking that they are consistent.
+// Projections implementing a rebuild use it together with HandleEventError
+// and Complete.
+func NewRebuildOptions(opts ...RebuildOption) (*RebuildOptions, error) {
+	options := &RebuildOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.logger == nil {
+		options.logger = slog.Default()
+	}
+	if options.policy == RebuildQuarantine && options.quarantine == nil {
+		return nil, errors.New("rebuild quarantine policy requires a quarantine store (use store.WithQuarantineStore)")
+	}
+	return options, nil
+}
+
+// Start prepares a rebuild of projectionName, clearing its earlier quarantine
+// records when quarantining.
+func (o *RebuildOptions) Start(ctx context.Context, projectionName string) error {
+	if o.policy != RebuildQuarantine {
+		return nil
+	}
+	if err := o.quarantine.ClearQuarantine(ctx, projectionName); err != nil {
+		return fmt.Errorf("failed to clear quarantine: %w", err)
+	}
+	return nil
+}
+
+// HandleEventError applies the policy to event, which a handler of
+// report.ProjectionName failed on with err. It returns nil if the rebuild can
+// continue, recording the event in report, or the error to abort with.
+func (o *RebuildOptions) HandleEventError(ctx context.Context, event *domain.Event, err error, report *RebuildReport) error {
+	if ctx.Err() != nil || o.policy == RebuildFail {
+		return fmt.Errorf("failed to handle event during rebuild: %w", err)
+	}
+
+	if o.policy == RebuildSkip {
+		o.logger.Warn("rebuild skipped event",
+			"projection", report.ProjectionName,
+			"event_id", event.ID,
+			"event_type", event.EventType,
+			"position", event.Position,
+			"error", err)
+		report.Skipped = append(report.Skipped, event.ID)
+		return nil
+	}
+
+	if qerr := o.quarantine.Quarantine(ctx, &QuarantinedEvent{
+		ProjectionName: report.ProjectionName,
+		EventID:        event.ID,
+		Position:       event.Position,
+		AggregateID:    event.AggregateID,
+		EventType:      event.EventType,
+		Error:          err.Error(),
+		QuarantinedAt:  domain.Now(),
+	}); qerr != nil {
+		return fmt.Errorf("failed to quarantine event %s (%v): %w", event.ID, err, qerr)
+	}
+	report.Quarantined = append(report.Quarantined, event.ID)
+	return nil
+}
+
+// Complete reports a finished rebuild to the WithRebuildReport callback.
+func (o *RebuildOptions) Complete(report RebuildReport) {
+	if o.report != nil {
+		o.report(report)
+	}
+}
+
+// Summary describes the events the rebuild skipped or quarantined, or returns
+// "" if there were none.
+func (r RebuildReport) Summary() string {
+	switch {
+	case len(r.Quarantined) > 0:
+		return fmt.Sprintf("quarantined %d events %v", len(r.Quarantined), r.Quarantined)
+	case len(r.Skipped) > 0:
+		return fmt.Sprintf("skipped %d events %v", len(r.Skipped), r.Skipped)
+	default:
+		return ""
+	}
+}
@@ -1,7 +1,11 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"time"
 
 	"github.com/plaenen/eventstore/pkg/domain"
@@ -28,6 +32,15 @@ type BaseRepository[T domain.Aggregate] struct {
 	aggregateType string
 	factory       func(id string) T
 	applier       func(aggregate T, event *domain.Event) error
+	cache         *aggregateCache[T] // nil when caching is disabled
+
+	snapshots       SnapshotStore // nil when snapshots are not used
+	snapshotRepair  bool
+	snapshotMetrics SnapshotMetrics
+	logger          *slog.Logger
+
+	maxLoadEvents   int // 0 = unlimited
+	streamBatchSize int // 0 = no streaming fallback
 }
 
 // NewRepository creates a new repository for the given aggregate type.
@@ -38,21 +51,74 @@ func NewRepository[T domain.Aggregate](
 	aggregateType string,
 	factory func(id string) T,
 	applier func(aggregate T, event *domain.Event) error,
+	opts ...RepositoryOption,
 ) *BaseRepository[T] {
-	return &BaseRepository[T]{
+	var config repositoryConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.logger == nil {
+		config.logger = slog.Default()
+	}
+
+	repo := &BaseRepository[T]{
 		eventStore:    eventStore,
 		aggregateType: aggregateType,
 		factory:       factory,
 		applier:       applier,
+
+		snapshots:       config.snapshots,
+		snapshotRepair:  config.snapshotRepair,
+		snapshotMetrics: config.snapshotMetrics,
+		logger:          config.logger,
+
+		maxLoadEvents:   config.maxLoadEvents,
+		streamBatchSize: config.streamBatchSize,
+	}
+	if config.cacheSize > 0 {
+		repo.cache = newAggregateCache[T](config.cacheSize)
 	}
+	return repo
 }
 
 // Load loads an aggregate by ID from the event store.
 func (r *BaseRepository[T]) Load(id string) (T, error) {
 	var zero T
 
+	if aggregate, ok := r.loadCached(id); ok {
+		return aggregate, nil
+	}
+
+	aggregate, ok, rejected, err := r.loadFromSnapshot(id)
+	if err != nil {
+		return zero, err
+	}
+	if ok {
+		return aggregate, nil
+	}
+
+	// Guard against replaying a pathological history into memory
+	tooMany, err := r.exceedsLoadLimit(id, 0)
+	if err != nil {
+		return zero, err
+	}
+	if tooMany {
+		streamer, ok := r.streamer()
+		if !ok {
+			return zero, r.tooManyEvents(id)
+		}
+		aggregate, err = r.loadStreaming(streamer, id)
+		if err != nil {
+			return zero, err
+		}
+		if rejected != nil && r.snapshotRepair {
+			r.repairSnapshot(aggregate, rejected)
+		}
+		return aggregate, nil
+	}
+
 	// Load events from store
-	events, err := r.eventStore.LoadEvents(id, 0)
+	events, err := r.eventStore.LoadEvents(context.Background(), id, 0)
 	if err != nil {
 		return zero, fmt.Errorf("failed to load events: %w", err)
 	}
@@ -62,7 +128,7 @@ func (r *BaseRepository[T]) Load(id string) (T, error) {
 	}
 
 	// Create new aggregate instance
-	aggregate := r.factory(id)
+	aggregate = r.factory(id)
 
 	// Apply all events to rebuild state
 	for _, event := range events {
@@ -81,6 +147,10 @@ func (r *BaseRepository[T]) Load(id string) (T, error) {
 		}
 	}
 
+	if rejected != nil && r.snapshotRepair {
+		r.repairSnapshot(aggregate, rejected)
+	}
+
 	return aggregate, nil
 }
 
@@ -95,13 +165,18 @@ func (r *BaseRepository[T]) Save(aggregate T) error {
 	expectedVersion := aggregate.Version() - int64(len(uncommittedEvents))
 
 	// Append events atomically with constraint validation
-	if err := r.eventStore.AppendEvents(aggregate.ID(), expectedVersion, uncommittedEvents); err != nil {
+	if err := r.eventStore.AppendEvents(context.Background(), aggregate.ID(), expectedVersion, uncommittedEvents); err != nil {
+		r.invalidateOnConflict(aggregate.ID(), err)
 		return fmt.Errorf("failed to append events: %w", err)
 	}
 
 	// Clear uncommitted events
 	aggregate.ClearUncommittedEvents()
 
+	if r.cache != nil && !isPartial(aggregate) {
+		r.cache.put(aggregate)
+	}
+
 	return nil
 }
 
@@ -121,6 +196,7 @@ func (r *BaseRepository[T]) SaveWithCommand(aggregate T, commandID string) (*dom
 
 	// Append events with idempotency
 	result, err := r.eventStore.AppendEventsIdempotent(
+		context.Background(),
 		aggregate.ID(),
 		expectedVersion,
 		uncommittedEvents,
@@ -128,17 +204,93 @@ func (r *BaseRepository[T]) SaveWithCommand(aggregate T, commandID string) (*dom
 		domain.DefaultCommandTTL,
 	)
 	if err != nil {
+		r.invalidateOnConflict(aggregate.ID(), err)
 		return nil, fmt.Errorf("failed to append events: %w", err)
 	}
 
-	// Clear uncommitted events only if we actually persisted them
-	if !result.AlreadyProcessed {
-		aggregate.ClearUncommittedEvents()
+	// A replayed command left its events out, and content deduplication
+	// dropped some: either way the aggregate's state is not what was stored,
+	// so it must not be served from the cache
+	if result.AlreadyProcessed || len(result.Deduplicated) > 0 {
+		if r.cache != nil {
+			r.cache.invalidate(aggregate.ID())
+		}
+		if !result.AlreadyProcessed {
+			aggregate.ClearUncommittedEvents()
+		}
+		return result, nil
+	}
+
+	aggregate.ClearUncommittedEvents()
+	if r.cache != nil && !isPartial(aggregate) {
+		r.cache.put(aggregate)
 	}
 
 	return result, nil
 }
 
+// SaveWithIdempotencyKey is SaveWithCommand for a caller-supplied business
+// idempotency key (e.g., an HTTP Idempotency-Key header) instead of a command
+// ID: retries carrying the same key for the same aggregate are recorded once,
+// whatever command ID the client generated. See domain.IdempotencyCommandID.
+func (r *BaseRepository[T]) SaveWithIdempotencyKey(aggregate T, key string) (*domain.CommandResult, error) {
+	if key == "" {
+		return nil, domain.ErrInvalidCommand
+	}
+	return r.SaveWithCommand(aggregate, domain.IdempotencyCommandID(aggregate.ID(), key))
+}
+
+// SaveWithMetadata is SaveWithCommand under the command ID meta identifies
+// the command by: its IdempotencyKey when set, its CommandID otherwise. See
+// domain.CommandMetadata.EffectiveCommandID.
+func (r *BaseRepository[T]) SaveWithMetadata(aggregate T, meta domain.CommandMetadata) (*domain.CommandResult, error) {
+	return r.SaveWithCommand(aggregate, meta.EffectiveCommandID(aggregate.ID()))
+}
+
+// loadCached returns the cached aggregate for id if it is still current.
+func (r *BaseRepository[T]) loadCached(id string) (T, bool) {
+	var zero T
+	if r.cache == nil {
+		return zero, false
+	}
+
+	aggregate, ok := r.cache.take(id)
+	if !ok {
+		return zero, false
+	}
+
+	// Only serve the cached instance if no one else appended in the meantime
+	version, err := r.eventStore.GetAggregateVersion(id)
+	if err != nil || version != aggregate.Version() || len(aggregate.UncommittedEvents()) > 0 {
+		return zero, false
+	}
+
+	return aggregate, true
+}
+
+// invalidateOnConflict drops a cached aggregate after a concurrency conflict.
+func (r *BaseRepository[T]) invalidateOnConflict(id string, err error) {
+	if r.cache != nil && isConcurrencyConflict(err) {
+		r.cache.invalidate(id)
+	}
+}
+
+// ForgetAggregate erases the copies of aggregate id the repository keeps
+// outside the event log: it drops the aggregate from the cache and deletes
+// its snapshots, so the next Load replays its events. Register it with
+// EncryptedEventCodec.OnForgetSubject when subjects are aggregates.
+func (r *BaseRepository[T]) ForgetAggregate(ctx context.Context, id string) error {
+	if r.cache != nil {
+		r.cache.invalidate(id)
+	}
+	if r.snapshots != nil {
+		if err := r.snapshots.DeleteOldSnapshots(id, math.MaxInt64); err != nil {
+			return fmt.Errorf("failed to delete snapshots of %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
 // Exists checks if an aggregate exists in the event store.
 func (r *BaseRepository[T]) Exists(id string) (bool, error) {
 	version, err := r.eventStore.GetAggregateVersion(id)
@@ -184,22 +336,5 @@ func (r *BaseRepository[T]) RetryOnConflict(id string, maxRetries int, fn func(T
 
 // isConcurrencyConflict checks if an error is due to optimistic locking failure
 func isConcurrencyConflict(err error) bool {
-	if err == nil {
-		return false
-	}
-	msg := err.Error()
-	return len(msg) > 0 && (
-		contains(msg, "concurrency conflict") ||
-		contains(msg, "version mismatch") ||
-		contains(msg, "optimistic lock"))
-}
-
-// contains checks if a string contains a substring
-func contains(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
+	return errors.Is(err, ErrConcurrencyConflict)
 }
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// ErrTooManyEvents is returned by Load when an aggregate has more events to
+// replay than allowed by WithMaxLoadEvents.
+var ErrTooManyEvents = errors.New("aggregate has too many events to load")
+
+// EventStreamer is implemented by event stores that can read an aggregate's
+// events in batches instead of all at once. The SQLite store implements it.
+type EventStreamer interface {
+	// StreamEvents yields the events of an aggregate after afterVersion in
+	// version order, reading batchSize events at a time. Iteration stops at
+	// the first error.
+	StreamEvents(aggregateID string, afterVersion int64, batchSize int) iter.Seq2[*domain.Event, error]
+}
+
+// LoadEventsPage returns up to limit events of aggregateID after afterVersion
+// in version order, for paging through a stream by version. With an
+// EventStreamer only the page is read; other stores load every event after
+// afterVersion and the rest is dropped. limit must be positive.
+func LoadEventsPage(ctx context.Context, eventStore EventStore, aggregateID string, afterVersion int64, limit int) ([]*domain.Event, error) {
+	streamer, ok := eventStore.(EventStreamer)
+	if !ok {
+		events, err := eventStore.LoadEvents(ctx, aggregateID, afterVersion)
+		if err != nil {
+			return nil, err
+		}
+		return events[:min(len(events), limit)], nil
+	}
+
+	events := make([]*domain.Event, 0, limit)
+	for event, err := range streamer.StreamEvents(aggregateID, afterVersion, limit) {
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+		if len(events) == limit {
+			break
+		}
+	}
+	return events, ctx.Err()
+}
+
+// WithMaxLoadEvents limits Load to aggregates with at most n events to replay
+// (after the snapshot, when one is used). Larger aggregates fail with
+// ErrTooManyEvents before any event is read, instead of exhausting memory;
+// the fix is to snapshot the aggregate (WithSnapshotStore) or compact its
+// stream. The limit is checked with one GetAggregateVersion query.
+//
+// Combine it with WithStreamingLoad to replay oversized aggregates in batches
+// instead of failing.
+func WithMaxLoadEvents(n int) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.maxLoadEvents = n
+	}
+}
+
+// WithStreamingLoad replays aggregates exceeding WithMaxLoadEvents in batches
+// of batchSize events, applying each batch before reading the next, so memory
+// use is bounded by the batch and the aggregate itself. It requires an event
+// store implementing EventStreamer; with other stores the limit still fails
+// the load. Aggregates within the limit are loaded as usual.
+func WithStreamingLoad(batchSize int) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.streamBatchSize = batchSize
+	}
+}
+
+// exceedsLoadLimit reports whether replaying the events of id after
+// afterVersion would exceed the WithMaxLoadEvents limit.
+func (r *BaseRepository[T]) exceedsLoadLimit(id string, afterVersion int64) (bool, error) {
+	if r.maxLoadEvents <= 0 {
+		return false, nil
+	}
+	version, err := r.eventStore.GetAggregateVersion(id)
+	if err != nil {
+		return false, fmt.Errorf("failed to get aggregate version: %w", err)
+	}
+	return version-afterVersion > int64(r.maxLoadEvents), nil
+}
+
+// tooManyEvents returns the ErrTooManyEvents error for id.
+func (r *BaseRepository[T]) tooManyEvents(id string) error {
+	return fmt.Errorf("%w: %s %s exceeds the limit of %d events; create a snapshot or compact its stream",
+		ErrTooManyEvents, r.aggregateType, id, r.maxLoadEvents)
+}
+
+// streamer returns the event store's EventStreamer if streaming loads are
+// enabled and supported.
+func (r *BaseRepository[T]) streamer() (EventStreamer, bool) {
+	if r.streamBatchSize <= 0 {
+		return nil, false
+	}
+	streamer, ok := r.eventStore.(EventStreamer)
+	return streamer, ok
+}
+
+// loadStreaming rebuilds the aggregate from its full history, one batch of
+// events at a time.
+func (r *BaseRepository[T]) loadStreaming(streamer EventStreamer, id string) (T, error) {
+	var zero T
+
+	aggregate := r.factory(id)
+	loaded, err := r.replayStreaming(streamer, aggregate, id, 0)
+	if err != nil {
+		return zero, err
+	}
+	if loaded == 0 {
+		return zero, domain.ErrAggregateNotFound
+	}
+	return aggregate, nil
+}
+
+// loadSnapshotStreaming restores snapshot into aggregate id and replays the
+// events after it one batch at a time, for a snapshot followed by more events
+// than WithMaxLoadEvents allows. It validates the snapshot as loadFromSnapshot
+// does.
+func (r *BaseRepository[T]) loadSnapshotStreaming(streamer EventStreamer, id string, aggregate T, snapshotable Snapshotable, snapshot *Snapshot) (T, bool, *Snapshot, error) {
+	var zero T
+
+	// The first event must be the one the snapshot was taken at
+	var first *domain.Event
+	for event, err := range streamer.StreamEvents(id, snapshot.Version-1, 1) {
+		if err != nil {
+			return zero, false, nil, fmt.Errorf("failed to load events: %w", err)
+		}
+		first = event
+		break
+	}
+	if first == nil || first.Version != snapshot.Version {
+		return zero, false, r.rejectSnapshot(snapshot, SnapshotRejectedMissingEvent), nil
+	}
+	if rejected := r.restoreSnapshot(aggregate, snapshotable, snapshot, id); rejected != nil {
+		return zero, false, rejected, nil
+	}
+
+	// Sets the version to the snapshot's, the batches advance it from there
+	if history, ok := any(aggregate).(interface{ LoadFromHistory([]*domain.Event) error }); ok {
+		if err := history.LoadFromHistory([]*domain.Event{first}); err != nil {
+			return zero, false, nil, fmt.Errorf("failed to load history: %w", err)
+		}
+	}
+	if _, err := r.replayStreaming(streamer, aggregate, id, snapshot.Version); err != nil {
+		return zero, false, nil, err
+	}
+	return aggregate, true, nil, nil
+}
+
+// replayStreaming applies the events of id after afterVersion to aggregate,
+// one batch at a time, and returns how many it applied.
+func (r *BaseRepository[T]) replayStreaming(streamer EventStreamer, aggregate T, id string, afterVersion int64) (int, error) {
+	history, hasHistory := any(aggregate).(interface{ LoadFromHistory([]*domain.Event) error })
+
+	batch := make([]*domain.Event, 0, r.streamBatchSize)
+	flush := func() error {
+		// LoadFromHistory only advances the version, so it can take the
+		// history batch by batch
+		if hasHistory {
+			if err := history.LoadFromHistory(batch); err != nil {
+				return fmt.Errorf("failed to load history: %w", err)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	loaded := 0
+	for event, err := range streamer.StreamEvents(id, afterVersion, r.streamBatchSize) {
+		if err != nil {
+			return 0, fmt.Errorf("failed to load events: %w", err)
+		}
+		if err := r.applier(aggregate, event); err != nil {
+			return 0, fmt.Errorf("failed to apply event: %w", err)
+		}
+		loaded++
+
+		batch = append(batch, event)
+		if len(batch) == r.streamBatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return loaded, nil
+}
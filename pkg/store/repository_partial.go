@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// partialAggregate is implemented by aggregates embedding domain.AggregateRoot.
+type partialAggregate interface {
+	MarkPartial()
+	IsPartial() bool
+}
+
+// LoadPartial rebuilds an aggregate from only the events of the given types;
+// other events are read but not applied. For aggregates with long histories
+// (e.g., a ledger with thousands of line items) this skips decoding and
+// folding the events a command doesn't need.
+//
+// The result is a partial view: fields fed by skipped events keep their zero
+// values. It is only safe for commands whose invariants depend solely on
+// eventTypes; a command that checks state built from other events will see it
+// missing and may accept what it should reject. The version is that of the
+// full stream, so saving new events keeps the usual concurrency checks.
+//
+// Partial aggregates report IsPartial, bypass snapshots, and are never put in
+// the aggregate cache. Event type aliases are matched by their canonical type.
+func (r *BaseRepository[T]) LoadPartial(id string, eventTypes []string) (T, error) {
+	var zero T
+
+	wanted := make(map[string]bool, len(eventTypes))
+	for _, eventType := range eventTypes {
+		wanted[domain.CanonicalEventType(eventType)] = true
+	}
+
+	aggregate := r.factory(id)
+	if partial, ok := any(aggregate).(partialAggregate); ok {
+		partial.MarkPartial()
+	}
+	apply := func(event *domain.Event) error {
+		if !wanted[domain.CanonicalEventType(event.EventType)] {
+			return nil
+		}
+		if err := r.applier(aggregate, event); err != nil {
+			return fmt.Errorf("failed to apply event: %w", err)
+		}
+		return nil
+	}
+
+	tooMany, err := r.exceedsLoadLimit(id, 0)
+	if err != nil {
+		return zero, err
+	}
+	if tooMany {
+		streamer, ok := r.streamer()
+		if !ok {
+			return zero, r.tooManyEvents(id)
+		}
+		return r.loadPartialStreaming(streamer, aggregate, apply)
+	}
+
+	events, err := r.eventStore.LoadEvents(context.Background(), id, 0)
+	if err != nil {
+		return zero, fmt.Errorf("failed to load events: %w", err)
+	}
+	if len(events) == 0 {
+		return zero, domain.ErrAggregateNotFound
+	}
+
+	for _, event := range events {
+		if err := apply(event); err != nil {
+			return zero, err
+		}
+	}
+
+	// The version covers skipped events too
+	if agg, ok := any(aggregate).(interface{ LoadFromHistory([]*domain.Event) error }); ok {
+		if err := agg.LoadFromHistory(events); err != nil {
+			return zero, fmt.Errorf("failed to load history: %w", err)
+		}
+	}
+
+	return aggregate, nil
+}
+
+// loadPartialStreaming is LoadPartial for aggregates exceeding
+// WithMaxLoadEvents, reading the history in batches.
+func (r *BaseRepository[T]) loadPartialStreaming(streamer EventStreamer, aggregate T, apply func(*domain.Event) error) (T, error) {
+	var zero T
+
+	history, hasHistory := any(aggregate).(interface{ LoadFromHistory([]*domain.Event) error })
+	batch := make([]*domain.Event, 0, r.streamBatchSize)
+	flush := func() error {
+		if hasHistory {
+			if err := history.LoadFromHistory(batch); err != nil {
+				return fmt.Errorf("failed to load history: %w", err)
+			}
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	loaded := 0
+	for event, err := range streamer.StreamEvents(aggregate.ID(), 0, r.streamBatchSize) {
+		if err != nil {
+			return zero, fmt.Errorf("failed to load events: %w", err)
+		}
+		if err := apply(event); err != nil {
+			return zero, err
+		}
+		loaded++
+
+		batch = append(batch, event)
+		if len(batch) == r.streamBatchSize {
+			if err := flush(); err != nil {
+				return zero, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return zero, err
+	}
+
+	if loaded == 0 {
+		return zero, domain.ErrAggregateNotFound
+	}
+	return aggregate, nil
+}
+
+// isPartial reports whether aggregate was loaded with LoadPartial.
+func isPartial(aggregate any) bool {
+	partial, ok := aggregate.(partialAggregate)
+	return ok && partial.IsPartial()
+}
@@ -0,0 +1,302 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// SnapshotMetrics receives snapshot health signals from a repository.
+// *observability.Metrics implements it.
+type SnapshotMetrics interface {
+	// RecordSnapshotRejected records a snapshot that failed validation and
+	// was bypassed by a full replay.
+	RecordSnapshotRejected(ctx context.Context, aggregateType, reason string)
+}
+
+// Reasons reported when a snapshot is rejected during load.
+const (
+	SnapshotRejectedTypeMismatch   = "aggregate_type_mismatch"
+	SnapshotRejectedInvalidVersion = "invalid_version"
+	SnapshotRejectedMissingEvent   = "missing_event"
+	SnapshotRejectedCorruptData    = "corrupt_data"
+	SnapshotRejectedIDMismatch     = "aggregate_id_mismatch"
+)
+
+// WithSnapshotStore makes Load start from the aggregate's latest snapshot
+// instead of replaying its whole history. Only aggregates implementing
+// Snapshotable use it; others are always replayed.
+//
+// A snapshot is trusted only if the event stream still has an event at the
+// snapshot's version and the snapshot decodes into an aggregate with the
+// requested ID. A snapshot taken past the end of the stream (for example after
+// restoring an older backup of the events) or left behind by a failed write is
+// rejected: Load logs a warning, reports it to WithSnapshotMetrics and falls
+// back to a full replay.
+func WithSnapshotStore(snapshots SnapshotStore) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.snapshots = snapshots
+	}
+}
+
+// WithSnapshotAutoRepair replaces a rejected snapshot with one taken from the
+// replayed aggregate, so the next Load can use it again. The rejected snapshot
+// is removed first when the snapshot store supports deleting a single snapshot
+// (the SQLite store does). Repair failures are logged and do not fail the load.
+func WithSnapshotAutoRepair() RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.snapshotRepair = true
+	}
+}
+
+// WithSnapshotMetrics reports rejected snapshots to metrics.
+func WithSnapshotMetrics(metrics SnapshotMetrics) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.snapshotMetrics = metrics
+	}
+}
+
+// WithRepositoryLogger logs rejected snapshots and repair failures to logger
+// (nil = slog.Default()).
+func WithRepositoryLogger(logger *slog.Logger) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.logger = logger
+	}
+}
+
+// snapshotDeleter is implemented by snapshot stores that can remove a single
+// snapshot.
+type snapshotDeleter interface {
+	DeleteSnapshot(aggregateID string, version int64) error
+}
+
+// loadFromSnapshot restores an aggregate from its latest snapshot and the
+// events after it. ok is false when no usable snapshot exists; rejected is the
+// snapshot that failed validation, if any.
+func (r *BaseRepository[T]) loadFromSnapshot(id string) (aggregate T, ok bool, rejected *Snapshot, err error) {
+	var zero T
+	if r.snapshots == nil {
+		return zero, false, nil, nil
+	}
+
+	aggregate = r.factory(id)
+	snapshotable, isSnapshotable := any(aggregate).(Snapshotable)
+	if !isSnapshotable {
+		return zero, false, nil, nil
+	}
+
+	snapshot, err := r.snapshots.GetLatestSnapshot(id)
+	if errors.Is(err, domain.ErrSnapshotNotFound) {
+		return zero, false, nil, nil
+	}
+	if err != nil {
+		return zero, false, nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+
+	if snapshot.AggregateType != "" && snapshot.AggregateType != r.aggregateType {
+		return zero, false, r.rejectSnapshot(snapshot, SnapshotRejectedTypeMismatch), nil
+	}
+	if snapshot.Version <= 0 {
+		return zero, false, r.rejectSnapshot(snapshot, SnapshotRejectedInvalidVersion), nil
+	}
+
+	// A snapshot too far behind is still restored, with the events after it
+	// replayed in batches
+	tooMany, err := r.exceedsLoadLimit(id, snapshot.Version)
+	if err != nil {
+		return zero, false, nil, err
+	}
+	if tooMany {
+		streamer, ok := r.streamer()
+		if !ok {
+			return zero, false, nil, r.tooManyEvents(id)
+		}
+		return r.loadSnapshotStreaming(streamer, id, aggregate, snapshotable, snapshot)
+	}
+
+	// The first event returned must be the one the snapshot was taken at
+	events, err := r.eventStore.LoadEvents(context.Background(), id, snapshot.Version-1)
+	if err != nil {
+		return zero, false, nil, fmt.Errorf("failed to load events: %w", err)
+	}
+	if len(events) == 0 || events[0].Version != snapshot.Version {
+		return zero, false, r.rejectSnapshot(snapshot, SnapshotRejectedMissingEvent), nil
+	}
+
+	if rejected := r.restoreSnapshot(aggregate, snapshotable, snapshot, id); rejected != nil {
+		return zero, false, rejected, nil
+	}
+
+	for _, event := range events[1:] {
+		if err := r.applier(aggregate, event); err != nil {
+			return zero, false, nil, fmt.Errorf("failed to apply event: %w", err)
+		}
+	}
+
+	// events starts at the snapshot version, so this sets the version to the
+	// last event without replaying what the snapshot already covers
+	if agg, ok := any(aggregate).(interface{ LoadFromHistory([]*domain.Event) error }); ok {
+		if err := agg.LoadFromHistory(events); err != nil {
+			return zero, false, nil, fmt.Errorf("failed to load history: %w", err)
+		}
+	}
+
+	return aggregate, true, nil, nil
+}
+
+// restoreSnapshot unmarshals snapshot into aggregate. It returns the snapshot,
+// rejected, if it does not hold the state of aggregate id.
+func (r *BaseRepository[T]) restoreSnapshot(aggregate T, snapshotable Snapshotable, snapshot *Snapshot, id string) *Snapshot {
+	if err := snapshotable.UnmarshalSnapshot(snapshot.Data); err != nil {
+		return r.rejectSnapshot(snapshot, SnapshotRejectedCorruptData)
+	}
+	if aggregate.ID() != id {
+		return r.rejectSnapshot(snapshot, SnapshotRejectedIDMismatch)
+	}
+	return nil
+}
+
+// rejectSnapshot reports a snapshot that failed validation and returns it.
+func (r *BaseRepository[T]) rejectSnapshot(snapshot *Snapshot, reason string) *Snapshot {
+	r.logger.Warn("rejected snapshot, replaying events",
+		"aggregate_type", r.aggregateType, "aggregate_id", snapshot.AggregateID,
+		"version", snapshot.Version, "reason", reason)
+	if r.snapshotMetrics != nil {
+		r.snapshotMetrics.RecordSnapshotRejected(context.Background(), r.aggregateType, reason)
+	}
+	return snapshot
+}
+
+// repairSnapshot replaces a rejected snapshot with the replayed state.
+func (r *BaseRepository[T]) repairSnapshot(aggregate T, rejected *Snapshot) {
+	snapshotable, ok := any(aggregate).(Snapshotable)
+	if !ok {
+		return
+	}
+
+	if deleter, ok := r.snapshots.(snapshotDeleter); ok {
+		if err := deleter.DeleteSnapshot(rejected.AggregateID, rejected.Version); err != nil {
+			r.logger.Error("failed to delete rejected snapshot",
+				"aggregate_type", r.aggregateType, "aggregate_id", rejected.AggregateID, "error", err)
+			return
+		}
+	}
+
+	if err := r.saveSnapshot(aggregate, snapshotable); err != nil {
+		r.logger.Error("failed to save repaired snapshot",
+			"aggregate_type", r.aggregateType, "aggregate_id", aggregate.ID(), "error", err)
+	}
+}
+
+// saveSnapshot writes a snapshot of aggregate at its current version.
+func (r *BaseRepository[T]) saveSnapshot(aggregate T, snapshotable Snapshotable) error {
+	started := time.Now()
+	data, err := snapshotable.MarshalSnapshot()
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	return r.snapshots.SaveSnapshot(&Snapshot{
+		AggregateID:   aggregate.ID(),
+		AggregateType: r.aggregateType,
+		Version:       aggregate.Version(),
+		Data:          data,
+		CreatedAt:     time.Now(),
+		Metadata: &SnapshotMetadata{
+			Size:         int64(len(data)),
+			EventCount:   aggregate.Version(),
+			CreationTime: time.Since(started).Milliseconds(),
+		},
+	})
+}
+
+// ForceSnapshot loads an aggregate and snapshots it at its current version,
+// whatever the snapshot strategy, e.g. before a large migration. It is a
+// no-op if the latest snapshot is already at that version.
+//
+// It may run concurrently with commands on the same aggregate: it works on
+// its own loaded instance, and a snapshot at a version that commands have
+// since moved past stays valid, as Load replays the events after it.
+func (r *BaseRepository[T]) ForceSnapshot(ctx context.Context, aggregateID string) error {
+	if r.snapshots == nil {
+		return errors.New("repository has no snapshot store (use store.WithSnapshotStore)")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	aggregate, err := r.Load(aggregateID)
+	if err != nil {
+		return err
+	}
+	if r.cache != nil && !isPartial(aggregate) {
+		// Load took the aggregate out of the cache; it is unchanged
+		defer r.cache.put(aggregate)
+	}
+	snapshotable, ok := any(aggregate).(Snapshotable)
+	if !ok {
+		return fmt.Errorf("%s aggregates do not support snapshots", r.aggregateType)
+	}
+	if r.hasSnapshotAt(aggregateID, aggregate.Version()) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := r.saveSnapshot(aggregate, snapshotable); err != nil {
+		// A concurrent call may have taken the same snapshot first
+		if r.hasSnapshotAt(aggregateID, aggregate.Version()) {
+			return nil
+		}
+		return fmt.Errorf("failed to save snapshot of %s %s: %w", r.aggregateType, aggregateID, err)
+	}
+	return nil
+}
+
+// hasSnapshotAt reports whether the latest snapshot of an aggregate is at
+// version or later.
+func (r *BaseRepository[T]) hasSnapshotAt(aggregateID string, version int64) bool {
+	latest, err := r.snapshots.GetLatestSnapshot(aggregateID)
+	return err == nil && latest.Version >= version
+}
+
+// PruneSnapshots deletes all but the keep most recent snapshots of an
+// aggregate, enforcing retention outside the write path. keep must be at
+// least 1, so Load always finds the latest snapshot; snapshots taken while
+// pruning are newer than those deleted.
+func (r *BaseRepository[T]) PruneSnapshots(ctx context.Context, aggregateID string, keep int) error {
+	if r.snapshots == nil {
+		return errors.New("repository has no snapshot store (use store.WithSnapshotStore)")
+	}
+	if keep < 1 {
+		return fmt.Errorf("cannot keep %d snapshots: keep at least 1", keep)
+	}
+
+	// Walk back to the oldest snapshot to keep
+	oldest, err := r.snapshots.GetLatestSnapshot(aggregateID)
+	for kept := 1; err == nil && kept < keep; kept++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		oldest, err = r.snapshots.GetSnapshotBeforeVersion(aggregateID, oldest.Version-1)
+	}
+	if errors.Is(err, domain.ErrSnapshotNotFound) {
+		return nil // No more than keep snapshots
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load snapshots of %s %s: %w", r.aggregateType, aggregateID, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := r.snapshots.DeleteOldSnapshots(aggregateID, oldest.Version); err != nil {
+		return fmt.Errorf("failed to prune snapshots of %s %s: %w", r.aggregateType, aggregateID, err)
+	}
+	return nil
+}
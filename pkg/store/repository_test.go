@@ -0,0 +1,770 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// counter is a minimal aggregate that sums the values of its events.
+type counter struct {
+	domain.AggregateRoot
+	total int64
+}
+
+func newCounter(id string) *counter {
+	return &counter{AggregateRoot: domain.NewAggregateRoot(id, "Counter")}
+}
+
+func (c *counter) ApplyEvent(event proto.Message) error {
+	c.total += event.(*wrapperspb.Int64Value).Value
+	return nil
+}
+
+func (c *counter) add(value int64) error {
+	event := wrapperspb.Int64(value)
+	if err := c.ApplyChange(event, "counter.Added", domain.EventMetadata{}); err != nil {
+		return err
+	}
+	return c.ApplyEvent(event)
+}
+
+func (c *counter) MarshalSnapshot() ([]byte, error) {
+	return proto.Marshal(wrapperspb.Int64(c.total))
+}
+
+func (c *counter) UnmarshalSnapshot(data []byte) error {
+	msg := &wrapperspb.Int64Value{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return err
+	}
+	c.total = msg.Value
+	return nil
+}
+
+func applyCounterEvent(c *counter, event *domain.Event) error {
+	msg := &wrapperspb.Int64Value{}
+	if err := proto.Unmarshal(event.Data, msg); err != nil {
+		return err
+	}
+	return c.ApplyEvent(msg)
+}
+
+// countingStore counts full event replays and version lookups.
+type countingStore struct {
+	store.EventStore
+	replays       int
+	versionChecks int
+}
+
+func (s *countingStore) GetAggregateVersion(aggregateID string) (int64, error) {
+	s.versionChecks++
+	return s.EventStore.GetAggregateVersion(aggregateID)
+}
+
+func (s *countingStore) LoadEvents(ctx context.Context, aggregateID string, afterVersion int64) ([]*domain.Event, error) {
+	s.replays++
+	return s.EventStore.LoadEvents(ctx, aggregateID, afterVersion)
+}
+
+func TestRepositoryAggregateCache(t *testing.T) {
+	sqliteStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	es := &countingStore{EventStore: sqliteStore}
+	repo := store.NewRepository[*counter](es, "Counter", newCounter, applyCounterEvent, store.WithAggregateCache(10))
+
+	agg := newCounter("counter-1")
+	if err := agg.add(5); err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+	if err := repo.Save(agg); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	t.Run("ServesCachedAggregateAfterSave", func(t *testing.T) {
+		loaded, err := repo.Load("counter-1")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if es.replays != 0 {
+			t.Errorf("expected cache hit without replay, got %d replays", es.replays)
+		}
+		if loaded.total != 5 || loaded.Version() != 1 {
+			t.Errorf("unexpected state: total=%d version=%d", loaded.total, loaded.Version())
+		}
+
+		if err := loaded.add(3); err != nil {
+			t.Fatalf("failed to add: %v", err)
+		}
+		if err := repo.Save(loaded); err != nil {
+			t.Fatalf("failed to save: %v", err)
+		}
+	})
+
+	t.Run("ReplaysWhenStoreMovedOn", func(t *testing.T) {
+		// Another writer appends behind the cache's back
+		other := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent)
+		stale, err := other.Load("counter-1")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if err := stale.add(10); err != nil {
+			t.Fatalf("failed to add: %v", err)
+		}
+		if err := other.Save(stale); err != nil {
+			t.Fatalf("failed to save: %v", err)
+		}
+
+		replaysBefore := es.replays
+		loaded, err := repo.Load("counter-1")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if es.replays != replaysBefore+1 {
+			t.Errorf("expected a replay after version mismatch")
+		}
+		if loaded.total != 18 || loaded.Version() != 3 {
+			t.Errorf("unexpected state: total=%d version=%d", loaded.total, loaded.Version())
+		}
+	})
+
+	t.Run("ConcurrentLoadsDoNotShareInstances", func(t *testing.T) {
+		agg := newCounter("counter-2")
+		agg.add(1)
+		if err := repo.Save(agg); err != nil {
+			t.Fatalf("failed to save: %v", err)
+		}
+
+		first, err := repo.Load("counter-2")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		second, err := repo.Load("counter-2")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if first == second {
+			t.Error("expected distinct instances for concurrent loads")
+		}
+	})
+}
+
+// rejectionRecorder records snapshot rejection reasons.
+type rejectionRecorder struct {
+	reasons []string
+}
+
+func (r *rejectionRecorder) RecordSnapshotRejected(ctx context.Context, aggregateType, reason string) {
+	r.reasons = append(r.reasons, reason)
+}
+
+func TestRepositorySnapshots(t *testing.T) {
+	sqliteStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer sqliteStore.Close()
+	snapshots := sqlite.NewSnapshotStore(sqliteStore.DB())
+
+	// saveCounter stores a counter with events 1, 2 and 3 (total 6)
+	saveCounter := func(t *testing.T, id string) {
+		t.Helper()
+		agg := newCounter(id)
+		for _, value := range []int64{1, 2, 3} {
+			if err := agg.add(value); err != nil {
+				t.Fatalf("failed to add: %v", err)
+			}
+		}
+		plain := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent)
+		if err := plain.Save(agg); err != nil {
+			t.Fatalf("failed to save: %v", err)
+		}
+	}
+
+	saveSnapshot := func(t *testing.T, id string, version int64, data []byte) {
+		t.Helper()
+		err := snapshots.SaveSnapshot(&store.Snapshot{
+			AggregateID:   id,
+			AggregateType: "Counter",
+			Version:       version,
+			Data:          data,
+			CreatedAt:     time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("failed to save snapshot: %v", err)
+		}
+	}
+
+	snapshotData := func(total int64) []byte {
+		data, _ := proto.Marshal(wrapperspb.Int64(total))
+		return data
+	}
+
+	t.Run("StartsFromValidSnapshot", func(t *testing.T) {
+		saveCounter(t, "valid")
+		// A total no replay could produce shows the snapshot was used
+		saveSnapshot(t, "valid", 2, snapshotData(100))
+
+		metrics := &rejectionRecorder{}
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+			store.WithSnapshotStore(snapshots), store.WithSnapshotMetrics(metrics))
+
+		loaded, err := repo.Load("valid")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if loaded.total != 103 || loaded.Version() != 3 {
+			t.Errorf("unexpected state: total=%d version=%d", loaded.total, loaded.Version())
+		}
+		if len(metrics.reasons) != 0 {
+			t.Errorf("expected no rejections, got %v", metrics.reasons)
+		}
+	})
+
+	rejections := []struct {
+		name    string
+		version int64
+		data    []byte
+		reason  string
+	}{
+		{"SnapshotPastEndOfStream", 10, snapshotData(100), store.SnapshotRejectedMissingEvent},
+		{"CorruptSnapshot", 2, []byte{0xff, 0xff}, store.SnapshotRejectedCorruptData},
+	}
+	for _, tc := range rejections {
+		t.Run(tc.name, func(t *testing.T) {
+			id := "rejected-" + tc.name
+			saveCounter(t, id)
+			saveSnapshot(t, id, tc.version, tc.data)
+
+			metrics := &rejectionRecorder{}
+			var logs bytes.Buffer
+			repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+				store.WithSnapshotStore(snapshots), store.WithSnapshotMetrics(metrics),
+				store.WithRepositoryLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+			loaded, err := repo.Load(id)
+			if err != nil {
+				t.Fatalf("failed to load: %v", err)
+			}
+			if loaded.total != 6 || loaded.Version() != 3 {
+				t.Errorf("expected full replay, got total=%d version=%d", loaded.total, loaded.Version())
+			}
+			if len(metrics.reasons) != 1 || metrics.reasons[0] != tc.reason {
+				t.Errorf("expected rejection %q, got %v", tc.reason, metrics.reasons)
+			}
+			if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "reason="+tc.reason) {
+				t.Errorf("expected a warning with reason %s, got %q", tc.reason, logs.String())
+			}
+
+			// Without auto-repair the bad snapshot is left in place
+			latest, err := snapshots.GetLatestSnapshot(id)
+			if err != nil {
+				t.Fatalf("failed to get snapshot: %v", err)
+			}
+			if latest.Version != tc.version {
+				t.Errorf("expected snapshot to be untouched, got version %d", latest.Version)
+			}
+		})
+	}
+
+	t.Run("AutoRepair", func(t *testing.T) {
+		saveCounter(t, "repair")
+		saveSnapshot(t, "repair", 10, snapshotData(100))
+
+		metrics := &rejectionRecorder{}
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+			store.WithSnapshotStore(snapshots), store.WithSnapshotMetrics(metrics), store.WithSnapshotAutoRepair())
+
+		if _, err := repo.Load("repair"); err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+
+		latest, err := snapshots.GetLatestSnapshot("repair")
+		if err != nil {
+			t.Fatalf("failed to get snapshot: %v", err)
+		}
+		if latest.Version != 3 {
+			t.Errorf("expected repaired snapshot at version 3, got %d", latest.Version)
+		}
+
+		loaded, err := repo.Load("repair")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if loaded.total != 6 || loaded.Version() != 3 {
+			t.Errorf("unexpected state: total=%d version=%d", loaded.total, loaded.Version())
+		}
+		if len(metrics.reasons) != 1 {
+			t.Errorf("expected the repaired snapshot to be accepted, got rejections %v", metrics.reasons)
+		}
+	})
+
+	t.Run("ForceSnapshot", func(t *testing.T) {
+		saveCounter(t, "forced")
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+			store.WithSnapshotStore(snapshots))
+		ctx := context.Background()
+
+		if err := repo.ForceSnapshot(ctx, "forced"); err != nil {
+			t.Fatalf("failed to force snapshot: %v", err)
+		}
+		latest, err := snapshots.GetLatestSnapshot("forced")
+		if err != nil {
+			t.Fatalf("failed to get snapshot: %v", err)
+		}
+		if latest.Version != 3 || !proto.Equal(mustUnmarshalInt64(t, latest.Data), wrapperspb.Int64(6)) {
+			t.Errorf("expected a snapshot of total 6 at version 3, got version %d", latest.Version)
+		}
+
+		// Forcing again at the same version is a no-op
+		if err := repo.ForceSnapshot(ctx, "forced"); err != nil {
+			t.Errorf("expected forcing an existing snapshot to succeed, got %v", err)
+		}
+
+		if err := repo.ForceSnapshot(ctx, "missing"); !errors.Is(err, domain.ErrAggregateNotFound) {
+			t.Errorf("expected ErrAggregateNotFound, got %v", err)
+		}
+		plain := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent)
+		if err := plain.ForceSnapshot(ctx, "forced"); err == nil {
+			t.Error("expected an error without a snapshot store")
+		}
+	})
+
+	t.Run("ForceSnapshotDuringCommands", func(t *testing.T) {
+		saveCounter(t, "busy")
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+			store.WithSnapshotStore(snapshots), store.WithAggregateCache(10))
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				agg, err := repo.Load("busy")
+				if err != nil {
+					t.Errorf("failed to load: %v", err)
+					return
+				}
+				agg.add(1)
+				if err := repo.Save(agg); err != nil {
+					t.Errorf("failed to save: %v", err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if err := repo.ForceSnapshot(context.Background(), "busy"); err != nil {
+					t.Errorf("failed to force snapshot: %v", err)
+					return
+				}
+			}
+		}()
+		wg.Wait()
+
+		loaded, err := repo.Load("busy")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if loaded.total != 26 || loaded.Version() != 23 {
+			t.Errorf("expected total 26 at version 23, got total=%d version=%d", loaded.total, loaded.Version())
+		}
+	})
+
+	t.Run("PruneSnapshots", func(t *testing.T) {
+		saveCounter(t, "pruned")
+		for version := int64(1); version <= 3; version++ {
+			saveSnapshot(t, "pruned", version, snapshotData(version))
+		}
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+			store.WithSnapshotStore(snapshots))
+		ctx := context.Background()
+
+		if err := repo.PruneSnapshots(ctx, "pruned", 5); err != nil {
+			t.Fatalf("failed to prune: %v", err)
+		}
+		if _, err := snapshots.GetSnapshotBeforeVersion("pruned", 1); err != nil {
+			t.Errorf("expected nothing pruned with fewer snapshots than kept: %v", err)
+		}
+
+		if err := repo.PruneSnapshots(ctx, "pruned", 2); err != nil {
+			t.Fatalf("failed to prune: %v", err)
+		}
+		if _, err := snapshots.GetSnapshotBeforeVersion("pruned", 1); !errors.Is(err, domain.ErrSnapshotNotFound) {
+			t.Errorf("expected the oldest snapshot to be pruned, got %v", err)
+		}
+		if kept, err := snapshots.GetSnapshotBeforeVersion("pruned", 2); err != nil || kept.Version != 2 {
+			t.Errorf("expected the snapshot at version 2 to be kept (%v)", err)
+		}
+		if latest, err := snapshots.GetLatestSnapshot("pruned"); err != nil || latest.Version != 3 {
+			t.Errorf("expected the latest snapshot to be kept (%v)", err)
+		}
+
+		if err := repo.PruneSnapshots(ctx, "pruned", 0); err == nil {
+			t.Error("expected keeping no snapshots to be rejected")
+		}
+		if err := repo.PruneSnapshots(ctx, "missing", 1); err != nil {
+			t.Errorf("expected pruning an aggregate without snapshots to succeed, got %v", err)
+		}
+	})
+}
+
+// mustUnmarshalInt64 decodes a counter snapshot.
+func mustUnmarshalInt64(t *testing.T, data []byte) *wrapperspb.Int64Value {
+	t.Helper()
+	msg := &wrapperspb.Int64Value{}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		t.Fatalf("failed to unmarshal snapshot: %v", err)
+	}
+	return msg
+}
+
+func TestRepositoryLoadLimit(t *testing.T) {
+	sqliteStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	// A counter with events 1..10 (total 55)
+	agg := newCounter("counter-1")
+	for value := int64(1); value <= 10; value++ {
+		if err := agg.add(value); err != nil {
+			t.Fatalf("failed to add: %v", err)
+		}
+	}
+	if err := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent).Save(agg); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	t.Run("RejectsOversizedAggregate", func(t *testing.T) {
+		// countingStore does not implement EventStreamer
+		es := &countingStore{EventStore: sqliteStore}
+		repo := store.NewRepository[*counter](es, "Counter", newCounter, applyCounterEvent,
+			store.WithMaxLoadEvents(5), store.WithStreamingLoad(3))
+
+		_, err := repo.Load("counter-1")
+		if !errors.Is(err, store.ErrTooManyEvents) {
+			t.Fatalf("expected ErrTooManyEvents, got %v", err)
+		}
+		if es.replays != 0 {
+			t.Errorf("expected no events to be loaded, got %d replays", es.replays)
+		}
+	})
+
+	t.Run("LoadsWithinLimit", func(t *testing.T) {
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+			store.WithMaxLoadEvents(10))
+
+		loaded, err := repo.Load("counter-1")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if loaded.total != 55 || loaded.Version() != 10 {
+			t.Errorf("expected total 55 at version 10, got %d at version %d", loaded.total, loaded.Version())
+		}
+	})
+
+	t.Run("StreamsOversizedAggregate", func(t *testing.T) {
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+			store.WithMaxLoadEvents(5), store.WithStreamingLoad(3))
+
+		loaded, err := repo.Load("counter-1")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if loaded.total != 55 || loaded.Version() != 10 {
+			t.Errorf("expected total 55 at version 10, got %d at version %d", loaded.total, loaded.Version())
+		}
+
+		// The streamed aggregate can be written to as usual
+		if err := loaded.add(11); err != nil {
+			t.Fatalf("failed to add: %v", err)
+		}
+		if err := repo.Save(loaded); err != nil {
+			t.Fatalf("failed to save streamed aggregate: %v", err)
+		}
+	})
+
+	t.Run("StreamsEventsAfterStaleSnapshot", func(t *testing.T) {
+		// The snapshot holds 100 more than the events, to tell it was used
+		snapshots := sqlite.NewSnapshotStore(sqliteStore.DB())
+		data, _ := proto.Marshal(wrapperspb.Int64(103)) // 1..2
+		if err := snapshots.SaveSnapshot(&store.Snapshot{
+			AggregateID:   "counter-1",
+			AggregateType: "Counter",
+			Version:       2,
+			Data:          data,
+			CreatedAt:     time.Now(),
+		}); err != nil {
+			t.Fatalf("failed to save snapshot: %v", err)
+		}
+
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+			store.WithMaxLoadEvents(5), store.WithStreamingLoad(3), store.WithSnapshotStore(snapshots))
+
+		loaded, err := repo.Load("counter-1")
+		if err != nil {
+			t.Fatalf("failed to load from snapshot: %v", err)
+		}
+		if loaded.total != 166 || loaded.Version() != 11 {
+			t.Errorf("expected total 166 (snapshot and events 3..11) at version 11, got %d at version %d", loaded.total, loaded.Version())
+		}
+	})
+
+	t.Run("LoadsEventsPage", func(t *testing.T) {
+		for name, es := range map[string]store.EventStore{
+			"Streamer":   sqliteStore,
+			"LoadEvents": &countingStore{EventStore: sqliteStore},
+		} {
+			page, err := store.LoadEventsPage(context.Background(), es, "counter-1", 4, 3)
+			if err != nil {
+				t.Fatalf("%s: failed to load page: %v", name, err)
+			}
+			if len(page) != 3 || page[0].Version != 5 || page[2].Version != 7 {
+				t.Errorf("%s: expected versions 5..7, got %d events", name, len(page))
+			}
+		}
+	})
+
+	t.Run("CountsEventsAfterSnapshot", func(t *testing.T) {
+		snapshots := sqlite.NewSnapshotStore(sqliteStore.DB())
+		data, _ := proto.Marshal(wrapperspb.Int64(36)) // 1..8
+		if err := snapshots.SaveSnapshot(&store.Snapshot{
+			AggregateID:   "counter-1",
+			AggregateType: "Counter",
+			Version:       8,
+			Data:          data,
+			CreatedAt:     time.Now(),
+		}); err != nil {
+			t.Fatalf("failed to save snapshot: %v", err)
+		}
+
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+			store.WithMaxLoadEvents(5), store.WithSnapshotStore(snapshots))
+
+		loaded, err := repo.Load("counter-1")
+		if err != nil {
+			t.Fatalf("failed to load from snapshot: %v", err)
+		}
+		if loaded.total != 66 || loaded.Version() != 11 {
+			t.Errorf("expected total 66 at version 11, got %d at version %d", loaded.total, loaded.Version())
+		}
+	})
+}
+
+func TestRepositoryLoadPartial(t *testing.T) {
+	sqliteStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	// A counter with additions 1..4 (total 10) and two bonuses of 100
+	agg := newCounter("counter-1")
+	for value := int64(1); value <= 4; value++ {
+		if err := agg.add(value); err != nil {
+			t.Fatalf("failed to add: %v", err)
+		}
+		if value%2 == 0 {
+			if err := agg.ApplyChange(wrapperspb.Int64(100), "counter.Bonus", domain.EventMetadata{}); err != nil {
+				t.Fatalf("failed to add bonus: %v", err)
+			}
+		}
+	}
+	if err := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent).Save(agg); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	t.Run("AppliesOnlyRequestedTypes", func(t *testing.T) {
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent)
+
+		loaded, err := repo.LoadPartial("counter-1", []string{"counter.Added"})
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if loaded.total != 10 || loaded.Version() != 6 || !loaded.IsPartial() {
+			t.Errorf("expected partial total 10 at version 6, got %d at version %d (partial %v)",
+				loaded.total, loaded.Version(), loaded.IsPartial())
+		}
+	})
+
+	t.Run("StreamsOversizedAggregate", func(t *testing.T) {
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent,
+			store.WithMaxLoadEvents(2), store.WithStreamingLoad(4))
+
+		loaded, err := repo.LoadPartial("counter-1", []string{"counter.Bonus"})
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if loaded.total != 200 || loaded.Version() != 6 {
+			t.Errorf("expected partial total 200 at version 6, got %d at version %d", loaded.total, loaded.Version())
+		}
+	})
+
+	t.Run("SavedPartialAggregateIsNotCached", func(t *testing.T) {
+		es := &countingStore{EventStore: sqliteStore}
+		repo := store.NewRepository[*counter](es, "Counter", newCounter, applyCounterEvent, store.WithAggregateCache(10))
+
+		partial, err := repo.LoadPartial("counter-1", []string{"counter.Added"})
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if err := partial.add(5); err != nil {
+			t.Fatalf("failed to add: %v", err)
+		}
+		if err := repo.Save(partial); err != nil {
+			t.Fatalf("failed to save partial aggregate: %v", err)
+		}
+
+		loaded, err := repo.Load("counter-1")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if loaded.total != 215 || loaded.IsPartial() {
+			t.Errorf("expected the full total 215, got %d (partial %v)", loaded.total, loaded.IsPartial())
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent)
+		if _, err := repo.LoadPartial("missing", []string{"counter.Added"}); !errors.Is(err, domain.ErrAggregateNotFound) {
+			t.Errorf("expected ErrAggregateNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRepositoryCacheSkipsDeduplicatedCommands(t *testing.T) {
+	sqliteStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false), sqlite.WithContentDedup(1))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer sqliteStore.Close()
+
+	es := &countingStore{EventStore: sqliteStore}
+	repo := store.NewRepository[*counter](es, "Counter", newCounter, applyCounterEvent, store.WithAggregateCache(10))
+
+	agg := newCounter("counter-1")
+	if err := agg.add(5); err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+	if _, err := repo.SaveWithCommand(agg, "cmd-1"); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	// loadAfter applies add(5) under commandID to the current aggregate and
+	// returns the aggregate loaded next, and whether that load found it in
+	// the cache
+	loadAfter := func(commandID string) (*counter, *domain.CommandResult, bool) {
+		t.Helper()
+		loaded, err := repo.Load("counter-1")
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		if err := loaded.add(5); err != nil {
+			t.Fatalf("failed to add: %v", err)
+		}
+		result, err := repo.SaveWithCommand(loaded, commandID)
+		if err != nil {
+			t.Fatalf("failed to save: %v", err)
+		}
+		versionChecks := es.versionChecks
+		reloaded, err := repo.Load("counter-1")
+		if err != nil {
+			t.Fatalf("failed to reload: %v", err)
+		}
+		return reloaded, result, es.versionChecks > versionChecks
+	}
+
+	t.Run("AlreadyProcessed", func(t *testing.T) {
+		loaded, result, cached := loadAfter("cmd-1")
+		if !result.AlreadyProcessed {
+			t.Fatal("expected the retried command to be reported as already processed")
+		}
+		if cached || loaded.total != 5 {
+			t.Errorf("expected a replay to the stored total 5, got %d (cached %v)", loaded.total, cached)
+		}
+	})
+
+	t.Run("ContentDeduplicated", func(t *testing.T) {
+		loaded, result, cached := loadAfter("cmd-2")
+		if len(result.Deduplicated) != 1 {
+			t.Fatalf("expected the repeated event to be deduplicated, got %d", len(result.Deduplicated))
+		}
+		if cached || loaded.total != 5 {
+			t.Errorf("expected a replay to the stored total 5, got %d (cached %v)", loaded.total, cached)
+		}
+	})
+}
+
+func TestRepositorySaveWithIdempotencyKey(t *testing.T) {
+	sqliteStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer sqliteStore.Close()
+	repo := store.NewRepository[*counter](sqliteStore, "Counter", newCounter, applyCounterEvent)
+
+	// Two requests carrying the same key, each handled from scratch as a
+	// retried HTTP request would be
+	for attempt := range 2 {
+		agg := newCounter("counter-1")
+		agg.SetCommandID(domain.GenerateID()) // the client forgot to reuse its command ID
+		if err := agg.add(5); err != nil {
+			t.Fatalf("failed to add: %v", err)
+		}
+		result, err := repo.SaveWithIdempotencyKey(agg, "payment-42")
+		if err != nil {
+			t.Fatalf("attempt %d: failed to save: %v", attempt, err)
+		}
+		if result.AlreadyProcessed != (attempt == 1) {
+			t.Errorf("attempt %d: unexpected AlreadyProcessed %v", attempt, result.AlreadyProcessed)
+		}
+	}
+
+	events, err := sqliteStore.LoadEvents(context.Background(), "counter-1", 0)
+	if err != nil {
+		t.Fatalf("failed to load events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("expected 1 event, got %d", len(events))
+	}
+
+	if domain.IdempotencyCommandID("counter-1", "payment-42") == domain.IdempotencyCommandID("counter-2", "payment-42") {
+		t.Error("expected the command ID to be scoped to the aggregate")
+	}
+	meta := domain.CommandMetadata{CommandID: "cmd-1", IdempotencyKey: "payment-42"}
+	if meta.EffectiveCommandID("counter-1") != domain.IdempotencyCommandID("counter-1", "payment-42") {
+		t.Error("expected the idempotency key to take precedence over the command ID")
+	}
+
+	// SaveWithMetadata reads the key from the command metadata, whatever its
+	// command ID
+	agg := newCounter("counter-1")
+	if err := agg.add(5); err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+	result, err := repo.SaveWithMetadata(agg, domain.CommandMetadata{CommandID: "cmd-2", IdempotencyKey: "payment-42"})
+	if err != nil {
+		t.Fatalf("failed to save with metadata: %v", err)
+	}
+	if !result.AlreadyProcessed {
+		t.Error("expected SaveWithMetadata to deduplicate on the idempotency key")
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrSagaNotFound is returned for a saga instance that does not exist.
+var ErrSagaNotFound = errors.New("saga instance not found")
+
+// SagaStatus represents the lifecycle status of a saga instance.
+type SagaStatus string
+
+const (
+	// SagaStatusRunning indicates the saga is waiting for its next event
+	SagaStatusRunning SagaStatus = "RUNNING"
+
+	// SagaStatusCompleted indicates the saga reached its final step
+	SagaStatusCompleted SagaStatus = "COMPLETED"
+
+	// SagaStatusCompensated indicates a command was rejected or the saga timed
+	// out, and the steps it had taken were compensated
+	SagaStatusCompensated SagaStatus = "COMPENSATED"
+
+	// SagaStatusFailed indicates a compensation failed; the instance needs
+	// manual attention
+	SagaStatusFailed SagaStatus = "FAILED"
+)
+
+// SagaInstance is the persisted state of one run of a saga (see pkg/saga).
+type SagaInstance struct {
+	SagaName string
+	ID       string // Correlation ID of the commands and events of the run
+	Status   SagaStatus
+
+	// Data is the state the saga's steps share, e.g. the accounts and amount
+	// of a money transfer
+	Data map[string]string
+
+	// Steps are the steps taken so far, in order; they are compensated in
+	// reverse order
+	Steps []string
+
+	Deadline  time.Time // Zero if the saga has no timeout
+	Error     string    // Why the saga compensated or failed
+	UpdatedAt time.Time
+}
+
+// SagaStore persists saga instances.
+type SagaStore interface {
+	// Save creates or replaces an instance.
+	Save(ctx context.Context, instance *SagaInstance) error
+
+	// Load loads an instance, or returns ErrSagaNotFound.
+	Load(ctx context.Context, sagaName, id string) (*SagaInstance, error)
+
+	// ListExpired returns the running instances of sagaName whose deadline
+	// is before now.
+	ListExpired(ctx context.Context, sagaName string, now time.Time) ([]*SagaInstance, error)
+
+	// Delete deletes an instance.
+	Delete(ctx context.Context, sagaName, id string) error
+}
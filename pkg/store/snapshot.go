@@ -17,11 +17,12 @@ type Snapshot struct {
 
 // SnapshotMetadata contains information about the snapshot.
 type SnapshotMetadata struct {
-	Size          int64  `json:"size"`           // Size of the snapshot in bytes
-	EventCount    int64  `json:"event_count"`    // Number of events included
-	CreationTime  int64  `json:"creation_time"`  // Time taken to create snapshot (ms)
-	SnapshotType  string `json:"snapshot_type"`  // Type of serialization used
-	SchemaVersion string `json:"schema_version"` // Version of the aggregate schema
+	Size          int64  `json:"size"`                  // Size of the snapshot in bytes
+	EventCount    int64  `json:"event_count"`           // Number of events included
+	CreationTime  int64  `json:"creation_time"`         // Time taken to create snapshot (ms)
+	SnapshotType  string `json:"snapshot_type"`         // Type of serialization used
+	SchemaVersion string `json:"schema_version"`        // Version of the aggregate schema
+	Compression   string `json:"compression,omitempty"` // SnapshotCodec the data was stored with ("" = none)
 }
 
 // MarshalMetadata serializes the snapshot metadata to JSON.
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Names of the built-in snapshot codecs, as recorded in
+// SnapshotMetadata.Compression.
+const (
+	SnapshotCompressionGzip = "gzip"
+	SnapshotCompressionZstd = "zstd"
+)
+
+// SnapshotCodec compresses snapshot data. Its name is stored with each
+// snapshot, so a snapshot store can decompress snapshots written with a
+// codec other than its own, or none.
+type SnapshotCodec interface {
+	// Name identifies the codec in SnapshotMetadata.Compression.
+	Name() string
+
+	// Compress returns the compressed form of data.
+	Compress(data []byte) ([]byte, error)
+
+	// Decompress reverses Compress.
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipSnapshotCodec compresses snapshots with gzip.
+type GzipSnapshotCodec struct {
+	level int
+}
+
+// NewGzipSnapshotCodec creates a gzip codec with a compression level from
+// gzip.BestSpeed to gzip.BestCompression, or gzip.DefaultCompression.
+func NewGzipSnapshotCodec(level int) (*GzipSnapshotCodec, error) {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		return nil, err
+	}
+	return &GzipSnapshotCodec{level: level}, nil
+}
+
+// Name returns SnapshotCompressionGzip.
+func (c *GzipSnapshotCodec) Name() string {
+	return SnapshotCompressionGzip
+}
+
+// Compress gzips data.
+func (c *GzipSnapshotCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, c.level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress gunzips data.
+func (c *GzipSnapshotCodec) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// ZstdSnapshotCodec compresses snapshots with Zstandard, which compresses
+// about as well as gzip at a fraction of the CPU cost. It is safe for
+// concurrent use.
+type ZstdSnapshotCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// NewZstdSnapshotCodec creates a Zstandard codec with the default level.
+func NewZstdSnapshotCodec() (*ZstdSnapshotCodec, error) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdSnapshotCodec{encoder: encoder, decoder: decoder}, nil
+}
+
+// Name returns SnapshotCompressionZstd.
+func (c *ZstdSnapshotCodec) Name() string {
+	return SnapshotCompressionZstd
+}
+
+// Compress compresses data with Zstandard.
+func (c *ZstdSnapshotCodec) Compress(data []byte) ([]byte, error) {
+	return c.encoder.EncodeAll(data, nil), nil
+}
+
+// Decompress decompresses Zstandard data.
+func (c *ZstdSnapshotCodec) Decompress(data []byte) ([]byte, error) {
+	return c.decoder.DecodeAll(data, nil)
+}
+
+// defaultZstdCodec is shared by DecompressSnapshot, as zstd codecs are costly
+// to create.
+var defaultZstdCodec = sync.OnceValues(NewZstdSnapshotCodec)
+
+// DecompressSnapshot returns the uncompressed data of a snapshot stored with
+// the named compression ("" = none). codec is used if its name matches,
+// otherwise the built-in codec of that name.
+func DecompressSnapshot(compression string, data []byte, codec SnapshotCodec) ([]byte, error) {
+	switch {
+	case compression == "":
+		return data, nil
+	case codec != nil && codec.Name() == compression:
+	case compression == SnapshotCompressionGzip:
+		codec = &GzipSnapshotCodec{}
+	case compression == SnapshotCompressionZstd:
+		zstdCodec, err := defaultZstdCodec()
+		if err != nil {
+			return nil, err
+		}
+		codec = zstdCodec
+	default:
+		return nil, fmt.Errorf("unknown snapshot compression %q", compression)
+	}
+
+	decompressed, err := codec.Decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s snapshot: %w", compression, err)
+	}
+	return decompressed, nil
+}
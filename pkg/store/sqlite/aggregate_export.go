@@ -0,0 +1,405 @@
+package sqlite
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// AggregateExportFormat identifies the bundle format written by
+// ExportAggregate. ImportAggregate rejects bundles of other formats.
+const AggregateExportFormat = "eventstore.aggregate/v1"
+
+// ErrUnsupportedExportFormat is returned by ImportAggregate for a bundle that
+// is not in AggregateExportFormat.
+var ErrUnsupportedExportFormat = errors.New("unsupported aggregate export format")
+
+// AggregateExport is the bundle written by ExportAggregate: a single JSON
+// object holding everything the store knows about one aggregate. Byte fields
+// (event and snapshot data) are base64-encoded and timestamps are RFC 3339.
+//
+//	{
+//	  "format": "eventstore.aggregate/v1",
+//	  "aggregate_id": "acc-123",
+//	  "exported_at": "2025-01-02T15:04:05Z",
+//	  "redacted": true,
+//	  "snapshots": [{"aggregate_type": "Account", "version": 100, "data": "...", "created_at": "...", "metadata": {...}}],
+//	  "unique_constraints": [{"index_name": "account_email", "value": "a@example.com", "created_at": "..."}],
+//	  "events": [{"id": "...", "aggregate_type": "Account", "event_type": "account.v1.AccountOpened",
+//	              "version": 1, "timestamp": "...", "data": "...", "metadata": {"principal_id": "..."},
+//	              "unique_constraints": [{"index_name": "account_email", "value": "a@example.com", "operation": "claim"}]}]
+//	}
+//
+// Events come last, in version order, so that ExportAggregate can stream them.
+type AggregateExport struct {
+	Format      string    `json:"format"`
+	AggregateID string    `json:"aggregate_id"`
+	ExportedAt  time.Time `json:"exported_at"`
+
+	// Redacted reports that the bundle was written with redaction hooks, so
+	// payloads may differ from the store's.
+	Redacted bool `json:"redacted,omitempty"`
+
+	Snapshots []ExportedSnapshot `json:"snapshots"`
+
+	// UniqueConstraints are the values the aggregate held when exported,
+	// omitted from bundles written with WithEventRedactor. ImportAggregate
+	// derives the claims from the events instead.
+	UniqueConstraints []ExportedConstraintClaim `json:"unique_constraints"`
+
+	Events []ExportedEvent `json:"events"`
+}
+
+// ExportedEvent is an event in an AggregateExport.
+type ExportedEvent struct {
+	ID                string                     `json:"id"`
+	AggregateType     string                     `json:"aggregate_type"`
+	EventType         string                     `json:"event_type"`
+	Version           int64                      `json:"version"`
+	Timestamp         time.Time                  `json:"timestamp"`
+	Data              []byte                     `json:"data"`
+	Metadata          ExportedEventMetadata      `json:"metadata"`
+	UniqueConstraints []ExportedConstraintChange `json:"unique_constraints,omitempty"`
+}
+
+// ExportedEventMetadata is the metadata of an ExportedEvent.
+type ExportedEventMetadata struct {
+	CausationID   string            `json:"causation_id,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	PrincipalID   string            `json:"principal_id,omitempty"`
+	TenantID      string            `json:"tenant_id,omitempty"`
+	Custom        map[string]string `json:"custom,omitempty"`
+}
+
+// ExportedConstraintChange is a unique constraint claimed or released by an
+// ExportedEvent.
+type ExportedConstraintChange struct {
+	IndexName string                     `json:"index_name"`
+	Value     string                     `json:"value"`
+	Operation domain.ConstraintOperation `json:"operation"`
+}
+
+// ExportedConstraintClaim is a unique value held by the exported aggregate.
+type ExportedConstraintClaim struct {
+	IndexName string    `json:"index_name"`
+	Value     string    `json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportedSnapshot is a snapshot in an AggregateExport.
+type ExportedSnapshot struct {
+	AggregateType string                  `json:"aggregate_type"`
+	Version       int64                   `json:"version"`
+	Data          []byte                  `json:"data"`
+	CreatedAt     time.Time               `json:"created_at"`
+	Metadata      *store.SnapshotMetadata `json:"metadata,omitempty"`
+}
+
+// ExportOption configures ExportAggregate.
+type ExportOption func(*exportConfig)
+
+type exportConfig struct {
+	redactEvent    func(event *domain.Event) error
+	redactSnapshot func(snapshot *store.Snapshot) error
+}
+
+// WithEventRedactor passes every event through redact before it is written,
+// e.g. to decode the payload, blank personal fields and re-encode it, or to
+// clear Metadata.PrincipalID. Unique constraint values (often e-mail addresses)
+// are part of the event too; the aggregate's current claims are left out of
+// the bundle so that they cannot bypass the redactor. Changes stay in the
+// bundle; the store is not modified. An error aborts the export.
+func WithEventRedactor(redact func(event *domain.Event) error) ExportOption {
+	return func(c *exportConfig) {
+		c.redactEvent = redact
+	}
+}
+
+// WithSnapshotRedactor passes every snapshot through redact before it is
+// written, like WithEventRedactor. Dropping snapshots is often simpler: set
+// snapshot.Data to nil to omit one, and the importer replays events instead.
+func WithSnapshotRedactor(redact func(snapshot *store.Snapshot) error) ExportOption {
+	return func(c *exportConfig) {
+		c.redactSnapshot = redact
+	}
+}
+
+// ExportAggregate writes the events, snapshots and unique constraint claims of
+// an aggregate to w as an AggregateExport, e.g. for a support engineer to hand
+// a customer's history to engineering without copying the database. Events are
+// streamed, so long histories are not held in memory. Returns
+// domain.ErrAggregateNotFound if the aggregate has no events.
+//
+// Writes that happen during the export may or may not be included.
+func (s *EventStore) ExportAggregate(ctx context.Context, aggregateID string, w io.Writer, opts ...ExportOption) error {
+	var config exportConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	version, err := s.GetAggregateVersion(aggregateID)
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrAggregateNotFound, aggregateID)
+	}
+
+	snapshots, err := s.exportSnapshots(ctx, aggregateID, config.redactSnapshot)
+	if err != nil {
+		return err
+	}
+	claims := []ExportedConstraintClaim{}
+	if config.redactEvent == nil {
+		if claims, err = s.exportConstraintClaims(ctx, aggregateID); err != nil {
+			return err
+		}
+	}
+
+	header := AggregateExport{
+		Format:            AggregateExportFormat,
+		AggregateID:       aggregateID,
+		ExportedAt:        time.Now().UTC(),
+		Redacted:          config.redactEvent != nil || config.redactSnapshot != nil,
+		Snapshots:         snapshots,
+		UniqueConstraints: claims,
+	}
+	data, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("failed to marshal export: %w", err)
+	}
+
+	// Splice the streamed events into the header, which ends in "events":null}
+	bw := bufio.NewWriter(w)
+	bw.Write(data[:len(data)-len(`null}`)])
+	bw.WriteString("[")
+	first := true
+	for event, err := range s.StreamEvents(aggregateID, 0, 0) {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if config.redactEvent != nil {
+			if err := config.redactEvent(event); err != nil {
+				return fmt.Errorf("failed to redact event %s: %w", event.ID, err)
+			}
+		}
+		data, err := json.Marshal(exportEvent(event))
+		if err != nil {
+			return fmt.Errorf("failed to marshal event %s: %w", event.ID, err)
+		}
+		if !first {
+			bw.WriteString(",")
+		}
+		first = false
+		bw.WriteString("\n")
+		bw.Write(data)
+	}
+	bw.WriteString("\n]}\n")
+	return bw.Flush()
+}
+
+// ImportAggregate loads a bundle written by ExportAggregate, typically into a
+// scratch store to reproduce a problem. Events are imported with their
+// original IDs, timestamps and metadata (see ImportEvents), re-claiming their
+// unique constraints, and the snapshots are restored. The aggregate must not
+// exist in the store yet. It returns the imported bundle.
+func (s *EventStore) ImportAggregate(ctx context.Context, r io.Reader) (*AggregateExport, error) {
+	var bundle AggregateExport
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("failed to decode export: %w", err)
+	}
+	if bundle.Format != AggregateExportFormat {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedExportFormat, bundle.Format)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	events := make([]*domain.Event, len(bundle.Events))
+	for i, exported := range bundle.Events {
+		events[i] = exported.toEvent(bundle.AggregateID)
+	}
+	if err := s.ImportEvents(bundle.AggregateID, 0, events); err != nil {
+		return nil, fmt.Errorf("failed to import events of %s: %w", bundle.AggregateID, err)
+	}
+
+	snapshots := NewSnapshotStore(s.db, WithSnapshotTablePrefix(string(s.tables)))
+	for _, exported := range bundle.Snapshots {
+		if err := snapshots.SaveSnapshot(&store.Snapshot{
+			AggregateID:   bundle.AggregateID,
+			AggregateType: exported.AggregateType,
+			Version:       exported.Version,
+			Data:          exported.Data,
+			CreatedAt:     exported.CreatedAt,
+			Metadata:      exported.Metadata,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return &bundle, nil
+}
+
+// exportSnapshots loads the snapshots of an aggregate in version order.
+func (s *EventStore) exportSnapshots(ctx context.Context, aggregateID string, redact func(*store.Snapshot) error) ([]ExportedSnapshot, error) {
+	s.mu.RLock()
+	rows, err := s.db.QueryContext(ctx, s.tables.rewrite(`
+		SELECT aggregate_type, version, data, created_at, metadata FROM snapshots
+		WHERE aggregate_id = ?
+		ORDER BY version`), aggregateID)
+	if err != nil {
+		s.mu.RUnlock()
+		return nil, fmt.Errorf("failed to load snapshots: %w", classifyError(err))
+	}
+	var loaded []*store.Snapshot
+	for rows.Next() {
+		var (
+			snapshot  = &store.Snapshot{AggregateID: aggregateID}
+			createdAt int64
+			metadata  sql.NullString
+		)
+		if err := rows.Scan(&snapshot.AggregateType, &snapshot.Version, &snapshot.Data, &createdAt, &metadata); err != nil {
+			rows.Close()
+			s.mu.RUnlock()
+			return nil, fmt.Errorf("failed to scan snapshot: %w", classifyError(err))
+		}
+		snapshot.CreatedAt = time.Unix(createdAt, 0)
+		if metadata.Valid && metadata.String != "" {
+			if snapshot.Metadata, err = store.UnmarshalMetadata(metadata.String); err != nil {
+				rows.Close()
+				s.mu.RUnlock()
+				return nil, fmt.Errorf("failed to unmarshal snapshot metadata: %w", err)
+			}
+		}
+		// Bundles carry snapshots uncompressed, whatever the importing store uses
+		if snapshot.Metadata != nil && snapshot.Metadata.Compression != "" {
+			if snapshot.Data, err = store.DecompressSnapshot(snapshot.Metadata.Compression, snapshot.Data, nil); err != nil {
+				rows.Close()
+				s.mu.RUnlock()
+				return nil, err
+			}
+			snapshot.Metadata.Compression = ""
+		}
+		loaded = append(loaded, snapshot)
+	}
+	rows.Close()
+	s.mu.RUnlock()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load snapshots: %w", classifyError(err))
+	}
+
+	exported := make([]ExportedSnapshot, 0, len(loaded))
+	for _, snapshot := range loaded {
+		if redact != nil {
+			if err := redact(snapshot); err != nil {
+				return nil, fmt.Errorf("failed to redact snapshot %d: %w", snapshot.Version, err)
+			}
+			if snapshot.Data == nil {
+				continue
+			}
+		}
+		exported = append(exported, ExportedSnapshot{
+			AggregateType: snapshot.AggregateType,
+			Version:       snapshot.Version,
+			Data:          snapshot.Data,
+			CreatedAt:     snapshot.CreatedAt.UTC(),
+			Metadata:      snapshot.Metadata,
+		})
+	}
+	return exported, nil
+}
+
+// exportConstraintClaims loads the unique values an aggregate holds.
+func (s *EventStore) exportConstraintClaims(ctx context.Context, aggregateID string) ([]ExportedConstraintClaim, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.QueryContext(ctx, s.tables.rewrite(`
+		SELECT index_name, value, created_at FROM unique_constraints
+		WHERE aggregate_id = ?
+		ORDER BY index_name, value`), aggregateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load constraints: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	claims := []ExportedConstraintClaim{}
+	for rows.Next() {
+		var (
+			claim     ExportedConstraintClaim
+			createdAt int64
+		)
+		if err := rows.Scan(&claim.IndexName, &claim.Value, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan constraint: %w", classifyError(err))
+		}
+		claim.CreatedAt = time.Unix(createdAt, 0).UTC()
+		claims = append(claims, claim)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load constraints: %w", classifyError(err))
+	}
+	return claims, nil
+}
+
+func exportEvent(event *domain.Event) ExportedEvent {
+	exported := ExportedEvent{
+		ID:            event.ID,
+		AggregateType: event.AggregateType,
+		EventType:     event.EventType,
+		Version:       event.Version,
+		Timestamp:     event.Timestamp.UTC(),
+		Data:          event.Data,
+		Metadata: ExportedEventMetadata{
+			CausationID:   event.Metadata.CausationID,
+			CorrelationID: event.Metadata.CorrelationID,
+			PrincipalID:   event.Metadata.PrincipalID,
+			TenantID:      event.Metadata.TenantID,
+			Custom:        event.Metadata.Custom,
+		},
+	}
+	for _, constraint := range event.UniqueConstraints {
+		exported.UniqueConstraints = append(exported.UniqueConstraints, ExportedConstraintChange{
+			IndexName: constraint.IndexName,
+			Value:     constraint.Value,
+			Operation: constraint.Operation,
+		})
+	}
+	return exported
+}
+
+func (e ExportedEvent) toEvent(aggregateID string) *domain.Event {
+	event := &domain.Event{
+		ID:            e.ID,
+		AggregateID:   aggregateID,
+		AggregateType: e.AggregateType,
+		EventType:     e.EventType,
+		Version:       e.Version,
+		Timestamp:     e.Timestamp,
+		Data:          e.Data,
+		Metadata: domain.EventMetadata{
+			CausationID:   e.Metadata.CausationID,
+			CorrelationID: e.Metadata.CorrelationID,
+			PrincipalID:   e.Metadata.PrincipalID,
+			TenantID:      e.Metadata.TenantID,
+			Custom:        e.Metadata.Custom,
+		},
+	}
+	for _, constraint := range e.UniqueConstraints {
+		event.UniqueConstraints = append(event.UniqueConstraints, domain.UniqueConstraint{
+			IndexName: constraint.IndexName,
+			Value:     constraint.Value,
+			Operation: constraint.Operation,
+		})
+	}
+	return event
+}
@@ -0,0 +1,185 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestExportAggregate(t *testing.T) {
+	ctx := context.Background()
+	newStore := func(t *testing.T) *sqlite.EventStore {
+		t.Helper()
+		eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		t.Cleanup(func() { eventStore.Close() })
+		return eventStore
+	}
+
+	source := newStore(t)
+	timestamp := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var events []*domain.Event
+	for i := 1; i <= 5; i++ {
+		event := &domain.Event{
+			ID:            fmt.Sprintf("evt-%d", i),
+			AggregateID:   "cust-1",
+			AggregateType: "Customer",
+			EventType:     "customer.v1.Updated",
+			Version:       int64(i),
+			Timestamp:     timestamp.Add(time.Duration(i) * time.Second),
+			Data:          []byte(fmt.Sprintf("email=alice-%d@example.com", i)),
+			Metadata: domain.EventMetadata{
+				PrincipalID:   "alice@example.com",
+				CorrelationID: "corr-1",
+				CausationID:   fmt.Sprintf("cmd-%d", i),
+				TenantID:      "tenant-a",
+				Custom:        map[string]string{"channel": "web"},
+			},
+		}
+		if i == 1 {
+			event.UniqueConstraints = []domain.UniqueConstraint{
+				{IndexName: "customer_email", Value: "alice@example.com", Operation: domain.ConstraintClaim},
+			}
+		}
+		events = append(events, event)
+	}
+	if err := source.AppendEvents(ctx, "cust-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+	snapshot := &store.Snapshot{
+		AggregateID:   "cust-1",
+		AggregateType: "Customer",
+		Version:       3,
+		Data:          []byte("state@3"),
+		CreatedAt:     timestamp,
+		Metadata:      &store.SnapshotMetadata{EventCount: 3, SnapshotType: "proto"},
+	}
+	if err := sqlite.NewSnapshotStore(source.DB()).SaveSnapshot(snapshot); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var bundle bytes.Buffer
+		if err := source.ExportAggregate(ctx, "cust-1", &bundle); err != nil {
+			t.Fatalf("failed to export: %v", err)
+		}
+
+		scratch := newStore(t)
+		imported, err := scratch.ImportAggregate(ctx, &bundle)
+		if err != nil {
+			t.Fatalf("failed to import: %v", err)
+		}
+		if imported.Redacted || len(imported.UniqueConstraints) != 1 {
+			t.Errorf("unexpected bundle header: %+v", imported)
+		}
+
+		want, err := source.LoadEvents(ctx, "cust-1", 0)
+		if err != nil {
+			t.Fatalf("failed to load source events: %v", err)
+		}
+		got, err := scratch.LoadEvents(ctx, "cust-1", 0)
+		if err != nil {
+			t.Fatalf("failed to load imported events: %v", err)
+		}
+		for _, event := range append(want, got...) {
+			event.Position = 0 // Positions are store-specific
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("imported events differ:\ngot  %+v\nwant %+v", got, want)
+		}
+
+		owner, err := scratch.GetConstraintOwner("customer_email", "alice@example.com")
+		if err != nil || owner != "cust-1" {
+			t.Errorf("expected the claim to be restored, got %q (%v)", owner, err)
+		}
+
+		restored, err := sqlite.NewSnapshotStore(scratch.DB()).GetLatestSnapshot("cust-1")
+		if err != nil {
+			t.Fatalf("failed to load imported snapshot: %v", err)
+		}
+		if restored.Version != 3 || string(restored.Data) != "state@3" ||
+			!restored.CreatedAt.Equal(snapshot.CreatedAt) || restored.Metadata.EventCount != 3 {
+			t.Errorf("unexpected imported snapshot: %+v", restored)
+		}
+
+		// The aggregate already exists now
+		var again bytes.Buffer
+		if err := source.ExportAggregate(ctx, "cust-1", &again); err != nil {
+			t.Fatalf("failed to export: %v", err)
+		}
+		if _, err := scratch.ImportAggregate(ctx, &again); !errors.Is(err, domain.ErrConcurrencyConflict) {
+			t.Errorf("expected ErrConcurrencyConflict, got %v", err)
+		}
+	})
+
+	t.Run("Redaction", func(t *testing.T) {
+		var bundle bytes.Buffer
+		err := source.ExportAggregate(ctx, "cust-1", &bundle,
+			sqlite.WithEventRedactor(func(event *domain.Event) error {
+				event.Data = []byte("redacted")
+				event.Metadata.PrincipalID = ""
+				for i := range event.UniqueConstraints {
+					event.UniqueConstraints[i].Value = "redacted@example.com"
+				}
+				return nil
+			}),
+			sqlite.WithSnapshotRedactor(func(snapshot *store.Snapshot) error {
+				snapshot.Data = nil
+				return nil
+			}))
+		if err != nil {
+			t.Fatalf("failed to export: %v", err)
+		}
+		if strings.Contains(bundle.String(), "alice") {
+			t.Errorf("expected personal data to be redacted, got %s", bundle.String())
+		}
+
+		scratch := newStore(t)
+		imported, err := scratch.ImportAggregate(ctx, &bundle)
+		if err != nil {
+			t.Fatalf("failed to import: %v", err)
+		}
+		if !imported.Redacted || len(imported.Snapshots) != 0 || len(imported.UniqueConstraints) != 0 || len(imported.Events) != 5 {
+			t.Errorf("unexpected redacted bundle: %+v", imported)
+		}
+		loaded, err := scratch.LoadEvents(ctx, "cust-1", 0)
+		if err != nil {
+			t.Fatalf("failed to load imported events: %v", err)
+		}
+		if string(loaded[0].Data) != "redacted" || loaded[0].Metadata.PrincipalID != "" {
+			t.Errorf("expected the redacted event, got %+v", loaded[0])
+		}
+
+		// The source store is untouched
+		original, err := source.LoadEvents(ctx, "cust-1", 0)
+		if err != nil {
+			t.Fatalf("failed to load source events: %v", err)
+		}
+		if original[0].Metadata.PrincipalID != "alice@example.com" {
+			t.Errorf("expected the source to keep its metadata, got %+v", original[0].Metadata)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		var bundle bytes.Buffer
+		if err := source.ExportAggregate(ctx, "cust-unknown", &bundle); !errors.Is(err, domain.ErrAggregateNotFound) {
+			t.Errorf("expected ErrAggregateNotFound, got %v", err)
+		}
+
+		_, err := newStore(t).ImportAggregate(ctx, strings.NewReader(`{"format":"other/v9","aggregate_id":"x"}`))
+		if !errors.Is(err, sqlite.ErrUnsupportedExportFormat) {
+			t.Errorf("expected ErrUnsupportedExportFormat, got %v", err)
+		}
+	})
+}
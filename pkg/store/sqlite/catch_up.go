@@ -0,0 +1,180 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/messaging"
+)
+
+// WithEventBus sets the bus that SubscribeFromPosition switches to once it
+// has caught up with the stored events. It must carry the events appended to
+// this store, published after AppendEvents returned so they have positions.
+func WithEventBus(bus messaging.EventBus) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.eventBus = bus
+	}
+}
+
+// CatchUpSubscription is a subscription started by SubscribeFromPosition.
+type CatchUpSubscription struct {
+	store   *EventStore
+	ctx     context.Context
+	handler messaging.EventHandler
+	done    chan struct{}
+
+	bus      messaging.Subscription
+	stopOnce sync.Once
+	position atomic.Int64 // Position of the last event handled
+
+	mu       sync.Mutex
+	live     bool                    // Caught up; bus events are handled as they arrive
+	buffered []*domain.EventEnvelope // Bus events received while catching up
+}
+
+// SubscribeFromPosition hands handler all events after fromPosition: first the
+// stored ones, read from the database, then those arriving on the event bus
+// (see WithEventBus). This brings a new projection online against an existing
+// store without a rebuild:
+//
+//	sub, err := eventStore.SubscribeFromPosition(ctx, checkpoint.Position, handler)
+//
+// The bus subscription is opened before reading the database, and bus events
+// received meanwhile are buffered, so nothing appended during the catch-up is
+// missed. Events are deduplicated on their global position: the handler sees
+// each position once, in increasing order. When a bus event skips positions,
+// e.g. because the bus delivered out of order, the missing events are read
+// from the database first. Bus events without a position are handed over as
+// they arrive.
+//
+// SubscribeFromPosition returns once the stored events are handled; an error
+// from handler during the catch-up is returned. Afterwards handler errors are
+// returned to the bus, which redelivers the event. The subscription ends when
+// ctx is done or Unsubscribe is called.
+func (s *EventStore) SubscribeFromPosition(ctx context.Context, fromPosition int64, handler messaging.EventHandler) (*CatchUpSubscription, error) {
+	if s.eventBus == nil {
+		return nil, errors.New("catch-up subscriptions need an event bus, see WithEventBus")
+	}
+
+	sub := &CatchUpSubscription{
+		store:   s,
+		ctx:     ctx,
+		handler: handler,
+		done:    make(chan struct{}),
+	}
+	sub.position.Store(fromPosition)
+
+	bus, err := s.eventBus.Subscribe(messaging.EventFilter{}, sub.handleLive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to event bus: %w", err)
+	}
+	sub.bus = bus
+
+	if err := sub.catchUp(); err != nil {
+		sub.Unsubscribe()
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Unsubscribe()
+		case <-sub.done:
+		}
+	}()
+	return sub, nil
+}
+
+// catchUp handles the stored events, then the buffered bus events, and
+// switches the subscription to live.
+func (c *CatchUpSubscription) catchUp() error {
+	// The bulk is read without holding the lock, so the bus can keep buffering
+	if err := c.handleStored(0); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, envelope := range c.buffered {
+		if err := c.handleLocked(envelope); err != nil {
+			return err
+		}
+	}
+	c.buffered = nil
+	c.live = true
+	return nil
+}
+
+// handleLive receives events from the bus.
+func (c *CatchUpSubscription) handleLive(envelope *domain.EventEnvelope) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.live {
+		c.buffered = append(c.buffered, envelope)
+		return nil
+	}
+	return c.handleLocked(envelope)
+}
+
+// handleLocked hands a bus event to the handler unless its position was
+// handled already, after reading any events before it that were skipped.
+func (c *CatchUpSubscription) handleLocked(envelope *domain.EventEnvelope) error {
+	position := envelope.Event.Position
+	if position == 0 {
+		return c.handler(envelope)
+	}
+	if position > c.position.Load()+1 {
+		if err := c.handleStored(position - 1); err != nil {
+			return err
+		}
+	}
+	if position <= c.position.Load() {
+		return nil // Duplicate
+	}
+
+	if err := c.handler(envelope); err != nil {
+		return err
+	}
+	c.position.Store(position)
+	return nil
+}
+
+// handleStored hands the stored events after the current position and up to
+// until (0 = all) to the handler.
+func (c *CatchUpSubscription) handleStored(until int64) error {
+	after := c.position.Load()
+	for event, err := range c.store.StreamAllEvents(c.ctx, after+1) {
+		if err != nil {
+			return fmt.Errorf("failed to read events after position %d: %w", after, err)
+		}
+		if until > 0 && event.Position > until {
+			return nil
+		}
+		if err := c.handler(&domain.EventEnvelope{Event: *event}); err != nil {
+			return fmt.Errorf("failed to handle event %s at position %d: %w", event.ID, event.Position, err)
+		}
+		c.position.Store(event.Position)
+	}
+	return nil
+}
+
+// Position returns the position of the last event handled.
+func (c *CatchUpSubscription) Position() int64 {
+	return c.position.Load()
+}
+
+// Unsubscribe stops the subscription.
+func (c *CatchUpSubscription) Unsubscribe() error {
+	var err error
+	c.stopOnce.Do(func() {
+		close(c.done)
+		err = c.bus.Unsubscribe()
+	})
+	return err
+}
@@ -0,0 +1,222 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/messaging"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+// syncBus is an event bus delivering published events synchronously.
+type syncBus struct {
+	mu       sync.Mutex
+	handlers map[int]messaging.EventHandler
+	next     int
+}
+
+func (b *syncBus) Publish(events []*domain.Event) error {
+	b.mu.Lock()
+	handlers := make([]messaging.EventHandler, 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.Unlock()
+
+	for _, event := range events {
+		for _, handler := range handlers {
+			if err := handler(&domain.EventEnvelope{Event: *event}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *syncBus) Subscribe(filter messaging.EventFilter, handler messaging.EventHandler, opts ...messaging.SubscribeOption) (messaging.Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.handlers == nil {
+		b.handlers = make(map[int]messaging.EventHandler)
+	}
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+	return syncSubscription{bus: b, id: id}, nil
+}
+
+func (b *syncBus) Close() error {
+	return nil
+}
+
+type syncSubscription struct {
+	bus *syncBus
+	id  int
+}
+
+func (s syncSubscription) Unsubscribe() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	delete(s.bus.handlers, s.id)
+	return nil
+}
+
+func TestSubscribeFromPosition(t *testing.T) {
+	ctx := context.Background()
+	bus := &syncBus{}
+	store, err := sqlite.NewEventStore(
+		sqlite.WithMemoryDatabase(),
+		sqlite.WithWALMode(false),
+		sqlite.WithEventBus(bus),
+	)
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer store.Close()
+
+	// appendNext stores the next event of agg-1 without publishing it
+	var version int64
+	appendNext := func() *domain.Event {
+		t.Helper()
+		version++
+		event := &domain.Event{
+			ID:            fmt.Sprintf("evt-%d", version),
+			AggregateID:   "agg-1",
+			AggregateType: "Test",
+			EventType:     "test.Happened",
+			Version:       version,
+			Timestamp:     time.Unix(1700000000+version, 0),
+			Data:          []byte("data"),
+		}
+		if err := store.AppendEvents(ctx, "agg-1", version-1, []*domain.Event{event}); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+		return event
+	}
+	publish := func(events ...*domain.Event) {
+		t.Helper()
+		if err := bus.Publish(events); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+	}
+
+	// History from before the subscription
+	for range 3 {
+		publish(appendNext())
+	}
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	handler := func(envelope *domain.EventEnvelope) error {
+		mu.Lock()
+		received = append(received, envelope.Event.ID)
+		mu.Unlock()
+
+		// An event appended and published during the catch-up reaches the
+		// handler from the database and from the bus
+		if envelope.Event.ID == "evt-2" {
+			publish(appendNext())
+		}
+		return nil
+	}
+	receivedIDs := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(received)
+	}
+
+	sub, err := store.SubscribeFromPosition(ctx, 1, handler)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	if got, want := receivedIDs(), []string{"evt-2", "evt-3", "evt-4"}; !slices.Equal(got, want) {
+		t.Fatalf("expected the catch-up to deliver %v once each, got %v", want, got)
+	}
+	if sub.Position() != 4 {
+		t.Errorf("expected position 4, got %d", sub.Position())
+	}
+
+	t.Run("LiveEventsAreDeduplicated", func(t *testing.T) {
+		event := appendNext()
+		publish(event)
+		publish(event)
+		if got := receivedIDs(); got[len(got)-1] != "evt-5" || slices.Index(got, "evt-5") != len(got)-1 {
+			t.Errorf("expected evt-5 once, got %v", got)
+		}
+	})
+
+	t.Run("SkippedEventsAreReadFromTheDatabase", func(t *testing.T) {
+		appendNext() // evt-6, never published
+		publish(appendNext())
+
+		got := receivedIDs()
+		if want := []string{"evt-6", "evt-7"}; !slices.Equal(got[len(got)-2:], want) {
+			t.Errorf("expected %v in order, got %v", want, got)
+		}
+		if sub.Position() != 7 {
+			t.Errorf("expected position 7, got %d", sub.Position())
+		}
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		if err := sub.Unsubscribe(); err != nil {
+			t.Fatalf("failed to unsubscribe: %v", err)
+		}
+		before := len(receivedIDs())
+		publish(appendNext())
+		if after := len(receivedIDs()); after != before {
+			t.Errorf("expected no events after unsubscribing, got %d more", after-before)
+		}
+	})
+}
+
+func TestSubscribeFromPositionHandlerError(t *testing.T) {
+	ctx := context.Background()
+	store, err := sqlite.NewEventStore(
+		sqlite.WithMemoryDatabase(),
+		sqlite.WithWALMode(false),
+		sqlite.WithEventBus(&syncBus{}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer store.Close()
+
+	event := &domain.Event{
+		ID: "evt-1", AggregateID: "agg-1", AggregateType: "Test", EventType: "test.Happened",
+		Version: 1, Timestamp: time.Now(), Data: []byte("data"),
+	}
+	if err := store.AppendEvents(ctx, "agg-1", 0, []*domain.Event{event}); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	_, err = store.SubscribeFromPosition(ctx, 0, func(*domain.EventEnvelope) error {
+		return fmt.Errorf("boom")
+	})
+	if err == nil {
+		t.Fatal("expected the handler error to fail the catch-up")
+	}
+}
+
+func TestSubscribeFromPositionWithoutEventBus(t *testing.T) {
+	store, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.SubscribeFromPosition(context.Background(), 0, func(*domain.EventEnvelope) error { return nil }); err == nil {
+		t.Error("expected an error without an event bus")
+	}
+}
@@ -12,12 +12,13 @@ import (
 var checkpointMigrationsFS embed.FS
 
 // runCheckpointMigrations runs all pending checkpoint migrations using our custom migrator.
-func runCheckpointMigrations(db *sql.DB) error {
-	m := migrate.New(db, "checkpoint_schema_migrations")
+func runCheckpointMigrations(db *sql.DB, prefix tablePrefix) error {
+	m := migrate.New(db, prefix.rewrite("checkpoint_schema_migrations"))
 
 	if err := m.LoadFromFS(checkpointMigrationsFS, "checkpoint_migrations"); err != nil {
 		return fmt.Errorf("failed to load checkpoint migrations: %w", err)
 	}
+	m.MapSQL(prefix.rewrite)
 
 	if err := m.Up(); err != nil {
 		return fmt.Errorf("failed to run checkpoint migrations: %w", err)
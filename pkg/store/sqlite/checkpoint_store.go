@@ -20,12 +20,16 @@ import (
 type CheckpointStore struct {
 	db      *sql.DB
 	queries *sqlcgen.Queries
+	tables  tablePrefix
 }
 
 // checkpointStoreConfig holds internal configuration for the checkpoint store.
 type checkpointStoreConfig struct {
 	// autoMigrate automatically runs pending migrations on startup
 	autoMigrate bool
+
+	// tablePrefix is prepended to all table and index names
+	tablePrefix string
 }
 
 // defaultCheckpointStoreConfig returns sensible defaults.
@@ -46,6 +50,15 @@ func WithCheckpointAutoMigrate(enabled bool) CheckpointStoreOption {
 	}
 }
 
+// WithCheckpointTablePrefix prepends prefix to the checkpoint table and its
+// migration table. Use the event store's prefix when sharing its database
+// (see WithTablePrefix).
+func WithCheckpointTablePrefix(prefix string) CheckpointStoreOption {
+	return func(c *checkpointStoreConfig) {
+		c.tablePrefix = prefix
+	}
+}
+
 // NewCheckpointStore creates a new SQLite checkpoint store with the given database and options.
 // By default, it will auto-migrate the database schema.
 //
@@ -71,14 +84,20 @@ func NewCheckpointStore(db *sql.DB, opts ...CheckpointStoreOption) (*CheckpointS
 		opt(&config)
 	}
 
+	tables, err := newTablePrefix(config.tablePrefix)
+	if err != nil {
+		return nil, err
+	}
+
 	store := &CheckpointStore{
 		db:      db,
-		queries: sqlcgen.New(db),
+		queries: tables.queries(db),
+		tables:  tables,
 	}
 
 	// Run migrations if auto-migrate is enabled
 	if config.autoMigrate {
-		if err := runCheckpointMigrations(db); err != nil {
+		if err := runCheckpointMigrations(db, tables); err != nil {
 			return nil, fmt.Errorf("failed to run checkpoint migrations: %w", err)
 		}
 	}
@@ -91,6 +110,11 @@ func (s *CheckpointStore) DB() *sql.DB {
 	return s.db
 }
 
+// TablePrefix returns the prefix of the store's table names (see WithCheckpointTablePrefix).
+func (s *CheckpointStore) TablePrefix() string {
+	return string(s.tables)
+}
+
 // Save saves a checkpoint in its own transaction.
 // WARNING: For atomic projection updates, use SaveInTx instead to avoid dual-write issues.
 func (s *CheckpointStore) Save(checkpoint *store.ProjectionCheckpoint) error {
@@ -137,7 +161,7 @@ func (s *CheckpointStore) Save(checkpoint *store.ProjectionCheckpoint) error {
 //	return tx.Commit()
 func (s *CheckpointStore) SaveInTx(tx *sql.Tx, checkpoint *store.ProjectionCheckpoint) error {
 	ctx := context.Background()
-	queries := sqlcgen.New(tx)
+	queries := s.tables.queries(tx)
 
 	err := queries.SaveCheckpoint(ctx, sqlcgen.SaveCheckpointParams{
 		ProjectionName: checkpoint.ProjectionName,
@@ -153,13 +177,31 @@ func (s *CheckpointStore) SaveInTx(tx *sql.Tx, checkpoint *store.ProjectionCheck
 	return nil
 }
 
+// Advance saves checkpoint unless the saved one is at a higher position. It
+// implements store.CheckpointAdvancer.
+func (s *CheckpointStore) Advance(checkpoint *store.ProjectionCheckpoint) error {
+	_, err := s.db.ExecContext(context.Background(), s.tables.rewrite(`
+		INSERT INTO projection_checkpoints (projection_name, position, last_event_id, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (projection_name) DO UPDATE SET
+			position = excluded.position,
+			last_event_id = excluded.last_event_id,
+			updated_at = excluded.updated_at
+		WHERE excluded.position > projection_checkpoints.position
+	`), checkpoint.ProjectionName, checkpoint.Position, checkpoint.LastEventID, checkpoint.UpdatedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to advance checkpoint: %w", err)
+	}
+	return nil
+}
+
 // Load loads a checkpoint for a projection.
 func (s *CheckpointStore) Load(projectionName string) (*store.ProjectionCheckpoint, error) {
 	ctx := context.Background()
 	row, err := s.queries.LoadCheckpoint(ctx, projectionName)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("checkpoint not found for projection %s", projectionName)
+		return nil, fmt.Errorf("%w for projection %s", store.ErrCheckpointNotFound, projectionName)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
@@ -175,6 +217,59 @@ func (s *CheckpointStore) Load(projectionName string) (*store.ProjectionCheckpoi
 	return &checkpoint, nil
 }
 
+// LoadAll returns the checkpoints of all projections, ordered by projection
+// name, e.g. for an admin view of every projection's position and when it last
+// moved.
+func (s *CheckpointStore) LoadAll() ([]*store.ProjectionCheckpoint, error) {
+	rows, err := s.db.QueryContext(context.Background(), s.tables.rewrite(`
+		SELECT projection_name, position, last_event_id, updated_at
+		FROM projection_checkpoints
+		ORDER BY projection_name
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []*store.ProjectionCheckpoint
+	for rows.Next() {
+		var (
+			checkpoint store.ProjectionCheckpoint
+			updatedAt  int64
+		)
+		if err := rows.Scan(&checkpoint.ProjectionName, &checkpoint.Position, &checkpoint.LastEventID, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint: %w", err)
+		}
+		checkpoint.UpdatedAt = time.Unix(updatedAt, 0)
+		checkpoints = append(checkpoints, &checkpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+
+	return checkpoints, nil
+}
+
+// List returns the checkpoints of all projections, ordered by projection name.
+//
+// Deprecated: Use LoadAll.
+func (s *CheckpointStore) List() ([]*store.ProjectionCheckpoint, error) {
+	return s.LoadAll()
+}
+
+// MinPosition returns the lowest position across all checkpoints, or 0 when
+// there are none.
+func (s *CheckpointStore) MinPosition(ctx context.Context) (int64, error) {
+	var position sql.NullInt64
+	err := s.db.QueryRowContext(ctx, s.tables.rewrite(`
+		SELECT MIN(position) FROM projection_checkpoints
+	`)).Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get minimum checkpoint position: %w", err)
+	}
+	return position.Int64, nil
+}
+
 // Delete deletes a checkpoint (for rebuilding).
 func (s *CheckpointStore) Delete(projectionName string) error {
 	ctx := context.Background()
@@ -187,11 +282,22 @@ func (s *CheckpointStore) Delete(projectionName string) error {
 	return nil
 }
 
+// Reset deletes the checkpoint of projectionName so the projection replays
+// the event store from the start the next time it runs, without touching its
+// data: handlers must tolerate seeing events again. To start from an empty
+// read model instead, rebuild the projection.
+func (s *CheckpointStore) Reset(projectionName string) error {
+	if err := s.Delete(projectionName); err != nil {
+		return fmt.Errorf("failed to reset checkpoint of %s: %w", projectionName, err)
+	}
+	return nil
+}
+
 // DeleteInTx deletes a checkpoint within the provided transaction.
 // This should be used when resetting projections atomically.
 func (s *CheckpointStore) DeleteInTx(tx *sql.Tx, projectionName string) error {
 	ctx := context.Background()
-	queries := sqlcgen.New(tx)
+	queries := s.tables.queries(tx)
 
 	err := queries.DeleteCheckpoint(ctx, projectionName)
 	if err != nil {
@@ -1,6 +1,7 @@
 package sqlite_test
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -112,7 +113,7 @@ func Example_projectionLoop() {
 	}
 
 	// Load events since last checkpoint
-	events, _ := eventStore.LoadAllEvents(checkpoint.Position, 100)
+	events, _ := eventStore.LoadAllEvents(context.Background(), checkpoint.Position, 100)
 
 	// Process events in a transaction
 	for _, event := range events {
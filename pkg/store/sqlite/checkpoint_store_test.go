@@ -1,10 +1,16 @@
 package sqlite_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/eventsourcing"
+	"github.com/plaenen/eventstore/pkg/store"
 	"github.com/plaenen/eventstore/pkg/store/sqlite"
 )
 
@@ -188,4 +194,155 @@ func TestCheckpointStore(t *testing.T) {
 			t.Error("checkpoint should not exist after deletion")
 		}
 	})
+
+	t.Run("LoadAllAndReset", func(t *testing.T) {
+		for i, name := range []string{"list-b", "list-a"} {
+			err := checkpointStore.Save(&eventsourcing.ProjectionCheckpoint{
+				ProjectionName: name,
+				Position:       int64(10 + i),
+				LastEventID:    fmt.Sprintf("event-%d", 10+i),
+				UpdatedAt:      time.Unix(1700000000, 0),
+			})
+			if err != nil {
+				t.Fatalf("failed to save checkpoint: %v", err)
+			}
+		}
+
+		all, err := checkpointStore.LoadAll()
+		if err != nil {
+			t.Fatalf("failed to load checkpoints: %v", err)
+		}
+		positions := make(map[string]int64)
+		var names []string
+		for _, checkpoint := range all {
+			positions[checkpoint.ProjectionName] = checkpoint.Position
+			names = append(names, checkpoint.ProjectionName)
+		}
+		if positions["list-a"] != 11 || positions["list-b"] != 10 {
+			t.Errorf("expected both checkpoints, got %v", positions)
+		}
+		if !slices.IsSorted(names) {
+			t.Errorf("expected checkpoints ordered by name, got %v", names)
+		}
+		if !all[0].UpdatedAt.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("expected the update time to be kept, got %v", all[0].UpdatedAt)
+		}
+
+		if err := checkpointStore.Reset("list-a"); err != nil {
+			t.Fatalf("failed to reset checkpoint: %v", err)
+		}
+		if _, err := checkpointStore.Load("list-a"); err == nil {
+			t.Error("expected the reset checkpoint to be gone")
+		}
+		if _, err := checkpointStore.Load("list-b"); err != nil {
+			t.Errorf("expected other checkpoints to be kept: %v", err)
+		}
+	})
+
+	t.Run("Advance", func(t *testing.T) {
+		for _, position := range []int64{5, 8, 3} {
+			err := checkpointStore.Advance(&eventsourcing.ProjectionCheckpoint{
+				ProjectionName: "advance",
+				Position:       position,
+				LastEventID:    fmt.Sprintf("event-%d", position),
+				UpdatedAt:      time.Now(),
+			})
+			if err != nil {
+				t.Fatalf("failed to advance checkpoint: %v", err)
+			}
+		}
+		checkpoint, err := checkpointStore.Load("advance")
+		if err != nil {
+			t.Fatalf("failed to load checkpoint: %v", err)
+		}
+		if checkpoint.Position != 8 || checkpoint.LastEventID != "event-8" {
+			t.Errorf("expected the checkpoint to stay at 8, got %+v", checkpoint)
+		}
+	})
+}
+
+type noopProjection struct{ name string }
+
+func (p noopProjection) Name() string                                        { return p.name }
+func (p noopProjection) Handle(context.Context, *domain.EventEnvelope) error { return nil }
+func (p noopProjection) Reset(context.Context) error                         { return nil }
+
+func TestCheckpointStore_SafePruneHorizon(t *testing.T) {
+	ctx := context.Background()
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	minPosition, err := checkpointStore.MinPosition(ctx)
+	if err != nil {
+		t.Fatalf("MinPosition failed: %v", err)
+	}
+	if minPosition != 0 {
+		t.Errorf("expected 0 without checkpoints, got %d", minPosition)
+	}
+
+	for name, position := range map[string]int64{"balances": 120, "statements": 80, "external-sync": 95} {
+		if err := checkpointStore.Save(&eventsourcing.ProjectionCheckpoint{
+			ProjectionName: name,
+			Position:       position,
+			UpdatedAt:      time.Now(),
+		}); err != nil {
+			t.Fatalf("failed to save checkpoint: %v", err)
+		}
+	}
+
+	minPosition, err = checkpointStore.MinPosition(ctx)
+	if err != nil {
+		t.Fatalf("MinPosition failed: %v", err)
+	}
+	if minPosition != 80 {
+		t.Errorf("expected minimum position 80, got %d", minPosition)
+	}
+
+	manager := eventsourcing.NewProjectionManager(checkpointStore, eventStore, nil)
+	manager.Register(noopProjection{name: "balances"})
+	manager.Register(noopProjection{name: "statements"})
+
+	horizon, err := manager.SafePruneHorizon(ctx)
+	if err != nil {
+		t.Fatalf("SafePruneHorizon failed: %v", err)
+	}
+	if horizon != 80 {
+		t.Errorf("expected horizon 80, got %d", horizon)
+	}
+
+	// A projection that has not processed anything holds the horizon at 0
+	manager.Register(noopProjection{name: "new-projection"})
+
+	horizon, err = manager.SafePruneHorizon(ctx)
+	if err != nil {
+		t.Fatalf("SafePruneHorizon failed: %v", err)
+	}
+	if horizon != 0 {
+		t.Errorf("expected horizon 0 with an unstarted projection, got %d", horizon)
+	}
+
+	// A checkpoint that cannot be loaded is not mistaken for a missing one
+	failing := eventsourcing.NewProjectionManager(failingLoadCheckpoints{checkpointStore}, eventStore, nil)
+	failing.Register(noopProjection{name: "balances"})
+	if _, err := failing.SafePruneHorizon(ctx); err == nil {
+		t.Error("expected SafePruneHorizon to fail when a checkpoint cannot be loaded")
+	}
+}
+
+// failingLoadCheckpoints is a checkpoint store whose Load always fails.
+type failingLoadCheckpoints struct {
+	*sqlite.CheckpointStore
+}
+
+func (failingLoadCheckpoints) Load(string) (*store.ProjectionCheckpoint, error) {
+	return nil, errors.New("database unavailable")
 }
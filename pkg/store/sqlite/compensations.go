@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// LoadCompensations returns the compensating events recorded for commandID,
+// across all aggregates, in global position order. See domain.Compensator.
+func (s *EventStore) LoadCompensations(commandID string) ([]*domain.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.QueryContext(context.Background(), s.tables.rewrite(`
+		SELECT event_id, aggregate_id, aggregate_type, event_type,
+		       version, timestamp, data, metadata, constraints, position
+		FROM events
+		WHERE causation_id = ?
+		  AND json_extract(metadata, '$.Custom.`+domain.CompensationKey+`') = 'true'
+		ORDER BY position ASC`), commandID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query compensations: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
@@ -0,0 +1,19 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	storelib "github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"github.com/plaenen/eventstore/pkg/store/storetest"
+)
+
+func TestEventStoreConformance(t *testing.T) {
+	storetest.RunEventStoreTests(t, func(t *testing.T) storelib.EventStore {
+		es, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		return es
+	})
+}
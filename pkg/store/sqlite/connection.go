@@ -0,0 +1,192 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	_ "modernc.org/sqlite" // Registers the "sqlite" driver
+)
+
+// WithDB makes the event store use db instead of opening its own pool. WAL
+// setup, the integrity check and migrations still run as configured, but the
+// pool is left as the caller set it up: WithDSN, WithMaxOpenConns and
+// WithMaxIdleConns are ignored, and Close does not close db.
+//
+// Per-connection settings (WithConnectHook, WithPragma, WithBusyTimeout,
+// WithSynchronous, WithWALAutoCheckpoint) cannot be applied to a pool the store
+// did not open; configure them on db instead.
+//
+// This lets the event store share a pool with other components, or use one
+// wrapped for instrumentation:
+//
+//	db, err := sql.Open("sqlite", "app.db")
+//	store, err := sqlite.NewEventStore(sqlite.WithDB(db))
+func WithDB(db *sql.DB) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.db = db
+	}
+}
+
+// WithConnectHook runs hook on every new connection of the pool, before the
+// connection is used. Use it for settings SQLite keeps per connection, such
+// as pragmas, which a single Exec on the pool would apply to one connection
+// only:
+//
+//	sqlite.WithConnectHook(func(conn *sql.Conn) error {
+//	    _, err := conn.ExecContext(context.Background(), "PRAGMA cache_size = -20000")
+//	    return err
+//	})
+//
+// A failing hook fails the connection attempt. Hooks run in the order they
+// were added.
+func WithConnectHook(hook func(conn *sql.Conn) error) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.connectHooks = append(c.connectHooks, hook)
+	}
+}
+
+// openDB opens the store's pool for dsn, running hooks on each new connection.
+func openDB(dsn string, hooks []func(conn *sql.Conn) error) (*sql.DB, error) {
+	if len(hooks) == 0 {
+		return sql.Open("sqlite", dsn)
+	}
+	drv, err := registeredDriver()
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&hookConnector{
+		driver: drv,
+		dsn:    dsn,
+		hooks:  hooks,
+	}), nil
+}
+
+// registeredDriver returns the driver registered as "sqlite". Unlike a new
+// driver value it carries the functions added with
+// sqlitedriver.RegisterScalarFunction and friends.
+func registeredDriver() (driver.Driver, error) {
+	db, err := sql.Open("sqlite", "")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return db.Driver(), nil
+}
+
+// checkInjectedDB rejects options that need to own the pool. The error names
+// every such option that was passed, along with the pool options WithDB
+// ignores.
+func checkInjectedDB(config eventStoreConfig) error {
+	var rejected []string
+	if len(config.connectHooks) > 0 {
+		rejected = append(rejected, "WithConnectHook")
+	}
+	if config.walAutoCheckpoint > 0 {
+		rejected = append(rejected, "WithWALAutoCheckpoint")
+	}
+	for _, p := range config.pragmas {
+		option := "WithPragma"
+		switch p.name {
+		case "busy_timeout":
+			option = "WithBusyTimeout"
+		case "synchronous":
+			option = "WithSynchronous"
+		}
+		if !slices.Contains(rejected, option) {
+			rejected = append(rejected, option)
+		}
+	}
+	if len(rejected) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s cannot be combined with WithDB: per-connection settings "+
+		"(WithConnectHook, WithPragma, WithBusyTimeout, WithSynchronous, WithWALAutoCheckpoint) "+
+		"must be configured on the injected db, and WithDSN, WithMaxOpenConns and WithMaxIdleConns are ignored",
+		strings.Join(rejected, ", "))
+}
+
+// mainDatabaseFile returns the file backing db's main schema, or "" for an
+// in-memory database.
+func mainDatabaseFile(db *sql.DB) (string, error) {
+	var file string
+	err := db.QueryRowContext(context.Background(),
+		"SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file)
+	return file, err
+}
+
+// hookConnector opens SQLite connections and runs the connect hooks on them.
+type hookConnector struct {
+	driver driver.Driver
+	dsn    string
+	hooks  []func(conn *sql.Conn) error
+}
+
+func (c *hookConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.runHooks(ctx, conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("connect hook: %w", err)
+	}
+	return conn, nil
+}
+
+func (c *hookConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// runHooks hands conn to the hooks as a *sql.Conn. database/sql cannot wrap a
+// driver connection directly, so conn is lent to a single-connection pool
+// that never closes it.
+func (c *hookConnector) runHooks(ctx context.Context, conn driver.Conn) error {
+	pool := sql.OpenDB(&lentConnector{conn: conn, driver: c.driver})
+	defer pool.Close()
+
+	sqlConn, err := pool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer sqlConn.Close()
+
+	for _, hook := range c.hooks {
+		if err := hook(sqlConn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lentConnector serves a single borrowed connection.
+type lentConnector struct {
+	conn   driver.Conn
+	driver driver.Driver
+}
+
+func (c *lentConnector) Connect(context.Context) (driver.Conn, error) {
+	if c.conn == nil {
+		return nil, errors.New("connection already lent")
+	}
+	conn := lentConn{c.conn}
+	c.conn = nil
+	return conn, nil
+}
+
+func (c *lentConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// lentConn leaves closing the connection to its owner.
+type lentConn struct {
+	driver.Conn
+}
+
+func (lentConn) Close() error {
+	return nil
+}
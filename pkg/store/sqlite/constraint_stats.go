@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ConstraintIndexStats summarizes the unique constraint values claimed in one
+// index (e.g., "email").
+type ConstraintIndexStats struct {
+	// IndexName is the constraint index
+	IndexName string
+
+	// Claims is the number of values currently claimed
+	Claims int64
+
+	// OldestClaim is when the longest-held value was claimed
+	OldestClaim time.Time
+}
+
+// OrphanedConstraint is a claimed unique value whose owning aggregate has no
+// events, so no aggregate will ever release it.
+type OrphanedConstraint struct {
+	IndexName   string
+	Value       string
+	AggregateID string
+	ClaimedAt   time.Time
+}
+
+// ConstraintStats returns the claimed values per unique constraint index,
+// ordered by index name.
+func (s *EventStore) ConstraintStats() ([]ConstraintIndexStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.QueryContext(context.Background(), s.tables.rewrite(`
+		SELECT index_name, COUNT(*), MIN(created_at)
+		FROM unique_constraints
+		GROUP BY index_name
+		ORDER BY index_name`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query constraint stats: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var stats []ConstraintIndexStats
+	for rows.Next() {
+		var (
+			index  ConstraintIndexStats
+			oldest int64
+		)
+		if err := rows.Scan(&index.IndexName, &index.Claims, &oldest); err != nil {
+			return nil, fmt.Errorf("failed to scan constraint stats: %w", classifyError(err))
+		}
+		index.OldestClaim = time.Unix(oldest, 0)
+		stats = append(stats, index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query constraint stats: %w", classifyError(err))
+	}
+	return stats, nil
+}
+
+// FindOrphanedConstraints returns claimed values whose owning aggregate has no
+// events, ordered by index and value. Appends can't produce them, since a claim
+// commits with its event; they are left behind when events are deleted outside
+// the store (e.g., a raw DELETE to remove an account), and keep the value
+// "already taken" until released by hand.
+func (s *EventStore) FindOrphanedConstraints() ([]OrphanedConstraint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.QueryContext(context.Background(), s.tables.rewrite(`
+		SELECT c.index_name, c.value, c.aggregate_id, c.created_at
+		FROM unique_constraints c
+		WHERE NOT EXISTS (SELECT 1 FROM events e WHERE e.aggregate_id = c.aggregate_id)
+		ORDER BY c.index_name, c.value`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orphaned constraints: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var orphans []OrphanedConstraint
+	for rows.Next() {
+		var (
+			orphan    OrphanedConstraint
+			claimedAt int64
+		)
+		if err := rows.Scan(&orphan.IndexName, &orphan.Value, &orphan.AggregateID, &claimedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan orphaned constraint: %w", classifyError(err))
+		}
+		orphan.ClaimedAt = time.Unix(claimedAt, 0)
+		orphans = append(orphans, orphan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query orphaned constraints: %w", classifyError(err))
+	}
+	return orphans, nil
+}
@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// WithContentDedup drops appended events whose type and payload are identical
+// to one of the aggregate's last window events (stored or earlier in the same
+// append), e.g. to absorb replays of an at-least-once ingestion source. A
+// window below 1 is treated as 1: only the immediately preceding event counts.
+//
+// Deduplication is lossy: two legitimate consecutive events with the same
+// content (say, two identical deposits) collapse into one, so enable it only
+// for stores fed by such sources. It is distinct from command idempotency,
+// which deduplicates by command ID rather than by content.
+//
+// Applies to AppendEvents and AppendEventsIdempotent, not to ImportEvents.
+// Kept events are renumbered to stay consecutive, and dropped events are not
+// stored (their Position stays 0). AppendEventsIdempotent lists them in
+// CommandResult.Deduplicated. An aggregate whose events were dropped has a
+// higher in-memory version than the store; reload it before saving again.
+func WithContentDedup(window int) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.contentDedupWindow = max(window, 1)
+	}
+}
+
+// dedupEvents splits events, about to be appended to aggregateID after
+// expectedVersion, into the events to store and the duplicates to drop, and
+// renumbers the kept events. It keeps all events unless the store uses
+// WithContentDedup.
+func (s *EventStore) dedupEvents(ctx context.Context, tx *sql.Tx, aggregateID string, expectedVersion int64, events []*domain.Event) (kept, deduped []*domain.Event, err error) {
+	if s.contentDedupWindow == 0 {
+		return events, nil, nil
+	}
+
+	// The window's hashes, oldest first
+	recent, err := s.recentContentHashes(ctx, tx, aggregateID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, event := range events {
+		hash := contentHash(event.EventType, event.Data)
+		if containsHash(recent, hash) {
+			deduped = append(deduped, event)
+			continue
+		}
+		kept = append(kept, event)
+		recent = append(recent, hash)
+		if len(recent) > s.contentDedupWindow {
+			recent = recent[1:]
+		}
+	}
+
+	for i, event := range kept {
+		event.Version = expectedVersion + int64(i) + 1
+	}
+	return kept, deduped, nil
+}
+
+// recentContentHashes returns the content hashes of the aggregate's last
+// events within the dedup window, oldest first.
+func (s *EventStore) recentContentHashes(ctx context.Context, tx *sql.Tx, aggregateID string) ([][sha256.Size]byte, error) {
+	rows, err := tx.QueryContext(ctx, s.tables.rewrite(`
+		SELECT event_type, data FROM events
+		WHERE aggregate_id = ?
+		ORDER BY version DESC
+		LIMIT ?`), aggregateID, s.contentDedupWindow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load recent events: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var hashes [][sha256.Size]byte
+	for rows.Next() {
+		var (
+			eventType string
+			data      []byte
+		)
+		if err := rows.Scan(&eventType, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan recent event: %w", classifyError(err))
+		}
+		hashes = append(hashes, contentHash(eventType, data))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load recent events: %w", classifyError(err))
+	}
+
+	for i, j := 0, len(hashes)-1; i < j; i, j = i+1, j-1 {
+		hashes[i], hashes[j] = hashes[j], hashes[i]
+	}
+	return hashes, nil
+}
+
+// contentHash hashes an event's type and payload, length-prefixing the type
+// so that no two different pairs hash the same input.
+func contentHash(eventType string, data []byte) [sha256.Size]byte {
+	h := sha256.New()
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(len(eventType)))
+	h.Write(buf[:])
+	h.Write([]byte(eventType))
+	h.Write(data)
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+func containsHash(hashes [][sha256.Size]byte, hash [sha256.Size]byte) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestContentDedup(t *testing.T) {
+	// events builds events for aggregateID from payloads, numbered after version
+	events := func(aggregateID string, version int64, payloads ...string) []*domain.Event {
+		var result []*domain.Event
+		for i, payload := range payloads {
+			v := version + int64(i) + 1
+			result = append(result, &domain.Event{
+				ID:            fmt.Sprintf("%s-%d-%d", aggregateID, v, time.Now().UnixNano()),
+				AggregateID:   aggregateID,
+				AggregateType: "Sensor",
+				EventType:     "sensor.v1.Reading",
+				Version:       v,
+				Timestamp:     time.Now(),
+				Data:          []byte(payload),
+			})
+		}
+		return result
+	}
+	stored := func(t *testing.T, eventStore *sqlite.EventStore, aggregateID string) []string {
+		t.Helper()
+		loaded, err := eventStore.LoadEvents(context.Background(), aggregateID, 0)
+		if err != nil {
+			t.Fatalf("failed to load events: %v", err)
+		}
+		var payloads []string
+		for i, event := range loaded {
+			if event.Version != int64(i+1) {
+				t.Errorf("event %d has version %d", i, event.Version)
+			}
+			payloads = append(payloads, string(event.Data))
+		}
+		return payloads
+	}
+	expectPayloads := func(t *testing.T, got []string, want ...string) {
+		t.Helper()
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+
+	t.Run("PrecedingEvent", func(t *testing.T) {
+		eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithContentDedup(1))
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer eventStore.Close()
+
+		if err := eventStore.AppendEvents(context.Background(), "s-1", 0, events("s-1", 0, "a")); err != nil {
+			t.Fatalf("failed to append events: %v", err)
+		}
+		// The replayed "a" and the second "b" are dropped; the final "a" is
+		// not identical to its predecessor and is kept
+		batch := events("s-1", 1, "a", "b", "b", "a")
+		if err := eventStore.AppendEvents(context.Background(), "s-1", 1, batch); err != nil {
+			t.Fatalf("failed to append events: %v", err)
+		}
+		expectPayloads(t, stored(t, eventStore, "s-1"), "a", "b", "a")
+
+		if batch[1].Version != 2 || batch[3].Version != 3 {
+			t.Errorf("expected kept events renumbered to 2 and 3, got %d and %d", batch[1].Version, batch[3].Version)
+		}
+		if batch[0].Position != 0 || batch[3].Position == 0 {
+			t.Errorf("expected positions only on kept events, got %d and %d", batch[0].Position, batch[3].Position)
+		}
+
+		// Other aggregates are not affected
+		if err := eventStore.AppendEvents(context.Background(), "s-2", 0, events("s-2", 0, "a")); err != nil {
+			t.Fatalf("failed to append events: %v", err)
+		}
+		expectPayloads(t, stored(t, eventStore, "s-2"), "a")
+	})
+
+	t.Run("WindowAndCommandResult", func(t *testing.T) {
+		eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithContentDedup(2))
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer eventStore.Close()
+
+		if err := eventStore.AppendEvents(context.Background(), "s-1", 0, events("s-1", 0, "a", "b")); err != nil {
+			t.Fatalf("failed to append events: %v", err)
+		}
+		result, err := eventStore.AppendEventsIdempotent(context.Background(), "s-1", 2, events("s-1", 2, "a", "c", "d", "b"), "cmd-1", time.Hour)
+		if err != nil {
+			t.Fatalf("failed to append events: %v", err)
+		}
+		// "a" is within the last two events; "b" no longer is once "c" and
+		// "d" are kept
+		expectPayloads(t, stored(t, eventStore, "s-1"), "a", "b", "c", "d", "b")
+		if len(result.Events) != 3 || len(result.Deduplicated) != 1 || string(result.Deduplicated[0].Data) != "a" {
+			t.Errorf("expected 3 kept and the replayed a deduplicated, got %d and %v", len(result.Events), result.Deduplicated)
+		}
+		if result.Position != result.Events[2].Position {
+			t.Errorf("expected position of the last kept event, got %d", result.Position)
+		}
+
+		replayed, err := eventStore.GetCommandResult("cmd-1")
+		if err != nil {
+			t.Fatalf("failed to get command result: %v", err)
+		}
+		if len(replayed.Events) != 3 {
+			t.Errorf("expected the command to record 3 events, got %d", len(replayed.Events))
+		}
+	})
+
+	t.Run("ImportsAndDefault", func(t *testing.T) {
+		deduping, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithContentDedup(1))
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer deduping.Close()
+		if err := deduping.ImportEvents("s-1", 0, events("s-1", 0, "a", "a")); err != nil {
+			t.Fatalf("failed to import events: %v", err)
+		}
+		expectPayloads(t, stored(t, deduping, "s-1"), "a", "a")
+
+		plain, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer plain.Close()
+		if err := plain.AppendEvents(context.Background(), "s-1", 0, events("s-1", 0, "a", "a")); err != nil {
+			t.Fatalf("failed to append events: %v", err)
+		}
+		expectPayloads(t, stored(t, plain, "s-1"), "a", "a")
+	})
+}
@@ -0,0 +1,236 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// ErrDeadLetterNotFound is returned for a dead letter ID that does not exist.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is an event a projection gave up on (see
+// SQLiteProjectionBuilder.WithDeadLetter).
+type DeadLetter struct {
+	ID             int64
+	ProjectionName string
+	EventID        string
+	EventType      string
+	AggregateID    string
+	Position       int64
+	Error          string // Error of the last attempt
+	Attempts       int
+	FailedAt       time.Time // Time of the last attempt
+
+	// Event is the event as it was handed to the projection, so it can be
+	// replayed without the event store
+	Event *domain.Event
+}
+
+// DeadLetterStore keeps the events projections failed to handle in the
+// projection_dead_letters table. It must use the projection's database, as
+// dead letters are recorded in the transaction that moves the checkpoint past
+// them.
+type DeadLetterStore struct {
+	db     *sql.DB
+	tables tablePrefix
+}
+
+// DeadLetterStoreOption configures a DeadLetterStore.
+type DeadLetterStoreOption func(*DeadLetterStore)
+
+// WithDeadLetterTablePrefix prepends prefix to the projection_dead_letters
+// table (see WithTablePrefix).
+func WithDeadLetterTablePrefix(prefix string) DeadLetterStoreOption {
+	return func(s *DeadLetterStore) {
+		s.tables = tablePrefix(prefix)
+	}
+}
+
+// NewDeadLetterStore creates a SQLite dead letter store, creating its table if
+// needed.
+//
+// Example:
+//
+//	deadLetters, _ := sqlite.NewDeadLetterStore(db)
+//	projection, err := sqlite.NewSQLiteProjectionBuilder("account-balance", db, checkpointStore, eventStore).
+//	    WithDeadLetter(deadLetters, 3).
+//	    On(accountv1.OnMoneyDeposited(...)).
+//	    Build()
+func NewDeadLetterStore(db *sql.DB, opts ...DeadLetterStoreOption) (*DeadLetterStore, error) {
+	s := &DeadLetterStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if _, err := newTablePrefix(string(s.tables)); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.Exec(s.tables.rewrite(`
+		CREATE TABLE IF NOT EXISTS projection_dead_letters (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			projection_name TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			aggregate_id TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			error TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			failed_at INTEGER NOT NULL,
+			event BLOB NOT NULL,
+			UNIQUE (projection_name, event_id)
+		)
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create projection_dead_letters table: %w", err)
+	}
+
+	return s, nil
+}
+
+// ListDeadLetters returns the dead letters of projectionName in event store
+// order.
+func (s *DeadLetterStore) ListDeadLetters(ctx context.Context, projectionName string) ([]*DeadLetter, error) {
+	rows, err := s.db.QueryContext(ctx, s.tables.rewrite(`
+		SELECT id, projection_name, event_id, event_type, aggregate_id, position, error, attempts, failed_at, event
+		FROM projection_dead_letters
+		WHERE projection_name = ?
+		ORDER BY position, id
+	`), projectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var deadLetters []*DeadLetter
+	for rows.Next() {
+		deadLetter, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	return deadLetters, nil
+}
+
+// GetDeadLetter returns the dead letter with the given ID, or
+// ErrDeadLetterNotFound.
+func (s *DeadLetterStore) GetDeadLetter(ctx context.Context, id int64) (*DeadLetter, error) {
+	row := s.db.QueryRowContext(ctx, s.tables.rewrite(`
+		SELECT id, projection_name, event_id, event_type, aggregate_id, position, error, attempts, failed_at, event
+		FROM projection_dead_letters
+		WHERE id = ?
+	`), id)
+	deadLetter, err := scanDeadLetter(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %d", ErrDeadLetterNotFound, id)
+	}
+	return deadLetter, err
+}
+
+// DeleteDeadLetter discards a dead letter without replaying it.
+func (s *DeadLetterStore) DeleteDeadLetter(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, s.tables.rewrite(`
+		DELETE FROM projection_dead_letters WHERE id = ?
+	`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", classifyError(err))
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %d", ErrDeadLetterNotFound, id)
+	}
+	return nil
+}
+
+// addInTx records an event a projection gave up on. An event dead-lettered
+// again, e.g. after a replay of the projection, replaces its earlier record.
+func (s *DeadLetterStore) addInTx(ctx context.Context, tx *sql.Tx, deadLetter *DeadLetter) error {
+	event, err := json.Marshal(deadLetter.Event)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead-lettered event: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, s.tables.rewrite(`
+		INSERT INTO projection_dead_letters (projection_name, event_id, event_type, aggregate_id, position, error, attempts, failed_at, event)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(projection_name, event_id) DO UPDATE SET
+			position = excluded.position,
+			error = excluded.error,
+			attempts = excluded.attempts,
+			failed_at = excluded.failed_at,
+			event = excluded.event
+	`), deadLetter.ProjectionName, deadLetter.EventID, deadLetter.EventType, deadLetter.AggregateID, deadLetter.Position,
+		deadLetter.Error, deadLetter.Attempts, deadLetter.FailedAt.Unix(), event)
+	if err != nil {
+		return fmt.Errorf("failed to record dead letter: %w", classifyError(err))
+	}
+	return nil
+}
+
+// recordAttempt counts a failed replay of a dead letter.
+func (s *DeadLetterStore) recordAttempt(ctx context.Context, id int64, cause error) error {
+	_, err := s.db.ExecContext(ctx, s.tables.rewrite(`
+		UPDATE projection_dead_letters
+		SET attempts = attempts + 1, error = ?, failed_at = ?
+		WHERE id = ?
+	`), cause.Error(), domain.Now().Unix(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update dead letter: %w", classifyError(err))
+	}
+	return nil
+}
+
+// deleteInTx removes a replayed dead letter.
+func (s *DeadLetterStore) deleteInTx(ctx context.Context, tx *sql.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, s.tables.rewrite(`
+		DELETE FROM projection_dead_letters WHERE id = ?
+	`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete dead letter: %w", classifyError(err))
+	}
+	return nil
+}
+
+// clearInTx removes the dead letters of projectionName.
+func (s *DeadLetterStore) clearInTx(ctx context.Context, tx *sql.Tx, projectionName string) error {
+	_, err := tx.ExecContext(ctx, s.tables.rewrite(`
+		DELETE FROM projection_dead_letters WHERE projection_name = ?
+	`), projectionName)
+	if err != nil {
+		return fmt.Errorf("failed to clear dead letters: %w", classifyError(err))
+	}
+	return nil
+}
+
+// scanDeadLetter reads a row selected by ListDeadLetters or GetDeadLetter.
+func scanDeadLetter(row interface{ Scan(...any) error }) (*DeadLetter, error) {
+	var (
+		deadLetter DeadLetter
+		failedAt   int64
+		event      []byte
+	)
+	err := row.Scan(&deadLetter.ID, &deadLetter.ProjectionName, &deadLetter.EventID, &deadLetter.EventType,
+		&deadLetter.AggregateID, &deadLetter.Position, &deadLetter.Error, &deadLetter.Attempts, &failedAt, &event)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+	}
+	deadLetter.FailedAt = domain.TimeFromUnix(failedAt)
+
+	deadLetter.Event = &domain.Event{}
+	if err := json.Unmarshal(event, deadLetter.Event); err != nil {
+		return nil, fmt.Errorf("failed to decode dead-lettered event %s: %w", deadLetter.EventID, err)
+	}
+	return &deadLetter, nil
+}
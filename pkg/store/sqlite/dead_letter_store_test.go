@@ -0,0 +1,200 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+// newDeadLetterProjection creates a counting projection that dead-letters the
+// test.Poisoned events poisoned fails on after retries, logging to logs.
+func newDeadLetterProjection(t *testing.T, retries int, logs *bytes.Buffer, poisoned sqlite.TransactionalEventHandler) (*sqlite.EventStore, *sqlite.CheckpointStore, *sqlite.DeadLetterStore, *sqlite.SQLiteProjection) {
+	t.Helper()
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	t.Cleanup(func() { eventStore.Close() })
+
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+	letters, err := sqlite.NewDeadLetterStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create dead letter store: %v", err)
+	}
+
+	built, err := sqlite.NewSQLiteProjectionBuilder("counting", eventStore.DB(), checkpointStore, eventStore).
+		WithSchema(func(ctx context.Context, db *sql.DB) error {
+			_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS counts (aggregate_id TEXT PRIMARY KEY, n INTEGER NOT NULL)`)
+			return err
+		}).
+		WithDeadLetter(letters, retries).
+		WithLogger(slog.New(slog.NewTextHandler(logs, nil))).
+		OnWithTx("test.Happened", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+			_, err := tx.ExecContext(ctx, `INSERT INTO counts (aggregate_id, n) VALUES (?, 1)
+				ON CONFLICT(aggregate_id) DO UPDATE SET n = n + 1`, envelope.AggregateID)
+			return err
+		}).
+		OnWithTx("test.Poisoned", poisoned).
+		OnReset(func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM counts`)
+			return err
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build projection: %v", err)
+	}
+	return eventStore, checkpointStore, letters, built.(*sqlite.SQLiteProjection)
+}
+
+func TestSQLiteProjection_DeadLetter(t *testing.T) {
+	ctx := context.Background()
+	errPoison := errors.New("cannot upcast event")
+
+	var (
+		attempts atomic.Int32
+		fixed    atomic.Bool
+	)
+	var logs bytes.Buffer
+	eventStore, checkpointStore, letters, projection := newDeadLetterProjection(t, 2, &logs, func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+		attempts.Add(1)
+		if !fixed.Load() {
+			return errPoison
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO counts (aggregate_id, n) VALUES (?, 1)`, envelope.AggregateID)
+		return err
+	})
+
+	poisoned := testEnvelope(2)
+	poisoned.EventType = "test.Poisoned"
+	poisoned.Data = []byte("payload")
+	for _, envelope := range []*domain.EventEnvelope{testEnvelope(1), poisoned, testEnvelope(3)} {
+		if err := projection.Handle(ctx, envelope); err != nil {
+			t.Fatalf("expected event %s to be handled or dead-lettered, got %v", envelope.ID, err)
+		}
+	}
+
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("expected 3 attempts (2 retries), got %d", got)
+	}
+	checkpoint, err := checkpointStore.Load("counting")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if checkpoint.Position != 3 {
+		t.Errorf("expected the checkpoint to move past the dead letter to 3, got %d", checkpoint.Position)
+	}
+
+	deadLetters, err := letters.ListDeadLetters(ctx, "counting")
+	if err != nil {
+		t.Fatalf("failed to list dead letters: %v", err)
+	}
+	if len(deadLetters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(deadLetters))
+	}
+	deadLetter := deadLetters[0]
+	if deadLetter.EventID != "evt-2" || deadLetter.EventType != "test.Poisoned" || deadLetter.Position != 2 ||
+		deadLetter.Attempts != 3 || deadLetter.Error != errPoison.Error() {
+		t.Errorf("unexpected dead letter: %+v", deadLetter)
+	}
+	if string(deadLetter.Event.Data) != "payload" || deadLetter.Event.AggregateID != poisoned.AggregateID {
+		t.Errorf("expected the dead letter to keep the event, got %+v", deadLetter.Event)
+	}
+	for _, want := range []string{"level=WARN", "projection=counting", "event_id=evt-2", "attempts=3"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected the dead-letter notice to contain %q, got %q", want, logs.String())
+		}
+	}
+
+	t.Run("ReplayFailure", func(t *testing.T) {
+		if err := projection.ReplayDeadLetter(ctx, deadLetter.ID); !errors.Is(err, errPoison) {
+			t.Fatalf("expected the replay to fail with the handler error, got %v", err)
+		}
+		deadLetter, err := letters.GetDeadLetter(ctx, deadLetter.ID)
+		if err != nil {
+			t.Fatalf("failed to get dead letter: %v", err)
+		}
+		if deadLetter.Attempts != 4 {
+			t.Errorf("expected the failed replay to be counted, got %d attempts", deadLetter.Attempts)
+		}
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		fixed.Store(true)
+		if err := projection.ReplayDeadLetter(ctx, deadLetter.ID); err != nil {
+			t.Fatalf("failed to replay dead letter: %v", err)
+		}
+		var n int
+		if err := eventStore.DB().QueryRow(`SELECT n FROM counts WHERE aggregate_id = ?`, poisoned.AggregateID).Scan(&n); err != nil {
+			t.Fatalf("expected the replayed event to be projected: %v", err)
+		}
+		if _, err := letters.GetDeadLetter(ctx, deadLetter.ID); !errors.Is(err, sqlite.ErrDeadLetterNotFound) {
+			t.Errorf("expected the replayed dead letter to be removed, got %v", err)
+		}
+		if err := projection.ReplayDeadLetter(ctx, deadLetter.ID); !errors.Is(err, sqlite.ErrDeadLetterNotFound) {
+			t.Errorf("expected replaying it again to fail with ErrDeadLetterNotFound, got %v", err)
+		}
+	})
+}
+
+func TestSQLiteProjection_DeadLetterSkipsRebuilds(t *testing.T) {
+	ctx := context.Background()
+	errPoison := errors.New("cannot upcast event")
+
+	_, _, letters, projection := newDeadLetterProjection(t, 0, &bytes.Buffer{}, func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+		return errPoison
+	})
+
+	poisoned := testEnvelope(1)
+	poisoned.EventType = "test.Poisoned"
+	if err := projection.Handle(ctx, poisoned); err != nil {
+		t.Fatalf("expected the event to be dead-lettered, got %v", err)
+	}
+	if err := projection.Handle(domain.WithReplay(ctx), poisoned); !errors.Is(err, errPoison) {
+		t.Errorf("expected replayed events to fail as before, got %v", err)
+	}
+
+	// A reset starts the projection over, dropping its dead letters
+	if err := projection.Reset(ctx); err != nil {
+		t.Fatalf("failed to reset: %v", err)
+	}
+	if deadLetters, err := letters.ListDeadLetters(ctx, "counting"); err != nil || len(deadLetters) != 0 {
+		t.Errorf("expected no dead letters after a reset, got %d (%v)", len(deadLetters), err)
+	}
+}
+
+func TestDeadLetterStoreTablePrefix(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	if _, err := sqlite.NewDeadLetterStore(eventStore.DB()); err != nil {
+		t.Fatalf("failed to create dead letter store: %v", err)
+	}
+	prefixed, err := sqlite.NewDeadLetterStore(eventStore.DB(), sqlite.WithDeadLetterTablePrefix("billing_"))
+	if err != nil {
+		t.Fatalf("failed to create prefixed dead letter store: %v", err)
+	}
+	if letters, err := prefixed.ListDeadLetters(ctx, "balances"); err != nil || len(letters) != 0 {
+		t.Errorf("expected no dead letters in the prefixed store, got %v (%v)", letters, err)
+	}
+
+	var tables int
+	if err := eventStore.DB().QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'billing_projection_dead_letters'`).Scan(&tables); err != nil || tables != 1 {
+		t.Errorf("expected the billing_projection_dead_letters table, got %d (%v)", tables, err)
+	}
+}
@@ -0,0 +1,64 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+
+	"github.com/plaenen/eventstore/pkg/store"
+	sqlite "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// contextError returns ctx.Err() if ctx is done, so a statement aborted by a
+// cancelled or expired context reports the cancellation instead of the driver
+// error it surfaced as, and classifies err otherwise.
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return classifyError(err)
+}
+
+// classifyError maps SQLite result codes to the store error taxonomy.
+// Errors that are already classified, or that do not come from the driver, are
+// returned unchanged.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var classified *store.Error
+	if errors.As(err, &classified) {
+		return err
+	}
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	// The low byte of an extended result code is its primary code
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return &store.Error{Kind: store.ErrBusy, Err: err}
+	case sqlite3.SQLITE_CORRUPT, sqlite3.SQLITE_NOTADB:
+		return &store.Error{Kind: store.ErrCorrupt, Err: err}
+	case sqlite3.SQLITE_CONSTRAINT:
+		return &store.Error{Kind: store.ErrConstraintViolation, Err: err}
+	}
+	return err
+}
+
+// classifyInsertEventError classifies a failed event insert. The events table
+// is unique on event ID and on (aggregate_id, version), so a uniqueness
+// violation means another writer appended the same version first.
+func classifyInsertEventError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return &store.Error{Kind: store.ErrConcurrencyConflict, Err: err}
+		}
+	}
+	return classifyError(err)
+}
@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// defaultStreamBatchSize is used by StreamEvents when batchSize is not positive.
+const defaultStreamBatchSize = 500
+
+// StreamEvents yields the events of an aggregate after afterVersion in version
+// order, querying batchSize events at a time, so an aggregate with a very long
+// history can be replayed without holding all of it in memory. Iteration stops
+// at the first error. It implements store.EventStreamer.
+func (s *EventStore) StreamEvents(aggregateID string, afterVersion int64, batchSize int) iter.Seq2[*domain.Event, error] {
+	if batchSize <= 0 {
+		batchSize = defaultStreamBatchSize
+	}
+	return func(yield func(*domain.Event, error) bool) {
+		version := afterVersion
+		for {
+			batch, err := s.loadEventsBatch(aggregateID, version, batchSize)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, event := range batch {
+				if !yield(event, nil) {
+					return
+				}
+			}
+			if len(batch) < batchSize {
+				return
+			}
+			version = batch[len(batch)-1].Version
+		}
+	}
+}
+
+// loadEventsBatch loads up to limit events of an aggregate after afterVersion.
+// The lock is held per batch only, so the consumer may use the store while
+// iterating.
+func (s *EventStore) loadEventsBatch(aggregateID string, afterVersion int64, limit int) ([]*domain.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.QueryContext(context.Background(), s.tables.rewrite(`
+		SELECT event_id, aggregate_id, aggregate_type, event_type,
+		       version, timestamp, data, metadata, constraints, position
+		FROM events
+		WHERE aggregate_id = ? AND version > ?
+		ORDER BY version ASC
+		LIMIT ?`), aggregateID, afterVersion, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+// StreamAllEvents yields the events at or after fromPosition in position order
+// (see store.AllEventsStreamer). With WAL mode and a connection pool it reads
+// them through a single cursor, one event at a time, from a snapshot taken
+// when iteration starts: events appended meanwhile are not included. The store
+// is not locked while the consumer handles an event, so it may write to the
+// same database, e.g. a projection rebuilding itself.
+//
+// With a single connection (":memory:") or without WAL, an open cursor would
+// block those writes, so the events are read in batches instead.
+func (s *EventStore) StreamAllEvents(ctx context.Context, fromPosition int64) iter.Seq2[*domain.Event, error] {
+	return func(yield func(*domain.Event, error) bool) {
+		if !s.canStreamWithCursor(ctx) {
+			for event, err := range store.StreamAllEvents(ctx, eventsOnly{s}, fromPosition, defaultStreamBatchSize) {
+				if !yield(event, err) || err != nil {
+					return
+				}
+			}
+			return
+		}
+
+		rows, err := s.db.QueryContext(ctx, s.tables.rewrite(`
+			SELECT event_id, aggregate_id, aggregate_type, event_type,
+			       version, timestamp, data, metadata, constraints, position
+			FROM events
+			WHERE position >= ?
+			ORDER BY position ASC`), fromPosition)
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to query all events: %w", contextError(ctx, err)))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			event, err := scanEvent(rows)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(event, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(nil, fmt.Errorf("failed to iterate events: %w", contextError(ctx, err)))
+		}
+	}
+}
+
+// canStreamWithCursor reports whether a long-lived read cursor leaves the
+// database writable: it needs WAL mode and a second connection.
+func (s *EventStore) canStreamWithCursor(ctx context.Context) bool {
+	if s.db.Stats().MaxOpenConnections == 1 {
+		return false
+	}
+	var mode string
+	if err := s.db.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode); err != nil {
+		return false
+	}
+	return strings.EqualFold(mode, "wal")
+}
+
+// eventsOnly hides the AllEventsStreamer implementation of an EventStore, so
+// store.StreamAllEvents falls back to paging through LoadAllEvents.
+type eventsOnly struct {
+	store.EventStore
+}
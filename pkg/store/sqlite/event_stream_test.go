@@ -0,0 +1,118 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestStreamAllEvents(t *testing.T) {
+	appendEvents := func(t *testing.T, eventStore *sqlite.EventStore, from, n int) {
+		t.Helper()
+		for i := from; i < from+n; i++ {
+			aggregateID := fmt.Sprintf("agg-%d", i)
+			err := eventStore.AppendEvents(context.Background(), aggregateID, 0, []*domain.Event{{
+				ID:            fmt.Sprintf("evt-%05d", i),
+				AggregateID:   aggregateID,
+				AggregateType: "TestAggregate",
+				EventType:     "test.Happened",
+				Version:       1,
+				Timestamp:     time.Unix(1700000000, 0),
+				Data:          []byte("data"),
+			}})
+			if err != nil {
+				t.Fatalf("failed to append event %d: %v", i, err)
+			}
+		}
+	}
+	// stream reads all events from position 1, checking they come in order
+	stream := func(t *testing.T, ctx context.Context, eventStore *sqlite.EventStore, each func(*domain.Event)) (int, error) {
+		t.Helper()
+		count, last := 0, int64(0)
+		for event, err := range eventStore.StreamAllEvents(ctx, 1) {
+			if err != nil {
+				return count, err
+			}
+			if event.Position <= last {
+				t.Fatalf("expected increasing positions, got %d after %d", event.Position, last)
+			}
+			last = event.Position
+			count++
+			if each != nil {
+				each(event)
+			}
+		}
+		return count, nil
+	}
+
+	t.Run("Cursor", func(t *testing.T) {
+		eventStore, err := sqlite.NewEventStore(sqlite.WithDSN(filepath.Join(t.TempDir(), "events.db")))
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer eventStore.Close()
+		appendEvents(t, eventStore, 0, 50)
+
+		// The store stays writable while the cursor is open; the stream reads
+		// the snapshot taken when it started
+		appended := false
+		count, err := stream(t, context.Background(), eventStore, func(*domain.Event) {
+			if !appended {
+				appendEvents(t, eventStore, 50, 1)
+				appended = true
+			}
+		})
+		if err != nil {
+			t.Fatalf("failed to stream events: %v", err)
+		}
+		if count != 50 {
+			t.Errorf("expected 50 events, got %d", count)
+		}
+	})
+
+	t.Run("Batched", func(t *testing.T) {
+		eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer eventStore.Close()
+		appendEvents(t, eventStore, 0, 1200)
+
+		count, err := stream(t, context.Background(), eventStore, nil)
+		if err != nil {
+			t.Fatalf("failed to stream events: %v", err)
+		}
+		if count != 1200 {
+			t.Errorf("expected 1200 events, got %d", count)
+		}
+	})
+
+	t.Run("Cancellation", func(t *testing.T) {
+		eventStore, err := sqlite.NewEventStore(sqlite.WithDSN(filepath.Join(t.TempDir(), "events.db")))
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer eventStore.Close()
+		appendEvents(t, eventStore, 0, 10)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		count, err := stream(t, ctx, eventStore, func(event *domain.Event) {
+			if event.Position == 3 {
+				cancel()
+			}
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if count != 3 {
+			t.Errorf("expected the stream to stop after 3 events, got %d", count)
+		}
+	})
+}
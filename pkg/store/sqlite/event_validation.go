@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/eventsourcing"
+)
+
+// InvalidEventPayloadCode is the AppError code returned when an appended
+// event's data does not decode as its declared event type.
+const InvalidEventPayloadCode = "INVALID_EVENT_PAYLOAD"
+
+// WithEventValidation decodes every appended event with registry and rejects
+// the append if any event's type is unknown or its data is not a valid
+// message of that type. The error is an *eventsourcing.ResponseError with code
+// INVALID_EVENT_PAYLOAD; nothing from the batch is stored.
+//
+// A malformed payload otherwise goes unnoticed until it is replayed, possibly
+// hours later during a rebuild. Decoding costs about as much as the proto
+// marshal that produced the data, so leave it on in production and skip it
+// for bulk imports of trusted events with WithoutEventValidation.
+//
+// Example:
+//
+//	eventStore, err := sqlite.NewEventStore(
+//	    sqlite.WithDSN("events.db"),
+//	    sqlite.WithEventValidation(domain.MergeEventRegistries(accountv1.EventRegistry, orderv1.EventRegistry)),
+//	)
+func WithEventValidation(registry domain.EventRegistry) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.eventRegistry = registry
+	}
+}
+
+type skipValidationKey struct{}
+
+// WithoutEventValidation returns a context whose appends skip
+// WithEventValidation, e.g. for a bulk import of events that were validated
+// when first written:
+//
+//	err := eventStore.AppendEvents(sqlite.WithoutEventValidation(ctx), aggregateID, 0, events)
+func WithoutEventValidation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipValidationKey{}, true)
+}
+
+// validateEvents checks the payload of every event when validation is enabled
+// and ctx does not skip it.
+func (s *EventStore) validateEvents(ctx context.Context, events []*domain.Event) error {
+	if s.eventRegistry == nil {
+		return nil
+	}
+	if skip, _ := ctx.Value(skipValidationKey{}).(bool); skip {
+		return nil
+	}
+
+	for _, event := range events {
+		if _, err := s.eventRegistry.Decode(event); err != nil {
+			return &eventsourcing.ResponseError{AppError: &eventsourcing.AppError{
+				Code:     InvalidEventPayloadCode,
+				Message:  fmt.Sprintf("event %s has an invalid payload: %v", event.ID, err),
+				Solution: "Marshal the event data from the proto message registered for its event type",
+				Details: map[string]string{
+					"event_id":     event.ID,
+					"event_type":   event.EventType,
+					"aggregate_id": event.AggregateID,
+				},
+			}}
+		}
+	}
+	return nil
+}
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/messaging"
 	"github.com/plaenen/eventstore/pkg/store/sqlite/sqlcgen"
 	_ "modernc.org/sqlite" // Pure Go SQLite driver
 )
@@ -19,6 +21,26 @@ type EventStore struct {
 	db      *sql.DB
 	queries *sqlcgen.Queries
 	mu      sync.RWMutex // Protects concurrent access to connection pool
+	path    string       // Database file path ("" for in-memory)
+	ownsDB  bool         // Close closes db (false for WithDB)
+	tables  tablePrefix  // Prepended to table and index names
+
+	eventRegistry domain.EventRegistry // Validates appended payloads (nil = off)
+
+	monotonicTimestamps bool // Clamp timestamps to be non-decreasing per aggregate
+	hashChain           bool // Link each aggregate's events in a hash chain
+	contentDedupWindow  int  // Drop events identical to one of the last n (0 = off)
+	outbox              bool // Write appended events to the event_outbox table
+
+	groupCommit *groupCommitter // Coalesces concurrent AppendEvents (nil when disabled)
+
+	eventBus messaging.EventBus // Live events for SubscribeFromPosition (nil = none)
+
+	logger *slog.Logger // Logs background failures such as periodic WAL checkpoints
+
+	// Background WAL checkpointing (nil when disabled)
+	stopWALCheckpoints chan struct{}
+	walCheckpointsDone chan struct{}
 }
 
 // eventStoreConfig holds internal configuration for the SQLite event store.
@@ -37,6 +59,51 @@ type eventStoreConfig struct {
 
 	// autoMigrate automatically runs pending migrations on startup
 	autoMigrate bool
+
+	// startupIntegrityCheck verifies the database file before it is used
+	startupIntegrityCheck bool
+
+	// walAutoCheckpoint is the WAL auto-checkpoint threshold in pages (0 = SQLite default)
+	walAutoCheckpoint int
+
+	// walCheckpointInterval is how often the WAL is checkpointed and truncated (0 = never)
+	walCheckpointInterval time.Duration
+
+	// eventRegistry validates appended event payloads (nil = no validation)
+	eventRegistry domain.EventRegistry
+
+	// tablePrefix is prepended to all table and index names
+	tablePrefix string
+
+	// monotonicTimestamps clamps event timestamps to be non-decreasing per aggregate
+	monotonicTimestamps bool
+
+	// hashChain links each aggregate's events in a hash chain
+	hashChain bool
+
+	// contentDedupWindow drops events identical to one of the aggregate's last n (0 = off)
+	contentDedupWindow int
+
+	// outbox writes appended events to the event_outbox table for an OutboxRelay
+	outbox bool
+
+	// db is a caller-provided pool used instead of opening dsn (nil = open dsn)
+	db *sql.DB
+
+	// connectHooks run on every new connection of the pool
+	connectHooks []func(conn *sql.Conn) error
+
+	// pragmas are set on every new connection of the pool, after those of walMode
+	pragmas []pragma
+
+	// groupCommitWindow is how long AppendEvents waits to share a transaction (0 = off)
+	groupCommitWindow time.Duration
+
+	// eventBus carries live events for SubscribeFromPosition (nil = none)
+	eventBus messaging.EventBus
+
+	// logger logs background failures (nil = slog.Default())
+	logger *slog.Logger
 }
 
 // defaultEventStoreConfig returns sensible defaults.
@@ -104,6 +171,40 @@ func WithAutoMigrate(enabled bool) EventStoreOption {
 	}
 }
 
+// WithStartupIntegrityCheck verifies the database with VerifyIntegrity when the
+// store is opened, and fails with ErrIntegrityCheckFailed if it is corrupt.
+// Recommended for edge devices prone to unclean shutdowns, where detecting
+// corruption at startup beats discovering it on a failed load later.
+func WithStartupIntegrityCheck() EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.startupIntegrityCheck = true
+	}
+}
+
+// WithTablePrefix prepends prefix to the names of all tables and indexes of the
+// store (events, snapshots, unique_constraints, ...) and its migration table,
+// so several independent event stores can live in one SQLite file. The prefix
+// must consist of letters, digits and underscores, e.g. "billing_".
+//
+// Stores sharing the database must be given the same prefix:
+//
+//	events, err := sqlite.NewEventStore(sqlite.WithFilename("app.db"), sqlite.WithTablePrefix("billing_"))
+//	snapshots := sqlite.NewSnapshotStore(events.DB(), sqlite.WithSnapshotTablePrefix(events.TablePrefix()))
+//	checkpoints, err := sqlite.NewCheckpointStore(events.DB(), sqlite.WithCheckpointTablePrefix(events.TablePrefix()))
+func WithTablePrefix(prefix string) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.tablePrefix = prefix
+	}
+}
+
+// WithLogger logs failures of the store's background work, such as periodic
+// WAL checkpoints, to logger (default slog.Default()).
+func WithLogger(logger *slog.Logger) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.logger = logger
+	}
+}
+
 // NewEventStore creates a new SQLite event store with the given options.
 //
 // Example usage:
@@ -129,49 +230,134 @@ func NewEventStore(opts ...EventStoreOption) (*EventStore, error) {
 		opt(&config)
 	}
 
-	db, err := sql.Open("sqlite", config.dsn)
+	tables, err := newTablePrefix(config.tablePrefix)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
-	// For :memory: databases, we need to ensure we use a single connection
-	// Otherwise each connection gets its own isolated in-memory database
-	if config.dsn == ":memory:" {
-		db.SetMaxOpenConns(1)
-		db.SetMaxIdleConns(1)
+	var (
+		db   *sql.DB
+		path string
+	)
+	if config.db != nil {
+		// The caller owns the pool and its configuration
+		if err := checkInjectedDB(config); err != nil {
+			return nil, err
+		}
+		db = config.db
+		path, err = mainDatabaseFile(db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read database file: %w", classifyError(err))
+		}
 	} else {
-		// Configure connection pool
-		db.SetMaxOpenConns(config.maxOpenConns)
-		db.SetMaxIdleConns(config.maxIdleConns)
+		dsn := config.dsn
+		if config.walAutoCheckpoint > 0 {
+			dsn = withPragma(dsn, fmt.Sprintf("wal_autocheckpoint(%d)", config.walAutoCheckpoint))
+		}
+
+		pragmas, err := connectionPragmas(config)
+		if err != nil {
+			return nil, err
+		}
+		hooks := config.connectHooks
+		if hook := pragmaHook(pragmas); hook != nil {
+			hooks = append([]func(conn *sql.Conn) error{hook}, hooks...)
+		}
+
+		db, err = openDB(dsn, hooks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", classifyError(err))
+		}
+
+		// For :memory: databases, we need to ensure we use a single connection
+		// Otherwise each connection gets its own isolated in-memory database
+		if config.dsn == ":memory:" {
+			db.SetMaxOpenConns(1)
+			db.SetMaxIdleConns(1)
+		} else {
+			// Configure connection pool
+			db.SetMaxOpenConns(config.maxOpenConns)
+			db.SetMaxIdleConns(config.maxIdleConns)
+		}
+		db.SetConnMaxLifetime(time.Hour)
+		path = databasePath(config.dsn)
 	}
-	db.SetConnMaxLifetime(time.Hour)
 
 	store := &EventStore{
 		db:      db,
-		queries: sqlcgen.New(db),
+		queries: tables.queries(db),
+		path:    path,
+		ownsDB:  config.db == nil,
+		tables:  tables,
+
+		eventRegistry:       config.eventRegistry,
+		monotonicTimestamps: config.monotonicTimestamps,
+		hashChain:           config.hashChain,
+		contentDedupWindow:  config.contentDedupWindow,
+		outbox:              config.outbox,
+		eventBus:            config.eventBus,
+		logger:              config.logger,
+	}
+	if store.logger == nil {
+		store.logger = slog.Default()
+	}
+	if config.groupCommitWindow > 0 {
+		store.groupCommit = newGroupCommitter(store, config.groupCommitWindow)
 	}
 
 	// Configure WAL mode if enabled
 	if config.walMode {
 		if err := store.setWALMode(); err != nil {
-			db.Close()
-			return nil, fmt.Errorf("failed to set WAL mode: %w", err)
+			store.closeDB()
+			return nil, fmt.Errorf("failed to set WAL mode: %w", classifyError(err))
+		}
+	}
+
+	// Verify the database before touching it any further
+	if config.startupIntegrityCheck {
+		report, err := verifyIntegrity(context.Background(), db)
+		if err != nil {
+			store.closeDB()
+			return nil, fmt.Errorf("failed to run startup integrity check: %w", err)
+		}
+		if !report.OK() {
+			store.closeDB()
+			return nil, fmt.Errorf("%w: %s", ErrIntegrityCheckFailed, report)
 		}
 	}
 
 	// Run migrations if auto-migrate is enabled
 	if config.autoMigrate {
-		if err := runMigrations(db); err != nil {
-			db.Close()
-			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		if err := runMigrations(db, tables); err != nil {
+			store.closeDB()
+			return nil, fmt.Errorf("failed to run migrations: %w", classifyError(err))
+		}
+	}
+	if config.outbox {
+		if err := createOutboxTable(db, tables); err != nil {
+			store.closeDB()
+			return nil, err
 		}
 	}
 
+	if config.walMode && config.walCheckpointInterval > 0 && store.path != "" {
+		store.stopWALCheckpoints = make(chan struct{})
+		store.walCheckpointsDone = make(chan struct{})
+		go store.runWALCheckpoints(config.walCheckpointInterval, store.stopWALCheckpoints, store.walCheckpointsDone)
+	}
+
 	return store, nil
 }
 
-// setWALMode configures the database for WAL mode.
+// setWALMode configures the database for WAL mode. The connection-scoped
+// settings that go with it are applied to each connection of an owned pool
+// by the connect hook; on a pool passed with WithDB they can only be set on
+// whichever connection runs the statement.
 func (s *EventStore) setWALMode() error {
+	if s.ownsDB {
+		_, err := s.db.Exec("PRAGMA journal_mode = WAL")
+		return err
+	}
 	_, err := s.db.Exec(`
 		PRAGMA journal_mode = WAL;
 		PRAGMA synchronous = NORMAL;
@@ -180,72 +366,121 @@ func (s *EventStore) setWALMode() error {
 	return err
 }
 
-// AppendEvents appends events to an aggregate's stream atomically.
-func (s *EventStore) AppendEvents(aggregateID string, expectedVersion int64, events []*domain.Event) error {
+// AppendEvents appends events to an aggregate's stream atomically. A
+// cancelled ctx aborts the append, rolling it back, and returns ctx.Err().
+func (s *EventStore) AppendEvents(ctx context.Context, aggregateID string, expectedVersion int64, events []*domain.Event) error {
+	if s.groupCommit != nil {
+		if len(events) == 0 {
+			return nil
+		}
+		if err := s.validateEvents(ctx, events); err != nil {
+			return err
+		}
+		return s.groupCommit.append(ctx, aggregateID, expectedVersion, events)
+	}
+	return s.appendEvents(ctx, aggregateID, expectedVersion, events, s.monotonicTimestamps, true)
+}
+
+// appendEvents implements AppendEvents and ImportEvents. dedup applies
+// WithContentDedup.
+func (s *EventStore) appendEvents(ctx context.Context, aggregateID string, expectedVersion int64, events []*domain.Event, monotonic, dedup bool) error {
 	if len(events) == 0 {
 		return nil
 	}
+	if err := s.validateEvents(ctx, events); err != nil {
+		return err
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", contextError(ctx, err))
 	}
 	defer tx.Rollback()
 
+	kept, _, err := s.insertStream(ctx, tx, aggregateID, expectedVersion, events, monotonic, dedup)
+	if err != nil {
+		return err
+	}
+
+	// Update global position
+	if err := s.updatePositions(ctx, tx); err != nil {
+		return fmt.Errorf("failed to update positions: %w", contextError(ctx, err))
+	}
+	if err := s.loadPositions(ctx, tx, kept); err != nil {
+		return err
+	}
+	if err := s.enqueueOutbox(ctx, tx, kept); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return contextError(ctx, err)
+	}
+	clampedPositions(events, kept)
+	return nil
+}
+
+// insertStream checks the aggregate's version and inserts its events and
+// unique constraints within tx. Positions are left to the caller. With dedup,
+// duplicates are dropped as configured by WithContentDedup; insertStream
+// returns the events it inserted and those it dropped. It stops with
+// ctx.Err() once ctx is cancelled.
+func (s *EventStore) insertStream(ctx context.Context, tx *sql.Tx, aggregateID string, expectedVersion int64, events []*domain.Event, monotonic, dedup bool) (kept, deduped []*domain.Event, err error) {
 	// Check optimistic concurrency
-	ctx := context.Background()
-	queries := sqlcgen.New(tx)
+	queries := s.tables.queries(tx)
 	currentVersionRaw, err := queries.GetAggregateVersion(ctx, aggregateID)
 	if err != nil {
-		return fmt.Errorf("failed to check current version: %w", err)
+		return nil, nil, fmt.Errorf("failed to check current version: %w", contextError(ctx, err))
 	}
 	currentVersion := currentVersionRaw.(int64)
 
 	if currentVersion != expectedVersion {
-		return domain.ErrConcurrencyConflict
+		return nil, nil, domain.ErrConcurrencyConflict
 	}
 
-	// Validate and insert unique constraints
-	for _, event := range events {
-		if err := s.validateConstraints(tx, event, aggregateID); err != nil {
-			return err
+	kept = events
+	if dedup {
+		kept, deduped, err = s.dedupEvents(ctx, tx, aggregateID, expectedVersion, events)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
 
-	// Insert events
-	for _, event := range events {
-		metadataJSON, _ := json.Marshal(event.Metadata)
-		constraintsJSON, _ := json.Marshal(event.UniqueConstraints)
-
-		err = queries.InsertEvent(ctx, sqlcgen.InsertEventParams{
-			EventID:       event.ID,
-			AggregateID:   event.AggregateID,
-			AggregateType: event.AggregateType,
-			EventType:     event.EventType,
-			Version:       event.Version,
-			Timestamp:     event.Timestamp.Unix(),
-			Data:          event.Data,
-			Metadata:      string(metadataJSON),
-			Constraints:   sql.NullString{String: string(constraintsJSON), Valid: len(constraintsJSON) > 0},
-		})
-		if err != nil {
-			return fmt.Errorf("failed to insert event: %w", err)
+	if monotonic {
+		if kept, err = s.clampTimestamps(ctx, tx, aggregateID, kept); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	// Update global position
-	if err := s.updatePositions(tx); err != nil {
-		return fmt.Errorf("failed to update positions: %w", err)
+	// Validate and insert unique constraints
+	for _, event := range kept {
+		if err := s.validateConstraints(ctx, tx, event, aggregateID); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return tx.Commit()
+	// Insert events
+	for _, event := range kept {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		if err := insertEvent(ctx, queries, event); err != nil {
+			return nil, nil, err
+		}
+		if err := s.chainEvent(ctx, tx, event); err != nil {
+			return nil, nil, err
+		}
+	}
+	return kept, deduped, nil
 }
 
-// AppendEventsIdempotent appends events with command-level idempotency.
+// AppendEventsIdempotent appends events with command-level idempotency. A
+// cancelled ctx aborts the append as with AppendEvents.
 func (s *EventStore) AppendEventsIdempotent(
+	ctx context.Context,
 	aggregateID string,
 	expectedVersion int64,
 	events []*domain.Event,
@@ -262,78 +497,54 @@ func (s *EventStore) AppendEventsIdempotent(
 			Events:    nil,
 		}, nil
 	}
+	if err := s.validateEvents(ctx, events); err != nil {
+		return nil, err
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Check if command already processed
-	result, err := s.getCommandResultNoLock(commandID)
+	result, err := s.getCommandResultNoLock(ctx, commandID)
 	if err == nil && result != nil {
 		return result, nil // Idempotent return
 	}
 
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", contextError(ctx, err))
 	}
 	defer tx.Rollback()
 
 	// Double-check within transaction
-	ctx := context.Background()
-	queries := sqlcgen.New(tx)
+	queries := s.tables.queries(tx)
 	existingCommand, err := queries.CheckCommandExists(ctx, commandID)
 	if err == nil && existingCommand != "" {
 		// Command was processed between our check and tx start
 		tx.Rollback()
-		return s.getCommandResultNoLock(commandID)
+		return s.getCommandResultNoLock(ctx, commandID)
 	} else if err != nil && err != sql.ErrNoRows {
-		return nil, fmt.Errorf("failed to check processed commands: %w", err)
+		return nil, fmt.Errorf("failed to check processed commands: %w", contextError(ctx, err))
 	}
 
-	// Check optimistic concurrency
-	currentVersionRaw, err := queries.GetAggregateVersion(ctx, aggregateID)
+	kept, deduped, err := s.insertStream(ctx, tx, aggregateID, expectedVersion, events, s.monotonicTimestamps, true)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check current version: %w", err)
-	}
-	currentVersion := currentVersionRaw.(int64)
-
-	if currentVersion != expectedVersion {
-		return nil, domain.ErrConcurrencyConflict
+		return nil, err
 	}
-
-	// Validate and insert unique constraints
-	for _, event := range events {
-		if err := s.validateConstraints(tx, event, aggregateID); err != nil {
-			return nil, err
-		}
-	}
-
-	// Insert events
-	eventIDs := make([]string, len(events))
-	for i, event := range events {
-		metadataJSON, _ := json.Marshal(event.Metadata)
-		constraintsJSON, _ := json.Marshal(event.UniqueConstraints)
-
-		err = queries.InsertEvent(ctx, sqlcgen.InsertEventParams{
-			EventID:       event.ID,
-			AggregateID:   event.AggregateID,
-			AggregateType: event.AggregateType,
-			EventType:     event.EventType,
-			Version:       event.Version,
-			Timestamp:     event.Timestamp.Unix(),
-			Data:          event.Data,
-			Metadata:      string(metadataJSON),
-			Constraints:   sql.NullString{String: string(constraintsJSON), Valid: len(constraintsJSON) > 0},
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to insert event: %w", err)
-		}
+	eventIDs := make([]string, len(kept))
+	for i, event := range kept {
 		eventIDs[i] = event.ID
 	}
 
 	// Update global position
-	if err := s.updatePositions(tx); err != nil {
-		return nil, fmt.Errorf("failed to update positions: %w", err)
+	if err := s.updatePositions(ctx, tx); err != nil {
+		return nil, fmt.Errorf("failed to update positions: %w", contextError(ctx, err))
+	}
+	if err := s.loadPositions(ctx, tx, kept); err != nil {
+		return nil, err
+	}
+	if err := s.enqueueOutbox(ctx, tx, kept); err != nil {
+		return nil, err
 	}
 
 	// Record processed command
@@ -349,25 +560,53 @@ func (s *EventStore) AppendEventsIdempotent(
 		EventIds:    string(eventIDsJSON),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to record command: %w", err)
+		return nil, fmt.Errorf("failed to record command: %w", contextError(ctx, err))
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", contextError(ctx, err))
 	}
+	clampedPositions(events, kept)
 
 	return &domain.CommandResult{
 		CommandID:        commandID,
-		Events:           events,
+		Events:           kept,
+		Deduplicated:     deduped,
 		AlreadyProcessed: false,
 		ProcessedAt:      now,
+		Position:         lastPosition(kept),
 	}, nil
 }
 
+// insertEvent inserts one event row.
+func insertEvent(ctx context.Context, queries *sqlcgen.Queries, event *domain.Event) error {
+	metadataJSON, _ := json.Marshal(event.Metadata)
+	constraintsJSON, _ := json.Marshal(event.UniqueConstraints)
+
+	err := queries.InsertEvent(ctx, sqlcgen.InsertEventParams{
+		EventID:       event.ID,
+		AggregateID:   event.AggregateID,
+		AggregateType: event.AggregateType,
+		EventType:     event.EventType,
+		Version:       event.Version,
+		Timestamp:     event.Timestamp.Unix(),
+		Data:          event.Data,
+		Metadata:      string(metadataJSON),
+		Constraints:   sql.NullString{String: string(constraintsJSON), Valid: len(constraintsJSON) > 0},
+		CorrelationID: nullString(event.Metadata.CorrelationID),
+		CausationID:   nullString(event.Metadata.CausationID),
+		PrincipalID:   nullString(event.Metadata.PrincipalID),
+		TenantID:      nullString(event.Metadata.TenantID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to insert event: %w", classifyInsertEventError(err))
+	}
+	return nil
+}
+
 // validateConstraints validates and applies unique constraints.
-func (s *EventStore) validateConstraints(tx *sql.Tx, event *domain.Event, aggregateID string) error {
-	ctx := context.Background()
-	queries := sqlcgen.New(tx)
+func (s *EventStore) validateConstraints(ctx context.Context, tx *sql.Tx, event *domain.Event, aggregateID string) error {
+	queries := s.tables.queries(tx)
 
 	for _, constraint := range event.UniqueConstraints {
 		switch constraint.Operation {
@@ -382,7 +621,7 @@ func (s *EventStore) validateConstraints(tx *sql.Tx, event *domain.Event, aggreg
 				// Value already claimed by different aggregate
 				return domain.NewUniqueConstraintError(constraint.IndexName, constraint.Value, ownerID)
 			} else if err != nil && err != sql.ErrNoRows {
-				return fmt.Errorf("failed to check uniqueness: %w", err)
+				return fmt.Errorf("failed to check uniqueness: %w", classifyError(err))
 			}
 
 			// Claim the value
@@ -393,7 +632,7 @@ func (s *EventStore) validateConstraints(tx *sql.Tx, event *domain.Event, aggreg
 				CreatedAt:   time.Now().Unix(),
 			})
 			if err != nil {
-				return fmt.Errorf("failed to claim constraint: %w", err)
+				return fmt.Errorf("failed to claim constraint: %w", classifyError(err))
 			}
 
 		case domain.ConstraintRelease:
@@ -404,7 +643,7 @@ func (s *EventStore) validateConstraints(tx *sql.Tx, event *domain.Event, aggreg
 				AggregateID: aggregateID,
 			})
 			if err != nil {
-				return fmt.Errorf("failed to release constraint: %w", err)
+				return fmt.Errorf("failed to release constraint: %w", classifyError(err))
 			}
 		}
 	}
@@ -412,10 +651,47 @@ func (s *EventStore) validateConstraints(tx *sql.Tx, event *domain.Event, aggreg
 }
 
 // updatePositions updates the global position for events.
-func (s *EventStore) updatePositions(tx *sql.Tx) error {
-	ctx := context.Background()
-	queries := sqlcgen.New(tx)
+func (s *EventStore) updatePositions(ctx context.Context, tx *sql.Tx) error {
+	queries := s.tables.queries(tx)
 	return queries.UpdateEventPositions(ctx)
 }
 
+// nullString maps an empty metadata field to NULL, keeping it out of the
+// metadata indexes.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// loadPositions sets the assigned global position on freshly inserted events,
+// so events published right after append carry the same position as events
+// later loaded from the store.
+func (s *EventStore) loadPositions(ctx context.Context, q rowQuerier, events []*domain.Event) error {
+	for _, event := range events {
+		var position sql.NullInt64
+		err := q.QueryRowContext(ctx,
+			s.tables.rewrite("SELECT position FROM events WHERE event_id = ?"), event.ID).Scan(&position)
+		if err != nil {
+			return fmt.Errorf("failed to load position of event %s: %w", event.ID, err)
+		}
+		event.Position = position.Int64
+	}
+	return nil
+}
+
+// lastPosition returns the highest global position among events.
+func lastPosition(events []*domain.Event) int64 {
+	var position int64
+	for _, event := range events {
+		if event.Position > position {
+			position = event.Position
+		}
+	}
+	return position
+}
+
 // Continue in next file...
@@ -15,12 +15,11 @@ import (
 func (s *EventStore) GetCommandResult(commandID string) (*domain.CommandResult, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.getCommandResultNoLock(commandID)
+	return s.getCommandResultNoLock(context.Background(), commandID)
 }
 
 // getCommandResultNoLock retrieves command result without locking (internal use).
-func (s *EventStore) getCommandResultNoLock(commandID string) (*domain.CommandResult, error) {
-	ctx := context.Background()
+func (s *EventStore) getCommandResultNoLock(ctx context.Context, commandID string) (*domain.CommandResult, error) {
 	row, err := s.queries.GetProcessedCommand(ctx, sqlcgen.GetProcessedCommandParams{
 		CommandID: commandID,
 		ExpiresAt: domain.Now().Unix(),
@@ -30,7 +29,7 @@ func (s *EventStore) getCommandResultNoLock(commandID string) (*domain.CommandRe
 		return nil, fmt.Errorf("command not found")
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to query command: %w", err)
+		return nil, fmt.Errorf("failed to query command: %w", classifyError(err))
 	}
 
 	processedAt := row.ProcessedAt
@@ -44,24 +43,27 @@ func (s *EventStore) getCommandResultNoLock(commandID string) (*domain.CommandRe
 	// Load the events
 	events := make([]*domain.Event, 0, len(eventIDs))
 	for _, eventID := range eventIDs {
-		event, err := s.loadEventByID(eventID)
+		event, err := s.loadEventByID(ctx, eventID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load event %s: %w", eventID, err)
 		}
 		events = append(events, event)
 	}
+	if err := s.loadPositions(ctx, s.db, events); err != nil {
+		return nil, err
+	}
 
 	return &domain.CommandResult{
 		CommandID:        commandID,
 		Events:           events,
 		AlreadyProcessed: true,
 		ProcessedAt:      time.Unix(processedAt, 0),
+		Position:         lastPosition(events),
 	}, nil
 }
 
 // loadEventByID loads a single event by its ID.
-func (s *EventStore) loadEventByID(eventID string) (*domain.Event, error) {
-	ctx := context.Background()
+func (s *EventStore) loadEventByID(ctx context.Context, eventID string) (*domain.Event, error) {
 	row, err := s.queries.LoadEventByID(ctx, eventID)
 	if err != nil {
 		return nil, err
@@ -86,17 +88,16 @@ func (s *EventStore) loadEventByID(eventID string) (*domain.Event, error) {
 }
 
 // LoadEvents loads all events for an aggregate starting from afterVersion.
-func (s *EventStore) LoadEvents(aggregateID string, afterVersion int64) ([]*domain.Event, error) {
+func (s *EventStore) LoadEvents(ctx context.Context, aggregateID string, afterVersion int64) ([]*domain.Event, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	ctx := context.Background()
 	rows, err := s.queries.LoadEvents(ctx, sqlcgen.LoadEventsParams{
 		AggregateID: aggregateID,
 		Version:     afterVersion,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query events: %w", err)
+		return nil, fmt.Errorf("failed to query events: %w", contextError(ctx, err))
 	}
 
 	events := make([]*domain.Event, 0, len(rows))
@@ -123,17 +124,16 @@ func (s *EventStore) LoadEvents(aggregateID string, afterVersion int64) ([]*doma
 }
 
 // LoadAllEvents loads all events from all aggregates for projection building.
-func (s *EventStore) LoadAllEvents(fromPosition int64, limit int) ([]*domain.Event, error) {
+func (s *EventStore) LoadAllEvents(ctx context.Context, fromPosition int64, limit int) ([]*domain.Event, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	ctx := context.Background()
 	rows, err := s.queries.LoadAllEvents(ctx, sqlcgen.LoadAllEventsParams{
 		Position: sql.NullInt64{Int64: fromPosition, Valid: true},
 		Limit:    int64(limit),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query all events: %w", err)
+		return nil, fmt.Errorf("failed to query all events: %w", contextError(ctx, err))
 	}
 
 	events := make([]*domain.Event, 0, len(rows))
@@ -146,6 +146,7 @@ func (s *EventStore) LoadAllEvents(fromPosition int64, limit int) ([]*domain.Eve
 			Version:       row.Version,
 			Timestamp:     time.Unix(row.Timestamp, 0),
 			Data:          row.Data,
+			Position:      row.Position.Int64,
 		}
 
 		json.Unmarshal([]byte(row.Metadata), &event.Metadata)
@@ -159,6 +160,32 @@ func (s *EventStore) LoadAllEvents(fromPosition int64, limit int) ([]*domain.Eve
 	return events, nil
 }
 
+// CountEvents returns the total number of events in the store.
+func (s *EventStore) CountEvents() (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int64
+	if err := s.db.QueryRowContext(context.Background(), s.tables.rewrite("SELECT COUNT(*) FROM events")).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count events: %w", classifyError(err))
+	}
+
+	return count, nil
+}
+
+// LatestPosition returns the position of the latest event (0 = none).
+func (s *EventStore) LatestPosition(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var position int64
+	if err := s.db.QueryRowContext(ctx, s.tables.rewrite("SELECT COALESCE(MAX(position), 0) FROM events")).Scan(&position); err != nil {
+		return 0, fmt.Errorf("failed to read latest position: %w", contextError(ctx, err))
+	}
+
+	return position, nil
+}
+
 // GetAggregateVersion returns the current version of an aggregate.
 func (s *EventStore) GetAggregateVersion(aggregateID string) (int64, error) {
 	s.mu.RLock()
@@ -167,7 +194,7 @@ func (s *EventStore) GetAggregateVersion(aggregateID string) (int64, error) {
 	ctx := context.Background()
 	versionRaw, err := s.queries.GetAggregateVersion(ctx, aggregateID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get aggregate version: %w", err)
+		return 0, fmt.Errorf("failed to get aggregate version: %w", classifyError(err))
 	}
 
 	return versionRaw.(int64), nil
@@ -188,7 +215,7 @@ func (s *EventStore) CheckUniqueness(indexName, value string) (available bool, o
 		return true, "", nil // Available
 	}
 	if err != nil {
-		return false, "", fmt.Errorf("failed to check uniqueness: %w", err)
+		return false, "", fmt.Errorf("failed to check uniqueness: %w", classifyError(err))
 	}
 
 	return false, ownerID, nil // Not available, already claimed
@@ -209,7 +236,7 @@ func (s *EventStore) GetConstraintOwner(indexName, value string) (string, error)
 		return "", nil // Not claimed
 	}
 	if err != nil {
-		return "", fmt.Errorf("failed to get constraint owner: %w", err)
+		return "", fmt.Errorf("failed to get constraint owner: %w", classifyError(err))
 	}
 
 	return ownerID, nil
@@ -222,23 +249,23 @@ func (s *EventStore) RebuildConstraints() error {
 
 	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", classifyError(err))
 	}
 	defer tx.Rollback()
 
 	ctx := context.Background()
-	queries := sqlcgen.New(tx)
+	queries := s.tables.queries(tx)
 
 	// Clear existing constraints
 	err = queries.DeleteAllConstraints(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to clear constraints: %w", err)
+		return fmt.Errorf("failed to clear constraints: %w", classifyError(err))
 	}
 
 	// Replay all events in order
 	rows, err := queries.GetAllConstraints(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to query events: %w", err)
+		return fmt.Errorf("failed to query events: %w", classifyError(err))
 	}
 
 	for _, row := range rows {
@@ -261,7 +288,7 @@ func (s *EventStore) RebuildConstraints() error {
 					CreatedAt:   domain.Now().Unix(),
 				})
 				if err != nil {
-					return fmt.Errorf("failed to rebuild constraint: %w", err)
+					return fmt.Errorf("failed to rebuild constraint: %w", classifyError(err))
 				}
 			} else if constraint.Operation == domain.ConstraintRelease {
 				err = queries.ReleaseConstraint(ctx, sqlcgen.ReleaseConstraintParams{
@@ -270,7 +297,7 @@ func (s *EventStore) RebuildConstraints() error {
 					AggregateID: row.AggregateID,
 				})
 				if err != nil {
-					return fmt.Errorf("failed to rebuild constraint release: %w", err)
+					return fmt.Errorf("failed to rebuild constraint release: %w", classifyError(err))
 				}
 			}
 		}
@@ -287,21 +314,129 @@ func (s *EventStore) CleanExpiredCommands() (int64, error) {
 	ctx := context.Background()
 	rowsAffected, err := s.queries.CleanExpiredCommands(ctx, domain.Now().Unix())
 	if err != nil {
-		return 0, fmt.Errorf("failed to clean expired commands: %w", err)
+		return 0, fmt.Errorf("failed to clean expired commands: %w", classifyError(err))
 	}
 
 	return rowsAffected, nil
 }
 
+// DeleteAggregatesWithPrefix permanently removes, in one transaction, every
+// aggregate whose ID starts with prefix: its events, the unpublished outbox
+// rows of those events (see WithOutbox), snapshots, unique constraint claims
+// and processed commands. It returns the number of deleted
+// events. Global positions of the remaining events are left unchanged, so
+// checkpoints stay valid. An empty prefix is refused rather than deleting
+// everything.
+func (s *EventStore) DeleteAggregatesWithPrefix(ctx context.Context, prefix string) (int64, error) {
+	if prefix == "" {
+		return 0, fmt.Errorf("aggregate ID prefix is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", classifyError(err))
+	}
+	defer tx.Rollback()
+
+	// A range over the binary collation finds the prefix with the
+	// aggregate_id indexes, and needs no LIKE escaping
+	upper := prefixUpperBound(prefix)
+	inRange := " WHERE aggregate_id >= ?"
+	args := []any{prefix}
+	if upper != "" {
+		inRange += " AND aggregate_id < ?"
+		args = append(args, upper)
+	}
+
+	// Events still waiting in the outbox (see WithOutbox) must not be
+	// published once deleted
+	hasOutbox, err := s.hasTable(ctx, tx, "event_outbox")
+	if err != nil {
+		return 0, err
+	}
+	if hasOutbox {
+		_, err := tx.ExecContext(ctx, s.tables.rewrite(
+			"DELETE FROM event_outbox WHERE event_id IN (SELECT event_id FROM events"+inRange+")"), args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete from event_outbox: %w", classifyError(err))
+		}
+	}
+
+	var deleted int64
+	for _, table := range []string{"events", "snapshots", "unique_constraints", "processed_commands"} {
+		result, err := tx.ExecContext(ctx, s.tables.rewrite("DELETE FROM "+table+inRange), args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete from %s: %w", table, classifyError(err))
+		}
+		if table == "events" {
+			if deleted, err = result.RowsAffected(); err != nil {
+				return 0, fmt.Errorf("failed to count deleted events: %w", classifyError(err))
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit deletion: %w", classifyError(err))
+	}
+	return deleted, nil
+}
+
+// hasTable reports whether the store's table (unprefixed name) exists.
+func (s *EventStore) hasTable(ctx context.Context, tx *sql.Tx, table string) (bool, error) {
+	var n int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`,
+		string(s.tables)+table).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %w", table, classifyError(err))
+	}
+	return n > 0, nil
+}
+
+// prefixUpperBound returns the smallest string greater than every string
+// starting with prefix, or "" if there is none (prefix is all 0xff bytes).
+func prefixUpperBound(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
+
 // DB returns the underlying database connection for direct SQL queries (e.g., projections).
 func (s *EventStore) DB() *sql.DB {
 	return s.db
 }
 
-// Close closes the event store and releases resources.
+// TablePrefix returns the prefix of the store's table names (see WithTablePrefix).
+func (s *EventStore) TablePrefix() string {
+	return string(s.tables)
+}
+
+// Close closes the event store and releases resources. A pool provided with
+// WithDB stays open.
 func (s *EventStore) Close() error {
+	if s.stopWALCheckpoints != nil {
+		close(s.stopWALCheckpoints)
+		<-s.walCheckpointsDone
+		s.stopWALCheckpoints = nil
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.closeDB()
+}
+
+// closeDB closes the pool unless it was provided with WithDB.
+func (s *EventStore) closeDB() error {
+	if !s.ownsDB {
+		return nil
+	}
 	return s.db.Close()
 }
@@ -1,8 +1,16 @@
 package sqlite_test
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -10,6 +18,9 @@ import (
 	"github.com/plaenen/eventstore/pkg/eventsourcing"
 	storelib "github.com/plaenen/eventstore/pkg/store"
 	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	sqlitedriver "modernc.org/sqlite"
 )
 
 func TestEventStore(t *testing.T) {
@@ -41,12 +52,12 @@ func TestEventStore(t *testing.T) {
 			},
 		}
 
-		err := store.AppendEvents(aggregateID, 0, events)
+		err := store.AppendEvents(context.Background(), aggregateID, 0, events)
 		if err != nil {
 			t.Fatalf("failed to append events: %v", err)
 		}
 
-		loaded, err := store.LoadEvents(aggregateID, 0)
+		loaded, err := store.LoadEvents(context.Background(), aggregateID, 0)
 		if err != nil {
 			t.Fatalf("failed to load events: %v", err)
 		}
@@ -65,7 +76,7 @@ func TestEventStore(t *testing.T) {
 		aggregateID := "test-aggregate-2"
 
 		// First event
-		err := store.AppendEvents(aggregateID, 0, []*domain.Event{
+		err := store.AppendEvents(context.Background(), aggregateID, 0, []*domain.Event{
 			{
 				ID:            "event-2",
 				AggregateID:   aggregateID,
@@ -82,7 +93,7 @@ func TestEventStore(t *testing.T) {
 		}
 
 		// Try to append with wrong expected version
-		err = store.AppendEvents(aggregateID, 0, []*domain.Event{
+		err = store.AppendEvents(context.Background(), aggregateID, 0, []*domain.Event{
 			{
 				ID:            "event-3",
 				AggregateID:   aggregateID,
@@ -106,7 +117,7 @@ func TestEventStore(t *testing.T) {
 		aggregateID2 := "test-aggregate-4"
 
 		// Claim unique email
-		err := store.AppendEvents(aggregateID1, 0, []*domain.Event{
+		err := store.AppendEvents(context.Background(), aggregateID1, 0, []*domain.Event{
 			{
 				ID:            "event-4",
 				AggregateID:   aggregateID1,
@@ -130,7 +141,7 @@ func TestEventStore(t *testing.T) {
 		}
 
 		// Try to claim same email with different aggregate
-		err = store.AppendEvents(aggregateID2, 0, []*domain.Event{
+		err = store.AppendEvents(context.Background(), aggregateID2, 0, []*domain.Event{
 			{
 				ID:            "event-5",
 				AggregateID:   aggregateID2,
@@ -150,8 +161,8 @@ func TestEventStore(t *testing.T) {
 			},
 		})
 
-		if err == nil {
-			t.Error("expected unique constraint violation")
+		if !errors.Is(err, domain.ErrUniqueConstraintViolation) {
+			t.Errorf("expected unique constraint violation, got %v", err)
 		}
 
 		// Check uniqueness
@@ -188,7 +199,7 @@ func TestEventStore(t *testing.T) {
 		}
 
 		// First append
-		result1, err := store.AppendEventsIdempotent(aggregateID, 0, events, commandID, 24*time.Hour)
+		result1, err := store.AppendEventsIdempotent(context.Background(), aggregateID, 0, events, commandID, 24*time.Hour)
 		if err != nil {
 			t.Fatalf("failed first append: %v", err)
 		}
@@ -197,7 +208,7 @@ func TestEventStore(t *testing.T) {
 		}
 
 		// Second append with same command ID
-		result2, err := store.AppendEventsIdempotent(aggregateID, 0, events, commandID, 24*time.Hour)
+		result2, err := store.AppendEventsIdempotent(context.Background(), aggregateID, 0, events, commandID, 24*time.Hour)
 		if err != nil {
 			t.Fatalf("failed second append: %v", err)
 		}
@@ -206,7 +217,7 @@ func TestEventStore(t *testing.T) {
 		}
 
 		// Verify only one event was persisted
-		loaded, err := store.LoadEvents(aggregateID, 0)
+		loaded, err := store.LoadEvents(context.Background(), aggregateID, 0)
 		if err != nil {
 			t.Fatalf("failed to load events: %v", err)
 		}
@@ -287,6 +298,123 @@ func TestEventStore(t *testing.T) {
 	})
 }
 
+func TestLoadAllEventsOrderedBy(t *testing.T) {
+	store, err := sqlite.NewEventStore(
+		sqlite.WithDSN(":memory:"),
+		sqlite.WithWALMode(false),
+	)
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	// Append events whose business timestamps disagree with append order.
+	appends := []struct {
+		id        string
+		aggregate string
+		version   int64
+		timestamp int64
+	}{
+		{"evt-a1", "agg-a", 1, 300},
+		{"evt-b1", "agg-b", 1, 100},
+		{"evt-c1", "agg-c", 1, 200},
+		{"evt-d1", "agg-d", 1, 100},
+	}
+	for _, a := range appends {
+		err := store.AppendEvents(context.Background(), a.aggregate, a.version-1, []*domain.Event{{
+			ID:            a.id,
+			AggregateID:   a.aggregate,
+			AggregateType: "TestAggregate",
+			EventType:     "test.Happened",
+			Version:       a.version,
+			Timestamp:     time.Unix(a.timestamp, 0),
+			Data:          []byte("data"),
+		}})
+		if err != nil {
+			t.Fatalf("failed to append %s: %v", a.id, err)
+		}
+	}
+
+	ids := func(events []*domain.Event) []string {
+		out := make([]string, len(events))
+		for i, e := range events {
+			out[i] = e.ID
+		}
+		return out
+	}
+
+	tests := []struct {
+		name  string
+		field sqlite.OrderField
+		from  int64
+		want  []string
+	}{
+		{"Timestamp", sqlite.OrderByTimestamp, 0, []string{"evt-b1", "evt-d1", "evt-c1", "evt-a1"}},
+		{"TimestampFrom", sqlite.OrderByTimestamp, 200, []string{"evt-c1", "evt-a1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := store.LoadAllEventsOrderedBy(ctx, tt.field, tt.from, 100)
+			if err != nil {
+				t.Fatalf("failed to load events: %v", err)
+			}
+			got := ids(events)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+
+	t.Run("Position", func(t *testing.T) {
+		all, err := store.LoadAllEvents(context.Background(), 0, 100)
+		if err != nil {
+			t.Fatalf("failed to load all events: %v", err)
+		}
+		events, err := store.LoadAllEventsOrderedBy(ctx, sqlite.OrderByPosition, 0, 100)
+		if err != nil {
+			t.Fatalf("failed to load events: %v", err)
+		}
+		if got, want := fmt.Sprint(ids(events)), fmt.Sprint(ids(all)); got != want {
+			t.Fatalf("expected the LoadAllEvents order %s, got %s", want, got)
+		}
+		for i := 1; i < len(events); i++ {
+			if events[i].Position < events[i-1].Position {
+				t.Errorf("expected ascending positions, got %d after %d", events[i].Position, events[i-1].Position)
+			}
+		}
+
+		// from is inclusive, and limit caps the page
+		from := events[len(events)-1].Position
+		page, err := store.LoadAllEventsOrderedBy(ctx, sqlite.OrderByPosition, from, 1)
+		if err != nil {
+			t.Fatalf("failed to load page: %v", err)
+		}
+		if len(page) != 1 || page[0].Position != from {
+			t.Errorf("expected one event at position %d, got %v", from, ids(page))
+		}
+	})
+
+	t.Run("UnsupportedField", func(t *testing.T) {
+		if _, err := store.LoadAllEventsOrderedBy(ctx, sqlite.OrderField(99), 0, 10); err == nil {
+			t.Fatal("expected error for unsupported order field")
+		}
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := store.LoadAllEventsOrderedBy(cancelled, sqlite.OrderByPosition, 0, 10); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
 func TestMain(m *testing.M) {
 	// Override time function for deterministic testing
 	eventsourcing.TimeFunc = func() time.Time {
@@ -300,3 +428,852 @@ func TestMain(m *testing.M) {
 
 	os.Exit(code)
 }
+
+func TestVerifyIntegrity(t *testing.T) {
+	t.Run("HealthyDatabase", func(t *testing.T) {
+		store, err := sqlite.NewEventStore(
+			sqlite.WithDSN(":memory:"),
+			sqlite.WithWALMode(false),
+			sqlite.WithStartupIntegrityCheck(),
+		)
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer store.Close()
+
+		report, err := store.VerifyIntegrity(context.Background())
+		if err != nil {
+			t.Fatalf("failed to verify integrity: %v", err)
+		}
+		if !report.OK() {
+			t.Errorf("expected healthy database, got %s", report)
+		}
+	})
+
+	t.Run("ForeignKeyViolation", func(t *testing.T) {
+		store, err := sqlite.NewEventStore(
+			sqlite.WithDSN(":memory:"),
+			sqlite.WithWALMode(false),
+		)
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer store.Close()
+
+		_, err = store.DB().Exec(`
+			CREATE TABLE parents (id INTEGER PRIMARY KEY);
+			CREATE TABLE children (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parents(id));
+			INSERT INTO children (id, parent_id) VALUES (7, 42);
+		`)
+		if err != nil {
+			t.Fatalf("failed to set up tables: %v", err)
+		}
+
+		report, err := store.VerifyIntegrity(context.Background())
+		if err != nil {
+			t.Fatalf("failed to verify integrity: %v", err)
+		}
+		if len(report.ForeignKeyViolations) != 1 {
+			t.Fatalf("expected 1 foreign key violation, got %+v", report.ForeignKeyViolations)
+		}
+		v := report.ForeignKeyViolations[0]
+		if v.Table != "children" || v.RowID != 7 || v.Parent != "parents" {
+			t.Errorf("unexpected violation: %+v", v)
+		}
+	})
+
+	t.Run("CorruptFileFailsAtStartup", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.db")
+		store, err := sqlite.NewEventStore(sqlite.WithDSN(path), sqlite.WithWALMode(false))
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		for i := 0; i < 200; i++ {
+			aggregateID := fmt.Sprintf("agg-%d", i)
+			err := store.AppendEvents(context.Background(), aggregateID, 0, []*domain.Event{{
+				ID:            fmt.Sprintf("evt-%d", i),
+				AggregateID:   aggregateID,
+				AggregateType: "TestAggregate",
+				EventType:     "test.Happened",
+				Version:       1,
+				Timestamp:     time.Now(),
+				Data:          []byte(strings.Repeat("x", 200)),
+			}})
+			if err != nil {
+				t.Fatalf("failed to append event: %v", err)
+			}
+		}
+		store.Close()
+
+		// Scribble over a page in the middle of the file, leaving the header intact
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read database file: %v", err)
+		}
+		for i := len(data) / 2; i < len(data)/2+4096 && i < len(data); i++ {
+			data[i] = 0xff
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("failed to write database file: %v", err)
+		}
+
+		_, err = sqlite.NewEventStore(
+			sqlite.WithDSN(path),
+			sqlite.WithWALMode(false),
+			sqlite.WithStartupIntegrityCheck(),
+		)
+		if !errors.Is(err, sqlite.ErrIntegrityCheckFailed) {
+			t.Fatalf("expected ErrIntegrityCheckFailed, got %v", err)
+		}
+	})
+}
+
+func TestWALCheckpointing(t *testing.T) {
+	appendHeavily := func(t *testing.T, store *sqlite.EventStore, prefix string, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			aggregateID := fmt.Sprintf("%s-%d", prefix, i)
+			err := store.AppendEvents(context.Background(), aggregateID, 0, []*domain.Event{{
+				ID:            aggregateID,
+				AggregateID:   aggregateID,
+				AggregateType: "TestAggregate",
+				EventType:     "test.Happened",
+				Version:       1,
+				Timestamp:     time.Now(),
+				Data:          []byte(strings.Repeat("x", 2048)),
+			}})
+			if err != nil {
+				t.Fatalf("failed to append event: %v", err)
+			}
+		}
+	}
+
+	t.Run("CheckpointWALTruncates", func(t *testing.T) {
+		store, err := sqlite.NewEventStore(sqlite.WithDSN(filepath.Join(t.TempDir(), "events.db")))
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer store.Close()
+
+		appendHeavily(t, store, "agg", 500)
+		stats, err := store.Stats()
+		if err != nil {
+			t.Fatalf("failed to get stats: %v", err)
+		}
+		if stats.WALSizeBytes == 0 {
+			t.Fatal("expected a non-empty WAL after writing")
+		}
+
+		result, err := store.CheckpointWAL()
+		if err != nil {
+			t.Fatalf("failed to checkpoint WAL: %v", err)
+		}
+		if result.Busy {
+			t.Fatal("checkpoint unexpectedly busy")
+		}
+		stats, _ = store.Stats()
+		if stats.WALSizeBytes != 0 {
+			t.Errorf("expected truncated WAL, got %d bytes", stats.WALSizeBytes)
+		}
+		if stats.DatabaseSizeBytes < 500*2048 {
+			t.Errorf("expected database to hold the checkpointed data, got %d bytes", stats.DatabaseSizeBytes)
+		}
+	})
+
+	t.Run("WALStaysBoundedUnderHeavyWrites", func(t *testing.T) {
+		store, err := sqlite.NewEventStore(
+			sqlite.WithDSN(filepath.Join(t.TempDir(), "events.db")),
+			sqlite.WithWALAutoCheckpoint(64),
+			sqlite.WithWALCheckpointInterval(20*time.Millisecond),
+		)
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer store.Close()
+
+		// ~2.4MB of payloads, more than twice what the WAL is allowed to hold
+		var maxWAL int64
+		for batch := 0; batch < 4; batch++ {
+			appendHeavily(t, store, fmt.Sprintf("batch-%d", batch), 300)
+			stats, err := store.Stats()
+			if err != nil {
+				t.Fatalf("failed to get stats: %v", err)
+			}
+			if stats.WALSizeBytes > maxWAL {
+				maxWAL = stats.WALSizeBytes
+			}
+		}
+
+		const limit = 1 << 20
+		if maxWAL > limit {
+			t.Errorf("expected WAL to stay below %d bytes, peaked at %d", limit, maxWAL)
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			stats, _ := store.Stats()
+			if stats.WALSizeBytes == 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected periodic checkpoint to truncate the WAL, still %d bytes", stats.WALSizeBytes)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+func TestErrorClassification(t *testing.T) {
+	newEvent := func(id string, version int64) *domain.Event {
+		return &domain.Event{
+			ID:            id,
+			AggregateID:   "agg-1",
+			AggregateType: "TestAggregate",
+			EventType:     "test.Happened",
+			Version:       version,
+			Timestamp:     time.Now(),
+			Data:          []byte("data"),
+		}
+	}
+
+	t.Run("DuplicateVersionIsConcurrencyConflict", func(t *testing.T) {
+		store, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		defer store.Close()
+
+		// Both events claim version 1, so the version check passes but the
+		// UNIQUE(aggregate_id, version) index rejects the second insert
+		err = store.AppendEvents(context.Background(), "agg-1", 0, []*domain.Event{newEvent("evt-1", 1), newEvent("evt-2", 1)})
+		if !errors.Is(err, storelib.ErrConcurrencyConflict) {
+			t.Fatalf("expected concurrency conflict, got %v", err)
+		}
+		if !storelib.IsRetryable(err) {
+			t.Error("expected concurrency conflict to be retryable")
+		}
+
+		var storeErr *storelib.Error
+		if !errors.As(err, &storeErr) {
+			t.Fatalf("expected *store.Error, got %T", err)
+		}
+		if storeErr.Unwrap() == nil {
+			t.Error("expected the driver error to be preserved")
+		}
+	})
+
+	t.Run("LockedDatabaseIsBusy", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.db")
+		store, err := sqlite.NewEventStore(sqlite.WithDSN(path))
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		defer store.Close()
+
+		other, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatalf("failed to open second connection: %v", err)
+		}
+		defer other.Close()
+
+		conn, err := other.Conn(context.Background())
+		if err != nil {
+			t.Fatalf("failed to get connection: %v", err)
+		}
+		defer conn.Close()
+		if _, err := conn.ExecContext(context.Background(), "BEGIN IMMEDIATE"); err != nil {
+			t.Fatalf("failed to lock database: %v", err)
+		}
+		defer conn.ExecContext(context.Background(), "ROLLBACK")
+
+		err = store.AppendEvents(context.Background(), "agg-1", 0, []*domain.Event{newEvent("evt-1", 1)})
+		if !errors.Is(err, storelib.ErrBusy) {
+			t.Fatalf("expected busy error, got %v", err)
+		}
+		if !storelib.IsRetryable(err) {
+			t.Error("expected busy error to be retryable")
+		}
+	})
+
+	t.Run("NotADatabaseIsCorrupt", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "events.db")
+		if err := os.WriteFile(path, []byte(strings.Repeat("not a database ", 512)), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		_, err := sqlite.NewEventStore(sqlite.WithDSN(path))
+		if !errors.Is(err, storelib.ErrCorrupt) {
+			t.Fatalf("expected corrupt error, got %v", err)
+		}
+		if storelib.IsRetryable(err) {
+			t.Error("expected corrupt error not to be retryable")
+		}
+	})
+}
+
+func TestEventValidation(t *testing.T) {
+	registry := domain.EventRegistry{
+		"test.Named": {
+			AggregateType: "TestAggregate",
+			New:           func() proto.Message { return &wrapperspb.StringValue{} },
+		},
+	}
+	valid, err := proto.Marshal(wrapperspb.String("alice"))
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	newEvent := func(id, eventType string, version int64, data []byte) *domain.Event {
+		return &domain.Event{
+			ID:            id,
+			AggregateID:   "agg-1",
+			AggregateType: "TestAggregate",
+			EventType:     eventType,
+			Version:       version,
+			Timestamp:     time.Now(),
+			Data:          data,
+		}
+	}
+
+	store, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithEventValidation(registry))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.AppendEvents(context.Background(), "agg-1", 0, []*domain.Event{newEvent("evt-1", "test.Named", 1, valid)}); err != nil {
+		t.Fatalf("failed to append valid event: %v", err)
+	}
+
+	rejected := map[string]*domain.Event{
+		"MalformedData":    newEvent("evt-2", "test.Named", 2, []byte{0xff, 0xff, 0xff}),
+		"UnknownEventType": newEvent("evt-3", "test.Unknown", 2, valid),
+	}
+	for name, event := range rejected {
+		t.Run(name, func(t *testing.T) {
+			err := store.AppendEvents(context.Background(), "agg-1", 1, []*domain.Event{event})
+			var responseErr *eventsourcing.ResponseError
+			if !errors.As(err, &responseErr) || responseErr.Code() != sqlite.InvalidEventPayloadCode {
+				t.Fatalf("expected %s, got %v", sqlite.InvalidEventPayloadCode, err)
+			}
+
+			_, err = store.AppendEventsIdempotent(context.Background(), "agg-1", 1, []*domain.Event{event}, "cmd-"+event.ID, time.Hour)
+			if !errors.As(err, &responseErr) {
+				t.Fatalf("expected idempotent append to be rejected, got %v", err)
+			}
+		})
+	}
+
+	version, err := store.GetAggregateVersion("agg-1")
+	if err != nil {
+		t.Fatalf("failed to get version: %v", err)
+	}
+	if version != 1 {
+		t.Errorf("expected rejected events not to be stored, version is %d", version)
+	}
+
+	t.Run("BypassedForBulkImport", func(t *testing.T) {
+		ctx := sqlite.WithoutEventValidation(context.Background())
+		if err := store.AppendEvents(ctx, "agg-1", 1, []*domain.Event{rejected["UnknownEventType"]}); err != nil {
+			t.Fatalf("expected the append to skip validation, got %v", err)
+		}
+	})
+}
+
+func TestTablePrefix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.db")
+
+	type subsystem struct {
+		events      *sqlite.EventStore
+		snapshots   *sqlite.SnapshotStore
+		checkpoints *sqlite.CheckpointStore
+	}
+	open := func(prefix string) subsystem {
+		t.Helper()
+		events, err := sqlite.NewEventStore(sqlite.WithDSN(path), sqlite.WithTablePrefix(prefix))
+		if err != nil {
+			t.Fatalf("failed to create %s event store: %v", prefix, err)
+		}
+		t.Cleanup(func() { events.Close() })
+		checkpoints, err := sqlite.NewCheckpointStore(events.DB(), sqlite.WithCheckpointTablePrefix(events.TablePrefix()))
+		if err != nil {
+			t.Fatalf("failed to create %s checkpoint store: %v", prefix, err)
+		}
+		return subsystem{
+			events:      events,
+			snapshots:   sqlite.NewSnapshotStore(events.DB(), sqlite.WithSnapshotTablePrefix(events.TablePrefix())),
+			checkpoints: checkpoints,
+		}
+	}
+	billing := open("billing_")
+	shipping := open("shipping_")
+
+	for name, sub := range map[string]subsystem{"billing": billing, "shipping": shipping} {
+		err := sub.events.AppendEvents(context.Background(), "order-1", 0, []*domain.Event{{
+			ID:            name + "-1",
+			AggregateID:   "order-1",
+			AggregateType: "Order",
+			EventType:     "order.Placed",
+			Version:       1,
+			Timestamp:     time.Now(),
+			Data:          []byte(name),
+		}})
+		if err != nil {
+			t.Fatalf("%s: failed to append: %v", name, err)
+		}
+		if err := sub.snapshots.SaveSnapshot(&storelib.Snapshot{
+			AggregateID:   "order-1",
+			AggregateType: "Order",
+			Version:       1,
+			Data:          []byte(name),
+			CreatedAt:     time.Now(),
+		}); err != nil {
+			t.Fatalf("%s: failed to save snapshot: %v", name, err)
+		}
+		if err := sub.checkpoints.Save(&storelib.ProjectionCheckpoint{
+			ProjectionName: "orders",
+			Position:       int64(len(name)),
+			LastEventID:    name + "-1",
+			UpdatedAt:      time.Now(),
+		}); err != nil {
+			t.Fatalf("%s: failed to save checkpoint: %v", name, err)
+		}
+	}
+
+	for name, sub := range map[string]subsystem{"billing": billing, "shipping": shipping} {
+		events, err := sub.events.LoadEvents(context.Background(), "order-1", 0)
+		if err != nil || len(events) != 1 || string(events[0].Data) != name {
+			t.Errorf("%s: expected only its own event, got %v (err %v)", name, events, err)
+		}
+		snapshot, err := sub.snapshots.GetLatestSnapshot("order-1")
+		if err != nil || string(snapshot.Data) != name {
+			t.Errorf("%s: expected its own snapshot, got %v (err %v)", name, snapshot, err)
+		}
+		checkpoint, err := sub.checkpoints.Load("orders")
+		if err != nil || checkpoint.LastEventID != name+"-1" {
+			t.Errorf("%s: expected its own checkpoint, got %v (err %v)", name, checkpoint, err)
+		}
+	}
+
+	rows, err := billing.events.DB().Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		t.Fatalf("failed to list tables: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			t.Fatalf("failed to scan table name: %v", err)
+		}
+		if !strings.HasPrefix(table, "billing_") && !strings.HasPrefix(table, "shipping_") {
+			t.Errorf("table %s was created without a prefix", table)
+		}
+	}
+
+	if _, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithTablePrefix("bad-prefix")); err == nil {
+		t.Error("expected an invalid prefix to be rejected")
+	}
+}
+
+func TestMetadataColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+
+	// Build a database at schema version 4, before metadata had its own
+	// columns, holding one event
+	legacy, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	files, err := filepath.Glob("migrations/00000[1-4]_*.up.sql")
+	if err != nil || len(files) != 4 {
+		t.Fatalf("expected 4 legacy migrations, got %v (err %v)", files, err)
+	}
+	for _, file := range files {
+		script, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if _, err := legacy.Exec(string(script)); err != nil {
+			t.Fatalf("failed to apply %s: %v", file, err)
+		}
+	}
+	_, err = legacy.Exec(`
+		CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at INTEGER NOT NULL);
+		INSERT INTO schema_migrations VALUES (1, 'initial_schema', 0), (2, 'add_snapshots', 0),
+			(3, 'add_timestamp_index', 0), (4, 'add_causation_index', 0);
+		INSERT INTO events (event_id, aggregate_id, aggregate_type, event_type, version, timestamp, data, metadata, position)
+		VALUES ('legacy-1', 'order-1', 'Order', 'order.Placed', 1, 0, x'00',
+			'{"CausationID":"cmd-1","CorrelationID":"corr-1","PrincipalID":"alice","TenantID":"acme","Custom":null}', 1);`)
+	if err != nil {
+		t.Fatalf("failed to seed legacy database: %v", err)
+	}
+	legacy.Close()
+
+	store, err := sqlite.NewEventStore(sqlite.WithDSN(path))
+	if err != nil {
+		t.Fatalf("failed to migrate legacy database: %v", err)
+	}
+	defer store.Close()
+
+	err = store.AppendEvents(context.Background(), "order-2", 0, []*domain.Event{{
+		ID:            "new-1",
+		AggregateID:   "order-2",
+		AggregateType: "Order",
+		EventType:     "order.Placed",
+		Version:       1,
+		Timestamp:     time.Now(),
+		Data:          []byte{0},
+		Metadata:      domain.EventMetadata{CausationID: "cmd-2", CorrelationID: "corr-1"},
+	}})
+	if err != nil {
+		t.Fatalf("failed to append event: %v", err)
+	}
+
+	t.Run("BackfilledAndAppended", func(t *testing.T) {
+		events, err := store.LoadEventsByCorrelationID(context.Background(), "corr-1")
+		if err != nil {
+			t.Fatalf("failed to load by correlation: %v", err)
+		}
+		if len(events) != 2 || events[0].ID != "legacy-1" || events[1].ID != "new-1" {
+			t.Fatalf("expected legacy-1 and new-1, got %v", events)
+		}
+		if events[0].Metadata.PrincipalID != "alice" {
+			t.Errorf("expected JSON metadata to be kept, got %+v", events[0].Metadata)
+		}
+
+		var principal, tenant string
+		err = store.DB().QueryRow("SELECT principal_id, tenant_id FROM events WHERE event_id = 'legacy-1'").Scan(&principal, &tenant)
+		if err != nil || principal != "alice" || tenant != "acme" {
+			t.Errorf("expected backfilled principal and tenant, got %q %q (err %v)", principal, tenant, err)
+		}
+
+		caused, err := store.LoadEventsByCausationID(context.Background(), "cmd-2")
+		if err != nil || len(caused) != 1 || caused[0].ID != "new-1" {
+			t.Errorf("expected new-1 caused by cmd-2, got %v (err %v)", caused, err)
+		}
+	})
+
+	t.Run("LookupsUseIndexes", func(t *testing.T) {
+		for column, index := range map[string]string{
+			"correlation_id": "idx_events_correlation",
+			"causation_id":   "idx_events_causation",
+		} {
+			rows, err := store.DB().Query("EXPLAIN QUERY PLAN SELECT event_id FROM events WHERE "+column+" = ? ORDER BY position", "x")
+			if err != nil {
+				t.Fatalf("failed to explain query: %v", err)
+			}
+			var plan strings.Builder
+			for rows.Next() {
+				var id, parent, notUsed int
+				var detail string
+				rows.Scan(&id, &parent, &notUsed, &detail)
+				plan.WriteString(detail + "\n")
+			}
+			rows.Close()
+			if !strings.Contains(plan.String(), index) {
+				t.Errorf("expected lookup by %s to use %s, plan:\n%s", column, index, plan.String())
+			}
+		}
+	})
+}
+
+func TestMonotonicTimestamps(t *testing.T) {
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	event := func(aggregateID string, version int64, timestamp time.Time) *domain.Event {
+		return &domain.Event{
+			ID:            fmt.Sprintf("%s-%d", aggregateID, version),
+			AggregateID:   aggregateID,
+			AggregateType: "Order",
+			EventType:     "order.Updated",
+			Version:       version,
+			Timestamp:     timestamp,
+			Data:          []byte{0},
+		}
+	}
+
+	t.Run("ClampsSkewedAppends", func(t *testing.T) {
+		store, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithMonotonicTimestamps())
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer store.Close()
+
+		if err := store.AppendEvents(context.Background(), "order-1", 0, []*domain.Event{event("order-1", 1, base)}); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+		// A failed append leaves the caller's events as they were
+		duplicate := event("order-1", 3, base.Add(time.Minute))
+		duplicate.ID = "order-1-1"
+		failed := []*domain.Event{event("order-1", 2, base.Add(-time.Hour)), duplicate}
+		if err := store.AppendEvents(context.Background(), "order-1", 1, failed); err == nil {
+			t.Fatal("expected appending a duplicate event ID to fail")
+		}
+		if !failed[0].Timestamp.Equal(base.Add(-time.Hour)) || failed[0].Metadata.Custom != nil {
+			t.Errorf("expected the failed event to be left alone, got %v %v", failed[0].Timestamp, failed[0].Metadata.Custom)
+		}
+
+		// A writer with a slow clock appends two events in the past
+		skewed := []*domain.Event{event("order-1", 2, base.Add(-time.Hour)), event("order-1", 3, base.Add(time.Minute))}
+		if err := store.AppendEvents(context.Background(), "order-1", 1, skewed); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+		if !skewed[0].Timestamp.Equal(base.Add(-time.Hour)) || skewed[0].Position != 2 {
+			t.Errorf("expected the caller's event to keep its timestamp and get its position, got %v at %d", skewed[0].Timestamp, skewed[0].Position)
+		}
+
+		events, err := store.LoadEvents(context.Background(), "order-1", 0)
+		if err != nil {
+			t.Fatalf("failed to load: %v", err)
+		}
+		want := []time.Time{base, base, base.Add(time.Minute)}
+		for i, e := range events {
+			if !e.Timestamp.Equal(want[i]) {
+				t.Errorf("event %d: timestamp %v, want %v", i+1, e.Timestamp, want[i])
+			}
+		}
+		if original, clamped := domain.ClampedTimestamp(events[1].Metadata); !clamped || !original.Equal(base.Add(-time.Hour)) {
+			t.Errorf("expected event 2 to record its original timestamp, got %v %v", original, clamped)
+		}
+		for _, i := range []int{0, 2} {
+			if _, clamped := domain.ClampedTimestamp(events[i].Metadata); clamped {
+				t.Errorf("event %d should not be marked clamped", i+1)
+			}
+		}
+	})
+
+	t.Run("DisabledByDefault", func(t *testing.T) {
+		store, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer store.Close()
+
+		store.AppendEvents(context.Background(), "order-1", 0, []*domain.Event{event("order-1", 1, base)})
+		store.AppendEvents(context.Background(), "order-1", 1, []*domain.Event{event("order-1", 2, base.Add(-time.Hour))})
+		events, _ := store.LoadEvents(context.Background(), "order-1", 0)
+		if len(events) != 2 || !events[1].Timestamp.Equal(base.Add(-time.Hour)) {
+			t.Errorf("expected the timestamp to be stored as written, got %v", events)
+		}
+	})
+
+	t.Run("ImportKeepsTimestampsMonotonic", func(t *testing.T) {
+		store, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create event store: %v", err)
+		}
+		defer store.Close()
+
+		imported := []*domain.Event{
+			event("order-1", 1, base.Add(-48*time.Hour)),
+			event("order-1", 2, base.Add(-72*time.Hour)),
+			event("order-1", 3, base.Add(-24*time.Hour)),
+		}
+		if err := store.ImportEvents("order-1", 0, imported); err != nil {
+			t.Fatalf("failed to import: %v", err)
+		}
+		events, _ := store.LoadEvents(context.Background(), "order-1", 0)
+		want := []time.Time{base.Add(-48 * time.Hour), base.Add(-48 * time.Hour), base.Add(-24 * time.Hour)}
+		for i, e := range events {
+			if !e.Timestamp.Equal(want[i]) {
+				t.Errorf("event %d: timestamp %v, want %v", i+1, e.Timestamp, want[i])
+			}
+		}
+
+		err = store.ImportEvents("order-2", 0, []*domain.Event{event("order-2", 1, time.Time{})})
+		if !errors.Is(err, sqlite.ErrMissingTimestamp) {
+			t.Errorf("expected ErrMissingTimestamp, got %v", err)
+		}
+	})
+}
+
+func TestWithDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	store, err := sqlite.NewEventStore(sqlite.WithDB(db))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	if store.DB() != db {
+		t.Fatal("expected the store to use the provided pool")
+	}
+
+	err = store.AppendEvents(context.Background(), "acc-1", 0, []*domain.Event{{
+		ID:            "evt-1",
+		AggregateID:   "acc-1",
+		AggregateType: "Account",
+		EventType:     "account.Opened",
+		Version:       1,
+		Timestamp:     time.Now(),
+		Data:          []byte("{}"),
+	}})
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatalf("failed to read journal mode: %v", err)
+	}
+	if mode != "wal" {
+		t.Errorf("expected WAL mode to be set up on the provided pool, got %q", mode)
+	}
+
+	stats, err := store.Stats()
+	if err != nil {
+		t.Fatalf("failed to read stats: %v", err)
+	}
+	if stats.WALSizeBytes == 0 {
+		t.Error("expected the database file to be resolved from the provided pool")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("failed to close store: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Errorf("expected the provided pool to stay open after Close: %v", err)
+	}
+
+	hook := func(conn *sql.Conn) error { return nil }
+	if _, err := sqlite.NewEventStore(sqlite.WithDB(db), sqlite.WithConnectHook(hook)); err == nil {
+		t.Error("expected WithConnectHook to be rejected together with WithDB")
+	}
+}
+
+func TestConnectHook(t *testing.T) {
+	var calls atomic.Int32
+	store, err := sqlite.NewEventStore(
+		sqlite.WithDSN(filepath.Join(t.TempDir(), "hooks.db")),
+		sqlite.WithConnectHook(func(conn *sql.Conn) error {
+			calls.Add(1)
+			_, err := conn.ExecContext(context.Background(), "PRAGMA cache_size = -1234")
+			return err
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer store.Close()
+
+	// Pin several connections at once so the pool has to open new ones
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := store.DB().Conn(ctx)
+		if err != nil {
+			t.Fatalf("failed to get connection: %v", err)
+		}
+		conns = append(conns, conn)
+	}
+	for i, conn := range conns {
+		var cacheSize int
+		if err := conn.QueryRowContext(ctx, "PRAGMA cache_size").Scan(&cacheSize); err != nil {
+			t.Fatalf("failed to read cache size: %v", err)
+		}
+		if cacheSize != -1234 {
+			t.Errorf("connection %d: expected the hook's cache_size, got %d", i, cacheSize)
+		}
+		conn.Close()
+	}
+	if calls.Load() < 3 {
+		t.Errorf("expected the hook to run once per connection, ran %d times", calls.Load())
+	}
+
+	failing, err := sqlite.NewEventStore(
+		sqlite.WithDSN(filepath.Join(t.TempDir(), "failing.db")),
+		sqlite.WithConnectHook(func(conn *sql.Conn) error { return errors.New("boom") }),
+	)
+	if err == nil {
+		failing.Close()
+		t.Fatal("expected a failing hook to fail opening the store")
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	newEvent := func(id string, version int64) *domain.Event {
+		return &domain.Event{
+			ID:            id,
+			AggregateID:   "agg-1",
+			AggregateType: "TestAggregate",
+			EventType:     "test.Happened",
+			Version:       version,
+			Timestamp:     time.Now(),
+			Data:          []byte("data"),
+		}
+	}
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		store, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		defer store.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = store.AppendEvents(ctx, "agg-1", 0, []*domain.Event{newEvent("evt-1", 1)})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected AppendEvents to return context.Canceled, got %v", err)
+		}
+		_, err = store.AppendEventsIdempotent(ctx, "agg-1", 0, []*domain.Event{newEvent("evt-1", 1)}, "cmd-1", time.Hour)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected AppendEventsIdempotent to return context.Canceled, got %v", err)
+		}
+		if _, err := store.LoadEvents(ctx, "agg-1", 0); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected LoadEvents to return context.Canceled, got %v", err)
+		}
+		if _, err := store.LoadAllEvents(ctx, 1, 10); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected LoadAllEvents to return context.Canceled, got %v", err)
+		}
+
+		if events, _ := store.LoadEvents(context.Background(), "agg-1", 0); len(events) != 0 {
+			t.Errorf("expected nothing appended, got %d events", len(events))
+		}
+	})
+
+	t.Run("CancelledMidAppend", func(t *testing.T) {
+		// A trigger cancels the append's context once its first event is
+		// inserted, while the second is still to come
+		registerCancelAppend.Do(func() {
+			sqlitedriver.MustRegisterScalarFunction("test_cancel_append", 0,
+				func(*sqlitedriver.FunctionContext, []driver.Value) (driver.Value, error) {
+					if cancel, ok := cancelAppend.Load().(context.CancelFunc); ok {
+						cancel()
+					}
+					return nil, nil
+				})
+		})
+
+		store, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		defer store.Close()
+		if _, err := store.DB().Exec(`CREATE TRIGGER cancel_append AFTER INSERT ON events
+			BEGIN SELECT test_cancel_append(); END`); err != nil {
+			t.Fatalf("failed to create trigger: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		cancelAppend.Store(cancel)
+		defer cancelAppend.Store(context.CancelFunc(func() {}))
+
+		err = store.AppendEvents(ctx, "agg-1", 0, []*domain.Event{newEvent("evt-1", 1), newEvent("evt-2", 2)})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if events, _ := store.LoadEvents(context.Background(), "agg-1", 0); len(events) != 0 {
+			t.Errorf("expected the cancelled append to be rolled back, got %d events", len(events))
+		}
+	})
+}
+
+var (
+	registerCancelAppend sync.Once
+	cancelAppend         atomic.Value // context.CancelFunc called by test_cancel_append()
+)
@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// maxGroupCommitBatch caps how many appends share one transaction; a full
+// batch is committed without waiting for the rest of the window.
+const maxGroupCommitBatch = 128
+
+// WithGroupCommitWindow enables group commit: concurrent AppendEvents calls
+// arriving within d of each other are written in a single transaction, paying
+// for one commit (and one global position update) instead of one per call.
+// Each call still waits for its own result, so the API is unchanged.
+//
+// Every append keeps its own optimistic concurrency and unique constraint
+// checks. An append that fails is rolled back on its own (using a savepoint)
+// and only its caller sees the error; the rest of the batch commits. If the
+// commit itself fails, every append in the batch fails.
+//
+// The window adds up to d of latency to each append, so it pays off under
+// concurrent load and costs latency for sequential writers. A value around
+// 1ms is a reasonable start. Only AppendEvents is batched;
+// AppendEventsIdempotent and ImportEvents commit on their own.
+//
+// An append whose context is cancelled while it waits fails with the
+// context's error at once. If its batch had not written it yet, it is left
+// out; once written, it commits with the batch, as any write whose caller gave
+// up may.
+func WithGroupCommitWindow(d time.Duration) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.groupCommitWindow = d
+	}
+}
+
+// appendRequest is one AppendEvents call waiting for its batch to commit.
+type appendRequest struct {
+	ctx             context.Context
+	aggregateID     string
+	expectedVersion int64
+	events          []*domain.Event
+
+	err  error
+	done chan struct{}
+}
+
+// groupCommitter collects concurrent appends into batches. The first append
+// of a batch leads it: it waits for the window (or a full batch) and commits
+// everyone's events, while later arrivals wait for their result.
+type groupCommitter struct {
+	store  *EventStore
+	window time.Duration
+
+	mu      sync.Mutex
+	pending []*appendRequest
+	full    chan struct{} // closed when pending reaches maxGroupCommitBatch
+}
+
+func newGroupCommitter(store *EventStore, window time.Duration) *groupCommitter {
+	return &groupCommitter{store: store, window: window}
+}
+
+// append queues the events and returns once their batch has committed.
+func (c *groupCommitter) append(ctx context.Context, aggregateID string, expectedVersion int64, events []*domain.Event) error {
+	req := &appendRequest{
+		ctx:             ctx,
+		aggregateID:     aggregateID,
+		expectedVersion: expectedVersion,
+		events:          events,
+		done:            make(chan struct{}),
+	}
+
+	c.mu.Lock()
+	c.pending = append(c.pending, req)
+	leader := len(c.pending) == 1
+	if leader {
+		c.full = make(chan struct{})
+	}
+	full := c.full
+	if len(c.pending) == maxGroupCommitBatch {
+		close(full)
+	}
+	c.mu.Unlock()
+
+	if leader {
+		timer := time.NewTimer(c.window)
+		select {
+		case <-timer.C:
+		case <-full:
+			timer.Stop()
+		}
+
+		c.commitPending()
+	}
+
+	select {
+	case <-req.done:
+		return req.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// commitPending writes the pending appends in one transaction and reports each
+// request's outcome. The batch is taken only once the store lock is held, so
+// appends arriving while the previous batch commits join this one.
+func (c *groupCommitter) commitPending() {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	c.mu.Lock()
+	batch := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+
+	err := c.store.writeBatch(batch)
+	for _, req := range batch {
+		if req.err == nil {
+			req.err = err
+		}
+		close(req.done)
+	}
+}
+
+// writeBatch appends each request within its own savepoint, so a failing
+// request is rolled back without affecting the others. It sets req.err for
+// failed requests and returns errors affecting the whole batch. The caller
+// holds s.mu.
+func (s *EventStore) writeBatch(batch []*appendRequest) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", classifyError(err))
+	}
+	defer tx.Rollback()
+
+	var appended []*domain.Event
+	for _, req := range batch {
+		if req.err = req.ctx.Err(); req.err != nil {
+			continue
+		}
+		if _, err := tx.Exec("SAVEPOINT group_append"); err != nil {
+			return fmt.Errorf("failed to create savepoint: %w", classifyError(err))
+		}
+
+		var kept []*domain.Event
+		kept, _, req.err = s.insertStream(context.Background(), tx, req.aggregateID, req.expectedVersion, req.events, s.monotonicTimestamps, true)
+		if req.err != nil {
+			if _, err := tx.Exec("ROLLBACK TO group_append"); err != nil {
+				return fmt.Errorf("failed to roll back to savepoint: %w", classifyError(err))
+			}
+		} else {
+			appended = append(appended, kept...)
+		}
+
+		if _, err := tx.Exec("RELEASE group_append"); err != nil {
+			return fmt.Errorf("failed to release savepoint: %w", classifyError(err))
+		}
+	}
+	if len(appended) == 0 {
+		return nil
+	}
+
+	// Update global position once for the whole batch
+	if err := s.updatePositions(context.Background(), tx); err != nil {
+		return fmt.Errorf("failed to update positions: %w", classifyError(err))
+	}
+	if err := s.loadPositions(context.Background(), tx, appended); err != nil {
+		return err
+	}
+	if err := s.enqueueOutbox(context.Background(), tx, appended); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return classifyError(err)
+	}
+	for _, req := range batch {
+		if req.err == nil {
+			clampedPositions(req.events, appended)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func groupCommitEvent(aggregateID string, version int64) *domain.Event {
+	return &domain.Event{
+		ID:            fmt.Sprintf("%s-v%d", aggregateID, version),
+		AggregateID:   aggregateID,
+		AggregateType: "Account",
+		EventType:     "account.v1.Deposited",
+		Version:       version,
+		Timestamp:     time.Now(),
+		Data:          []byte("payload"),
+	}
+}
+
+func TestGroupCommit(t *testing.T) {
+	eventStore, err := sqlite.NewEventStore(
+		sqlite.WithFilename(filepath.Join(t.TempDir(), "events.db")),
+		sqlite.WithGroupCommitWindow(5*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	t.Run("ConcurrentAppendsToDistinctAggregates", func(t *testing.T) {
+		const writers = 200
+
+		var wg sync.WaitGroup
+		errs := make([]error, writers)
+		for i := range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = eventStore.AppendEvents(context.Background(), fmt.Sprintf("acc-%d", i), 0, []*domain.Event{groupCommitEvent(fmt.Sprintf("acc-%d", i), 1)})
+			}()
+		}
+		wg.Wait()
+
+		for i, err := range errs {
+			if err != nil {
+				t.Errorf("append %d failed: %v", i, err)
+			}
+		}
+
+		events, err := eventStore.LoadAllEvents(context.Background(), 0, writers+1)
+		if err != nil {
+			t.Fatalf("failed to load events: %v", err)
+		}
+		if len(events) != writers {
+			t.Fatalf("expected %d events, got %d", writers, len(events))
+		}
+		for _, event := range events {
+			if event.Position == 0 {
+				t.Fatalf("event %s has no position", event.ID)
+			}
+		}
+	})
+
+	t.Run("ConflictsGoToTheirCaller", func(t *testing.T) {
+		// Ten writers race for version 1 of the same aggregate while others
+		// append elsewhere; exactly one of the racers may win
+		const racers = 10
+
+		var (
+			wg        sync.WaitGroup
+			wins      atomic.Int32
+			conflicts atomic.Int32
+		)
+		for i := range racers {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				event := groupCommitEvent("contested", 1)
+				event.ID = fmt.Sprintf("contested-racer-%d", i)
+				switch err := eventStore.AppendEvents(context.Background(), "contested", 0, []*domain.Event{event}); {
+				case err == nil:
+					wins.Add(1)
+				case errors.Is(err, domain.ErrConcurrencyConflict):
+					conflicts.Add(1)
+				default:
+					t.Errorf("racer %d: unexpected error: %v", i, err)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				aggregateID := fmt.Sprintf("bystander-%d", i)
+				if err := eventStore.AppendEvents(context.Background(), aggregateID, 0, []*domain.Event{groupCommitEvent(aggregateID, 1)}); err != nil {
+					t.Errorf("bystander %d failed: %v", i, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if wins.Load() != 1 || conflicts.Load() != racers-1 {
+			t.Errorf("expected 1 win and %d conflicts, got %d and %d", racers-1, wins.Load(), conflicts.Load())
+		}
+		version, err := eventStore.GetAggregateVersion("contested")
+		if err != nil || version != 1 {
+			t.Errorf("expected contested at version 1, got %d (err %v)", version, err)
+		}
+	})
+
+	t.Run("FailedAppendIsRolledBackAlone", func(t *testing.T) {
+		claim := func(aggregateID string) *domain.Event {
+			event := groupCommitEvent(aggregateID, 1)
+			event.UniqueConstraints = []domain.UniqueConstraint{{IndexName: "email", Value: "a@example.com", Operation: domain.ConstraintClaim}}
+			return event
+		}
+		if err := eventStore.AppendEvents(context.Background(), "owner", 0, []*domain.Event{claim("owner")}); err != nil {
+			t.Fatalf("failed to claim email: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		var claimErr, otherErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			claimErr = eventStore.AppendEvents(context.Background(), "thief", 0, []*domain.Event{claim("thief"), groupCommitEvent("thief", 2)})
+		}()
+		go func() {
+			defer wg.Done()
+			otherErr = eventStore.AppendEvents(context.Background(), "honest", 0, []*domain.Event{groupCommitEvent("honest", 1)})
+		}()
+		wg.Wait()
+
+		if !errors.Is(claimErr, domain.ErrUniqueConstraintViolation) {
+			t.Errorf("expected unique constraint violation, got %v", claimErr)
+		}
+		if otherErr != nil {
+			t.Errorf("unrelated append failed: %v", otherErr)
+		}
+		if version, _ := eventStore.GetAggregateVersion("thief"); version != 0 {
+			t.Errorf("expected failed append to leave no events, got version %d", version)
+		}
+	})
+}
+
+func TestGroupCommitFollowerHonoursContext(t *testing.T) {
+	eventStore, err := sqlite.NewEventStore(
+		sqlite.WithFilename(filepath.Join(t.TempDir(), "events.db")),
+		sqlite.WithGroupCommitWindow(500*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	// The leader holds the batch open for the whole window
+	leaderErr := make(chan error, 1)
+	go func() {
+		leaderErr <- eventStore.AppendEvents(context.Background(), "leader", 0, []*domain.Event{groupCommitEvent("leader", 1)})
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = eventStore.AppendEvents(ctx, "follower", 0, []*domain.Event{groupCommitEvent("follower", 1)})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the follower to fail with its context, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("expected the follower to give up with its context, waited %v", elapsed)
+	}
+
+	if err := <-leaderErr; err != nil {
+		t.Fatalf("leader failed: %v", err)
+	}
+	if version, _ := eventStore.GetAggregateVersion("follower"); version != 0 {
+		t.Errorf("expected the abandoned append to be left out, got version %d", version)
+	}
+}
+
+// BenchmarkAppendEvents_Concurrent compares 200 concurrent writers appending
+// single events with and without group commit. Compare the ops/s metric.
+func BenchmarkAppendEvents_Concurrent(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		opts []sqlite.EventStoreOption
+	}{
+		{"Direct", nil},
+		{"GroupCommit", []sqlite.EventStoreOption{sqlite.WithGroupCommitWindow(time.Millisecond)}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			opts := append([]sqlite.EventStoreOption{sqlite.WithFilename(filepath.Join(b.TempDir(), "events.db"))}, bm.opts...)
+			eventStore, err := sqlite.NewEventStore(opts...)
+			if err != nil {
+				b.Fatalf("failed to create event store: %v", err)
+			}
+			defer eventStore.Close()
+
+			const writers = 200
+			var (
+				next atomic.Int64
+				wg   sync.WaitGroup
+			)
+
+			b.ResetTimer()
+			start := time.Now()
+			for w := range writers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := next.Add(1); i <= int64(b.N); i = next.Add(1) {
+						aggregateID := fmt.Sprintf("acc-%d-%d", w, i)
+						if err := eventStore.AppendEvents(context.Background(), aggregateID, 0, []*domain.Event{groupCommitEvent(aggregateID, 1)}); err != nil {
+							b.Errorf("append failed: %v", err)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "ops/s")
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// WithHashChain links each aggregate's events in a hash chain for tamper
+// detection. Every appended event stores the hash of its predecessor
+// (prev_hash) and its own hash, computed as
+//
+//	hash = SHA-256(prev_hash || event_id || data || version)
+//
+// so editing, removing or reordering a stored event breaks the chain from that
+// version on. Use VerifyChain to check an aggregate.
+//
+// Events appended before the option was enabled have no hash; an aggregate's
+// chain starts at its first hashed event. The chain covers the event rows
+// only: snapshots don't affect it, and VerifyChain accepts a chain whose
+// earliest events have been archived (see VerifyChain).
+func WithHashChain() EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.hashChain = true
+	}
+}
+
+// chainEvent sets the prev_hash and hash columns of event, which has just been
+// inserted within tx. It does nothing unless the store uses WithHashChain.
+func (s *EventStore) chainEvent(ctx context.Context, tx *sql.Tx, event *domain.Event) error {
+	if !s.hashChain {
+		return nil
+	}
+
+	// The predecessor was written earlier in this transaction or before it;
+	// s.mu and the transaction keep the aggregate's chain head stable
+	var prevHash sql.NullString
+	err := tx.QueryRowContext(ctx, s.tables.rewrite(
+		"SELECT hash FROM events WHERE aggregate_id = ? AND version = ?"),
+		event.AggregateID, event.Version-1).Scan(&prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to load previous hash: %w", classifyError(err))
+	}
+
+	hash := eventHash(prevHash.String, event.ID, event.Data, event.Version)
+	_, err = tx.ExecContext(ctx, s.tables.rewrite(
+		"UPDATE events SET prev_hash = ?, hash = ? WHERE event_id = ?"),
+		prevHash.String, hash, event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to store event hash: %w", classifyError(err))
+	}
+	return nil
+}
+
+// eventHash computes an event's chain hash. Fields are length-prefixed so
+// that no two different events hash the same input.
+func eventHash(prevHash, eventID string, data []byte, version int64) string {
+	h := sha256.New()
+	var buf [8]byte
+	for _, field := range [][]byte{[]byte(prevHash), []byte(eventID), data} {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(field)))
+		h.Write(buf[:])
+		h.Write(field)
+	}
+	binary.BigEndian.PutUint64(buf[:], uint64(version))
+	h.Write(buf[:])
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyChain recomputes the hash chain of an aggregate's events (see
+// WithHashChain). It returns true if the chain is intact; otherwise it returns
+// false and the version of the first event whose hash or link doesn't match,
+// or that is missing from the middle of the chain.
+//
+// Verification starts at the aggregate's first hashed event. If the events
+// before it have been archived, its stored prev_hash is taken as the anchor;
+// keep the archived events' hashes to verify the link across the boundary.
+// Removing the latest events of a stream can't be detected from the stream
+// alone; compare the aggregate version with a known value to catch it.
+func (s *EventStore) VerifyChain(aggregateID string) (bool, int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ctx := context.Background()
+	rows, err := s.db.QueryContext(ctx, s.tables.rewrite(`
+		SELECT event_id, version, data, prev_hash, hash FROM events
+		WHERE aggregate_id = ?
+		ORDER BY version`), aggregateID)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to load events: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var (
+		started     bool  // a hashed event has been seen
+		lastVersion int64 // version of the previous row (0 = none)
+		lastHash    string
+	)
+	for rows.Next() {
+		var (
+			eventID        string
+			version        int64
+			data           []byte
+			prevHash, hash sql.NullString
+		)
+		if err := rows.Scan(&eventID, &version, &data, &prevHash, &hash); err != nil {
+			return false, 0, fmt.Errorf("failed to scan event: %w", classifyError(err))
+		}
+
+		switch {
+		case !hash.Valid:
+			// Events from before the chain was enabled; a gap in the
+			// hashes after the chain started means tampering
+			if started {
+				return false, version, nil
+			}
+		case !started:
+			// Anchor: the first event of the stream, or the first after
+			// unhashed events, starts a new chain. After a gap (archived
+			// events), trust the stored link.
+			started = true
+			if (version == 1 || lastVersion != 0) && prevHash.String != "" {
+				return false, version, nil
+			}
+		default:
+			if version != lastVersion+1 {
+				return false, lastVersion + 1, nil
+			}
+			if prevHash.String != lastHash {
+				return false, version, nil
+			}
+		}
+
+		if hash.Valid && eventHash(prevHash.String, eventID, data, version) != hash.String {
+			return false, version, nil
+		}
+		lastVersion, lastHash = version, hash.String
+	}
+	if err := rows.Err(); err != nil {
+		return false, 0, fmt.Errorf("failed to load events: %w", classifyError(err))
+	}
+	return true, 0, nil
+}
@@ -0,0 +1,96 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestHashChain(t *testing.T) {
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithHashChain())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	appendEvents := func(aggregateID string, from, to int64) {
+		t.Helper()
+		var events []*domain.Event
+		for v := from; v <= to; v++ {
+			events = append(events, &domain.Event{
+				ID:            fmt.Sprintf("%s-v%d", aggregateID, v),
+				AggregateID:   aggregateID,
+				AggregateType: "Account",
+				EventType:     "account.v1.Deposited",
+				Version:       v,
+				Timestamp:     time.Now(),
+				Data:          []byte(fmt.Sprintf("deposit %d", v)),
+			})
+		}
+		if err := eventStore.AppendEvents(context.Background(), aggregateID, from-1, events); err != nil {
+			t.Fatalf("failed to append events: %v", err)
+		}
+	}
+	verify := func(aggregateID string, wantOK bool, wantVersion int64) {
+		t.Helper()
+		ok, version, err := eventStore.VerifyChain(aggregateID)
+		if err != nil {
+			t.Fatalf("VerifyChain failed: %v", err)
+		}
+		if ok != wantOK || version != wantVersion {
+			t.Errorf("expected (%v, %d), got (%v, %d)", wantOK, wantVersion, ok, version)
+		}
+	}
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := eventStore.DB().Exec(query, args...); err != nil {
+			t.Fatalf("failed to tamper: %v", err)
+		}
+	}
+
+	t.Run("IntactChain", func(t *testing.T) {
+		appendEvents("intact", 1, 3)
+		appendEvents("intact", 4, 5)
+		verify("intact", true, 0)
+	})
+
+	t.Run("ModifiedData", func(t *testing.T) {
+		appendEvents("edited", 1, 5)
+		exec("UPDATE events SET data = ? WHERE aggregate_id = ? AND version = 3", []byte("deposit 1000"), "edited")
+		verify("edited", false, 3)
+	})
+
+	t.Run("RemovedEvent", func(t *testing.T) {
+		appendEvents("removed", 1, 5)
+		exec("DELETE FROM events WHERE aggregate_id = ? AND version = 2", "removed")
+		verify("removed", false, 2)
+	})
+
+	t.Run("RehashedEvent", func(t *testing.T) {
+		// Rewriting an event together with its own hash still breaks the
+		// link from its successor
+		appendEvents("rehashed", 1, 3)
+		exec("UPDATE events SET data = ?, hash = ? WHERE aggregate_id = ? AND version = 2", []byte("forged"), "forged-hash", "rehashed")
+		verify("rehashed", false, 2)
+	})
+
+	t.Run("ArchivedPrefix", func(t *testing.T) {
+		appendEvents("archived", 1, 5)
+		exec("DELETE FROM events WHERE aggregate_id = ? AND version <= 2", "archived")
+		verify("archived", true, 0)
+	})
+
+	t.Run("ChainStartsAfterUnhashedEvents", func(t *testing.T) {
+		appendEvents("legacy", 1, 3)
+		exec("UPDATE events SET prev_hash = NULL, hash = NULL WHERE aggregate_id = ?", "legacy")
+		appendEvents("legacy", 4, 5)
+		verify("legacy", true, 0)
+
+		exec("UPDATE events SET hash = NULL WHERE aggregate_id = ? AND version = 4", "legacy")
+		verify("legacy", false, 5)
+	})
+}
@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrIntegrityCheckFailed is returned when the database fails an integrity check.
+var ErrIntegrityCheckFailed = errors.New("database integrity check failed")
+
+// IntegrityReport is the result of VerifyIntegrity.
+type IntegrityReport struct {
+	// Problems lists the messages reported by PRAGMA integrity_check
+	// (empty when the database file is consistent)
+	Problems []string
+
+	// ForeignKeyViolations lists the rows reported by PRAGMA foreign_key_check
+	ForeignKeyViolations []ForeignKeyViolation
+
+	// CheckedAt is when the check ran
+	CheckedAt time.Time
+}
+
+// ForeignKeyViolation is a row whose foreign key references a missing parent row.
+type ForeignKeyViolation struct {
+	// Table is the table containing the offending row
+	Table string
+
+	// RowID is the rowid of the offending row (0 for WITHOUT ROWID tables)
+	RowID int64
+
+	// Parent is the table the foreign key refers to
+	Parent string
+
+	// ForeignKeyIndex identifies the failing foreign key constraint of Table
+	ForeignKeyIndex int
+}
+
+// OK returns true if no problems were found.
+func (r IntegrityReport) OK() bool {
+	return len(r.Problems) == 0 && len(r.ForeignKeyViolations) == 0
+}
+
+// String summarizes the problems found.
+func (r IntegrityReport) String() string {
+	if r.OK() {
+		return "ok"
+	}
+
+	var parts []string
+	parts = append(parts, r.Problems...)
+	for _, v := range r.ForeignKeyViolations {
+		parts = append(parts, fmt.Sprintf("foreign key violation: %s rowid %d references missing row in %s (constraint %d)",
+			v.Table, v.RowID, v.Parent, v.ForeignKeyIndex))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// VerifyIntegrity checks the database file for corruption with
+// PRAGMA integrity_check and for dangling references with PRAGMA foreign_key_check.
+//
+// A returned error means the checks could not run; problems found by the checks
+// are reported in the IntegrityReport. On large databases integrity_check reads
+// every page, so expect it to take a while.
+func (s *EventStore) VerifyIntegrity(ctx context.Context) (IntegrityReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return verifyIntegrity(ctx, s.db)
+}
+
+func verifyIntegrity(ctx context.Context, db *sql.DB) (IntegrityReport, error) {
+	report := IntegrityReport{CheckedAt: time.Now()}
+
+	rows, err := db.QueryContext(ctx, "PRAGMA integrity_check")
+	if err != nil {
+		return report, fmt.Errorf("failed to run integrity check: %w", classifyError(err))
+	}
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			rows.Close()
+			return report, fmt.Errorf("failed to scan integrity check result: %w", err)
+		}
+		if message != "ok" {
+			report.Problems = append(report.Problems, message)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return report, fmt.Errorf("failed to read integrity check results: %w", classifyError(err))
+	}
+	rows.Close()
+
+	rows, err = db.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return report, fmt.Errorf("failed to run foreign key check: %w", classifyError(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			violation ForeignKeyViolation
+			rowID     sql.NullInt64
+		)
+		if err := rows.Scan(&violation.Table, &rowID, &violation.Parent, &violation.ForeignKeyIndex); err != nil {
+			return report, fmt.Errorf("failed to scan foreign key check result: %w", err)
+		}
+		violation.RowID = rowID.Int64
+		report.ForeignKeyViolations = append(report.ForeignKeyViolations, violation)
+	}
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("failed to read foreign key check results: %w", classifyError(err))
+	}
+
+	return report, nil
+}
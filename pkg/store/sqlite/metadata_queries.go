@@ -0,0 +1,44 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// LoadEventsByCorrelationID returns all events sharing correlationID, across
+// aggregates, in global position order: everything one business transaction
+// touched, such as the full fan-out of one user action. The lookup uses the
+// indexed correlation_id column, which is filled from the event metadata at
+// insert time.
+func (s *EventStore) LoadEventsByCorrelationID(ctx context.Context, correlationID string) ([]*domain.Event, error) {
+	return s.loadEventsByMetadata(ctx, "correlation_id", correlationID)
+}
+
+// LoadEventsByCausationID returns the events caused directly by causationID
+// (usually a command ID), in global position order. Following the IDs of the
+// returned events walks the causation chain one step at a time.
+func (s *EventStore) LoadEventsByCausationID(ctx context.Context, causationID string) ([]*domain.Event, error) {
+	return s.loadEventsByMetadata(ctx, "causation_id", causationID)
+}
+
+// loadEventsByMetadata loads the events whose indexed metadata column equals
+// value. column is one of the fixed metadata column names, never user input.
+func (s *EventStore) loadEventsByMetadata(ctx context.Context, column, value string) ([]*domain.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.QueryContext(ctx, s.tables.rewrite(`
+		SELECT event_id, aggregate_id, aggregate_type, event_type,
+		       version, timestamp, data, metadata, constraints, position
+		FROM events
+		WHERE `+column+` = ?
+		ORDER BY position ASC`), value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events by %s: %w", column, contextError(ctx, err))
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
@@ -103,6 +103,15 @@ func (m *Migrator) LoadFromFS(fsys embed.FS, dir string) error {
 	return nil
 }
 
+// MapSQL replaces the up and down SQL of every loaded migration with fn(sql),
+// e.g. to rename tables. Call it after loading and before Up or Down.
+func (m *Migrator) MapSQL(fn func(sql string) string) {
+	for i := range m.migrations {
+		m.migrations[i].Up = fn(m.migrations[i].Up)
+		m.migrations[i].Down = fn(m.migrations[i].Down)
+	}
+}
+
 // ensureMigrationTable creates the migration tracking table if it doesn't exist.
 func (m *Migrator) ensureMigrationTable() error {
 	query := fmt.Sprintf(`
@@ -84,3 +84,74 @@ func TestMigratorWithFS(t *testing.T) {
 
 	t.Log("Migration system works end-to-end!")
 }
+
+func TestVerifySchema(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	m := New(db, "test_migrations")
+	m.migrations = []Migration{
+		{Version: 1, Name: "accounts", Up: `
+			CREATE TABLE accounts (id TEXT PRIMARY KEY, owner TEXT NOT NULL, balance INTEGER NOT NULL DEFAULT 0);
+			CREATE INDEX idx_accounts_owner ON accounts(owner);`},
+		{Version: 2, Name: "audit", Up: `CREATE TABLE audit (id INTEGER PRIMARY KEY, note TEXT)`},
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	// Unrelated tables sharing the database are ignored
+	if _, err := db.Exec("CREATE TABLE other (id INTEGER)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	drifts, err := m.VerifySchema(db)
+	if err != nil {
+		t.Fatalf("failed to verify schema: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Fatalf("expected no drift, got %v", drifts)
+	}
+
+	// Hand-edit the database
+	for _, stmt := range []string{
+		"DROP INDEX idx_accounts_owner",
+		"CREATE UNIQUE INDEX idx_accounts_balance ON accounts(balance)",
+		"ALTER TABLE accounts ADD COLUMN nickname TEXT",
+		"ALTER TABLE accounts ADD COLUMN legacy INTEGER",
+		"DROP TABLE audit",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("failed to execute %q: %v", stmt, err)
+		}
+	}
+	// Edit a migration after it was applied
+	m.migrations[0].Up = `
+		CREATE TABLE accounts (id TEXT PRIMARY KEY, owner TEXT NOT NULL, balance REAL NOT NULL DEFAULT 0, nickname TEXT, currency TEXT);
+		CREATE INDEX idx_accounts_owner ON accounts(owner);`
+
+	drifts, err = m.VerifySchema(db)
+	if err != nil {
+		t.Fatalf("failed to verify schema: %v", err)
+	}
+	expected := []SchemaDrift{
+		{Table: "accounts", Kind: DriftColumnMismatch, Name: "balance", Expected: "REAL NOT NULL DEFAULT 0", Actual: "INTEGER NOT NULL DEFAULT 0"},
+		{Table: "accounts", Kind: DriftMissingColumn, Name: "currency", Expected: "TEXT"},
+		{Table: "accounts", Kind: DriftExtraColumn, Name: "legacy", Actual: "INTEGER"},
+		{Table: "accounts", Kind: DriftMissingIndex, Name: "idx_accounts_owner", Expected: "(owner)"},
+		{Table: "accounts", Kind: DriftExtraIndex, Name: "idx_accounts_balance", Actual: "UNIQUE (balance)"},
+		{Table: "audit", Kind: DriftMissingTable},
+	}
+	if len(drifts) != len(expected) {
+		t.Fatalf("expected %d drifts, got %d: %v", len(expected), len(drifts), drifts)
+	}
+	for i := range expected {
+		if drifts[i] != expected[i] {
+			t.Errorf("drift %d: expected %q, got %q", i, expected[i], drifts[i])
+		}
+	}
+}
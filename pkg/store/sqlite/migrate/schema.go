@@ -0,0 +1,376 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrSchemaDrift indicates that a database schema differs from the schema its
+// applied migrations describe.
+var ErrSchemaDrift = errors.New("schema drift")
+
+// DriftKind classifies a SchemaDrift.
+type DriftKind string
+
+const (
+	DriftMissingTable   DriftKind = "missing table"
+	DriftMissingColumn  DriftKind = "missing column"
+	DriftExtraColumn    DriftKind = "extra column"
+	DriftColumnMismatch DriftKind = "column mismatch"
+	DriftMissingIndex   DriftKind = "missing index"
+	DriftExtraIndex     DriftKind = "extra index"
+	DriftIndexMismatch  DriftKind = "index mismatch"
+)
+
+// SchemaDrift is a difference between the live schema and the schema the
+// applied migrations describe.
+type SchemaDrift struct {
+	Table    string
+	Kind     DriftKind
+	Name     string // Column or index name, empty for DriftMissingTable
+	Expected string // Definition according to the migrations, if any
+	Actual   string // Definition in the database, if any
+}
+
+// String describes the drift on a single line.
+func (d SchemaDrift) String() string {
+	s := fmt.Sprintf("%s: %s", d.Table, d.Kind)
+	if d.Name != "" {
+		s += " " + d.Name
+	}
+	switch {
+	case d.Expected != "" && d.Actual != "":
+		s += fmt.Sprintf(" (expected %s, got %s)", d.Expected, d.Actual)
+	case d.Expected != "":
+		s += fmt.Sprintf(" (%s)", d.Expected)
+	case d.Actual != "":
+		s += fmt.Sprintf(" (%s)", d.Actual)
+	}
+	return s
+}
+
+// VerifySchema compares the schema of db with the schema the applied
+// migrations describe, and returns the differences (nil if there are none).
+//
+// The expected schema is reconstructed by running the up scripts of the
+// versions recorded in db's migration table on an empty in-memory database
+// of the same driver. Only the tables those scripts leave behind are
+// compared, so unrelated tables sharing the database are ignored. Migrations
+// that read tables they did not create cannot be replayed and make
+// VerifySchema fail.
+func (m *Migrator) VerifySchema(db *sql.DB) ([]SchemaDrift, error) {
+	applied, err := m.appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	scratch := sql.OpenDB(memoryConnector{driver: db.Driver()})
+	defer scratch.Close()
+	// Every connection would get its own in-memory database
+	scratch.SetMaxOpenConns(1)
+
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			continue
+		}
+		if _, err := scratch.Exec(migration.Up); err != nil {
+			return nil, fmt.Errorf("failed to replay migration %d: %w", migration.Version, err)
+		}
+	}
+
+	expected, err := readSchema(scratch, m.tableName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read expected schema: %w", err)
+	}
+	tables := make([]string, 0, len(expected))
+	for table := range expected {
+		tables = append(tables, table)
+	}
+	actual, err := readSchema(db, m.tableName, tables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database schema: %w", err)
+	}
+
+	sort.Strings(tables)
+	var drifts []SchemaDrift
+	for _, table := range tables {
+		drifts = append(drifts, diffTable(table, expected[table], actual[table])...)
+	}
+	return drifts, nil
+}
+
+// appliedVersions returns the versions recorded in db's migration table.
+func (m *Migrator) appliedVersions(db *sql.DB) (map[int]bool, error) {
+	var exists int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", m.tableName,
+	).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up table %s: %w", m.tableName, err)
+	}
+	applied := make(map[int]bool)
+	if exists == 0 {
+		return applied, nil
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT version FROM %s", quoteIdent(m.tableName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// memoryConnector opens in-memory databases with the driver of another
+// database, so the migrate package needs no driver of its own.
+type memoryConnector struct {
+	driver driver.Driver
+}
+
+func (c memoryConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(":memory:")
+}
+
+func (c memoryConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// tableSchema is the introspected schema of a single table.
+type tableSchema struct {
+	columns []column
+	indexes map[string]string // Index name -> definition
+}
+
+type column struct {
+	name       string
+	definition string
+}
+
+// readSchema introspects the given tables of db, or all of its tables except
+// SQLite's internal ones and the migration table when tables is nil. Tables
+// that do not exist are absent from the result.
+func readSchema(db *sql.DB, migrationTable string, tables []string) (map[string]*tableSchema, error) {
+	if tables == nil {
+		rows, err := db.Query(
+			"SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name != ?",
+			migrationTable,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			tables = append(tables, name)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	schema := make(map[string]*tableSchema)
+	for _, table := range tables {
+		columns, err := readColumns(db, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			continue // No such table
+		}
+		indexes, err := readIndexes(db, table)
+		if err != nil {
+			return nil, err
+		}
+		schema[table] = &tableSchema{columns: columns, indexes: indexes}
+	}
+	return schema, nil
+}
+
+// readColumns returns the columns of table in declaration order.
+func readColumns(db *sql.DB, table string) ([]column, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", quoteIdent(table)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []column
+	for rows.Next() {
+		var (
+			cid          int
+			name, typ    string
+			notNull, pk  int
+			defaultValue sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &defaultValue, &pk); err != nil {
+			return nil, err
+		}
+		definition := strings.ToUpper(typ)
+		if definition == "" {
+			definition = "(no type)"
+		}
+		if pk > 0 {
+			definition += " PRIMARY KEY"
+		}
+		if notNull != 0 {
+			definition += " NOT NULL"
+		}
+		if defaultValue.Valid {
+			definition += " DEFAULT " + defaultValue.String
+		}
+		columns = append(columns, column{name: name, definition: definition})
+	}
+	return columns, rows.Err()
+}
+
+// readIndexes returns the indexes of table, including the automatic indexes
+// backing UNIQUE and PRIMARY KEY constraints.
+func readIndexes(db *sql.DB, table string) (map[string]string, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA index_list(%s)", quoteIdent(table)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read indexes of %s: %w", table, err)
+	}
+	type index struct {
+		name    string
+		unique  bool
+		partial bool
+	}
+	var list []index
+	for rows.Next() {
+		var (
+			seq, unique, partial int
+			name, origin         string
+		)
+		if err := rows.Scan(&seq, &name, &unique, &origin, &partial); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, index{name: name, unique: unique != 0, partial: partial != 0})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Query the columns after closing the list: the connection may be the
+	// only one (as for the in-memory scratch database)
+	indexes := make(map[string]string, len(list))
+	for _, idx := range list {
+		columns, err := readIndexColumns(db, idx.name)
+		if err != nil {
+			return nil, err
+		}
+		definition := "(" + strings.Join(columns, ", ") + ")"
+		if idx.unique {
+			definition = "UNIQUE " + definition
+		}
+		if idx.partial {
+			definition += " WHERE ..."
+		}
+		indexes[idx.name] = definition
+	}
+	return indexes, nil
+}
+
+// readIndexColumns returns the indexed columns in index order. Expressions
+// are reported as "<expr>".
+func readIndexColumns(db *sql.DB, index string) ([]string, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA index_info(%s)", quoteIdent(index)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of index %s: %w", index, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var (
+			seqno, cid int
+			name       sql.NullString
+		)
+		if err := rows.Scan(&seqno, &cid, &name); err != nil {
+			return nil, err
+		}
+		if name.Valid {
+			columns = append(columns, name.String)
+		} else {
+			columns = append(columns, "<expr>")
+		}
+	}
+	return columns, rows.Err()
+}
+
+// diffTable compares the expected and actual schema of a table. actual is nil
+// if the table does not exist.
+func diffTable(table string, expected, actual *tableSchema) []SchemaDrift {
+	if actual == nil {
+		return []SchemaDrift{{Table: table, Kind: DriftMissingTable}}
+	}
+
+	var drifts []SchemaDrift
+	actualColumns := make(map[string]string, len(actual.columns))
+	for _, c := range actual.columns {
+		actualColumns[c.name] = c.definition
+	}
+	expectedColumns := make(map[string]bool, len(expected.columns))
+	for _, c := range expected.columns {
+		expectedColumns[c.name] = true
+		definition, ok := actualColumns[c.name]
+		switch {
+		case !ok:
+			drifts = append(drifts, SchemaDrift{Table: table, Kind: DriftMissingColumn, Name: c.name, Expected: c.definition})
+		case definition != c.definition:
+			drifts = append(drifts, SchemaDrift{Table: table, Kind: DriftColumnMismatch, Name: c.name, Expected: c.definition, Actual: definition})
+		}
+	}
+	for _, c := range actual.columns {
+		if !expectedColumns[c.name] {
+			drifts = append(drifts, SchemaDrift{Table: table, Kind: DriftExtraColumn, Name: c.name, Actual: c.definition})
+		}
+	}
+
+	for _, name := range sortedKeys(expected.indexes) {
+		definition, ok := actual.indexes[name]
+		switch {
+		case !ok:
+			drifts = append(drifts, SchemaDrift{Table: table, Kind: DriftMissingIndex, Name: name, Expected: expected.indexes[name]})
+		case definition != expected.indexes[name]:
+			drifts = append(drifts, SchemaDrift{Table: table, Kind: DriftIndexMismatch, Name: name, Expected: expected.indexes[name], Actual: definition})
+		}
+	}
+	for _, name := range sortedKeys(actual.indexes) {
+		if _, ok := expected.indexes[name]; !ok {
+			drifts = append(drifts, SchemaDrift{Table: table, Kind: DriftExtraIndex, Name: name, Actual: actual.indexes[name]})
+		}
+	}
+	return drifts
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// quoteIdent quotes an SQLite identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
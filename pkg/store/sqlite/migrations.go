@@ -12,12 +12,13 @@ import (
 var migrationsFS embed.FS
 
 // runMigrations runs all pending migrations using our custom migrator.
-func runMigrations(db *sql.DB) error {
-	m := migrate.New(db, "schema_migrations")
+func runMigrations(db *sql.DB, prefix tablePrefix) error {
+	m := migrate.New(db, prefix.rewrite("schema_migrations"))
 
 	if err := m.LoadFromFS(migrationsFS, "migrations"); err != nil {
 		return fmt.Errorf("failed to load migrations: %w", err)
 	}
+	m.MapSQL(prefix.rewrite)
 
 	if err := m.Up(); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
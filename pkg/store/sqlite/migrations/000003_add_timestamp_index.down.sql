@@ -0,0 +1,3 @@
+-- Rollback business-time ordering index
+
+DROP INDEX IF EXISTS idx_events_timestamp_position;
@@ -0,0 +1,4 @@
+-- Index for business-time ordered reads (see LoadAllEventsOrderedBy)
+
+CREATE INDEX IF NOT EXISTS idx_events_timestamp_position
+    ON events(timestamp, position);
@@ -0,0 +1,3 @@
+-- Rollback causation index
+
+DROP INDEX IF EXISTS idx_events_causation;
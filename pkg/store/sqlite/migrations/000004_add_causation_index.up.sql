@@ -0,0 +1,4 @@
+-- Index for causation lookups (see LoadCompensations)
+
+CREATE INDEX IF NOT EXISTS idx_events_causation
+    ON events(json_extract(metadata, '$.CausationID'));
@@ -0,0 +1,14 @@
+-- Rollback metadata columns
+
+DROP INDEX IF EXISTS idx_events_tenant;
+DROP INDEX IF EXISTS idx_events_principal;
+DROP INDEX IF EXISTS idx_events_correlation;
+DROP INDEX IF EXISTS idx_events_causation;
+
+ALTER TABLE events DROP COLUMN tenant_id;
+ALTER TABLE events DROP COLUMN principal_id;
+ALTER TABLE events DROP COLUMN causation_id;
+ALTER TABLE events DROP COLUMN correlation_id;
+
+CREATE INDEX IF NOT EXISTS idx_events_causation
+    ON events(json_extract(metadata, '$.CausationID'));
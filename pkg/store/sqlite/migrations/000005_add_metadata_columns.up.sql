@@ -0,0 +1,31 @@
+-- Extract the well-known metadata fields into indexed columns
+-- (see LoadEventsByCorrelationID, LoadEventsByCausationID). The JSON metadata
+-- column stays the source of truth for custom fields.
+
+ALTER TABLE events ADD COLUMN correlation_id TEXT;
+ALTER TABLE events ADD COLUMN causation_id TEXT;
+ALTER TABLE events ADD COLUMN principal_id TEXT;
+ALTER TABLE events ADD COLUMN tenant_id TEXT;
+
+-- Backfill existing events from their JSON metadata
+UPDATE events SET
+    correlation_id = NULLIF(json_extract(metadata, '$.CorrelationID'), ''),
+    causation_id = NULLIF(json_extract(metadata, '$.CausationID'), ''),
+    principal_id = NULLIF(json_extract(metadata, '$.PrincipalID'), ''),
+    tenant_id = NULLIF(json_extract(metadata, '$.TenantID'), '')
+WHERE json_valid(metadata);
+
+-- The causation column replaces the JSON expression index
+DROP INDEX IF EXISTS idx_events_causation;
+
+CREATE INDEX IF NOT EXISTS idx_events_causation
+    ON events(causation_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_events_correlation
+    ON events(correlation_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_events_principal
+    ON events(principal_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_events_tenant
+    ON events(tenant_id, position);
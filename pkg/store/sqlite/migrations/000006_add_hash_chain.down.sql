@@ -0,0 +1,4 @@
+-- Rollback hash chain columns
+
+ALTER TABLE events DROP COLUMN hash;
+ALTER TABLE events DROP COLUMN prev_hash;
@@ -0,0 +1,6 @@
+-- Per-aggregate hash chain for tamper detection (see WithHashChain).
+-- Events appended without the option, including all existing events, keep
+-- NULL hashes.
+
+ALTER TABLE events ADD COLUMN prev_hash TEXT;
+ALTER TABLE events ADD COLUMN hash TEXT;
@@ -6,7 +6,7 @@ import "database/sql"
 func (s *EventStore) RunMigrations() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return runMigrations(s.db)
+	return runMigrations(s.db, s.tables)
 }
 
 // GetMigrationVersion returns the current migration version by querying the database directly.
@@ -20,7 +20,7 @@ func (s *EventStore) GetMigrationVersion() (uint, bool, error) {
 
 	var version int64
 	var dirty bool
-	err := s.db.QueryRow("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	err := s.db.QueryRow(s.tables.rewrite("SELECT version, dirty FROM schema_migrations LIMIT 1")).Scan(&version, &dirty)
 	if err == sql.ErrNoRows {
 		return 0, false, nil // No migrations run yet
 	}
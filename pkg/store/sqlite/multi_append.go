@@ -0,0 +1,73 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// AppendEventsMulti appends events to several aggregates in one transaction,
+// implementing store.MultiAggregateAppender. Each aggregate gets the same
+// version, constraint and dedup checks as AppendEvents; if any fails, nothing
+// is appended and the error names the aggregate. An aggregate may appear more
+// than once, with expected versions following on from each other. The batch
+// commits on its own, even with WithGroupCommitWindow.
+//
+// Example:
+//
+//	err := eventStore.AppendEventsMulti(ctx, []store.AggregateAppend{
+//	    {AggregateID: "acc-a", ExpectedVersion: 3, Events: []*domain.Event{debited}},
+//	    {AggregateID: "acc-b", ExpectedVersion: 7, Events: []*domain.Event{credited}},
+//	})
+func (s *EventStore) AppendEventsMulti(ctx context.Context, appends []store.AggregateAppend) error {
+	for _, aggregate := range appends {
+		if err := s.validateEvents(ctx, aggregate.Events); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", contextError(ctx, err))
+	}
+	defer tx.Rollback()
+
+	var appended []*domain.Event
+	for _, aggregate := range appends {
+		if len(aggregate.Events) == 0 {
+			continue
+		}
+		kept, _, err := s.insertStream(ctx, tx, aggregate.AggregateID, aggregate.ExpectedVersion, aggregate.Events, s.monotonicTimestamps, true)
+		if err != nil {
+			return fmt.Errorf("failed to append to %s: %w", aggregate.AggregateID, err)
+		}
+		appended = append(appended, kept...)
+	}
+	if len(appended) == 0 {
+		return nil
+	}
+
+	// Update global position once for the whole batch
+	if err := s.updatePositions(ctx, tx); err != nil {
+		return fmt.Errorf("failed to update positions: %w", contextError(ctx, err))
+	}
+	if err := s.loadPositions(ctx, tx, appended); err != nil {
+		return err
+	}
+	if err := s.enqueueOutbox(ctx, tx, appended); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return contextError(ctx, err)
+	}
+	for _, aggregate := range appends {
+		clampedPositions(aggregate.Events, appended)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestAppendEventsMulti(t *testing.T) {
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	ctx := context.Background()
+
+	event := func(aggregateID string, version int64, eventType string, constraints ...domain.UniqueConstraint) *domain.Event {
+		return &domain.Event{
+			ID:                fmt.Sprintf("%s-%d", aggregateID, version),
+			AggregateID:       aggregateID,
+			AggregateType:     "Account",
+			EventType:         eventType,
+			Version:           version,
+			Timestamp:         time.Now(),
+			Data:              []byte(eventType),
+			UniqueConstraints: constraints,
+		}
+	}
+	versions := func() (int64, int64) {
+		a, _ := eventStore.GetAggregateVersion("acc-a")
+		b, _ := eventStore.GetAggregateVersion("acc-b")
+		return a, b
+	}
+
+	err = eventStore.AppendEventsMulti(ctx, []store.AggregateAppend{
+		{AggregateID: "acc-a", ExpectedVersion: 0, Events: []*domain.Event{event("acc-a", 1, "test.Opened")}},
+		{AggregateID: "acc-b", ExpectedVersion: 0, Events: []*domain.Event{event("acc-b", 1, "test.Opened")}},
+	})
+	if err != nil {
+		t.Fatalf("failed to open accounts: %v", err)
+	}
+
+	t.Run("Transfer", func(t *testing.T) {
+		debit, credit := event("acc-a", 2, "test.Debited"), event("acc-b", 2, "test.Credited")
+		err := eventStore.AppendEventsMulti(ctx, []store.AggregateAppend{
+			{AggregateID: "acc-a", ExpectedVersion: 1, Events: []*domain.Event{debit}},
+			{AggregateID: "acc-b", ExpectedVersion: 1, Events: []*domain.Event{credit}},
+		})
+		if err != nil {
+			t.Fatalf("failed to transfer: %v", err)
+		}
+		if a, b := versions(); a != 2 || b != 2 {
+			t.Errorf("expected both accounts at version 2, got %d and %d", a, b)
+		}
+		if debit.Position == 0 || credit.Position == 0 {
+			t.Errorf("expected positions to be assigned, got %d and %d", debit.Position, credit.Position)
+		}
+	})
+
+	t.Run("ConflictRollsBackAll", func(t *testing.T) {
+		err := eventStore.AppendEventsMulti(ctx, []store.AggregateAppend{
+			{AggregateID: "acc-a", ExpectedVersion: 2, Events: []*domain.Event{event("acc-a", 3, "test.Debited")}},
+			{AggregateID: "acc-b", ExpectedVersion: 1, Events: []*domain.Event{event("acc-b", 2, "test.Credited")}},
+		})
+		if !errors.Is(err, domain.ErrConcurrencyConflict) {
+			t.Fatalf("expected a concurrency conflict, got %v", err)
+		}
+		if a, b := versions(); a != 2 || b != 2 {
+			t.Errorf("expected nothing appended, got versions %d and %d", a, b)
+		}
+	})
+
+	t.Run("ConstraintsAcrossBatch", func(t *testing.T) {
+		claim := domain.UniqueConstraint{IndexName: "iban", Value: "BE01", Operation: domain.ConstraintClaim}
+		err := eventStore.AppendEventsMulti(ctx, []store.AggregateAppend{
+			{AggregateID: "acc-a", ExpectedVersion: 2, Events: []*domain.Event{event("acc-a", 3, "test.IbanAssigned", claim)}},
+			{AggregateID: "acc-b", ExpectedVersion: 2, Events: []*domain.Event{event("acc-b", 3, "test.IbanAssigned", claim)}},
+		})
+		if !errors.Is(err, domain.ErrUniqueConstraintViolation) {
+			t.Fatalf("expected a unique constraint violation, got %v", err)
+		}
+		if a, b := versions(); a != 2 || b != 2 {
+			t.Errorf("expected nothing appended, got versions %d and %d", a, b)
+		}
+		if owner, _ := eventStore.GetConstraintOwner("iban", "BE01"); owner != "" {
+			t.Errorf("expected the claim to be rolled back, owned by %q", owner)
+		}
+	})
+}
@@ -0,0 +1,341 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/messaging"
+)
+
+const (
+	// DefaultOutboxPollInterval is how often an OutboxRelay checks the outbox
+	// when no poll interval is given.
+	DefaultOutboxPollInterval = time.Second
+
+	// DefaultOutboxBatchSize is how many events an OutboxRelay publishes at
+	// once when no batch size is given.
+	DefaultOutboxBatchSize = 100
+
+	// DefaultOutboxRetention is how long an OutboxRelay keeps published
+	// events in the outbox when no retention is given.
+	DefaultOutboxRetention = 24 * time.Hour
+)
+
+// WithOutbox makes the store write every event it appends to the
+// event_outbox table, in the transaction that appends it. An OutboxRelay
+// publishes the events from there, so an event is published if and only if
+// it was committed, even if the process crashes right after the commit.
+func WithOutbox() EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.outbox = true
+	}
+}
+
+// createOutboxTable creates the event_outbox table if needed.
+func createOutboxTable(db *sql.DB, tables tablePrefix) error {
+	_, err := db.Exec(tables.rewrite(`
+		CREATE TABLE IF NOT EXISTS event_outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			event_id TEXT NOT NULL,
+			event BLOB NOT NULL,
+			created_at INTEGER NOT NULL,
+			published_at INTEGER
+		);
+		CREATE INDEX IF NOT EXISTS idx_event_outbox_pending ON event_outbox (id) WHERE published_at IS NULL;
+	`))
+	if err != nil {
+		return fmt.Errorf("failed to create event_outbox table: %w", classifyError(err))
+	}
+	return nil
+}
+
+// enqueueOutbox writes events to the outbox within tx. It does nothing unless
+// the store uses WithOutbox.
+func (s *EventStore) enqueueOutbox(ctx context.Context, tx *sql.Tx, events []*domain.Event) error {
+	if !s.outbox {
+		return nil
+	}
+
+	now := domain.Now().Unix()
+	for _, event := range events {
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %s for the outbox: %w", event.ID, err)
+		}
+		_, err = tx.ExecContext(ctx, s.tables.rewrite(`
+			INSERT INTO event_outbox (event_id, event, created_at) VALUES (?, ?, ?)
+		`), event.ID, encoded, now)
+		if err != nil {
+			return fmt.Errorf("failed to write event %s to the outbox: %w", event.ID, contextError(ctx, err))
+		}
+	}
+	return nil
+}
+
+// OutboxRelay publishes the events of the event_outbox table (see WithOutbox)
+// to an event bus, in append order. A row is marked published only once the
+// bus accepted it; rows that failed to publish are retried on the next poll,
+// so every event is delivered at least once. Run a single relay per database.
+//
+// OutboxRelay implements runner.Service.
+type OutboxRelay struct {
+	db           *sql.DB
+	bus          messaging.EventBus
+	tables       tablePrefix
+	pollInterval time.Duration
+	batchSize    int
+	retention    time.Duration // Negative = keep published events
+
+	mu   sync.Mutex
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// OutboxRelayOption configures an OutboxRelay.
+type OutboxRelayOption func(*OutboxRelay)
+
+// WithOutboxTablePrefix prepends prefix to the event_outbox table. Use the
+// event store's prefix (see WithTablePrefix).
+func WithOutboxTablePrefix(prefix string) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		r.tables = tablePrefix(prefix)
+	}
+}
+
+// WithOutboxPollInterval sets how often the relay checks the outbox
+// (DefaultOutboxPollInterval if zero).
+func WithOutboxPollInterval(d time.Duration) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		r.pollInterval = d
+	}
+}
+
+// WithOutboxBatchSize sets how many events the relay publishes at once
+// (DefaultOutboxBatchSize if zero).
+func WithOutboxBatchSize(n int) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		r.batchSize = n
+	}
+}
+
+// WithOutboxRetention sets how long the relay keeps published events in the
+// outbox (DefaultOutboxRetention if zero). While running, it purges the events
+// published longer ago than retention once per retention period, so purged
+// events stay between one and two periods. A negative retention keeps them
+// until PurgePublished is called.
+func WithOutboxRetention(retention time.Duration) OutboxRelayOption {
+	return func(r *OutboxRelay) {
+		r.retention = retention
+	}
+}
+
+// NewOutboxRelay creates a relay publishing the outbox of db to bus, creating
+// the event_outbox table if needed.
+//
+// Example:
+//
+//	eventStore, _ := sqlite.NewEventStore(sqlite.WithDSN("app.db"), sqlite.WithOutbox())
+//	relay, _ := sqlite.NewOutboxRelay(eventStore.DB(), eventBus)
+//	r := runner.New([]runner.Service{eventBusService, relay})
+func NewOutboxRelay(db *sql.DB, bus messaging.EventBus, opts ...OutboxRelayOption) (*OutboxRelay, error) {
+	r := &OutboxRelay{
+		db:           db,
+		bus:          bus,
+		pollInterval: DefaultOutboxPollInterval,
+		batchSize:    DefaultOutboxBatchSize,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.pollInterval <= 0 {
+		r.pollInterval = DefaultOutboxPollInterval
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = DefaultOutboxBatchSize
+	}
+	if r.retention == 0 {
+		r.retention = DefaultOutboxRetention
+	}
+	if _, err := newTablePrefix(string(r.tables)); err != nil {
+		return nil, err
+	}
+
+	if err := createOutboxTable(db, r.tables); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Name returns the service name for logging.
+func (r *OutboxRelay) Name() string {
+	return "outbox-relay"
+}
+
+// Start starts publishing the outbox in the background. The relay runs until
+// Stop; ctx only bounds the start itself.
+func (r *OutboxRelay) Start(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		return fmt.Errorf("outbox relay already running")
+	}
+
+	relayCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	r.stop = stop
+	r.done = make(chan struct{})
+	go r.run(relayCtx, r.done)
+	return nil
+}
+
+// Stop stops the relay, waiting for a publish in progress to finish or ctx
+// to be done. Unpublished events stay in the outbox for the next start.
+func (r *OutboxRelay) Stop(ctx context.Context) error {
+	r.mu.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+
+	stop()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("outbox relay did not stop: %w", ctx.Err())
+	}
+}
+
+// run publishes the outbox every poll interval, and purges it every retention
+// period, until ctx is done.
+func (r *OutboxRelay) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	var purge <-chan time.Time
+	if r.retention > 0 {
+		purgeTicker := time.NewTicker(r.retention)
+		defer purgeTicker.Stop()
+		purge = purgeTicker.C
+	}
+
+	for {
+		// A failed publish is retried on the next tick
+		_, _ = r.Relay(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-purge:
+			// A failed purge is retried on the next period
+			_, _ = r.PurgePublished(ctx, domain.Now().Add(-r.retention))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Relay publishes the pending events of the outbox, a batch at a time, and
+// returns how many it published. It stops at the first batch the bus fails
+// to accept, leaving it pending, so events are published in order.
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		ids, events, err := r.pending(ctx)
+		if err != nil || len(events) == 0 {
+			return published, err
+		}
+
+		if err := r.bus.Publish(events); err != nil {
+			return published, fmt.Errorf("failed to publish outbox events: %w", err)
+		}
+		if err := r.markPublished(ctx, ids); err != nil {
+			return published, err
+		}
+		published += len(events)
+	}
+}
+
+// Pending returns the number of events waiting in the outbox.
+func (r *OutboxRelay) Pending(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, r.tables.rewrite(`
+		SELECT COUNT(*) FROM event_outbox WHERE published_at IS NULL
+	`)).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending outbox events: %w", classifyError(err))
+	}
+	return n, nil
+}
+
+// PurgePublished deletes the events published before cutoff from the outbox
+// and returns how many it deleted.
+func (r *OutboxRelay) PurgePublished(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, r.tables.rewrite(`
+		DELETE FROM event_outbox WHERE published_at IS NOT NULL AND published_at < ?
+	`), cutoff.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge outbox: %w", classifyError(err))
+	}
+	return result.RowsAffected()
+}
+
+// pending loads the next batch of unpublished events.
+func (r *OutboxRelay) pending(ctx context.Context) ([]int64, []*domain.Event, error) {
+	rows, err := r.db.QueryContext(ctx, r.tables.rewrite(`
+		SELECT id, event FROM event_outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT ?
+	`), r.batchSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load outbox: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var events []*domain.Event
+	for rows.Next() {
+		var id int64
+		var encoded []byte
+		if err := rows.Scan(&id, &encoded); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		event := &domain.Event{}
+		if err := json.Unmarshal(encoded, event); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode outbox event %d: %w", id, err)
+		}
+		ids = append(ids, id)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to load outbox: %w", err)
+	}
+	return ids, events, nil
+}
+
+// markPublished marks the outbox rows ids as published.
+func (r *OutboxRelay) markPublished(ctx context.Context, ids []int64) error {
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, domain.Now().Unix())
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	_, err := r.db.ExecContext(ctx, r.tables.rewrite(`
+		UPDATE event_outbox SET published_at = ? WHERE id IN (`+placeholders+`)
+	`), args...)
+	if err != nil {
+		return fmt.Errorf("failed to mark outbox events published: %w", classifyError(err))
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/messaging"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+// outboxBus records the events published to it, failing while fail is set.
+type outboxBus struct {
+	mu        sync.Mutex
+	published []*domain.Event
+	fail      bool
+}
+
+func (b *outboxBus) Publish(events []*domain.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fail {
+		return errors.New("no ack")
+	}
+	b.published = append(b.published, events...)
+	return nil
+}
+
+func (b *outboxBus) Subscribe(filter messaging.EventFilter, handler messaging.EventHandler, opts ...messaging.SubscribeOption) (messaging.Subscription, error) {
+	return nil, errors.New("not supported")
+}
+
+func (b *outboxBus) Close() error { return nil }
+
+func (b *outboxBus) setFail(fail bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fail = fail
+}
+
+func (b *outboxBus) eventIDs() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ids := make([]string, len(b.published))
+	for i, event := range b.published {
+		ids[i] = event.ID
+	}
+	return ids
+}
+
+func outboxEvent(aggregateID string, version int64) *domain.Event {
+	return &domain.Event{
+		ID:            fmt.Sprintf("%s-%d", aggregateID, version),
+		AggregateID:   aggregateID,
+		AggregateType: "Account",
+		EventType:     "AccountOpened",
+		Version:       version,
+		Timestamp:     time.Now(),
+		Data:          []byte(`{}`),
+	}
+}
+
+func TestOutboxRelay(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithOutbox())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	bus := &outboxBus{}
+	relay, err := sqlite.NewOutboxRelay(eventStore.DB(), bus, sqlite.WithOutboxBatchSize(2))
+	if err != nil {
+		t.Fatalf("failed to create relay: %v", err)
+	}
+
+	if err := eventStore.AppendEvents(ctx, "acc-1", 0, []*domain.Event{outboxEvent("acc-1", 1), outboxEvent("acc-1", 2)}); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+	if _, err := eventStore.AppendEventsIdempotent(ctx, "acc-2", 0, []*domain.Event{outboxEvent("acc-2", 1)}, "cmd-1", time.Hour); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+	// A rejected append leaves nothing in the outbox
+	if err := eventStore.AppendEvents(ctx, "acc-1", 0, []*domain.Event{outboxEvent("acc-1", 9)}); err == nil {
+		t.Fatal("expected a concurrency conflict")
+	}
+
+	// Nothing is marked published while the bus fails
+	bus.setFail(true)
+	if _, err := relay.Relay(ctx); err == nil {
+		t.Fatal("expected the publish to fail")
+	}
+	if pending, _ := relay.Pending(ctx); pending != 3 {
+		t.Errorf("expected 3 pending events, got %d", pending)
+	}
+
+	bus.setFail(false)
+	published, err := relay.Relay(ctx)
+	if err != nil {
+		t.Fatalf("failed to relay: %v", err)
+	}
+	if published != 3 {
+		t.Errorf("expected 3 published events, got %d", published)
+	}
+	want := []string{"acc-1-1", "acc-1-2", "acc-2-1"}
+	if got := bus.eventIDs(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if bus.published[2].Position == 0 {
+		t.Error("expected relayed events to carry their position")
+	}
+	if pending, _ := relay.Pending(ctx); pending != 0 {
+		t.Errorf("expected no pending events, got %d", pending)
+	}
+
+	// Published events are purged once old enough
+	if purged, err := relay.PurgePublished(ctx, time.Now().Add(time.Minute)); err != nil || purged != 3 {
+		t.Errorf("expected 3 purged events, got %d (%v)", purged, err)
+	}
+}
+
+func TestOutboxRelayLifecycle(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithOutbox(), sqlite.WithTablePrefix("billing_"))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	bus := &outboxBus{}
+	relay, err := sqlite.NewOutboxRelay(eventStore.DB(), bus,
+		sqlite.WithOutboxTablePrefix("billing_"), sqlite.WithOutboxPollInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create relay: %v", err)
+	}
+
+	// The start context only bounds the start
+	startCtx, cancel := context.WithCancel(ctx)
+	if err := relay.Start(startCtx); err != nil {
+		t.Fatalf("failed to start relay: %v", err)
+	}
+	cancel()
+
+	if err := eventStore.AppendEvents(ctx, "acc-1", 0, []*domain.Event{outboxEvent("acc-1", 1)}); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(bus.eventIDs()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the relay to publish the event")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := relay.Stop(ctx); err != nil {
+		t.Fatalf("failed to stop relay: %v", err)
+	}
+	var tables int
+	if err := eventStore.DB().QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'billing_event_outbox'`).Scan(&tables); err != nil || tables != 1 {
+		t.Errorf("expected the billing_event_outbox table, got %d (%v)", tables, err)
+	}
+}
+
+func TestOutboxDeleteAggregatesWithPrefix(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithOutbox())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	bus := &outboxBus{}
+	relay, err := sqlite.NewOutboxRelay(eventStore.DB(), bus)
+	if err != nil {
+		t.Fatalf("failed to create relay: %v", err)
+	}
+
+	for _, id := range []string{"t1/acc-1", "t2/acc-1"} {
+		if err := eventStore.AppendEvents(ctx, id, 0, []*domain.Event{outboxEvent(id, 1)}); err != nil {
+			t.Fatalf("failed to append events: %v", err)
+		}
+	}
+	if _, err := eventStore.DeleteAggregatesWithPrefix(ctx, "t1/"); err != nil {
+		t.Fatalf("failed to delete aggregates: %v", err)
+	}
+
+	// The deleted tenant's events are never published
+	if _, err := relay.Relay(ctx); err != nil {
+		t.Fatalf("failed to relay: %v", err)
+	}
+	if got := bus.eventIDs(); fmt.Sprint(got) != "[t2/acc-1-1]" {
+		t.Errorf("expected only the remaining tenant's event, got %v", got)
+	}
+}
+
+func TestOutboxRelayRetention(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithOutbox())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	bus := &outboxBus{}
+	relay, err := sqlite.NewOutboxRelay(eventStore.DB(), bus,
+		sqlite.WithOutboxPollInterval(10*time.Millisecond), sqlite.WithOutboxRetention(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create relay: %v", err)
+	}
+	if err := eventStore.AppendEvents(ctx, "acc-1", 0, []*domain.Event{outboxEvent("acc-1", 1)}); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+	if err := relay.Start(ctx); err != nil {
+		t.Fatalf("failed to start relay: %v", err)
+	}
+	defer relay.Stop(ctx)
+
+	// Published events are purged once older than the retention
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var rows int
+		if err := eventStore.DB().QueryRow(`SELECT COUNT(*) FROM event_outbox`).Scan(&rows); err != nil {
+			t.Fatalf("failed to count outbox rows: %v", err)
+		}
+		if rows == 0 && len(bus.eventIDs()) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the published event to be purged, %d rows left", rows)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// SQLite keeps most pragmas per connection, and a pool opens connections
+// whenever it needs more, so WithPragma and friends are applied to every
+// connection as it is opened rather than once to the pool.
+//
+// Connection-scoped pragmas must be set on every connection and are lost
+// when it closes: busy_timeout, synchronous, foreign_keys, cache_size,
+// temp_store, mmap_size, wal_autocheckpoint, query_only.
+//
+// Database-scoped pragmas are stored in the database file and apply to all
+// connections once set: journal_mode=WAL (see WithWALMode), page_size and
+// auto_vacuum (which only take effect on an empty database or after VACUUM),
+// application_id and user_version. Setting them with WithPragma is harmless
+// but redundant after the first connection.
+
+// pragma is a PRAGMA statement applied to every connection.
+type pragma struct {
+	name  string
+	value string
+}
+
+var (
+	pragmaName  = regexp.MustCompile(`^[A-Za-z_]+$`)
+	pragmaValue = regexp.MustCompile(`^[A-Za-z0-9_.+-]+$`)
+)
+
+// WithPragma sets PRAGMA name = value on every connection of the pool, e.g.
+// WithPragma("cache_size", "-20000"). Pragmas are applied in the order they
+// were added, after the settings of WithWALMode, so a later pragma overrides
+// an earlier one. Names may contain letters and underscores, values letters,
+// digits and "_.+-".
+func WithPragma(name, value string) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.pragmas = append(c.pragmas, pragma{name: name, value: value})
+	}
+}
+
+// WithBusyTimeout makes a connection wait up to d for a lock held by another
+// connection instead of failing with ErrBusy at once (PRAGMA busy_timeout).
+// Recommended when several connections write, e.g. when the pool is shared
+// with other components.
+func WithBusyTimeout(d time.Duration) EventStoreOption {
+	return WithPragma("busy_timeout", strconv.FormatInt(d.Milliseconds(), 10))
+}
+
+// WithSynchronous sets how often SQLite syncs to disk (PRAGMA synchronous):
+// OFF, NORMAL, FULL or EXTRA. WithWALMode defaults to NORMAL, which can lose
+// the last transactions on power loss but never corrupts the database; use
+// FULL where every committed event must survive.
+func WithSynchronous(mode string) EventStoreOption {
+	return WithPragma("synchronous", mode)
+}
+
+// connectionPragmas returns the pragmas to apply to every connection.
+func connectionPragmas(config eventStoreConfig) ([]pragma, error) {
+	var pragmas []pragma
+	if config.walMode {
+		pragmas = append(pragmas,
+			pragma{name: "synchronous", value: "NORMAL"},
+			pragma{name: "foreign_keys", value: "ON"},
+		)
+	}
+	for _, p := range config.pragmas {
+		if !pragmaName.MatchString(p.name) {
+			return nil, fmt.Errorf("invalid pragma name %q", p.name)
+		}
+		if !pragmaValue.MatchString(p.value) {
+			return nil, fmt.Errorf("invalid value %q for pragma %s", p.value, p.name)
+		}
+		pragmas = append(pragmas, p)
+	}
+	return pragmas, nil
+}
+
+// pragmaHook returns a connect hook applying pragmas, or nil if there are none.
+func pragmaHook(pragmas []pragma) func(conn *sql.Conn) error {
+	if len(pragmas) == 0 {
+		return nil
+	}
+	return func(conn *sql.Conn) error {
+		for _, p := range pragmas {
+			if _, err := conn.ExecContext(context.Background(), "PRAGMA "+p.name+" = "+p.value); err != nil {
+				return fmt.Errorf("failed to set pragma %s: %w", p.name, err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,102 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestPragmas(t *testing.T) {
+	store, err := sqlite.NewEventStore(
+		sqlite.WithDSN(filepath.Join(t.TempDir(), "pragmas.db")),
+		sqlite.WithBusyTimeout(5*time.Second),
+		sqlite.WithSynchronous("FULL"),
+		sqlite.WithPragma("cache_size", "-4321"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer store.Close()
+
+	// Pin several connections at once so the pool has to open new ones
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := store.DB().Conn(ctx)
+		if err != nil {
+			t.Fatalf("failed to get connection: %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+
+	for i, conn := range conns {
+		var busyTimeout, synchronous, cacheSize, foreignKeys int
+		for _, read := range []struct {
+			pragma string
+			dest   *int
+		}{
+			{"busy_timeout", &busyTimeout},
+			{"synchronous", &synchronous},
+			{"cache_size", &cacheSize},
+			{"foreign_keys", &foreignKeys},
+		} {
+			if err := conn.QueryRowContext(ctx, "PRAGMA "+read.pragma).Scan(read.dest); err != nil {
+				t.Fatalf("failed to read %s: %v", read.pragma, err)
+			}
+		}
+		if busyTimeout != 5000 {
+			t.Errorf("connection %d: expected busy_timeout 5000, got %d", i, busyTimeout)
+		}
+		// WithSynchronous overrides the NORMAL default of WAL mode (FULL = 2)
+		if synchronous != 2 {
+			t.Errorf("connection %d: expected synchronous FULL, got %d", i, synchronous)
+		}
+		if cacheSize != -4321 {
+			t.Errorf("connection %d: expected cache_size -4321, got %d", i, cacheSize)
+		}
+		if foreignKeys != 1 {
+			t.Errorf("connection %d: expected foreign keys on, got %d", i, foreignKeys)
+		}
+	}
+}
+
+func TestPragmasRejected(t *testing.T) {
+	for name, opt := range map[string]sqlite.EventStoreOption{
+		"InvalidName":  sqlite.WithPragma("cache_size; DROP TABLE events", "1"),
+		"InvalidValue": sqlite.WithPragma("cache_size", "1; DROP TABLE events"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			store, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), opt)
+			if err == nil {
+				store.Close()
+				t.Fatal("expected the pragma to be rejected")
+			}
+		})
+	}
+
+	t.Run("WithDB", func(t *testing.T) {
+		db, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		defer db.Close()
+
+		store, err := sqlite.NewEventStore(sqlite.WithDB(db), sqlite.WithBusyTimeout(time.Second),
+			sqlite.WithPragma("cache_size", "-2000"), sqlite.WithWALAutoCheckpoint(100))
+		if err == nil {
+			store.Close()
+			t.Fatal("expected WithBusyTimeout to be rejected together with WithDB")
+		}
+		for _, option := range []string{"WithBusyTimeout", "WithPragma", "WithWALAutoCheckpoint", "WithMaxOpenConns"} {
+			if !strings.Contains(err.Error(), option) {
+				t.Errorf("expected the error to name %s, got %v", option, err)
+			}
+		}
+	})
+}
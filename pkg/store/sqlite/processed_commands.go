@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// ListProcessedCommands returns up to limit unexpired command idempotency
+// records with a command ID after afterCommandID, ordered by command ID. It
+// implements store.ProcessedCommandCopier.
+func (s *EventStore) ListProcessedCommands(ctx context.Context, afterCommandID string, limit int) ([]*store.ProcessedCommand, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.QueryContext(ctx, s.tables.rewrite(`
+		SELECT command_id, aggregate_id, processed_at, expires_at, event_ids
+		FROM processed_commands
+		WHERE command_id > ? AND expires_at > ?
+		ORDER BY command_id
+		LIMIT ?
+	`), afterCommandID, domain.Now().Unix(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processed commands: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var commands []*store.ProcessedCommand
+	for rows.Next() {
+		var (
+			record                 = &store.ProcessedCommand{}
+			processedAt, expiresAt int64
+			eventIDs               string
+		)
+		if err := rows.Scan(&record.CommandID, &record.AggregateID, &processedAt, &expiresAt, &eventIDs); err != nil {
+			return nil, fmt.Errorf("failed to scan processed command: %w", err)
+		}
+		if err := json.Unmarshal([]byte(eventIDs), &record.EventIDs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal event IDs of command %s: %w", record.CommandID, err)
+		}
+		record.ProcessedAt = domain.TimeFromUnix(processedAt)
+		record.ExpiresAt = domain.TimeFromUnix(expiresAt)
+		commands = append(commands, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list processed commands: %w", classifyError(err))
+	}
+
+	return commands, nil
+}
+
+// ImportProcessedCommands stores command idempotency records, e.g. copied
+// from another store by store.Migrate, replacing records with the same
+// command ID. It implements store.ProcessedCommandCopier.
+func (s *EventStore) ImportProcessedCommands(ctx context.Context, commands []*store.ProcessedCommand) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", classifyError(err))
+	}
+	defer tx.Rollback()
+
+	for _, command := range commands {
+		eventIDs, err := json.Marshal(command.EventIDs)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event IDs of command %s: %w", command.CommandID, err)
+		}
+		_, err = tx.ExecContext(ctx, s.tables.rewrite(`
+			INSERT INTO processed_commands (command_id, aggregate_id, processed_at, expires_at, event_ids)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(command_id) DO UPDATE SET
+				aggregate_id = excluded.aggregate_id,
+				processed_at = excluded.processed_at,
+				expires_at = excluded.expires_at,
+				event_ids = excluded.event_ids
+		`), command.CommandID, command.AggregateID, command.ProcessedAt.Unix(), command.ExpiresAt.Unix(), string(eventIDs))
+		if err != nil {
+			return fmt.Errorf("failed to import command %s: %w", command.CommandID, classifyError(err))
+		}
+	}
+
+	return classifyError(tx.Commit())
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
+	"log/slog"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/store"
@@ -25,11 +30,27 @@ type SQLiteProjectionBuilder struct {
 	checkpointStore *CheckpointStore
 	statusStore     *ProjectionStatusStore
 	eventStore      store.EventStore
-	handlers        map[string]TransactionalEventHandler
+	handlers        map[string][]TransactionalEventHandler // In registration order
 	resetFunc       func(context.Context, *sql.Tx) error
 	schemaFunc      func(context.Context, *sql.DB) error
 	migrationsFS    fs.FS
 	migrationsPath  string
+	verifySchema    bool
+
+	checkpointEvery    int
+	checkpointInterval time.Duration
+
+	batchSize int
+
+	limits store.HandlerLimits
+	logger *slog.Logger
+
+	deadLetters       *DeadLetterStore
+	deadLetterRetries int
+
+	emits bool // OnWithEmit handlers registered
+
+	mappingErrs []error // Invalid mappings passed to Map
 }
 
 // NewSQLiteProjectionBuilder creates a new SQLite-specific projection builder.
@@ -58,8 +79,12 @@ func NewSQLiteProjectionBuilder(
 	checkpointStore *CheckpointStore,
 	eventStore store.EventStore,
 ) *SQLiteProjectionBuilder {
-	// Create status store
-	statusStore, _ := NewProjectionStatusStore(db)
+	// Create status store, sharing the checkpoint store's table prefix
+	var prefix string
+	if checkpointStore != nil {
+		prefix = checkpointStore.TablePrefix()
+	}
+	statusStore, _ := NewProjectionStatusStore(db, WithStatusTablePrefix(prefix))
 
 	return &SQLiteProjectionBuilder{
 		name:            name,
@@ -67,10 +92,20 @@ func NewSQLiteProjectionBuilder(
 		checkpointStore: checkpointStore,
 		statusStore:     statusStore,
 		eventStore:      eventStore,
-		handlers:        make(map[string]TransactionalEventHandler),
+		handlers:        make(map[string][]TransactionalEventHandler),
 	}
 }
 
+// WithStatusStore makes the projection record its status in statusStore
+// instead of a store of its own, so subscribers registered with
+// ProjectionStatusStore.Subscribe see its status changes as they happen.
+// Give statusStore the checkpoint store's table prefix, as the default store
+// has, so tools reading both tables (such as eventstore-admin) line them up.
+func (b *SQLiteProjectionBuilder) WithStatusStore(statusStore *ProjectionStatusStore) *SQLiteProjectionBuilder {
+	b.statusStore = statusStore
+	return b
+}
+
 // WithSchema registers a function to initialize the projection schema.
 // This is called during Build() to ensure tables exist.
 // Deprecated: Use WithMigrations for version-controlled schema evolution.
@@ -105,10 +140,104 @@ func (b *SQLiteProjectionBuilder) WithMigrations(migrationsFS fs.FS, path string
 	return b
 }
 
+// WithSchemaVerification makes Build compare the projection's tables with the
+// schema its migrations describe, after running them, and fail with
+// migrate.ErrSchemaDrift if they differ. This catches migrations edited after
+// they were applied and hand-edited tables before handlers fail on them.
+// Without it, drift can still be checked with SQLiteProjection.VerifySchema.
+// Requires WithMigrations.
+func (b *SQLiteProjectionBuilder) WithSchemaVerification() *SQLiteProjectionBuilder {
+	b.verifySchema = true
+	return b
+}
+
+// WithCheckpointInterval batches checkpoint writes: instead of writing the
+// checkpoint in every Handle transaction, it is written at most every n events
+// or every d, whichever comes first (a zero value disables that trigger). The
+// projection tracks the high-water mark in memory in between. With d set, a
+// background ticker also flushes it every d, so a projection that falls quiet
+// mid-batch still persists its progress; call Close on shutdown to stop the
+// ticker and persist the rest.
+//
+// This roughly halves the write volume at high event rates, at the cost of
+// at-least-once delivery: after a crash, the events handled since the last
+// persisted checkpoint are handled again. Handlers MUST be idempotent (e.g.,
+// use upserts or guard on the event version) when batching is enabled. Queries
+// waiting for read-your-writes consistency also observe the persisted
+// checkpoint, so they may wait up to one interval longer.
+//
+// Example:
+//
+//	projection, err := sqlite.NewSQLiteProjectionBuilder("account-balance", db, checkpointStore, eventStore).
+//	    WithCheckpointInterval(100, time.Second).
+//	    OnWithTx("account.v1.MoneyDeposited", upsertBalance).
+//	    Build()
+//	defer projection.(*sqlite.SQLiteProjection).Close()
+func (b *SQLiteProjectionBuilder) WithCheckpointInterval(n int, d time.Duration) *SQLiteProjectionBuilder {
+	b.checkpointEvery = n
+	b.checkpointInterval = d
+	return b
+}
+
+// WithBatchSize makes HandleBatch and Rebuild apply up to n events in one
+// transaction with a single checkpoint write, instead of a transaction per
+// event. Batches of 100 rebuild about four times faster on a file database
+// (see BenchmarkSQLiteProjection_Rebuild). A handler error rolls back the
+// whole batch, so the checkpoint never covers events that were not applied.
+func (b *SQLiteProjectionBuilder) WithBatchSize(n int) *SQLiteProjectionBuilder {
+	b.batchSize = n
+	return b
+}
+
+// WithHandlerTimeout cancels a handler's context after d. The transaction of a
+// timed-out handler is rolled back before Handle returns store.ErrHandlerTimeout,
+// which is retryable, so the event bus redelivers the event. A wedged handler
+// no longer stalls the projection; if it is still running, every further use
+// of its transaction fails with sql.ErrTxDone.
+func (b *SQLiteProjectionBuilder) WithHandlerTimeout(d time.Duration) *SQLiteProjectionBuilder {
+	b.limits.Timeout = d
+	return b
+}
+
+// WithSlowHandlerWarning logs handlers that take at least threshold and
+// reports them to metrics (may be nil), e.g. an *observability.Metrics.
+func (b *SQLiteProjectionBuilder) WithSlowHandlerWarning(threshold time.Duration, metrics store.ProjectionMetrics) *SQLiteProjectionBuilder {
+	b.limits.SlowThreshold = threshold
+	b.limits.Metrics = metrics
+	return b
+}
+
+// WithLogger logs the projection's warnings and errors, such as dead-lettered
+// events, slow handlers and failed checkpoint flushes, to logger (default
+// slog.Default()).
+func (b *SQLiteProjectionBuilder) WithLogger(logger *slog.Logger) *SQLiteProjectionBuilder {
+	b.logger = logger
+	b.limits.Logger = logger
+	return b
+}
+
+// WithDeadLetter stops a failing handler from stalling the projection: an
+// event the handler still fails on after retries more attempts is recorded in
+// deadLetters, with its error and attempt count, and the checkpoint moves past
+// it. Operators list the dead letters with DeadLetterStore.ListDeadLetters and,
+// once the cause is fixed, retry them with SQLiteProjection.ReplayDeadLetter.
+//
+// Only handler errors are dead-lettered; failures to begin or commit the
+// transaction are returned as before. Rebuilds are not affected, as they have
+// their own policy (see store.OnEventError).
+func (b *SQLiteProjectionBuilder) WithDeadLetter(deadLetters *DeadLetterStore, retries int) *SQLiteProjectionBuilder {
+	b.deadLetters = deadLetters
+	b.deadLetterRetries = retries
+	return b
+}
+
 // On registers an event handler registration with automatic transaction handling.
 //
 // The handler can access the transaction via sqlite.TxFromContext(ctx).
 // Transaction begin, checkpoint update, and commit are handled automatically.
+// Several handlers may be registered for one event type, e.g. for independent
+// views of a composite read model; they run in registration order in the same
+// transaction, so an error in any of them rolls back the writes of all.
 //
 // Example:
 //
@@ -119,23 +248,50 @@ func (b *SQLiteProjectionBuilder) WithMigrations(migrationsFS fs.FS, path string
 //	}))
 func (b *SQLiteProjectionBuilder) On(registration store.EventHandlerRegistration) *SQLiteProjectionBuilder {
 	// Wrap the handler to inject transaction
-	b.handlers[registration.EventType] = func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+	b.addHandler(domain.CanonicalEventType(registration.EventType), func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
 		// Create a context that carries the transaction
 		txCtx := context.WithValue(ctx, txContextKey{}, tx)
 
 		// Call the original handler with the transaction context
 		return registration.Handler(txCtx, envelope)
-	}
+	})
 	return b
 }
 
 // OnWithTx registers a handler that directly receives the transaction.
 // This is useful when you need more control over the transaction.
+// Like On, it adds to the handlers already registered for eventType.
 func (b *SQLiteProjectionBuilder) OnWithTx(eventType string, handler TransactionalEventHandler) *SQLiteProjectionBuilder {
-	b.handlers[eventType] = handler
+	b.addHandler(eventType, handler)
 	return b
 }
 
+// addHandler registers handler after the handlers of eventType.
+func (b *SQLiteProjectionBuilder) addHandler(eventType string, handler TransactionalEventHandler) {
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// chainHandlers combines the handlers of each event type into one calling
+// them in order and stopping at the first error.
+func chainHandlers(handlers map[string][]TransactionalEventHandler) map[string]TransactionalEventHandler {
+	chained := make(map[string]TransactionalEventHandler, len(handlers))
+	for eventType, registered := range handlers {
+		if len(registered) == 1 {
+			chained[eventType] = registered[0]
+			continue
+		}
+		chained[eventType] = func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+			for _, handler := range registered {
+				if err := handler(ctx, tx, envelope); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return chained
+}
+
 // OnReset registers a function to reset the projection state.
 // The function receives a transaction to perform the reset.
 func (b *SQLiteProjectionBuilder) OnReset(resetFunc func(context.Context, *sql.Tx) error) *SQLiteProjectionBuilder {
@@ -145,13 +301,44 @@ func (b *SQLiteProjectionBuilder) OnReset(resetFunc func(context.Context, *sql.T
 
 // Build creates the final Projection implementation with full SQLite integration.
 func (b *SQLiteProjectionBuilder) Build() (store.Projection, error) {
+	if err := b.checkEmitter(); err != nil {
+		return nil, err
+	}
+	if err := errors.Join(b.mappingErrs...); err != nil {
+		return nil, fmt.Errorf("invalid row mapping: %w", err)
+	}
+
 	// Run migrations if provided (preferred approach)
+	var migrator *migrate.Migrator
 	if b.migrationsFS != nil {
-		if err := runProjectionMigrations(b.db, b.migrationsFS, b.migrationsPath, b.name); err != nil {
+		var err error
+		migrator, err = runProjectionMigrations(b.db, b.migrationsFS, b.migrationsPath, b.checkpointStore.TablePrefix(), b.name)
+		if err != nil {
 			return nil, fmt.Errorf("failed to run migrations: %w", err)
 		}
 	}
 
+	if b.deadLetterRetries < 0 {
+		return nil, fmt.Errorf("invalid dead letter retries %d: must not be negative", b.deadLetterRetries)
+	}
+	logger := b.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	if b.verifySchema {
+		if migrator == nil {
+			return nil, fmt.Errorf("schema verification requires WithMigrations")
+		}
+		drifts, err := migrator.VerifySchema(b.db)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify schema: %w", err)
+		}
+		if len(drifts) > 0 {
+			return nil, fmt.Errorf("%w in projection %s: %s", migrate.ErrSchemaDrift, b.name, formatDrifts(drifts))
+		}
+	}
+
 	// Initialize schema if provided (deprecated, but still supported)
 	if b.schemaFunc != nil {
 		if err := b.schemaFunc(context.Background(), b.db); err != nil {
@@ -165,8 +352,25 @@ func (b *SQLiteProjectionBuilder) Build() (store.Projection, error) {
 		checkpointStore: b.checkpointStore,
 		statusStore:     b.statusStore,
 		eventStore:      b.eventStore,
-		handlers:        b.handlers,
+		handlers:        chainHandlers(b.handlers),
 		resetFunc:       b.resetFunc,
+		limits:          b.limits,
+		logger:          logger,
+		migrator:        migrator,
+
+		deadLetters:       b.deadLetters,
+		deadLetterRetries: b.deadLetterRetries,
+
+		checkpointEvery:    b.checkpointEvery,
+		checkpointInterval: b.checkpointInterval,
+		lastFlush:          time.Now(),
+
+		batchSize: b.batchSize,
+	}
+	if b.checkpointInterval > 0 {
+		projection.stopFlush = make(chan struct{})
+		projection.flushDone = make(chan struct{})
+		go projection.flushPeriodically()
 	}
 
 	// Set initial status to READY
@@ -188,6 +392,26 @@ type SQLiteProjection struct {
 	eventStore      store.EventStore
 	handlers        map[string]TransactionalEventHandler
 	resetFunc       func(context.Context, *sql.Tx) error
+	limits          store.HandlerLimits
+	logger          *slog.Logger
+	migrator        *migrate.Migrator // Nil without WithMigrations
+
+	deadLetters       *DeadLetterStore // Nil without WithDeadLetter
+	deadLetterRetries int
+
+	// Checkpoint batching (see WithCheckpointInterval)
+	checkpointEvery    int
+	checkpointInterval time.Duration
+	checkpointMu       sync.Mutex                  // Guards the fields below; never held across a query
+	flushMu            sync.Mutex                  // Serializes FlushCheckpoint and Reset
+	pending            *store.ProjectionCheckpoint // Handled but not yet persisted
+	pendingCount       int
+	lastFlush          time.Time
+	stopFlush          chan struct{} // Closed by Close to stop flushPeriodically
+	flushDone          chan struct{} // Closed when flushPeriodically returns
+	closeOnce          sync.Once
+
+	batchSize int // Events per HandleBatch transaction (see WithBatchSize)
 }
 
 // Name returns the projection name.
@@ -195,14 +419,69 @@ func (p *SQLiteProjection) Name() string {
 	return p.name
 }
 
+// EventTypes returns the event types the projection has handlers for, sorted,
+// e.g. for cqrs.WithHandledEventTypes.
+func (p *SQLiteProjection) EventTypes() []string {
+	eventTypes := make([]string, 0, len(p.handlers))
+	for eventType := range p.handlers {
+		eventTypes = append(eventTypes, eventType)
+	}
+	slices.Sort(eventTypes)
+	return eventTypes
+}
+
+// VerifySchema compares the projection's tables with the schema its applied
+// migrations describe and returns the differences. It returns nil for
+// projections built without WithMigrations.
+func (p *SQLiteProjection) VerifySchema() ([]migrate.SchemaDrift, error) {
+	if p.migrator == nil {
+		return nil, nil
+	}
+	return p.migrator.VerifySchema(p.db)
+}
+
 // Handle processes an event with automatic transaction and checkpoint management.
 func (p *SQLiteProjection) Handle(ctx context.Context, envelope *domain.EventEnvelope) error {
-	handler, exists := p.handlers[envelope.EventType]
+	handler, exists := p.handlers[domain.CanonicalEventType(envelope.EventType)]
 	if !exists {
 		// No handler registered for this event type - skip it
 		return nil
 	}
+	if p.deadLetters == nil || domain.IsReplay(ctx) {
+		return p.handle(ctx, handler, envelope)
+	}
+
+	var (
+		err      error
+		attempts int
+	)
+	for attempts <= p.deadLetterRetries {
+		attempts++
+		err = p.handle(ctx, handler, envelope)
+		var failed *handlerError
+		if err == nil || !errors.As(err, &failed) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return p.deadLetter(ctx, envelope, err, attempts)
+}
 
+// handlerError is a handler failure, as opposed to a failure to run the
+// handler's transaction.
+type handlerError struct {
+	err error
+}
+
+func (e *handlerError) Error() string {
+	return fmt.Sprintf("handler failed: %v", e.err)
+}
+
+func (e *handlerError) Unwrap() error {
+	return e.err
+}
+
+// handle runs handler and updates the checkpoint in one transaction.
+func (p *SQLiteProjection) handle(ctx context.Context, handler TransactionalEventHandler, envelope *domain.EventEnvelope) error {
 	// Begin transaction
 	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -211,35 +490,307 @@ func (p *SQLiteProjection) Handle(ctx context.Context, envelope *domain.EventEnv
 	defer tx.Rollback() // Rollback if we don't commit
 
 	// Call handler with transaction
-	if err := handler(ctx, tx, envelope); err != nil {
-		return fmt.Errorf("handler failed: %w", err)
+	err = p.limits.Run(ctx, p.name, envelope.EventType, func(ctx context.Context) error {
+		return handler(ctx, tx, envelope)
+	})
+	if err != nil {
+		// A timed-out handler may still be running: end the transaction now,
+		// so it cannot write through it any more
+		tx.Rollback()
+		return &handlerError{err: err}
 	}
 
 	// Update checkpoint in same transaction (atomic!)
+	return p.commitCheckpoint(tx, envelope)
+}
+
+// HandleBatch applies envelopes in transactions of up to the batch size set
+// with WithBatchSize (in one transaction without it), writing the checkpoint
+// once per transaction, at its last event.
+//
+// A handler error rolls back the batch it occurred in and is returned, leaving
+// the checkpoint at the last event of the batches committed before it. With
+// WithDeadLetter, the events of a failed batch are handled one by one instead,
+// so only the failing event is retried and dead-lettered.
+func (p *SQLiteProjection) HandleBatch(ctx context.Context, envelopes []*domain.EventEnvelope) error {
+	if len(envelopes) == 0 {
+		return nil
+	}
+	size := p.batchSize
+	if size <= 0 {
+		size = len(envelopes)
+	}
+
+	for batch := range slices.Chunk(envelopes, size) {
+		err := p.handleBatch(ctx, batch)
+		var failed *handlerError
+		if err == nil || !errors.As(err, &failed) || p.deadLetters == nil || domain.IsReplay(ctx) || ctx.Err() != nil {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, envelope := range batch {
+			if err := p.Handle(ctx, envelope); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handleBatch runs the handlers of batch and updates the checkpoint to its
+// last event in one transaction.
+func (p *SQLiteProjection) handleBatch(ctx context.Context, batch []*domain.EventEnvelope) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, envelope := range batch {
+		handler, exists := p.handlers[domain.CanonicalEventType(envelope.EventType)]
+		if !exists {
+			continue
+		}
+		err := p.limits.Run(ctx, p.name, envelope.EventType, func(ctx context.Context) error {
+			return handler(ctx, tx, envelope)
+		})
+		if err != nil {
+			tx.Rollback() // see handle
+			return fmt.Errorf("event %s: %w", envelope.ID, &handlerError{err: err})
+		}
+	}
+
+	return p.commitCheckpoint(tx, batch[len(batch)-1])
+}
+
+// deadLetter records an event the handler gave up on and moves the checkpoint
+// past it.
+func (p *SQLiteProjection) deadLetter(ctx context.Context, envelope *domain.EventEnvelope, cause error, attempts int) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	event := envelope.Event
+	err = p.deadLetters.addInTx(ctx, tx, &DeadLetter{
+		ProjectionName: p.name,
+		EventID:        event.ID,
+		EventType:      event.EventType,
+		AggregateID:    event.AggregateID,
+		Position:       event.Position,
+		Error:          errors.Unwrap(cause).Error(),
+		Attempts:       attempts,
+		FailedAt:       domain.Now(),
+		Event:          &event,
+	})
+	if err != nil {
+		return fmt.Errorf("%w (%w)", cause, err)
+	}
+	if err := p.commitCheckpoint(tx, envelope); err != nil {
+		return err
+	}
+
+	p.logger.Warn("dead-lettered event",
+		"projection", p.name, "event_id", event.ID, "event_type", event.EventType,
+		"attempts", attempts, "error", errors.Unwrap(cause))
+	return nil
+}
+
+// commitCheckpoint moves the checkpoint to envelope and commits tx, which then
+// holds the projection update and the checkpoint atomically.
+//
+// An event without a global position, one never read from the event store,
+// commits tx without moving the checkpoint.
+func (p *SQLiteProjection) commitCheckpoint(tx *sql.Tx, envelope *domain.EventEnvelope) error {
+	if envelope.Position == 0 {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
 	checkpoint := &store.ProjectionCheckpoint{
 		ProjectionName: p.name,
-		Position:       envelope.Version,
+		Position:       envelope.Position,
 		LastEventID:    envelope.ID,
 		UpdatedAt:      domain.Now(),
 	}
-	if err := p.checkpointStore.SaveInTx(tx, checkpoint); err != nil {
-		return fmt.Errorf("failed to save checkpoint: %w", err)
+	writeCheckpoint := p.checkpointDue()
+	if writeCheckpoint {
+		if err := p.checkpointStore.SaveInTx(tx, checkpoint); err != nil {
+			return fmt.Errorf("failed to save checkpoint: %w", err)
+		}
 	}
 
-	// Commit transaction (both projection update and checkpoint atomically)
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	p.checkpointHandled(checkpoint, writeCheckpoint)
 	return nil
 }
 
+// ReplayDeadLetter hands a dead-lettered event of this projection to its
+// handler again, e.g. after fixing the handler or the data it failed on. On
+// success the dead letter is removed in the handler's transaction; on failure
+// its attempt count and error are updated and the error is returned. The
+// checkpoint is left alone, so handlers must tolerate the event arriving after
+// later ones.
+func (p *SQLiteProjection) ReplayDeadLetter(ctx context.Context, id int64) error {
+	if p.deadLetters == nil {
+		return errors.New("projection has no dead letter store (use WithDeadLetter)")
+	}
+	deadLetter, err := p.deadLetters.GetDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	if deadLetter.ProjectionName != p.name {
+		return fmt.Errorf("%w: %d in projection %s", ErrDeadLetterNotFound, id, p.name)
+	}
+
+	envelope := &domain.EventEnvelope{Event: *deadLetter.Event}
+	handler, exists := p.handlers[domain.CanonicalEventType(envelope.EventType)]
+	if !exists {
+		return fmt.Errorf("projection %s has no handler for %s", p.name, envelope.EventType)
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = p.limits.Run(ctx, p.name, envelope.EventType, func(ctx context.Context) error {
+		return handler(ctx, tx, envelope)
+	})
+	if err != nil {
+		tx.Rollback()
+		if recordErr := p.deadLetters.recordAttempt(ctx, id, err); recordErr != nil {
+			return fmt.Errorf("handler failed: %w (%w)", err, recordErr)
+		}
+		return fmt.Errorf("handler failed: %w", err)
+	}
+
+	if err := p.deadLetters.deleteInTx(ctx, tx, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// batchesCheckpoints reports whether checkpoint batching is enabled.
+func (p *SQLiteProjection) batchesCheckpoints() bool {
+	return p.checkpointEvery > 0 || p.checkpointInterval > 0
+}
+
+// checkpointDue reports whether the next handled event should write the checkpoint.
+func (p *SQLiteProjection) checkpointDue() bool {
+	if !p.batchesCheckpoints() {
+		return true
+	}
+
+	p.checkpointMu.Lock()
+	defer p.checkpointMu.Unlock()
+
+	if p.checkpointEvery > 0 && p.pendingCount+1 >= p.checkpointEvery {
+		return true
+	}
+	return p.checkpointInterval > 0 && time.Since(p.lastFlush) >= p.checkpointInterval
+}
+
+// checkpointHandled records a committed event in the in-memory high-water mark.
+func (p *SQLiteProjection) checkpointHandled(checkpoint *store.ProjectionCheckpoint, persisted bool) {
+	if !p.batchesCheckpoints() {
+		return
+	}
+
+	p.checkpointMu.Lock()
+	defer p.checkpointMu.Unlock()
+
+	if persisted {
+		p.pending = nil
+		p.pendingCount = 0
+		p.lastFlush = time.Now()
+		return
+	}
+	p.pending = checkpoint
+	p.pendingCount++
+}
+
+// FlushCheckpoint persists the in-memory checkpoint high-water mark when
+// checkpoint batching is enabled. It is a no-op if nothing is pending.
+func (p *SQLiteProjection) FlushCheckpoint(ctx context.Context) error {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.checkpointMu.Lock()
+	pending := p.pending
+	p.checkpointMu.Unlock()
+	if pending == nil {
+		return nil
+	}
+
+	// Handle may persist a later checkpoint meanwhile; Advance keeps it
+	if err := p.checkpointStore.Advance(pending); err != nil {
+		return fmt.Errorf("failed to flush checkpoint: %w", err)
+	}
+
+	p.checkpointMu.Lock()
+	defer p.checkpointMu.Unlock()
+	if p.pending == pending {
+		p.pending = nil
+		p.pendingCount = 0
+	}
+	p.lastFlush = time.Now()
+	return nil
+}
+
+// flushPeriodically flushes the pending checkpoint every checkpointInterval
+// until Close.
+func (p *SQLiteProjection) flushPeriodically() {
+	defer close(p.flushDone)
+
+	ticker := time.NewTicker(p.checkpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.FlushCheckpoint(context.Background()); err != nil {
+				p.logger.Error("failed to flush checkpoint", "projection", p.name, "error", err)
+			}
+		case <-p.stopFlush:
+			return
+		}
+	}
+}
+
+// Close stops the background checkpoint flush and flushes any pending
+// checkpoint. Call it on shutdown when checkpoint batching is enabled.
+func (p *SQLiteProjection) Close() error {
+	p.closeOnce.Do(func() {
+		if p.stopFlush != nil {
+			close(p.stopFlush)
+			<-p.flushDone
+		}
+	})
+	return p.FlushCheckpoint(context.Background())
+}
+
 // Reset resets the projection state.
 func (p *SQLiteProjection) Reset(ctx context.Context) error {
 	if p.resetFunc == nil {
 		return nil // No reset function registered
 	}
 
+	// Keep background flushes from bringing back the dropped checkpoint
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
 	// Reset in a transaction
 	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -256,15 +807,102 @@ func (p *SQLiteProjection) Reset(ctx context.Context) error {
 		return fmt.Errorf("failed to delete checkpoint: %w", err)
 	}
 
+	// The events left behind are handled again from the start
+	if p.deadLetters != nil {
+		if err := p.deadLetters.clearInTx(ctx, tx, p.name); err != nil {
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit reset: %w", err)
 	}
 
+	// Drop the unflushed high-water mark along with the checkpoint
+	p.checkpointMu.Lock()
+	p.pending = nil
+	p.pendingCount = 0
+	p.checkpointMu.Unlock()
+
 	return nil
 }
 
 // Rebuild rebuilds the projection from the event store with status tracking.
-func (p *SQLiteProjection) Rebuild(ctx context.Context) error {
+// By default an event a handler fails on aborts the rebuild; use
+// store.OnEventError to skip or quarantine such events instead.
+func (p *SQLiteProjection) Rebuild(ctx context.Context, opts ...store.RebuildOption) error {
+	return p.rebuild(ctx, nil, opts)
+}
+
+// RebuildWithProgress rebuilds the projection in the background and streams
+// progress updates, so callers such as a CLI or an HTTP endpoint can show a
+// live progress bar instead of polling the status store.
+//
+// The progress channel receives an update every 100 events and a final update
+// once the rebuild completes; it is closed when the rebuild ends. Updates are
+// dropped rather than blocking the rebuild if the receiver falls behind. The
+// error channel receives exactly one value (nil on success) and is then closed.
+//
+// TotalEvents and EstimatedETA are only set when the event store implements
+// store.EventCounter.
+//
+// Example:
+//
+//	progress, errc := projection.RebuildWithProgress(ctx)
+//	for p := range progress {
+//	    fmt.Printf("%d/%d events (%.0f/s)\n", p.EventsProcessed, p.TotalEvents, p.EventsPerSecond)
+//	}
+//	if err := <-errc; err != nil {
+//	    return err
+//	}
+func (p *SQLiteProjection) RebuildWithProgress(ctx context.Context, opts ...store.RebuildOption) (<-chan store.RebuildProgress, <-chan error) {
+	progress := make(chan store.RebuildProgress, 16)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(progress)
+
+		errc <- p.rebuild(ctx, func(update store.RebuildProgress, final bool) {
+			if final {
+				// Always deliver the final update
+				select {
+				case progress <- update:
+				case <-ctx.Done():
+				}
+				return
+			}
+			select {
+			case progress <- update:
+			default:
+			}
+		}, opts)
+	}()
+
+	return progress, errc
+}
+
+// rebuild resets the projection and replays all events, reporting progress to
+// report (if non-nil) every 100 events and once more when done.
+func (p *SQLiteProjection) rebuild(ctx context.Context, report func(update store.RebuildProgress, final bool), opts []store.RebuildOption) error {
+	options, err := store.NewRebuildOptions(opts...)
+	if err != nil {
+		return err
+	}
+	if err := options.Start(ctx, p.name); err != nil {
+		return err
+	}
+	failures := store.RebuildReport{ProjectionName: p.name}
+	startedAt := domain.Now()
+
+	// Count events upfront so progress has a meaningful ETA
+	var totalEvents int64
+	if counter, ok := p.eventStore.(store.EventCounter); ok {
+		if count, err := counter.CountEvents(); err == nil {
+			totalEvents = count
+		}
+	}
+
 	// Set status to REBUILDING
 	rebuildState := &store.ProjectionState{
 		ProjectionName: p.name,
@@ -273,8 +911,8 @@ func (p *SQLiteProjection) Rebuild(ctx context.Context) error {
 		UpdatedAt:      domain.Now(),
 		Progress: &store.RebuildProgress{
 			EventsProcessed: 0,
-			TotalEvents:     0, // Unknown
-			StartedAt:       domain.Now(),
+			TotalEvents:     totalEvents,
+			StartedAt:       startedAt,
 		},
 	}
 	if err := p.statusStore.Save(rebuildState); err != nil {
@@ -293,69 +931,153 @@ func (p *SQLiteProjection) Rebuild(ctx context.Context) error {
 		return fmt.Errorf("failed to reset projection: %w", err)
 	}
 
-	// Replay all events from EventStore
-	position := int64(0)
-	batchSize := 1000
+	// Replay all events from EventStore; handlers see domain.IsReplay(ctx)
+	ctx = domain.WithReplay(ctx)
 	eventsProcessed := int64(0)
 
-	for {
-		events, err := p.eventStore.LoadAllEvents(position, batchSize)
-		if err != nil {
+	// Events are streamed one at a time and applied in batches (see
+	// WithBatchSize), so memory stays bounded however large the store is
+	batchSize := max(p.batchSize, 1)
+	batch := make([]*domain.EventEnvelope, 0, batchSize)
+	applyBatch := func() error {
+		if err := p.applyRebuildBatch(ctx, batch, options, &failures); err != nil {
 			// Set status to FAILED
 			_ = p.statusStore.Save(&store.ProjectionState{
 				ProjectionName: p.name,
 				Status:         store.ProjectionStatusFailed,
-				Message:        fmt.Sprintf("Failed to load events: %v", err),
+				Message:        fmt.Sprintf("Failed to handle event: %v", err),
 				UpdatedAt:      domain.Now(),
 			})
-			return fmt.Errorf("failed to load events: %w", err)
+			return err
 		}
 
-		if len(events) == 0 {
-			break
+		// Update progress every 100 events
+		previous := eventsProcessed
+		eventsProcessed += int64(len(batch))
+		batch = batch[:0]
+		if eventsProcessed/100 > previous/100 {
+			update := rebuildProgress(startedAt, eventsProcessed, totalEvents)
+			_ = p.statusStore.UpdateProgress(p.name, &update)
+			if report != nil {
+				report(update, false)
+			}
 		}
+		return nil
+	}
 
-		for _, event := range events {
-			envelope := &domain.EventEnvelope{Event: *event}
-			if err := p.Handle(ctx, envelope); err != nil {
-				// Set status to FAILED
-				_ = p.statusStore.Save(&store.ProjectionState{
-					ProjectionName: p.name,
-					Status:         store.ProjectionStatusFailed,
-					Message:        fmt.Sprintf("Failed to handle event: %v", err),
-					UpdatedAt:      domain.Now(),
-				})
-				return fmt.Errorf("failed to handle event during rebuild: %w", err)
-			}
-			position++
-			eventsProcessed++
-
-			// Update progress every 100 events
-			if eventsProcessed%100 == 0 {
-				_ = p.statusStore.UpdateProgress(p.name, &store.RebuildProgress{
-					EventsProcessed: eventsProcessed,
-					TotalEvents:     0, // Unknown
-					StartedAt:       rebuildState.Progress.StartedAt,
-				})
+	for event, err := range store.StreamAllEvents(ctx, p.eventStore, 1, rebuildBatchSize) {
+		if err != nil {
+			message := fmt.Sprintf("Failed to load events: %v", err)
+			if ctx.Err() != nil {
+				message = fmt.Sprintf("Rebuild cancelled: %v", ctx.Err())
+				err = fmt.Errorf("rebuild cancelled: %w", ctx.Err())
+			} else {
+				err = fmt.Errorf("failed to load events: %w", err)
 			}
+			// Set status to FAILED
+			_ = p.statusStore.Save(&store.ProjectionState{
+				ProjectionName: p.name,
+				Status:         store.ProjectionStatusFailed,
+				Message:        message,
+				UpdatedAt:      domain.Now(),
+			})
+			return err
 		}
 
-		if len(events) < batchSize {
-			break
+		batch = append(batch, &domain.EventEnvelope{Event: *event})
+		if len(batch) == batchSize {
+			if err := applyBatch(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := applyBatch(); err != nil {
+			return err
 		}
 	}
 
+	if err := p.FlushCheckpoint(ctx); err != nil {
+		_ = p.statusStore.Save(&store.ProjectionState{
+			ProjectionName: p.name,
+			Status:         store.ProjectionStatusFailed,
+			Message:        fmt.Sprintf("Failed to flush checkpoint: %v", err),
+			UpdatedAt:      domain.Now(),
+		})
+		return err
+	}
+
+	if report != nil {
+		report(rebuildProgress(startedAt, eventsProcessed, totalEvents), true)
+	}
+
 	// Set status to READY
+	message := fmt.Sprintf("Rebuild complete - processed %d events", eventsProcessed)
+	if summary := failures.Summary(); summary != "" {
+		message += ", " + summary
+	}
 	_ = p.statusStore.Save(&store.ProjectionState{
 		ProjectionName: p.name,
 		Status:         store.ProjectionStatusReady,
-		Message:        fmt.Sprintf("Rebuild complete - processed %d events", eventsProcessed),
+		Message:        message,
 		UpdatedAt:      domain.Now(),
 	})
 
+	failures.EventsProcessed = eventsProcessed
+	options.Complete(failures)
+
+	return nil
+}
+
+// applyRebuildBatch applies a batch of replayed events in one transaction. If
+// that fails, the batch is rolled back and its events are handled one by one,
+// so the rebuild's event error policy applies to the failing event alone.
+func (p *SQLiteProjection) applyRebuildBatch(ctx context.Context, batch []*domain.EventEnvelope, options *store.RebuildOptions, failures *store.RebuildReport) error {
+	if len(batch) > 1 && p.handleBatch(ctx, batch) == nil {
+		return nil
+	}
+
+	for _, envelope := range batch {
+		if err := p.Handle(ctx, envelope); err != nil {
+			if err := options.HandleEventError(ctx, &envelope.Event, err, failures); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// rebuildBatchSize is how many events a rebuild loads at a time from event
+// stores that cannot stream them one by one.
+const rebuildBatchSize = 1000
+
+// rebuildProgress computes the processing rate and, when the total is known,
+// the estimated completion time.
+func rebuildProgress(startedAt time.Time, processed, total int64) store.RebuildProgress {
+	progress := store.RebuildProgress{
+		EventsProcessed: processed,
+		TotalEvents:     total,
+		StartedAt:       startedAt,
+	}
+
+	elapsed := time.Since(startedAt).Seconds()
+	if elapsed <= 0 || processed == 0 {
+		return progress
+	}
+	progress.EventsPerSecond = float64(processed) / elapsed
+
+	if total > 0 {
+		remaining := total - processed
+		if remaining < 0 {
+			remaining = 0
+		}
+		eta := time.Now().Add(time.Duration(float64(remaining) / progress.EventsPerSecond * float64(time.Second)))
+		progress.EstimatedETA = &eta
+	}
+
+	return progress
+}
+
 // GetCheckpoint returns the current checkpoint position.
 func (p *SQLiteProjection) GetCheckpoint(ctx context.Context) (*store.ProjectionCheckpoint, error) {
 	return p.checkpointStore.Load(p.name)
@@ -389,13 +1111,15 @@ func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
 // migration system as the event store.
 //
 // Projection migrations are tracked separately using a table named:
-// projection_{projectionName}_schema_migrations
-func runProjectionMigrations(db *sql.DB, migrationsFS fs.FS, path string, projectionName string) error {
+// {tablePrefix}projection_{projectionName}_schema_migrations
+// where tablePrefix is the checkpoint store's prefix. The projection's own
+// tables are named by its migrations and are not prefixed.
+func runProjectionMigrations(db *sql.DB, migrationsFS fs.FS, path string, tablePrefix string, projectionName string) (*migrate.Migrator, error) {
 	// Use the existing migration runner with a custom table name
 	// This ensures projection migrations are tracked separately from event store migrations
 	// Sanitize projection name for use in table name (replace hyphens with underscores)
 	sanitizedName := sanitizeTableName(projectionName)
-	tableName := fmt.Sprintf("projection_%s_schema_migrations", sanitizedName)
+	tableName := fmt.Sprintf("%sprojection_%s_schema_migrations", tablePrefix, sanitizedName)
 
 	// Create migrator
 	migrator := migrate.New(db, tableName)
@@ -404,19 +1128,28 @@ func runProjectionMigrations(db *sql.DB, migrationsFS fs.FS, path string, projec
 	// We need to convert fs.FS to embed.FS for the migrator
 	embedFS, ok := migrationsFS.(embed.FS)
 	if !ok {
-		return fmt.Errorf("migrationsFS must be an embed.FS")
+		return nil, fmt.Errorf("migrationsFS must be an embed.FS")
 	}
 
 	if err := migrator.LoadFromFS(embedFS, path); err != nil {
-		return fmt.Errorf("failed to load migrations: %w", err)
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
 	}
 
 	// Run migrations
 	if err := migrator.Up(); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	return nil
+	return migrator, nil
+}
+
+// formatDrifts joins schema drifts into a single line for error messages.
+func formatDrifts(drifts []migrate.SchemaDrift) string {
+	parts := make([]string, len(drifts))
+	for i, drift := range drifts {
+		parts[i] = drift.String()
+	}
+	return strings.Join(parts, "; ")
 }
 
 // sanitizeTableName replaces characters that are invalid in SQLite table names.
@@ -0,0 +1,706 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"github.com/plaenen/eventstore/pkg/store/sqlite/migrate"
+)
+
+func TestSQLiteProjection_RebuildWithProgress(t *testing.T) {
+	eventStore, err := sqlite.NewEventStore(
+		sqlite.WithDSN(":memory:"),
+		sqlite.WithWALMode(false),
+	)
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	const totalEvents = 250
+	for i := 0; i < totalEvents; i++ {
+		aggregateID := fmt.Sprintf("agg-%d", i)
+		err := eventStore.AppendEvents(context.Background(), aggregateID, 0, []*domain.Event{{
+			ID:            fmt.Sprintf("evt-%d", i),
+			AggregateID:   aggregateID,
+			AggregateType: "TestAggregate",
+			EventType:     "test.Happened",
+			Version:       1,
+			Timestamp:     time.Now(),
+			Data:          []byte("data"),
+		}})
+		if err != nil {
+			t.Fatalf("failed to append event %d: %v", i, err)
+		}
+	}
+
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	handled := 0
+	built, err := sqlite.NewSQLiteProjectionBuilder("progress-test", eventStore.DB(), checkpointStore, eventStore).
+		OnWithTx("test.Happened", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+			handled++
+			return nil
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build projection: %v", err)
+	}
+	projection := built.(*sqlite.SQLiteProjection)
+
+	progress, errc := projection.RebuildWithProgress(context.Background())
+
+	var updates []int64
+	var last int64
+	for update := range progress {
+		if update.TotalEvents != totalEvents {
+			t.Errorf("expected total %d, got %d", totalEvents, update.TotalEvents)
+		}
+		if update.EventsProcessed < last {
+			t.Errorf("progress went backwards: %d after %d", update.EventsProcessed, last)
+		}
+		last = update.EventsProcessed
+		updates = append(updates, update.EventsProcessed)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("rebuild failed: %v", err)
+	}
+
+	if handled != totalEvents {
+		t.Errorf("expected %d handled events, got %d", totalEvents, handled)
+	}
+	if len(updates) == 0 || updates[len(updates)-1] != totalEvents {
+		t.Errorf("expected final update with %d events, got %v", totalEvents, updates)
+	}
+
+	status, err := projection.GetStatus(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load status: %v", err)
+	}
+	if !projection.IsReady(context.Background()) {
+		t.Errorf("expected projection to be ready, got %s", status.Status)
+	}
+}
+
+// newCountingProjection builds a projection that upserts a counter per aggregate.
+func newCountingProjection(tb testing.TB, dsn string, configure func(*sqlite.SQLiteProjectionBuilder)) (*sqlite.EventStore, *sqlite.CheckpointStore, *sqlite.SQLiteProjection) {
+	tb.Helper()
+
+	eventStore, err := sqlite.NewEventStore(sqlite.WithDSN(dsn), sqlite.WithWALMode(dsn != ":memory:"))
+	if err != nil {
+		tb.Fatalf("failed to create event store: %v", err)
+	}
+	tb.Cleanup(func() { eventStore.Close() })
+
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		tb.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	builder := sqlite.NewSQLiteProjectionBuilder("counting", eventStore.DB(), checkpointStore, eventStore).
+		WithSchema(func(ctx context.Context, db *sql.DB) error {
+			_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS counts (aggregate_id TEXT PRIMARY KEY, n INTEGER NOT NULL)`)
+			return err
+		}).
+		OnWithTx("test.Happened", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+			_, err := tx.ExecContext(ctx, `INSERT INTO counts (aggregate_id, n) VALUES (?, 1)
+				ON CONFLICT(aggregate_id) DO UPDATE SET n = n + 1`, envelope.AggregateID)
+			return err
+		})
+	if configure != nil {
+		configure(builder)
+	}
+
+	built, err := builder.Build()
+	if err != nil {
+		tb.Fatalf("failed to build projection: %v", err)
+	}
+	return eventStore, checkpointStore, built.(*sqlite.SQLiteProjection)
+}
+
+func testEnvelope(position int64) *domain.EventEnvelope {
+	return &domain.EventEnvelope{Event: domain.Event{
+		ID:            fmt.Sprintf("evt-%d", position),
+		AggregateID:   fmt.Sprintf("agg-%d", position%10),
+		AggregateType: "TestAggregate",
+		EventType:     "test.Happened",
+		Version:       1,
+		Position:      position,
+	}}
+}
+
+func TestSQLiteProjection_CheckpointInterval(t *testing.T) {
+	ctx := context.Background()
+	_, checkpointStore, projection := newCountingProjection(t, ":memory:", func(b *sqlite.SQLiteProjectionBuilder) {
+		b.WithCheckpointInterval(10, 0)
+	})
+
+	for position := int64(1); position <= 25; position++ {
+		if err := projection.Handle(ctx, testEnvelope(position)); err != nil {
+			t.Fatalf("failed to handle event %d: %v", position, err)
+		}
+	}
+
+	checkpoint, err := checkpointStore.Load("counting")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if checkpoint.Position != 20 {
+		t.Errorf("expected checkpoint persisted at 20, got %d", checkpoint.Position)
+	}
+
+	if err := projection.Close(); err != nil {
+		t.Fatalf("failed to close projection: %v", err)
+	}
+	checkpoint, err = checkpointStore.Load("counting")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if checkpoint.Position != 25 {
+		t.Errorf("expected flushed checkpoint at 25, got %d", checkpoint.Position)
+	}
+}
+
+func TestSQLiteProjection_CheckpointIntervalFlushesInBackground(t *testing.T) {
+	ctx := context.Background()
+	_, checkpointStore, projection := newCountingProjection(t, ":memory:", func(b *sqlite.SQLiteProjectionBuilder) {
+		b.WithCheckpointInterval(100, 50*time.Millisecond)
+	})
+	defer projection.Close()
+
+	// A partial batch, after which the projection falls quiet
+	for position := int64(1); position <= 3; position++ {
+		if err := projection.Handle(ctx, testEnvelope(position)); err != nil {
+			t.Fatalf("failed to handle event %d: %v", position, err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		checkpoint, err := checkpointStore.Load("counting")
+		if err == nil && checkpoint.Position == 3 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the partial batch to be flushed at 3, got %+v (%v)", checkpoint, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSQLiteProjection_HandleBatch(t *testing.T) {
+	ctx := context.Background()
+	errPoison := errors.New("cannot upcast event")
+	eventStore, checkpointStore, projection := newCountingProjection(t, ":memory:", func(b *sqlite.SQLiteProjectionBuilder) {
+		b.WithBatchSize(3).
+			OnWithTx("test.Poisoned", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+				return errPoison
+			})
+	})
+	counted := func() int {
+		t.Helper()
+		var n int
+		if err := eventStore.DB().QueryRow(`SELECT COALESCE(SUM(n), 0) FROM counts`).Scan(&n); err != nil {
+			t.Fatalf("failed to count rows: %v", err)
+		}
+		return n
+	}
+
+	var envelopes []*domain.EventEnvelope
+	for position := int64(1); position <= 8; position++ {
+		envelopes = append(envelopes, testEnvelope(position))
+	}
+	envelopes[4].EventType = "test.Poisoned" // Second batch: 4, 5, 6
+
+	if err := projection.HandleBatch(ctx, envelopes); !errors.Is(err, errPoison) {
+		t.Fatalf("expected the poisoned batch to fail, got %v", err)
+	}
+	if n := counted(); n != 3 {
+		t.Errorf("expected only the first batch to be applied, got %d events", n)
+	}
+	checkpoint, err := checkpointStore.Load("counting")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if checkpoint.Position != 3 || checkpoint.LastEventID != "evt-3" {
+		t.Errorf("expected the checkpoint at the last applied event 3, got %d (%s)", checkpoint.Position, checkpoint.LastEventID)
+	}
+
+	envelopes[4].EventType = "test.Happened"
+	if err := projection.HandleBatch(ctx, envelopes[3:]); err != nil {
+		t.Fatalf("failed to handle batch: %v", err)
+	}
+	if n := counted(); n != 8 {
+		t.Errorf("expected all 8 events to be applied, got %d", n)
+	}
+	if checkpoint, err := checkpointStore.Load("counting"); err != nil || checkpoint.Position != 8 {
+		t.Errorf("expected the checkpoint at 8, got %+v (%v)", checkpoint, err)
+	}
+}
+
+func TestSQLiteProjection_RebuildBatches(t *testing.T) {
+	ctx := context.Background()
+	eventStore, checkpointStore, projection := newCountingProjection(t, ":memory:", func(b *sqlite.SQLiteProjectionBuilder) {
+		b.WithBatchSize(4).
+			OnWithTx("test.Poisoned", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+				return errors.New("cannot upcast event")
+			}).
+			OnReset(func(ctx context.Context, tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, `DELETE FROM counts`)
+				return err
+			})
+	})
+	for i := 1; i <= 10; i++ {
+		eventType := "test.Happened"
+		if i == 6 {
+			eventType = "test.Poisoned"
+		}
+		aggregateID := fmt.Sprintf("agg-%d", i)
+		if err := eventStore.AppendEvents(ctx, aggregateID, 0, []*domain.Event{{
+			ID: fmt.Sprintf("evt-%d", i), AggregateID: aggregateID, AggregateType: "TestAggregate",
+			EventType: eventType, Version: 1, Timestamp: time.Unix(1700000000+int64(i), 0), Data: []byte("data"),
+		}}); err != nil {
+			t.Fatalf("failed to append event: %v", err)
+		}
+	}
+
+	var report store.RebuildReport
+	var logs bytes.Buffer
+	err := projection.Rebuild(ctx, store.OnEventError(store.RebuildSkip),
+		store.WithRebuildReport(func(r store.RebuildReport) { report = r }),
+		store.WithRebuildLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+	if err != nil {
+		t.Fatalf("failed to rebuild: %v", err)
+	}
+
+	// The batch with the poisoned event is applied event by event instead
+	var n int
+	if err := eventStore.DB().QueryRow(`SELECT COUNT(*) FROM counts`).Scan(&n); err != nil {
+		t.Fatalf("failed to count rows: %v", err)
+	}
+	if n != 9 || len(report.Skipped) != 1 || report.Skipped[0] != "evt-6" || report.EventsProcessed != 10 {
+		t.Errorf("expected 9 events applied and evt-6 skipped, got %d and %+v", n, report)
+	}
+	for _, want := range []string{"level=WARN", "projection=counting", "event_id=evt-6", "event_type=test.Poisoned", "position=6"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("expected the skip notice to contain %q, got %q", want, logs.String())
+		}
+	}
+	if checkpoint, err := checkpointStore.Load("counting"); err != nil || checkpoint.Position != 10 {
+		t.Errorf("expected the checkpoint at 10, got %+v (%v)", checkpoint, err)
+	}
+}
+
+func TestSQLiteProjection_MultipleHandlers(t *testing.T) {
+	ctx := context.Background()
+	errSummary := errors.New("summary unavailable")
+	failSummary := false
+
+	// Two views of a composite read model react to the same event
+	eventStore, _, projection := newCountingProjection(t, ":memory:", func(b *sqlite.SQLiteProjectionBuilder) {
+		b.WithSchema(func(ctx context.Context, db *sql.DB) error {
+			_, err := db.ExecContext(ctx, `
+				CREATE TABLE IF NOT EXISTS counts (aggregate_id TEXT PRIMARY KEY, n INTEGER NOT NULL);
+				CREATE TABLE IF NOT EXISTS summary (event_id TEXT PRIMARY KEY)`)
+			return err
+		}).
+			On(store.EventHandlerRegistration{
+				EventType: "test.Happened",
+				Handler: func(ctx context.Context, envelope *domain.EventEnvelope) error {
+					tx, _ := sqlite.TxFromContext(ctx)
+					if failSummary {
+						return errSummary
+					}
+					_, err := tx.ExecContext(ctx, `INSERT INTO summary (event_id) VALUES (?)`, envelope.ID)
+					return err
+				},
+			})
+	})
+	rows := func(table string) int {
+		t.Helper()
+		var n int
+		if err := eventStore.DB().QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		return n
+	}
+
+	if err := projection.Handle(ctx, testEnvelope(1)); err != nil {
+		t.Fatalf("failed to handle event: %v", err)
+	}
+	if rows("counts") != 1 || rows("summary") != 1 {
+		t.Errorf("expected both handlers to write, got %d counts and %d summaries", rows("counts"), rows("summary"))
+	}
+
+	// The second handler failing rolls back the first one's writes
+	failSummary = true
+	if err := projection.Handle(ctx, testEnvelope(2)); !errors.Is(err, errSummary) {
+		t.Fatalf("expected the second handler's error, got %v", err)
+	}
+	if rows("counts") != 1 || rows("summary") != 1 {
+		t.Errorf("expected no writes from the failed event, got %d counts and %d summaries", rows("counts"), rows("summary"))
+	}
+}
+
+//go:embed testdata/projection_migrations/*.sql
+var projectionMigrationsFS embed.FS
+
+func TestSQLiteProjection_VerifySchema(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "projections.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	checkpointStore, err := sqlite.NewCheckpointStore(db)
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	build := func() (store.Projection, error) {
+		return sqlite.NewSQLiteProjectionBuilder("balances", db, checkpointStore, nil).
+			WithMigrations(projectionMigrationsFS, "testdata/projection_migrations").
+			WithSchemaVerification().
+			Build()
+	}
+
+	built, err := build()
+	if err != nil {
+		t.Fatalf("failed to build projection: %v", err)
+	}
+	projection := built.(*sqlite.SQLiteProjection)
+	drifts, err := projection.VerifySchema()
+	if err != nil {
+		t.Fatalf("failed to verify schema: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Fatalf("expected no drift, got %v", drifts)
+	}
+
+	// Someone "fixes" the table by hand
+	if _, err := db.Exec("DROP INDEX idx_balances_balance"); err != nil {
+		t.Fatalf("failed to drop index: %v", err)
+	}
+	drifts, err = projection.VerifySchema()
+	if err != nil {
+		t.Fatalf("failed to verify schema: %v", err)
+	}
+	if len(drifts) != 1 || drifts[0].Kind != migrate.DriftMissingIndex || drifts[0].Name != "idx_balances_balance" {
+		t.Errorf("expected missing index, got %v", drifts)
+	}
+
+	if _, err := build(); !errors.Is(err, migrate.ErrSchemaDrift) {
+		t.Errorf("expected Build to fail with ErrSchemaDrift, got %v", err)
+	}
+}
+
+type slowHandlerRecorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *slowHandlerRecorder) RecordProjectionHandlerSlow(ctx context.Context, projectionName, eventType string, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, projectionName+"/"+eventType)
+}
+
+func TestSQLiteProjection_HandlerTimeout(t *testing.T) {
+	ctx := context.Background()
+	recorder := &slowHandlerRecorder{}
+	_, checkpointStore, projection := newCountingProjection(t, ":memory:", func(b *sqlite.SQLiteProjectionBuilder) {
+		b.WithHandlerTimeout(20*time.Millisecond).
+			WithSlowHandlerWarning(10*time.Millisecond, recorder).
+			OnWithTx("test.Stuck", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+	})
+
+	if err := projection.Handle(ctx, testEnvelope(1)); err != nil {
+		t.Fatalf("failed to handle event: %v", err)
+	}
+
+	stuck := testEnvelope(2)
+	stuck.EventType = "test.Stuck"
+	err := projection.Handle(ctx, stuck)
+	if !errors.Is(err, store.ErrHandlerTimeout) {
+		t.Fatalf("expected ErrHandlerTimeout, got %v", err)
+	}
+	if !store.IsRetryable(err) {
+		t.Error("expected a handler timeout to be retryable")
+	}
+
+	checkpoint, err := checkpointStore.Load("counting")
+	if err != nil {
+		t.Fatalf("failed to load checkpoint: %v", err)
+	}
+	if checkpoint.Position != 1 {
+		t.Errorf("expected checkpoint to stay at 1 after the timeout, got %d", checkpoint.Position)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.calls) != 1 || recorder.calls[0] != "counting/test.Stuck" {
+		t.Errorf("expected one slow-handler report for test.Stuck, got %v", recorder.calls)
+	}
+}
+
+func TestSQLiteProjection_TimedOutHandlerLosesTx(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	used := make(chan error, 1)
+	_, _, projection := newCountingProjection(t, ":memory:", func(b *sqlite.SQLiteProjectionBuilder) {
+		b.WithHandlerTimeout(20*time.Millisecond).
+			OnWithTx("test.Stuck", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+				// Ignores its context, then writes after the timeout
+				<-release
+				_, err := tx.Exec("SELECT 1")
+				used <- err
+				return err
+			})
+	})
+
+	stuck := testEnvelope(1)
+	stuck.EventType = "test.Stuck"
+	if err := projection.Handle(ctx, stuck); !errors.Is(err, store.ErrHandlerTimeout) {
+		t.Fatalf("expected ErrHandlerTimeout, got %v", err)
+	}
+
+	close(release)
+	select {
+	case err := <-used:
+		if !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("expected the timed-out handler's transaction to be done, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed-out handler did not finish")
+	}
+
+	// The projection keeps working on the same database
+	if err := projection.Handle(ctx, testEnvelope(2)); err != nil {
+		t.Fatalf("failed to handle event after the timeout: %v", err)
+	}
+}
+
+func BenchmarkSQLiteProjection_Handle(b *testing.B) {
+	benchmarks := []struct {
+		name      string
+		configure func(*sqlite.SQLiteProjectionBuilder)
+	}{
+		{"PerEventCheckpoint", nil},
+		{"Every100Events", func(builder *sqlite.SQLiteProjectionBuilder) {
+			builder.WithCheckpointInterval(100, time.Second)
+		}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			dsn := filepath.Join(b.TempDir(), "bench.db")
+			_, _, projection := newCountingProjection(b, dsn, bm.configure)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := projection.Handle(ctx, testEnvelope(int64(i+1))); err != nil {
+					b.Fatalf("failed to handle event: %v", err)
+				}
+			}
+			b.StopTimer()
+			projection.Close()
+		})
+	}
+}
+
+// BenchmarkSQLiteProjection_Rebuild rebuilds a projection of 2000 events with
+// a transaction per event and in batches.
+func BenchmarkSQLiteProjection_Rebuild(b *testing.B) {
+	const aggregates, eventsPerAggregate = 20, 100
+
+	for _, batchSize := range []int{1, 100, 1000} {
+		b.Run(fmt.Sprintf("BatchSize%d", batchSize), func(b *testing.B) {
+			dsn := filepath.Join(b.TempDir(), "bench.db")
+			eventStore, _, projection := newCountingProjection(b, dsn, func(builder *sqlite.SQLiteProjectionBuilder) {
+				builder.WithBatchSize(batchSize).
+					OnReset(func(ctx context.Context, tx *sql.Tx) error {
+						_, err := tx.ExecContext(ctx, `DELETE FROM counts`)
+						return err
+					})
+			})
+			ctx := context.Background()
+
+			for a := range aggregates {
+				aggregateID := fmt.Sprintf("agg-%d", a)
+				events := make([]*domain.Event, eventsPerAggregate)
+				for v := range events {
+					events[v] = &domain.Event{
+						ID: fmt.Sprintf("evt-%d-%d", a, v), AggregateID: aggregateID, AggregateType: "TestAggregate",
+						EventType: "test.Happened", Version: int64(v + 1), Timestamp: time.Now(), Data: []byte("data"),
+					}
+				}
+				if err := eventStore.AppendEvents(ctx, aggregateID, 0, events); err != nil {
+					b.Fatalf("failed to append events: %v", err)
+				}
+			}
+
+			for b.Loop() {
+				if err := projection.Rebuild(ctx); err != nil {
+					b.Fatalf("failed to rebuild: %v", err)
+				}
+			}
+			b.ReportMetric(float64(aggregates*eventsPerAggregate*b.N)/b.Elapsed().Seconds(), "events/s")
+		})
+	}
+}
+
+func TestSQLiteProjection_OnWithEmit(t *testing.T) {
+	eventStore, err := sqlite.NewEventStore(sqlite.WithDSN(":memory:"), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	db := eventStore.DB()
+
+	checkpointStore, err := sqlite.NewCheckpointStore(db)
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE balances (account_id TEXT PRIMARY KEY, balance INTEGER NOT NULL)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	// Amounts are carried as the event data, e.g. "-15"
+	overdraftsSeen := 0
+	built, err := sqlite.NewSQLiteProjectionBuilder("balances", db, checkpointStore, eventStore).
+		OnWithEmit("test.AmountBooked", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) ([]*domain.Event, error) {
+			var balance int
+			err := tx.QueryRow(`
+				INSERT INTO balances (account_id, balance) VALUES (?, CAST(? AS INTEGER))
+				ON CONFLICT (account_id) DO UPDATE SET balance = balance + excluded.balance
+				RETURNING balance`, envelope.AggregateID, string(envelope.Data)).Scan(&balance)
+			if err != nil || balance >= 0 {
+				return nil, err
+			}
+			if envelope.AggregateID == "broken" {
+				return []*domain.Event{{AggregateID: "alerts-broken"}}, nil // missing event type
+			}
+			return []*domain.Event{{
+				AggregateID:   "alerts-" + envelope.AggregateID,
+				AggregateType: "Alert",
+				EventType:     "test.Overdrawn",
+				Data:          []byte(envelope.AggregateID),
+			}}, nil
+		}).
+		OnWithEmit("test.Overdrawn", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) ([]*domain.Event, error) {
+			overdraftsSeen++
+			return nil, nil
+		}).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build projection: %v", err)
+	}
+	projection := built.(*sqlite.SQLiteProjection)
+
+	book := func(aggregateID string, version int64, amount string) *domain.EventEnvelope {
+		t.Helper()
+		event := &domain.Event{
+			ID:            fmt.Sprintf("%s-%d", aggregateID, version),
+			AggregateID:   aggregateID,
+			AggregateType: "Account",
+			EventType:     "test.AmountBooked",
+			Version:       version,
+			Timestamp:     time.Now(),
+			Data:          []byte(amount),
+			Metadata:      domain.EventMetadata{CorrelationID: "corr-" + aggregateID},
+		}
+		if err := eventStore.AppendEvents(context.Background(), aggregateID, version-1, []*domain.Event{event}); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+		return &domain.EventEnvelope{Event: *event}
+	}
+
+	ctx := context.Background()
+	for _, envelope := range []*domain.EventEnvelope{book("acc-1", 1, "10"), book("acc-1", 2, "-15")} {
+		if err := projection.Handle(ctx, envelope); err != nil {
+			t.Fatalf("failed to handle %s: %v", envelope.ID, err)
+		}
+	}
+
+	alerts, err := eventStore.LoadEvents(ctx, "alerts-acc-1", 0)
+	if err != nil || len(alerts) != 1 {
+		t.Fatalf("expected one derived event, got %v (err %v)", alerts, err)
+	}
+	alert := alerts[0]
+	if alert.Version != 1 || alert.Metadata.CausationID != "acc-1-2" || alert.Metadata.CorrelationID != "corr-acc-1" ||
+		alert.Metadata.Custom[sqlite.EmittedByKey] != "balances" {
+		t.Errorf("unexpected derived event: %+v", alert)
+	}
+
+	t.Run("RedeliveryDoesNotEmitTwice", func(t *testing.T) {
+		withdrawal, _ := eventStore.LoadEvents(ctx, "acc-1", 1)
+		if err := projection.Handle(ctx, &domain.EventEnvelope{Event: *withdrawal[0]}); err != nil {
+			t.Fatalf("failed to handle redelivery: %v", err)
+		}
+		if alerts, _ := eventStore.LoadEvents(ctx, "alerts-acc-1", 0); len(alerts) != 1 {
+			t.Errorf("expected still one derived event, got %d", len(alerts))
+		}
+	})
+
+	t.Run("FailedEmitRollsBack", func(t *testing.T) {
+		if err := projection.Handle(ctx, book("broken", 1, "-1")); err == nil {
+			t.Fatal("expected an invalid derived event to fail the handler")
+		}
+		var count int
+		db.QueryRow("SELECT COUNT(*) FROM balances WHERE account_id = 'broken'").Scan(&count)
+		if count != 0 {
+			t.Error("expected the read model update to be rolled back")
+		}
+		if alerts, _ := eventStore.LoadEvents(ctx, "alerts-broken", 0); len(alerts) != 0 {
+			t.Errorf("expected no derived events, got %d", len(alerts))
+		}
+	})
+
+	t.Run("OwnEventsDoNotRetrigger", func(t *testing.T) {
+		if err := projection.Handle(ctx, &domain.EventEnvelope{Event: *alert}); err != nil {
+			t.Fatalf("failed to handle derived event: %v", err)
+		}
+		if overdraftsSeen != 0 {
+			t.Errorf("expected the projection to skip its own derived events, saw %d", overdraftsSeen)
+		}
+	})
+
+	t.Run("RequiresSharedDatabase", func(t *testing.T) {
+		other, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		defer other.Close()
+		otherCheckpoints, err := sqlite.NewCheckpointStore(other)
+		if err != nil {
+			t.Fatalf("failed to create checkpoint store: %v", err)
+		}
+		_, err = sqlite.NewSQLiteProjectionBuilder("elsewhere", other, otherCheckpoints, eventStore).
+			OnWithEmit("test.AmountBooked", func(context.Context, *sql.Tx, *domain.EventEnvelope) ([]*domain.Event, error) {
+				return nil, nil
+			}).
+			Build()
+		if !errors.Is(err, sqlite.ErrEmitRequiresSharedDB) {
+			t.Errorf("expected ErrEmitRequiresSharedDB, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// EmittedByKey is the EventMetadata.Custom key naming the projection that
+// emitted a derived event (see OnWithEmit).
+const EmittedByKey = "emitted_by"
+
+// ErrEmitRequiresSharedDB is returned by Build when a projection registers
+// OnWithEmit handlers but its event store is not a SQLite EventStore on the
+// projection's database, so derived events could not be appended atomically.
+var ErrEmitRequiresSharedDB = errors.New("OnWithEmit requires the projection to use the SQLite event store's database")
+
+// EmittingEventHandler updates a projection within tx and returns derived
+// events to append to the event store in the same transaction.
+type EmittingEventHandler func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) ([]*domain.Event, error)
+
+// OnWithEmit registers a handler that can emit derived domain events, e.g. an
+// OverdraftDetected event when a balance drops below zero. The returned events
+// are appended in the projection's transaction, so the read model update, the
+// derived events and the checkpoint commit or roll back together. The projection
+// must share its database with the SQLite event store.
+//
+// Each returned event needs AggregateID, AggregateType, EventType and Data. The
+// store fills in the rest:
+//   - Version continues the target aggregate's stream (no optimistic check)
+//   - ID is derived from the projection name and the source event, so a
+//     redelivered source event does not emit twice
+//   - CausationID defaults to the source event ID; CorrelationID, TenantID and
+//     PrincipalID default to the source event's
+//   - Timestamp defaults to now
+//
+// Loop prevention: derived events are marked with EmittedByKey and never
+// re-trigger handlers of the projection that emitted them. A different
+// projection does see them, so two projections emitting events for each other
+// can loop forever; make sure emitted event types do not lead back to their
+// emitter.
+//
+// Derived events are stored, not published: projections reading the event
+// store see them, event bus subscribers only once something publishes them.
+//
+// Example:
+//
+//	builder.OnWithEmit(accountv1.MoneyWithdrawnEventType, func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) ([]*domain.Event, error) {
+//	    var balance float64
+//	    if err := tx.QueryRow("SELECT balance FROM balances WHERE id = ?", envelope.AggregateID).Scan(&balance); err != nil {
+//	        return nil, err
+//	    }
+//	    if balance >= 0 {
+//	        return nil, nil
+//	    }
+//	    data, _ := proto.Marshal(&alertsv1.OverdraftDetected{AccountId: envelope.AggregateID})
+//	    return []*domain.Event{{
+//	        AggregateID:   "overdraft-" + envelope.AggregateID,
+//	        AggregateType: "OverdraftAlert",
+//	        EventType:     "alerts.v1.OverdraftDetected",
+//	        Data:          data,
+//	    }}, nil
+//	})
+func (b *SQLiteProjectionBuilder) OnWithEmit(eventType string, handler EmittingEventHandler) *SQLiteProjectionBuilder {
+	b.emits = true
+	b.addHandler(domain.CanonicalEventType(eventType), func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+		if envelope.Metadata.Custom[EmittedByKey] == b.name {
+			return nil
+		}
+
+		events, err := handler(ctx, tx, envelope)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		return b.eventStore.(*EventStore).appendDerived(ctx, tx, b.name, envelope, events)
+	})
+	return b
+}
+
+// checkEmitter verifies that OnWithEmit handlers can append to the event store.
+func (b *SQLiteProjectionBuilder) checkEmitter() error {
+	if !b.emits {
+		return nil
+	}
+	eventStore, ok := b.eventStore.(*EventStore)
+	if !ok || eventStore.db != b.db {
+		return ErrEmitRequiresSharedDB
+	}
+	return nil
+}
+
+// appendDerived appends the events a projection emitted for source within the
+// projection's transaction tx.
+func (s *EventStore) appendDerived(ctx context.Context, tx *sql.Tx, projection string, source *domain.EventEnvelope, events []*domain.Event) error {
+	queries := s.tables.queries(tx)
+
+	var inserted []*domain.Event
+	for i, event := range events {
+		if event.AggregateID == "" || event.EventType == "" {
+			return fmt.Errorf("derived event %d needs an aggregate ID and event type", i)
+		}
+		if event.ID == "" {
+			event.ID = domain.GenerateDeterministicEventID(projection+":"+source.ID, event.AggregateID, i)
+		}
+
+		// A redelivered source event emitted this event already
+		var exists int
+		err := tx.QueryRowContext(ctx, s.tables.rewrite("SELECT COUNT(*) FROM events WHERE event_id = ?"), event.ID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check derived event: %w", classifyError(err))
+		}
+		if exists > 0 {
+			continue
+		}
+
+		version, err := queries.GetAggregateVersion(ctx, event.AggregateID)
+		if err != nil {
+			return fmt.Errorf("failed to load version of %s: %w", event.AggregateID, classifyError(err))
+		}
+		event.Version = version.(int64) + 1
+		if event.Timestamp.IsZero() {
+			event.Timestamp = domain.Now()
+		}
+		event.Metadata = derivedMetadata(event.Metadata, projection, source)
+
+		if err := s.validateEvents(ctx, []*domain.Event{event}); err != nil {
+			return err
+		}
+		if s.monotonicTimestamps {
+			clamped, err := s.clampTimestamps(ctx, tx, event.AggregateID, []*domain.Event{event})
+			if err != nil {
+				return err
+			}
+			event = clamped[0]
+		}
+		if err := s.validateConstraints(ctx, tx, event, event.AggregateID); err != nil {
+			return err
+		}
+		if err := insertEvent(ctx, queries, event); err != nil {
+			return err
+		}
+		if err := s.chainEvent(ctx, tx, event); err != nil {
+			return err
+		}
+		inserted = append(inserted, event)
+	}
+
+	if err := s.updatePositions(ctx, tx); err != nil {
+		return fmt.Errorf("failed to update positions: %w", classifyError(err))
+	}
+	if err := s.loadPositions(ctx, tx, inserted); err != nil {
+		return err
+	}
+	return s.enqueueOutbox(ctx, tx, inserted)
+}
+
+// derivedMetadata fills the metadata of an event emitted by projection from
+// its source event.
+func derivedMetadata(meta domain.EventMetadata, projection string, source *domain.EventEnvelope) domain.EventMetadata {
+	if meta.CausationID == "" {
+		meta.CausationID = source.ID
+	}
+	if meta.CorrelationID == "" {
+		meta.CorrelationID = source.Metadata.CorrelationID
+	}
+	if meta.TenantID == "" {
+		meta.TenantID = source.Metadata.TenantID
+	}
+	if meta.PrincipalID == "" {
+		meta.PrincipalID = source.Metadata.PrincipalID
+	}
+
+	custom := make(map[string]string, len(meta.Custom)+1)
+	for k, v := range meta.Custom {
+		custom[k] = v
+	}
+	custom[EmittedByKey] = projection
+	meta.Custom = custom
+	return meta
+}
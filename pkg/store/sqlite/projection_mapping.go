@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// Map registers declarative row mappings (see store.MapEvent) as event
+// handlers: the projection generates the INSERT ... ON CONFLICT, UPDATE or
+// DELETE statement for each event and runs it in the event's transaction,
+// so simple projections need no hand-written SQL. Drop to On or OnWithTx for
+// event types that need more than one row change.
+//
+// Upserting mappings need a primary key or unique index over their key
+// columns. Invalid mappings make Build fail.
+//
+// Example:
+//
+//	builder.Map(
+//	    accountv1.MapAccountOpened().
+//	        ToTable("account_summary").
+//	        Key("account_id", func(e *accountv1.AccountOpenedEvent) any { return e.AccountId }).
+//	        Columns(map[string]func(*accountv1.AccountOpenedEvent) any{
+//	            "owner_name": func(e *accountv1.AccountOpenedEvent) any { return e.OwnerName },
+//	            "balance":    func(e *accountv1.AccountOpenedEvent) any { return e.InitialBalance },
+//	        }),
+//	    accountv1.MapMoneyDeposited().
+//	        ToTable("account_summary").
+//	        Update().
+//	        Key("account_id", func(e *accountv1.MoneyDepositedEvent) any { return e.AccountId }).
+//	        Column("balance", func(e *accountv1.MoneyDepositedEvent) any { return e.NewBalance }).
+//	        Increment("transaction_count", func(*accountv1.MoneyDepositedEvent) any { return 1 }),
+//	)
+func (b *SQLiteProjectionBuilder) Map(mappings ...store.RowMapping) *SQLiteProjectionBuilder {
+	for _, mapping := range mappings {
+		if err := mapping.Validate(); err != nil {
+			b.mappingErrs = append(b.mappingErrs, err)
+			continue
+		}
+		b.addHandler(domain.CanonicalEventType(mapping.EventType()), func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+			row, err := mapping.MapRow(envelope)
+			if err != nil {
+				return err
+			}
+			query, args, err := mappedRowSQL(row)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("failed to write %s row: %w", row.Table, err)
+			}
+			return nil
+		})
+	}
+	return b
+}
+
+// mappedRowSQL returns the statement applying row and its arguments.
+func mappedRowSQL(row *store.MappedRow) (string, []any, error) {
+	for _, name := range append([]string{row.Table}, mappedColumnNames(row)...) {
+		if !identifierPattern.MatchString(name) {
+			return "", nil, fmt.Errorf("invalid identifier %q in mapping", name)
+		}
+	}
+
+	var (
+		query strings.Builder
+		args  []any
+	)
+	where := func() {
+		query.WriteString(" WHERE ")
+		for i, key := range row.Key {
+			if i > 0 {
+				query.WriteString(" AND ")
+			}
+			fmt.Fprintf(&query, "%s = ?", key.Column)
+			args = append(args, key.Value)
+		}
+	}
+
+	switch row.Mode {
+	case store.MappingDelete:
+		fmt.Fprintf(&query, "DELETE FROM %s", row.Table)
+		where()
+
+	case store.MappingUpdate:
+		fmt.Fprintf(&query, "UPDATE %s SET ", row.Table)
+		var assignments []string
+		for _, column := range row.Set {
+			assignments = append(assignments, column.Column+" = ?")
+			args = append(args, column.Value)
+		}
+		for _, column := range row.Add {
+			assignments = append(assignments, fmt.Sprintf("%s = %s + ?", column.Column, column.Column))
+			args = append(args, column.Value)
+		}
+		query.WriteString(strings.Join(assignments, ", "))
+		where()
+
+	default:
+		var columns, keys, updates []string
+		for _, group := range [][]store.ColumnValue{row.Key, row.Set, row.Add} {
+			for _, column := range group {
+				columns = append(columns, column.Column)
+				args = append(args, column.Value)
+			}
+		}
+		for _, key := range row.Key {
+			keys = append(keys, key.Column)
+		}
+		for _, column := range row.Set {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", column.Column, column.Column))
+		}
+		for _, column := range row.Add {
+			updates = append(updates, fmt.Sprintf("%s = %s.%s + excluded.%s", column.Column, row.Table, column.Column, column.Column))
+		}
+		fmt.Fprintf(&query, "INSERT INTO %s (%s) VALUES (%s) ON CONFLICT(%s) DO UPDATE SET %s",
+			row.Table, strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "),
+			strings.Join(keys, ", "), strings.Join(updates, ", "))
+	}
+
+	return query.String(), args, nil
+}
+
+// mappedColumnNames returns the names of all columns of row.
+func mappedColumnNames(row *store.MappedRow) []string {
+	var names []string
+	for _, group := range [][]store.ColumnValue{row.Key, row.Set, row.Add} {
+		for _, column := range group {
+			names = append(names, column.Column)
+		}
+	}
+	return names
+}
@@ -0,0 +1,132 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSQLiteProjection_Map(t *testing.T) {
+	eventStore, err := sqlite.NewEventStore(sqlite.WithDSN(":memory:"), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create checkpoint store: %v", err)
+	}
+
+	built, err := sqlite.NewSQLiteProjectionBuilder("account_summary", eventStore.DB(), checkpointStore, eventStore).
+		WithSchema(func(ctx context.Context, db *sql.DB) error {
+			_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS account_summary (
+				account_id TEXT PRIMARY KEY,
+				owner_name TEXT NOT NULL,
+				balance TEXT NOT NULL,
+				transactions INTEGER NOT NULL DEFAULT 0
+			)`)
+			return err
+		}).
+		Map(
+			accountv1.MapAccountOpened().
+				ToTable("account_summary").
+				Key("account_id", func(e *accountv1.AccountOpenedEvent) any { return e.AccountId }).
+				Columns(map[string]func(*accountv1.AccountOpenedEvent) any{
+					"owner_name": func(e *accountv1.AccountOpenedEvent) any { return e.OwnerName },
+					"balance":    func(e *accountv1.AccountOpenedEvent) any { return e.InitialBalance },
+				}),
+			accountv1.MapMoneyDeposited().
+				ToTable("account_summary").
+				Update().
+				Key("account_id", func(e *accountv1.MoneyDepositedEvent) any { return e.AccountId }).
+				Column("balance", func(e *accountv1.MoneyDepositedEvent) any { return e.NewBalance }).
+				Increment("transactions", func(*accountv1.MoneyDepositedEvent) any { return 1 }),
+			accountv1.MapAccountClosed().
+				ToTable("account_summary").
+				Delete().
+				Key("account_id", func(e *accountv1.AccountClosedEvent) any { return e.AccountId }),
+		).
+		Build()
+	if err != nil {
+		t.Fatalf("failed to build projection: %v", err)
+	}
+	projection := built.(*sqlite.SQLiteProjection)
+
+	ctx := context.Background()
+	handle := func(aggregateID string, version int64, eventType string, event proto.Message) {
+		t.Helper()
+		data, err := proto.Marshal(event)
+		if err != nil {
+			t.Fatalf("failed to marshal event: %v", err)
+		}
+		envelope := &domain.EventEnvelope{Event: domain.Event{
+			ID:            fmt.Sprintf("%s-%d", aggregateID, version),
+			AggregateID:   aggregateID,
+			AggregateType: "Account",
+			EventType:     eventType,
+			Version:       version,
+			Timestamp:     time.Now(),
+			Data:          data,
+		}}
+		if err := projection.Handle(ctx, envelope); err != nil {
+			t.Fatalf("failed to handle %s: %v", eventType, err)
+		}
+	}
+	summary := func(accountID string) (owner, balance string, transactions int, err error) {
+		err = eventStore.DB().QueryRow(`SELECT owner_name, balance, transactions FROM account_summary WHERE account_id = ?`,
+			accountID).Scan(&owner, &balance, &transactions)
+		return owner, balance, transactions, err
+	}
+
+	handle("acc-1", 1, accountv1.AccountOpenedEventType, &accountv1.AccountOpenedEvent{AccountId: "acc-1", OwnerName: "Alice", InitialBalance: "10"})
+	handle("acc-1", 2, accountv1.MoneyDepositedEventType, &accountv1.MoneyDepositedEvent{AccountId: "acc-1", Amount: "5", NewBalance: "15"})
+	handle("acc-1", 3, accountv1.MoneyDepositedEventType, &accountv1.MoneyDepositedEvent{AccountId: "acc-1", Amount: "5", NewBalance: "20"})
+
+	owner, balance, transactions, err := summary("acc-1")
+	if err != nil {
+		t.Fatalf("failed to load summary: %v", err)
+	}
+	if owner != "Alice" || balance != "20" || transactions != 2 {
+		t.Errorf("unexpected summary: owner %q, balance %q, transactions %d", owner, balance, transactions)
+	}
+
+	t.Run("UpsertReplacesRow", func(t *testing.T) {
+		handle("acc-1", 4, accountv1.AccountOpenedEventType, &accountv1.AccountOpenedEvent{AccountId: "acc-1", OwnerName: "Alice B", InitialBalance: "20"})
+		if owner, _, transactions, _ := summary("acc-1"); owner != "Alice B" || transactions != 2 {
+			t.Errorf("expected the row to be updated in place, got owner %q, transactions %d", owner, transactions)
+		}
+	})
+
+	t.Run("UpdateWithoutRow", func(t *testing.T) {
+		handle("acc-2", 1, accountv1.MoneyDepositedEventType, &accountv1.MoneyDepositedEvent{AccountId: "acc-2", NewBalance: "5"})
+		if _, _, _, err := summary("acc-2"); err != sql.ErrNoRows {
+			t.Errorf("expected no row for an update of a missing account, got %v", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		handle("acc-1", 5, accountv1.AccountClosedEventType, &accountv1.AccountClosedEvent{AccountId: "acc-1"})
+		if _, _, _, err := summary("acc-1"); err != sql.ErrNoRows {
+			t.Errorf("expected the row to be deleted, got %v", err)
+		}
+	})
+
+	t.Run("InvalidMapping", func(t *testing.T) {
+		_, err := sqlite.NewSQLiteProjectionBuilder("invalid", eventStore.DB(), checkpointStore, eventStore).
+			Map(accountv1.MapAccountOpened().
+				Key("account_id", func(e *accountv1.AccountOpenedEvent) any { return e.AccountId })).
+			Build()
+		if err == nil || !strings.Contains(err.Error(), "no table") {
+			t.Errorf("expected a missing table error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidCursor is returned by Paginate when a cursor is malformed or was
+// issued for a different table or key column.
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 1000
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PageOptions configures a Paginate call.
+type PageOptions struct {
+	// Key is the column rows are ordered and paged by. It must be unique and
+	// should be indexed (the primary key is ideal). Defaults to "rowid".
+	Key string
+
+	// Columns lists the columns to return (nil = all columns)
+	Columns []string
+
+	// Limit is the page size (default 50, at most 1000)
+	Limit int
+
+	// Cursor resumes after the last row of a previous page; empty for the
+	// first page. Pass Page.NextCursor unchanged.
+	Cursor string
+
+	// Descending pages from the largest key down
+	Descending bool
+
+	// Where is an optional SQL filter, e.g. "status = ?", with its Args. It is
+	// inserted verbatim and must not contain untrusted input.
+	Where string
+	Args  []any
+}
+
+// Page is one page of projection rows.
+type Page struct {
+	// Rows maps column names to the driver's values
+	Rows []map[string]any
+
+	// NextCursor fetches the following page; empty on the last page
+	NextCursor string
+}
+
+// pageCursor is the decoded form of an opaque cursor.
+type pageCursor struct {
+	Table string `json:"t"`
+	Key   string `json:"k"`
+	Last  any    `json:"v"`
+}
+
+// Paginate returns one page of a projection table using keyset pagination:
+// each page continues with WHERE key > last key of the previous page instead
+// of an OFFSET, so deep pages cost the same as the first and rows inserted
+// while paging neither shift nor repeat earlier ones.
+//
+// Example:
+//
+//	page, err := sqlite.Paginate(ctx, db, "account_balance", sqlite.PageOptions{
+//	    Key:    "account_id",
+//	    Limit:  100,
+//	    Cursor: r.URL.Query().Get("cursor"),
+//	})
+//	// respond with page.Rows and page.NextCursor as next_cursor
+func Paginate(ctx context.Context, db *sql.DB, table string, opts PageOptions) (*Page, error) {
+	key := opts.Key
+	if key == "" {
+		key = "rowid"
+	}
+	if !identifierPattern.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	for _, column := range append([]string{key}, opts.Columns...) {
+		if !identifierPattern.MatchString(column) {
+			return nil, fmt.Errorf("invalid column name %q", column)
+		}
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	columns := "*"
+	if len(opts.Columns) > 0 {
+		columns = `"` + strings.Join(opts.Columns, `", "`) + `"`
+	}
+	// Select the key explicitly so the cursor can be built even when it is
+	// not among the requested columns (rowid never is with *)
+	query := fmt.Sprintf(`SELECT "%s" AS __page_key, %s FROM "%s"`, key, columns, table)
+
+	var conditions []string
+	args := append([]any(nil), opts.Args...)
+	if opts.Where != "" {
+		conditions = append(conditions, "("+opts.Where+")")
+	}
+	if opts.Cursor != "" {
+		last, err := decodePageCursor(opts.Cursor, table, key)
+		if err != nil {
+			return nil, err
+		}
+		op := ">"
+		if opts.Descending {
+			op = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf(`"%s" %s ?`, key, op))
+		args = append(args, last)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	order := "ASC"
+	if opts.Descending {
+		order = "DESC"
+	}
+	// Fetch one extra row to learn whether another page follows
+	query += fmt.Sprintf(` ORDER BY "%s" %s LIMIT %d`, key, order, limit+1)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", table, classifyError(err))
+	}
+	defer rows.Close()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	page := &Page{}
+	var lastKey any
+	for rows.Next() {
+		if len(page.Rows) == limit {
+			cursor, err := encodePageCursor(table, key, lastKey)
+			if err != nil {
+				return nil, err
+			}
+			page.NextCursor = cursor
+			break
+		}
+
+		values := make([]any, len(names))
+		pointers := make([]any, len(names))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		row := make(map[string]any, len(names)-1)
+		for i, name := range names[1:] {
+			row[name] = values[i+1]
+		}
+		lastKey = values[0]
+		page.Rows = append(page.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", classifyError(err))
+	}
+
+	return page, nil
+}
+
+// Paginate returns one page of one of the projection's read model tables,
+// as the package-level Paginate does on the projection's database.
+//
+// Example:
+//
+//	page, err := projection.Paginate(ctx, "account_view", sqlite.PageOptions{
+//	    Key:    "account_id",
+//	    Cursor: cursor,
+//	})
+func (p *SQLiteProjection) Paginate(ctx context.Context, table string, opts PageOptions) (*Page, error) {
+	return Paginate(ctx, p.db, table, opts)
+}
+
+// encodePageCursor serializes the last key of a page as URL-safe base64 JSON.
+func encodePageCursor(table, key string, last any) (string, error) {
+	if b, ok := last.([]byte); ok {
+		last = string(b)
+	}
+	data, err := json.Marshal(pageCursor{Table: table, Key: key, Last: last})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodePageCursor returns the last key stored in cursor, checking that it
+// belongs to table and key.
+func decodePageCursor(cursor, table, key string) (any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	// UseNumber keeps integer keys above 2^53 exact
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded pageCursor
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if decoded.Table != table || decoded.Key != key {
+		return nil, fmt.Errorf("%w: issued for %s.%s", ErrInvalidCursor, decoded.Table, decoded.Key)
+	}
+
+	switch last := decoded.Last.(type) {
+	case json.Number:
+		if n, err := last.Int64(); err == nil {
+			return n, nil
+		}
+		return last.Float64()
+	case string:
+		return last, nil
+	default:
+		return nil, ErrInvalidCursor
+	}
+}
@@ -0,0 +1,125 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestPaginate(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithDSN(":memory:"), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+	db := eventStore.DB()
+
+	if _, err := db.Exec(`CREATE TABLE account_balance (account_id TEXT PRIMARY KEY, balance INTEGER NOT NULL)`); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	for i := 1; i <= 25; i++ {
+		if _, err := db.Exec(`INSERT INTO account_balance VALUES (?, ?)`, fmt.Sprintf("acc-%02d", i), i*10); err != nil {
+			t.Fatalf("failed to insert row: %v", err)
+		}
+	}
+
+	collect := func(t *testing.T, opts sqlite.PageOptions) ([]string, []int) {
+		t.Helper()
+		var ids []string
+		var sizes []int
+		for {
+			page, err := sqlite.Paginate(ctx, db, "account_balance", opts)
+			if err != nil {
+				t.Fatalf("failed to paginate: %v", err)
+			}
+			sizes = append(sizes, len(page.Rows))
+			for _, row := range page.Rows {
+				if id, ok := row["account_id"].(string); ok {
+					ids = append(ids, id)
+				} else {
+					ids = append(ids, fmt.Sprint(row["balance"]))
+				}
+			}
+			if page.NextCursor == "" {
+				return ids, sizes
+			}
+			opts.Cursor = page.NextCursor
+		}
+	}
+
+	t.Run("PagesByKey", func(t *testing.T) {
+		ids, sizes := collect(t, sqlite.PageOptions{Key: "account_id", Limit: 10})
+		if fmt.Sprint(sizes) != "[10 10 5]" {
+			t.Errorf("expected pages of [10 10 5], got %v", sizes)
+		}
+		if len(ids) != 25 || ids[0] != "acc-01" || ids[24] != "acc-25" {
+			t.Errorf("expected acc-01..acc-25 in order, got %v", ids)
+		}
+	})
+
+	t.Run("DescendingWithFilter", func(t *testing.T) {
+		ids, _ := collect(t, sqlite.PageOptions{
+			Key:        "account_id",
+			Limit:      4,
+			Descending: true,
+			Where:      "balance > ?",
+			Args:       []any{200},
+		})
+		if fmt.Sprint(ids) != "[acc-25 acc-24 acc-23 acc-22 acc-21]" {
+			t.Errorf("unexpected rows: %v", ids)
+		}
+	})
+
+	t.Run("DefaultRowidKey", func(t *testing.T) {
+		balances, sizes := collect(t, sqlite.PageOptions{Columns: []string{"balance"}, Limit: 20})
+		if fmt.Sprint(sizes) != "[20 5]" || balances[24] != "250" {
+			t.Errorf("unexpected pages %v: %v", sizes, balances)
+		}
+	})
+
+	t.Run("RejectsForeignCursor", func(t *testing.T) {
+		page, err := sqlite.Paginate(ctx, db, "account_balance", sqlite.PageOptions{Key: "account_id", Limit: 1})
+		if err != nil {
+			t.Fatalf("failed to paginate: %v", err)
+		}
+		_, err = sqlite.Paginate(ctx, db, "account_balance", sqlite.PageOptions{Key: "balance", Cursor: page.NextCursor})
+		if !errors.Is(err, sqlite.ErrInvalidCursor) {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+		_, err = sqlite.Paginate(ctx, db, "account_balance", sqlite.PageOptions{Cursor: "not a cursor"})
+		if !errors.Is(err, sqlite.ErrInvalidCursor) {
+			t.Errorf("expected ErrInvalidCursor, got %v", err)
+		}
+	})
+
+	t.Run("FromProjection", func(t *testing.T) {
+		checkpointStore, err := sqlite.NewCheckpointStore(db)
+		if err != nil {
+			t.Fatalf("failed to create checkpoint store: %v", err)
+		}
+		built, err := sqlite.NewSQLiteProjectionBuilder("balances", db, checkpointStore, eventStore).Build()
+		if err != nil {
+			t.Fatalf("failed to build projection: %v", err)
+		}
+		projection := built.(*sqlite.SQLiteProjection)
+		defer projection.Close()
+
+		page, err := projection.Paginate(ctx, "account_balance", sqlite.PageOptions{Key: "account_id", Limit: 30})
+		if err != nil {
+			t.Fatalf("failed to paginate: %v", err)
+		}
+		if len(page.Rows) != 25 || page.NextCursor != "" {
+			t.Errorf("expected all 25 rows on one page, got %d (next cursor %q)", len(page.Rows), page.NextCursor)
+		}
+	})
+
+	t.Run("RejectsInvalidIdentifiers", func(t *testing.T) {
+		if _, err := sqlite.Paginate(ctx, db, "account_balance; DROP TABLE x", sqlite.PageOptions{}); err == nil {
+			t.Error("expected an invalid table name to be rejected")
+		}
+	})
+}
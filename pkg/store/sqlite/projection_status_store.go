@@ -4,20 +4,59 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/plaenen/eventstore/pkg/domain"
 	"github.com/plaenen/eventstore/pkg/store"
 )
 
+// ErrProjectionFailed is returned by WaitForReady when the projection is in
+// the FAILED state.
+var ErrProjectionFailed = errors.New("projection failed")
+
+// statusPollInterval is how often WaitForReady re-reads the status, to notice
+// changes saved through another ProjectionStatusStore or process.
+const statusPollInterval = 500 * time.Millisecond
+
 // ProjectionStatusStore implements store.ProjectionStatusStore for SQLite.
 type ProjectionStatusStore struct {
-	db *sql.DB
+	db     *sql.DB
+	tables tablePrefix
+
+	subMu       sync.Mutex
+	subscribers map[int]statusSubscriber
+	nextSubID   int
+}
+
+// statusSubscriber is a callback registered with Subscribe.
+type statusSubscriber struct {
+	projectionName string // "" for all projections
+	fn             func(store.ProjectionState)
+}
+
+// ProjectionStatusStoreOption configures a ProjectionStatusStore.
+type ProjectionStatusStoreOption func(*ProjectionStatusStore)
+
+// WithStatusTablePrefix prepends prefix to the projection_status table (see
+// WithTablePrefix).
+func WithStatusTablePrefix(prefix string) ProjectionStatusStoreOption {
+	return func(s *ProjectionStatusStore) {
+		s.tables = tablePrefix(prefix)
+	}
 }
 
 // NewProjectionStatusStore creates a new SQLite-based projection status store.
-func NewProjectionStatusStore(db *sql.DB) (*ProjectionStatusStore, error) {
+func NewProjectionStatusStore(db *sql.DB, opts ...ProjectionStatusStoreOption) (*ProjectionStatusStore, error) {
 	store := &ProjectionStatusStore{db: db}
+	for _, opt := range opts {
+		opt(store)
+	}
+	if _, err := newTablePrefix(string(store.tables)); err != nil {
+		return nil, err
+	}
 
 	// Create status table
 	if err := store.ensureTable(); err != nil {
@@ -29,7 +68,7 @@ func NewProjectionStatusStore(db *sql.DB) (*ProjectionStatusStore, error) {
 
 // ensureTable creates the projection status table if it doesn't exist.
 func (s *ProjectionStatusStore) ensureTable() error {
-	_, err := s.db.Exec(`
+	_, err := s.db.Exec(s.tables.rewrite(`
 		CREATE TABLE IF NOT EXISTS projection_status (
 			projection_name TEXT PRIMARY KEY,
 			status TEXT NOT NULL,
@@ -37,7 +76,7 @@ func (s *ProjectionStatusStore) ensureTable() error {
 			updated_at INTEGER NOT NULL,
 			progress_json TEXT
 		)
-	`)
+	`))
 	if err != nil {
 		return fmt.Errorf("failed to create projection_status table: %w", err)
 	}
@@ -59,7 +98,7 @@ func (s *ProjectionStatusStore) Save(state *store.ProjectionState) error {
 		progressJSON = &str
 	}
 
-	_, err := s.db.ExecContext(ctx, `
+	_, err := s.db.ExecContext(ctx, s.tables.rewrite(`
 		INSERT INTO projection_status (projection_name, status, message, updated_at, progress_json)
 		VALUES (?, ?, ?, ?, ?)
 		ON CONFLICT(projection_name) DO UPDATE SET
@@ -67,12 +106,13 @@ func (s *ProjectionStatusStore) Save(state *store.ProjectionState) error {
 			message = excluded.message,
 			updated_at = excluded.updated_at,
 			progress_json = excluded.progress_json
-	`, state.ProjectionName, state.Status, state.Message, state.UpdatedAt.Unix(), progressJSON)
+	`), state.ProjectionName, state.Status, state.Message, state.UpdatedAt.Unix(), progressJSON)
 
 	if err != nil {
 		return fmt.Errorf("failed to save projection status: %w", err)
 	}
 
+	s.notify(*state)
 	return nil
 }
 
@@ -85,11 +125,11 @@ func (s *ProjectionStatusStore) Load(projectionName string) (*store.ProjectionSt
 	var updatedAt int64
 	var progressJSON sql.NullString
 
-	err := s.db.QueryRowContext(ctx, `
+	err := s.db.QueryRowContext(ctx, s.tables.rewrite(`
 		SELECT status, message, updated_at, progress_json
 		FROM projection_status
 		WHERE projection_name = ?
-	`, projectionName).Scan(&status, &message, &updatedAt, &progressJSON)
+	`), projectionName).Scan(&status, &message, &updatedAt, &progressJSON)
 
 	if err == sql.ErrNoRows {
 		// No status found - assume ready
@@ -104,6 +144,47 @@ func (s *ProjectionStatusStore) Load(projectionName string) (*store.ProjectionSt
 		return nil, fmt.Errorf("failed to load projection status: %w", err)
 	}
 
+	return newProjectionState(projectionName, status, message, updatedAt, progressJSON)
+}
+
+// List returns the status of every projection that has saved one, ordered by name.
+func (s *ProjectionStatusStore) List() ([]*store.ProjectionState, error) {
+	rows, err := s.db.QueryContext(context.Background(), s.tables.rewrite(`
+		SELECT projection_name, status, message, updated_at, progress_json
+		FROM projection_status
+		ORDER BY projection_name
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projection status: %w", err)
+	}
+	defer rows.Close()
+
+	var states []*store.ProjectionState
+	for rows.Next() {
+		var (
+			name, status string
+			message      sql.NullString
+			updatedAt    int64
+			progressJSON sql.NullString
+		)
+		if err := rows.Scan(&name, &status, &message, &updatedAt, &progressJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan projection status: %w", err)
+		}
+		state, err := newProjectionState(name, status, message, updatedAt, progressJSON)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list projection status: %w", err)
+	}
+
+	return states, nil
+}
+
+// newProjectionState builds a ProjectionState from a projection_status row.
+func newProjectionState(projectionName, status string, message sql.NullString, updatedAt int64, progressJSON sql.NullString) (*store.ProjectionState, error) {
 	state := &store.ProjectionState{
 		ProjectionName: projectionName,
 		Status:         store.ProjectionStatus(status),
@@ -135,15 +216,140 @@ func (s *ProjectionStatusStore) UpdateProgress(projectionName string, progress *
 		return fmt.Errorf("failed to marshal progress: %w", err)
 	}
 
-	_, err = s.db.ExecContext(ctx, `
+	_, err = s.db.ExecContext(ctx, s.tables.rewrite(`
 		UPDATE projection_status
 		SET progress_json = ?, updated_at = ?
 		WHERE projection_name = ?
-	`, string(data), domain.Now().Unix(), projectionName)
+	`), string(data), domain.Now().Unix(), projectionName)
 
 	if err != nil {
 		return fmt.Errorf("failed to update progress: %w", err)
 	}
 
+	if s.hasSubscribers(projectionName) {
+		if state, err := s.Load(projectionName); err == nil {
+			s.notify(*state)
+		}
+	}
 	return nil
 }
+
+// ListAll returns the status of every projection that has saved one, ordered
+// by name, as values callers can keep without sharing them with the store.
+func (s *ProjectionStatusStore) ListAll() ([]store.ProjectionState, error) {
+	states, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]store.ProjectionState, len(states))
+	for i, state := range states {
+		all[i] = *state
+	}
+	return all, nil
+}
+
+// Subscribe calls fn with the new state whenever the status of projectionName
+// is saved or its progress updated through this store, or for every
+// projection if projectionName is empty. It returns a function that cancels
+// the subscription.
+//
+// fn runs synchronously in the goroutine saving the status (e.g., a rebuild),
+// so it must return quickly. Changes saved through another
+// ProjectionStatusStore are not seen; share one store between projections
+// and their observers with SQLiteProjectionBuilder.WithStatusStore.
+//
+// Example:
+//
+//	unsubscribe := statusStore.Subscribe("account-balance", func(state store.ProjectionState) {
+//	    log.Printf("%s is %s: %s", state.ProjectionName, state.Status, state.Message)
+//	})
+//	defer unsubscribe()
+func (s *ProjectionStatusStore) Subscribe(projectionName string, fn func(store.ProjectionState)) (unsubscribe func()) {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	if s.subscribers == nil {
+		s.subscribers = make(map[int]statusSubscriber)
+	}
+	id := s.nextSubID
+	s.nextSubID++
+	s.subscribers[id] = statusSubscriber{projectionName: projectionName, fn: fn}
+
+	return func() {
+		s.subMu.Lock()
+		defer s.subMu.Unlock()
+		delete(s.subscribers, id)
+	}
+}
+
+// WaitForReady blocks until projectionName is READY, e.g. at startup before
+// serving queries from a projection that is being rebuilt. It returns an
+// error wrapping ErrProjectionFailed if the projection is FAILED, or ctx's
+// error once ctx is done. A projection without a saved status counts as
+// ready, as with Load.
+//
+// Status changes saved through this store are seen immediately; others, such
+// as a rebuild in another process, within half a second.
+func (s *ProjectionStatusStore) WaitForReady(ctx context.Context, projectionName string) error {
+	changed := make(chan struct{}, 1)
+	unsubscribe := s.Subscribe(projectionName, func(store.ProjectionState) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	ticker := time.NewTicker(statusPollInterval)
+	defer ticker.Stop()
+
+	for {
+		state, err := s.Load(projectionName)
+		if err != nil {
+			return err
+		}
+		switch state.Status {
+		case store.ProjectionStatusReady:
+			return nil
+		case store.ProjectionStatusFailed:
+			return fmt.Errorf("%w: %s: %s", ErrProjectionFailed, projectionName, state.Message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for projection %s: %w", projectionName, ctx.Err())
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// hasSubscribers reports whether any subscriber watches projectionName.
+func (s *ProjectionStatusStore) hasSubscribers(projectionName string) bool {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+
+	for _, sub := range s.subscribers {
+		if sub.projectionName == "" || sub.projectionName == projectionName {
+			return true
+		}
+	}
+	return false
+}
+
+// notify calls the subscribers watching state's projection.
+func (s *ProjectionStatusStore) notify(state store.ProjectionState) {
+	s.subMu.Lock()
+	var fns []func(store.ProjectionState)
+	for _, sub := range s.subscribers {
+		if sub.projectionName == "" || sub.projectionName == state.ProjectionName {
+			fns = append(fns, sub.fn)
+		}
+	}
+	s.subMu.Unlock()
+
+	for _, fn := range fns {
+		fn(state)
+	}
+}
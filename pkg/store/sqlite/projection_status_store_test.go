@@ -0,0 +1,136 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestProjectionStatusStore(t *testing.T) {
+	eventStore, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase())
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	statusStore, err := sqlite.NewProjectionStatusStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create status store: %v", err)
+	}
+
+	t.Run("SubscribeSeesRebuildTransitions", func(t *testing.T) {
+		if err := eventStore.AppendEvents(context.Background(), "agg-1", 0, []*domain.Event{{
+			ID:            "evt-1",
+			AggregateID:   "agg-1",
+			AggregateType: "TestAggregate",
+			EventType:     "test.Happened",
+			Version:       1,
+			Timestamp:     time.Now(),
+			Data:          []byte("data"),
+		}}); err != nil {
+			t.Fatalf("failed to append event: %v", err)
+		}
+
+		checkpointStore, err := sqlite.NewCheckpointStore(eventStore.DB())
+		if err != nil {
+			t.Fatalf("failed to create checkpoint store: %v", err)
+		}
+		built, err := sqlite.NewSQLiteProjectionBuilder("watched", eventStore.DB(), checkpointStore, eventStore).
+			WithStatusStore(statusStore).
+			OnWithTx("test.Happened", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+				return nil
+			}).
+			Build()
+		if err != nil {
+			t.Fatalf("failed to build projection: %v", err)
+		}
+
+		var (
+			mu       sync.Mutex
+			statuses []store.ProjectionStatus
+		)
+		unsubscribe := statusStore.Subscribe("watched", func(state store.ProjectionState) {
+			mu.Lock()
+			defer mu.Unlock()
+			statuses = append(statuses, state.Status)
+		})
+
+		if err := built.(*sqlite.SQLiteProjection).Rebuild(context.Background()); err != nil {
+			t.Fatalf("rebuild failed: %v", err)
+		}
+		unsubscribe()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(statuses) < 2 || statuses[0] != store.ProjectionStatusRebuilding || statuses[len(statuses)-1] != store.ProjectionStatusReady {
+			t.Errorf("expected REBUILDING ... READY, got %v", statuses)
+		}
+	})
+
+	t.Run("UnsubscribeStopsNotifications", func(t *testing.T) {
+		calls := 0
+		unsubscribe := statusStore.Subscribe("", func(store.ProjectionState) { calls++ })
+		unsubscribe()
+
+		if err := statusStore.Save(&store.ProjectionState{ProjectionName: "other", Status: store.ProjectionStatusPaused, UpdatedAt: time.Now()}); err != nil {
+			t.Fatalf("failed to save status: %v", err)
+		}
+		if calls != 0 {
+			t.Errorf("expected no calls after unsubscribe, got %d", calls)
+		}
+	})
+
+	t.Run("ListAll", func(t *testing.T) {
+		states, err := statusStore.ListAll()
+		if err != nil {
+			t.Fatalf("ListAll failed: %v", err)
+		}
+		if len(states) != 2 || states[0].ProjectionName != "other" || states[1].ProjectionName != "watched" {
+			t.Fatalf("expected other and watched, got %+v", states)
+		}
+		if states[0].Status != store.ProjectionStatusPaused || states[1].Status != store.ProjectionStatusReady {
+			t.Errorf("unexpected statuses: %s, %s", states[0].Status, states[1].Status)
+		}
+	})
+
+	t.Run("WaitForReady", func(t *testing.T) {
+		ctx := context.Background()
+		save := func(status store.ProjectionStatus, message string) {
+			if err := statusStore.Save(&store.ProjectionState{ProjectionName: "slow", Status: status, Message: message, UpdatedAt: time.Now()}); err != nil {
+				t.Errorf("failed to save status: %v", err)
+			}
+		}
+
+		save(store.ProjectionStatusRebuilding, "")
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			save(store.ProjectionStatusReady, "")
+		}()
+		start := time.Now()
+		if err := statusStore.WaitForReady(ctx, "slow"); err != nil {
+			t.Fatalf("WaitForReady failed: %v", err)
+		}
+		if waited := time.Since(start); waited > 300*time.Millisecond {
+			t.Errorf("expected the subscription to wake WaitForReady, waited %v", waited)
+		}
+
+		save(store.ProjectionStatusFailed, "disk full")
+		if err := statusStore.WaitForReady(ctx, "slow"); !errors.Is(err, sqlite.ErrProjectionFailed) {
+			t.Errorf("expected ErrProjectionFailed, got %v", err)
+		}
+
+		save(store.ProjectionStatusRebuilding, "")
+		timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		if err := statusStore.WaitForReady(timeoutCtx, "slow"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// QuarantineStore implements store.QuarantineStore for SQLite, keeping the
+// events projections failed on during a rebuild in the projection_quarantine
+// table.
+type QuarantineStore struct {
+	db     *sql.DB
+	tables tablePrefix
+}
+
+// QuarantineStoreOption configures a QuarantineStore.
+type QuarantineStoreOption func(*QuarantineStore)
+
+// WithQuarantineTablePrefix prepends prefix to the projection_quarantine table
+// (see WithTablePrefix).
+func WithQuarantineTablePrefix(prefix string) QuarantineStoreOption {
+	return func(s *QuarantineStore) {
+		s.tables = tablePrefix(prefix)
+	}
+}
+
+// NewQuarantineStore creates a SQLite quarantine store, creating its table if
+// needed.
+//
+// Example:
+//
+//	quarantine, _ := sqlite.NewQuarantineStore(db)
+//	err := projection.Rebuild(ctx,
+//	    store.OnEventError(store.RebuildQuarantine),
+//	    store.WithQuarantineStore(quarantine))
+func NewQuarantineStore(db *sql.DB, opts ...QuarantineStoreOption) (*QuarantineStore, error) {
+	s := &QuarantineStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if _, err := newTablePrefix(string(s.tables)); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.Exec(s.tables.rewrite(`
+		CREATE TABLE IF NOT EXISTS projection_quarantine (
+			projection_name TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			aggregate_id TEXT NOT NULL,
+			event_type TEXT NOT NULL,
+			error TEXT NOT NULL,
+			quarantined_at INTEGER NOT NULL,
+			PRIMARY KEY (projection_name, event_id)
+		)
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create projection_quarantine table: %w", err)
+	}
+
+	return s, nil
+}
+
+// Quarantine records an event a projection failed to handle.
+func (s *QuarantineStore) Quarantine(ctx context.Context, event *store.QuarantinedEvent) error {
+	_, err := s.db.ExecContext(ctx, s.tables.rewrite(`
+		INSERT INTO projection_quarantine (projection_name, event_id, position, aggregate_id, event_type, error, quarantined_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(projection_name, event_id) DO UPDATE SET
+			position = excluded.position,
+			aggregate_id = excluded.aggregate_id,
+			event_type = excluded.event_type,
+			error = excluded.error,
+			quarantined_at = excluded.quarantined_at
+	`), event.ProjectionName, event.EventID, event.Position, event.AggregateID, event.EventType, event.Error,
+		event.QuarantinedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to quarantine event: %w", classifyError(err))
+	}
+	return nil
+}
+
+// ListQuarantined returns the quarantined events of projectionName in event
+// store order.
+func (s *QuarantineStore) ListQuarantined(ctx context.Context, projectionName string) ([]*store.QuarantinedEvent, error) {
+	rows, err := s.db.QueryContext(ctx, s.tables.rewrite(`
+		SELECT event_id, position, aggregate_id, event_type, error, quarantined_at
+		FROM projection_quarantine
+		WHERE projection_name = ?
+		ORDER BY position, event_id
+	`), projectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quarantined events: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var events []*store.QuarantinedEvent
+	for rows.Next() {
+		event := &store.QuarantinedEvent{ProjectionName: projectionName}
+		var quarantinedAt int64
+		if err := rows.Scan(&event.EventID, &event.Position, &event.AggregateID, &event.EventType, &event.Error, &quarantinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan quarantined event: %w", err)
+		}
+		event.QuarantinedAt = domain.TimeFromUnix(quarantinedAt)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list quarantined events: %w", err)
+	}
+
+	return events, nil
+}
+
+// ClearQuarantine removes the quarantined events of projectionName.
+func (s *QuarantineStore) ClearQuarantine(ctx context.Context, projectionName string) error {
+	_, err := s.db.ExecContext(ctx, s.tables.rewrite(`
+		DELETE FROM projection_quarantine WHERE projection_name = ?
+	`), projectionName)
+	if err != nil {
+		return fmt.Errorf("failed to clear quarantine: %w", classifyError(err))
+	}
+	return nil
+}
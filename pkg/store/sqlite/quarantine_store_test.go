@@ -0,0 +1,136 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestRebuildEventErrorPolicy(t *testing.T) {
+	ctx := context.Background()
+	errPoison := errors.New("cannot upcast event")
+
+	// A projection failing on the events of agg-poison
+	eventStore, _, projection := newCountingProjection(t, ":memory:", func(b *sqlite.SQLiteProjectionBuilder) {
+		b.OnWithTx("test.Poisoned", func(ctx context.Context, tx *sql.Tx, envelope *domain.EventEnvelope) error {
+			return errPoison
+		})
+	})
+	for i := 1; i <= 6; i++ {
+		eventType, aggregateID := "test.Happened", fmt.Sprintf("agg-%d", i)
+		if i == 2 || i == 5 {
+			eventType, aggregateID = "test.Poisoned", fmt.Sprintf("agg-poison-%d", i)
+		}
+		if err := eventStore.AppendEvents(ctx, aggregateID, 0, []*domain.Event{{
+			ID:            fmt.Sprintf("evt-%d", i),
+			AggregateID:   aggregateID,
+			AggregateType: "TestAggregate",
+			EventType:     eventType,
+			Version:       1,
+			Timestamp:     time.Now(),
+			Data:          []byte("data"),
+		}}); err != nil {
+			t.Fatalf("failed to append event: %v", err)
+		}
+	}
+
+	quarantine, err := sqlite.NewQuarantineStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create quarantine store: %v", err)
+	}
+	statusStore, err := sqlite.NewProjectionStatusStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create status store: %v", err)
+	}
+	counted := func(t *testing.T) int {
+		t.Helper()
+		var n int
+		if err := eventStore.DB().QueryRow(`SELECT COUNT(*) FROM counts`).Scan(&n); err != nil {
+			t.Fatalf("failed to count rows: %v", err)
+		}
+		return n
+	}
+
+	t.Run("Fail", func(t *testing.T) {
+		if err := projection.Rebuild(ctx); !errors.Is(err, errPoison) {
+			t.Fatalf("expected the rebuild to fail on the poison event, got %v", err)
+		}
+		if err := projection.Rebuild(ctx, store.OnEventError(store.RebuildQuarantine)); err == nil {
+			t.Error("expected quarantining without a quarantine store to fail")
+		}
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		var report store.RebuildReport
+		err := projection.Rebuild(ctx, store.OnEventError(store.RebuildSkip),
+			store.WithRebuildReport(func(r store.RebuildReport) { report = r }))
+		if err != nil {
+			t.Fatalf("failed to rebuild: %v", err)
+		}
+		if !reflect.DeepEqual(report.Skipped, []string{"evt-2", "evt-5"}) || len(report.Quarantined) != 0 || report.EventsProcessed != 6 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		if n := counted(t); n != 4 {
+			t.Errorf("expected 4 applied events, got %d", n)
+		}
+	})
+
+	t.Run("Quarantine", func(t *testing.T) {
+		// Stale records of an earlier rebuild are cleared
+		if err := quarantine.Quarantine(ctx, &store.QuarantinedEvent{
+			ProjectionName: "counting", EventID: "evt-fixed", EventType: "test.Happened", QuarantinedAt: time.Now(),
+		}); err != nil {
+			t.Fatalf("failed to quarantine: %v", err)
+		}
+
+		var report store.RebuildReport
+		err := projection.Rebuild(ctx,
+			store.OnEventError(store.RebuildQuarantine),
+			store.WithQuarantineStore(quarantine),
+			store.WithRebuildReport(func(r store.RebuildReport) { report = r }))
+		if err != nil {
+			t.Fatalf("failed to rebuild: %v", err)
+		}
+		if !reflect.DeepEqual(report.Quarantined, []string{"evt-2", "evt-5"}) {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		if n := counted(t); n != 4 {
+			t.Errorf("expected 4 applied events, got %d", n)
+		}
+
+		quarantined, err := quarantine.ListQuarantined(ctx, "counting")
+		if err != nil {
+			t.Fatalf("failed to list quarantined events: %v", err)
+		}
+		if len(quarantined) != 2 {
+			t.Fatalf("expected 2 quarantined events, got %+v", quarantined)
+		}
+		if q := quarantined[0]; q.EventID != "evt-2" || q.AggregateID != "agg-poison-2" ||
+			q.EventType != "test.Poisoned" || q.Position != 2 || q.QuarantinedAt.IsZero() {
+			t.Errorf("unexpected quarantined event: %+v", q)
+		}
+
+		state, err := statusStore.Load("counting")
+		if err != nil {
+			t.Fatalf("failed to load status: %v", err)
+		}
+		if state.Status != store.ProjectionStatusReady || state.Message != "Rebuild complete - processed 6 events, quarantined 2 events [evt-2 evt-5]" {
+			t.Errorf("unexpected status: %+v", state)
+		}
+
+		if err := quarantine.ClearQuarantine(ctx, "counting"); err != nil {
+			t.Fatalf("failed to clear quarantine: %v", err)
+		}
+		if quarantined, _ := quarantine.ListQuarantined(ctx, "counting"); len(quarantined) != 0 {
+			t.Errorf("expected an empty quarantine, got %+v", quarantined)
+		}
+	})
+}
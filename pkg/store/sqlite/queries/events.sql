@@ -6,9 +6,10 @@ WHERE aggregate_id = ?;
 -- name: InsertEvent :exec
 INSERT INTO events (
     event_id, aggregate_id, aggregate_type, event_type,
-    version, timestamp, data, metadata, constraints, position
+    version, timestamp, data, metadata, constraints, position,
+    correlation_id, causation_id, principal_id, tenant_id
 )
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULL);
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, ?, ?, ?, ?);
 
 -- name: LoadEventByID :one
 SELECT event_id, aggregate_id, aggregate_type, event_type,
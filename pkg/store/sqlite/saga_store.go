@@ -0,0 +1,184 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// SagaStore implements store.SagaStore for SQLite, keeping saga instances in
+// the saga_instances table.
+type SagaStore struct {
+	db     *sql.DB
+	tables tablePrefix
+}
+
+// SagaStoreOption configures a SagaStore.
+type SagaStoreOption func(*SagaStore)
+
+// WithSagaTablePrefix prepends prefix to the saga_instances table (see
+// WithTablePrefix).
+func WithSagaTablePrefix(prefix string) SagaStoreOption {
+	return func(s *SagaStore) {
+		s.tables = tablePrefix(prefix)
+	}
+}
+
+// NewSagaStore creates a SQLite saga store, creating its table if needed.
+//
+// Example:
+//
+//	sagas, _ := sqlite.NewSagaStore(eventStore.DB())
+//	transfer := saga.NewBuilder("money-transfer", transport, sagas).
+//	    StartWith(withdraw).Compensate(refund).
+//	    On(accountv1.MoneyWithdrawnEventType).Then(deposit).
+//	    On(accountv1.MoneyDepositedEventType).Complete().
+//	    Build()
+func NewSagaStore(db *sql.DB, opts ...SagaStoreOption) (*SagaStore, error) {
+	s := &SagaStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if _, err := newTablePrefix(string(s.tables)); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.Exec(s.tables.rewrite(`
+		CREATE TABLE IF NOT EXISTS saga_instances (
+			saga_name TEXT NOT NULL,
+			id TEXT NOT NULL,
+			status TEXT NOT NULL,
+			data TEXT NOT NULL,
+			steps TEXT NOT NULL,
+			deadline_ms INTEGER NOT NULL,
+			error TEXT NOT NULL,
+			updated_at INTEGER NOT NULL,
+			PRIMARY KEY (saga_name, id)
+		)
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create saga_instances table: %w", err)
+	}
+
+	return s, nil
+}
+
+// Save creates or replaces a saga instance.
+func (s *SagaStore) Save(ctx context.Context, instance *store.SagaInstance) error {
+	data, err := json.Marshal(instance.Data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal saga data: %w", err)
+	}
+	steps, err := json.Marshal(instance.Steps)
+	if err != nil {
+		return fmt.Errorf("failed to marshal saga steps: %w", err)
+	}
+	var deadline int64
+	if !instance.Deadline.IsZero() {
+		deadline = instance.Deadline.UnixMilli()
+	}
+
+	_, err = s.db.ExecContext(ctx, s.tables.rewrite(`
+		INSERT INTO saga_instances (saga_name, id, status, data, steps, deadline_ms, error, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(saga_name, id) DO UPDATE SET
+			status = excluded.status,
+			data = excluded.data,
+			steps = excluded.steps,
+			deadline_ms = excluded.deadline_ms,
+			error = excluded.error,
+			updated_at = excluded.updated_at
+	`), instance.SagaName, instance.ID, string(instance.Status), string(data), string(steps), deadline,
+		instance.Error, instance.UpdatedAt.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to save saga instance: %w", classifyError(err))
+	}
+	return nil
+}
+
+// Load loads a saga instance, or returns store.ErrSagaNotFound.
+func (s *SagaStore) Load(ctx context.Context, sagaName, id string) (*store.SagaInstance, error) {
+	row := s.db.QueryRowContext(ctx, s.tables.rewrite(`
+		SELECT saga_name, id, status, data, steps, deadline_ms, error, updated_at
+		FROM saga_instances
+		WHERE saga_name = ? AND id = ?
+	`), sagaName, id)
+	instance, err := scanSagaInstance(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s/%s", store.ErrSagaNotFound, sagaName, id)
+	}
+	return instance, err
+}
+
+// ListExpired returns the running instances of sagaName whose deadline is
+// before now, earliest deadline first.
+func (s *SagaStore) ListExpired(ctx context.Context, sagaName string, now time.Time) ([]*store.SagaInstance, error) {
+	rows, err := s.db.QueryContext(ctx, s.tables.rewrite(`
+		SELECT saga_name, id, status, data, steps, deadline_ms, error, updated_at
+		FROM saga_instances
+		WHERE saga_name = ? AND status = ? AND deadline_ms > 0 AND deadline_ms < ?
+		ORDER BY deadline_ms, id
+	`), sagaName, string(store.SagaStatusRunning), now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired sagas: %w", classifyError(err))
+	}
+	defer rows.Close()
+
+	var instances []*store.SagaInstance
+	for rows.Next() {
+		instance, err := scanSagaInstance(rows)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list expired sagas: %w", err)
+	}
+
+	return instances, nil
+}
+
+// Delete deletes a saga instance.
+func (s *SagaStore) Delete(ctx context.Context, sagaName, id string) error {
+	_, err := s.db.ExecContext(ctx, s.tables.rewrite(`
+		DELETE FROM saga_instances WHERE saga_name = ? AND id = ?
+	`), sagaName, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saga instance: %w", classifyError(err))
+	}
+	return nil
+}
+
+// scanSagaInstance scans a saga_instances row.
+func scanSagaInstance(row interface{ Scan(...any) error }) (*store.SagaInstance, error) {
+	instance := &store.SagaInstance{}
+	var status, data, steps string
+	var deadline, updatedAt int64
+	if err := row.Scan(&instance.SagaName, &instance.ID, &status, &data, &steps, &deadline, &instance.Error, &updatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan saga instance: %w", err)
+	}
+	instance.Status = store.SagaStatus(status)
+	if err := json.Unmarshal([]byte(data), &instance.Data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal saga data: %w", err)
+	}
+	if err := json.Unmarshal([]byte(steps), &instance.Steps); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal saga steps: %w", err)
+	}
+	if deadline > 0 {
+		instance.Deadline = time.UnixMilli(deadline)
+	}
+	instance.UpdatedAt = domain.TimeFromUnix(updatedAt)
+	return instance, nil
+}
+
+var _ store.SagaStore = (*SagaStore)(nil)
@@ -0,0 +1,100 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+)
+
+func TestSagaStore(t *testing.T) {
+	ctx := context.Background()
+	eventStore, err := sqlite.NewEventStore(sqlite.WithDSN(":memory:"))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer eventStore.Close()
+
+	sagas, err := sqlite.NewSagaStore(eventStore.DB())
+	if err != nil {
+		t.Fatalf("failed to create saga store: %v", err)
+	}
+
+	if _, err := sagas.Load(ctx, "transfer", "t-1"); !errors.Is(err, store.ErrSagaNotFound) {
+		t.Fatalf("expected ErrSagaNotFound, got %v", err)
+	}
+
+	now := time.Now()
+	instance := &store.SagaInstance{
+		SagaName:  "transfer",
+		ID:        "t-1",
+		Status:    store.SagaStatusRunning,
+		Data:      map[string]string{"from": "acc-a", "to": "acc-b", "amount": "25.00"},
+		Steps:     []string{"start"},
+		Deadline:  now.Add(-time.Second),
+		UpdatedAt: now,
+	}
+	if err := sagas.Save(ctx, instance); err != nil {
+		t.Fatalf("failed to save instance: %v", err)
+	}
+	// Neither expired: no deadline, and a deadline still ahead
+	for id, deadline := range map[string]time.Time{"t-2": {}, "t-3": now.Add(time.Hour)} {
+		if err := sagas.Save(ctx, &store.SagaInstance{SagaName: "transfer", ID: id, Status: store.SagaStatusRunning, Deadline: deadline, UpdatedAt: now}); err != nil {
+			t.Fatalf("failed to save instance: %v", err)
+		}
+	}
+
+	loaded, err := sagas.Load(ctx, "transfer", "t-1")
+	if err != nil {
+		t.Fatalf("failed to load instance: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Data, instance.Data) || !reflect.DeepEqual(loaded.Steps, instance.Steps) ||
+		loaded.Status != store.SagaStatusRunning || loaded.Deadline.UnixMilli() != instance.Deadline.UnixMilli() {
+		t.Errorf("expected %+v, got %+v", instance, loaded)
+	}
+
+	expired, err := sagas.ListExpired(ctx, "transfer", now)
+	if err != nil {
+		t.Fatalf("failed to list expired instances: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != "t-1" {
+		t.Errorf("expected t-1 to be expired, got %v", expired)
+	}
+
+	// Finished instances do not expire
+	instance.Status = store.SagaStatusCompensated
+	instance.Error = "timed out"
+	if err := sagas.Save(ctx, instance); err != nil {
+		t.Fatalf("failed to update instance: %v", err)
+	}
+	if expired, _ := sagas.ListExpired(ctx, "transfer", now); len(expired) != 0 {
+		t.Errorf("expected no expired instances, got %v", expired)
+	}
+	if loaded, _ := sagas.Load(ctx, "transfer", "t-1"); loaded.Error != "timed out" {
+		t.Errorf("expected the updated instance, got %+v", loaded)
+	}
+
+	if err := sagas.Delete(ctx, "transfer", "t-1"); err != nil {
+		t.Fatalf("failed to delete instance: %v", err)
+	}
+	if _, err := sagas.Load(ctx, "transfer", "t-1"); !errors.Is(err, store.ErrSagaNotFound) {
+		t.Errorf("expected ErrSagaNotFound after delete, got %v", err)
+	}
+
+	// A prefixed store keeps its instances apart
+	prefixed, err := sqlite.NewSagaStore(eventStore.DB(), sqlite.WithSagaTablePrefix("billing_"))
+	if err != nil {
+		t.Fatalf("failed to create prefixed saga store: %v", err)
+	}
+	if _, err := prefixed.Load(ctx, "transfer", "t-2"); !errors.Is(err, store.ErrSagaNotFound) {
+		t.Errorf("expected ErrSagaNotFound in the prefixed store, got %v", err)
+	}
+	var tables int
+	if err := eventStore.DB().QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'billing_saga_instances'`).Scan(&tables); err != nil || tables != 1 {
+		t.Errorf("expected the billing_saga_instances table, got %d (%v)", tables, err)
+	}
+}
@@ -10,6 +10,10 @@ CREATE TABLE IF NOT EXISTS events (
     metadata TEXT NOT NULL,
     constraints TEXT,
     position INTEGER,
+    correlation_id TEXT,
+    causation_id TEXT,
+    principal_id TEXT,
+    tenant_id TEXT,
     UNIQUE (aggregate_id, version)
 );
 
@@ -25,6 +29,23 @@ CREATE INDEX IF NOT EXISTS idx_events_type
 CREATE INDEX IF NOT EXISTS idx_events_position
     ON events(position);
 
+-- Index for business-time ordered reads
+CREATE INDEX IF NOT EXISTS idx_events_timestamp_position
+    ON events(timestamp, position);
+
+-- Indexes for metadata lookups (see LoadCompensations, LoadEventsByCorrelationID)
+CREATE INDEX IF NOT EXISTS idx_events_causation
+    ON events(causation_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_events_correlation
+    ON events(correlation_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_events_principal
+    ON events(principal_id, position);
+
+CREATE INDEX IF NOT EXISTS idx_events_tenant
+    ON events(tenant_id, position);
+
 -- Unique constraints table: enforces uniqueness
 CREATE TABLE IF NOT EXISTS unique_constraints (
     index_name TEXT NOT NULL,
@@ -15,23 +15,72 @@ import (
 type SnapshotStore struct {
 	db      *sql.DB
 	queries *sqlcgen.Queries
+	tables  tablePrefix
+	codec   store.SnapshotCodec // Compresses saved snapshots (nil = off)
+}
+
+// SnapshotStoreOption configures a SnapshotStore.
+type SnapshotStoreOption func(*SnapshotStore)
+
+// WithSnapshotTablePrefix reads and writes the snapshots table of an event
+// store created with the same WithTablePrefix. The snapshots table is created
+// by the event store's migrations.
+func WithSnapshotTablePrefix(prefix string) SnapshotStoreOption {
+	return func(s *SnapshotStore) {
+		s.tables = tablePrefix(prefix)
+	}
+}
+
+// WithSnapshotCompression compresses the data of saved snapshots with codec,
+// e.g. store.NewZstdSnapshotCodec. The codec is recorded in each snapshot's
+// metadata (SnapshotMetadata.Compression) and loaded snapshots are
+// decompressed transparently, so snapshots saved before compression was
+// enabled, or with another built-in codec, stay readable.
+func WithSnapshotCompression(codec store.SnapshotCodec) SnapshotStoreOption {
+	return func(s *SnapshotStore) {
+		s.codec = codec
+	}
 }
 
 // NewSnapshotStore creates a new SQLite-backed snapshot store.
-func NewSnapshotStore(db *sql.DB) *SnapshotStore {
-	return &SnapshotStore{
-		db:      db,
-		queries: sqlcgen.New(db),
+func NewSnapshotStore(db *sql.DB, opts ...SnapshotStoreOption) *SnapshotStore {
+	s := &SnapshotStore{db: db}
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.queries = s.tables.queries(db)
+	return s
 }
 
 // SaveSnapshot persists a snapshot for an aggregate.
 func (s *SnapshotStore) SaveSnapshot(snapshot *store.Snapshot) error {
 	ctx := context.Background()
 
+	data, meta := snapshot.Data, snapshot.Metadata
+	if meta != nil && meta.Compression != "" {
+		// Record how this store saved the data, not how it was loaded
+		copied := *meta
+		copied.Compression = ""
+		meta = &copied
+	}
+	if s.codec != nil {
+		compressed, err := s.codec.Compress(snapshot.Data)
+		if err != nil {
+			return fmt.Errorf("failed to compress snapshot: %w", err)
+		}
+		data = compressed
+
+		copied := store.SnapshotMetadata{}
+		if meta != nil {
+			copied = *meta
+		}
+		copied.Compression = s.codec.Name()
+		meta = &copied
+	}
+
 	metadata := ""
-	if snapshot.Metadata != nil {
-		m, err := snapshot.Metadata.MarshalMetadata()
+	if meta != nil {
+		m, err := meta.MarshalMetadata()
 		if err != nil {
 			return fmt.Errorf("failed to marshal metadata: %w", err)
 		}
@@ -42,7 +91,7 @@ func (s *SnapshotStore) SaveSnapshot(snapshot *store.Snapshot) error {
 		AggregateID:   snapshot.AggregateID,
 		AggregateType: snapshot.AggregateType,
 		Version:       snapshot.Version,
-		Data:          snapshot.Data,
+		Data:          data,
 		CreatedAt:     snapshot.CreatedAt.Unix(),
 		Metadata: sql.NullString{
 			String: metadata,
@@ -69,7 +118,7 @@ func (s *SnapshotStore) GetLatestSnapshot(aggregateID string) (*store.Snapshot,
 		return nil, fmt.Errorf("failed to get latest snapshot: %w", err)
 	}
 
-	return rowToSnapshot(row)
+	return s.rowToSnapshot(row)
 }
 
 // GetSnapshotBeforeVersion retrieves the latest snapshot at or before a specific version.
@@ -87,7 +136,7 @@ func (s *SnapshotStore) GetSnapshotBeforeVersion(aggregateID string, version int
 		return nil, fmt.Errorf("failed to get snapshot before version: %w", err)
 	}
 
-	return rowToSnapshot(row)
+	return s.rowToSnapshot(row)
 }
 
 // DeleteOldSnapshots removes snapshots older than the specified version for an aggregate.
@@ -106,6 +155,18 @@ func (s *SnapshotStore) DeleteOldSnapshots(aggregateID string, olderThanVersion
 	return nil
 }
 
+// DeleteSnapshot removes the snapshot of an aggregate at exactly version.
+// Deleting a snapshot that does not exist is not an error.
+func (s *SnapshotStore) DeleteSnapshot(aggregateID string, version int64) error {
+	_, err := s.db.ExecContext(context.Background(),
+		s.tables.rewrite("DELETE FROM snapshots WHERE aggregate_id = ? AND version = ?"), aggregateID, version)
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+
+	return nil
+}
+
 // GetSnapshotStats returns statistics about snapshots in the store.
 func (s *SnapshotStore) GetSnapshotStats() (*store.SnapshotStats, error) {
 	ctx := context.Background()
@@ -145,8 +206,9 @@ func (s *SnapshotStore) GetSnapshotStats() (*store.SnapshotStats, error) {
 	}, nil
 }
 
-// Helper function to convert sqlc snapshot to store.Snapshot
-func rowToSnapshot(row sqlcgen.Snapshot) (*store.Snapshot, error) {
+// rowToSnapshot converts a sqlc snapshot to a store.Snapshot, decompressing
+// its data.
+func (s *SnapshotStore) rowToSnapshot(row sqlcgen.Snapshot) (*store.Snapshot, error) {
 	var metadata *store.SnapshotMetadata
 	if row.Metadata.Valid && row.Metadata.String != "" {
 		m, err := store.UnmarshalMetadata(row.Metadata.String)
@@ -156,11 +218,20 @@ func rowToSnapshot(row sqlcgen.Snapshot) (*store.Snapshot, error) {
 		metadata = m
 	}
 
+	data := row.Data
+	if metadata != nil {
+		decompressed, err := store.DecompressSnapshot(metadata.Compression, row.Data, s.codec)
+		if err != nil {
+			return nil, err
+		}
+		data = decompressed
+	}
+
 	return &store.Snapshot{
 		AggregateID:   row.AggregateID,
 		AggregateType: row.AggregateType,
 		Version:       row.Version,
-		Data:          row.Data,
+		Data:          data,
 		CreatedAt:     time.Unix(row.CreatedAt, 0),
 		Metadata:      metadata,
 	}, nil
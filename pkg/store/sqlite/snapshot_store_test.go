@@ -0,0 +1,178 @@
+package sqlite_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+	"time"
+
+	accountv1 "github.com/plaenen/eventstore/examples/pb/account/v1"
+	storelib "github.com/plaenen/eventstore/pkg/store"
+	"github.com/plaenen/eventstore/pkg/store/sqlite"
+	"google.golang.org/protobuf/proto"
+)
+
+// accountHistory returns the serialized state of an account with n
+// transactions, a realistic large aggregate.
+func accountHistory(t testing.TB, n int) []byte {
+	t.Helper()
+	history := &accountv1.AccountHistoryResponse{}
+	for i := range n {
+		history.Transactions = append(history.Transactions, &accountv1.TransactionView{
+			TransactionId: fmt.Sprintf("tx-%08d", i),
+			Type:          accountv1.TransactionType(2 + i%2),
+			Amount:        fmt.Sprintf("%d.%02d", 10+i%500, i%100),
+			BalanceAfter:  fmt.Sprintf("%d.%02d", 1000+i*7, (i*13)%100),
+			Timestamp:     1700000000 + int64(i)*60,
+		})
+	}
+	data, err := proto.Marshal(history)
+	if err != nil {
+		t.Fatalf("failed to marshal history: %v", err)
+	}
+	return data
+}
+
+func snapshotCodecs(t testing.TB) map[string]storelib.SnapshotCodec {
+	t.Helper()
+	gzipCodec, err := storelib.NewGzipSnapshotCodec(gzip.DefaultCompression)
+	if err != nil {
+		t.Fatalf("failed to create gzip codec: %v", err)
+	}
+	zstdCodec, err := storelib.NewZstdSnapshotCodec()
+	if err != nil {
+		t.Fatalf("failed to create zstd codec: %v", err)
+	}
+	return map[string]storelib.SnapshotCodec{"gzip": gzipCodec, "zstd": zstdCodec}
+}
+
+func TestSnapshotCompression(t *testing.T) {
+	data := accountHistory(t, 200)
+
+	for name, codec := range snapshotCodecs(t) {
+		t.Run(name, func(t *testing.T) {
+			events, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+			if err != nil {
+				t.Fatalf("failed to create event store: %v", err)
+			}
+			defer events.Close()
+			plain := sqlite.NewSnapshotStore(events.DB())
+			compressed := sqlite.NewSnapshotStore(events.DB(), sqlite.WithSnapshotCompression(codec))
+
+			metadata := &storelib.SnapshotMetadata{Size: int64(len(data)), SnapshotType: "proto"}
+			if err := compressed.SaveSnapshot(&storelib.Snapshot{
+				AggregateID: "acc-1", AggregateType: "Account", Version: 2,
+				Data: data, CreatedAt: time.Now(), Metadata: metadata,
+			}); err != nil {
+				t.Fatalf("failed to save snapshot: %v", err)
+			}
+			if metadata.Compression != "" {
+				t.Error("expected the caller's metadata to be left untouched")
+			}
+
+			var stored []byte
+			if err := events.DB().QueryRow("SELECT data FROM snapshots WHERE version = 2").Scan(&stored); err != nil {
+				t.Fatalf("failed to read stored snapshot: %v", err)
+			}
+			if len(stored) >= len(data) {
+				t.Errorf("expected the stored snapshot to be smaller than %d bytes, got %d", len(data), len(stored))
+			}
+
+			loaded, err := compressed.GetLatestSnapshot("acc-1")
+			if err != nil {
+				t.Fatalf("failed to load snapshot: %v", err)
+			}
+			if !bytes.Equal(loaded.Data, data) {
+				t.Error("expected the loaded snapshot to be decompressed")
+			}
+			if loaded.Metadata.Compression != codec.Name() || loaded.Metadata.SnapshotType != "proto" {
+				t.Errorf("expected %s compression and the original metadata, got %+v", codec.Name(), loaded.Metadata)
+			}
+
+			// A store without compression reads it too, and its own
+			// snapshots stay readable once compression is enabled
+			if loaded, err := plain.GetLatestSnapshot("acc-1"); err != nil || !bytes.Equal(loaded.Data, data) {
+				t.Errorf("expected an uncompressed store to read the snapshot (%v)", err)
+			}
+			if err := plain.SaveSnapshot(&storelib.Snapshot{
+				AggregateID: "acc-2", AggregateType: "Account", Version: 1,
+				Data: data, CreatedAt: time.Now(),
+			}); err != nil {
+				t.Fatalf("failed to save uncompressed snapshot: %v", err)
+			}
+			if loaded, err := compressed.GetSnapshotBeforeVersion("acc-2", 1); err != nil || !bytes.Equal(loaded.Data, data) {
+				t.Errorf("expected the uncompressed snapshot to stay readable (%v)", err)
+			}
+		})
+	}
+}
+
+func TestSnapshotCompressionUnknownCodec(t *testing.T) {
+	events, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+	if err != nil {
+		t.Fatalf("failed to create event store: %v", err)
+	}
+	defer events.Close()
+
+	snapshots := sqlite.NewSnapshotStore(events.DB())
+	if err := snapshots.SaveSnapshot(&storelib.Snapshot{
+		AggregateID: "acc-1", AggregateType: "Account", Version: 1,
+		Data: []byte("data"), CreatedAt: time.Now(),
+		Metadata: &storelib.SnapshotMetadata{},
+	}); err != nil {
+		t.Fatalf("failed to save snapshot: %v", err)
+	}
+	if _, err := events.DB().Exec(`UPDATE snapshots SET metadata = '{"compression":"lz4"}'`); err != nil {
+		t.Fatalf("failed to update metadata: %v", err)
+	}
+
+	if _, err := snapshots.GetLatestSnapshot("acc-1"); err == nil {
+		t.Error("expected an unknown compression to fail loading")
+	}
+}
+
+// BenchmarkSnapshotCompression saves and loads the snapshot of an account
+// with 1000 transactions. Compare the stored-bytes metric for the size
+// reduction of each codec.
+func BenchmarkSnapshotCompression(b *testing.B) {
+	data := accountHistory(b, 1000)
+	codecs := snapshotCodecs(b)
+
+	for _, name := range []string{"none", "gzip", "zstd"} {
+		b.Run(name, func(b *testing.B) {
+			events, err := sqlite.NewEventStore(sqlite.WithMemoryDatabase(), sqlite.WithWALMode(false))
+			if err != nil {
+				b.Fatalf("failed to create event store: %v", err)
+			}
+			defer events.Close()
+
+			var opts []sqlite.SnapshotStoreOption
+			if codec, ok := codecs[name]; ok {
+				opts = append(opts, sqlite.WithSnapshotCompression(codec))
+			}
+			snapshots := sqlite.NewSnapshotStore(events.DB(), opts...)
+
+			version := int64(0)
+			for b.Loop() {
+				version++
+				if err := snapshots.SaveSnapshot(&storelib.Snapshot{
+					AggregateID: "acc-1", AggregateType: "Account", Version: version,
+					Data: data, CreatedAt: time.Now(),
+				}); err != nil {
+					b.Fatalf("failed to save snapshot: %v", err)
+				}
+				if _, err := snapshots.GetLatestSnapshot("acc-1"); err != nil {
+					b.Fatalf("failed to load snapshot: %v", err)
+				}
+			}
+
+			var stored int64
+			if err := events.DB().QueryRow("SELECT length(data) FROM snapshots WHERE version = 1").Scan(&stored); err != nil {
+				b.Fatalf("failed to read stored size: %v", err)
+			}
+			b.ReportMetric(float64(len(data)), "raw-bytes")
+			b.ReportMetric(float64(stored), "stored-bytes")
+		})
+	}
+}
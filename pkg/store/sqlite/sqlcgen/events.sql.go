@@ -26,9 +26,10 @@ func (q *Queries) GetAggregateVersion(ctx context.Context, aggregateID string) (
 const insertEvent = `-- name: InsertEvent :exec
 INSERT INTO events (
     event_id, aggregate_id, aggregate_type, event_type,
-    version, timestamp, data, metadata, constraints, position
+    version, timestamp, data, metadata, constraints, position,
+    correlation_id, causation_id, principal_id, tenant_id
 )
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NULL, ?, ?, ?, ?)
 `
 
 type InsertEventParams struct {
@@ -41,6 +42,10 @@ type InsertEventParams struct {
 	Data          []byte         `json:"data"`
 	Metadata      string         `json:"metadata"`
 	Constraints   sql.NullString `json:"constraints"`
+	CorrelationID sql.NullString `json:"correlation_id"`
+	CausationID   sql.NullString `json:"causation_id"`
+	PrincipalID   sql.NullString `json:"principal_id"`
+	TenantID      sql.NullString `json:"tenant_id"`
 }
 
 func (q *Queries) InsertEvent(ctx context.Context, arg InsertEventParams) error {
@@ -54,6 +59,10 @@ func (q *Queries) InsertEvent(ctx context.Context, arg InsertEventParams) error
 		arg.Data,
 		arg.Metadata,
 		arg.Constraints,
+		arg.CorrelationID,
+		arg.CausationID,
+		arg.PrincipalID,
+		arg.TenantID,
 	)
 	return err
 }
@@ -19,6 +19,10 @@ type Event struct {
 	Metadata      string         `json:"metadata"`
 	Constraints   sql.NullString `json:"constraints"`
 	Position      sql.NullInt64  `json:"position"`
+	CorrelationID sql.NullString `json:"correlation_id"`
+	CausationID   sql.NullString `json:"causation_id"`
+	PrincipalID   sql.NullString `json:"principal_id"`
+	TenantID      sql.NullString `json:"tenant_id"`
 }
 
 type ProcessedCommand struct {
@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// SubjectKeyStore implements store.KeyStore for SQLite, keeping the data keys
+// of store.EncryptedEventCodec in the subject_keys table.
+//
+// Forgetting a subject deletes its key row, but SQLite may keep the deleted
+// bytes in free pages and the WAL until they are overwritten. Keep the keys in
+// a database of their own, opened with PRAGMA secure_delete, to make sure a
+// forgotten key is gone from disk.
+type SubjectKeyStore struct {
+	db     *sql.DB
+	tables tablePrefix
+}
+
+// SubjectKeyStoreOption configures a SubjectKeyStore.
+type SubjectKeyStoreOption func(*SubjectKeyStore)
+
+// WithSubjectKeyTablePrefix prepends prefix to the subject_keys table (see
+// WithTablePrefix).
+func WithSubjectKeyTablePrefix(prefix string) SubjectKeyStoreOption {
+	return func(s *SubjectKeyStore) {
+		s.tables = tablePrefix(prefix)
+	}
+}
+
+// NewSubjectKeyStore creates a SQLite subject key store, creating its table if
+// needed.
+//
+// Example:
+//
+//	keys, _ := sqlite.NewSubjectKeyStore(keysDB)
+//	encrypted := store.NewCodecStore(eventStore, store.NewEncryptedEventCodec(keys))
+//	err := encrypted.ForgetSubject(ctx, "customer-42")
+func NewSubjectKeyStore(db *sql.DB, opts ...SubjectKeyStoreOption) (*SubjectKeyStore, error) {
+	s := &SubjectKeyStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if _, err := newTablePrefix(string(s.tables)); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.Exec(s.tables.rewrite(`
+		CREATE TABLE IF NOT EXISTS subject_keys (
+			subject_id TEXT PRIMARY KEY,
+			key BLOB NOT NULL,
+			created_at INTEGER NOT NULL
+		)
+	`))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subject_keys table: %w", err)
+	}
+
+	return s, nil
+}
+
+// LoadKey returns the key of subjectID, or store.ErrSubjectKeyNotFound.
+func (s *SubjectKeyStore) LoadKey(ctx context.Context, subjectID string) ([]byte, error) {
+	var key []byte
+	err := s.db.QueryRowContext(ctx, s.tables.rewrite(
+		"SELECT key FROM subject_keys WHERE subject_id = ?"), subjectID).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, store.ErrSubjectKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subject key: %w", contextError(ctx, err))
+	}
+	return key, nil
+}
+
+// CreateKey stores key for subjectID unless the subject has a key already,
+// and returns the subject's key.
+func (s *SubjectKeyStore) CreateKey(ctx context.Context, subjectID string, key []byte) ([]byte, error) {
+	var stored []byte
+	err := s.db.QueryRowContext(ctx, s.tables.rewrite(`
+		INSERT INTO subject_keys (subject_id, key, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(subject_id) DO UPDATE SET subject_id = excluded.subject_id
+		RETURNING key
+	`), subjectID, key, time.Now().Unix()).Scan(&stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create subject key: %w", contextError(ctx, err))
+	}
+	return stored, nil
+}
+
+// DeleteKey deletes the key of subjectID, if any.
+func (s *SubjectKeyStore) DeleteKey(ctx context.Context, subjectID string) error {
+	_, err := s.db.ExecContext(ctx, s.tables.rewrite(
+		"DELETE FROM subject_keys WHERE subject_id = ?"), subjectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete subject key: %w", contextError(ctx, err))
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	"github.com/plaenen/eventstore/pkg/store/sqlite/sqlcgen"
+)
+
+// prefixedIdentifiers matches the tables and indexes this package creates. The
+// SQL in migrations, sqlc queries and the stores names them unprefixed; a
+// tablePrefix rewrites them at execution time.
+var prefixedIdentifiers = regexp.MustCompile(
+	`\b(events|unique_constraints|processed_commands|snapshots|projection_checkpoints|projection_status|projection_quarantine|projection_dead_letters|saga_instances|event_outbox|subject_keys|schema_migrations|checkpoint_schema_migrations|idx_[A-Za-z0-9_]+)\b`)
+
+// tablePrefix is prepended to every table and index name of a store, so that
+// independent stores can share one database file.
+type tablePrefix string
+
+// newTablePrefix validates prefix, which must be empty or a valid unquoted
+// SQLite identifier such as "billing_".
+func newTablePrefix(prefix string) (tablePrefix, error) {
+	if prefix != "" && !identifierPattern.MatchString(prefix) {
+		return "", fmt.Errorf("invalid table prefix %q: use letters, digits and underscores", prefix)
+	}
+	return tablePrefix(prefix), nil
+}
+
+// rewrite prefixes the table and index names in query.
+func (p tablePrefix) rewrite(query string) string {
+	if p == "" {
+		return query
+	}
+	return prefixedIdentifiers.ReplaceAllString(query, string(p)+"$1")
+}
+
+// queries returns sqlc queries running against the prefixed tables.
+func (p tablePrefix) queries(db sqlcgen.DBTX) *sqlcgen.Queries {
+	if p == "" {
+		return sqlcgen.New(db)
+	}
+	return sqlcgen.New(prefixedDBTX{db: db, prefix: p})
+}
+
+// prefixedDBTX rewrites the statements sqlc issues before passing them on.
+type prefixedDBTX struct {
+	db     sqlcgen.DBTX
+	prefix tablePrefix
+}
+
+func (d prefixedDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.db.ExecContext(ctx, d.prefix.rewrite(query), args...)
+}
+
+func (d prefixedDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return d.db.PrepareContext(ctx, d.prefix.rewrite(query))
+}
+
+func (d prefixedDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.QueryContext(ctx, d.prefix.rewrite(query), args...)
+}
+
+func (d prefixedDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRowContext(ctx, d.prefix.rewrite(query), args...)
+}
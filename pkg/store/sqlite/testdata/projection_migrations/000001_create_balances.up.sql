@@ -0,0 +1,5 @@
+CREATE TABLE balances (
+    account_id TEXT PRIMARY KEY,
+    balance INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_balances_balance ON balances(balance);
@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+)
+
+// ErrMissingTimestamp is returned by ImportEvents for events without a timestamp.
+var ErrMissingTimestamp = errors.New("imported event has no timestamp")
+
+// WithMonotonicTimestamps makes AppendEvents and AppendEventsIdempotent keep
+// each aggregate's event timestamps non-decreasing: an event timestamped
+// before its predecessor (clock skew between writers) is moved forward to the
+// predecessor's timestamp. Clamped events keep their original timestamp in
+// their metadata; see domain.ClampedTimestamp. Only the stored events are
+// clamped: the events passed in keep their timestamps.
+//
+// Business-time reads (LoadAllEventsOrderedBy with OrderByTimestamp) then list
+// an aggregate's events in version order.
+func WithMonotonicTimestamps() EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.monotonicTimestamps = true
+	}
+}
+
+// ImportEvents appends events that carry explicit timestamps, e.g. when
+// restoring a backup or migrating from another system. Timestamps are kept as
+// given, except that they are clamped to be monotonic per aggregate as with
+// WithMonotonicTimestamps, whether or not the store was opened with it.
+// Events without a timestamp fail with ErrMissingTimestamp.
+func (s *EventStore) ImportEvents(aggregateID string, expectedVersion int64, events []*domain.Event) error {
+	for _, event := range events {
+		if event.Timestamp.IsZero() {
+			return fmt.Errorf("%w: %s", ErrMissingTimestamp, event.ID)
+		}
+	}
+	return s.appendEvents(context.Background(), aggregateID, expectedVersion, events, true, false)
+}
+
+// clampTimestamps returns events, about to be appended to aggregateID, with
+// timestamps made non-decreasing and no earlier than the aggregate's last
+// stored event. Stored timestamps have second precision, so the floor is that
+// second. Clamped events are copies, so the caller's events keep their
+// timestamps, also when the append fails (see clampedPositions).
+func (s *EventStore) clampTimestamps(ctx context.Context, q rowQuerier, aggregateID string, events []*domain.Event) ([]*domain.Event, error) {
+	var last int64
+	err := q.QueryRowContext(ctx, s.tables.rewrite(`
+		SELECT timestamp FROM events
+		WHERE aggregate_id = ?
+		ORDER BY version DESC
+		LIMIT 1`), aggregateID).Scan(&last)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to load last timestamp: %w", classifyError(err))
+	}
+
+	var floor time.Time
+	if err == nil {
+		floor = time.Unix(last, 0)
+	}
+	clamped, cloned := events, false
+	for i, event := range events {
+		if event.Timestamp.Before(floor) {
+			if !cloned {
+				clamped, cloned = slices.Clone(events), true // Leave the caller's slice alone
+			}
+			copied := *event
+			domain.ClampTimestamp(&copied, floor)
+			clamped[i] = &copied
+		}
+		floor = clamped[i].Timestamp
+	}
+	return clamped, nil
+}
+
+// clampedPositions gives the caller's events the positions of the copies
+// clampTimestamps stored in their place, once the append has committed.
+func clampedPositions(events, stored []*domain.Event) {
+	var copies map[string]*domain.Event
+	for _, event := range stored {
+		if _, clamped := domain.ClampedTimestamp(event.Metadata); clamped {
+			if copies == nil {
+				copies = make(map[string]*domain.Event)
+			}
+			copies[event.ID] = event
+		}
+	}
+	for _, event := range events {
+		if copied, ok := copies[event.ID]; ok && copied != event {
+			event.Position = copied.Position
+		}
+	}
+}
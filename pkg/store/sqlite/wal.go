@@ -0,0 +1,161 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// WithWALAutoCheckpoint sets the WAL auto-checkpoint threshold in pages
+// (PRAGMA wal_autocheckpoint) on every connection. SQLite's default is 1000
+// pages; a lower value keeps the -wal file smaller at the cost of more
+// frequent checkpoints. Auto-checkpoints never shrink the -wal file, see
+// WithWALCheckpointInterval for that.
+func WithWALAutoCheckpoint(pages int) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.walAutoCheckpoint = pages
+	}
+}
+
+// WithWALCheckpointInterval runs CheckpointWAL in the background every d.
+//
+// Long-running processes that always hold a connection open may never let
+// SQLite reset the WAL, so the -wal file keeps growing and reads get slower.
+// A periodic TRUNCATE checkpoint copies the WAL back into the database and
+// truncates the file to zero bytes.
+func WithWALCheckpointInterval(d time.Duration) EventStoreOption {
+	return func(c *eventStoreConfig) {
+		c.walCheckpointInterval = d
+	}
+}
+
+// WALCheckpoint is the result of a WAL checkpoint.
+type WALCheckpoint struct {
+	// Busy is true if the checkpoint could not complete because of concurrent
+	// readers or writers (it is retried on the next run)
+	Busy bool
+
+	// LogFrames is the number of frames in the WAL before the checkpoint
+	LogFrames int
+
+	// CheckpointedFrames is the number of frames copied into the database
+	CheckpointedFrames int
+}
+
+// CheckpointWAL copies the WAL into the database and truncates the -wal file
+// (PRAGMA wal_checkpoint(TRUNCATE)). Writes are blocked while it runs.
+func (s *EventStore) CheckpointWAL() (WALCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		result WALCheckpoint
+		busy   int
+	)
+	err := s.db.QueryRowContext(context.Background(), "PRAGMA wal_checkpoint(TRUNCATE)").
+		Scan(&busy, &result.LogFrames, &result.CheckpointedFrames)
+	if err != nil {
+		return result, fmt.Errorf("failed to checkpoint WAL: %w", classifyError(err))
+	}
+	result.Busy = busy != 0
+	return result, nil
+}
+
+// Compact rebuilds the database file to reclaim the space of deleted rows
+// (VACUUM) and then truncates the WAL. The store is locked for the duration,
+// which is proportional to the database size.
+func (s *EventStore) Compact(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", classifyError(err))
+	}
+	if s.path != "" {
+		if _, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			return fmt.Errorf("failed to checkpoint WAL: %w", classifyError(err))
+		}
+	}
+	return nil
+}
+
+// Stats describes the on-disk footprint of the event store.
+type Stats struct {
+	// DatabaseSizeBytes is the size of the main database (page_count * page_size)
+	DatabaseSizeBytes int64
+
+	// WALSizeBytes is the size of the -wal file (0 when absent, in-memory, or
+	// not in WAL mode)
+	WALSizeBytes int64
+}
+
+// Stats returns storage statistics of the event store.
+func (s *EventStore) Stats() (Stats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var stats Stats
+	var pageCount, pageSize int64
+	ctx := context.Background()
+	if err := s.db.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount); err != nil {
+		return stats, fmt.Errorf("failed to read page count: %w", classifyError(err))
+	}
+	if err := s.db.QueryRowContext(ctx, "PRAGMA page_size").Scan(&pageSize); err != nil {
+		return stats, fmt.Errorf("failed to read page size: %w", classifyError(err))
+	}
+	stats.DatabaseSizeBytes = pageCount * pageSize
+
+	if s.path != "" {
+		info, err := os.Stat(s.path + "-wal")
+		if err == nil {
+			stats.WALSizeBytes = info.Size()
+		} else if !os.IsNotExist(err) {
+			return stats, fmt.Errorf("failed to stat WAL file: %w", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// runWALCheckpoints checkpoints the WAL every interval until stop is closed.
+func (s *EventStore) runWALCheckpoints(interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := s.CheckpointWAL(); err != nil {
+				s.logger.Error("periodic WAL checkpoint failed", "path", s.path, "error", err)
+			}
+		}
+	}
+}
+
+// databasePath returns the file path of a DSN, or "" for in-memory databases.
+func databasePath(dsn string) string {
+	path := strings.TrimPrefix(dsn, "file:")
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	if path == "" || path == ":memory:" {
+		return ""
+	}
+	return path
+}
+
+// withPragma adds a _pragma parameter to the DSN so the driver applies it to
+// every new connection.
+func withPragma(dsn, pragma string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "_pragma=" + pragma
+}
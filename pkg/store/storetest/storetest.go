@@ -0,0 +1,388 @@
+// Package storetest provides a conformance suite for store.EventStore
+// implementations, so every backend is held to the behavior the SQLite store
+// defines.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/plaenen/eventstore/pkg/domain"
+	"github.com/plaenen/eventstore/pkg/store"
+)
+
+// Factory returns a new, empty event store. The suite closes it when the
+// subtest using it ends.
+type Factory func(t *testing.T) store.EventStore
+
+// RunEventStoreTests runs the conformance suite against the stores made by
+// newStore, one fresh store per subtest:
+//
+//	func TestConformance(t *testing.T) {
+//	    storetest.RunEventStoreTests(t, func(t *testing.T) store.EventStore {
+//	        return memory.NewEventStore()
+//	    })
+//	}
+func RunEventStoreTests(t *testing.T, newStore Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, es store.EventStore)
+	}{
+		{"AppendAndLoadEvents", testAppendAndLoadEvents},
+		{"ConcurrencyConflict", testConcurrencyConflict},
+		{"ConcurrentAppends", testConcurrentAppends},
+		{"IdempotentAppends", testIdempotentAppends},
+		{"UniqueConstraints", testUniqueConstraints},
+		{"LoadAllEventsOrdering", testLoadAllEventsOrdering},
+		{"Compensations", testCompensations},
+		{"CancelledContext", testCancelledContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es := newStore(t)
+			defer es.Close()
+			tt.run(t, es)
+		})
+	}
+}
+
+// newEvent returns event version of aggregateID with a payload and metadata.
+func newEvent(aggregateID string, version int64) *domain.Event {
+	return &domain.Event{
+		ID:            fmt.Sprintf("%s-v%d", aggregateID, version),
+		AggregateID:   aggregateID,
+		AggregateType: "Conformance",
+		EventType:     "conformance.Happened",
+		Version:       version,
+		Timestamp:     time.Unix(1700000000+version, 0),
+		Data:          []byte(fmt.Sprintf("payload %d", version)),
+		Metadata: domain.EventMetadata{
+			CausationID:   "cmd-" + aggregateID,
+			CorrelationID: "corr-" + aggregateID,
+			PrincipalID:   "tester",
+			Custom:        map[string]string{"source": "conformance"},
+		},
+	}
+}
+
+func testAppendAndLoadEvents(t *testing.T, es store.EventStore) {
+	ctx := context.Background()
+	events := []*domain.Event{newEvent("agg-1", 1), newEvent("agg-1", 2)}
+	if err := es.AppendEvents(ctx, "agg-1", 0, events); err != nil {
+		t.Fatalf("failed to append events: %v", err)
+	}
+	for _, event := range events {
+		if event.Position <= 0 {
+			t.Errorf("expected a position to be assigned to %s, got %d", event.ID, event.Position)
+		}
+	}
+
+	loaded, err := es.LoadEvents(ctx, "agg-1", 0)
+	if err != nil {
+		t.Fatalf("failed to load events: %v", err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(loaded))
+	}
+	for i, got := range loaded {
+		want := events[i]
+		if got.ID != want.ID || got.AggregateID != want.AggregateID || got.AggregateType != want.AggregateType ||
+			got.EventType != want.EventType || got.Version != want.Version || string(got.Data) != string(want.Data) {
+			t.Errorf("event %d: expected %+v, got %+v", i, want, got)
+		}
+		if !got.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("event %d: expected timestamp %v, got %v", i, want.Timestamp, got.Timestamp)
+		}
+		if got.Metadata.CausationID != want.Metadata.CausationID ||
+			got.Metadata.CorrelationID != want.Metadata.CorrelationID ||
+			got.Metadata.PrincipalID != want.Metadata.PrincipalID ||
+			got.Metadata.Custom["source"] != "conformance" {
+			t.Errorf("event %d: expected metadata %+v, got %+v", i, want.Metadata, got.Metadata)
+		}
+	}
+
+	after, err := es.LoadEvents(ctx, "agg-1", 1)
+	if err != nil {
+		t.Fatalf("failed to load events after version 1: %v", err)
+	}
+	if len(after) != 1 || after[0].Version != 2 {
+		t.Errorf("expected only version 2 after version 1, got %d events", len(after))
+	}
+
+	version, err := es.GetAggregateVersion("agg-1")
+	if err != nil || version != 2 {
+		t.Errorf("expected version 2, got %d (%v)", version, err)
+	}
+	if version, err := es.GetAggregateVersion("missing"); err != nil || version != 0 {
+		t.Errorf("expected version 0 for a missing aggregate, got %d (%v)", version, err)
+	}
+	if missing, err := es.LoadEvents(ctx, "missing", 0); err != nil || len(missing) != 0 {
+		t.Errorf("expected no events for a missing aggregate, got %d (%v)", len(missing), err)
+	}
+}
+
+func testConcurrencyConflict(t *testing.T, es store.EventStore) {
+	ctx := context.Background()
+	if err := es.AppendEvents(ctx, "agg-1", 0, []*domain.Event{newEvent("agg-1", 1)}); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	err := es.AppendEvents(ctx, "agg-1", 0, []*domain.Event{newEvent("agg-1", 2)})
+	if !errors.Is(err, domain.ErrConcurrencyConflict) {
+		t.Fatalf("expected a concurrency conflict for a stale version, got %v", err)
+	}
+	if !store.IsRetryable(err) {
+		t.Error("expected the conflict to be retryable")
+	}
+
+	err = es.AppendEvents(ctx, "agg-1", 5, []*domain.Event{newEvent("agg-1", 6)})
+	if !errors.Is(err, domain.ErrConcurrencyConflict) {
+		t.Errorf("expected a concurrency conflict for a future version, got %v", err)
+	}
+
+	if version, _ := es.GetAggregateVersion("agg-1"); version != 1 {
+		t.Errorf("expected rejected appends to leave version 1, got %d", version)
+	}
+}
+
+func testConcurrentAppends(t *testing.T, es store.EventStore) {
+	const writers = 8
+	var (
+		wg        sync.WaitGroup
+		succeeded atomic.Int32
+	)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			event := newEvent("agg-race", 1)
+			event.ID = fmt.Sprintf("race-%d", i)
+			err := es.AppendEvents(context.Background(), "agg-race", 0, []*domain.Event{event})
+			if err == nil {
+				succeeded.Add(1)
+			} else if !store.IsRetryable(err) {
+				t.Errorf("expected a retryable error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded.Load() != 1 {
+		t.Errorf("expected exactly one writer to succeed, got %d", succeeded.Load())
+	}
+	if version, _ := es.GetAggregateVersion("agg-race"); version != 1 {
+		t.Errorf("expected version 1, got %d", version)
+	}
+}
+
+func testIdempotentAppends(t *testing.T, es store.EventStore) {
+	ctx := context.Background()
+	events := []*domain.Event{newEvent("agg-1", 1), newEvent("agg-1", 2)}
+	first, err := es.AppendEventsIdempotent(ctx, "agg-1", 0, events, "cmd-1", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	if first.AlreadyProcessed || len(first.Events) != 2 || first.Position != events[1].Position {
+		t.Errorf("unexpected first result: %+v", first)
+	}
+
+	// A retry with the same events is deduplicated without a version check
+	retry := []*domain.Event{newEvent("agg-1", 1), newEvent("agg-1", 2)}
+	second, err := es.AppendEventsIdempotent(ctx, "agg-1", 0, retry, "cmd-1", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to retry: %v", err)
+	}
+	if !second.AlreadyProcessed {
+		t.Error("expected the retry to be reported as already processed")
+	}
+	if len(second.Events) != 2 || second.Events[0].ID != events[0].ID || second.Events[1].ID != events[1].ID {
+		t.Errorf("expected the original events, got %+v", second.Events)
+	}
+	if second.Position != first.Position {
+		t.Errorf("expected position %d, got %d", first.Position, second.Position)
+	}
+	if version, _ := es.GetAggregateVersion("agg-1"); version != 2 {
+		t.Errorf("expected the retry to append nothing, got version %d", version)
+	}
+
+	result, err := es.GetCommandResult("cmd-1")
+	if err != nil || result == nil || !result.AlreadyProcessed || len(result.Events) != 2 {
+		t.Errorf("expected the stored command result, got %+v (%v)", result, err)
+	}
+
+	if _, err := es.AppendEventsIdempotent(ctx, "agg-2", 0, []*domain.Event{newEvent("agg-2", 1)}, "", time.Hour); !errors.Is(err, domain.ErrInvalidCommand) {
+		t.Errorf("expected ErrInvalidCommand without a command ID, got %v", err)
+	}
+
+	empty, err := es.AppendEventsIdempotent(ctx, "agg-2", 0, nil, "cmd-empty", time.Hour)
+	if err != nil || empty.AlreadyProcessed || len(empty.Events) != 0 {
+		t.Errorf("expected an empty result for no events, got %+v (%v)", empty, err)
+	}
+}
+
+func testUniqueConstraints(t *testing.T, es store.EventStore) {
+	ctx := context.Background()
+	claim := []domain.UniqueConstraint{
+		{IndexName: "email", Value: "a@example.com", Operation: domain.ConstraintClaim},
+	}
+	release := []domain.UniqueConstraint{
+		{IndexName: "email", Value: "a@example.com", Operation: domain.ConstraintRelease},
+	}
+
+	alice := newEvent("user-1", 1)
+	alice.UniqueConstraints = claim
+	if err := es.AppendEvents(ctx, "user-1", 0, []*domain.Event{alice}); err != nil {
+		t.Fatalf("failed to claim: %v", err)
+	}
+	if available, owner, err := es.CheckUniqueness("email", "a@example.com"); err != nil || available || owner != "user-1" {
+		t.Errorf("expected the value to be owned by user-1, got available=%v owner=%q (%v)", available, owner, err)
+	}
+
+	// A batch violating a constraint is rejected as a whole
+	bob := []*domain.Event{newEvent("user-2", 1), newEvent("user-2", 2)}
+	bob[1].UniqueConstraints = claim
+	err := es.AppendEvents(ctx, "user-2", 0, bob)
+	if !errors.Is(err, domain.ErrUniqueConstraintViolation) {
+		t.Fatalf("expected a unique constraint violation, got %v", err)
+	}
+	if version, _ := es.GetAggregateVersion("user-2"); version != 0 {
+		t.Errorf("expected the rejected batch to append nothing, got version %d", version)
+	}
+
+	// Re-claiming one's own value is allowed
+	again := newEvent("user-1", 2)
+	again.UniqueConstraints = claim
+	if err := es.AppendEvents(ctx, "user-1", 1, []*domain.Event{again}); err != nil {
+		t.Fatalf("failed to re-claim own value: %v", err)
+	}
+
+	released := newEvent("user-1", 3)
+	released.UniqueConstraints = release
+	if err := es.AppendEvents(ctx, "user-1", 2, []*domain.Event{released}); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	if available, _, err := es.CheckUniqueness("email", "a@example.com"); err != nil || !available {
+		t.Errorf("expected the released value to be available (%v)", err)
+	}
+
+	if err := es.AppendEvents(ctx, "user-2", 0, bob); err != nil {
+		t.Fatalf("failed to claim the released value: %v", err)
+	}
+
+	if err := es.RebuildConstraints(); err != nil {
+		t.Fatalf("failed to rebuild constraints: %v", err)
+	}
+	if owner, err := es.GetConstraintOwner("email", "a@example.com"); err != nil || owner != "user-2" {
+		t.Errorf("expected user-2 to own the value after a rebuild, got %q (%v)", owner, err)
+	}
+	if owner, err := es.GetConstraintOwner("email", "unclaimed@example.com"); err != nil || owner != "" {
+		t.Errorf("expected no owner for an unclaimed value, got %q (%v)", owner, err)
+	}
+}
+
+func testLoadAllEventsOrdering(t *testing.T, es store.EventStore) {
+	ctx := context.Background()
+
+	// Interleave appends to several aggregates. The SQLite store derives
+	// positions from timestamps, so each append gets a later one.
+	var appended []*domain.Event
+	for version := int64(1); version <= 3; version++ {
+		for _, aggregateID := range []string{"agg-b", "agg-a", "agg-c"} {
+			event := newEvent(aggregateID, version)
+			event.Timestamp = time.Unix(1700000000+int64(len(appended)), 0)
+			if err := es.AppendEvents(ctx, aggregateID, version-1, []*domain.Event{event}); err != nil {
+				t.Fatalf("failed to append %s: %v", event.ID, err)
+			}
+			appended = append(appended, event)
+		}
+	}
+
+	all, err := es.LoadAllEvents(ctx, 1, 100)
+	if err != nil {
+		t.Fatalf("failed to load all events: %v", err)
+	}
+	if len(all) != len(appended) {
+		t.Fatalf("expected %d events, got %d", len(appended), len(all))
+	}
+	for i, event := range all {
+		if event.ID != appended[i].ID {
+			t.Errorf("position %d: expected %s in append order, got %s", i, appended[i].ID, event.ID)
+		}
+		if event.Position != appended[i].Position {
+			t.Errorf("%s: expected position %d as assigned on append, got %d", event.ID, appended[i].Position, event.Position)
+		}
+		if i > 0 && event.Position <= all[i-1].Position {
+			t.Errorf("expected increasing positions, got %d after %d", event.Position, all[i-1].Position)
+		}
+	}
+
+	// fromPosition is inclusive
+	page, err := es.LoadAllEvents(ctx, all[4].Position, 3)
+	if err != nil {
+		t.Fatalf("failed to load a page: %v", err)
+	}
+	if len(page) != 3 || page[0].ID != all[4].ID || page[2].ID != all[6].ID {
+		t.Errorf("expected events 5 to 7, got %d events", len(page))
+	}
+
+	rest, err := es.LoadAllEvents(ctx, all[len(all)-1].Position+1, 100)
+	if err != nil || len(rest) != 0 {
+		t.Errorf("expected no events past the end, got %d (%v)", len(rest), err)
+	}
+
+	if reader, ok := es.(store.LatestPositionReader); ok {
+		latest, err := reader.LatestPosition(ctx)
+		if err != nil || latest != all[len(all)-1].Position {
+			t.Errorf("expected latest position %d, got %d (%v)", all[len(all)-1].Position, latest, err)
+		}
+	}
+}
+
+func testCompensations(t *testing.T, es store.EventStore) {
+	ctx := context.Background()
+	deposit := newEvent("acc-1", 1)
+	deposit.Metadata.CausationID = "cmd-deposit"
+	if err := es.AppendEvents(ctx, "acc-1", 0, []*domain.Event{deposit}); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	reversal := newEvent("acc-1", 2)
+	reversal.Metadata.CausationID = "cmd-deposit"
+	reversal.Metadata.Custom = map[string]string{domain.CompensationKey: "true"}
+	if err := es.AppendEvents(ctx, "acc-1", 1, []*domain.Event{reversal}); err != nil {
+		t.Fatalf("failed to append compensation: %v", err)
+	}
+
+	compensations, err := es.LoadCompensations("cmd-deposit")
+	if err != nil {
+		t.Fatalf("failed to load compensations: %v", err)
+	}
+	if len(compensations) != 1 || compensations[0].ID != reversal.ID {
+		t.Errorf("expected only the compensating event, got %d events", len(compensations))
+	}
+	if others, err := es.LoadCompensations("cmd-other"); err != nil || len(others) != 0 {
+		t.Errorf("expected no compensations for another command, got %d (%v)", len(others), err)
+	}
+}
+
+func testCancelledContext(t *testing.T, es store.EventStore) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := es.AppendEvents(ctx, "agg-1", 0, []*domain.Event{newEvent("agg-1", 1)})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if version, _ := es.GetAggregateVersion("agg-1"); version != 0 {
+		t.Errorf("expected a cancelled append to append nothing, got version %d", version)
+	}
+
+	if _, err := es.LoadEvents(ctx, "agg-1", 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled from LoadEvents, got %v", err)
+	}
+}
@@ -177,9 +177,11 @@ func (vr *ValidationResult) ToAppError() *eventsourcing.AppError {
 
 	// Create a new app error
 	appError := &eventsourcing.AppError{
-		Code:     string(vr.ValidationCode),
-		Message:  vr.Message,
-		Solution: vr.SuggestedAction,
+		Code:            string(vr.ValidationCode),
+		Message:         vr.Message,
+		Solution:        vr.SuggestedAction,
+		Details:         make(map[string]string),
+		FieldViolations: []*eventsourcing.FieldViolation{vr.ToFieldViolation()},
 	}
 	for key, value := range vr.Metadata {
 		appError.Details[key] = fmt.Sprintf("%v", value)
@@ -189,9 +191,42 @@ func (vr *ValidationResult) ToAppError() *eventsourcing.AppError {
 	return appError
 }
 
+// ToFieldViolation converts the validation result to a FieldViolation
+func (vr *ValidationResult) ToFieldViolation() *eventsourcing.FieldViolation {
+	return &eventsourcing.FieldViolation{
+		Field:      vr.FieldName,
+		Constraint: string(vr.ValidationCode),
+		Message:    vr.Message,
+	}
+}
+
+// ToFieldViolations returns a FieldViolation for every failed validation
+func (f FieldValidationResults) ToFieldViolations() []*eventsourcing.FieldViolation {
+	var violations []*eventsourcing.FieldViolation
+	for _, fieldValidation := range f {
+		for _, validation := range fieldValidation.Validations {
+			if !validation.IsValid {
+				violations = append(violations, validation.ToFieldViolation())
+			}
+		}
+	}
+	return violations
+}
+
+// ToAppError returns a VALIDATION_FAILED AppError listing every failed
+// validation, or nil if all validations passed
+func (f FieldValidationResults) ToAppError() *eventsourcing.AppError {
+	violations := f.ToFieldViolations()
+	if len(violations) == 0 {
+		return nil
+	}
+	return eventsourcing.NewValidationAppError("Validation failed", violations...)
+}
+
 // ValidationBuilder helps build collections of validation results
 type ValidationBuilder struct {
 	results map[string][]*ValidationResult
+	fields  []string // Field names in the order they were first added
 }
 
 // NewValidationBuilder creates a new validation builder
@@ -207,28 +242,33 @@ func (b *ValidationBuilder) Add(result *ValidationResult, options ...ValidationO
 	for _, option := range options {
 		option(result)
 	}
+	if _, ok := b.results[result.FieldName]; !ok {
+		b.fields = append(b.fields, result.FieldName)
+	}
 	b.results[result.FieldName] = append(b.results[result.FieldName], result)
 	return b
 }
 
-// Build returns all validation results grouped by field
+// Build returns all validation results grouped by field, in the order the
+// fields were added
 func (b *ValidationBuilder) Build() FieldValidationResults {
 	fieldValidations := make(FieldValidationResults, 0, len(b.results))
-	for fieldName, results := range b.results {
+	for _, fieldName := range b.fields {
 		fieldValidations = append(fieldValidations, &FieldValidations{
 			FieldName:   fieldName,
-			Validations: results,
+			Validations: b.results[fieldName],
 		})
 	}
 	return fieldValidations
 }
 
-// BuildErrors returns only validation results that have errors
+// BuildErrors returns only validation results that have errors, in the order
+// the fields were added
 func (b *ValidationBuilder) BuildErrors() FieldValidationResults {
 	fieldValidations := make(FieldValidationResults, 0)
-	for fieldName, results := range b.results {
+	for _, fieldName := range b.fields {
 		var errorResults []*ValidationResult
-		for _, result := range results {
+		for _, result := range b.results[fieldName] {
 			if !result.IsValid {
 				errorResults = append(errorResults, result)
 			}